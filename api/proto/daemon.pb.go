@@ -206,14 +206,21 @@ func (x *ProcessCapacity) GetNetworkInterfaces() []string {
 
 // NodeInfo represents information about a node in the cluster
 type NodeInfo struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	Ip            string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
-	Port          int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
-	Capacity      *ProcessCapacity       `protobuf:"bytes,5,opt,name=capacity,proto3" json:"capacity,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Hostname       string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Ip             string                 `protobuf:"bytes,3,opt,name=ip,proto3" json:"ip,omitempty"`
+	Port           int32                  `protobuf:"varint,4,opt,name=port,proto3" json:"port,omitempty"`
+	Capacity       *ProcessCapacity       `protobuf:"bytes,5,opt,name=capacity,proto3" json:"capacity,omitempty"`
+	Version        string                 `protobuf:"bytes,6,opt,name=version,proto3" json:"version,omitempty"`                                       // Daemon version, used to key controller-side caches
+	Simulated      bool                   `protobuf:"varint,7,opt,name=simulated,proto3" json:"simulated,omitempty"`                                  // Results are synthetic; no iperf3 process is run
+	IperfAvailable bool                   `protobuf:"varint,8,opt,name=iperf_available,json=iperfAvailable,proto3" json:"iperf_available,omitempty"`  // iperf3 --version ran successfully
+	IperfError     string                 `protobuf:"bytes,9,opt,name=iperf_error,json=iperfError,proto3" json:"iperf_error,omitempty"`               // Why iperf3 cannot be run, if it cannot
+	PortRange      *PortRange             `protobuf:"bytes,10,opt,name=port_range,json=portRange,proto3" json:"port_range,omitempty"`                 // The daemon's configured server port range
+	TotalPorts     int32                  `protobuf:"varint,11,opt,name=total_ports,json=totalPorts,proto3" json:"total_ports,omitempty"`             // Server ports in the daemon's range
+	AvailablePorts int32                  `protobuf:"varint,12,opt,name=available_ports,json=availablePorts,proto3" json:"available_ports,omitempty"` // Server ports not allocated to a test
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *NodeInfo) Reset() {
@@ -281,6 +288,55 @@ func (x *NodeInfo) GetCapacity() *ProcessCapacity {
 	return nil
 }
 
+func (x *NodeInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetSimulated() bool {
+	if x != nil {
+		return x.Simulated
+	}
+	return false
+}
+
+func (x *NodeInfo) GetIperfAvailable() bool {
+	if x != nil {
+		return x.IperfAvailable
+	}
+	return false
+}
+
+func (x *NodeInfo) GetIperfError() string {
+	if x != nil {
+		return x.IperfError
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetPortRange() *PortRange {
+	if x != nil {
+		return x.PortRange
+	}
+	return nil
+}
+
+func (x *NodeInfo) GetTotalPorts() int32 {
+	if x != nil {
+		return x.TotalPorts
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetAvailablePorts() int32 {
+	if x != nil {
+		return x.AvailablePorts
+	}
+	return 0
+}
+
 // TestProfile contains all iperf3 parameters for a test
 type TestProfile struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
@@ -301,6 +357,10 @@ type TestProfile struct {
 	OmitSeconds       int32                  `protobuf:"varint,15,opt,name=omit_seconds,json=omitSeconds,proto3" json:"omit_seconds,omitempty"`                                                                       // Seconds to omit at start
 	JsonOutput        string                 `protobuf:"bytes,16,opt,name=json_output,json=jsonOutput,proto3" json:"json_output,omitempty"`                                                                           // JSON output mode
 	ExtraFlags        map[string]string      `protobuf:"bytes,17,rep,name=extra_flags,json=extraFlags,proto3" json:"extra_flags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Additional iperf3 flags
+	ProcessesPerPair  int32                  `protobuf:"varint,18,opt,name=processes_per_pair,json=processesPerPair,proto3" json:"processes_per_pair,omitempty"`                                                      // Number of iperf3 processes per pair (0 or 1 means one)
+	Burst             *BurstSchedule         `protobuf:"bytes,19,opt,name=burst,proto3" json:"burst,omitempty"`                                                                                                       // Run repeated short UDP bursts instead of one sustained test
+	DurationMs        int32                  `protobuf:"varint,20,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`                                                                          // Duration in milliseconds; takes precedence over duration_seconds when > 0
+	Capture           *CapturePolicy         `protobuf:"bytes,21,opt,name=capture,proto3" json:"capture,omitempty"`                                                                                                   // When to keep a packet capture of the test; unset captures nothing
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -454,6 +514,151 @@ func (x *TestProfile) GetExtraFlags() map[string]string {
 	return nil
 }
 
+func (x *TestProfile) GetProcessesPerPair() int32 {
+	if x != nil {
+		return x.ProcessesPerPair
+	}
+	return 0
+}
+
+func (x *TestProfile) GetBurst() *BurstSchedule {
+	if x != nil {
+		return x.Burst
+	}
+	return nil
+}
+
+func (x *TestProfile) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *TestProfile) GetCapture() *CapturePolicy {
+	if x != nil {
+		return x.Capture
+	}
+	return nil
+}
+
+// CapturePolicy asks a daemon with packet capture enabled to capture a
+// client test's traffic, keeping the capture only when the test fails or
+// falls below a throughput
+type CapturePolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OnFailure     bool                   `protobuf:"varint,1,opt,name=on_failure,json=onFailure,proto3" json:"on_failure,omitempty"`
+	BelowBps      float64                `protobuf:"fixed64,2,opt,name=below_bps,json=belowBps,proto3" json:"below_bps,omitempty"` // Keep when a direction's throughput is below this; 0 disables
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapturePolicy) Reset() {
+	*x = CapturePolicy{}
+	mi := &file_api_proto_daemon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapturePolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapturePolicy) ProtoMessage() {}
+
+func (x *CapturePolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapturePolicy.ProtoReflect.Descriptor instead.
+func (*CapturePolicy) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CapturePolicy) GetOnFailure() bool {
+	if x != nil {
+		return x.OnFailure
+	}
+	return false
+}
+
+func (x *CapturePolicy) GetBelowBps() float64 {
+	if x != nil {
+		return x.BelowBps
+	}
+	return 0
+}
+
+// BurstSchedule describes a UDP microburst test: repeat bursts of on_ms at
+// the profile bandwidth, each followed by off_ms of idle time
+type BurstSchedule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OnMs          int32                  `protobuf:"varint,1,opt,name=on_ms,json=onMs,proto3" json:"on_ms,omitempty"`
+	OffMs         int32                  `protobuf:"varint,2,opt,name=off_ms,json=offMs,proto3" json:"off_ms,omitempty"`
+	Repeat        int32                  `protobuf:"varint,3,opt,name=repeat,proto3" json:"repeat,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BurstSchedule) Reset() {
+	*x = BurstSchedule{}
+	mi := &file_api_proto_daemon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BurstSchedule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BurstSchedule) ProtoMessage() {}
+
+func (x *BurstSchedule) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BurstSchedule.ProtoReflect.Descriptor instead.
+func (*BurstSchedule) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BurstSchedule) GetOnMs() int32 {
+	if x != nil {
+		return x.OnMs
+	}
+	return 0
+}
+
+func (x *BurstSchedule) GetOffMs() int32 {
+	if x != nil {
+		return x.OffMs
+	}
+	return 0
+}
+
+func (x *BurstSchedule) GetRepeat() int32 {
+	if x != nil {
+		return x.Repeat
+	}
+	return 0
+}
+
 // TestPair represents a source-destination pair for testing
 type TestPair struct {
 	state           protoimpl.MessageState `protogen:"open.v1"`
@@ -468,7 +673,7 @@ type TestPair struct {
 
 func (x *TestPair) Reset() {
 	*x = TestPair{}
-	mi := &file_api_proto_daemon_proto_msgTypes[3]
+	mi := &file_api_proto_daemon_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -480,7 +685,7 @@ func (x *TestPair) String() string {
 func (*TestPair) ProtoMessage() {}
 
 func (x *TestPair) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[3]
+	mi := &file_api_proto_daemon_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -493,7 +698,7 @@ func (x *TestPair) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TestPair.ProtoReflect.Descriptor instead.
 func (*TestPair) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{3}
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *TestPair) GetSourceId() string {
@@ -542,7 +747,7 @@ type TestTopology struct {
 
 func (x *TestTopology) Reset() {
 	*x = TestTopology{}
-	mi := &file_api_proto_daemon_proto_msgTypes[4]
+	mi := &file_api_proto_daemon_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -554,7 +759,7 @@ func (x *TestTopology) String() string {
 func (*TestTopology) ProtoMessage() {}
 
 func (x *TestTopology) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[4]
+	mi := &file_api_proto_daemon_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -567,7 +772,7 @@ func (x *TestTopology) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TestTopology.ProtoReflect.Descriptor instead.
 func (*TestTopology) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{4}
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *TestTopology) GetServerAssignments() []*TestPair {
@@ -586,23 +791,41 @@ func (x *TestTopology) GetClientAssignments() []*TestPair {
 
 // TestResult contains the output from an iperf3 run
 type TestResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TestId        string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
-	SourceId      string                 `protobuf:"bytes,2,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
-	DestinationId string                 `protobuf:"bytes,3,opt,name=destination_id,json=destinationId,proto3" json:"destination_id,omitempty"`
-	Status        TestStatus             `protobuf:"varint,4,opt,name=status,proto3,enum=iperf.daemon.v1.TestStatus" json:"status,omitempty"`
-	IperfJson     string                 `protobuf:"bytes,5,opt,name=iperf_json,json=iperfJson,proto3" json:"iperf_json,omitempty"` // Raw iperf3 JSON output
-	ErrorMessage  string                 `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
-	StartTimeUnix int64                  `protobuf:"varint,7,opt,name=start_time_unix,json=startTimeUnix,proto3" json:"start_time_unix,omitempty"`
-	EndTimeUnix   int64                  `protobuf:"varint,8,opt,name=end_time_unix,json=endTimeUnix,proto3" json:"end_time_unix,omitempty"`
-	ExitCode      int32                  `protobuf:"varint,9,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TestId          string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
+	SourceId        string                 `protobuf:"bytes,2,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"`
+	DestinationId   string                 `protobuf:"bytes,3,opt,name=destination_id,json=destinationId,proto3" json:"destination_id,omitempty"`
+	Status          TestStatus             `protobuf:"varint,4,opt,name=status,proto3,enum=iperf.daemon.v1.TestStatus" json:"status,omitempty"`
+	IperfJson       string                 `protobuf:"bytes,5,opt,name=iperf_json,json=iperfJson,proto3" json:"iperf_json,omitempty"` // Raw iperf3 JSON output
+	ErrorMessage    string                 `protobuf:"bytes,6,opt,name=error_message,json=errorMessage,proto3" json:"error_message,omitempty"`
+	StartTimeUnix   int64                  `protobuf:"varint,7,opt,name=start_time_unix,json=startTimeUnix,proto3" json:"start_time_unix,omitempty"`
+	EndTimeUnix     int64                  `protobuf:"varint,8,opt,name=end_time_unix,json=endTimeUnix,proto3" json:"end_time_unix,omitempty"`
+	ExitCode        int32                  `protobuf:"varint,9,opt,name=exit_code,json=exitCode,proto3" json:"exit_code,omitempty"`
+	StartDelayMs    int32                  `protobuf:"varint,10,opt,name=start_delay_ms,json=startDelayMs,proto3" json:"start_delay_ms,omitempty"`        // Requested start offset of the client
+	DurationSeconds int32                  `protobuf:"varint,11,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"` // Requested duration of the client
+	DurationMs      int32                  `protobuf:"varint,12,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`                // Requested duration of the client in milliseconds
+	// Data-plane addresses: the destination the client was asked to reach, and
+	// the local and remote addresses of its connection as reported by iperf3
+	DestinationAddress string `protobuf:"bytes,13,opt,name=destination_address,json=destinationAddress,proto3" json:"destination_address,omitempty"`
+	LocalAddress       string `protobuf:"bytes,14,opt,name=local_address,json=localAddress,proto3" json:"local_address,omitempty"`
+	RemoteAddress      string `protobuf:"bytes,15,opt,name=remote_address,json=remoteAddress,proto3" json:"remote_address,omitempty"`
+	CongestionControl  string `protobuf:"bytes,16,opt,name=congestion_control,json=congestionControl,proto3" json:"congestion_control,omitempty"` // Algorithm requested with -C, if any
+	// Copy of iperf_json the daemon saved under its result directory when
+	// Initialize set save_results, and the host it is on
+	ResultFile string `protobuf:"bytes,17,opt,name=result_file,json=resultFile,proto3" json:"result_file,omitempty"`
+	ResultHost string `protobuf:"bytes,18,opt,name=result_host,json=resultHost,proto3" json:"result_host,omitempty"`
+	// Start and end of the client process with millisecond precision
+	StartTimeUnixMs int64  `protobuf:"varint,19,opt,name=start_time_unix_ms,json=startTimeUnixMs,proto3" json:"start_time_unix_ms,omitempty"`
+	EndTimeUnixMs   int64  `protobuf:"varint,20,opt,name=end_time_unix_ms,json=endTimeUnixMs,proto3" json:"end_time_unix_ms,omitempty"`
+	RunId           string `protobuf:"bytes,21,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`                   // Run whose Initialize preceded the test's start
+	CaptureFile     string `protobuf:"bytes,22,opt,name=capture_file,json=captureFile,proto3" json:"capture_file,omitempty"` // Packet capture kept under the result directory, on result_host
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *TestResult) Reset() {
 	*x = TestResult{}
-	mi := &file_api_proto_daemon_proto_msgTypes[5]
+	mi := &file_api_proto_daemon_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -614,7 +837,7 @@ func (x *TestResult) String() string {
 func (*TestResult) ProtoMessage() {}
 
 func (x *TestResult) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[5]
+	mi := &file_api_proto_daemon_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -627,7 +850,7 @@ func (x *TestResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TestResult.ProtoReflect.Descriptor instead.
 func (*TestResult) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{5}
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *TestResult) GetTestId() string {
@@ -693,23 +916,140 @@ func (x *TestResult) GetExitCode() int32 {
 	return 0
 }
 
+func (x *TestResult) GetStartDelayMs() int32 {
+	if x != nil {
+		return x.StartDelayMs
+	}
+	return 0
+}
+
+func (x *TestResult) GetDurationSeconds() int32 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *TestResult) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *TestResult) GetDestinationAddress() string {
+	if x != nil {
+		return x.DestinationAddress
+	}
+	return ""
+}
+
+func (x *TestResult) GetLocalAddress() string {
+	if x != nil {
+		return x.LocalAddress
+	}
+	return ""
+}
+
+func (x *TestResult) GetRemoteAddress() string {
+	if x != nil {
+		return x.RemoteAddress
+	}
+	return ""
+}
+
+func (x *TestResult) GetCongestionControl() string {
+	if x != nil {
+		return x.CongestionControl
+	}
+	return ""
+}
+
+func (x *TestResult) GetResultFile() string {
+	if x != nil {
+		return x.ResultFile
+	}
+	return ""
+}
+
+func (x *TestResult) GetResultHost() string {
+	if x != nil {
+		return x.ResultHost
+	}
+	return ""
+}
+
+func (x *TestResult) GetStartTimeUnixMs() int64 {
+	if x != nil {
+		return x.StartTimeUnixMs
+	}
+	return 0
+}
+
+func (x *TestResult) GetEndTimeUnixMs() int64 {
+	if x != nil {
+		return x.EndTimeUnixMs
+	}
+	return 0
+}
+
+func (x *TestResult) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *TestResult) GetCaptureFile() string {
+	if x != nil {
+		return x.CaptureFile
+	}
+	return ""
+}
+
 // DaemonStatus represents daemon health and resource usage
 type DaemonStatus struct {
-	state            protoimpl.MessageState `protogen:"open.v1"`
-	Healthy          bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
-	RunningProcesses int32                  `protobuf:"varint,2,opt,name=running_processes,json=runningProcesses,proto3" json:"running_processes,omitempty"`
-	CompletedTests   int32                  `protobuf:"varint,3,opt,name=completed_tests,json=completedTests,proto3" json:"completed_tests,omitempty"`
-	FailedTests      int32                  `protobuf:"varint,4,opt,name=failed_tests,json=failedTests,proto3" json:"failed_tests,omitempty"`
-	CurrentCapacity  *ProcessCapacity       `protobuf:"bytes,5,opt,name=current_capacity,json=currentCapacity,proto3" json:"current_capacity,omitempty"`
-	UptimeSeconds    int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
-	Version          string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Healthy              bool                   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	RunningProcesses     int32                  `protobuf:"varint,2,opt,name=running_processes,json=runningProcesses,proto3" json:"running_processes,omitempty"`
+	CompletedTests       int32                  `protobuf:"varint,3,opt,name=completed_tests,json=completedTests,proto3" json:"completed_tests,omitempty"`
+	FailedTests          int32                  `protobuf:"varint,4,opt,name=failed_tests,json=failedTests,proto3" json:"failed_tests,omitempty"`
+	CurrentCapacity      *ProcessCapacity       `protobuf:"bytes,5,opt,name=current_capacity,json=currentCapacity,proto3" json:"current_capacity,omitempty"`
+	UptimeSeconds        int64                  `protobuf:"varint,6,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	Version              string                 `protobuf:"bytes,7,opt,name=version,proto3" json:"version,omitempty"`
+	PortReservations     []*PortReservation     `protobuf:"bytes,8,rep,name=port_reservations,json=portReservations,proto3" json:"port_reservations,omitempty"` // Port sub-ranges claimed by controller runs
+	ServerPorts          []int32                `protobuf:"varint,9,rep,packed,name=server_ports,json=serverPorts,proto3" json:"server_ports,omitempty"`        // Ports with a running iperf3 server
+	StoredResults        int32                  `protobuf:"varint,10,opt,name=stored_results,json=storedResults,proto3" json:"stored_results,omitempty"`        // Results not yet retrieved with clear_after_retrieval
+	Maintenance          bool                   `protobuf:"varint,11,opt,name=maintenance,proto3" json:"maintenance,omitempty"`                                 // New tests are refused until maintenance is disabled
+	MaintenanceSinceUnix int64                  `protobuf:"varint,12,opt,name=maintenance_since_unix,json=maintenanceSinceUnix,proto3" json:"maintenance_since_unix,omitempty"`
+	MaintenanceReason    string                 `protobuf:"bytes,13,opt,name=maintenance_reason,json=maintenanceReason,proto3" json:"maintenance_reason,omitempty"`
+	Simulated            bool                   `protobuf:"varint,14,opt,name=simulated,proto3" json:"simulated,omitempty"`                                 // Started with --simulate; results are synthetic
+	Processes            []*ProcessStatus       `protobuf:"bytes,15,rep,name=processes,proto3" json:"processes,omitempty"`                                  // Only with GetStatusRequest.include_processes
+	IperfAvailable       bool                   `protobuf:"varint,16,opt,name=iperf_available,json=iperfAvailable,proto3" json:"iperf_available,omitempty"` // iperf3 --version ran successfully
+	IperfError           string                 `protobuf:"bytes,17,opt,name=iperf_error,json=iperfError,proto3" json:"iperf_error,omitempty"`              // Why iperf3 cannot be run, if it cannot
+	// Size of the stored results, and the results dropped because storing
+	// them would have exceeded the daemon's caps
+	StoredResultBytes int64 `protobuf:"varint,18,opt,name=stored_result_bytes,json=storedResultBytes,proto3" json:"stored_result_bytes,omitempty"`
+	ResultsEvicted    int64 `protobuf:"varint,19,opt,name=results_evicted,json=resultsEvicted,proto3" json:"results_evicted,omitempty"`
+	ResultsRejected   int64 `protobuf:"varint,20,opt,name=results_rejected,json=resultsRejected,proto3" json:"results_rejected,omitempty"`
+	// Tests of the run GetStatusRequest.run_id names that finished, counted
+	// since the run started whether or not their results were retrieved.
+	// run_id is only set when the daemon knows the run.
+	RunId             string `protobuf:"bytes,21,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	RunCompletedTests int32  `protobuf:"varint,22,opt,name=run_completed_tests,json=runCompletedTests,proto3" json:"run_completed_tests,omitempty"`
+	RunFailedTests    int32  `protobuf:"varint,23,opt,name=run_failed_tests,json=runFailedTests,proto3" json:"run_failed_tests,omitempty"`
+	// Processes stopped that have not exited yet, included in running_processes
+	StoppingProcesses int32      `protobuf:"varint,24,opt,name=stopping_processes,json=stoppingProcesses,proto3" json:"stopping_processes,omitempty"`
+	PortRange         *PortRange `protobuf:"bytes,25,opt,name=port_range,json=portRange,proto3" json:"port_range,omitempty"`                 // The daemon's configured server port range
+	TotalPorts        int32      `protobuf:"varint,26,opt,name=total_ports,json=totalPorts,proto3" json:"total_ports,omitempty"`             // Server ports in the daemon's range
+	AvailablePorts    int32      `protobuf:"varint,27,opt,name=available_ports,json=availablePorts,proto3" json:"available_ports,omitempty"` // Server ports not allocated to a test
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
 }
 
 func (x *DaemonStatus) Reset() {
 	*x = DaemonStatus{}
-	mi := &file_api_proto_daemon_proto_msgTypes[6]
+	mi := &file_api_proto_daemon_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -721,7 +1061,7 @@ func (x *DaemonStatus) String() string {
 func (*DaemonStatus) ProtoMessage() {}
 
 func (x *DaemonStatus) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[6]
+	mi := &file_api_proto_daemon_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -734,7 +1074,7 @@ func (x *DaemonStatus) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DaemonStatus.ProtoReflect.Descriptor instead.
 func (*DaemonStatus) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{6}
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DaemonStatus) GetHealthy() bool {
@@ -786,34 +1126,1733 @@ func (x *DaemonStatus) GetVersion() string {
 	return ""
 }
 
-type InitializeRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	PortRangeStart int32                  `protobuf:"varint,1,opt,name=port_range_start,json=portRangeStart,proto3" json:"port_range_start,omitempty"`
-	PortRangeEnd   int32                  `protobuf:"varint,2,opt,name=port_range_end,json=portRangeEnd,proto3" json:"port_range_end,omitempty"`
-	MaxProcesses   int32                  `protobuf:"varint,3,opt,name=max_processes,json=maxProcesses,proto3" json:"max_processes,omitempty"`
-	CpuAffinity    bool                   `protobuf:"varint,4,opt,name=cpu_affinity,json=cpuAffinity,proto3" json:"cpu_affinity,omitempty"`
-	LogLevel       string                 `protobuf:"bytes,5,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
-	ResultDir      string                 `protobuf:"bytes,6,opt,name=result_dir,json=resultDir,proto3" json:"result_dir,omitempty"`
-	SaveResults    bool                   `protobuf:"varint,7,opt,name=save_results,json=saveResults,proto3" json:"save_results,omitempty"` // Whether to save results to timestamped files
-	unknownFields  protoimpl.UnknownFields
+func (x *DaemonStatus) GetPortReservations() []*PortReservation {
+	if x != nil {
+		return x.PortReservations
+	}
+	return nil
+}
+
+func (x *DaemonStatus) GetServerPorts() []int32 {
+	if x != nil {
+		return x.ServerPorts
+	}
+	return nil
+}
+
+func (x *DaemonStatus) GetStoredResults() int32 {
+	if x != nil {
+		return x.StoredResults
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetMaintenance() bool {
+	if x != nil {
+		return x.Maintenance
+	}
+	return false
+}
+
+func (x *DaemonStatus) GetMaintenanceSinceUnix() int64 {
+	if x != nil {
+		return x.MaintenanceSinceUnix
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetMaintenanceReason() string {
+	if x != nil {
+		return x.MaintenanceReason
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetSimulated() bool {
+	if x != nil {
+		return x.Simulated
+	}
+	return false
+}
+
+func (x *DaemonStatus) GetProcesses() []*ProcessStatus {
+	if x != nil {
+		return x.Processes
+	}
+	return nil
+}
+
+func (x *DaemonStatus) GetIperfAvailable() bool {
+	if x != nil {
+		return x.IperfAvailable
+	}
+	return false
+}
+
+func (x *DaemonStatus) GetIperfError() string {
+	if x != nil {
+		return x.IperfError
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetStoredResultBytes() int64 {
+	if x != nil {
+		return x.StoredResultBytes
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetResultsEvicted() int64 {
+	if x != nil {
+		return x.ResultsEvicted
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetResultsRejected() int64 {
+	if x != nil {
+		return x.ResultsRejected
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *DaemonStatus) GetRunCompletedTests() int32 {
+	if x != nil {
+		return x.RunCompletedTests
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetRunFailedTests() int32 {
+	if x != nil {
+		return x.RunFailedTests
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetStoppingProcesses() int32 {
+	if x != nil {
+		return x.StoppingProcesses
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetPortRange() *PortRange {
+	if x != nil {
+		return x.PortRange
+	}
+	return nil
+}
+
+func (x *DaemonStatus) GetTotalPorts() int32 {
+	if x != nil {
+		return x.TotalPorts
+	}
+	return 0
+}
+
+func (x *DaemonStatus) GetAvailablePorts() int32 {
+	if x != nil {
+		return x.AvailablePorts
+	}
+	return 0
+}
+
+// ProcessStatus describes one running iperf3 process
+type ProcessStatus struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TestId          string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"` // "server-<port>" for servers
+	Server          bool                   `protobuf:"varint,2,opt,name=server,proto3" json:"server,omitempty"`
+	Port            int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	StartTimeUnixMs int64                  `protobuf:"varint,4,opt,name=start_time_unix_ms,json=startTimeUnixMs,proto3" json:"start_time_unix_ms,omitempty"`
+	Pid             int32                  `protobuf:"varint,5,opt,name=pid,proto3" json:"pid,omitempty"`           // 0 until the iperf3 process runs, and for simulated tests
+	Stopping        bool                   `protobuf:"varint,6,opt,name=stopping,proto3" json:"stopping,omitempty"` // Stopped, but the process has not exited yet
+	Owner           string                 `protobuf:"bytes,7,opt,name=owner,proto3" json:"owner,omitempty"`        // The controller run that started it; "" if none was given
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ProcessStatus) Reset() {
+	*x = ProcessStatus{}
+	mi := &file_api_proto_daemon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessStatus) ProtoMessage() {}
+
+func (x *ProcessStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessStatus.ProtoReflect.Descriptor instead.
+func (*ProcessStatus) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ProcessStatus) GetTestId() string {
+	if x != nil {
+		return x.TestId
+	}
+	return ""
+}
+
+func (x *ProcessStatus) GetServer() bool {
+	if x != nil {
+		return x.Server
+	}
+	return false
+}
+
+func (x *ProcessStatus) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *ProcessStatus) GetStartTimeUnixMs() int64 {
+	if x != nil {
+		return x.StartTimeUnixMs
+	}
+	return 0
+}
+
+func (x *ProcessStatus) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+func (x *ProcessStatus) GetStopping() bool {
+	if x != nil {
+		return x.Stopping
+	}
+	return false
+}
+
+func (x *ProcessStatus) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+// PortRange is an inclusive range of server ports
+type PortRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Start         int32                  `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32                  `protobuf:"varint,2,opt,name=end,proto3" json:"end,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PortRange) Reset() {
+	*x = PortRange{}
+	mi := &file_api_proto_daemon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortRange) ProtoMessage() {}
+
+func (x *PortRange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortRange.ProtoReflect.Descriptor instead.
+func (*PortRange) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *PortRange) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *PortRange) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+// PortReservation is a port sub-range claimed by one controller run
+type PortReservation struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Owner          string                 `protobuf:"bytes,1,opt,name=owner,proto3" json:"owner,omitempty"`
+	Range          *PortRange             `protobuf:"bytes,2,opt,name=range,proto3" json:"range,omitempty"`
+	RequestedPorts int32                  `protobuf:"varint,3,opt,name=requested_ports,json=requestedPorts,proto3" json:"requested_ports,omitempty"` // Server ports the run's topology needs
+	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
-func (x *InitializeRequest) Reset() {
-	*x = InitializeRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[7]
+func (x *PortReservation) Reset() {
+	*x = PortReservation{}
+	mi := &file_api_proto_daemon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PortReservation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PortReservation) ProtoMessage() {}
+
+func (x *PortReservation) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PortReservation.ProtoReflect.Descriptor instead.
+func (*PortReservation) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PortReservation) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *PortReservation) GetRange() *PortRange {
+	if x != nil {
+		return x.Range
+	}
+	return nil
+}
+
+func (x *PortReservation) GetRequestedPorts() int32 {
+	if x != nil {
+		return x.RequestedPorts
+	}
+	return 0
+}
+
+// NodeFacts describes the host environment at the time of a run
+type NodeFacts struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	Os                   string                 `protobuf:"bytes,1,opt,name=os,proto3" json:"os,omitempty"`
+	Platform             string                 `protobuf:"bytes,2,opt,name=platform,proto3" json:"platform,omitempty"` // Distribution and version, e.g. "ubuntu 22.04"
+	KernelVersion        string                 `protobuf:"bytes,3,opt,name=kernel_version,json=kernelVersion,proto3" json:"kernel_version,omitempty"`
+	Arch                 string                 `protobuf:"bytes,4,opt,name=arch,proto3" json:"arch,omitempty"`
+	IperfVersion         string                 `protobuf:"bytes,5,opt,name=iperf_version,json=iperfVersion,proto3" json:"iperf_version,omitempty"`
+	DefaultQdisc         string                 `protobuf:"bytes,6,opt,name=default_qdisc,json=defaultQdisc,proto3" json:"default_qdisc,omitempty"`                           // net.core.default_qdisc
+	TcpCongestionControl string                 `protobuf:"bytes,7,opt,name=tcp_congestion_control,json=tcpCongestionControl,proto3" json:"tcp_congestion_control,omitempty"` // net.ipv4.tcp_congestion_control
+	RmemMax              int64                  `protobuf:"varint,8,opt,name=rmem_max,json=rmemMax,proto3" json:"rmem_max,omitempty"`                                         // net.core.rmem_max
+	WmemMax              int64                  `protobuf:"varint,9,opt,name=wmem_max,json=wmemMax,proto3" json:"wmem_max,omitempty"`                                         // net.core.wmem_max
+	Interfaces           []*InterfaceFacts      `protobuf:"bytes,10,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *NodeFacts) Reset() {
+	*x = NodeFacts{}
+	mi := &file_api_proto_daemon_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeFacts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeFacts) ProtoMessage() {}
+
+func (x *NodeFacts) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeFacts.ProtoReflect.Descriptor instead.
+func (*NodeFacts) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *NodeFacts) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetPlatform() string {
+	if x != nil {
+		return x.Platform
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetKernelVersion() string {
+	if x != nil {
+		return x.KernelVersion
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetIperfVersion() string {
+	if x != nil {
+		return x.IperfVersion
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetDefaultQdisc() string {
+	if x != nil {
+		return x.DefaultQdisc
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetTcpCongestionControl() string {
+	if x != nil {
+		return x.TcpCongestionControl
+	}
+	return ""
+}
+
+func (x *NodeFacts) GetRmemMax() int64 {
+	if x != nil {
+		return x.RmemMax
+	}
+	return 0
+}
+
+func (x *NodeFacts) GetWmemMax() int64 {
+	if x != nil {
+		return x.WmemMax
+	}
+	return 0
+}
+
+func (x *NodeFacts) GetInterfaces() []*InterfaceFacts {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+// InterfaceFacts describes a network interface
+type InterfaceFacts struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Mtu           int32                  `protobuf:"varint,2,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	SpeedMbps     int64                  `protobuf:"varint,3,opt,name=speed_mbps,json=speedMbps,proto3" json:"speed_mbps,omitempty"` // 0 when unknown
+	Driver        string                 `protobuf:"bytes,4,opt,name=driver,proto3" json:"driver,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InterfaceFacts) Reset() {
+	*x = InterfaceFacts{}
+	mi := &file_api_proto_daemon_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InterfaceFacts) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InterfaceFacts) ProtoMessage() {}
+
+func (x *InterfaceFacts) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InterfaceFacts.ProtoReflect.Descriptor instead.
+func (*InterfaceFacts) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *InterfaceFacts) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InterfaceFacts) GetMtu() int32 {
+	if x != nil {
+		return x.Mtu
+	}
+	return 0
+}
+
+func (x *InterfaceFacts) GetSpeedMbps() int64 {
+	if x != nil {
+		return x.SpeedMbps
+	}
+	return 0
+}
+
+func (x *InterfaceFacts) GetDriver() string {
+	if x != nil {
+		return x.Driver
+	}
+	return ""
+}
+
+type InitializeRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	PortRangeStart int32                  `protobuf:"varint,1,opt,name=port_range_start,json=portRangeStart,proto3" json:"port_range_start,omitempty"`
+	PortRangeEnd   int32                  `protobuf:"varint,2,opt,name=port_range_end,json=portRangeEnd,proto3" json:"port_range_end,omitempty"`
+	MaxProcesses   int32                  `protobuf:"varint,3,opt,name=max_processes,json=maxProcesses,proto3" json:"max_processes,omitempty"`
+	CpuAffinity    bool                   `protobuf:"varint,4,opt,name=cpu_affinity,json=cpuAffinity,proto3" json:"cpu_affinity,omitempty"`
+	LogLevel       string                 `protobuf:"bytes,5,opt,name=log_level,json=logLevel,proto3" json:"log_level,omitempty"`
+	ResultDir      string                 `protobuf:"bytes,6,opt,name=result_dir,json=resultDir,proto3" json:"result_dir,omitempty"`
+	SaveResults    bool                   `protobuf:"varint,7,opt,name=save_results,json=saveResults,proto3" json:"save_results,omitempty"` // Save a copy of each client result under the result directory
+	// Record CPU, memory and NIC counters this often until StopAll; 0 disables
+	SampleIntervalMs int32 `protobuf:"varint,8,opt,name=sample_interval_ms,json=sampleIntervalMs,proto3" json:"sample_interval_ms,omitempty"`
+	// Identifies the run this Initialize starts. Results of the clients it
+	// starts are tagged with it and GetStatus can count them separately; the
+	// daemon assigns one if it is empty.
+	RunId         string `protobuf:"bytes,9,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitializeRequest) Reset() {
+	*x = InitializeRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitializeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeRequest) ProtoMessage() {}
+
+func (x *InitializeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeRequest.ProtoReflect.Descriptor instead.
+func (*InitializeRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *InitializeRequest) GetPortRangeStart() int32 {
+	if x != nil {
+		return x.PortRangeStart
+	}
+	return 0
+}
+
+func (x *InitializeRequest) GetPortRangeEnd() int32 {
+	if x != nil {
+		return x.PortRangeEnd
+	}
+	return 0
+}
+
+func (x *InitializeRequest) GetMaxProcesses() int32 {
+	if x != nil {
+		return x.MaxProcesses
+	}
+	return 0
+}
+
+func (x *InitializeRequest) GetCpuAffinity() bool {
+	if x != nil {
+		return x.CpuAffinity
+	}
+	return false
+}
+
+func (x *InitializeRequest) GetLogLevel() string {
+	if x != nil {
+		return x.LogLevel
+	}
+	return ""
+}
+
+func (x *InitializeRequest) GetResultDir() string {
+	if x != nil {
+		return x.ResultDir
+	}
+	return ""
+}
+
+func (x *InitializeRequest) GetSaveResults() bool {
+	if x != nil {
+		return x.SaveResults
+	}
+	return false
+}
+
+func (x *InitializeRequest) GetSampleIntervalMs() int32 {
+	if x != nil {
+		return x.SampleIntervalMs
+	}
+	return 0
+}
+
+func (x *InitializeRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type InitializeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	NodeInfo      *NodeInfo              `protobuf:"bytes,3,opt,name=node_info,json=nodeInfo,proto3" json:"node_info,omitempty"`
+	RunId         string                 `protobuf:"bytes,4,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"` // The run's ID, as given or assigned
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitializeResponse) Reset() {
+	*x = InitializeResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitializeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitializeResponse) ProtoMessage() {}
+
+func (x *InitializeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitializeResponse.ProtoReflect.Descriptor instead.
+func (*InitializeResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *InitializeResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *InitializeResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *InitializeResponse) GetNodeInfo() *NodeInfo {
+	if x != nil {
+		return x.NodeInfo
+	}
+	return nil
+}
+
+func (x *InitializeResponse) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type PrepareTestRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Topology  *TestTopology          `protobuf:"bytes,1,opt,name=topology,proto3" json:"topology,omitempty"`
+	PortRange *PortRange             `protobuf:"bytes,2,opt,name=port_range,json=portRange,proto3" json:"port_range,omitempty"` // Optional sub-range this run's server ports come from
+	Owner     string                 `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`                          // Identifies the controller run holding the port range
+	// What to do when a profile requests a congestion control algorithm the
+	// host does not have: "reject" (default) refuses the topology, "warn" only
+	// reports it
+	CongestionControlPolicy string `protobuf:"bytes,4,opt,name=congestion_control_policy,json=congestionControlPolicy,proto3" json:"congestion_control_policy,omitempty"`
+	// Allocate the topology's server ports from the daemon's range, or the
+	// reserved sub-range, and return them in allocated_ports. Ports allocated
+	// by an earlier request of the same owner are released first.
+	AllocatePorts bool `protobuf:"varint,5,opt,name=allocate_ports,json=allocatePorts,proto3" json:"allocate_ports,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PrepareTestRequest) Reset() {
+	*x = PrepareTestRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrepareTestRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrepareTestRequest) ProtoMessage() {}
+
+func (x *PrepareTestRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrepareTestRequest.ProtoReflect.Descriptor instead.
+func (*PrepareTestRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *PrepareTestRequest) GetTopology() *TestTopology {
+	if x != nil {
+		return x.Topology
+	}
+	return nil
+}
+
+func (x *PrepareTestRequest) GetPortRange() *PortRange {
+	if x != nil {
+		return x.PortRange
+	}
+	return nil
+}
+
+func (x *PrepareTestRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+func (x *PrepareTestRequest) GetCongestionControlPolicy() string {
+	if x != nil {
+		return x.CongestionControlPolicy
+	}
+	return ""
+}
+
+func (x *PrepareTestRequest) GetAllocatePorts() bool {
+	if x != nil {
+		return x.AllocatePorts
+	}
+	return false
+}
+
+type PrepareTestResponse struct {
+	state                        protoimpl.MessageState `protogen:"open.v1"`
+	CanHandle                    bool                   `protobuf:"varint,1,opt,name=can_handle,json=canHandle,proto3" json:"can_handle,omitempty"`
+	Message                      string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	RequiredCapacity             *ProcessCapacity       `protobuf:"bytes,3,opt,name=required_capacity,json=requiredCapacity,proto3" json:"required_capacity,omitempty"`
+	AvailableCapacity            *ProcessCapacity       `protobuf:"bytes,4,opt,name=available_capacity,json=availableCapacity,proto3" json:"available_capacity,omitempty"`
+	PortRange                    *PortRange             `protobuf:"bytes,5,opt,name=port_range,json=portRange,proto3" json:"port_range,omitempty"`                                                            // The daemon's configured server port range
+	TotalPorts                   int32                  `protobuf:"varint,6,opt,name=total_ports,json=totalPorts,proto3" json:"total_ports,omitempty"`                                                        // Server ports in the daemon's range
+	AvailablePorts               int32                  `protobuf:"varint,7,opt,name=available_ports,json=availablePorts,proto3" json:"available_ports,omitempty"`                                            // Server ports not allocated to another test
+	UnavailableCongestionControl []string               `protobuf:"bytes,8,rep,name=unavailable_congestion_control,json=unavailableCongestionControl,proto3" json:"unavailable_congestion_control,omitempty"` // Requested algorithms the host does not have
+	AvailableCongestionControl   []string               `protobuf:"bytes,9,rep,name=available_congestion_control,json=availableCongestionControl,proto3" json:"available_congestion_control,omitempty"`       // Empty if the host does not report them
+	Breakdown                    *CapacityBreakdown     `protobuf:"bytes,10,opt,name=breakdown,proto3" json:"breakdown,omitempty"`                                                                            // What the daemon's slots and ports are used by
+	// Server ports allocated for the topology when requested, one per iperf3
+	// server process, held until all processes are stopped
+	AllocatedPorts []int32 `protobuf:"varint,11,rep,packed,name=allocated_ports,json=allocatedPorts,proto3" json:"allocated_ports,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *PrepareTestResponse) Reset() {
+	*x = PrepareTestResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PrepareTestResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PrepareTestResponse) ProtoMessage() {}
+
+func (x *PrepareTestResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PrepareTestResponse.ProtoReflect.Descriptor instead.
+func (*PrepareTestResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *PrepareTestResponse) GetCanHandle() bool {
+	if x != nil {
+		return x.CanHandle
+	}
+	return false
+}
+
+func (x *PrepareTestResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *PrepareTestResponse) GetRequiredCapacity() *ProcessCapacity {
+	if x != nil {
+		return x.RequiredCapacity
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetAvailableCapacity() *ProcessCapacity {
+	if x != nil {
+		return x.AvailableCapacity
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetPortRange() *PortRange {
+	if x != nil {
+		return x.PortRange
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetTotalPorts() int32 {
+	if x != nil {
+		return x.TotalPorts
+	}
+	return 0
+}
+
+func (x *PrepareTestResponse) GetAvailablePorts() int32 {
+	if x != nil {
+		return x.AvailablePorts
+	}
+	return 0
+}
+
+func (x *PrepareTestResponse) GetUnavailableCongestionControl() []string {
+	if x != nil {
+		return x.UnavailableCongestionControl
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetAvailableCongestionControl() []string {
+	if x != nil {
+		return x.AvailableCongestionControl
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetBreakdown() *CapacityBreakdown {
+	if x != nil {
+		return x.Breakdown
+	}
+	return nil
+}
+
+func (x *PrepareTestResponse) GetAllocatedPorts() []int32 {
+	if x != nil {
+		return x.AllocatedPorts
+	}
+	return nil
+}
+
+// CapacityBreakdown accounts for a daemon's process slots and server ports
+type CapacityBreakdown struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MaxSlots       int32                  `protobuf:"varint,1,opt,name=max_slots,json=maxSlots,proto3" json:"max_slots,omitempty"`
+	UsedSlots      int32                  `protobuf:"varint,2,opt,name=used_slots,json=usedSlots,proto3" json:"used_slots,omitempty"`
+	RunningServers int32                  `protobuf:"varint,3,opt,name=running_servers,json=runningServers,proto3" json:"running_servers,omitempty"`
+	RunningClients int32                  `protobuf:"varint,4,opt,name=running_clients,json=runningClients,proto3" json:"running_clients,omitempty"`
+	// Used slots held by no running process, such as those leaked on an
+	// error path; negative if processes run without a slot
+	UnaccountedSlots  int32 `protobuf:"varint,5,opt,name=unaccounted_slots,json=unaccountedSlots,proto3" json:"unaccounted_slots,omitempty"`
+	StoppingProcesses int32 `protobuf:"varint,6,opt,name=stopping_processes,json=stoppingProcesses,proto3" json:"stopping_processes,omitempty"` // Stopped but not exited; they hold no slot
+	TotalPorts        int32 `protobuf:"varint,7,opt,name=total_ports,json=totalPorts,proto3" json:"total_ports,omitempty"`
+	ServerPorts       int32 `protobuf:"varint,8,opt,name=server_ports,json=serverPorts,proto3" json:"server_ports,omitempty"`          // Ports of the range running servers listen on
+	AllocatedPorts    int32 `protobuf:"varint,9,opt,name=allocated_ports,json=allocatedPorts,proto3" json:"allocated_ports,omitempty"` // Ports the allocator handed to tests
+	FreePorts         int32 `protobuf:"varint,10,opt,name=free_ports,json=freePorts,proto3" json:"free_ports,omitempty"`
+	ReservedPorts     int32 `protobuf:"varint,11,opt,name=reserved_ports,json=reservedPorts,proto3" json:"reserved_ports,omitempty"` // Ports of the ranges reserved by runs
+	// Ways the process manager's, capacity calculator's and port allocator's
+	// counts disagree
+	Divergences   []string `protobuf:"bytes,12,rep,name=divergences,proto3" json:"divergences,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapacityBreakdown) Reset() {
+	*x = CapacityBreakdown{}
+	mi := &file_api_proto_daemon_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapacityBreakdown) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapacityBreakdown) ProtoMessage() {}
+
+func (x *CapacityBreakdown) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapacityBreakdown.ProtoReflect.Descriptor instead.
+func (*CapacityBreakdown) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CapacityBreakdown) GetMaxSlots() int32 {
+	if x != nil {
+		return x.MaxSlots
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetUsedSlots() int32 {
+	if x != nil {
+		return x.UsedSlots
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetRunningServers() int32 {
+	if x != nil {
+		return x.RunningServers
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetRunningClients() int32 {
+	if x != nil {
+		return x.RunningClients
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetUnaccountedSlots() int32 {
+	if x != nil {
+		return x.UnaccountedSlots
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetStoppingProcesses() int32 {
+	if x != nil {
+		return x.StoppingProcesses
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetTotalPorts() int32 {
+	if x != nil {
+		return x.TotalPorts
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetServerPorts() int32 {
+	if x != nil {
+		return x.ServerPorts
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetAllocatedPorts() int32 {
+	if x != nil {
+		return x.AllocatedPorts
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetFreePorts() int32 {
+	if x != nil {
+		return x.FreePorts
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetReservedPorts() int32 {
+	if x != nil {
+		return x.ReservedPorts
+	}
+	return 0
+}
+
+func (x *CapacityBreakdown) GetDivergences() []string {
+	if x != nil {
+		return x.Divergences
+	}
+	return nil
+}
+
+type StartServersRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Ports          []int32                `protobuf:"varint,1,rep,packed,name=ports,proto3" json:"ports,omitempty"`
+	TimeoutSeconds int32                  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
+	BindIps        map[int32]string       `protobuf:"bytes,3,rep,name=bind_ips,json=bindIps,proto3" json:"bind_ips,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Port -> local address its server binds to (iperf3 -B); other ports listen on every address
+	Owner          string                 `protobuf:"bytes,4,opt,name=owner,proto3" json:"owner,omitempty"`                                                                                               // The controller run the servers belong to, which StopAll stops them for
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StartServersRequest) Reset() {
+	*x = StartServersRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartServersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartServersRequest) ProtoMessage() {}
+
+func (x *StartServersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartServersRequest.ProtoReflect.Descriptor instead.
+func (*StartServersRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *StartServersRequest) GetPorts() []int32 {
+	if x != nil {
+		return x.Ports
+	}
+	return nil
+}
+
+func (x *StartServersRequest) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *StartServersRequest) GetBindIps() map[int32]string {
+	if x != nil {
+		return x.BindIps
+	}
+	return nil
+}
+
+func (x *StartServersRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type StartServersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	StartedPorts  []int32                `protobuf:"varint,3,rep,packed,name=started_ports,json=startedPorts,proto3" json:"started_ports,omitempty"`
+	Errors        []string               `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartServersResponse) Reset() {
+	*x = StartServersResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartServersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartServersResponse) ProtoMessage() {}
+
+func (x *StartServersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartServersResponse.ProtoReflect.Descriptor instead.
+func (*StartServersResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *StartServersResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StartServersResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StartServersResponse) GetStartedPorts() []int32 {
+	if x != nil {
+		return x.StartedPorts
+	}
+	return nil
+}
+
+func (x *StartServersResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type ClientTarget struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	TestId           string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
+	DestinationIp    string                 `protobuf:"bytes,2,opt,name=destination_ip,json=destinationIp,proto3" json:"destination_ip,omitempty"`
+	DestinationPort  int32                  `protobuf:"varint,3,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
+	Profile          *TestProfile           `protobuf:"bytes,4,opt,name=profile,proto3" json:"profile,omitempty"`
+	DestinationPorts []int32                `protobuf:"varint,5,rep,packed,name=destination_ports,json=destinationPorts,proto3" json:"destination_ports,omitempty"` // One port per process when processes_per_pair > 1
+	DurationSeconds  int32                  `protobuf:"varint,6,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`           // Overrides the profile duration when > 0
+	StartDelayMs     int32                  `protobuf:"varint,7,opt,name=start_delay_ms,json=startDelayMs,proto3" json:"start_delay_ms,omitempty"`                  // Delay before the client starts, measured from StartClients
+	DurationMs       int32                  `protobuf:"varint,8,opt,name=duration_ms,json=durationMs,proto3" json:"duration_ms,omitempty"`                          // Overrides the profile duration when > 0; takes precedence over duration_seconds
+	IpVersion        int32                  `protobuf:"varint,9,opt,name=ip_version,json=ipVersion,proto3" json:"ip_version,omitempty"`                             // 4 or 6 to pass iperf3 -4 or -6; 0 leaves the family to the destination address
+	BindIp           string                 `protobuf:"bytes,10,opt,name=bind_ip,json=bindIp,proto3" json:"bind_ip,omitempty"`                                      // Local address the client binds to (iperf3 -B); empty leaves it to the routing table
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *ClientTarget) Reset() {
+	*x = ClientTarget{}
+	mi := &file_api_proto_daemon_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ClientTarget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClientTarget) ProtoMessage() {}
+
+func (x *ClientTarget) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClientTarget.ProtoReflect.Descriptor instead.
+func (*ClientTarget) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ClientTarget) GetTestId() string {
+	if x != nil {
+		return x.TestId
+	}
+	return ""
+}
+
+func (x *ClientTarget) GetDestinationIp() string {
+	if x != nil {
+		return x.DestinationIp
+	}
+	return ""
+}
+
+func (x *ClientTarget) GetDestinationPort() int32 {
+	if x != nil {
+		return x.DestinationPort
+	}
+	return 0
+}
+
+func (x *ClientTarget) GetProfile() *TestProfile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+func (x *ClientTarget) GetDestinationPorts() []int32 {
+	if x != nil {
+		return x.DestinationPorts
+	}
+	return nil
+}
+
+func (x *ClientTarget) GetDurationSeconds() int32 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *ClientTarget) GetStartDelayMs() int32 {
+	if x != nil {
+		return x.StartDelayMs
+	}
+	return 0
+}
+
+func (x *ClientTarget) GetDurationMs() int32 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+func (x *ClientTarget) GetIpVersion() int32 {
+	if x != nil {
+		return x.IpVersion
+	}
+	return 0
+}
+
+func (x *ClientTarget) GetBindIp() string {
+	if x != nil {
+		return x.BindIp
+	}
+	return ""
+}
+
+type StartClientsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Targets       []*ClientTarget        `protobuf:"bytes,1,rep,name=targets,proto3" json:"targets,omitempty"`
+	Owner         string                 `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"` // The controller run the clients belong to, which StopAll stops them for
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartClientsRequest) Reset() {
+	*x = StartClientsRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartClientsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartClientsRequest) ProtoMessage() {}
+
+func (x *StartClientsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartClientsRequest.ProtoReflect.Descriptor instead.
+func (*StartClientsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *StartClientsRequest) GetTargets() []*ClientTarget {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+func (x *StartClientsRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type StartClientsResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message        string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	StartedTestIds []string               `protobuf:"bytes,3,rep,name=started_test_ids,json=startedTestIds,proto3" json:"started_test_ids,omitempty"`
+	Errors         []string               `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *StartClientsResponse) Reset() {
+	*x = StartClientsResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartClientsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartClientsResponse) ProtoMessage() {}
+
+func (x *StartClientsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartClientsResponse.ProtoReflect.Descriptor instead.
+func (*StartClientsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *StartClientsResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StartClientsResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StartClientsResponse) GetStartedTestIds() []string {
+	if x != nil {
+		return x.StartedTestIds
+	}
+	return nil
+}
+
+func (x *StartClientsResponse) GetErrors() []string {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+type StopAllRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Kill processes at once, including those stopped earlier that have not
+	// exited, instead of sending SIGTERM and killing them after the daemon's
+	// process stop timeout
+	Force bool `protobuf:"varint,1,opt,name=force,proto3" json:"force,omitempty"`
+	// Stop only the processes of this controller run and release only its
+	// reserved port range and allocated ports, leaving those of other runs
+	// sharing the daemon. Empty stops every process and releases every range
+	// and port.
+	Owner         string `protobuf:"bytes,2,opt,name=owner,proto3" json:"owner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopAllRequest) Reset() {
+	*x = StopAllRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopAllRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopAllRequest) ProtoMessage() {}
+
+func (x *StopAllRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopAllRequest.ProtoReflect.Descriptor instead.
+func (*StopAllRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *StopAllRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+func (x *StopAllRequest) GetOwner() string {
+	if x != nil {
+		return x.Owner
+	}
+	return ""
+}
+
+type StopAllResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	StoppedProcesses int32                  `protobuf:"varint,3,opt,name=stopped_processes,json=stoppedProcesses,proto3" json:"stopped_processes,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *StopAllResponse) Reset() {
+	*x = StopAllResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopAllResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopAllResponse) ProtoMessage() {}
+
+func (x *StopAllResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopAllResponse.ProtoReflect.Descriptor instead.
+func (*StopAllResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *StopAllResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StopAllResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StopAllResponse) GetStoppedProcesses() int32 {
+	if x != nil {
+		return x.StoppedProcesses
+	}
+	return 0
+}
+
+type GetResultsRequest struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	TestIds             []string               `protobuf:"bytes,1,rep,name=test_ids,json=testIds,proto3" json:"test_ids,omitempty"` // Empty means all results
+	ClearAfterRetrieval bool                   `protobuf:"varint,2,opt,name=clear_after_retrieval,json=clearAfterRetrieval,proto3" json:"clear_after_retrieval,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *GetResultsRequest) Reset() {
+	*x = GetResultsRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultsRequest) ProtoMessage() {}
+
+func (x *GetResultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultsRequest.ProtoReflect.Descriptor instead.
+func (*GetResultsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *GetResultsRequest) GetTestIds() []string {
+	if x != nil {
+		return x.TestIds
+	}
+	return nil
+}
+
+func (x *GetResultsRequest) GetClearAfterRetrieval() bool {
+	if x != nil {
+		return x.ClearAfterRetrieval
+	}
+	return false
+}
+
+type GetResultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*TestResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResultsResponse) Reset() {
+	*x = GetResultsResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResultsResponse) ProtoMessage() {}
+
+func (x *GetResultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResultsResponse.ProtoReflect.Descriptor instead.
+func (*GetResultsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *GetResultsResponse) GetResults() []*TestResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *GetResultsResponse) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+type GetStatusRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	IncludeProcesses bool                   `protobuf:"varint,1,opt,name=include_processes,json=includeProcesses,proto3" json:"include_processes,omitempty"` // List running processes in DaemonStatus.processes
+	RunId            string                 `protobuf:"bytes,2,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`                                   // Count this run's tests in DaemonStatus.run_*_tests
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *GetStatusRequest) GetIncludeProcesses() bool {
+	if x != nil {
+		return x.IncludeProcesses
+	}
+	return false
+}
+
+func (x *GetStatusRequest) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *DaemonStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[29]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InitializeRequest) String() string {
+func (x *GetStatusResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InitializeRequest) ProtoMessage() {}
+func (*GetStatusResponse) ProtoMessage() {}
 
-func (x *InitializeRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[7]
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[29]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -824,84 +2863,77 @@ func (x *InitializeRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InitializeRequest.ProtoReflect.Descriptor instead.
-func (*InitializeRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{29}
 }
 
-func (x *InitializeRequest) GetPortRangeStart() int32 {
+func (x *GetStatusResponse) GetStatus() *DaemonStatus {
 	if x != nil {
-		return x.PortRangeStart
+		return x.Status
 	}
-	return 0
+	return nil
 }
 
-func (x *InitializeRequest) GetPortRangeEnd() int32 {
-	if x != nil {
-		return x.PortRangeEnd
-	}
-	return 0
+type GetCapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InitializeRequest) GetMaxProcesses() int32 {
-	if x != nil {
-		return x.MaxProcesses
-	}
-	return 0
+func (x *GetCapabilitiesRequest) Reset() {
+	*x = GetCapabilitiesRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
-func (x *InitializeRequest) GetCpuAffinity() bool {
-	if x != nil {
-		return x.CpuAffinity
-	}
-	return false
+func (x *GetCapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *InitializeRequest) GetLogLevel() string {
-	if x != nil {
-		return x.LogLevel
-	}
-	return ""
-}
+func (*GetCapabilitiesRequest) ProtoMessage() {}
 
-func (x *InitializeRequest) GetResultDir() string {
+func (x *GetCapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[30]
 	if x != nil {
-		return x.ResultDir
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *InitializeRequest) GetSaveResults() bool {
-	if x != nil {
-		return x.SaveResults
-	}
-	return false
+// Deprecated: Use GetCapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*GetCapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{30}
 }
 
-type InitializeResponse struct {
+type GetCapabilitiesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	NodeInfo      *NodeInfo              `protobuf:"bytes,3,opt,name=node_info,json=nodeInfo,proto3" json:"node_info,omitempty"`
+	Version       string                 `protobuf:"bytes,1,opt,name=version,proto3" json:"version,omitempty"`
+	Features      []string               `protobuf:"bytes,2,rep,name=features,proto3" json:"features,omitempty"` // Feature names, e.g. "processes_per_pair"
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *InitializeResponse) Reset() {
-	*x = InitializeResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[8]
+func (x *GetCapabilitiesResponse) Reset() {
+	*x = GetCapabilitiesResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[31]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *InitializeResponse) String() string {
+func (x *GetCapabilitiesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*InitializeResponse) ProtoMessage() {}
+func (*GetCapabilitiesResponse) ProtoMessage() {}
 
-func (x *InitializeResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[8]
+func (x *GetCapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[31]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -912,54 +2944,83 @@ func (x *InitializeResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use InitializeResponse.ProtoReflect.Descriptor instead.
-func (*InitializeResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use GetCapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*GetCapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{31}
 }
 
-func (x *InitializeResponse) GetSuccess() bool {
+func (x *GetCapabilitiesResponse) GetVersion() string {
 	if x != nil {
-		return x.Success
+		return x.Version
 	}
-	return false
+	return ""
 }
 
-func (x *InitializeResponse) GetMessage() string {
+func (x *GetCapabilitiesResponse) GetFeatures() []string {
 	if x != nil {
-		return x.Message
+		return x.Features
 	}
-	return ""
+	return nil
 }
 
-func (x *InitializeResponse) GetNodeInfo() *NodeInfo {
+type GatherFactsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GatherFactsRequest) Reset() {
+	*x = GatherFactsRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GatherFactsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GatherFactsRequest) ProtoMessage() {}
+
+func (x *GatherFactsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[32]
 	if x != nil {
-		return x.NodeInfo
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-type PrepareTestRequest struct {
+// Deprecated: Use GatherFactsRequest.ProtoReflect.Descriptor instead.
+func (*GatherFactsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{32}
+}
+
+type GatherFactsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Topology      *TestTopology          `protobuf:"bytes,1,opt,name=topology,proto3" json:"topology,omitempty"`
+	Facts         *NodeFacts             `protobuf:"bytes,1,opt,name=facts,proto3" json:"facts,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PrepareTestRequest) Reset() {
-	*x = PrepareTestRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[9]
+func (x *GatherFactsResponse) Reset() {
+	*x = GatherFactsResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[33]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PrepareTestRequest) String() string {
+func (x *GatherFactsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PrepareTestRequest) ProtoMessage() {}
+func (*GatherFactsResponse) ProtoMessage() {}
 
-func (x *PrepareTestRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[9]
+func (x *GatherFactsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[33]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -970,43 +3031,40 @@ func (x *PrepareTestRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PrepareTestRequest.ProtoReflect.Descriptor instead.
-func (*PrepareTestRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use GatherFactsResponse.ProtoReflect.Descriptor instead.
+func (*GatherFactsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{33}
 }
 
-func (x *PrepareTestRequest) GetTopology() *TestTopology {
+func (x *GatherFactsResponse) GetFacts() *NodeFacts {
 	if x != nil {
-		return x.Topology
+		return x.Facts
 	}
 	return nil
 }
 
-type PrepareTestResponse struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	CanHandle         bool                   `protobuf:"varint,1,opt,name=can_handle,json=canHandle,proto3" json:"can_handle,omitempty"`
-	Message           string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	RequiredCapacity  *ProcessCapacity       `protobuf:"bytes,3,opt,name=required_capacity,json=requiredCapacity,proto3" json:"required_capacity,omitempty"`
-	AvailableCapacity *ProcessCapacity       `protobuf:"bytes,4,opt,name=available_capacity,json=availableCapacity,proto3" json:"available_capacity,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type DownloadFileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"` // As reported in TestResult.result_file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PrepareTestResponse) Reset() {
-	*x = PrepareTestResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[10]
+func (x *DownloadFileRequest) Reset() {
+	*x = DownloadFileRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[34]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *PrepareTestResponse) String() string {
+func (x *DownloadFileRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PrepareTestResponse) ProtoMessage() {}
+func (*DownloadFileRequest) ProtoMessage() {}
 
-func (x *PrepareTestResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[10]
+func (x *DownloadFileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[34]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1017,62 +3075,83 @@ func (x *PrepareTestResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PrepareTestResponse.ProtoReflect.Descriptor instead.
-func (*PrepareTestResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use DownloadFileRequest.ProtoReflect.Descriptor instead.
+func (*DownloadFileRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{34}
 }
 
-func (x *PrepareTestResponse) GetCanHandle() bool {
+func (x *DownloadFileRequest) GetPath() string {
 	if x != nil {
-		return x.CanHandle
+		return x.Path
 	}
-	return false
+	return ""
 }
 
-func (x *PrepareTestResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+type FileChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *PrepareTestResponse) GetRequiredCapacity() *ProcessCapacity {
+func (x *FileChunk) Reset() {
+	*x = FileChunk{}
+	mi := &file_api_proto_daemon_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChunk) ProtoMessage() {}
+
+func (x *FileChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[35]
 	if x != nil {
-		return x.RequiredCapacity
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *PrepareTestResponse) GetAvailableCapacity() *ProcessCapacity {
+// Deprecated: Use FileChunk.ProtoReflect.Descriptor instead.
+func (*FileChunk) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *FileChunk) GetData() []byte {
 	if x != nil {
-		return x.AvailableCapacity
+		return x.Data
 	}
 	return nil
 }
 
-type StartServersRequest struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	Ports          []int32                `protobuf:"varint,1,rep,packed,name=ports,proto3" json:"ports,omitempty"`
-	TimeoutSeconds int32                  `protobuf:"varint,2,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+type GetSamplesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StartServersRequest) Reset() {
-	*x = StartServersRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[11]
+func (x *GetSamplesRequest) Reset() {
+	*x = GetSamplesRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[36]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StartServersRequest) String() string {
+func (x *GetSamplesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StartServersRequest) ProtoMessage() {}
+func (*GetSamplesRequest) ProtoMessage() {}
 
-func (x *StartServersRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[11]
+func (x *GetSamplesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[36]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1083,50 +3162,82 @@ func (x *StartServersRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StartServersRequest.ProtoReflect.Descriptor instead.
-func (*StartServersRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use GetSamplesRequest.ProtoReflect.Descriptor instead.
+func (*GetSamplesRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{36}
 }
 
-func (x *StartServersRequest) GetPorts() []int32 {
+type GetSamplesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Samples       []*ResourceSample      `protobuf:"bytes,1,rep,name=samples,proto3" json:"samples,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSamplesResponse) Reset() {
+	*x = GetSamplesResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSamplesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSamplesResponse) ProtoMessage() {}
+
+func (x *GetSamplesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[37]
 	if x != nil {
-		return x.Ports
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return nil
+	return mi.MessageOf(x)
 }
 
-func (x *StartServersRequest) GetTimeoutSeconds() int32 {
+// Deprecated: Use GetSamplesResponse.ProtoReflect.Descriptor instead.
+func (*GetSamplesResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *GetSamplesResponse) GetSamples() []*ResourceSample {
 	if x != nil {
-		return x.TimeoutSeconds
+		return x.Samples
 	}
-	return 0
+	return nil
 }
 
-type StartServersResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	StartedPorts  []int32                `protobuf:"varint,3,rep,packed,name=started_ports,json=startedPorts,proto3" json:"started_ports,omitempty"`
-	Errors        []string               `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+// ResourceSample is the host's resource usage at one point in time
+type ResourceSample struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TimeUnixMs      int64                  `protobuf:"varint,1,opt,name=time_unix_ms,json=timeUnixMs,proto3" json:"time_unix_ms,omitempty"`
+	CpuPercent      float64                `protobuf:"fixed64,2,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"` // Busy share of all cores since the previous sample
+	MemoryUsedBytes uint64                 `protobuf:"varint,3,opt,name=memory_used_bytes,json=memoryUsedBytes,proto3" json:"memory_used_bytes,omitempty"`
+	MemoryPercent   float64                `protobuf:"fixed64,4,opt,name=memory_percent,json=memoryPercent,proto3" json:"memory_percent,omitempty"`
+	Nics            []*NICCounters         `protobuf:"bytes,5,rep,name=nics,proto3" json:"nics,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *StartServersResponse) Reset() {
-	*x = StartServersResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[12]
+func (x *ResourceSample) Reset() {
+	*x = ResourceSample{}
+	mi := &file_api_proto_daemon_proto_msgTypes[38]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StartServersResponse) String() string {
+func (x *ResourceSample) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StartServersResponse) ProtoMessage() {}
+func (*ResourceSample) ProtoMessage() {}
 
-func (x *StartServersResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[12]
+func (x *ResourceSample) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[38]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1137,64 +3248,71 @@ func (x *StartServersResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StartServersResponse.ProtoReflect.Descriptor instead.
-func (*StartServersResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{12}
+// Deprecated: Use ResourceSample.ProtoReflect.Descriptor instead.
+func (*ResourceSample) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{38}
 }
 
-func (x *StartServersResponse) GetSuccess() bool {
+func (x *ResourceSample) GetTimeUnixMs() int64 {
 	if x != nil {
-		return x.Success
+		return x.TimeUnixMs
 	}
-	return false
+	return 0
 }
 
-func (x *StartServersResponse) GetMessage() string {
+func (x *ResourceSample) GetCpuPercent() float64 {
 	if x != nil {
-		return x.Message
+		return x.CpuPercent
 	}
-	return ""
+	return 0
 }
 
-func (x *StartServersResponse) GetStartedPorts() []int32 {
+func (x *ResourceSample) GetMemoryUsedBytes() uint64 {
 	if x != nil {
-		return x.StartedPorts
+		return x.MemoryUsedBytes
 	}
-	return nil
+	return 0
 }
 
-func (x *StartServersResponse) GetErrors() []string {
+func (x *ResourceSample) GetMemoryPercent() float64 {
 	if x != nil {
-		return x.Errors
+		return x.MemoryPercent
+	}
+	return 0
+}
+
+func (x *ResourceSample) GetNics() []*NICCounters {
+	if x != nil {
+		return x.Nics
 	}
 	return nil
 }
 
-type ClientTarget struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	TestId          string                 `protobuf:"bytes,1,opt,name=test_id,json=testId,proto3" json:"test_id,omitempty"`
-	DestinationIp   string                 `protobuf:"bytes,2,opt,name=destination_ip,json=destinationIp,proto3" json:"destination_ip,omitempty"`
-	DestinationPort int32                  `protobuf:"varint,3,opt,name=destination_port,json=destinationPort,proto3" json:"destination_port,omitempty"`
-	Profile         *TestProfile           `protobuf:"bytes,4,opt,name=profile,proto3" json:"profile,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+// NICCounters are an interface's cumulative byte counters
+type NICCounters struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	BytesSent     uint64                 `protobuf:"varint,2,opt,name=bytes_sent,json=bytesSent,proto3" json:"bytes_sent,omitempty"`
+	BytesRecv     uint64                 `protobuf:"varint,3,opt,name=bytes_recv,json=bytesRecv,proto3" json:"bytes_recv,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ClientTarget) Reset() {
-	*x = ClientTarget{}
-	mi := &file_api_proto_daemon_proto_msgTypes[13]
+func (x *NICCounters) Reset() {
+	*x = NICCounters{}
+	mi := &file_api_proto_daemon_proto_msgTypes[39]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ClientTarget) String() string {
+func (x *NICCounters) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ClientTarget) ProtoMessage() {}
+func (*NICCounters) ProtoMessage() {}
 
-func (x *ClientTarget) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[13]
+func (x *NICCounters) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[39]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1205,61 +3323,54 @@ func (x *ClientTarget) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ClientTarget.ProtoReflect.Descriptor instead.
-func (*ClientTarget) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{13}
-}
-
-func (x *ClientTarget) GetTestId() string {
-	if x != nil {
-		return x.TestId
-	}
-	return ""
+// Deprecated: Use NICCounters.ProtoReflect.Descriptor instead.
+func (*NICCounters) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{39}
 }
 
-func (x *ClientTarget) GetDestinationIp() string {
+func (x *NICCounters) GetName() string {
 	if x != nil {
-		return x.DestinationIp
+		return x.Name
 	}
 	return ""
 }
 
-func (x *ClientTarget) GetDestinationPort() int32 {
+func (x *NICCounters) GetBytesSent() uint64 {
 	if x != nil {
-		return x.DestinationPort
+		return x.BytesSent
 	}
 	return 0
 }
 
-func (x *ClientTarget) GetProfile() *TestProfile {
+func (x *NICCounters) GetBytesRecv() uint64 {
 	if x != nil {
-		return x.Profile
+		return x.BytesRecv
 	}
-	return nil
+	return 0
 }
 
-type StartClientsRequest struct {
+type StopTestsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Targets       []*ClientTarget        `protobuf:"bytes,1,rep,name=targets,proto3" json:"targets,omitempty"`
+	TestIds       []string               `protobuf:"bytes,1,rep,name=test_ids,json=testIds,proto3" json:"test_ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StartClientsRequest) Reset() {
-	*x = StartClientsRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[14]
+func (x *StopTestsRequest) Reset() {
+	*x = StopTestsRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StartClientsRequest) String() string {
+func (x *StopTestsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StartClientsRequest) ProtoMessage() {}
+func (*StopTestsRequest) ProtoMessage() {}
 
-func (x *StartClientsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[14]
+func (x *StopTestsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1270,43 +3381,41 @@ func (x *StartClientsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StartClientsRequest.ProtoReflect.Descriptor instead.
-func (*StartClientsRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{14}
+// Deprecated: Use StopTestsRequest.ProtoReflect.Descriptor instead.
+func (*StopTestsRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{40}
 }
 
-func (x *StartClientsRequest) GetTargets() []*ClientTarget {
+func (x *StopTestsRequest) GetTestIds() []string {
 	if x != nil {
-		return x.Targets
+		return x.TestIds
 	}
 	return nil
 }
 
-type StartClientsResponse struct {
+type StopTestsResponse struct {
 	state          protoimpl.MessageState `protogen:"open.v1"`
-	Success        bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message        string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	StartedTestIds []string               `protobuf:"bytes,3,rep,name=started_test_ids,json=startedTestIds,proto3" json:"started_test_ids,omitempty"`
-	Errors         []string               `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	StoppedTestIds []string               `protobuf:"bytes,1,rep,name=stopped_test_ids,json=stoppedTestIds,proto3" json:"stopped_test_ids,omitempty"`
+	Errors         []string               `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"` // Tests that were not running, with the reason
 	unknownFields  protoimpl.UnknownFields
 	sizeCache      protoimpl.SizeCache
 }
 
-func (x *StartClientsResponse) Reset() {
-	*x = StartClientsResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[15]
+func (x *StopTestsResponse) Reset() {
+	*x = StopTestsResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StartClientsResponse) String() string {
+func (x *StopTestsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StartClientsResponse) ProtoMessage() {}
+func (*StopTestsResponse) ProtoMessage() {}
 
-func (x *StartClientsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[15]
+func (x *StopTestsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1317,61 +3426,48 @@ func (x *StartClientsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StartClientsResponse.ProtoReflect.Descriptor instead.
-func (*StartClientsResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{15}
-}
-
-func (x *StartClientsResponse) GetSuccess() bool {
-	if x != nil {
-		return x.Success
-	}
-	return false
-}
-
-func (x *StartClientsResponse) GetMessage() string {
-	if x != nil {
-		return x.Message
-	}
-	return ""
+// Deprecated: Use StopTestsResponse.ProtoReflect.Descriptor instead.
+func (*StopTestsResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *StartClientsResponse) GetStartedTestIds() []string {
+func (x *StopTestsResponse) GetStoppedTestIds() []string {
 	if x != nil {
-		return x.StartedTestIds
+		return x.StoppedTestIds
 	}
 	return nil
 }
 
-func (x *StartClientsResponse) GetErrors() []string {
+func (x *StopTestsResponse) GetErrors() []string {
 	if x != nil {
 		return x.Errors
 	}
 	return nil
 }
 
-type StopAllRequest struct {
+type SetMaintenanceRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Force         bool                   `protobuf:"varint,1,opt,name=force,proto3" json:"force,omitempty"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"` // Shown in status and in the messages of refused RPCs
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StopAllRequest) Reset() {
-	*x = StopAllRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[16]
+func (x *SetMaintenanceRequest) Reset() {
+	*x = SetMaintenanceRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StopAllRequest) String() string {
+func (x *SetMaintenanceRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopAllRequest) ProtoMessage() {}
+func (*SetMaintenanceRequest) ProtoMessage() {}
 
-func (x *StopAllRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[16]
+func (x *SetMaintenanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1382,42 +3478,49 @@ func (x *StopAllRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopAllRequest.ProtoReflect.Descriptor instead.
-func (*StopAllRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{16}
+// Deprecated: Use SetMaintenanceRequest.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *StopAllRequest) GetForce() bool {
+func (x *SetMaintenanceRequest) GetEnabled() bool {
 	if x != nil {
-		return x.Force
+		return x.Enabled
 	}
 	return false
 }
 
-type StopAllResponse struct {
+func (x *SetMaintenanceRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type SetMaintenanceResponse struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
-	Success          bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message          string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	StoppedProcesses int32                  `protobuf:"varint,3,opt,name=stopped_processes,json=stoppedProcesses,proto3" json:"stopped_processes,omitempty"`
+	Enabled          bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	SinceUnix        int64                  `protobuf:"varint,2,opt,name=since_unix,json=sinceUnix,proto3" json:"since_unix,omitempty"`                      // When maintenance was enabled, 0 when disabled
+	RunningProcesses int32                  `protobuf:"varint,3,opt,name=running_processes,json=runningProcesses,proto3" json:"running_processes,omitempty"` // Processes still running, which are left to finish
 	unknownFields    protoimpl.UnknownFields
 	sizeCache        protoimpl.SizeCache
 }
 
-func (x *StopAllResponse) Reset() {
-	*x = StopAllResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[17]
+func (x *SetMaintenanceResponse) Reset() {
+	*x = SetMaintenanceResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StopAllResponse) String() string {
+func (x *SetMaintenanceResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StopAllResponse) ProtoMessage() {}
+func (*SetMaintenanceResponse) ProtoMessage() {}
 
-func (x *StopAllResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[17]
+func (x *SetMaintenanceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1428,55 +3531,57 @@ func (x *StopAllResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StopAllResponse.ProtoReflect.Descriptor instead.
-func (*StopAllResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{17}
+// Deprecated: Use SetMaintenanceResponse.ProtoReflect.Descriptor instead.
+func (*SetMaintenanceResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *StopAllResponse) GetSuccess() bool {
+func (x *SetMaintenanceResponse) GetEnabled() bool {
 	if x != nil {
-		return x.Success
+		return x.Enabled
 	}
 	return false
 }
 
-func (x *StopAllResponse) GetMessage() string {
+func (x *SetMaintenanceResponse) GetSinceUnix() int64 {
 	if x != nil {
-		return x.Message
+		return x.SinceUnix
 	}
-	return ""
+	return 0
 }
 
-func (x *StopAllResponse) GetStoppedProcesses() int32 {
+func (x *SetMaintenanceResponse) GetRunningProcesses() int32 {
 	if x != nil {
-		return x.StoppedProcesses
+		return x.RunningProcesses
 	}
 	return 0
 }
 
-type GetResultsRequest struct {
-	state               protoimpl.MessageState `protogen:"open.v1"`
-	TestIds             []string               `protobuf:"bytes,1,rep,name=test_ids,json=testIds,proto3" json:"test_ids,omitempty"` // Empty means all results
-	ClearAfterRetrieval bool                   `protobuf:"varint,2,opt,name=clear_after_retrieval,json=clearAfterRetrieval,proto3" json:"clear_after_retrieval,omitempty"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+// ProbeTarget is an address and port to attempt a TCP connection to
+type ProbeTarget struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Port          int32                  `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetResultsRequest) Reset() {
-	*x = GetResultsRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[18]
+func (x *ProbeTarget) Reset() {
+	*x = ProbeTarget{}
+	mi := &file_api_proto_daemon_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetResultsRequest) String() string {
+func (x *ProbeTarget) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetResultsRequest) ProtoMessage() {}
+func (*ProbeTarget) ProtoMessage() {}
 
-func (x *GetResultsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[18]
+func (x *ProbeTarget) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1487,48 +3592,57 @@ func (x *GetResultsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetResultsRequest.ProtoReflect.Descriptor instead.
-func (*GetResultsRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{18}
+// Deprecated: Use ProbeTarget.ProtoReflect.Descriptor instead.
+func (*ProbeTarget) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *GetResultsRequest) GetTestIds() []string {
+func (x *ProbeTarget) GetNodeId() string {
 	if x != nil {
-		return x.TestIds
+		return x.NodeId
 	}
-	return nil
+	return ""
 }
 
-func (x *GetResultsRequest) GetClearAfterRetrieval() bool {
+func (x *ProbeTarget) GetAddress() string {
 	if x != nil {
-		return x.ClearAfterRetrieval
+		return x.Address
 	}
-	return false
+	return ""
 }
 
-type GetResultsResponse struct {
+func (x *ProbeTarget) GetPort() int32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+type ProbeConnectivityRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Results       []*TestResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
-	TotalCount    int32                  `protobuf:"varint,2,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	Targets       []*ProbeTarget         `protobuf:"bytes,1,rep,name=targets,proto3" json:"targets,omitempty"`
+	TimeoutMs     int32                  `protobuf:"varint,2,opt,name=timeout_ms,json=timeoutMs,proto3" json:"timeout_ms,omitempty"`             // Per connection attempt
+	MaxConcurrent int32                  `protobuf:"varint,3,opt,name=max_concurrent,json=maxConcurrent,proto3" json:"max_concurrent,omitempty"` // Attempts in flight at once
+	BudgetMs      int32                  `protobuf:"varint,4,opt,name=budget_ms,json=budgetMs,proto3" json:"budget_ms,omitempty"`                // Total time; targets not attempted within it are reported as not probed
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetResultsResponse) Reset() {
-	*x = GetResultsResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[19]
+func (x *ProbeConnectivityRequest) Reset() {
+	*x = ProbeConnectivityRequest{}
+	mi := &file_api_proto_daemon_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetResultsResponse) String() string {
+func (x *ProbeConnectivityRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetResultsResponse) ProtoMessage() {}
+func (*ProbeConnectivityRequest) ProtoMessage() {}
 
-func (x *GetResultsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[19]
+func (x *ProbeConnectivityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1539,46 +3653,66 @@ func (x *GetResultsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetResultsResponse.ProtoReflect.Descriptor instead.
-func (*GetResultsResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{19}
+// Deprecated: Use ProbeConnectivityRequest.ProtoReflect.Descriptor instead.
+func (*ProbeConnectivityRequest) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *GetResultsResponse) GetResults() []*TestResult {
+func (x *ProbeConnectivityRequest) GetTargets() []*ProbeTarget {
 	if x != nil {
-		return x.Results
+		return x.Targets
 	}
 	return nil
 }
 
-func (x *GetResultsResponse) GetTotalCount() int32 {
+func (x *ProbeConnectivityRequest) GetTimeoutMs() int32 {
 	if x != nil {
-		return x.TotalCount
+		return x.TimeoutMs
 	}
 	return 0
 }
 
-type GetStatusRequest struct {
+func (x *ProbeConnectivityRequest) GetMaxConcurrent() int32 {
+	if x != nil {
+		return x.MaxConcurrent
+	}
+	return 0
+}
+
+func (x *ProbeConnectivityRequest) GetBudgetMs() int32 {
+	if x != nil {
+		return x.BudgetMs
+	}
+	return 0
+}
+
+type ProbeResult struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
+	Target        *ProbeTarget           `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Reachable     bool                   `protobuf:"varint,2,opt,name=reachable,proto3" json:"reachable,omitempty"`                  // The node answered, by accepting or refusing the connection
+	Refused       bool                   `protobuf:"varint,3,opt,name=refused,proto3" json:"refused,omitempty"`                      // Nothing listens on the port, but the node answered
+	LatencyUs     int64                  `protobuf:"varint,4,opt,name=latency_us,json=latencyUs,proto3" json:"latency_us,omitempty"` // Time to connect or be refused
+	Error         string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`                           // Why the node did not answer
+	Probed        bool                   `protobuf:"varint,6,opt,name=probed,proto3" json:"probed,omitempty"`                        // False when the budget ran out first
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetStatusRequest) Reset() {
-	*x = GetStatusRequest{}
-	mi := &file_api_proto_daemon_proto_msgTypes[20]
+func (x *ProbeResult) Reset() {
+	*x = ProbeResult{}
+	mi := &file_api_proto_daemon_proto_msgTypes[46]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetStatusRequest) String() string {
+func (x *ProbeResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetStatusRequest) ProtoMessage() {}
+func (*ProbeResult) ProtoMessage() {}
 
-func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[20]
+func (x *ProbeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[46]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1589,33 +3723,75 @@ func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
-func (*GetStatusRequest) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{20}
+// Deprecated: Use ProbeResult.ProtoReflect.Descriptor instead.
+func (*ProbeResult) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{46}
 }
 
-type GetStatusResponse struct {
+func (x *ProbeResult) GetTarget() *ProbeTarget {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *ProbeResult) GetReachable() bool {
+	if x != nil {
+		return x.Reachable
+	}
+	return false
+}
+
+func (x *ProbeResult) GetRefused() bool {
+	if x != nil {
+		return x.Refused
+	}
+	return false
+}
+
+func (x *ProbeResult) GetLatencyUs() int64 {
+	if x != nil {
+		return x.LatencyUs
+	}
+	return 0
+}
+
+func (x *ProbeResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ProbeResult) GetProbed() bool {
+	if x != nil {
+		return x.Probed
+	}
+	return false
+}
+
+type ProbeConnectivityResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Status        *DaemonStatus          `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Results       []*ProbeResult         `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *GetStatusResponse) Reset() {
-	*x = GetStatusResponse{}
-	mi := &file_api_proto_daemon_proto_msgTypes[21]
+func (x *ProbeConnectivityResponse) Reset() {
+	*x = ProbeConnectivityResponse{}
+	mi := &file_api_proto_daemon_proto_msgTypes[47]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *GetStatusResponse) String() string {
+func (x *ProbeConnectivityResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*GetStatusResponse) ProtoMessage() {}
+func (*ProbeConnectivityResponse) ProtoMessage() {}
 
-func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_api_proto_daemon_proto_msgTypes[21]
+func (x *ProbeConnectivityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_proto_daemon_proto_msgTypes[47]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1626,14 +3802,14 @@ func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
-func (*GetStatusResponse) Descriptor() ([]byte, []int) {
-	return file_api_proto_daemon_proto_rawDescGZIP(), []int{21}
+// Deprecated: Use ProbeConnectivityResponse.ProtoReflect.Descriptor instead.
+func (*ProbeConnectivityResponse) Descriptor() ([]byte, []int) {
+	return file_api_proto_daemon_proto_rawDescGZIP(), []int{47}
 }
 
-func (x *GetStatusResponse) GetStatus() *DaemonStatus {
+func (x *ProbeConnectivityResponse) GetResults() []*ProbeResult {
 	if x != nil {
-		return x.Status
+		return x.Results
 	}
 	return nil
 }
@@ -1648,13 +3824,24 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\x13available_processes\x18\x02 \x01(\x05R\x12availableProcesses\x12\x1b\n" +
 	"\tcpu_cores\x18\x03 \x01(\x05R\bcpuCores\x124\n" +
 	"\x16available_memory_bytes\x18\x04 \x01(\x03R\x14availableMemoryBytes\x12-\n" +
-	"\x12network_interfaces\x18\x05 \x03(\tR\x11networkInterfaces\"\x98\x01\n" +
+	"\x12network_interfaces\x18\x05 \x03(\tR\x11networkInterfaces\"\x9f\x03\n" +
 	"\bNodeInfo\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
 	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x0e\n" +
 	"\x02ip\x18\x03 \x01(\tR\x02ip\x12\x12\n" +
 	"\x04port\x18\x04 \x01(\x05R\x04port\x12<\n" +
-	"\bcapacity\x18\x05 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\bcapacity\"\xae\x05\n" +
+	"\bcapacity\x18\x05 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\bcapacity\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\tR\aversion\x12\x1c\n" +
+	"\tsimulated\x18\a \x01(\bR\tsimulated\x12'\n" +
+	"\x0fiperf_available\x18\b \x01(\bR\x0eiperfAvailable\x12\x1f\n" +
+	"\viperf_error\x18\t \x01(\tR\n" +
+	"iperfError\x129\n" +
+	"\n" +
+	"port_range\x18\n" +
+	" \x01(\v2\x1a.iperf.daemon.v1.PortRangeR\tportRange\x12\x1f\n" +
+	"\vtotal_ports\x18\v \x01(\x05R\n" +
+	"totalPorts\x12'\n" +
+	"\x0favailable_ports\x18\f \x01(\x05R\x0eavailablePorts\"\xed\x06\n" +
 	"\vTestProfile\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12)\n" +
 	"\x10duration_seconds\x18\x02 \x01(\x05R\x0fdurationSeconds\x125\n" +
@@ -1676,10 +3863,23 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\vjson_output\x18\x10 \x01(\tR\n" +
 	"jsonOutput\x12M\n" +
 	"\vextra_flags\x18\x11 \x03(\v2,.iperf.daemon.v1.TestProfile.ExtraFlagsEntryR\n" +
-	"extraFlags\x1a=\n" +
+	"extraFlags\x12,\n" +
+	"\x12processes_per_pair\x18\x12 \x01(\x05R\x10processesPerPair\x124\n" +
+	"\x05burst\x18\x13 \x01(\v2\x1e.iperf.daemon.v1.BurstScheduleR\x05burst\x12\x1f\n" +
+	"\vduration_ms\x18\x14 \x01(\x05R\n" +
+	"durationMs\x128\n" +
+	"\acapture\x18\x15 \x01(\v2\x1e.iperf.daemon.v1.CapturePolicyR\acapture\x1a=\n" +
 	"\x0fExtraFlagsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd8\x01\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"K\n" +
+	"\rCapturePolicy\x12\x1d\n" +
+	"\n" +
+	"on_failure\x18\x01 \x01(\bR\tonFailure\x12\x1b\n" +
+	"\tbelow_bps\x18\x02 \x01(\x01R\bbelowBps\"S\n" +
+	"\rBurstSchedule\x12\x13\n" +
+	"\x05on_ms\x18\x01 \x01(\x05R\x04onMs\x12\x15\n" +
+	"\x06off_ms\x18\x02 \x01(\x05R\x05offMs\x12\x16\n" +
+	"\x06repeat\x18\x03 \x01(\x05R\x06repeat\"\xd8\x01\n" +
 	"\bTestPair\x12\x1b\n" +
 	"\tsource_id\x18\x01 \x01(\tR\bsourceId\x12%\n" +
 	"\x0edestination_id\x18\x02 \x01(\tR\rdestinationId\x12%\n" +
@@ -1688,7 +3888,7 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\aprofile\x18\x05 \x01(\v2\x1c.iperf.daemon.v1.TestProfileR\aprofile\"\xa2\x01\n" +
 	"\fTestTopology\x12H\n" +
 	"\x12server_assignments\x18\x01 \x03(\v2\x19.iperf.daemon.v1.TestPairR\x11serverAssignments\x12H\n" +
-	"\x12client_assignments\x18\x02 \x03(\v2\x19.iperf.daemon.v1.TestPairR\x11clientAssignments\"\xcb\x02\n" +
+	"\x12client_assignments\x18\x02 \x03(\v2\x19.iperf.daemon.v1.TestPairR\x11clientAssignments\"\xbb\x06\n" +
 	"\n" +
 	"TestResult\x12\x17\n" +
 	"\atest_id\x18\x01 \x01(\tR\x06testId\x12\x1b\n" +
@@ -1700,7 +3900,24 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\rerror_message\x18\x06 \x01(\tR\ferrorMessage\x12&\n" +
 	"\x0fstart_time_unix\x18\a \x01(\x03R\rstartTimeUnix\x12\"\n" +
 	"\rend_time_unix\x18\b \x01(\x03R\vendTimeUnix\x12\x1b\n" +
-	"\texit_code\x18\t \x01(\x05R\bexitCode\"\xaf\x02\n" +
+	"\texit_code\x18\t \x01(\x05R\bexitCode\x12$\n" +
+	"\x0estart_delay_ms\x18\n" +
+	" \x01(\x05R\fstartDelayMs\x12)\n" +
+	"\x10duration_seconds\x18\v \x01(\x05R\x0fdurationSeconds\x12\x1f\n" +
+	"\vduration_ms\x18\f \x01(\x05R\n" +
+	"durationMs\x12/\n" +
+	"\x13destination_address\x18\r \x01(\tR\x12destinationAddress\x12#\n" +
+	"\rlocal_address\x18\x0e \x01(\tR\flocalAddress\x12%\n" +
+	"\x0eremote_address\x18\x0f \x01(\tR\rremoteAddress\x12-\n" +
+	"\x12congestion_control\x18\x10 \x01(\tR\x11congestionControl\x12\x1f\n" +
+	"\vresult_file\x18\x11 \x01(\tR\n" +
+	"resultFile\x12\x1f\n" +
+	"\vresult_host\x18\x12 \x01(\tR\n" +
+	"resultHost\x12+\n" +
+	"\x12start_time_unix_ms\x18\x13 \x01(\x03R\x0fstartTimeUnixMs\x12'\n" +
+	"\x10end_time_unix_ms\x18\x14 \x01(\x03R\rendTimeUnixMs\x12\x15\n" +
+	"\x06run_id\x18\x15 \x01(\tR\x05runId\x12!\n" +
+	"\fcapture_file\x18\x16 \x01(\tR\vcaptureFile\"\x9e\t\n" +
 	"\fDaemonStatus\x12\x18\n" +
 	"\ahealthy\x18\x01 \x01(\bR\ahealthy\x12+\n" +
 	"\x11running_processes\x18\x02 \x01(\x05R\x10runningProcesses\x12'\n" +
@@ -1708,7 +3925,66 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\ffailed_tests\x18\x04 \x01(\x05R\vfailedTests\x12K\n" +
 	"\x10current_capacity\x18\x05 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\x0fcurrentCapacity\x12%\n" +
 	"\x0euptime_seconds\x18\x06 \x01(\x03R\ruptimeSeconds\x12\x18\n" +
-	"\aversion\x18\a \x01(\tR\aversion\"\x8a\x02\n" +
+	"\aversion\x18\a \x01(\tR\aversion\x12M\n" +
+	"\x11port_reservations\x18\b \x03(\v2 .iperf.daemon.v1.PortReservationR\x10portReservations\x12!\n" +
+	"\fserver_ports\x18\t \x03(\x05R\vserverPorts\x12%\n" +
+	"\x0estored_results\x18\n" +
+	" \x01(\x05R\rstoredResults\x12 \n" +
+	"\vmaintenance\x18\v \x01(\bR\vmaintenance\x124\n" +
+	"\x16maintenance_since_unix\x18\f \x01(\x03R\x14maintenanceSinceUnix\x12-\n" +
+	"\x12maintenance_reason\x18\r \x01(\tR\x11maintenanceReason\x12\x1c\n" +
+	"\tsimulated\x18\x0e \x01(\bR\tsimulated\x12<\n" +
+	"\tprocesses\x18\x0f \x03(\v2\x1e.iperf.daemon.v1.ProcessStatusR\tprocesses\x12'\n" +
+	"\x0fiperf_available\x18\x10 \x01(\bR\x0eiperfAvailable\x12\x1f\n" +
+	"\viperf_error\x18\x11 \x01(\tR\n" +
+	"iperfError\x12.\n" +
+	"\x13stored_result_bytes\x18\x12 \x01(\x03R\x11storedResultBytes\x12'\n" +
+	"\x0fresults_evicted\x18\x13 \x01(\x03R\x0eresultsEvicted\x12)\n" +
+	"\x10results_rejected\x18\x14 \x01(\x03R\x0fresultsRejected\x12\x15\n" +
+	"\x06run_id\x18\x15 \x01(\tR\x05runId\x12.\n" +
+	"\x13run_completed_tests\x18\x16 \x01(\x05R\x11runCompletedTests\x12(\n" +
+	"\x10run_failed_tests\x18\x17 \x01(\x05R\x0erunFailedTests\x12-\n" +
+	"\x12stopping_processes\x18\x18 \x01(\x05R\x11stoppingProcesses\x129\n" +
+	"\n" +
+	"port_range\x18\x19 \x01(\v2\x1a.iperf.daemon.v1.PortRangeR\tportRange\x12\x1f\n" +
+	"\vtotal_ports\x18\x1a \x01(\x05R\n" +
+	"totalPorts\x12'\n" +
+	"\x0favailable_ports\x18\x1b \x01(\x05R\x0eavailablePorts\"\xc5\x01\n" +
+	"\rProcessStatus\x12\x17\n" +
+	"\atest_id\x18\x01 \x01(\tR\x06testId\x12\x16\n" +
+	"\x06server\x18\x02 \x01(\bR\x06server\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\x12+\n" +
+	"\x12start_time_unix_ms\x18\x04 \x01(\x03R\x0fstartTimeUnixMs\x12\x10\n" +
+	"\x03pid\x18\x05 \x01(\x05R\x03pid\x12\x1a\n" +
+	"\bstopping\x18\x06 \x01(\bR\bstopping\x12\x14\n" +
+	"\x05owner\x18\a \x01(\tR\x05owner\"3\n" +
+	"\tPortRange\x12\x14\n" +
+	"\x05start\x18\x01 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x02 \x01(\x05R\x03end\"\x82\x01\n" +
+	"\x0fPortReservation\x12\x14\n" +
+	"\x05owner\x18\x01 \x01(\tR\x05owner\x120\n" +
+	"\x05range\x18\x02 \x01(\v2\x1a.iperf.daemon.v1.PortRangeR\x05range\x12'\n" +
+	"\x0frequested_ports\x18\x03 \x01(\x05R\x0erequestedPorts\"\xe9\x02\n" +
+	"\tNodeFacts\x12\x0e\n" +
+	"\x02os\x18\x01 \x01(\tR\x02os\x12\x1a\n" +
+	"\bplatform\x18\x02 \x01(\tR\bplatform\x12%\n" +
+	"\x0ekernel_version\x18\x03 \x01(\tR\rkernelVersion\x12\x12\n" +
+	"\x04arch\x18\x04 \x01(\tR\x04arch\x12#\n" +
+	"\riperf_version\x18\x05 \x01(\tR\fiperfVersion\x12#\n" +
+	"\rdefault_qdisc\x18\x06 \x01(\tR\fdefaultQdisc\x124\n" +
+	"\x16tcp_congestion_control\x18\a \x01(\tR\x14tcpCongestionControl\x12\x19\n" +
+	"\brmem_max\x18\b \x01(\x03R\armemMax\x12\x19\n" +
+	"\bwmem_max\x18\t \x01(\x03R\awmemMax\x12?\n" +
+	"\n" +
+	"interfaces\x18\n" +
+	" \x03(\v2\x1f.iperf.daemon.v1.InterfaceFactsR\n" +
+	"interfaces\"m\n" +
+	"\x0eInterfaceFacts\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03mtu\x18\x02 \x01(\x05R\x03mtu\x12\x1d\n" +
+	"\n" +
+	"speed_mbps\x18\x03 \x01(\x03R\tspeedMbps\x12\x16\n" +
+	"\x06driver\x18\x04 \x01(\tR\x06driver\"\xcf\x02\n" +
 	"\x11InitializeRequest\x12(\n" +
 	"\x10port_range_start\x18\x01 \x01(\x05R\x0eportRangeStart\x12$\n" +
 	"\x0eport_range_end\x18\x02 \x01(\x05R\fportRangeEnd\x12#\n" +
@@ -1717,41 +3993,92 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\tlog_level\x18\x05 \x01(\tR\blogLevel\x12\x1d\n" +
 	"\n" +
 	"result_dir\x18\x06 \x01(\tR\tresultDir\x12!\n" +
-	"\fsave_results\x18\a \x01(\bR\vsaveResults\"\x80\x01\n" +
+	"\fsave_results\x18\a \x01(\bR\vsaveResults\x12,\n" +
+	"\x12sample_interval_ms\x18\b \x01(\x05R\x10sampleIntervalMs\x12\x15\n" +
+	"\x06run_id\x18\t \x01(\tR\x05runId\"\x97\x01\n" +
 	"\x12InitializeResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x126\n" +
-	"\tnode_info\x18\x03 \x01(\v2\x19.iperf.daemon.v1.NodeInfoR\bnodeInfo\"O\n" +
+	"\tnode_info\x18\x03 \x01(\v2\x19.iperf.daemon.v1.NodeInfoR\bnodeInfo\x12\x15\n" +
+	"\x06run_id\x18\x04 \x01(\tR\x05runId\"\x83\x02\n" +
 	"\x12PrepareTestRequest\x129\n" +
-	"\btopology\x18\x01 \x01(\v2\x1d.iperf.daemon.v1.TestTopologyR\btopology\"\xee\x01\n" +
+	"\btopology\x18\x01 \x01(\v2\x1d.iperf.daemon.v1.TestTopologyR\btopology\x129\n" +
+	"\n" +
+	"port_range\x18\x02 \x01(\v2\x1a.iperf.daemon.v1.PortRangeR\tportRange\x12\x14\n" +
+	"\x05owner\x18\x03 \x01(\tR\x05owner\x12:\n" +
+	"\x19congestion_control_policy\x18\x04 \x01(\tR\x17congestionControlPolicy\x12%\n" +
+	"\x0eallocate_ports\x18\x05 \x01(\bR\rallocatePorts\"\xe6\x04\n" +
 	"\x13PrepareTestResponse\x12\x1d\n" +
 	"\n" +
 	"can_handle\x18\x01 \x01(\bR\tcanHandle\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12M\n" +
 	"\x11required_capacity\x18\x03 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\x10requiredCapacity\x12O\n" +
-	"\x12available_capacity\x18\x04 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\x11availableCapacity\"T\n" +
+	"\x12available_capacity\x18\x04 \x01(\v2 .iperf.daemon.v1.ProcessCapacityR\x11availableCapacity\x129\n" +
+	"\n" +
+	"port_range\x18\x05 \x01(\v2\x1a.iperf.daemon.v1.PortRangeR\tportRange\x12\x1f\n" +
+	"\vtotal_ports\x18\x06 \x01(\x05R\n" +
+	"totalPorts\x12'\n" +
+	"\x0favailable_ports\x18\a \x01(\x05R\x0eavailablePorts\x12D\n" +
+	"\x1eunavailable_congestion_control\x18\b \x03(\tR\x1cunavailableCongestionControl\x12@\n" +
+	"\x1cavailable_congestion_control\x18\t \x03(\tR\x1aavailableCongestionControl\x12@\n" +
+	"\tbreakdown\x18\n" +
+	" \x01(\v2\".iperf.daemon.v1.CapacityBreakdownR\tbreakdown\x12'\n" +
+	"\x0fallocated_ports\x18\v \x03(\x05R\x0eallocatedPorts\"\xd2\x03\n" +
+	"\x11CapacityBreakdown\x12\x1b\n" +
+	"\tmax_slots\x18\x01 \x01(\x05R\bmaxSlots\x12\x1d\n" +
+	"\n" +
+	"used_slots\x18\x02 \x01(\x05R\tusedSlots\x12'\n" +
+	"\x0frunning_servers\x18\x03 \x01(\x05R\x0erunningServers\x12'\n" +
+	"\x0frunning_clients\x18\x04 \x01(\x05R\x0erunningClients\x12+\n" +
+	"\x11unaccounted_slots\x18\x05 \x01(\x05R\x10unaccountedSlots\x12-\n" +
+	"\x12stopping_processes\x18\x06 \x01(\x05R\x11stoppingProcesses\x12\x1f\n" +
+	"\vtotal_ports\x18\a \x01(\x05R\n" +
+	"totalPorts\x12!\n" +
+	"\fserver_ports\x18\b \x01(\x05R\vserverPorts\x12'\n" +
+	"\x0fallocated_ports\x18\t \x01(\x05R\x0eallocatedPorts\x12\x1d\n" +
+	"\n" +
+	"free_ports\x18\n" +
+	" \x01(\x05R\tfreePorts\x12%\n" +
+	"\x0ereserved_ports\x18\v \x01(\x05R\rreservedPorts\x12 \n" +
+	"\vdivergences\x18\f \x03(\tR\vdivergences\"\xf4\x01\n" +
 	"\x13StartServersRequest\x12\x14\n" +
 	"\x05ports\x18\x01 \x03(\x05R\x05ports\x12'\n" +
-	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\"\x87\x01\n" +
+	"\x0ftimeout_seconds\x18\x02 \x01(\x05R\x0etimeoutSeconds\x12L\n" +
+	"\bbind_ips\x18\x03 \x03(\v21.iperf.daemon.v1.StartServersRequest.BindIpsEntryR\abindIps\x12\x14\n" +
+	"\x05owner\x18\x04 \x01(\tR\x05owner\x1a:\n" +
+	"\fBindIpsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x05R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x87\x01\n" +
 	"\x14StartServersResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12#\n" +
 	"\rstarted_ports\x18\x03 \x03(\x05R\fstartedPorts\x12\x16\n" +
-	"\x06errors\x18\x04 \x03(\tR\x06errors\"\xb1\x01\n" +
+	"\x06errors\x18\x04 \x03(\tR\x06errors\"\x88\x03\n" +
 	"\fClientTarget\x12\x17\n" +
 	"\atest_id\x18\x01 \x01(\tR\x06testId\x12%\n" +
 	"\x0edestination_ip\x18\x02 \x01(\tR\rdestinationIp\x12)\n" +
 	"\x10destination_port\x18\x03 \x01(\x05R\x0fdestinationPort\x126\n" +
-	"\aprofile\x18\x04 \x01(\v2\x1c.iperf.daemon.v1.TestProfileR\aprofile\"N\n" +
+	"\aprofile\x18\x04 \x01(\v2\x1c.iperf.daemon.v1.TestProfileR\aprofile\x12+\n" +
+	"\x11destination_ports\x18\x05 \x03(\x05R\x10destinationPorts\x12)\n" +
+	"\x10duration_seconds\x18\x06 \x01(\x05R\x0fdurationSeconds\x12$\n" +
+	"\x0estart_delay_ms\x18\a \x01(\x05R\fstartDelayMs\x12\x1f\n" +
+	"\vduration_ms\x18\b \x01(\x05R\n" +
+	"durationMs\x12\x1d\n" +
+	"\n" +
+	"ip_version\x18\t \x01(\x05R\tipVersion\x12\x17\n" +
+	"\abind_ip\x18\n" +
+	" \x01(\tR\x06bindIp\"d\n" +
 	"\x13StartClientsRequest\x127\n" +
-	"\atargets\x18\x01 \x03(\v2\x1d.iperf.daemon.v1.ClientTargetR\atargets\"\x8c\x01\n" +
+	"\atargets\x18\x01 \x03(\v2\x1d.iperf.daemon.v1.ClientTargetR\atargets\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\"\x8c\x01\n" +
 	"\x14StartClientsResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12(\n" +
 	"\x10started_test_ids\x18\x03 \x03(\tR\x0estartedTestIds\x12\x16\n" +
-	"\x06errors\x18\x04 \x03(\tR\x06errors\"&\n" +
+	"\x06errors\x18\x04 \x03(\tR\x06errors\"<\n" +
 	"\x0eStopAllRequest\x12\x14\n" +
-	"\x05force\x18\x01 \x01(\bR\x05force\"r\n" +
+	"\x05force\x18\x01 \x01(\bR\x05force\x12\x14\n" +
+	"\x05owner\x18\x02 \x01(\tR\x05owner\"r\n" +
 	"\x0fStopAllResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12+\n" +
@@ -1762,10 +4089,73 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\x12GetResultsResponse\x125\n" +
 	"\aresults\x18\x01 \x03(\v2\x1b.iperf.daemon.v1.TestResultR\aresults\x12\x1f\n" +
 	"\vtotal_count\x18\x02 \x01(\x05R\n" +
-	"totalCount\"\x12\n" +
-	"\x10GetStatusRequest\"J\n" +
+	"totalCount\"V\n" +
+	"\x10GetStatusRequest\x12+\n" +
+	"\x11include_processes\x18\x01 \x01(\bR\x10includeProcesses\x12\x15\n" +
+	"\x06run_id\x18\x02 \x01(\tR\x05runId\"J\n" +
 	"\x11GetStatusResponse\x125\n" +
-	"\x06status\x18\x01 \x01(\v2\x1d.iperf.daemon.v1.DaemonStatusR\x06status*H\n" +
+	"\x06status\x18\x01 \x01(\v2\x1d.iperf.daemon.v1.DaemonStatusR\x06status\"\x18\n" +
+	"\x16GetCapabilitiesRequest\"O\n" +
+	"\x17GetCapabilitiesResponse\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\tR\aversion\x12\x1a\n" +
+	"\bfeatures\x18\x02 \x03(\tR\bfeatures\"\x14\n" +
+	"\x12GatherFactsRequest\"G\n" +
+	"\x13GatherFactsResponse\x120\n" +
+	"\x05facts\x18\x01 \x01(\v2\x1a.iperf.daemon.v1.NodeFactsR\x05facts\")\n" +
+	"\x13DownloadFileRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\"\x1f\n" +
+	"\tFileChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"\x13\n" +
+	"\x11GetSamplesRequest\"O\n" +
+	"\x12GetSamplesResponse\x129\n" +
+	"\asamples\x18\x01 \x03(\v2\x1f.iperf.daemon.v1.ResourceSampleR\asamples\"\xd8\x01\n" +
+	"\x0eResourceSample\x12 \n" +
+	"\ftime_unix_ms\x18\x01 \x01(\x03R\n" +
+	"timeUnixMs\x12\x1f\n" +
+	"\vcpu_percent\x18\x02 \x01(\x01R\n" +
+	"cpuPercent\x12*\n" +
+	"\x11memory_used_bytes\x18\x03 \x01(\x04R\x0fmemoryUsedBytes\x12%\n" +
+	"\x0ememory_percent\x18\x04 \x01(\x01R\rmemoryPercent\x120\n" +
+	"\x04nics\x18\x05 \x03(\v2\x1c.iperf.daemon.v1.NICCountersR\x04nics\"_\n" +
+	"\vNICCounters\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1d\n" +
+	"\n" +
+	"bytes_sent\x18\x02 \x01(\x04R\tbytesSent\x12\x1d\n" +
+	"\n" +
+	"bytes_recv\x18\x03 \x01(\x04R\tbytesRecv\"-\n" +
+	"\x10StopTestsRequest\x12\x19\n" +
+	"\btest_ids\x18\x01 \x03(\tR\atestIds\"U\n" +
+	"\x11StopTestsResponse\x12(\n" +
+	"\x10stopped_test_ids\x18\x01 \x03(\tR\x0estoppedTestIds\x12\x16\n" +
+	"\x06errors\x18\x02 \x03(\tR\x06errors\"I\n" +
+	"\x15SetMaintenanceRequest\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"~\n" +
+	"\x16SetMaintenanceResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x1d\n" +
+	"\n" +
+	"since_unix\x18\x02 \x01(\x03R\tsinceUnix\x12+\n" +
+	"\x11running_processes\x18\x03 \x01(\x05R\x10runningProcesses\"T\n" +
+	"\vProbeTarget\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x12\n" +
+	"\x04port\x18\x03 \x01(\x05R\x04port\"\xb5\x01\n" +
+	"\x18ProbeConnectivityRequest\x126\n" +
+	"\atargets\x18\x01 \x03(\v2\x1c.iperf.daemon.v1.ProbeTargetR\atargets\x12\x1d\n" +
+	"\n" +
+	"timeout_ms\x18\x02 \x01(\x05R\ttimeoutMs\x12%\n" +
+	"\x0emax_concurrent\x18\x03 \x01(\x05R\rmaxConcurrent\x12\x1b\n" +
+	"\tbudget_ms\x18\x04 \x01(\x05R\bbudgetMs\"\xc8\x01\n" +
+	"\vProbeResult\x124\n" +
+	"\x06target\x18\x01 \x01(\v2\x1c.iperf.daemon.v1.ProbeTargetR\x06target\x12\x1c\n" +
+	"\treachable\x18\x02 \x01(\bR\treachable\x12\x18\n" +
+	"\arefused\x18\x03 \x01(\bR\arefused\x12\x1d\n" +
+	"\n" +
+	"latency_us\x18\x04 \x01(\x03R\tlatencyUs\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12\x16\n" +
+	"\x06probed\x18\x06 \x01(\bR\x06probed\"S\n" +
+	"\x19ProbeConnectivityResponse\x126\n" +
+	"\aresults\x18\x01 \x03(\v2\x1c.iperf.daemon.v1.ProbeResultR\aresults*H\n" +
 	"\bProtocol\x12\x18\n" +
 	"\x14PROTOCOL_UNSPECIFIED\x10\x00\x12\x10\n" +
 	"\fPROTOCOL_TCP\x10\x01\x12\x10\n" +
@@ -1776,7 +4166,8 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\x13TEST_STATUS_PENDING\x10\x01\x12\x17\n" +
 	"\x13TEST_STATUS_RUNNING\x10\x02\x12\x19\n" +
 	"\x15TEST_STATUS_COMPLETED\x10\x03\x12\x16\n" +
-	"\x12TEST_STATUS_FAILED\x10\x042\xf3\x04\n" +
+	"\x12TEST_STATUS_FAILED\x10\x042\x81\n" +
+	"\n" +
 	"\rDaemonService\x12U\n" +
 	"\n" +
 	"Initialize\x12\".iperf.daemon.v1.InitializeRequest\x1a#.iperf.daemon.v1.InitializeResponse\x12X\n" +
@@ -1786,7 +4177,15 @@ const file_api_proto_daemon_proto_rawDesc = "" +
 	"\aStopAll\x12\x1f.iperf.daemon.v1.StopAllRequest\x1a .iperf.daemon.v1.StopAllResponse\x12U\n" +
 	"\n" +
 	"GetResults\x12\".iperf.daemon.v1.GetResultsRequest\x1a#.iperf.daemon.v1.GetResultsResponse\x12R\n" +
-	"\tGetStatus\x12!.iperf.daemon.v1.GetStatusRequest\x1a\".iperf.daemon.v1.GetStatusResponseB;Z9github.com/bensons/iperf-cnc/api/proto/daemon/v1;daemonv1b\x06proto3"
+	"\tGetStatus\x12!.iperf.daemon.v1.GetStatusRequest\x1a\".iperf.daemon.v1.GetStatusResponse\x12d\n" +
+	"\x0fGetCapabilities\x12'.iperf.daemon.v1.GetCapabilitiesRequest\x1a(.iperf.daemon.v1.GetCapabilitiesResponse\x12X\n" +
+	"\vGatherFacts\x12#.iperf.daemon.v1.GatherFactsRequest\x1a$.iperf.daemon.v1.GatherFactsResponse\x12a\n" +
+	"\x0eSetMaintenance\x12&.iperf.daemon.v1.SetMaintenanceRequest\x1a'.iperf.daemon.v1.SetMaintenanceResponse\x12R\n" +
+	"\tStopTests\x12!.iperf.daemon.v1.StopTestsRequest\x1a\".iperf.daemon.v1.StopTestsResponse\x12U\n" +
+	"\n" +
+	"GetSamples\x12\".iperf.daemon.v1.GetSamplesRequest\x1a#.iperf.daemon.v1.GetSamplesResponse\x12R\n" +
+	"\fDownloadFile\x12$.iperf.daemon.v1.DownloadFileRequest\x1a\x1a.iperf.daemon.v1.FileChunk0\x01\x12j\n" +
+	"\x11ProbeConnectivity\x12).iperf.daemon.v1.ProbeConnectivityRequest\x1a*.iperf.daemon.v1.ProbeConnectivityResponseB;Z9github.com/bensons/iperf-cnc/api/proto/daemon/v1;daemonv1b\x06proto3"
 
 var (
 	file_api_proto_daemon_proto_rawDescOnce sync.Once
@@ -1801,70 +4200,129 @@ func file_api_proto_daemon_proto_rawDescGZIP() []byte {
 }
 
 var file_api_proto_daemon_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_api_proto_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_api_proto_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 50)
 var file_api_proto_daemon_proto_goTypes = []any{
-	(Protocol)(0),                // 0: iperf.daemon.v1.Protocol
-	(TestStatus)(0),              // 1: iperf.daemon.v1.TestStatus
-	(*ProcessCapacity)(nil),      // 2: iperf.daemon.v1.ProcessCapacity
-	(*NodeInfo)(nil),             // 3: iperf.daemon.v1.NodeInfo
-	(*TestProfile)(nil),          // 4: iperf.daemon.v1.TestProfile
-	(*TestPair)(nil),             // 5: iperf.daemon.v1.TestPair
-	(*TestTopology)(nil),         // 6: iperf.daemon.v1.TestTopology
-	(*TestResult)(nil),           // 7: iperf.daemon.v1.TestResult
-	(*DaemonStatus)(nil),         // 8: iperf.daemon.v1.DaemonStatus
-	(*InitializeRequest)(nil),    // 9: iperf.daemon.v1.InitializeRequest
-	(*InitializeResponse)(nil),   // 10: iperf.daemon.v1.InitializeResponse
-	(*PrepareTestRequest)(nil),   // 11: iperf.daemon.v1.PrepareTestRequest
-	(*PrepareTestResponse)(nil),  // 12: iperf.daemon.v1.PrepareTestResponse
-	(*StartServersRequest)(nil),  // 13: iperf.daemon.v1.StartServersRequest
-	(*StartServersResponse)(nil), // 14: iperf.daemon.v1.StartServersResponse
-	(*ClientTarget)(nil),         // 15: iperf.daemon.v1.ClientTarget
-	(*StartClientsRequest)(nil),  // 16: iperf.daemon.v1.StartClientsRequest
-	(*StartClientsResponse)(nil), // 17: iperf.daemon.v1.StartClientsResponse
-	(*StopAllRequest)(nil),       // 18: iperf.daemon.v1.StopAllRequest
-	(*StopAllResponse)(nil),      // 19: iperf.daemon.v1.StopAllResponse
-	(*GetResultsRequest)(nil),    // 20: iperf.daemon.v1.GetResultsRequest
-	(*GetResultsResponse)(nil),   // 21: iperf.daemon.v1.GetResultsResponse
-	(*GetStatusRequest)(nil),     // 22: iperf.daemon.v1.GetStatusRequest
-	(*GetStatusResponse)(nil),    // 23: iperf.daemon.v1.GetStatusResponse
-	nil,                          // 24: iperf.daemon.v1.TestProfile.ExtraFlagsEntry
+	(Protocol)(0),                     // 0: iperf.daemon.v1.Protocol
+	(TestStatus)(0),                   // 1: iperf.daemon.v1.TestStatus
+	(*ProcessCapacity)(nil),           // 2: iperf.daemon.v1.ProcessCapacity
+	(*NodeInfo)(nil),                  // 3: iperf.daemon.v1.NodeInfo
+	(*TestProfile)(nil),               // 4: iperf.daemon.v1.TestProfile
+	(*CapturePolicy)(nil),             // 5: iperf.daemon.v1.CapturePolicy
+	(*BurstSchedule)(nil),             // 6: iperf.daemon.v1.BurstSchedule
+	(*TestPair)(nil),                  // 7: iperf.daemon.v1.TestPair
+	(*TestTopology)(nil),              // 8: iperf.daemon.v1.TestTopology
+	(*TestResult)(nil),                // 9: iperf.daemon.v1.TestResult
+	(*DaemonStatus)(nil),              // 10: iperf.daemon.v1.DaemonStatus
+	(*ProcessStatus)(nil),             // 11: iperf.daemon.v1.ProcessStatus
+	(*PortRange)(nil),                 // 12: iperf.daemon.v1.PortRange
+	(*PortReservation)(nil),           // 13: iperf.daemon.v1.PortReservation
+	(*NodeFacts)(nil),                 // 14: iperf.daemon.v1.NodeFacts
+	(*InterfaceFacts)(nil),            // 15: iperf.daemon.v1.InterfaceFacts
+	(*InitializeRequest)(nil),         // 16: iperf.daemon.v1.InitializeRequest
+	(*InitializeResponse)(nil),        // 17: iperf.daemon.v1.InitializeResponse
+	(*PrepareTestRequest)(nil),        // 18: iperf.daemon.v1.PrepareTestRequest
+	(*PrepareTestResponse)(nil),       // 19: iperf.daemon.v1.PrepareTestResponse
+	(*CapacityBreakdown)(nil),         // 20: iperf.daemon.v1.CapacityBreakdown
+	(*StartServersRequest)(nil),       // 21: iperf.daemon.v1.StartServersRequest
+	(*StartServersResponse)(nil),      // 22: iperf.daemon.v1.StartServersResponse
+	(*ClientTarget)(nil),              // 23: iperf.daemon.v1.ClientTarget
+	(*StartClientsRequest)(nil),       // 24: iperf.daemon.v1.StartClientsRequest
+	(*StartClientsResponse)(nil),      // 25: iperf.daemon.v1.StartClientsResponse
+	(*StopAllRequest)(nil),            // 26: iperf.daemon.v1.StopAllRequest
+	(*StopAllResponse)(nil),           // 27: iperf.daemon.v1.StopAllResponse
+	(*GetResultsRequest)(nil),         // 28: iperf.daemon.v1.GetResultsRequest
+	(*GetResultsResponse)(nil),        // 29: iperf.daemon.v1.GetResultsResponse
+	(*GetStatusRequest)(nil),          // 30: iperf.daemon.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),         // 31: iperf.daemon.v1.GetStatusResponse
+	(*GetCapabilitiesRequest)(nil),    // 32: iperf.daemon.v1.GetCapabilitiesRequest
+	(*GetCapabilitiesResponse)(nil),   // 33: iperf.daemon.v1.GetCapabilitiesResponse
+	(*GatherFactsRequest)(nil),        // 34: iperf.daemon.v1.GatherFactsRequest
+	(*GatherFactsResponse)(nil),       // 35: iperf.daemon.v1.GatherFactsResponse
+	(*DownloadFileRequest)(nil),       // 36: iperf.daemon.v1.DownloadFileRequest
+	(*FileChunk)(nil),                 // 37: iperf.daemon.v1.FileChunk
+	(*GetSamplesRequest)(nil),         // 38: iperf.daemon.v1.GetSamplesRequest
+	(*GetSamplesResponse)(nil),        // 39: iperf.daemon.v1.GetSamplesResponse
+	(*ResourceSample)(nil),            // 40: iperf.daemon.v1.ResourceSample
+	(*NICCounters)(nil),               // 41: iperf.daemon.v1.NICCounters
+	(*StopTestsRequest)(nil),          // 42: iperf.daemon.v1.StopTestsRequest
+	(*StopTestsResponse)(nil),         // 43: iperf.daemon.v1.StopTestsResponse
+	(*SetMaintenanceRequest)(nil),     // 44: iperf.daemon.v1.SetMaintenanceRequest
+	(*SetMaintenanceResponse)(nil),    // 45: iperf.daemon.v1.SetMaintenanceResponse
+	(*ProbeTarget)(nil),               // 46: iperf.daemon.v1.ProbeTarget
+	(*ProbeConnectivityRequest)(nil),  // 47: iperf.daemon.v1.ProbeConnectivityRequest
+	(*ProbeResult)(nil),               // 48: iperf.daemon.v1.ProbeResult
+	(*ProbeConnectivityResponse)(nil), // 49: iperf.daemon.v1.ProbeConnectivityResponse
+	nil,                               // 50: iperf.daemon.v1.TestProfile.ExtraFlagsEntry
+	nil,                               // 51: iperf.daemon.v1.StartServersRequest.BindIpsEntry
 }
 var file_api_proto_daemon_proto_depIdxs = []int32{
 	2,  // 0: iperf.daemon.v1.NodeInfo.capacity:type_name -> iperf.daemon.v1.ProcessCapacity
-	0,  // 1: iperf.daemon.v1.TestProfile.protocol:type_name -> iperf.daemon.v1.Protocol
-	24, // 2: iperf.daemon.v1.TestProfile.extra_flags:type_name -> iperf.daemon.v1.TestProfile.ExtraFlagsEntry
-	4,  // 3: iperf.daemon.v1.TestPair.profile:type_name -> iperf.daemon.v1.TestProfile
-	5,  // 4: iperf.daemon.v1.TestTopology.server_assignments:type_name -> iperf.daemon.v1.TestPair
-	5,  // 5: iperf.daemon.v1.TestTopology.client_assignments:type_name -> iperf.daemon.v1.TestPair
-	1,  // 6: iperf.daemon.v1.TestResult.status:type_name -> iperf.daemon.v1.TestStatus
-	2,  // 7: iperf.daemon.v1.DaemonStatus.current_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
-	3,  // 8: iperf.daemon.v1.InitializeResponse.node_info:type_name -> iperf.daemon.v1.NodeInfo
-	6,  // 9: iperf.daemon.v1.PrepareTestRequest.topology:type_name -> iperf.daemon.v1.TestTopology
-	2,  // 10: iperf.daemon.v1.PrepareTestResponse.required_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
-	2,  // 11: iperf.daemon.v1.PrepareTestResponse.available_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
-	4,  // 12: iperf.daemon.v1.ClientTarget.profile:type_name -> iperf.daemon.v1.TestProfile
-	15, // 13: iperf.daemon.v1.StartClientsRequest.targets:type_name -> iperf.daemon.v1.ClientTarget
-	7,  // 14: iperf.daemon.v1.GetResultsResponse.results:type_name -> iperf.daemon.v1.TestResult
-	8,  // 15: iperf.daemon.v1.GetStatusResponse.status:type_name -> iperf.daemon.v1.DaemonStatus
-	9,  // 16: iperf.daemon.v1.DaemonService.Initialize:input_type -> iperf.daemon.v1.InitializeRequest
-	11, // 17: iperf.daemon.v1.DaemonService.PrepareTest:input_type -> iperf.daemon.v1.PrepareTestRequest
-	13, // 18: iperf.daemon.v1.DaemonService.StartServers:input_type -> iperf.daemon.v1.StartServersRequest
-	16, // 19: iperf.daemon.v1.DaemonService.StartClients:input_type -> iperf.daemon.v1.StartClientsRequest
-	18, // 20: iperf.daemon.v1.DaemonService.StopAll:input_type -> iperf.daemon.v1.StopAllRequest
-	20, // 21: iperf.daemon.v1.DaemonService.GetResults:input_type -> iperf.daemon.v1.GetResultsRequest
-	22, // 22: iperf.daemon.v1.DaemonService.GetStatus:input_type -> iperf.daemon.v1.GetStatusRequest
-	10, // 23: iperf.daemon.v1.DaemonService.Initialize:output_type -> iperf.daemon.v1.InitializeResponse
-	12, // 24: iperf.daemon.v1.DaemonService.PrepareTest:output_type -> iperf.daemon.v1.PrepareTestResponse
-	14, // 25: iperf.daemon.v1.DaemonService.StartServers:output_type -> iperf.daemon.v1.StartServersResponse
-	17, // 26: iperf.daemon.v1.DaemonService.StartClients:output_type -> iperf.daemon.v1.StartClientsResponse
-	19, // 27: iperf.daemon.v1.DaemonService.StopAll:output_type -> iperf.daemon.v1.StopAllResponse
-	21, // 28: iperf.daemon.v1.DaemonService.GetResults:output_type -> iperf.daemon.v1.GetResultsResponse
-	23, // 29: iperf.daemon.v1.DaemonService.GetStatus:output_type -> iperf.daemon.v1.GetStatusResponse
-	23, // [23:30] is the sub-list for method output_type
-	16, // [16:23] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	12, // 1: iperf.daemon.v1.NodeInfo.port_range:type_name -> iperf.daemon.v1.PortRange
+	0,  // 2: iperf.daemon.v1.TestProfile.protocol:type_name -> iperf.daemon.v1.Protocol
+	50, // 3: iperf.daemon.v1.TestProfile.extra_flags:type_name -> iperf.daemon.v1.TestProfile.ExtraFlagsEntry
+	6,  // 4: iperf.daemon.v1.TestProfile.burst:type_name -> iperf.daemon.v1.BurstSchedule
+	5,  // 5: iperf.daemon.v1.TestProfile.capture:type_name -> iperf.daemon.v1.CapturePolicy
+	4,  // 6: iperf.daemon.v1.TestPair.profile:type_name -> iperf.daemon.v1.TestProfile
+	7,  // 7: iperf.daemon.v1.TestTopology.server_assignments:type_name -> iperf.daemon.v1.TestPair
+	7,  // 8: iperf.daemon.v1.TestTopology.client_assignments:type_name -> iperf.daemon.v1.TestPair
+	1,  // 9: iperf.daemon.v1.TestResult.status:type_name -> iperf.daemon.v1.TestStatus
+	2,  // 10: iperf.daemon.v1.DaemonStatus.current_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
+	13, // 11: iperf.daemon.v1.DaemonStatus.port_reservations:type_name -> iperf.daemon.v1.PortReservation
+	11, // 12: iperf.daemon.v1.DaemonStatus.processes:type_name -> iperf.daemon.v1.ProcessStatus
+	12, // 13: iperf.daemon.v1.DaemonStatus.port_range:type_name -> iperf.daemon.v1.PortRange
+	12, // 14: iperf.daemon.v1.PortReservation.range:type_name -> iperf.daemon.v1.PortRange
+	15, // 15: iperf.daemon.v1.NodeFacts.interfaces:type_name -> iperf.daemon.v1.InterfaceFacts
+	3,  // 16: iperf.daemon.v1.InitializeResponse.node_info:type_name -> iperf.daemon.v1.NodeInfo
+	8,  // 17: iperf.daemon.v1.PrepareTestRequest.topology:type_name -> iperf.daemon.v1.TestTopology
+	12, // 18: iperf.daemon.v1.PrepareTestRequest.port_range:type_name -> iperf.daemon.v1.PortRange
+	2,  // 19: iperf.daemon.v1.PrepareTestResponse.required_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
+	2,  // 20: iperf.daemon.v1.PrepareTestResponse.available_capacity:type_name -> iperf.daemon.v1.ProcessCapacity
+	12, // 21: iperf.daemon.v1.PrepareTestResponse.port_range:type_name -> iperf.daemon.v1.PortRange
+	20, // 22: iperf.daemon.v1.PrepareTestResponse.breakdown:type_name -> iperf.daemon.v1.CapacityBreakdown
+	51, // 23: iperf.daemon.v1.StartServersRequest.bind_ips:type_name -> iperf.daemon.v1.StartServersRequest.BindIpsEntry
+	4,  // 24: iperf.daemon.v1.ClientTarget.profile:type_name -> iperf.daemon.v1.TestProfile
+	23, // 25: iperf.daemon.v1.StartClientsRequest.targets:type_name -> iperf.daemon.v1.ClientTarget
+	9,  // 26: iperf.daemon.v1.GetResultsResponse.results:type_name -> iperf.daemon.v1.TestResult
+	10, // 27: iperf.daemon.v1.GetStatusResponse.status:type_name -> iperf.daemon.v1.DaemonStatus
+	14, // 28: iperf.daemon.v1.GatherFactsResponse.facts:type_name -> iperf.daemon.v1.NodeFacts
+	40, // 29: iperf.daemon.v1.GetSamplesResponse.samples:type_name -> iperf.daemon.v1.ResourceSample
+	41, // 30: iperf.daemon.v1.ResourceSample.nics:type_name -> iperf.daemon.v1.NICCounters
+	46, // 31: iperf.daemon.v1.ProbeConnectivityRequest.targets:type_name -> iperf.daemon.v1.ProbeTarget
+	46, // 32: iperf.daemon.v1.ProbeResult.target:type_name -> iperf.daemon.v1.ProbeTarget
+	48, // 33: iperf.daemon.v1.ProbeConnectivityResponse.results:type_name -> iperf.daemon.v1.ProbeResult
+	16, // 34: iperf.daemon.v1.DaemonService.Initialize:input_type -> iperf.daemon.v1.InitializeRequest
+	18, // 35: iperf.daemon.v1.DaemonService.PrepareTest:input_type -> iperf.daemon.v1.PrepareTestRequest
+	21, // 36: iperf.daemon.v1.DaemonService.StartServers:input_type -> iperf.daemon.v1.StartServersRequest
+	24, // 37: iperf.daemon.v1.DaemonService.StartClients:input_type -> iperf.daemon.v1.StartClientsRequest
+	26, // 38: iperf.daemon.v1.DaemonService.StopAll:input_type -> iperf.daemon.v1.StopAllRequest
+	28, // 39: iperf.daemon.v1.DaemonService.GetResults:input_type -> iperf.daemon.v1.GetResultsRequest
+	30, // 40: iperf.daemon.v1.DaemonService.GetStatus:input_type -> iperf.daemon.v1.GetStatusRequest
+	32, // 41: iperf.daemon.v1.DaemonService.GetCapabilities:input_type -> iperf.daemon.v1.GetCapabilitiesRequest
+	34, // 42: iperf.daemon.v1.DaemonService.GatherFacts:input_type -> iperf.daemon.v1.GatherFactsRequest
+	44, // 43: iperf.daemon.v1.DaemonService.SetMaintenance:input_type -> iperf.daemon.v1.SetMaintenanceRequest
+	42, // 44: iperf.daemon.v1.DaemonService.StopTests:input_type -> iperf.daemon.v1.StopTestsRequest
+	38, // 45: iperf.daemon.v1.DaemonService.GetSamples:input_type -> iperf.daemon.v1.GetSamplesRequest
+	36, // 46: iperf.daemon.v1.DaemonService.DownloadFile:input_type -> iperf.daemon.v1.DownloadFileRequest
+	47, // 47: iperf.daemon.v1.DaemonService.ProbeConnectivity:input_type -> iperf.daemon.v1.ProbeConnectivityRequest
+	17, // 48: iperf.daemon.v1.DaemonService.Initialize:output_type -> iperf.daemon.v1.InitializeResponse
+	19, // 49: iperf.daemon.v1.DaemonService.PrepareTest:output_type -> iperf.daemon.v1.PrepareTestResponse
+	22, // 50: iperf.daemon.v1.DaemonService.StartServers:output_type -> iperf.daemon.v1.StartServersResponse
+	25, // 51: iperf.daemon.v1.DaemonService.StartClients:output_type -> iperf.daemon.v1.StartClientsResponse
+	27, // 52: iperf.daemon.v1.DaemonService.StopAll:output_type -> iperf.daemon.v1.StopAllResponse
+	29, // 53: iperf.daemon.v1.DaemonService.GetResults:output_type -> iperf.daemon.v1.GetResultsResponse
+	31, // 54: iperf.daemon.v1.DaemonService.GetStatus:output_type -> iperf.daemon.v1.GetStatusResponse
+	33, // 55: iperf.daemon.v1.DaemonService.GetCapabilities:output_type -> iperf.daemon.v1.GetCapabilitiesResponse
+	35, // 56: iperf.daemon.v1.DaemonService.GatherFacts:output_type -> iperf.daemon.v1.GatherFactsResponse
+	45, // 57: iperf.daemon.v1.DaemonService.SetMaintenance:output_type -> iperf.daemon.v1.SetMaintenanceResponse
+	43, // 58: iperf.daemon.v1.DaemonService.StopTests:output_type -> iperf.daemon.v1.StopTestsResponse
+	39, // 59: iperf.daemon.v1.DaemonService.GetSamples:output_type -> iperf.daemon.v1.GetSamplesResponse
+	37, // 60: iperf.daemon.v1.DaemonService.DownloadFile:output_type -> iperf.daemon.v1.FileChunk
+	49, // 61: iperf.daemon.v1.DaemonService.ProbeConnectivity:output_type -> iperf.daemon.v1.ProbeConnectivityResponse
+	48, // [48:62] is the sub-list for method output_type
+	34, // [34:48] is the sub-list for method input_type
+	34, // [34:34] is the sub-list for extension type_name
+	34, // [34:34] is the sub-list for extension extendee
+	0,  // [0:34] is the sub-list for field type_name
 }
 
 func init() { file_api_proto_daemon_proto_init() }
@@ -1878,7 +4336,7 @@ func file_api_proto_daemon_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_proto_daemon_proto_rawDesc), len(file_api_proto_daemon_proto_rawDesc)),
 			NumEnums:      2,
-			NumMessages:   23,
+			NumMessages:   50,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,13 +19,20 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	DaemonService_Initialize_FullMethodName   = "/iperf.daemon.v1.DaemonService/Initialize"
-	DaemonService_PrepareTest_FullMethodName  = "/iperf.daemon.v1.DaemonService/PrepareTest"
-	DaemonService_StartServers_FullMethodName = "/iperf.daemon.v1.DaemonService/StartServers"
-	DaemonService_StartClients_FullMethodName = "/iperf.daemon.v1.DaemonService/StartClients"
-	DaemonService_StopAll_FullMethodName      = "/iperf.daemon.v1.DaemonService/StopAll"
-	DaemonService_GetResults_FullMethodName   = "/iperf.daemon.v1.DaemonService/GetResults"
-	DaemonService_GetStatus_FullMethodName    = "/iperf.daemon.v1.DaemonService/GetStatus"
+	DaemonService_Initialize_FullMethodName        = "/iperf.daemon.v1.DaemonService/Initialize"
+	DaemonService_PrepareTest_FullMethodName       = "/iperf.daemon.v1.DaemonService/PrepareTest"
+	DaemonService_StartServers_FullMethodName      = "/iperf.daemon.v1.DaemonService/StartServers"
+	DaemonService_StartClients_FullMethodName      = "/iperf.daemon.v1.DaemonService/StartClients"
+	DaemonService_StopAll_FullMethodName           = "/iperf.daemon.v1.DaemonService/StopAll"
+	DaemonService_GetResults_FullMethodName        = "/iperf.daemon.v1.DaemonService/GetResults"
+	DaemonService_GetStatus_FullMethodName         = "/iperf.daemon.v1.DaemonService/GetStatus"
+	DaemonService_GetCapabilities_FullMethodName   = "/iperf.daemon.v1.DaemonService/GetCapabilities"
+	DaemonService_GatherFacts_FullMethodName       = "/iperf.daemon.v1.DaemonService/GatherFacts"
+	DaemonService_SetMaintenance_FullMethodName    = "/iperf.daemon.v1.DaemonService/SetMaintenance"
+	DaemonService_StopTests_FullMethodName         = "/iperf.daemon.v1.DaemonService/StopTests"
+	DaemonService_GetSamples_FullMethodName        = "/iperf.daemon.v1.DaemonService/GetSamples"
+	DaemonService_DownloadFile_FullMethodName      = "/iperf.daemon.v1.DaemonService/DownloadFile"
+	DaemonService_ProbeConnectivity_FullMethodName = "/iperf.daemon.v1.DaemonService/ProbeConnectivity"
 )
 
 // DaemonServiceClient is the client API for DaemonService service.
@@ -48,6 +55,25 @@ type DaemonServiceClient interface {
 	GetResults(ctx context.Context, in *GetResultsRequest, opts ...grpc.CallOption) (*GetResultsResponse, error)
 	// GetStatus returns current daemon health and resource usage
 	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// GetCapabilities returns the daemon version and the optional features it supports
+	GetCapabilities(ctx context.Context, in *GetCapabilitiesRequest, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error)
+	// GatherFacts returns read-only facts about the host environment
+	GatherFacts(ctx context.Context, in *GatherFactsRequest, opts ...grpc.CallOption) (*GatherFactsResponse, error)
+	// SetMaintenance stops the daemon accepting new tests, or lets it accept
+	// them again; running tests are not affected
+	SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*SetMaintenanceResponse, error)
+	// StopTests stops the client tests with the given IDs, leaving other
+	// processes running; each stopped test is stored as a failed result
+	StopTests(ctx context.Context, in *StopTestsRequest, opts ...grpc.CallOption) (*StopTestsResponse, error)
+	// GetSamples returns the resource usage samples recorded since sampling
+	// was last started by Initialize
+	GetSamples(ctx context.Context, in *GetSamplesRequest, opts ...grpc.CallOption) (*GetSamplesResponse, error)
+	// DownloadFile streams a result file the daemon saved, as named by
+	// TestResult.result_file; only files under the result directory are served
+	DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error)
+	// ProbeConnectivity attempts a TCP connection from this node to each
+	// target, such as the data port of another node, without sending data
+	ProbeConnectivity(ctx context.Context, in *ProbeConnectivityRequest, opts ...grpc.CallOption) (*ProbeConnectivityResponse, error)
 }
 
 type daemonServiceClient struct {
@@ -128,6 +154,85 @@ func (c *daemonServiceClient) GetStatus(ctx context.Context, in *GetStatusReques
 	return out, nil
 }
 
+func (c *daemonServiceClient) GetCapabilities(ctx context.Context, in *GetCapabilitiesRequest, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, DaemonService_GetCapabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) GatherFacts(ctx context.Context, in *GatherFactsRequest, opts ...grpc.CallOption) (*GatherFactsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GatherFactsResponse)
+	err := c.cc.Invoke(ctx, DaemonService_GatherFacts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) SetMaintenance(ctx context.Context, in *SetMaintenanceRequest, opts ...grpc.CallOption) (*SetMaintenanceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetMaintenanceResponse)
+	err := c.cc.Invoke(ctx, DaemonService_SetMaintenance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) StopTests(ctx context.Context, in *StopTestsRequest, opts ...grpc.CallOption) (*StopTestsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopTestsResponse)
+	err := c.cc.Invoke(ctx, DaemonService_StopTests_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) GetSamples(ctx context.Context, in *GetSamplesRequest, opts ...grpc.CallOption) (*GetSamplesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetSamplesResponse)
+	err := c.cc.Invoke(ctx, DaemonService_GetSamples_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *daemonServiceClient) DownloadFile(ctx context.Context, in *DownloadFileRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[FileChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &DaemonService_ServiceDesc.Streams[0], DaemonService_DownloadFile_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DownloadFileRequest, FileChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DaemonService_DownloadFileClient = grpc.ServerStreamingClient[FileChunk]
+
+func (c *daemonServiceClient) ProbeConnectivity(ctx context.Context, in *ProbeConnectivityRequest, opts ...grpc.CallOption) (*ProbeConnectivityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ProbeConnectivityResponse)
+	err := c.cc.Invoke(ctx, DaemonService_ProbeConnectivity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // DaemonServiceServer is the server API for DaemonService service.
 // All implementations must embed UnimplementedDaemonServiceServer
 // for forward compatibility.
@@ -148,6 +253,25 @@ type DaemonServiceServer interface {
 	GetResults(context.Context, *GetResultsRequest) (*GetResultsResponse, error)
 	// GetStatus returns current daemon health and resource usage
 	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// GetCapabilities returns the daemon version and the optional features it supports
+	GetCapabilities(context.Context, *GetCapabilitiesRequest) (*GetCapabilitiesResponse, error)
+	// GatherFacts returns read-only facts about the host environment
+	GatherFacts(context.Context, *GatherFactsRequest) (*GatherFactsResponse, error)
+	// SetMaintenance stops the daemon accepting new tests, or lets it accept
+	// them again; running tests are not affected
+	SetMaintenance(context.Context, *SetMaintenanceRequest) (*SetMaintenanceResponse, error)
+	// StopTests stops the client tests with the given IDs, leaving other
+	// processes running; each stopped test is stored as a failed result
+	StopTests(context.Context, *StopTestsRequest) (*StopTestsResponse, error)
+	// GetSamples returns the resource usage samples recorded since sampling
+	// was last started by Initialize
+	GetSamples(context.Context, *GetSamplesRequest) (*GetSamplesResponse, error)
+	// DownloadFile streams a result file the daemon saved, as named by
+	// TestResult.result_file; only files under the result directory are served
+	DownloadFile(*DownloadFileRequest, grpc.ServerStreamingServer[FileChunk]) error
+	// ProbeConnectivity attempts a TCP connection from this node to each
+	// target, such as the data port of another node, without sending data
+	ProbeConnectivity(context.Context, *ProbeConnectivityRequest) (*ProbeConnectivityResponse, error)
 	mustEmbedUnimplementedDaemonServiceServer()
 }
 
@@ -179,6 +303,27 @@ func (UnimplementedDaemonServiceServer) GetResults(context.Context, *GetResultsR
 func (UnimplementedDaemonServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
 }
+func (UnimplementedDaemonServiceServer) GetCapabilities(context.Context, *GetCapabilitiesRequest) (*GetCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
+func (UnimplementedDaemonServiceServer) GatherFacts(context.Context, *GatherFactsRequest) (*GatherFactsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GatherFacts not implemented")
+}
+func (UnimplementedDaemonServiceServer) SetMaintenance(context.Context, *SetMaintenanceRequest) (*SetMaintenanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMaintenance not implemented")
+}
+func (UnimplementedDaemonServiceServer) StopTests(context.Context, *StopTestsRequest) (*StopTestsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StopTests not implemented")
+}
+func (UnimplementedDaemonServiceServer) GetSamples(context.Context, *GetSamplesRequest) (*GetSamplesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSamples not implemented")
+}
+func (UnimplementedDaemonServiceServer) DownloadFile(*DownloadFileRequest, grpc.ServerStreamingServer[FileChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadFile not implemented")
+}
+func (UnimplementedDaemonServiceServer) ProbeConnectivity(context.Context, *ProbeConnectivityRequest) (*ProbeConnectivityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProbeConnectivity not implemented")
+}
 func (UnimplementedDaemonServiceServer) mustEmbedUnimplementedDaemonServiceServer() {}
 func (UnimplementedDaemonServiceServer) testEmbeddedByValue()                       {}
 
@@ -326,6 +471,125 @@ func _DaemonService_GetStatus_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _DaemonService_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetCapabilities(ctx, req.(*GetCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_GatherFacts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GatherFactsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GatherFacts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_GatherFacts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GatherFacts(ctx, req.(*GatherFactsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_SetMaintenance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetMaintenanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).SetMaintenance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_SetMaintenance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).SetMaintenance(ctx, req.(*SetMaintenanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_StopTests_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopTestsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).StopTests(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_StopTests_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).StopTests(ctx, req.(*StopTestsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_GetSamples_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSamplesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).GetSamples(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_GetSamples_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).GetSamples(ctx, req.(*GetSamplesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DaemonService_DownloadFile_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadFileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DaemonServiceServer).DownloadFile(m, &grpc.GenericServerStream[DownloadFileRequest, FileChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type DaemonService_DownloadFileServer = grpc.ServerStreamingServer[FileChunk]
+
+func _DaemonService_ProbeConnectivity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeConnectivityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DaemonServiceServer).ProbeConnectivity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DaemonService_ProbeConnectivity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DaemonServiceServer).ProbeConnectivity(ctx, req.(*ProbeConnectivityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // DaemonService_ServiceDesc is the grpc.ServiceDesc for DaemonService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -361,7 +625,37 @@ var DaemonService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetStatus",
 			Handler:    _DaemonService_GetStatus_Handler,
 		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _DaemonService_GetCapabilities_Handler,
+		},
+		{
+			MethodName: "GatherFacts",
+			Handler:    _DaemonService_GatherFacts_Handler,
+		},
+		{
+			MethodName: "SetMaintenance",
+			Handler:    _DaemonService_SetMaintenance_Handler,
+		},
+		{
+			MethodName: "StopTests",
+			Handler:    _DaemonService_StopTests_Handler,
+		},
+		{
+			MethodName: "GetSamples",
+			Handler:    _DaemonService_GetSamples_Handler,
+		},
+		{
+			MethodName: "ProbeConnectivity",
+			Handler:    _DaemonService_ProbeConnectivity_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DownloadFile",
+			Handler:       _DaemonService_DownloadFile_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "api/proto/daemon.proto",
 }
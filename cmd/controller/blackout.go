@@ -0,0 +1,82 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// maxListedBlackoutPairs is the number of pairs held back by blackout
+// windows that planning lists
+const maxListedBlackoutPairs = 20
+
+// maxBlackoutWait returns how long tests wait for blackout windows of their
+// nodes; a negative setting doesn't wait
+func maxBlackoutWait(cfg *config.ControllerConfig) time.Duration {
+	return time.Duration(max(cfg.Controller.Concurrency.MaxBlackoutWaitSeconds, 0)) * time.Second
+}
+
+// configureBlackouts applies the blackout wait to orch
+func configureBlackouts(orch *orchestrator.Orchestrator, cfg *config.ControllerConfig) {
+	orch.SetMaxBlackoutWait(maxBlackoutWait(cfg))
+}
+
+// logBlackouts logs the pairs that blackout windows of their nodes would
+// hold back or skip if the run started now
+func logBlackouts(cfg *config.ControllerConfig, topo *topology.Topology) {
+	impact := topo.BlackoutImpact(time.Now(), maxBlackoutWait(cfg))
+	if len(impact) == 0 {
+		return
+	}
+
+	skipped := 0
+	for _, pair := range impact {
+		if pair.Skipped {
+			skipped++
+		}
+	}
+	log.Printf("  Blackout windows: %d pairs deferred and %d skipped if started now", len(impact)-skipped, skipped)
+	for i, pair := range impact {
+		if i == maxListedBlackoutPairs {
+			log.Printf("    ... and %d more", len(impact)-i)
+			break
+		}
+		action := "deferred"
+		if pair.Skipped {
+			action = "skipped, would wait"
+		}
+		log.Printf("    %s -> %s: %s %v (blackout of %s)", pair.Pair.Source.ID, pair.Pair.Destination.ID,
+			action, pair.Delay.Round(time.Second), strings.Join(pair.Nodes, ", "))
+	}
+}
+
+// blackoutDeferrals returns the blackout deferral of each test of topo that
+// started later than scheduled for blackout windows
+func blackoutDeferrals(topo *topology.Topology) map[string]*aggregator.BlackoutDeferral {
+	deferrals := make(map[string]*aggregator.BlackoutDeferral)
+	for _, pair := range topo.Pairs {
+		if pair.BlackoutDelay > 0 {
+			deferrals[pair.TestID] = &aggregator.BlackoutDeferral{
+				DelaySeconds: pair.BlackoutDelay.Seconds(),
+				Nodes:        pair.BlackoutNodes,
+			}
+		}
+	}
+	return deferrals
+}
+
+// addBlackoutSkips adds a result for each test orch skipped for blackout
+// windows
+func addBlackoutSkips(agg *aggregator.Aggregator, orch *orchestrator.Orchestrator) error {
+	for _, skip := range orch.BlackoutSkipped() {
+		if err := agg.AddBlackoutSkip(skip.Pair.TestID, skip.Wait, skip.Nodes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
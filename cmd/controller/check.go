@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// checkState is a Nagios plugin state, used as the process exit code
+type checkState int
+
+const (
+	checkOK       checkState = 0
+	checkWarning  checkState = 1
+	checkCritical checkState = 2
+	checkUnknown  checkState = 3
+)
+
+func (s checkState) String() string {
+	switch s {
+	case checkOK:
+		return "OK"
+	case checkWarning:
+		return "WARNING"
+	case checkCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// checkOptions holds the flags of the check command
+type checkOptions struct {
+	configPath string
+	from       string
+	to         string
+	profile    string
+	warn       string
+	crit       string
+	duration   float64
+	timeout    time.Duration
+	jsonFile   string
+	verbose    bool
+}
+
+func newCheckCommand() *cobra.Command {
+	var opts checkOptions
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Run a single-pair test and report it as a Nagios/Icinga check",
+		Long: `check runs one short test between two nodes and prints a single status
+line with perfdata. The exit code is 0 (OK), 1 (WARNING), 2 (CRITICAL) or
+3 (UNKNOWN) depending on the measured throughput and the thresholds.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			state, message := runCheck(opts)
+			fmt.Printf("IPERF %s - %s\n", state, message)
+			os.Exit(int(state))
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringVar(&opts.from, "from", "", "source (client) node ID")
+	cmd.Flags().StringVar(&opts.to, "to", "", "destination (server) node ID")
+	cmd.Flags().StringVar(&opts.profile, "profile", "",
+		"test profile to use (defaults to the topology default profile)")
+	cmd.Flags().StringVar(&opts.warn, "warn", "", "warning when throughput is below this rate (e.g. 8G)")
+	cmd.Flags().StringVar(&opts.crit, "crit", "", "critical when throughput is below this rate (e.g. 5G)")
+	cmd.Flags().Float64Var(&opts.duration, "duration", 5, "test duration in seconds, fractions allowed")
+	cmd.Flags().DurationVar(&opts.timeout, "timeout", time.Minute,
+		"hard deadline for the whole check; the test is stopped and cleaned up when exceeded")
+	cmd.Flags().StringVar(&opts.jsonFile, "json-file", "", "also write the result to this JSON file")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false, "log progress to stderr")
+	for _, name := range []string{"from", "to", "warn", "crit"} {
+		if err := cmd.MarkFlagRequired(name); err != nil {
+			panic(err) // This should never happen during initialization
+		}
+	}
+
+	return cmd
+}
+
+// runCheck runs the check and returns its state and status line
+func runCheck(opts checkOptions) (checkState, string) {
+	if !opts.verbose {
+		log.SetOutput(io.Discard)
+		defer log.SetOutput(os.Stderr)
+	}
+
+	warn, crit, err := parseThresholds(opts.warn, opts.crit)
+	if err != nil {
+		return checkUnknown, err.Error()
+	}
+	if units.Seconds(opts.duration) < models.MinDuration {
+		return checkUnknown, fmt.Sprintf("--duration must be at least %s seconds", units.FormatSeconds(models.MinDuration))
+	}
+
+	cfg, err := config.LoadControllerConfig(opts.configPath)
+	if err != nil {
+		return checkUnknown, fmt.Sprintf("failed to load config: %v", err)
+	}
+	cfg.SetDefaults()
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	result, err := runCheckPair(ctx, cfg, opts)
+	if err != nil {
+		if deadlineExceeded(ctx, err) {
+			return checkUnknown, fmt.Sprintf("%s -> %s: deadline of %s exceeded", opts.from, opts.to, opts.timeout)
+		}
+		return checkUnknown, fmt.Sprintf("%s -> %s: %v", opts.from, opts.to, err)
+	}
+	if result.Status != "TEST_STATUS_COMPLETED" {
+		return checkCritical, fmt.Sprintf("%s -> %s: test failed: %s", opts.from, opts.to, result.ErrorMessage)
+	}
+	if cfg.Controller.Analysis.Confidence.IgnoreLow && result.Confidence == aggregator.ConfidenceLow {
+		return checkUnknown, fmt.Sprintf("%s -> %s throughput %s is low confidence (%s) | %s", opts.from, opts.to,
+			units.FormatBitRate(result.ThroughputBps, 2), strings.Join(result.ConfidenceReasons, "; "),
+			perfdata(result.ThroughputBps, warn, crit))
+	}
+
+	return throughputState(result.ThroughputBps, warn, crit), fmt.Sprintf("%s -> %s throughput %s | %s", opts.from, opts.to,
+		units.FormatBitRate(result.ThroughputBps, 2), perfdata(result.ThroughputBps, warn, crit))
+}
+
+// deadlineExceeded reports whether err was caused by the check's deadline:
+// either ctx expired, or a daemon call, possibly one of several node
+// failures, ran out of time
+func deadlineExceeded(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if multi, ok := nodeerr.As(err); ok {
+		for _, nodeErr := range multi.Errors {
+			if status.Code(nodeErr.Err) == codes.DeadlineExceeded {
+				return true
+			}
+		}
+	}
+	return status.Code(err) == codes.DeadlineExceeded
+}
+
+// parseThresholds parses the --warn and --crit rates, of which the critical
+// one must not be above the warning one
+func parseThresholds(warnFlag, critFlag string) (float64, float64, error) {
+	warn, err := units.ParseBitRate(warnFlag)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --warn: %w", err)
+	}
+	crit, err := units.ParseBitRate(critFlag)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --crit: %w", err)
+	}
+	if crit > warn {
+		return 0, 0, errors.New("--crit must not be greater than --warn")
+	}
+	return warn, crit, nil
+}
+
+// throughputState returns the state of a measured throughput: critical
+// below crit, warning below warn and OK otherwise
+func throughputState(throughput, warn, crit float64) checkState {
+	switch {
+	case throughput < crit:
+		return checkCritical
+	case throughput < warn:
+		return checkWarning
+	default:
+		return checkOK
+	}
+}
+
+// runCheckPair runs the single test from opts.from to opts.to and returns its
+// aggregated result. Daemon processes are stopped on every path.
+func runCheckPair(ctx context.Context, cfg *config.ControllerConfig, opts checkOptions) (*aggregator.TestResult, error) {
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	profileName := opts.profile
+	if profileName == "" {
+		profileName = cfg.Controller.Topology.DefaultProfile
+	}
+	profile, err := profileRegistry.GetProfile(profileName)
+	if err != nil {
+		return nil, err
+	}
+	profile = profile.Clone()
+	profile.Duration = units.Seconds(opts.duration)
+
+	source, err := nodeRegistry.GetNode(opts.from)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := nodeRegistry.GetNode(opts.to)
+	if err != nil {
+		return nil, err
+	}
+
+	topoGen := topology.NewGenerator(nodeRegistry, profileRegistry, profile)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		topoGen.SetPortRange(int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+	topo, err := topoGen.GeneratePair(source.ID, dest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := client.NewPool(time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second)
+	if connErr := pool.ConnectAll(ctx, []*models.Node{source, dest}); connErr != nil {
+		return nil, connErr
+	}
+	defer func() {
+		if closeErr := pool.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close connection pool: %v", closeErr)
+		}
+	}()
+
+	cache := openNodeCache(cfg, false)
+	pool.SetCache(cache)
+	defer saveNodeCache(cache)
+
+	// Results are dropped on failure since nothing consumes them
+	orch := orchestrator.NewOrchestrator(pool, false, false, "")
+	orch.SetFailurePolicy(orchestrator.FailurePolicy{StopAll: true, ClearResults: true})
+	orch.SetCongestionControlPolicy(cfg.Controller.CongestionControlPolicy)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		orch.SetPortRange(pr.Owner, int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+	if err := orch.ExecuteTest(ctx, topo); err != nil {
+		return nil, err
+	}
+
+	agg := aggregator.NewAggregator()
+	configureAnalysis(agg, cfg)
+	if err := agg.CollectResults(ctx, pool); err != nil {
+		return nil, err
+	}
+
+	var result *aggregator.TestResult
+	for _, r := range agg.GetResults() {
+		if r.TestID == topo.Pairs[0].TestID {
+			result = r
+		}
+	}
+	if result == nil {
+		return nil, fmt.Errorf("no result returned for %s", topo.Pairs[0].TestID)
+	}
+
+	if opts.jsonFile != "" {
+		writer := output.NewWriter(opts.jsonFile, "")
+		configureWriter(writer, cfg)
+		writer.SetOverwrite(true) // Checks run repeatedly, each replacing the last result
+		if err := writer.WriteAll(agg.GetSummary(), []*aggregator.TestResult{result}); err != nil {
+			return nil, fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// perfdata formats throughput and thresholds as Nagios performance data in
+// Gbps, such as throughput=9.2Gbps;8;5, the throughput rounded to 0.01Gbps
+func perfdata(throughput, warn, crit float64) string {
+	format := func(gbps float64) string {
+		return strconv.FormatFloat(gbps, 'f', -1, 64)
+	}
+	return fmt.Sprintf("throughput=%sGbps;%s;%s", format(math.Round(throughput/units.Gbps*100)/100),
+		format(warn/units.Gbps), format(crit/units.Gbps))
+}
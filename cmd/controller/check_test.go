@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+)
+
+func TestPerfdata(t *testing.T) {
+	tests := []struct {
+		name       string
+		throughput float64
+		warn       float64
+		crit       float64
+		want       string
+	}{
+		{"whole thresholds", 9.2e9, 8e9, 5e9, "throughput=9.2Gbps;8;5"},
+		{"throughput rounded", 9.876e9, 8e9, 5e9, "throughput=9.88Gbps;8;5"},
+		{"fractional thresholds", 950e6, 2.5e9, 500e6, "throughput=0.95Gbps;2.5;0.5"},
+		{"nothing measured", 0, 1e9, 0, "throughput=0Gbps;1;0"},
+		{"above 1Tbps", 1.2e12, 1e12, 400e9, "throughput=1200Gbps;1000;400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := perfdata(tt.throughput, tt.warn, tt.crit); got != tt.want {
+				t.Errorf("perfdata(%g, %g, %g) = %s, want %s", tt.throughput, tt.warn, tt.crit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		warn     string
+		crit     string
+		wantWarn float64
+		wantCrit float64
+		wantErr  string
+	}{
+		{"warn above crit", "8G", "5G", 8e9, 5e9, ""},
+		{"equal thresholds", "1G", "1000M", 1e9, 1e9, ""},
+		{"crit above warn", "5G", "8G", 0, 0, "--crit must not be greater than --warn"},
+		{"invalid warn", "fast", "5G", 0, 0, "invalid --warn"},
+		{"invalid crit", "8G", "", 0, 0, "invalid --crit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn, crit, err := parseThresholds(tt.warn, tt.crit)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("parseThresholds(%q, %q) error = %v", tt.warn, tt.crit, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("parseThresholds(%q, %q) error = %v, want %q", tt.warn, tt.crit, err, tt.wantErr)
+			}
+			if warn != tt.wantWarn || crit != tt.wantCrit {
+				t.Errorf("parseThresholds(%q, %q) = %g, %g, want %g, %g",
+					tt.warn, tt.crit, warn, crit, tt.wantWarn, tt.wantCrit)
+			}
+		})
+	}
+}
+
+func TestThroughputState(t *testing.T) {
+	const warn, crit = 8e9, 5e9
+	tests := []struct {
+		name       string
+		throughput float64
+		want       checkState
+	}{
+		{"above warn", 9.2e9, checkOK},
+		{"at warn", warn, checkOK},
+		{"below warn", 7.9e9, checkWarning},
+		{"at crit", crit, checkWarning},
+		{"below crit", 4.9e9, checkCritical},
+		{"nothing measured", 0, checkCritical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := throughputState(tt.throughput, warn, crit); got != tt.want {
+				t.Errorf("throughputState(%g) = %s, want %s", tt.throughput, got, tt.want)
+			}
+		})
+	}
+
+	// Equal thresholds leave no warning band
+	if got := throughputState(crit-1, crit, crit); got != checkCritical {
+		t.Errorf("throughputState() below equal thresholds = %s, want CRITICAL", got)
+	}
+}
+
+func TestDeadlineExceeded(t *testing.T) {
+	grpcDeadline := status.Error(codes.DeadlineExceeded, "context deadline exceeded")
+	multi := func(errs ...error) error {
+		multi := nodeerr.New("start clients")
+		for i, err := range errs {
+			multi.Add(fmt.Sprintf("node%d", i+1), err)
+		}
+		return multi
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"context deadline", fmt.Errorf("wait: %w", context.DeadlineExceeded), true},
+		{"gRPC deadline", grpcDeadline, true},
+		{"gRPC deadline on one node", multi(errors.New("connection refused"), grpcDeadline), true},
+		{"wrapped node failures", fmt.Errorf("clients: %w", multi(grpcDeadline)), true},
+		{"other gRPC error", status.Error(codes.Unavailable, "connection refused"), false},
+		{"other node failures", multi(errors.New("iperf3 not found")), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := deadlineExceeded(context.Background(), tt.err); got != tt.want {
+				t.Errorf("deadlineExceeded(%v) = %t, want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+
+	// Any error once the check's own deadline has passed
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	if !deadlineExceeded(ctx, errors.New("failed to stop processes")) {
+		t.Error("deadlineExceeded() after the deadline = false, want true")
+	}
+}
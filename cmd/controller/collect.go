@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+func newCollectCommand() *cobra.Command {
+	var configPath string
+	var resultsFile string
+
+	cmd := &cobra.Command{
+		Use:   "collect",
+		Short: "Collect results missing from a partial run and merge them into its output",
+		Long: `collect retries the nodes listed under collection_errors in a results file
+written by run or "clients start", and merges the results they still hold
+into the same JSON and CSV files. Nodes that fail again stay listed.
+
+It exits with status 2 while results are still missing, like run.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return collectMissing(configPath, resultsFile)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringVar(&resultsFile, "results", "",
+		"results file to merge into (default: the configured json_file)")
+
+	return cmd
+}
+
+// collectMissing retrieves the results missing from a partial results file
+// and rewrites the file, and the CSV file, with them merged in
+func collectMissing(configPath, resultsFile string) error {
+	cfg, err := config.LoadControllerConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDefaults()
+
+	csvFile := cfg.Controller.Output.CSVFile
+	timelineCSVFile := cfg.Controller.Output.TimelineCSVFile
+	schemaFile := cfg.Controller.Output.SchemaFile
+	if resultsFile == "" {
+		resultsFile = cfg.Controller.Output.JSONFile
+	} else if resultsFile != cfg.Controller.Output.JSONFile {
+		// The CSV and schema files belong to the configured results file
+		csvFile = ""
+		timelineCSVFile = ""
+		schemaFile = ""
+	}
+
+	previous, err := output.ReadJSON(resultsFile)
+	if err != nil {
+		return err
+	}
+	if len(previous.CollectionErrors) == 0 {
+		fmt.Printf("No results missing from %s\n", resultsFile)
+		return nil
+	}
+	if previous.Summary.FilteredResults > 0 {
+		return fmt.Errorf("%s was written with output filters; results left out by them cannot be merged", resultsFile)
+	}
+	if metadata := previous.Metadata; metadata != nil && metadata.Aggregation != nil &&
+		metadata.Aggregation.Mode == output.AggregationStreaming {
+		return fmt.Errorf("%s holds a sample of streamed results; late results cannot be merged into it", resultsFile)
+	}
+
+	agg := aggregator.NewAggregator()
+	configureAnalysis(agg, cfg)
+	if err := tagLateResults(cfg, agg, previous.Metadata); err != nil {
+		return err
+	}
+	agg.RestoreResults(previous.Results)
+	agg.SetBisection(previous.Metadata != nil && previous.Metadata.Bisection != nil)
+
+	sources := aggregator.MissingTestSources(previous.CollectionErrors)
+	expected := make(map[string]bool, len(sources))
+	for testID := range sources {
+		expected[testID] = true
+	}
+	agg.SetExpectedTests(expected)
+	agg.SetTestSources(sources)
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	pool := client.NewPool(time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second)
+	defer func() {
+		if closeErr := pool.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close connection pool: %v", closeErr)
+		}
+	}()
+	for _, collectionErr := range previous.CollectionErrors {
+		node, err := nodeRegistry.GetNode(collectionErr.Node)
+		if err != nil {
+			agg.AddCollectionError(collectionErr.Node, err)
+			continue
+		}
+		if err := pool.Connect(ctx, node); err != nil {
+			agg.AddCollectionError(node.ID, err)
+		}
+	}
+
+	before := len(previous.Results)
+	if pool.Count() > 0 {
+		if err := agg.CollectResults(ctx, pool); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	warnForeignResults(agg)
+
+	results := agg.GetResults()
+	log.Printf("Collected %d late results", len(results)-before)
+
+	metadata := previous.Metadata
+	if metadata == nil {
+		metadata = &output.RunMetadata{}
+	}
+	// A run that failed part-way stays partial however many results arrive
+	metadata.Partial = metadata.FailedPhase != ""
+	writer := output.NewWriter(resultsFile, csvFile)
+	writer.SetOverwrite(true) // Merges into the files read above
+	configureWriter(writer, cfg)
+	partialErr := reportCollectionErrors(agg, writer, metadata)
+	writer.SetMetadata(metadata)
+	writer.SetForeignResults(mergeForeignResults(previous.ForeignResults, agg.GetForeignResults()))
+	writer.SetConfig(previous.Config)
+	writer.SetTimeline(agg.Timeline(), timelineCSVFile)
+	pairRepetitions(writer, results, false)
+	writer.SetSchemaFile(schemaFile)
+	summary := agg.GetSummary()
+	summary.ExcludedTests = previous.Summary.ExcludedTests
+	summary.ReverseSkippedTests = previous.Summary.ReverseSkippedTests
+	if err := writer.WriteAll(summary, results); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	fmt.Printf("Merged results into %s\n", resultsFile)
+	fmt.Printf("Completed: %d, Failed: %d\n", summary.CompletedTests, summary.FailedTests)
+	return partialErr
+}
+
+// tagLateResults sets the profiles, groups and background flags of the
+// configured topology on agg, so that late results are tagged like the
+// others. Runs of a plan file are tagged from the plan, if it is unchanged.
+// Rotating topologies choose different pairs on every run, so their late
+// results are left untagged; partial meshes are regenerated with the run's
+// seed.
+func tagLateResults(cfg *config.ControllerConfig, agg *aggregator.Aggregator, metadata *output.RunMetadata) error {
+	var plan *topology.PlanFile
+	if metadata != nil && metadata.Plan != nil {
+		var sum string
+		var err error
+		if plan, sum, err = topology.ReadPlanFile(metadata.Plan.Path); err != nil || sum != metadata.Plan.SHA256 {
+			log.Printf("Warning: plan file %s is missing or changed since the run; late results are left untagged",
+				metadata.Plan.Path)
+			return nil
+		}
+	} else if cfg.Controller.Topology.Type == "rotating" {
+		return nil
+	} else if metadata != nil && metadata.PartialMesh != nil {
+		cfg.Controller.Topology.Seed = metadata.PartialMesh.Seed
+	}
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		return fmt.Errorf("failed to get default profile: %w", err)
+	}
+	var topo *topology.Topology
+	if plan != nil {
+		restricted, _ := plan.Restrict(nodeRegistry)
+		topo, err = setup.ImportPlan(cfg, nodeRegistry, profileRegistry, defaultProfile, restricted)
+	} else {
+		topo, err = setup.GenerateTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	agg.SetTestGroups(topo.TestGroups())
+	agg.SetTestProfiles(topo.TestProfiles())
+	agg.SetTestFamilies(topo.TestFamilies())
+	agg.SetTestInterfaces(topo.TestInterfaces())
+	agg.SetTestHops(topo.TestHops())
+	agg.SetTestGroupPairs(topo.TestGroupPairs())
+	agg.SetTestRepetitions(topo.TestRepetitions())
+	agg.SetTestLabels(topo.TestLabels())
+	agg.SetBackgroundTests(topo.BackgroundTests())
+	return nil
+}
+
+// mergeForeignResults combines the foreign results of the original output
+// with those returned while collecting late results
+func mergeForeignResults(previous, late *aggregator.ForeignResults) *aggregator.ForeignResults {
+	if previous == nil {
+		return late
+	}
+	if late == nil {
+		return previous
+	}
+
+	merged := &aggregator.ForeignResults{
+		Count:   previous.Count + late.Count,
+		ByNode:  make(map[string]int, len(previous.ByNode)+len(late.ByNode)),
+		Results: append(append([]*aggregator.TestResult{}, previous.Results...), late.Results...),
+	}
+	for nodeID, count := range previous.ByNode {
+		merged.ByNode[nodeID] += count
+	}
+	for nodeID, count := range late.ByNode {
+		merged.ByNode[nodeID] += count
+	}
+	return merged
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+func newCoverageCommand() *cobra.Command {
+	var configPath string
+	var sla time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "coverage",
+		Short: "Report how long ago each pair of a rotating topology was measured",
+		Long: `coverage reads the coverage file of a rotating topology and reports how long
+ago its pairs were last measured. With --sla it lists the pairs that were not
+measured within that time, stalest first.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return reportCoverage(configPath, sla)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().DurationVar(&sla, "sla", 0,
+		"list pairs not measured within this time, e.g. 168h")
+
+	return cmd
+}
+
+// reportCoverage prints coverage age statistics and any pairs outside sla
+func reportCoverage(configPath string, sla time.Duration) error {
+	cfg, err := config.LoadControllerConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDefaults()
+
+	coverage, err := loadCoverage(cfg)
+	if err != nil {
+		return err
+	}
+	if coverage == nil {
+		return fmt.Errorf("coverage is only recorded for rotating topologies")
+	}
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pairs := topology.MeshPairs(nodeRegistry)
+	printCoverageStats(coverage.Stats(pairs, now))
+
+	if sla <= 0 {
+		return nil
+	}
+	stale := coverage.Stale(pairs, now, sla)
+	fmt.Printf("\nPairs not measured within %v: %d\n", sla, len(stale))
+	for _, pair := range stale {
+		last := coverage.LastTested(pair[0], pair[1])
+		if last.IsZero() {
+			fmt.Printf("  %s -> %s: never\n", pair[0], pair[1])
+			continue
+		}
+		fmt.Printf("  %s -> %s: %v ago\n", pair[0], pair[1], now.Sub(last).Round(time.Minute))
+	}
+	return nil
+}
+
+// printCoverageStats prints coverage age statistics
+func printCoverageStats(stats topology.CoverageStats) {
+	fmt.Printf("Pairs: %d\n", stats.Pairs)
+	fmt.Printf("  Never measured: %d\n", stats.NeverTested)
+	if stats.Pairs > stats.NeverTested {
+		fmt.Printf("  Last measured: newest %v, median %v, oldest %v ago\n",
+			stats.Newest.Round(time.Minute), stats.Median.Round(time.Minute), stats.Oldest.Round(time.Minute))
+	}
+}
+
+// loadCoverage loads the coverage state of a rotating topology, returning
+// nil for other topology types
+func loadCoverage(cfg *config.ControllerConfig) (*topology.Coverage, error) {
+	if cfg.Controller.Topology.Type != "rotating" {
+		return nil, nil
+	}
+	return topology.LoadCoverage(cfg.Controller.Topology.CoverageFile)
+}
+
+// logCoverage logs which share of the mesh a rotating topology tests and how
+// stale the mesh was before this run
+func logCoverage(coverage *topology.Coverage, nodeRegistry *models.NodeRegistry, topo *topology.Topology) {
+	pairs := topology.MeshPairs(nodeRegistry)
+	stats := coverage.Stats(pairs, time.Now())
+
+	log.Printf("Rotating topology: testing %d of %d pairs", topo.GetMeasuredCount(), len(pairs))
+	log.Printf("  Never measured: %d", stats.NeverTested)
+	if stats.Pairs > stats.NeverTested {
+		log.Printf("  Last measured: newest %v, median %v, oldest %v ago",
+			stats.Newest.Round(time.Minute), stats.Median.Round(time.Minute), stats.Oldest.Round(time.Minute))
+	}
+}
+
+// updateCoverage records the completed measured pairs of a rotating topology
+// in its coverage file, logging rather than failing
+func updateCoverage(cfg *config.ControllerConfig, results []*aggregator.TestResult) {
+	coverage, err := loadCoverage(cfg)
+	if err != nil {
+		log.Printf("Warning: failed to update coverage: %v", err)
+		return
+	}
+	if coverage == nil {
+		return
+	}
+
+	recorded := 0
+	for _, result := range results {
+		if result.Background || result.Status != "TEST_STATUS_COMPLETED" {
+			continue
+		}
+		coverage.Record(result.SourceNode, result.DestNode, time.Unix(result.EndTime, 0))
+		recorded++
+	}
+
+	if err := coverage.Save(); err != nil {
+		log.Printf("Warning: failed to update coverage: %v", err)
+		return
+	}
+	log.Printf("Coverage: recorded %d measured pairs in %s", recorded, cfg.Controller.Topology.CoverageFile)
+}
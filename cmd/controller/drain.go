@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+)
+
+// drainPollInterval is how often drain checks whether running tests finished
+const drainPollInterval = 2 * time.Second
+
+// drainOptions holds the flags of the drain command
+type drainOptions struct {
+	configPath string
+	nodes      []string
+	reason     string
+	wait       time.Duration
+	undo       bool
+}
+
+func newDrainCommand() *cobra.Command {
+	var opts drainOptions
+
+	cmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Put daemons in maintenance mode and stop their iperf3 processes",
+		Long: `drain puts daemons in maintenance mode, in which they refuse new tests
+while status, results and stop requests keep working, and then stops their
+iperf3 processes. With --wait, running tests are given that long to finish
+before the remaining processes are stopped.
+
+Daemons stay in maintenance mode, also across runs, until "drain --undo".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return drainNodes(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringSliceVar(&opts.nodes, "nodes", nil,
+		"node IDs to drain (default: every configured node)")
+	cmd.Flags().StringVar(&opts.reason, "reason", "",
+		"reason shown in the daemons' status and in refused requests")
+	cmd.Flags().DurationVar(&opts.wait, "wait", 0,
+		"let running tests finish for up to this long before stopping processes")
+	cmd.Flags().BoolVar(&opts.undo, "undo", false,
+		"take the daemons out of maintenance mode instead")
+
+	return cmd
+}
+
+// drainNodes enables maintenance mode on the selected daemons and stops
+// their processes, or disables it with opts.undo
+func drainNodes(opts drainOptions) error {
+	cfg, err := config.LoadControllerConfig(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDefaults()
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	nodes := nodeRegistry.GetAllNodes()
+	if len(opts.nodes) > 0 {
+		nodes = make([]*models.Node, 0, len(opts.nodes))
+		for _, id := range opts.nodes {
+			node, err := nodeRegistry.GetNode(id)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, node)
+		}
+	}
+
+	ctx := context.Background()
+	pool := client.NewPool(time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second)
+	pool.SetPollPolicy(cfg.Controller.Concurrency.PollPolicy())
+	defer func() {
+		if closeErr := pool.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close connection pool: %v", closeErr)
+		}
+	}()
+	if connErr := pool.ConnectAll(ctx, nodes); connErr != nil {
+		return fmt.Errorf("failed to connect to daemons: %w", connErr)
+	}
+
+	if opts.undo {
+		if err := pool.SetMaintenance(ctx, false, ""); err != nil {
+			return err
+		}
+		fmt.Printf("Maintenance mode disabled on %d nodes\n", pool.Count())
+		return nil
+	}
+
+	// Refuse new work first, so that nothing starts while processes stop
+	if err := pool.SetMaintenance(ctx, true, opts.reason); err != nil {
+		return err
+	}
+	fmt.Printf("Maintenance mode enabled on %d nodes\n", pool.Count())
+
+	if opts.wait > 0 {
+		if waitForIdle(ctx, pool, opts.wait) {
+			fmt.Println("All running tests finished")
+			return nil
+		}
+		fmt.Printf("Processes still running, or nodes not answering, after %s; stopping them\n", opts.wait)
+	}
+
+	if err := pool.StopAll(ctx, ""); err != nil {
+		return err
+	}
+	fmt.Println("Stopped all iperf3 processes")
+	return nil
+}
+
+// waitForIdle polls the daemons until every one reports no running client
+// processes, returning false if that does not happen within timeout. Servers
+// keep listening until they are stopped, so they are not waited for.
+func waitForIdle(ctx context.Context, pool *client.Pool, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	ticker := pool.PollTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		statuses, err := pool.ProcessStatus(ctx)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+
+		running := 0
+		for _, status := range statuses {
+			running += runningClients(status)
+		}
+		if err == nil && running == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		log.Printf("Waiting for %d running tests to finish", running)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+}
+
+// runningClients counts the client processes a daemon runs, leaving out
+// those already stopped. Daemons that cannot list their processes only
+// report a count including servers, which is used instead.
+func runningClients(status *pb.DaemonStatus) int {
+	if len(status.Processes) == 0 {
+		return int(status.RunningProcesses)
+	}
+	running := 0
+	for _, process := range status.Processes {
+		if !process.Server && !process.Stopping {
+			running++
+		}
+	}
+	return running
+}
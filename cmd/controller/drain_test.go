@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+)
+
+// processClient reports the given processes in GetStatus; other methods are
+// not used
+type processClient struct {
+	pb.DaemonServiceClient
+	processes []*pb.ProcessStatus
+}
+
+func (c *processClient) GetStatus(_ context.Context, req *pb.GetStatusRequest, _ ...grpc.CallOption) (*pb.GetStatusResponse, error) {
+	status := &pb.DaemonStatus{RunningProcesses: int32(len(c.processes))} // #nosec G115 -- Test process count is small
+	if req.IncludeProcesses {
+		status.Processes = c.processes
+	}
+	return &pb.GetStatusResponse{Status: status}, nil
+}
+
+func TestWaitForIdle(t *testing.T) {
+	servers := []*pb.ProcessStatus{
+		{TestId: "server-5201", Server: true, Port: 5201},
+		{TestId: "server-5202", Server: true, Port: 5202},
+	}
+
+	tests := []struct {
+		name      string
+		processes []*pb.ProcessStatus
+		wantIdle  bool
+	}{
+		{"nothing running", nil, true},
+		{"servers still listening", servers, true},
+		{"client stopping", append([]*pb.ProcessStatus{{TestId: "test-1", Stopping: true}}, servers...), true},
+		{"client running", append([]*pb.ProcessStatus{{TestId: "test-1"}}, servers...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := client.NewPool(time.Second)
+			pool.AddClient(&models.Node{ID: "node1"}, &processClient{processes: tt.processes})
+			pool.AddClient(&models.Node{ID: "node2"}, &processClient{processes: servers})
+
+			if idle := waitForIdle(context.Background(), pool, time.Millisecond); idle != tt.wantIdle {
+				t.Errorf("waitForIdle() = %t, want %t", idle, tt.wantIdle)
+			}
+		})
+	}
+}
+
+func TestRunningClients(t *testing.T) {
+	// Daemons unable to list processes only report their count
+	if got := runningClients(&pb.DaemonStatus{RunningProcesses: 3}); got != 3 {
+		t.Errorf("runningClients() without a process list = %d, want 3", got)
+	}
+	status := &pb.DaemonStatus{
+		RunningProcesses: 3,
+		Processes: []*pb.ProcessStatus{
+			{TestId: "server-5201", Server: true},
+			{TestId: "test-1"},
+			{TestId: "test-2", Stopping: true},
+		},
+	}
+	if got := runningClients(status); got != 1 {
+		t.Errorf("runningClients() = %d, want 1", got)
+	}
+}
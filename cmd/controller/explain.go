@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+)
+
+func newExplainCommand() *cobra.Command {
+	var list bool
+
+	cmd := &cobra.Command{
+		Use:   "explain <field>",
+		Short: "Describe a configuration field",
+		Long: `explain prints the type, default, validation rules and an example of a
+field of the controller or daemon configuration, given by its path from
+the top of the file. List indexes and map keys may be given or left out,
+so controller.test_profiles.default.duration and
+controller.test_profiles.duration name the same field.`,
+		Example: `  iperf-controller explain controller.topology.overrides
+  iperf-controller explain controller.nodes[0].data_ips
+  iperf-controller explain daemon.port_range
+  iperf-controller explain --list`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if list {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if list {
+				fmt.Println(strings.Join(config.FieldPaths(), "\n"))
+				return nil
+			}
+			return explainField(args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&list, "list", false, "list the paths of all configuration fields")
+
+	return cmd
+}
+
+// explainField prints the description of the configuration field at path
+func explainField(path string) error {
+	info, err := config.Explain(path)
+	if err != nil {
+		return err
+	}
+	fmt.Print(info.Format())
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// checkSourceCapacity asks the daemon of a one_to_many topology's source
+// whether it can run all of the source's clients at once. Preparing without
+// a port range claims nothing on the daemon. An unreachable daemon is only
+// warned about, as validate otherwise works offline.
+func checkSourceCapacity(cfg *config.ControllerConfig) error {
+	sourceID := cfg.Controller.Topology.Source
+	nodeRegistry, topo, err := buildTopology(cfg)
+	if err != nil {
+		return err
+	}
+	nodeTopologies, err := topology.GenerateNodeTopologies(topo)
+	if err != nil {
+		return err
+	}
+	source, err := nodeRegistry.GetNode(sourceID)
+	if err != nil {
+		return err
+	}
+	clients := len(nodeTopologies[sourceID].ClientAssignments)
+
+	timeout := time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pool := client.NewPool(timeout)
+	defer func() {
+		_ = pool.Close()
+	}()
+	if err := pool.Connect(ctx, source); err != nil {
+		fmt.Printf("⚠ Could not check the capacity of source %s: %v\n", sourceID, err)
+		return nil
+	}
+	nodeClient, err := pool.GetClient(sourceID)
+	if err != nil {
+		return err
+	}
+	resp, err := nodeClient.Client.PrepareTest(ctx, &pb.PrepareTestRequest{Topology: nodeTopologies[sourceID]})
+	if err != nil {
+		fmt.Printf("⚠ Could not check the capacity of source %s: %v\n", sourceID, err)
+		return nil
+	}
+	if !resp.CanHandle {
+		return fmt.Errorf("source %s cannot run its %d clients at once: %s", sourceID, clients, resp.Message)
+	}
+
+	available := resp.GetAvailableCapacity()
+	fmt.Printf("✓ Source %s can run its %d clients at once (%d of %d process slots free)\n",
+		sourceID, clients, available.GetAvailableProcesses(), available.GetMaxProcesses())
+	return nil
+}
+
+// buildTopology generates the configured topology without contacting any
+// daemon, as run would before quorum checks
+func buildTopology(cfg *config.ControllerConfig) (*models.NodeRegistry, *topology.Topology, error) {
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get default profile: %w", err)
+	}
+	coverage, err := loadCoverage(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	topo, err := setup.GenerateTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nodeRegistry, topo, nil
+}
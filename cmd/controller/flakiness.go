@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+)
+
+// flakyPairCount is how many pairs the flakiest pairs view lists
+const flakyPairCount = 5
+
+// pairFlakiness computes the flakiness of the run's pairs and adds it to
+// writer's output when some pair ran more than once. A streaming run keeps
+// only a sample of its results, from which it cannot be computed.
+func pairFlakiness(cfg *config.ControllerConfig, writer *output.Writer, results []*aggregator.TestResult,
+	streaming bool) []*aggregator.PairFlakiness {
+	if streaming {
+		if cfg.Controller.Analysis.MinSuccessRatio > 0 || cfg.Controller.Output.FlakinessCSVFile != "" {
+			log.Printf("Warning: skipping pair flakiness, which needs every result, for a streaming run")
+		}
+		return nil
+	}
+
+	flakiness := aggregator.Flakiness(results)
+	if aggregator.Repeated(flakiness) {
+		writer.SetFlakiness(flakiness, cfg.Controller.Output.FlakinessCSVFile)
+	}
+	return flakiness
+}
+
+// pairRepetitions adds the throughput spread of the run's repeated pairs to
+// writer's output. Like flakiness, it needs every result, which a streaming
+// run does not keep.
+func pairRepetitions(writer *output.Writer, results []*aggregator.TestResult, streaming bool) {
+	if streaming {
+		return
+	}
+	if repetitions := aggregator.Repetitions(results); len(repetitions) > 0 {
+		writer.SetRepetitions(repetitions)
+	}
+}
+
+// printFlakyPairs lists the pairs that ran more than once and failed some
+// attempts or varied in throughput, flakiest first
+func printFlakyPairs(flakiness []*aggregator.PairFlakiness) {
+	flaky := make([]*aggregator.PairFlakiness, 0, flakyPairCount)
+	for _, pair := range flakiness {
+		if pair.Attempts > 1 && (pair.Failures > 0 || pair.ThroughputCV > 0) {
+			flaky = append(flaky, pair)
+		}
+	}
+	if len(flaky) == 0 {
+		return
+	}
+	if len(flaky) > flakyPairCount {
+		flaky = flaky[:flakyPairCount]
+	}
+
+	fmt.Println("  Flakiest pairs:")
+	for _, pair := range flaky {
+		fmt.Printf("    %s: %d/%d attempts failed, avg %s, CV %.1f%%\n", flakyPairName(pair),
+			pair.Failures, pair.Attempts, units.FormatBitRate(pair.AvgThroughputBps, 2), pair.ThroughputCV*100)
+	}
+}
+
+// checkSuccessRatio returns an error naming the pairs that completed less
+// than minRatio of their attempts; 0 disables the check
+func checkSuccessRatio(flakiness []*aggregator.PairFlakiness, minRatio float64) error {
+	if minRatio <= 0 {
+		return nil
+	}
+	below := make([]string, 0)
+	for _, pair := range flakiness {
+		if pair.SuccessRatio < minRatio {
+			below = append(below, fmt.Sprintf("%s (%d/%d)", flakyPairName(pair),
+				pair.Attempts-pair.Failures, pair.Attempts))
+		}
+	}
+	if len(below) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d pairs completed less than %.0f%% of their attempts: %s",
+		len(below), minRatio*100, strings.Join(below, ", "))
+}
+
+// flakyPairName names a pair by its nodes and whichever of its family,
+// interface and profile the run set
+func flakyPairName(pair *aggregator.PairFlakiness) string {
+	name := pair.SourceNode + " -> " + pair.DestNode
+	details := make([]string, 0, 3)
+	for _, detail := range []string{pair.AddressFamily, pair.Interface, pair.Profile} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	if len(details) > 0 {
+		name += " (" + strings.Join(details, ", ") + ")"
+	}
+	return name
+}
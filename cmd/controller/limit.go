@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+	"github.com/bensons/iperf-cnc/internal/controller/tui"
+)
+
+// checkTestCount refuses a topology of more than max_total_tests pairs and
+// asks for confirmation, at a terminal, of one of more than
+// confirm_tests_above. yesReally skips both.
+func checkTestCount(cfg *config.ControllerConfig, topo *topology.Topology, yesReally bool) error {
+	if yesReally {
+		return nil
+	}
+	var answers io.Reader // Left nil when there is no terminal to ask at
+	if tui.IsTerminal() {
+		answers = os.Stdin
+	}
+	return checkTestLimits(cfg, topo.GetTestCount(), answers)
+}
+
+// checkTestLimits applies the topology's test limits to count tests, reading
+// the confirmation from answers; a nil answers skips the confirmation
+func checkTestLimits(cfg *config.ControllerConfig, count int, answers io.Reader) error {
+	settings := cfg.Controller.Topology
+	if limit := settings.MaxTotalTests; limit > 0 && count > limit {
+		return fmt.Errorf("the topology has %d tests, more than max_total_tests (%d); "+
+			"raise topology.max_total_tests or pass --yes-really to run it", count, limit)
+	}
+	if threshold := settings.ConfirmTestsAbove; threshold > 0 && count > threshold && answers != nil {
+		question := fmt.Sprintf("The topology has %d tests across %d nodes. Run them?", count, len(cfg.Controller.Nodes))
+		if !confirm(question, answers) {
+			return fmt.Errorf("aborted: %d tests not confirmed", count)
+		}
+	}
+	return nil
+}
+
+// confirm asks question and reports whether the line read from answers is
+// yes; a line cut short by EOF is a no
+func confirm(question string, answers io.Reader) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(answers).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
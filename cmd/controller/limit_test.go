@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// limitConfig returns a configuration with the given test limits, defaulted
+// when 0 as they are when loaded
+func limitConfig(maxTotal, confirmAbove int) *config.ControllerConfig {
+	cfg := &config.ControllerConfig{}
+	cfg.Controller.Topology.MaxTotalTests = maxTotal
+	cfg.Controller.Topology.ConfirmTestsAbove = confirmAbove
+	cfg.SetDefaults()
+	return cfg
+}
+
+func TestCheckTestLimits(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         *config.ControllerConfig
+		count       int
+		answers     string
+		interactive bool
+		wantErr     string
+	}{
+		{"at confirmation threshold", limitConfig(0, 0), config.DefaultConfirmTestsAbove, "", true, ""},
+		{"above threshold confirmed", limitConfig(0, 0), config.DefaultConfirmTestsAbove + 1, "y\n", true, ""},
+		{"above threshold refused", limitConfig(0, 0), config.DefaultConfirmTestsAbove + 1, "n\n", true, "not confirmed"},
+		{"above threshold unanswered", limitConfig(0, 0), config.DefaultConfirmTestsAbove + 1, "", true, "not confirmed"},
+		{"above threshold without a terminal", limitConfig(0, 0), config.DefaultConfirmTestsAbove + 1, "", false, ""},
+		{"at hard cap", limitConfig(0, 0), config.DefaultMaxTotalTests, "yes\n", true, ""},
+		{"above hard cap", limitConfig(0, 0), config.DefaultMaxTotalTests + 1, "y\n", true, "max_total_tests (5000)"},
+		{"above hard cap without a terminal", limitConfig(0, 0), config.DefaultMaxTotalTests + 1, "", false, "max_total_tests (5000)"},
+		{"configured limits", limitConfig(10, 5), 11, "y\n", true, "max_total_tests (10)"},
+		{"configured threshold", limitConfig(10, 5), 6, "n\n", true, "not confirmed"},
+		{"negative values disable both", limitConfig(-1, -1), 100000, "", true, ""},
+		{"limits not defaulted", &config.ControllerConfig{}, 100000, "", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var answers io.Reader
+			if tt.interactive {
+				answers = strings.NewReader(tt.answers)
+			}
+			err := checkTestLimits(tt.cfg, tt.count, answers)
+
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("checkTestLimits(%d) error = %v", tt.count, err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkTestLimits(%d) error = %v, want %q", tt.count, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answers string
+		want    bool
+	}{
+		{"y\n", true},
+		{"yes\n", true},
+		{"  YES \n", true},
+		{"Y\r\n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"sure\n", false},
+		{"", false},  // EOF
+		{"y", false}, // EOF before the end of the line
+	}
+
+	for _, tt := range tests {
+		if got := confirm("Run them?", strings.NewReader(tt.answers)); got != tt.want {
+			t.Errorf("confirm(%q) = %t, want %t", tt.answers, got, tt.want)
+		}
+	}
+}
+
+func TestCheckTestCount_YesReally(t *testing.T) {
+	topo := &topology.Topology{Pairs: make([]*topology.TestPair, config.DefaultMaxTotalTests+1)}
+	if err := checkTestCount(limitConfig(0, 0), topo, true); err != nil {
+		t.Errorf("checkTestCount() with --yes-really error = %v", err)
+	}
+	if err := checkTestCount(limitConfig(0, 0), topo, false); err == nil {
+		t.Error("checkTestCount() above max_total_tests succeeded")
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+)
+
+// lintProfiles passes each lint finding on the test profiles to report and,
+// with strict set, fails if there were any. Window sizes are checked against
+// the rmem_max of the configured nodes as cached by earlier runs, so no
+// daemon is contacted.
+func lintProfiles(cfg *config.ControllerConfig, strict bool, report func(finding string)) error {
+	env := config.LintEnv{Facts: make(map[string]*models.NodeFacts)}
+	if !cfg.Controller.NodeCache.Disabled {
+		known := client.LoadNodeCache(cfg.Controller.NodeCache.File, 0).KnownFacts()
+		for _, node := range cfg.Controller.Nodes {
+			if facts, exists := known[node.ID]; exists {
+				env.Facts[node.ID] = facts
+			}
+		}
+	}
+
+	findings := cfg.LintProfiles(env)
+	for _, finding := range findings {
+		report(finding.String())
+	}
+	if strict && len(findings) > 0 {
+		return fmt.Errorf("%d test profile lint findings with --strict-profiles; fix the profiles or list the rules under their lint_ignore",
+			len(findings))
+	}
+	return nil
+}
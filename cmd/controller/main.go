@@ -2,21 +2,31 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	pb "github.com/bensons/iperf-cnc/api/proto"
 	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/debughttp"
 	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/common/units"
 	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
 	"github.com/bensons/iperf-cnc/internal/controller/client"
 	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
 	"github.com/bensons/iperf-cnc/internal/controller/output"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
 	"github.com/bensons/iperf-cnc/internal/controller/topology"
+	"github.com/bensons/iperf-cnc/internal/controller/tui"
 )
 
 var (
@@ -25,13 +35,38 @@ var (
 	date    = "unknown"
 )
 
+// exitPartialResults is the exit status when results were written but some
+// nodes' results could not be collected
+const exitPartialResults = 2
+
+// errPartialResults is returned, wrapped, when results were written without
+// the results of some nodes
+var errPartialResults = errors.New("partial results written")
+
 func main() {
 	if err := newRootCommand().Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		printError(err)
+		if errors.Is(err, errPartialResults) {
+			os.Exit(exitPartialResults)
+		}
+		if errors.Is(err, errQuorumNotMet) {
+			os.Exit(exitQuorumNotMet)
+		}
 		os.Exit(1)
 	}
 }
 
+// printError reports err on stderr. Failures on several nodes are listed as
+// a table, one node per row, rather than run into one line.
+func printError(err error) {
+	multi, ok := nodeerr.As(err)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s failed on %d nodes\n\n%s", multi.Op, multi.Len(), multi.Table())
+}
+
 func newRootCommand() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "iperf-controller",
@@ -44,23 +79,112 @@ across a cluster of nodes running iperf-daemon.`,
 	rootCmd.AddCommand(newRunCommand())
 	rootCmd.AddCommand(newValidateCommand())
 	rootCmd.AddCommand(newStatusCommand())
+	rootCmd.AddCommand(newCheckCommand())
+	rootCmd.AddCommand(newServersCommand())
+	rootCmd.AddCommand(newClientsCommand())
+	rootCmd.AddCommand(newCoverageCommand())
+	rootCmd.AddCommand(newCollectCommand())
+	rootCmd.AddCommand(newFetchArtifactsCommand())
+	rootCmd.AddCommand(newDrainCommand())
+	rootCmd.AddCommand(newTagsCommand())
+	rootCmd.AddCommand(newWorkloadsCommand())
+	rootCmd.AddCommand(newPlanCommand())
+	rootCmd.AddCommand(newExplainCommand())
 
 	return rootCmd
 }
 
+// runOptions holds the flags of the run command
+type runOptions struct {
+	configPath   string
+	useTUI       bool
+	refreshFacts bool
+	// includeForeign counts results for test IDs outside the topology
+	includeForeign bool
+	verbose        bool
+	force          bool
+	// overwrite replaces existing output files instead of refusing to run
+	overwrite bool
+	// noQuorum runs on the nodes available whatever the configured quorum
+	noQuorum bool
+	// yesReally runs topologies beyond the test count safety limits
+	yesReally bool
+	// rpcStats prints the daemon RPC latency table after the run
+	rpcStats bool
+	// strictProfiles fails the run on test profile lint findings
+	strictProfiles bool
+	// subset restricts the run to some of the configured nodes
+	subset config.NodeSubset
+	// planPath runs the pairs of a plan file instead of the generated topology
+	planPath string
+	// progressFormat is "text" or "json", which writes progress events as
+	// JSON lines to progressFile or stdout
+	progressFormat string
+	progressFile   string
+	// workload applies a workload preset to the fields the configuration
+	// leaves unset
+	workload string
+}
+
 func newRunCommand() *cobra.Command {
-	var configPath string
+	var opts runOptions
 
 	cmd := &cobra.Command{
 		Use:   "run",
 		Short: "Run a test based on configuration",
+		Long: `run executes a test based on configuration and writes its results.
+
+It exits with status 0 when every result was collected, 1 on failure, and 2
+when results were written but some nodes' results could not be collected.
+Those results can be added to the output later with "collect". It exits
+with status 3, before starting anything, when fewer nodes than the
+configured quorum are healthy.`,
+		Example: `  iperf-controller run -c controller.yaml
+  iperf-controller run -c controller.yaml --tui --overwrite
+  iperf-controller run -c controller.yaml --tag "zone:us-east" --exclude-nodes node7
+  iperf-controller run -c controller.yaml --plan plan.yaml
+  iperf-controller run -c controller.yaml --progress-format json > events.jsonl`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runTest(configPath)
+			return runTest(opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+	cmd.Flags().StringVarP(&opts.configPath, "config", "c", "./controller.yaml",
 		"path to configuration file")
+	cmd.Flags().BoolVar(&opts.useTUI, "tui", false,
+		"show an interactive dashboard while the test runs (falls back to logging without a terminal)")
+	cmd.Flags().BoolVar(&opts.refreshFacts, "refresh-facts", false,
+		"re-query capabilities and facts from every node instead of using the node cache")
+	cmd.Flags().BoolVar(&opts.force, "force", false,
+		"stop processes and clear uncollected results left on daemons by another run instead of refusing to start")
+	cmd.Flags().BoolVarP(&opts.verbose, "verbose", "v", false,
+		"log every node's progress in each phase instead of periodic totals")
+	cmd.Flags().BoolVar(&opts.overwrite, "overwrite", false,
+		"replace existing output files instead of refusing to run")
+	cmd.Flags().BoolVar(&opts.yesReally, "yes-really", false,
+		"run topologies of more than topology.max_total_tests tests, without asking for confirmation")
+	cmd.Flags().BoolVar(&opts.noQuorum, "no-quorum", false,
+		"skip the quorum check and run with every node, as without a quorum configured")
+	cmd.Flags().BoolVar(&opts.rpcStats, "rpc-stats", false,
+		"print the latency of daemon RPCs by method and node after the run")
+	cmd.Flags().BoolVar(&opts.strictProfiles, "strict-profiles", false,
+		"refuse to run when test profiles have lint findings instead of warning about them")
+	cmd.Flags().BoolVar(&opts.includeForeign, "include-foreign", false,
+		"count results for test IDs outside this run's topology instead of reporting them separately")
+	cmd.Flags().StringVar(&opts.progressFormat, "progress-format", progressFormatText,
+		"progress reporting: text, or json for one JSON object per progress event on stdout (human-readable output moves to stderr)")
+	cmd.Flags().StringVar(&opts.progressFile, "progress-file", "",
+		"write --progress-format json events to this file instead of stdout")
+	cmd.Flags().StringVar(&opts.workload, "workload", "",
+		"apply this workload preset (see \"workloads list\") to the topology, profile and analysis settings the configuration leaves unset")
+	cmd.Flags().StringVar(&opts.planPath, "plan", "",
+		"run the pairs of this plan file, as written by \"plan --export\" or other tools, instead of generating the topology")
+	cmd.Flags().StringSliceVar(&opts.subset.Nodes, "nodes", nil,
+		"run only between these node IDs (comma-separated)")
+	cmd.Flags().StringSliceVar(&opts.subset.Tags, "tag", nil,
+		"run only between nodes matching any of these tag expressions, e.g. \"zone:us-east and not rack:a\"; combined with --nodes, nodes matching either are used")
+	cmd.Flags().StringSliceVar(&opts.subset.Exclude, "exclude-nodes", nil,
+		"leave these node IDs out of the run")
 	if err := cmd.MarkFlagRequired("config"); err != nil {
 		panic(err) // This should never happen during initialization
 	}
@@ -70,17 +194,40 @@ func newRunCommand() *cobra.Command {
 
 func newValidateCommand() *cobra.Command {
 	var configPath string
+	var strictProfiles bool
+	var workload string
+	var deep bool
 
 	cmd := &cobra.Command{
 		Use:   "validate",
 		Short: "Validate configuration file",
+		Long: `validate loads the configuration, checks it the way run does, and lints
+the test profiles, without contacting any daemon except, for one_to_many
+topologies, the source's, which is asked whether it can run all of its
+clients at once. "explain <field>" describes what a field does and which
+values it accepts.
+
+With --deep, every node's daemon is also asked for the process slots and
+server ports it has free, and the topology is checked against them the way
+run checks it before preparing a test. Every node short of either is
+reported. Nothing is started or reserved on the daemons.`,
+		Example: `  iperf-controller validate -c controller.yaml
+  iperf-controller validate -c controller.yaml --strict-profiles
+  iperf-controller validate -c controller.yaml --workload fabric-acceptance
+  iperf-controller validate -c controller.yaml --deep`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return validateConfig(configPath)
+			return validateConfig(configPath, strictProfiles, workload, deep)
 		},
 	}
 
 	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
 		"path to configuration file")
+	cmd.Flags().BoolVar(&strictProfiles, "strict-profiles", false,
+		"treat test profile lint findings as errors")
+	cmd.Flags().StringVar(&workload, "workload", "",
+		"validate the configuration with this workload preset applied, as run --workload does")
+	cmd.Flags().BoolVar(&deep, "deep", false,
+		"check the topology against the process slots and server ports every daemon has free")
 	if err := cmd.MarkFlagRequired("config"); err != nil {
 		panic(err) // This should never happen during initialization
 	}
@@ -89,18 +236,33 @@ func newValidateCommand() *cobra.Command {
 }
 
 func newStatusCommand() *cobra.Command {
-	var configPath string
+	var opts statusOptions
 
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Check status of all configured nodes",
+		Long: `Check status of all configured nodes.
+
+With --probe-data, every daemon also attempts a TCP connection to the data
+port of every other node: the first port of a running iperf3 server, or else
+the first port a run would allocate. The outcomes are shown as a
+reachability matrix, and pairs reachable only one way, which usually means a
+one-way firewall rule, are listed.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return checkStatus(configPath)
+			return checkStatus(opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+	cmd.Flags().StringVarP(&opts.configPath, "config", "c", "./controller.yaml",
 		"path to configuration file")
+	cmd.Flags().BoolVar(&opts.probeData, "probe-data", false,
+		"probe data-plane reachability between every pair of nodes")
+	cmd.Flags().DurationVar(&opts.probe.Timeout, "probe-timeout", 2*time.Second,
+		"time each data-plane connection attempt may take")
+	cmd.Flags().IntVar(&opts.probe.Concurrent, "probe-concurrency", 16,
+		"connection attempts each daemon has in flight at once")
+	cmd.Flags().DurationVar(&opts.probe.Budget, "probe-budget", 15*time.Second,
+		"total time of the data-plane probe; pairs not probed within it are shown as such")
 	if err := cmd.MarkFlagRequired("config"); err != nil {
 		panic(err) // This should never happen during initialization
 	}
@@ -108,77 +270,98 @@ func newStatusCommand() *cobra.Command {
 	return cmd
 }
 
-func runTest(configPath string) error {
+func runTest(opts runOptions) (runErr error) {
+	events, closeEvents, err := openProgressEvents(opts)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+
 	fmt.Printf("iperf-controller version %s\n", version)
-	fmt.Printf("Loading configuration from: %s\n\n", configPath)
+	fmt.Printf("Loading configuration from: %s\n\n", opts.configPath)
 
 	// Load configuration
-	cfg, err := config.LoadControllerConfig(configPath)
+	cfg, expansion, err := config.LoadControllerConfigWorkload(opts.configPath, opts.workload)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if expansion != nil {
+		printWorkload(expansion)
+	}
 	cfg.SetDefaults()
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Warning: %s", warning)
+	}
+	plan, planSource, err := loadPlan(cfg, opts.planPath)
+	if err != nil {
+		return err
+	}
+	subset, err := selectNodes(cfg, opts.subset)
+	if err != nil {
+		return err
+	}
+	err = lintProfiles(cfg, opts.strictProfiles, func(finding string) {
+		log.Printf("Warning: %s", finding)
+	})
+	if err != nil {
+		return err
+	}
+	files := newOutputFiles(cfg, opts.overwrite)
+	if err := files.check(cfg); err != nil {
+		return err
+	}
 
-	// Build node registry
-	nodeRegistry := models.NewNodeRegistry()
-	for _, nodeConfig := range cfg.Controller.Nodes {
-		node := &models.Node{
-			ID:       nodeConfig.ID,
-			Hostname: nodeConfig.Hostname,
-			IP:       nodeConfig.IP,
-			Port:     nodeConfig.Port,
-			Tags:     nodeConfig.Tags,
-		}
-		if addErr := nodeRegistry.AddNode(node); addErr != nil {
-			return fmt.Errorf("failed to add node: %w", addErr)
-		}
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
 	}
 
 	log.Printf("Loaded %d nodes from configuration", nodeRegistry.Count())
 
-	// Build profile registry
-	profileRegistry := models.NewProfileRegistry()
-	for name, profileConfig := range cfg.Controller.TestProfiles {
-		// Convert protocol string to Protocol type
-		protocol := models.ProtocolTCP // Default to TCP
-		if profileConfig.Protocol == "udp" {
-			protocol = models.ProtocolUDP
-		}
-
-		profile := &models.TestProfile{
-			Name:              name,
-			Duration:          profileConfig.Duration,
-			Protocol:          protocol,
-			Bandwidth:         profileConfig.Bandwidth,
-			WindowSize:        profileConfig.WindowSize,
-			Parallel:          profileConfig.Parallel,
-			Bidirectional:     profileConfig.Bidirectional,
-			Reverse:           profileConfig.Reverse,
-			BufferLength:      profileConfig.BufferLength,
-			CongestionControl: profileConfig.CongestionControl,
-			MSS:               profileConfig.MSS,
-			NoDelay:           profileConfig.NoDelay,
-			TOS:               profileConfig.TOS,
-			ZeroCopy:          profileConfig.ZeroCopy,
-			OmitSeconds:       profileConfig.OmitSeconds,
-		}
-		if addErr := profileRegistry.AddProfile(profile); addErr != nil {
-			return fmt.Errorf("failed to add profile: %w", addErr)
-		}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return err
 	}
 
 	log.Printf("Loaded %d test profiles", len(cfg.Controller.TestProfiles))
 
+	filter, err := buildResultFilter(cfg, nodeRegistry)
+	if err != nil {
+		return err
+	}
+
 	// Get default profile
 	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
 	if err != nil {
 		return fmt.Errorf("failed to get default profile: %w", err)
 	}
 
+	coverage, err := loadCoverage(cfg)
+	if err != nil {
+		return err
+	}
+
+	// The topology is sized before any daemon is contacted, so that an
+	// oversized one costs nothing
+	topo, err := planTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage, plan)
+	if err != nil {
+		return err
+	}
+	if err := checkTestCount(cfg, topo, opts.yesReally); err != nil {
+		return err
+	}
+	streaming := streamResults(cfg, topo.GetTestCount())
+	if streaming {
+		if err := files.checkStream(cfg); err != nil {
+			return err
+		}
+	}
+
 	// Create client pool and connect
 	ctx := context.Background()
 	timeout := time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second
 	pool := client.NewPool(timeout)
+	pool.SetPollPolicy(cfg.Controller.Concurrency.PollPolicy())
 
 	log.Println("Connecting to daemons...")
 	nodes := nodeRegistry.GetAllNodes()
@@ -193,32 +376,40 @@ func runTest(configPath string) error {
 
 	log.Printf("Connected to %d daemons\n", pool.Count())
 
-	// Generate topology
-	log.Println("Generating test topology...")
-	topoGen := topology.NewGenerator(nodeRegistry, profileRegistry, defaultProfile)
-
-	// Apply overrides from config
-	for _, override := range cfg.Controller.Topology.Overrides {
-		// For now, simple implementation: if "nodes" is specified, apply to all pairs
-		if len(override.Nodes) >= 2 {
-			for i, src := range override.Nodes {
-				for j, dst := range override.Nodes {
-					if i != j {
-						if overrideErr := topoGen.AddOverride(src, dst, override.Profile); overrideErr != nil {
-							return fmt.Errorf("failed to add topology override: %w", overrideErr)
-						}
-					}
-				}
+	health := pool.CheckNodeHealth(ctx)
+	log.Printf("%d of %d nodes are healthy", health.Healthy, health.Total)
+	if opts.noQuorum {
+		if cfg.Controller.Quorum.Enabled() {
+			log.Println("Warning: quorum check skipped (--no-quorum)")
+		}
+	} else if cfg.Controller.Quorum.Enabled() {
+		planned := nodeRegistry.Count()
+		if nodeRegistry, err = applyQuorum(cfg, pool, nodeRegistry, health); err != nil {
+			return err
+		}
+		// The missing nodes' pairs are left out
+		if nodeRegistry.Count() < planned {
+			if topo, err = planTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage, plan); err != nil {
+				return err
 			}
 		}
 	}
 
-	topo, err := topoGen.GenerateFullMesh()
-	if err != nil {
-		return fmt.Errorf("failed to generate topology: %w", err)
-	}
+	cache := openNodeCache(cfg, opts.refreshFacts)
+	pool.SetCache(cache)
+	defer saveNodeCache(cache)
 
-	log.Printf("Generated topology: %d test pairs\n", topo.GetTestCount())
+	// A connection only fails once used; find nodes that went away before
+	// any of them is asked to change state
+	planned := nodeRegistry.Count()
+	if nodeRegistry, err = applyLiveness(ctx, cfg, pool, nodeRegistry, health.Total, opts.noQuorum); err != nil {
+		return err
+	}
+	if nodeRegistry.Count() < planned {
+		if topo, err = planTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage, plan); err != nil {
+			return err
+		}
+	}
 
 	// Execute test
 	log.Println("\nStarting test execution...")
@@ -233,55 +424,1013 @@ func runTest(configPath string) error {
 		}
 	}
 	orch := orchestrator.NewOrchestrator(pool, cfg.Controller.Output.SaveDaemonResults, cfg.Controller.Output.SaveRawResults, rawResultsDir)
-	if err := orch.ExecuteTest(ctx, topo); err != nil {
+	orch.SetFailurePolicy(orchestrator.FailurePolicy{
+		StopAll:        cfg.Controller.OnFailure.StopAllEnabled(),
+		ClearResults:   cfg.Controller.OnFailure.ClearResultsEnabled(),
+		CollectPartial: cfg.Controller.OnFailure.CollectPartialEnabled(),
+	})
+	if pr := cfg.Controller.PortRange; pr != nil {
+		orch.SetPortRange(pr.Owner, int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+	orch.SetForce(opts.force)
+	orch.SetCongestionControlPolicy(cfg.Controller.CongestionControlPolicy)
+	orch.SetMaxConcurrentTests(cfg.Controller.Concurrency.MaxConcurrentTests)
+	orch.SetProcessStopTimeout(time.Duration(cfg.Controller.Concurrency.ProcessStopSeconds) * time.Second)
+	configureStallPolicy(orch, cfg)
+	configureBlackouts(orch, cfg)
+	configureLogging(orch, cfg, opts.verbose)
+	if sampling := cfg.Controller.ResourceSampling; sampling.Enabled() {
+		orch.SetSampleInterval(units.Seconds(sampling.IntervalSeconds))
+	}
+	if events != nil {
+		orch.SetEventSink(events.Write)
+		defer func() { endProgressEvents(orch, events, runErr) }()
+	}
+
+	if debugServer := startDebugListener(cfg, pool); debugServer != nil {
+		debugServer.SetState(func() interface{} { return orch.Progress().Snapshot() })
+		defer func() {
+			if closeErr := debugServer.Close(); closeErr != nil {
+				log.Printf("Warning: failed to close debug listener: %v", closeErr)
+			}
+		}()
+	}
+
+	if planSource != nil {
+		planSource.Pairs = topo.GetTestCount()
+	}
+	if err := executeTest(ctx, orch, pool, topo, opts.useTUI); err != nil {
+		if partial := orch.PartialResults(); len(partial) > 0 {
+			writePartialResults(cfg, orch, topo, partial, pool, filter, subset, planSource, workload(expansion), files)
+		}
 		return fmt.Errorf("test execution failed: %w", err)
 	}
 
 	// Collect and aggregate results
 	log.Println("\nAggregating results...")
 	agg := aggregator.NewAggregator()
+	configureAnalysis(agg, cfg)
+	agg.SetTestGroups(topo.TestGroups())
+	agg.SetTestProfiles(topo.TestProfiles())
+	agg.SetTestFamilies(topo.TestFamilies())
+	agg.SetTestInterfaces(topo.TestInterfaces())
+	agg.SetTestHops(topo.TestHops())
+	agg.SetTestGroupPairs(topo.TestGroupPairs())
+	agg.SetTestRepetitions(topo.TestRepetitions())
+	agg.SetTestLabels(topo.TestLabels())
+	agg.SetTestDeferrals(blackoutDeferrals(topo))
+	agg.SetBackgroundTests(topo.BackgroundTests())
+	agg.SetBisection(topo.Bisection != nil)
+	agg.SetTestSources(topo.TestSources())
+	agg.SetTestDestinations(topo.TestDestinations())
+	if !opts.includeForeign {
+		agg.SetExpectedTests(topo.TestIDs())
+	}
+
+	writer := output.NewWriter(cfg.Controller.Output.JSONFile, cfg.Controller.Output.CSVFile)
+	files.apply(writer)
+	configureWriter(writer, cfg)
+	writer.SetFilter(filter)
+	var stream *output.ResultStream
+	if streaming {
+		stream, err = writer.OpenStream(cfg.Controller.Output.StreamFile)
+		if err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+		agg.SetStreaming(stream, aggregator.DefaultSampleSize)
+		log.Printf("Streaming the results of %d tests to %s", topo.GetTestCount(), writer.StreamPath())
+	}
 	if err := agg.CollectResults(ctx, pool); err != nil {
+		if stream != nil {
+			stream.Abort()
+		}
+		return fmt.Errorf("failed to collect results: %w", err)
+	}
+	if err := addBlackoutSkips(agg, orch); err != nil {
+		if stream != nil {
+			stream.Abort()
+		}
 		return fmt.Errorf("failed to collect results: %w", err)
 	}
+	if stream != nil {
+		if err := stream.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+	warnForeignResults(agg)
+	collectResourceUsage(ctx, agg, pool, cfg)
 
 	results := agg.GetResults()
 	summary := agg.GetSummary()
+	summary.ExcludedTests = topo.Excluded
+	summary.ReverseSkippedTests = topo.ReverseSkipped
+	usage := agg.ResourceUsage()
 
-	log.Printf("Collected %d results", len(results))
+	log.Printf("Collected %d results", agg.GetResultCount())
 	log.Printf("Completed: %d, Failed: %d", summary.CompletedTests, summary.FailedTests)
+	if summary.BlackoutDeferredTests > 0 || summary.BlackoutSkippedTests > 0 {
+		log.Printf("Blackout windows: %d tests deferred, %d skipped", summary.BlackoutDeferredTests, summary.BlackoutSkippedTests)
+	}
+
+	var diagnoses []*aggregator.MTUDiagnosis
+	written := results
+	if cfg.Controller.Analysis.MTUHeuristics && streaming {
+		log.Printf("Warning: skipping MTU heuristics, which need every result, for a streaming run")
+	} else if cfg.Controller.Analysis.MTUHeuristics {
+		var probes []*aggregator.TestResult
+		diagnoses, probes = probeMTU(ctx, cfg, pool, nodeRegistry, profileRegistry, defaultProfile, topo, results)
+		written = append(written[:len(written):len(written)], probes...)
+		summary.DiagnosticTests = len(probes)
+	}
 
 	// Write outputs
 	log.Println("\nWriting output files...")
-	writer := output.NewWriter(cfg.Controller.Output.JSONFile, cfg.Controller.Output.CSVFile)
-	if err := writer.WriteAll(summary, results); err != nil {
+	metadata := runMetadata(orch, pool)
+	metadata.NodeSubset = subset
+	metadata.Plan = planSource
+	metadata.PartialMesh = partialMesh(topo)
+	metadata.Bisection = bisection(topo)
+	metadata.Workload = workload(expansion)
+	metadata.Aggregation = &output.Aggregation{Mode: output.AggregationInMemory, Results: agg.GetResultCount()}
+	if streaming {
+		metadata.Aggregation.Mode = output.AggregationStreaming
+		metadata.Aggregation.StreamFile = writer.StreamPath()
+		metadata.Aggregation.Sampled = len(results)
+	}
+	partialErr := reportCollectionErrors(agg, writer, metadata)
+	writer.SetMetadata(metadata)
+	writer.SetForeignResults(agg.GetForeignResults())
+	writer.SetTimeline(agg.Timeline(), cfg.Controller.Output.TimelineCSVFile)
+	flakiness := pairFlakiness(cfg, writer, results, streaming)
+	pairRepetitions(writer, results, streaming)
+	writer.SetResourceUsage(usage)
+	writer.SetSchemaFile(cfg.Controller.Output.SchemaFile)
+	if diagnoses != nil {
+		writer.SetDiagnostics(&output.Diagnostics{MTU: diagnoses})
+	}
+	embedConfig(writer, cfg)
+	if err := writer.WriteAll(summary, written); err != nil {
 		return fmt.Errorf("failed to write output: %w", err)
 	}
 
-	if cfg.Controller.Output.JSONFile != "" {
-		log.Printf("JSON output: %s", cfg.Controller.Output.JSONFile)
-	}
-	if cfg.Controller.Output.CSVFile != "" {
-		log.Printf("CSV output: %s", cfg.Controller.Output.CSVFile)
+	logOutputPaths(writer)
+	if streaming && coverage != nil {
+		log.Printf("Warning: coverage not updated; a streaming run keeps only a sample of its results")
+	} else {
+		updateCoverage(cfg, results)
 	}
 
 	fmt.Println("\n✓ Test complete!")
 	fmt.Printf("  Total tests: %d\n", summary.TotalTests)
 	fmt.Printf("  Completed: %d\n", summary.CompletedTests)
 	fmt.Printf("  Failed: %d\n", summary.FailedTests)
+	if summary.BackgroundTests > 0 {
+		fmt.Printf("  Background (excluded from statistics): %d\n", summary.BackgroundTests)
+	}
+	if summary.DiagnosticTests > 0 {
+		fmt.Printf("  Diagnostic probes (excluded from statistics): %d\n", summary.DiagnosticTests)
+	}
+	if summary.ExcludedTests > 0 {
+		fmt.Printf("  Excluded by topology exclusions (not run): %d\n", summary.ExcludedTests)
+	}
+	if streaming {
+		fmt.Printf("  Results streamed to %s; the JSON output holds a sample of %d\n", writer.StreamPath(), len(results))
+	}
+	if summary.DiscrepantTests > 0 {
+		fmt.Printf("  Sender/receiver discrepancies: %d\n", summary.DiscrepantTests)
+	}
 	if summary.AvgThroughput > 0 {
-		fmt.Printf("  Avg throughput: %.2f Gbps\n", summary.AvgThroughput/1e9)
+		fmt.Printf("  Avg throughput: %s\n", units.FormatBitRate(summary.AvgThroughput, 2))
+	}
+	if summary.BisectionBps > 0 {
+		fmt.Printf("  Bisection bandwidth: %s over %d pairs\n", units.FormatBitRate(summary.BisectionBps, 2),
+			summary.CompletedTests)
+	}
+	if summary.MaxRetransmitsPerMB > 0 {
+		fmt.Printf("  Retransmits/MB: avg %.3f, max %.3f\n", summary.AvgRetransmitsPerMB, summary.MaxRetransmitsPerMB)
+	}
+	if summary.HighRetransmitTests > 0 {
+		fmt.Printf("  High retransmit rate: %d\n", summary.HighRetransmitTests)
+	}
+	if summary.PeakAggregateBps > 0 {
+		fmt.Printf("  Peak aggregate throughput: %s at %s\n", units.FormatBitRate(summary.PeakAggregateBps, 2),
+			time.Unix(0, int64(summary.PeakAggregateTime*1e9)).Format(time.RFC3339))
+	}
+	if summary.WindowLimitedTests+summary.LossOrCPULimitedTests > 0 {
+		fmt.Printf("  TCP limits: %d near cwnd/RTT (window-limited), %d below it (likely loss- or CPU-limited)\n",
+			summary.WindowLimitedTests, summary.LossOrCPULimitedTests)
+	}
+	if summary.SetupAvgMs > 0 {
+		fmt.Printf("  Setup time: min %.0f ms, avg %.0f ms, p95 %.0f ms\n", summary.SetupMinMs, summary.SetupAvgMs, summary.SetupP95Ms)
+	}
+	if summary.MediumConfidenceTests+summary.LowConfidenceTests > 0 {
+		fmt.Printf("  Confidence: %d high, %d medium, %d low\n", summary.HighConfidenceTests,
+			summary.MediumConfidenceTests, summary.LowConfidenceTests)
+	}
+	printRetransmitOutliers(results)
+	printLowConfidence(results)
+	printSetupOutliers(results)
+	printAddressMismatches(results)
+	printV6Lagging(results)
+	printCongestionControlMismatches(results)
+	printFlakyPairs(flakiness)
+	printMTUDiagnoses(diagnoses)
+	printStalledTests(orch.StalledTests())
+	printLingeringProcesses(orch.LingeringProcesses())
+	printHighCPUNodes(usage)
+	printSlowRPCNodes(metadata.RPCLatency)
+	if opts.rpcStats {
+		printRPCStats(metadata.RPCLatency)
+	}
+	for _, group := range topo.Groups {
+		if gs, exists := summary.Groups[group.Name]; exists {
+			fmt.Printf("  Group %s: %d/%d completed, avg %s\n", group.Name,
+				gs.CompletedTests, gs.TotalTests, units.FormatBitRate(gs.AvgThroughput, 2))
+		}
+	}
+	for _, family := range cfg.Controller.Topology.AddressFamilies {
+		if fs, exists := summary.Families[family]; exists {
+			fmt.Printf("  Family %s: %d/%d completed, avg %s\n", family,
+				fs.CompletedTests, fs.TotalTests, units.FormatBitRate(fs.AvgThroughput, 2))
+		}
+	}
+	printGroupSummaries("Group pair", summary.GroupPairs)
+	printGroupSummaries("Interface", summary.Interfaces)
+	if compatNodes := pool.CompatibilityNodes(); len(compatNodes) > 0 {
+		fmt.Printf("  Nodes in compatibility mode: %d\n", len(compatNodes))
+	}
+	if cache != nil {
+		stats := cache.Stats()
+		fmt.Printf("  Node cache: %d hits, %d misses\n", stats.Hits, stats.Misses)
 	}
 
-	return nil
+	if partialErr != nil {
+		return partialErr
+	}
+	return checkSuccessRatio(flakiness, cfg.Controller.Analysis.MinSuccessRatio)
 }
 
-func validateConfig(configPath string) error {
+// printGroupSummaries prints the summaries of tag group pairs or interfaces,
+// sorted by name, so that in-group and cross-group throughput, or that of
+// each interface, can be compared
+func printGroupSummaries(label string, summaries map[string]*aggregator.GroupSummary) {
+	names := make([]string, 0, len(summaries))
+	for name := range summaries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		gs := summaries[name]
+		fmt.Printf("  %s %s: %d/%d completed, avg %s\n", label, name,
+			gs.CompletedTests, gs.TotalTests, units.FormatBitRate(gs.AvgThroughput, 2))
+	}
+}
+
+// retransmitOutlierCount is how many tests the retransmit outlier view lists
+const retransmitOutlierCount = 5
+
+// printRetransmitOutliers lists the tests with the highest retransmit rate
+// per MB sent, so that lossy paths stand out regardless of test length
+func printRetransmitOutliers(results []*aggregator.TestResult) {
+	outliers := aggregator.TopRetransmitRates(results, retransmitOutlierCount)
+	if len(outliers) == 0 {
+		return
+	}
+
+	fmt.Println("  Highest retransmit rates:")
+	for _, result := range outliers {
+		flag := ""
+		if result.RetransmitsFlagged {
+			flag = " !"
+		}
+		fmt.Printf("    %s (%s -> %s): %.3f/MB, %d retransmits%s\n", result.TestID,
+			result.SourceNode, result.DestNode, result.RetransmitsPerMB, result.Retransmits, flag)
+	}
+}
+
+// printAddressMismatches lists the tests whose connection reached a
+// different address than the intended destination, such as through NAT
+func printAddressMismatches(results []*aggregator.TestResult) {
+	mismatches := aggregator.AddressMismatches(results)
+	if len(mismatches) == 0 {
+		return
+	}
+
+	fmt.Printf("  Address mismatches: %d\n", len(mismatches))
+	for _, result := range mismatches {
+		fmt.Printf("    %s (%s -> %s): intended %s, connected to %s from %s\n", result.TestID,
+			result.SourceNode, result.DestNode, result.IntendedAddress, result.DataRemoteAddress, result.DataLocalAddress)
+	}
+}
+
+// printV6Lagging lists the dual-stack pairs whose IPv6 throughput lags their
+// IPv4 throughput by more than the threshold
+func printV6Lagging(results []*aggregator.TestResult) {
+	lagging := aggregator.V6Lagging(results)
+	if len(lagging) == 0 {
+		return
+	}
+
+	fmt.Printf("  IPv6 lagging IPv4: %d pairs\n", len(lagging))
+	for _, result := range lagging {
+		fmt.Printf("    %s -> %s: %s over IPv6, %.0f%% below IPv4\n", result.SourceNode, result.DestNode,
+			units.FormatBitRate(result.ThroughputBps, 2), result.V6LagPercent)
+	}
+}
+
+// printSetupOutliers lists the tests whose connection setup took far longer
+// than the run's median, an early sign of SYN drops, DNS stalls or
+// overloaded accept queues on the destination
+func printSetupOutliers(results []*aggregator.TestResult) {
+	outliers := aggregator.SetupOutliers(results)
+	if len(outliers) == 0 {
+		return
+	}
+
+	fmt.Printf("  Slow connection setup: %d\n", len(outliers))
+	for _, result := range outliers {
+		fmt.Printf("    %s (%s -> %s): %.0f ms\n", result.TestID, result.SourceNode, result.DestNode, result.SetupMs)
+	}
+}
+
+// printLowConfidence lists the tests rated low confidence with why
+func printLowConfidence(results []*aggregator.TestResult) {
+	low := aggregator.LowConfidence(results)
+	if len(low) == 0 {
+		return
+	}
+
+	fmt.Printf("  Low confidence results: %d\n", len(low))
+	for _, result := range low {
+		fmt.Printf("    %s (%s -> %s): %s\n", result.TestID, result.SourceNode, result.DestNode,
+			strings.Join(result.ConfidenceReasons, "; "))
+	}
+}
+
+// printCongestionControlMismatches lists the tests whose sender used another
+// congestion control algorithm than the profile requested
+func printCongestionControlMismatches(results []*aggregator.TestResult) {
+	mismatches := aggregator.CongestionControlMismatches(results)
+	if len(mismatches) == 0 {
+		return
+	}
+
+	fmt.Printf("  Congestion control mismatches: %d\n", len(mismatches))
+	for _, result := range mismatches {
+		fmt.Printf("    %s (%s -> %s): requested %s, sender used %s\n", result.TestID,
+			result.SourceNode, result.DestNode, result.RequestedCongestionControl, result.CongestionControl)
+	}
+}
+
+// printStalledTests lists the tests the wait phase gave up on
+func printStalledTests(stalled []*orchestrator.StalledTest) {
+	if len(stalled) == 0 {
+		return
+	}
+
+	fmt.Printf("  Stalled tests: %d\n", len(stalled))
+	for _, test := range stalled {
+		action := "left running"
+		if test.Stopped {
+			action = "stopped"
+		}
+		fmt.Printf("    %s on %s: running %.0fs, %s\n", test.TestID, test.Node, test.RunningSeconds, action)
+	}
+}
+
+// printLingeringProcesses lists the nodes whose processes survived cleanup,
+// which the next run on them would refuse to start over
+func printLingeringProcesses(lingering []*orchestrator.LingeringNode) {
+	if len(lingering) == 0 {
+		return
+	}
+
+	fmt.Printf("  Processes left running after cleanup: %d nodes\n", len(lingering))
+	for _, node := range lingering {
+		pids := "PIDs unknown"
+		if len(node.PIDs) > 0 {
+			pids = fmt.Sprintf("PIDs %v", node.PIDs)
+		}
+		fmt.Printf("    %s: %d processes, %s\n", node.Node, node.Processes, pids)
+	}
+}
+
+// printHighCPUNodes lists the nodes whose CPU was above the threshold while
+// their tests ran, as their throughput may be host-limited
+func printHighCPUNodes(usage map[string]*aggregator.NodeUsage) {
+	nodes := aggregator.HighCPUNodes(usage)
+	if len(nodes) == 0 {
+		return
+	}
+
+	fmt.Printf("  High CPU during tests: %d nodes\n", len(nodes))
+	for _, nodeID := range nodes {
+		fmt.Printf("    %s: peak %.0f%%\n", nodeID, usage[nodeID].PeakTestCPUPercent)
+	}
+}
+
+// printSlowRPCNodes lists the nodes whose RPCs took a multiple of the fleet
+// median, which localizes a slow run to the nodes holding it up
+func printSlowRPCNodes(stats *client.RPCStats) {
+	if stats == nil || len(stats.SlowNodes) == 0 {
+		return
+	}
+
+	fmt.Printf("  Slow RPCs: %d nodes\n", len(stats.SlowNodes))
+	for _, slow := range stats.SlowNodes {
+		fmt.Printf("    %s %s: avg %.0f ms, fleet median %.0f ms\n", slow.NodeID, slow.Method, slow.AvgMs, slow.FleetMedianMs)
+	}
+}
+
+// printRPCStats prints the latency of daemon RPCs by method over the fleet,
+// then by node
+func printRPCStats(stats *client.RPCStats) {
+	if stats == nil {
+		return
+	}
+
+	fmt.Println("  RPC latency (ms):")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "    NODE\tMETHOD\tCALLS\tERRORS\tMIN\tAVG\tP95\tMAX")
+	printLatencyRows(w, "(all)", stats.Methods)
+	nodeIDs := make([]string, 0, len(stats.Nodes))
+	for nodeID := range stats.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		printLatencyRows(w, nodeID, stats.Nodes[nodeID])
+	}
+	if err := w.Flush(); err != nil {
+		log.Printf("Warning: failed to print RPC latency: %v", err)
+	}
+}
+
+// printLatencyRows writes a table row per method, in method order
+func printLatencyRows(w io.Writer, nodeID string, methods map[string]*client.LatencyStats) {
+	names := make([]string, 0, len(methods))
+	for method := range methods {
+		names = append(names, method)
+	}
+	sort.Strings(names)
+	for _, method := range names {
+		stats := methods[method]
+		fmt.Fprintf(w, "    %s\t%s\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\n", nodeID, method,
+			stats.Calls, stats.Errors, stats.MinMs, stats.AvgMs, stats.P95Ms, stats.MaxMs)
+	}
+}
+
+// startDebugListener starts the debug listener if enabled and publishes the
+// pool's counters. A listener that fails to start is logged, not fatal.
+func startDebugListener(cfg *config.ControllerConfig, pool *client.Pool) *debughttp.Server {
+	debugCfg := cfg.Controller.Debug
+	if !debugCfg.Enabled {
+		return nil
+	}
+
+	debugServer, err := debughttp.Start(debugCfg.Address, debugCfg.Port)
+	if err != nil {
+		log.Printf("Warning: failed to start debug listener: %v", err)
+		return nil
+	}
+	debughttp.Publish("pool", func() interface{} {
+		return map[string]interface{}{
+			"connected_nodes": pool.Count(),
+			"rpc_counts":      pool.RPCCounts(),
+			"rpc_latency":     pool.RPCStats(),
+		}
+	})
+	log.Printf("Debug listener on http://%s/debug/", debugServer.Addr())
+
+	return debugServer
+}
+
+// planTopology generates the topology of the run, or imports it from plan
+// when set, and logs its size. Planned pairs of nodes not in nodeRegistry
+// are left out.
+func planTopology(cfg *config.ControllerConfig, nodeRegistry *models.NodeRegistry, profileRegistry *models.ProfileRegistry,
+	defaultProfile *models.TestProfile, coverage *topology.Coverage, plan *topology.PlanFile) (*topology.Topology, error) {
+	var topo *topology.Topology
+	var err error
+	if plan != nil {
+		log.Println("Importing test plan...")
+		restricted, dropped := plan.Restrict(nodeRegistry)
+		if dropped > 0 {
+			log.Printf("Warning: %d planned pairs left out: their nodes are not part of the run", dropped)
+		}
+		topo, err = setup.ImportPlan(cfg, nodeRegistry, profileRegistry, defaultProfile, restricted)
+		coverage = nil
+	} else {
+		log.Println("Generating test topology...")
+		topo, err = setup.GenerateTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if coverage != nil {
+		logCoverage(coverage, nodeRegistry, topo)
+	}
+
+	log.Printf("Generated topology: %d test pairs\n", topo.GetTestCount())
+	if topo.Excluded > 0 {
+		log.Printf("  Excluded %d tests of pairs in topology exclusions", topo.Excluded)
+	}
+	if topo.ReverseSkipped > 0 {
+		log.Printf("  Undirected: skipped %d tests of pairs whose reverse is tested", topo.ReverseSkipped)
+	}
+	if chain := &cfg.Controller.Topology; chain.Type == "chain" {
+		log.Printf("  Chain: %s, %d hops tested both ways", strings.Join(chain.Order, " - "), len(chain.Order)-1)
+		if chain.IncludeEndToEnd && len(chain.Order) > 2 {
+			log.Printf("  End to end: %s -> %s", chain.Order[0], chain.Order[len(chain.Order)-1])
+		}
+	}
+	if sample := topo.Sample; sample != nil {
+		switch {
+		case sample.MaxTests > 0 && sample.FullMesh():
+			log.Printf("  Partial mesh: max_tests %d covers all %d node pairs; testing the full mesh",
+				sample.MaxTests, sample.MeshPairs)
+		case sample.MaxTests > 0:
+			log.Printf("  Partial mesh: %d of %d node pairs (max_tests %d, seed %d)",
+				sample.Pairs, sample.MeshPairs, sample.MaxTests, sample.Seed)
+		default:
+			log.Printf("  Partial mesh: %d of %d node pairs (density %g, seed %d)",
+				sample.Pairs, sample.MeshPairs, sample.Density, sample.Seed)
+		}
+	}
+	if grouping := topo.TagGroups; grouping != nil {
+		log.Printf("  Tag groups: %s pairs of %d %s groups (%s)", grouping.Mode, len(grouping.Groups),
+			grouping.Key, strings.Join(grouping.Groups, ", "))
+		if len(grouping.Ungrouped) > 0 {
+			log.Printf("Warning: nodes without a %s:<value> tag are not tested: %s",
+				grouping.Key, strings.Join(grouping.Ungrouped, ", "))
+		}
+	}
+	if repeated := len(topo.TestRepetitions()); repeated > 0 {
+		log.Printf("  Repeated pairs: %d tests are repetitions of pairs", repeated)
+	}
+	if topo.Rounds > 0 {
+		log.Printf("  Schedule: %d rounds, each node in at most one pair per round", topo.Rounds)
+	}
+	if split := topo.Bisection; split != nil {
+		log.Printf("  Bisection: side_a of %d nodes, side_b of %d", len(split.SideA), len(split.SideB))
+		if len(split.Unpaired) > 0 {
+			log.Printf("Warning: bisection sides are unbalanced; not testing %s", strings.Join(split.Unpaired, ", "))
+		}
+	}
+	for _, family := range cfg.Controller.Topology.AddressFamilies {
+		if skipped := topo.FamilySkips[models.AddressFamily(family)]; skipped > 0 {
+			log.Printf("  Skipped %d %s pairs: a node has no %s data address", skipped, family, family)
+		}
+	}
+	if cfg.Controller.Topology.PerInterface {
+		log.Printf("  Per interface: %d tests over matching interfaces", len(topo.TestInterfaces()))
+		names := make([]string, 0, len(topo.InterfaceSkips))
+		for name := range topo.InterfaceSkips {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			log.Printf("  Skipped %d pairs over %s: only one of their nodes has it", topo.InterfaceSkips[name], name)
+		}
+	}
+	logConcurrency(cfg, topo)
+	logBlackouts(cfg, topo)
+	if background := topo.GetTestCount() - topo.GetMeasuredCount(); background > 0 {
+		log.Printf("  Measured pairs: %d", topo.GetMeasuredCount())
+		log.Printf("  Background pairs: %d (unmeasured load for the whole run)", background)
+		for _, pair := range topo.Pairs {
+			if pair.Background {
+				log.Printf("    %s -> %s (%ds)", pair.Source.ID, pair.Destination.ID, pair.ScheduleSeconds())
+			}
+		}
+	}
+	return topo, nil
+}
+
+// partialMesh returns the record of topo's partial mesh selection for the
+// output metadata, or nil if it is not a partial mesh
+func partialMesh(topo *topology.Topology) *output.PartialMesh {
+	if topo.Sample == nil {
+		return nil
+	}
+	mesh := &output.PartialMesh{
+		Density:   topo.Sample.Density,
+		MaxTests:  topo.Sample.MaxTests,
+		Seed:      topo.Sample.Seed,
+		Pairs:     topo.Sample.Pairs,
+		MeshPairs: topo.Sample.MeshPairs,
+	}
+	for _, pair := range topo.Sample.Selected {
+		mesh.Selected = append(mesh.Selected, output.MeshPair{SourceNode: pair[0], DestNode: pair[1]})
+	}
+	return mesh
+}
+
+// bisection returns the sides of a bisection topology for the run metadata,
+// or nil for other topologies
+func bisection(topo *topology.Topology) *output.Bisection {
+	if topo.Bisection == nil {
+		return nil
+	}
+	return &output.Bisection{
+		SideA:    topo.Bisection.SideA,
+		SideB:    topo.Bisection.SideB,
+		Unpaired: topo.Bisection.Unpaired,
+	}
+}
+
+// logConcurrency logs how many of the topology's client processes run at
+// once under max_concurrent_tests, which counts bidirectional ones twice
+func logConcurrency(cfg *config.ControllerConfig, topo *topology.Topology) {
+	limit := cfg.Controller.Concurrency.MaxConcurrentTests
+	clients := topo.GetClientCount()
+	weight := topo.GetClientWeight()
+	if limit <= 0 || weight <= limit {
+		log.Printf("  Effective concurrency: all %d client processes at once", clients)
+		return
+	}
+	if weight > clients {
+		log.Printf("  Effective concurrency: %d of %d client process weight at once, bidirectional processes counted twice (max_concurrent_tests); the rest start as earlier tests finish",
+			limit, weight)
+		return
+	}
+	log.Printf("  Effective concurrency: %d of %d client processes at once (max_concurrent_tests); the rest start as earlier tests finish",
+		limit, clients)
+}
+
+// selectNodes restricts cfg to the nodes in subset, logging the overrides
+// and groups changed as a result. It returns the subset to record in the run
+// metadata, or nil when every node is used.
+func selectNodes(cfg *config.ControllerConfig, subset config.NodeSubset) (*output.NodeSubset, error) {
+	if subset.IsEmpty() {
+		return nil, nil
+	}
+
+	configured := len(cfg.Controller.Nodes)
+	warnings, err := cfg.SelectNodes(subset)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node selection: %w", err)
+	}
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	selected := &output.NodeSubset{
+		Selection:       subset.String(),
+		ConfiguredNodes: configured,
+	}
+	for _, node := range cfg.Controller.Nodes {
+		id := node.ID
+		if id == "" {
+			id = node.Hostname
+		}
+		selected.Nodes = append(selected.Nodes, id)
+	}
+	log.Printf("Running on %d of %d configured nodes (%s)", len(selected.Nodes), configured, selected.Selection)
+	return selected, nil
+}
+
+// runMetadata describes the run for the JSON output
+func runMetadata(orch *orchestrator.Orchestrator, pool *client.Pool) *output.RunMetadata {
+	metadata := &output.RunMetadata{
+		RunID:              orch.RunID(),
+		CompatibilityNodes: pool.CompatibilityNodes(),
+		NodeFacts:          orch.NodeFacts(),
+		NodeHealth:         pool.NodeHealth(),
+		Liveness:           pool.Liveness(),
+		RPCLatency:         pool.RPCStats(),
+	}
+	if simulated := pool.SimulatedNodes(); len(simulated) > 0 {
+		metadata.SimulatedNodes = simulated
+	}
+	metadata.StalledTests = orch.StalledTests()
+	metadata.LingeringProcesses = orch.LingeringProcesses()
+	if stats := pool.PollStats(); stats.Polls > 0 {
+		metadata.Polling = &stats
+	}
+	if pool.Cache() != nil {
+		stats := pool.Cache().Stats()
+		metadata.NodeCache = &stats
+	}
+	return metadata
+}
+
+// openNodeCache loads the node cache unless it is disabled, in which case it
+// returns nil and every node is queried
+func openNodeCache(cfg *config.ControllerConfig, refresh bool) *client.NodeCache {
+	if cfg.Controller.NodeCache.Disabled {
+		return nil
+	}
+
+	cache := client.LoadNodeCache(cfg.Controller.NodeCache.File,
+		time.Duration(cfg.Controller.NodeCache.TTLSeconds)*time.Second)
+	cache.SetRefresh(refresh)
+	return cache
+}
+
+// buildResultFilter parses the configured output filters, returning a nil
+// filter, which keeps every result, if none are configured
+func buildResultFilter(cfg *config.ControllerConfig, nodes *models.NodeRegistry) (*output.Filter, error) {
+	filters := cfg.Controller.Output.Filters
+	if filters == nil {
+		return nil, nil
+	}
+	filter, err := output.NewFilter(filters.Include, filters.Exclude, nodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output filters: %w", err)
+	}
+	return filter, nil
+}
+
+// configureLogging applies the logging settings to orch; verbose overrides
+// the configured verbosity when set
+func configureLogging(orch *orchestrator.Orchestrator, cfg *config.ControllerConfig, verbose bool) {
+	orch.SetVerbose(verbose || cfg.Controller.Logging.Verbose)
+	orch.SetProgressLogInterval(time.Duration(cfg.Controller.Logging.ProgressIntervalSeconds) * time.Second)
+}
+
+// configureStallPolicy applies the stall settings to orch; a negative
+// window keeps the fixed wait
+func configureStallPolicy(orch *orchestrator.Orchestrator, cfg *config.ControllerConfig) {
+	window := time.Duration(max(cfg.Controller.Concurrency.StallWindowSeconds, 0)) * time.Second
+	orch.SetStallPolicy(window, cfg.Controller.Concurrency.StopStalledTests)
+}
+
+// configureAnalysis applies the analysis thresholds and confidence rules to
+// agg
+func configureAnalysis(agg *aggregator.Aggregator, cfg *config.ControllerConfig) {
+	analysis := cfg.Controller.Analysis
+	agg.SetDiscrepancyThreshold(analysis.DiscrepancyThreshold())
+	agg.SetRetransmitThreshold(analysis.RetransmitThresholdPerMB)
+	agg.SetV6LagThreshold(analysis.V6LagThresholdPercent)
+	agg.SetTimelineBucket(units.Seconds(analysis.TimelineBucketSeconds))
+	agg.SetConfidencePolicy(confidencePolicy(cfg))
+}
+
+// configureWriter applies the output settings shared by every results file
+// to writer
+func configureWriter(writer *output.Writer, cfg *config.ControllerConfig) {
+	writer.SetBurstRows(cfg.Controller.Output.BurstRows)
+}
+
+// confidencePolicy returns the configured confidence rules, with defaults
+// set, for the aggregator
+func confidencePolicy(cfg *config.ControllerConfig) aggregator.ConfidencePolicy {
+	confidence := cfg.Controller.Analysis.Confidence
+	policy := aggregator.ConfidencePolicy{
+		Disabled:   make(map[string]bool, len(confidence.DisabledRules)),
+		CPUPercent: confidence.CPUPercent,
+		MinOverlap: confidence.MinOverlap,
+	}
+	for _, rule := range confidence.DisabledRules {
+		policy.Disabled[rule] = true
+	}
+	return policy
+}
+
+// collectResourceUsage retrieves the daemons' resource samples when sampling
+// is enabled. Nodes whose samples cannot be retrieved are only logged.
+func collectResourceUsage(ctx context.Context, agg *aggregator.Aggregator, pool *client.Pool, cfg *config.ControllerConfig) {
+	sampling := cfg.Controller.ResourceSampling
+	if !sampling.Enabled() {
+		return
+	}
+	agg.SetCPUThreshold(sampling.CPUThresholdPercent)
+	if err := agg.CollectSamples(ctx, pool); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// warnForeignResults logs a warning naming the nodes that returned results
+// for test IDs outside the run's topology
+func warnForeignResults(agg *aggregator.Aggregator) {
+	foreign := agg.GetForeignResults()
+	if foreign == nil {
+		return
+	}
+
+	nodes := make([]string, 0, len(foreign.ByNode))
+	for nodeID, count := range foreign.ByNode {
+		nodes = append(nodes, fmt.Sprintf("%s (%d)", nodeID, count))
+	}
+	sort.Strings(nodes)
+
+	log.Printf("WARNING: %d results for test IDs outside this run's topology were returned by %s",
+		foreign.Count, strings.Join(nodes, ", "))
+	log.Printf("WARNING: they are excluded from the summary and written to foreign_results; use --include-foreign to count them")
+}
+
+// reportCollectionErrors logs the nodes whose results could not be collected,
+// adds them to the writer's output and marks the run as partial. It returns
+// an error wrapping errPartialResults if there were any.
+func reportCollectionErrors(agg *aggregator.Aggregator, writer *output.Writer, metadata *output.RunMetadata) error {
+	collectionErrors := agg.GetCollectionErrors()
+	if len(collectionErrors) == 0 {
+		return nil
+	}
+
+	missing := 0
+	for _, collectionErr := range collectionErrors {
+		missing += collectionErr.MissingResults
+		log.Printf("Warning: failed to collect results from node %s (%d results missing): %s",
+			collectionErr.Node, collectionErr.MissingResults, collectionErr.Error)
+	}
+	writer.SetCollectionErrors(collectionErrors)
+	metadata.Partial = true
+
+	return fmt.Errorf("%w: results of %d nodes (%d tests) could not be collected; retry with \"collect\"",
+		errPartialResults, len(collectionErrors), missing)
+}
+
+// embedConfig adds the redacted effective configuration to the writer's JSON
+// output when output.embed_config is set
+func embedConfig(writer *output.Writer, cfg *config.ControllerConfig) {
+	if !cfg.Controller.Output.EmbedConfig {
+		return
+	}
+	values, err := cfg.Redacted().ToMap()
+	if err != nil {
+		log.Printf("Warning: failed to embed config in output: %v", err)
+		return
+	}
+	writer.SetConfig(values)
+}
+
+// saveNodeCache writes the node cache back, logging rather than failing
+func saveNodeCache(cache *client.NodeCache) {
+	if err := cache.Save(); err != nil {
+		log.Printf("Warning: failed to save node cache: %v", err)
+	}
+}
+
+// writePartialResults aggregates the results collected after a failed run
+// and writes them to the configured partial results file
+func writePartialResults(cfg *config.ControllerConfig, orch *orchestrator.Orchestrator, topo *topology.Topology,
+	partial []*pb.TestResult, pool *client.Pool, filter *output.Filter, subset *output.NodeSubset, plan *output.PlanSource,
+	workload *output.Workload, files outputFiles) {
+	agg := aggregator.NewAggregator()
+	configureAnalysis(agg, cfg)
+	agg.SetTestGroups(topo.TestGroups())
+	agg.SetTestProfiles(topo.TestProfiles())
+	agg.SetTestFamilies(topo.TestFamilies())
+	agg.SetTestInterfaces(topo.TestInterfaces())
+	agg.SetTestHops(topo.TestHops())
+	agg.SetTestGroupPairs(topo.TestGroupPairs())
+	agg.SetTestRepetitions(topo.TestRepetitions())
+	agg.SetTestLabels(topo.TestLabels())
+	agg.SetBackgroundTests(topo.BackgroundTests())
+	agg.SetBisection(topo.Bisection != nil)
+	if err := agg.AddResults(partial); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	writer := output.NewWriter(cfg.Controller.OnFailure.PartialResultsFile, "")
+	files.apply(writer)
+	configureWriter(writer, cfg)
+	metadata := runMetadata(orch, pool)
+	metadata.NodeSubset = subset
+	metadata.Plan = plan
+	metadata.PartialMesh = partialMesh(topo)
+	metadata.Bisection = bisection(topo)
+	metadata.Workload = workload
+	metadata.Partial = true
+	metadata.FailedPhase = orch.FailedPhase()
+	writer.SetTimeline(agg.Timeline(), "")
+	if failedNodes := orch.FailedNodes(); failedNodes != nil {
+		metadata.FailedNodes = failedNodes.Errors
+	}
+	writer.SetMetadata(metadata)
+	writer.SetFilter(filter)
+	embedConfig(writer, cfg)
+	summary := agg.GetSummary()
+	summary.ExcludedTests = topo.Excluded
+	summary.ReverseSkippedTests = topo.ReverseSkipped
+	if err := writer.WriteAll(summary, agg.GetResults()); err != nil {
+		log.Printf("Warning: failed to write partial results: %v", err)
+		return
+	}
+
+	log.Printf("Partial results (%d tests) written to %s", len(agg.GetResults()), writer.JSONPath())
+}
+
+// outputFiles is how a run names its output files
+type outputFiles struct {
+	overwrite bool
+	suffix    string // Inserted before the extension of every file, or ""
+}
+
+// newOutputFiles returns the output file naming of a run starting now
+func newOutputFiles(cfg *config.ControllerConfig, overwrite bool) outputFiles {
+	files := outputFiles{overwrite: overwrite}
+	if cfg.Controller.Output.TimestampSuffix {
+		files.suffix = output.RunSuffix(time.Now())
+	}
+	return files
+}
+
+// check refuses a run whose output files already exist and would be
+// neither replaced nor renamed, before any test is run
+func (f outputFiles) check(cfg *config.ControllerConfig) error {
+	if f.overwrite || f.suffix != "" {
+		return nil
+	}
+	out := cfg.Controller.Output
+	schemaFile := ""
+	if out.JSONFile != "" {
+		schemaFile = out.SchemaFile
+	}
+	return output.CheckOutputFiles(out.JSONFile, out.CSVFile, out.TimelineCSVFile, out.FlakinessCSVFile, schemaFile)
+}
+
+// checkStream refuses a streaming run whose stream file already exists,
+// like check does for the other output files
+func (f outputFiles) checkStream(cfg *config.ControllerConfig) error {
+	if f.overwrite || f.suffix != "" {
+		return nil
+	}
+	return output.CheckOutputFiles(cfg.Controller.Output.StreamFile)
+}
+
+// streamResults reports whether a run of testCount tests streams its
+// results rather than keep them in memory
+func streamResults(cfg *config.ControllerConfig, testCount int) bool {
+	threshold := cfg.Controller.Output.StreamingThreshold
+	return threshold > 0 && testCount > threshold
+}
+
+// apply sets the naming on writer
+func (f outputFiles) apply(writer *output.Writer) {
+	writer.SetOverwrite(f.overwrite)
+	writer.SetFileSuffix(f.suffix)
+}
+
+// logOutputPaths logs the files writer wrote
+func logOutputPaths(writer *output.Writer) {
+	if path := writer.JSONPath(); path != "" {
+		log.Printf("JSON output: %s", path)
+	}
+	if path := writer.CSVPath(); path != "" {
+		log.Printf("CSV output: %s", path)
+	}
+	if path := writer.StreamPath(); path != "" {
+		log.Printf("Streamed results: %s", path)
+	}
+	if path := writer.TimelineCSVPath(); path != "" {
+		log.Printf("Timeline CSV output: %s", path)
+	}
+	if path := writer.FlakinessCSVPath(); path != "" {
+		log.Printf("Flakiness CSV output: %s", path)
+	}
+	if path := writer.SchemaPath(); path != "" {
+		log.Printf("JSON Schema: %s", path)
+	}
+}
+
+// executeTest runs the orchestrator, driving the dashboard when requested and
+// a terminal is available
+func executeTest(ctx context.Context, orch *orchestrator.Orchestrator, pool *client.Pool, topo *topology.Topology, useTUI bool) error {
+	if !useTUI {
+		return orch.ExecuteTest(ctx, topo)
+	}
+	if !tui.IsTerminal() {
+		log.Println("Not running in a terminal; --tui disabled, using log output")
+		return orch.ExecuteTest(ctx, topo)
+	}
+
+	runCtx, abort := context.WithCancel(ctx)
+	defer abort()
+
+	dashCtx, stopDash := context.WithCancel(ctx)
+	dash := tui.New(orch, pool, abort)
+
+	log.SetOutput(dash.LogWriter())
+	done := make(chan error, 1)
+	go func() {
+		done <- dash.Run(dashCtx)
+	}()
+
+	err := orch.ExecuteTest(runCtx, topo)
+
+	stopDash()
+	dashErr := <-done
+	log.SetOutput(os.Stderr)
+	if dashErr != nil {
+		log.Printf("Warning: dashboard failed: %v", dashErr)
+	}
+
+	return err
+}
+
+func validateConfig(configPath string, strictProfiles bool, workload string, deep bool) error {
 	fmt.Printf("Validating configuration: %s\n", configPath)
 
-	cfg, err := config.LoadControllerConfig(configPath)
+	cfg, expansion, err := config.LoadControllerConfigWorkload(configPath, workload)
 	if err != nil {
 		return fmt.Errorf("❌ Configuration invalid: %w", err)
 	}
+	if expansion != nil {
+		printWorkload(expansion)
+	}
 
 	cfg.SetDefaults()
 
@@ -295,32 +1444,123 @@ func validateConfig(configPath string) error {
 	fmt.Printf("  Profiles: %d\n", len(cfg.Controller.TestProfiles))
 	fmt.Printf("  Default profile: %s\n", cfg.Controller.Topology.DefaultProfile)
 	fmt.Printf("  Topology type: %s\n", cfg.Controller.Topology.Type)
+	if file := cfg.Controller.Topology.SourceFile(); file != "" {
+		fmt.Printf("  Topology file: %s\n", file)
+	}
+	// Tags are not resolved here; only the filter syntax is checked
+	if _, err := buildResultFilter(cfg, nil); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	for _, warning := range cfg.Warnings() {
+		fmt.Printf("⚠ %s\n", warning)
+	}
+	err = lintProfiles(cfg, strictProfiles, func(finding string) {
+		fmt.Printf("⚠ %s\n", finding)
+	})
+	if err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if err := checkDaemonPorts(cfg); err != nil {
+		return fmt.Errorf("❌ %w", err)
+	}
+	if cfg.Controller.Topology.Type == "one_to_many" {
+		if err := checkSourceCapacity(cfg); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+	}
+	if deep {
+		if err := checkNodeCapacity(cfg); err != nil {
+			return fmt.Errorf("❌ %w", err)
+		}
+	}
+
+	return nil
+}
+
+// checkNodeCapacity checks the topology against the process slots and
+// server ports every daemon reports free, without initializing them
+func checkNodeCapacity(cfg *config.ControllerConfig) error {
+	nodeRegistry, topo, err := buildTopology(cfg)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	pool := client.NewPool(timeout)
+	defer func() {
+		_ = pool.Close()
+	}()
+	nodes := nodeRegistry.GetAllNodes()
+	if err := pool.ConnectAll(ctx, nodes); err != nil {
+		return err
+	}
+
+	orch := orchestrator.NewOrchestrator(pool, false, false, "")
+	if err := orch.CheckCapacity(ctx, topo); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Daemons have the process slots and server ports the topology needs on %d nodes\n", len(nodes))
+	return nil
+}
+
+// checkDaemonPorts checks the topology's server ports per node against the
+// daemon port ranges mirrored in the configuration, without contacting the
+// daemons. Nodes without a mirrored range are not checked.
+func checkDaemonPorts(cfg *config.ControllerConfig) error {
+	capacity := make(map[string]topology.PortCapacity)
+	for _, node := range cfg.Controller.Nodes {
+		if pr := cfg.NodeDaemonPortRange(node.ID); pr != nil {
+			capacity[node.ID] = topology.PortCapacity{
+				Start:     int32(pr.Start), // #nosec G115 -- Ports are validated to be in valid range
+				End:       int32(pr.End),   // #nosec G115 -- Ports are validated to be in valid range
+				Total:     pr.Size(),
+				Available: pr.Size(),
+			}
+		}
+	}
+	if len(capacity) == 0 {
+		return nil
+	}
+
+	_, topo, err := buildTopology(cfg)
+	if err != nil {
+		return err
+	}
+	if err := topo.CheckPortCapacity(capacity); err != nil {
+		return err
+	}
 
+	fmt.Printf("✓ Daemon port ranges fit the topology's server ports on %d nodes\n", len(capacity))
 	return nil
 }
 
-func checkStatus(configPath string) error {
-	fmt.Printf("Checking node status from: %s\n\n", configPath)
+// printNodeAddresses prints the control-plane address the controller uses
+// and the data-plane address iperf3 traffic is sent to
+func printNodeAddresses(node *models.Node) {
+	fmt.Printf("  Control address: %s\n", node.Address())
+	fmt.Printf("  Data address: %s\n", node.DataAddress())
+}
+
+func checkStatus(opts statusOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	fmt.Printf("Checking node status from: %s\n\n", opts.configPath)
 
 	// Load configuration
-	cfg, err := config.LoadControllerConfig(configPath)
+	cfg, err := config.LoadControllerConfig(opts.configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	cfg.SetDefaults()
 
-	// Build node registry
-	nodeRegistry := models.NewNodeRegistry()
-	for _, nodeConfig := range cfg.Controller.Nodes {
-		node := &models.Node{
-			ID:       nodeConfig.ID,
-			Hostname: nodeConfig.Hostname,
-			IP:       nodeConfig.IP,
-			Port:     nodeConfig.Port,
-		}
-		if addErr := nodeRegistry.AddNode(node); addErr != nil {
-			return fmt.Errorf("failed to add node: %w", addErr)
-		}
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
 	}
 
 	// Create client pool and connect
@@ -352,6 +1592,7 @@ func checkStatus(configPath string) error {
 		status, exists := statuses[node.ID]
 		if !exists {
 			fmt.Printf("%-20s  %s\n", node.ID, "❌ OFFLINE")
+			printNodeAddresses(node)
 			continue
 		}
 
@@ -359,17 +1600,55 @@ func checkStatus(configPath string) error {
 		if !status.Healthy {
 			healthSymbol = "❌"
 		}
+		// Maintenance is deliberate, so it is shown apart from unhealthy
+		state := "ONLINE"
+		if status.Maintenance {
+			healthSymbol = "🔧"
+			state = "MAINTENANCE"
+		}
+		if status.Simulated {
+			state += " (SIMULATED, results are synthetic)"
+		}
+		if status.IperfError != "" {
+			healthSymbol = "❌"
+			state += ", iperf3 NOT FOUND"
+		}
 
-		fmt.Printf("%-20s  %s ONLINE\n", node.ID, healthSymbol)
+		fmt.Printf("%-20s  %s %s\n", node.ID, healthSymbol, state)
+		printNodeAddresses(node)
+		if status.IperfError != "" {
+			fmt.Printf("  iperf3: %s\n", status.IperfError)
+		}
+		if status.Maintenance {
+			fmt.Printf("  Maintenance since: %s\n",
+				time.Unix(status.MaintenanceSinceUnix, 0).Format(time.RFC3339))
+			if status.MaintenanceReason != "" {
+				fmt.Printf("  Maintenance reason: %s\n", status.MaintenanceReason)
+			}
+		}
 		fmt.Printf("  Running processes: %d\n", status.RunningProcesses)
 		fmt.Printf("  Completed tests: %d\n", status.CompletedTests)
 		fmt.Printf("  Failed tests: %d\n", status.FailedTests)
+		fmt.Printf("  Stored results: %d (%d bytes)\n", status.StoredResults, status.StoredResultBytes)
+		if status.ResultsEvicted+status.ResultsRejected > 0 {
+			fmt.Printf("  Results dropped at the storage caps: %d evicted, %d rejected\n",
+				status.ResultsEvicted, status.ResultsRejected)
+		}
 		fmt.Printf("  Available capacity: %d/%d\n",
 			status.CurrentCapacity.AvailableProcesses,
 			status.CurrentCapacity.MaxProcesses)
 		fmt.Printf("  Uptime: %d seconds\n", status.UptimeSeconds)
+		for _, reservation := range status.PortReservations {
+			fmt.Printf("  Port range %d-%d: %s (%d ports requested)\n",
+				reservation.Range.GetStart(), reservation.Range.GetEnd(),
+				reservation.Owner, reservation.RequestedPorts)
+		}
 		fmt.Println()
 	}
 
+	if opts.probeData {
+		probeData(ctx, cfg, pool, nodes, statuses, opts.probe)
+	}
+
 	return nil
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// maxMTUProbePairs is how many suspect pairs the MTU heuristics probe at
+// most, lowest throughput first
+const maxMTUProbePairs = 10
+
+// probeMTU runs the MTU heuristics after a run: the low-throughput TCP
+// pairs of results are probed once per configured MSS, one MSS after the
+// other, within the probe budget. It returns a diagnosis per probed pair
+// and the probe results, marked as diagnostic. Failures are logged, and
+// leave the run's results alone.
+func probeMTU(ctx context.Context, cfg *config.ControllerConfig, pool *client.Pool, nodeRegistry *models.NodeRegistry,
+	profileRegistry *models.ProfileRegistry, defaultProfile *models.TestProfile, topo *topology.Topology,
+	results []*aggregator.TestResult) ([]*aggregator.MTUDiagnosis, []*aggregator.TestResult) {
+	analysis := cfg.Controller.Analysis
+	pairs := make(map[string]*topology.TestPair, len(topo.Pairs))
+	tcp := make(map[string]bool, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs[pair.TestID] = pair
+		tcp[pair.TestID] = pair.Profile.Protocol != models.ProtocolUDP
+	}
+	suspects := aggregator.MTUSuspects(results, tcp, maxMTUProbePairs)
+	if len(suspects) == 0 {
+		log.Println("MTU heuristics: no low-throughput TCP pairs to probe")
+		return []*aggregator.MTUDiagnosis{}, nil
+	}
+
+	// Each MSS is a group, run after the previous one
+	probeSeconds := int(math.Ceil(analysis.MTUProbeSeconds))
+	estimate := time.Duration(len(analysis.MTUProbeMSS)*(probeSeconds+topology.GroupOverheadSeconds)+
+		topology.GroupOverheadSeconds) * time.Second
+	budget := time.Duration(analysis.MTUProbeBudgetSeconds) * time.Second
+	if budget > 0 && estimate > budget {
+		log.Printf("Warning: MTU probes of %d pairs need about %s, over mtu_probe_budget_seconds (%d); skipped",
+			len(suspects), estimate, analysis.MTUProbeBudgetSeconds)
+		return nil, nil
+	}
+
+	plan := &topology.PlanFile{Version: topology.PlanVersion}
+	probeOf := make(map[string]*aggregator.TestResult) // probe test ID -> suspect
+	msses := make(map[string]int)                      // probe test ID -> MSS
+	noReverse := false
+	previous := ""
+	for _, mss := range analysis.MTUProbeMSS {
+		group := topology.PlannedGroup{Name: fmt.Sprintf("mtu-probe-%d", mss)}
+		if previous != "" {
+			group.After = []string{previous}
+		}
+		previous = group.Name
+		plan.Groups = append(plan.Groups, group)
+
+		for _, suspect := range suspects {
+			pair := pairs[suspect.TestID]
+			testID := fmt.Sprintf("mtu%d-%s", mss, suspect.TestID)
+			plan.Pairs = append(plan.Pairs, topology.PlannedPair{
+				TestID:      testID,
+				Source:      pair.Source.ID,
+				Destination: pair.Destination.ID,
+				Profile:     pair.Profile.Name,
+				// One stream from the client at the probed MSS
+				Params:          &topology.PlanParams{MSS: mss, Parallel: 1, Reverse: &noReverse, Bidirectional: &noReverse},
+				DurationSeconds: analysis.MTUProbeSeconds,
+				Family:          string(pair.Family),
+				Group:           group.Name,
+			})
+			probeOf[testID] = suspect
+			msses[testID] = mss
+		}
+	}
+	probeTopo, err := setup.ImportPlan(cfg, nodeRegistry, profileRegistry, defaultProfile, plan)
+	if err != nil {
+		log.Printf("Warning: MTU probes skipped: %v", err)
+		return nil, nil
+	}
+
+	log.Printf("\nMTU heuristics: probing %d low-throughput TCP pairs at MSS %v", len(suspects), analysis.MTUProbeMSS)
+	probeCtx := ctx
+	if budget > 0 {
+		var cancel context.CancelFunc
+		probeCtx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	// Probe results are dropped on failure since the run's are already kept
+	orch := orchestrator.NewOrchestrator(pool, false, false, "")
+	orch.SetFailurePolicy(orchestrator.FailurePolicy{StopAll: true, ClearResults: true})
+	orch.SetCongestionControlPolicy(cfg.Controller.CongestionControlPolicy)
+	orch.SetProcessStopTimeout(time.Duration(cfg.Controller.Concurrency.ProcessStopSeconds) * time.Second)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		orch.SetPortRange(pr.Owner, int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+	if err := orch.ExecuteTest(probeCtx, probeTopo); err != nil {
+		log.Printf("Warning: MTU probes failed: %v", err)
+		return nil, nil
+	}
+
+	agg := aggregator.NewAggregator()
+	agg.SetRetransmitThreshold(analysis.RetransmitThresholdPerMB)
+	agg.SetTestGroups(probeTopo.TestGroups())
+	agg.SetTestProfiles(probeTopo.TestProfiles())
+	agg.SetTestSources(probeTopo.TestSources())
+	agg.SetTestDestinations(probeTopo.TestDestinations())
+	agg.SetExpectedTests(probeTopo.TestIDs())
+	if err := agg.CollectResults(ctx, pool); err != nil {
+		log.Printf("Warning: failed to collect MTU probe results: %v", err)
+		return nil, nil
+	}
+
+	probes := agg.GetResults()
+	bySuspect := make(map[string]map[int]*aggregator.TestResult)
+	for _, probe := range probes {
+		probe.Diagnostic = true
+		suspect := probeOf[probe.TestID]
+		if bySuspect[suspect.TestID] == nil {
+			bySuspect[suspect.TestID] = make(map[int]*aggregator.TestResult)
+		}
+		bySuspect[suspect.TestID][msses[probe.TestID]] = probe
+	}
+	diagnoses := make([]*aggregator.MTUDiagnosis, 0, len(suspects))
+	for _, suspect := range suspects {
+		if probed := bySuspect[suspect.TestID]; len(probed) > 0 {
+			diagnoses = append(diagnoses, aggregator.DiagnoseMTU(suspect, probed))
+		}
+	}
+	return diagnoses, probes
+}
+
+// printMTUDiagnoses lists the pairs the MTU heuristics suspect of an MTU
+// issue
+func printMTUDiagnoses(diagnoses []*aggregator.MTUDiagnosis) {
+	suspected := make([]*aggregator.MTUDiagnosis, 0)
+	for _, diagnosis := range diagnoses {
+		if diagnosis.Suspected {
+			suspected = append(suspected, diagnosis)
+		}
+	}
+	if len(diagnoses) == 0 {
+		return
+	}
+
+	fmt.Printf("  MTU heuristics: %d of %d probed pairs suspected\n", len(suspected), len(diagnoses))
+	for _, diagnosis := range suspected {
+		fmt.Printf("    %s\n", diagnosis.Summary)
+	}
+}
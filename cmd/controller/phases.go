@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// phaseSession holds what the phase subcommands share: the configuration,
+// its registries, a connected pool and an orchestrator over it
+type phaseSession struct {
+	cfg      *config.ControllerConfig
+	nodes    *models.NodeRegistry
+	profiles *models.ProfileRegistry
+	pool     *client.Pool
+	cache    *client.NodeCache
+	orch     *orchestrator.Orchestrator
+}
+
+// openPhaseSession loads the configuration and connects to every node in it
+func openPhaseSession(ctx context.Context, configPath string) (*phaseSession, error) {
+	cfg, err := config.LoadControllerConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDefaults()
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := client.NewPool(time.Duration(cfg.Controller.Concurrency.ConnectionTimeout) * time.Second)
+	pool.SetPollPolicy(cfg.Controller.Concurrency.PollPolicy())
+	if connErr := pool.ConnectAll(ctx, nodeRegistry.GetAllNodes()); connErr != nil {
+		return nil, fmt.Errorf("failed to connect to daemons: %w", connErr)
+	}
+
+	cache := openNodeCache(cfg, false)
+	pool.SetCache(cache)
+
+	orch := orchestrator.NewOrchestrator(pool, cfg.Controller.Output.SaveDaemonResults, false, "")
+	orch.SetCongestionControlPolicy(cfg.Controller.CongestionControlPolicy)
+	orch.SetMaxConcurrentTests(cfg.Controller.Concurrency.MaxConcurrentTests)
+	orch.SetProcessStopTimeout(time.Duration(cfg.Controller.Concurrency.ProcessStopSeconds) * time.Second)
+	configureStallPolicy(orch, cfg)
+	configureBlackouts(orch, cfg)
+	configureLogging(orch, cfg, false)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		orch.SetPortRange(pr.Owner, int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+
+	return &phaseSession{
+		cfg:      cfg,
+		nodes:    nodeRegistry,
+		profiles: profileRegistry,
+		pool:     pool,
+		cache:    cache,
+		orch:     orch,
+	}, nil
+}
+
+// Close saves the node cache and closes the daemon connections
+func (s *phaseSession) Close() {
+	saveNodeCache(s.cache)
+	if err := s.pool.Close(); err != nil {
+		log.Printf("Warning: failed to close connection pool: %v", err)
+	}
+}
+
+func newServersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "servers",
+		Short: "Start or stop iperf3 servers without running clients",
+	}
+
+	cmd.AddCommand(newServersStartCommand())
+	cmd.AddCommand(newServersStopCommand())
+
+	return cmd
+}
+
+func newServersStartCommand() *cobra.Command {
+	var configPath string
+	var pairsFile string
+	var force bool
+	var yesReally bool
+
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Prepare the nodes and start the servers of the configured topology",
+		Long: `servers start initializes and prepares every node, starts the iperf3
+servers of the configured topology and writes the planned pairs, with their
+server ports, to a pairs file for "clients start". Servers keep running
+until "servers stop".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return startServers(configPath, pairsFile, force, yesReally)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringVar(&pairsFile, "pairs-out", "pairs.json",
+		"file to write the planned pairs and server ports to")
+	cmd.Flags().BoolVar(&force, "force", false,
+		"stop processes and clear uncollected results left on daemons instead of refusing to start")
+	cmd.Flags().BoolVar(&yesReally, "yes-really", false,
+		"start topologies of more than topology.max_total_tests tests, without asking for confirmation")
+
+	return cmd
+}
+
+func newServersStopCommand() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "stop",
+		Short: "Stop all iperf3 processes and release port reservations",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			session, err := openPhaseSession(ctx, configPath)
+			if err != nil {
+				return err
+			}
+			defer session.Close()
+
+			// The servers were started by another invocation, under its owner
+			session.orch.StopEveryRun()
+			return session.orch.Cleanup(ctx)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+
+	return cmd
+}
+
+func newClientsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clients",
+		Short: "Start iperf3 clients against servers started earlier",
+	}
+
+	cmd.AddCommand(newClientsStartCommand())
+
+	return cmd
+}
+
+func newClientsStartCommand() *cobra.Command {
+	var configPath string
+	var pairsFile string
+	var wait bool
+	var includeForeign bool
+	var overwrite bool
+
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start the clients of a pairs file written by servers start",
+		Long: `clients start starts the iperf3 clients listed in a pairs file. The
+servers of every pair must already be running; otherwise nothing is started.
+With --wait it also waits for the clients and writes the results to the
+configured output files.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return startClients(configPath, pairsFile, wait, includeForeign, overwrite)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringVar(&pairsFile, "pairs", "", "pairs file written by servers start")
+	cmd.Flags().BoolVar(&wait, "wait", false, "wait for the clients and collect their results")
+	cmd.Flags().BoolVar(&includeForeign, "include-foreign", false,
+		"with --wait, count results for test IDs outside the pairs file instead of reporting them separately")
+	cmd.Flags().BoolVar(&overwrite, "overwrite", false,
+		"with --wait, replace existing output files instead of refusing to start")
+	if err := cmd.MarkFlagRequired("pairs"); err != nil {
+		panic(err) // This should never happen during initialization
+	}
+
+	return cmd
+}
+
+// startServers runs the initialize, pre-flight, prepare and start servers
+// phases for the configured topology and writes its plan to pairsFile
+func startServers(configPath, pairsFile string, force, yesReally bool) error {
+	ctx := context.Background()
+	session, err := openPhaseSession(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	defaultProfile, err := session.profiles.GetProfile(session.cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		return fmt.Errorf("failed to get default profile: %w", err)
+	}
+	coverage, err := loadCoverage(session.cfg)
+	if err != nil {
+		return err
+	}
+	topo, err := setup.GenerateTopology(session.cfg, session.nodes, session.profiles, defaultProfile, coverage)
+	if err != nil {
+		return err
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("topology groups are scheduled by the run command and cannot be started in phases")
+	}
+	if err := checkTestCount(session.cfg, topo, yesReally); err != nil {
+		return err
+	}
+	if coverage != nil {
+		logCoverage(coverage, session.nodes, topo)
+	}
+
+	if err := session.orch.Initialize(ctx, topo); err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	session.orch.SetForce(force)
+	if err := session.orch.Preflight(ctx); err != nil {
+		return fmt.Errorf("pre-flight check failed: %w", err)
+	}
+	if err := session.orch.Prepare(ctx, topo); err != nil {
+		return fmt.Errorf("prepare failed: %w", err)
+	}
+	if err := session.orch.StartServers(ctx, topo.ServerPorts, topo.ServerBinds()); err != nil {
+		return fmt.Errorf("starting servers failed: %w", err)
+	}
+
+	if err := writePairsFile(pairsFile, topo.Plan()); err != nil {
+		return err
+	}
+
+	fmt.Printf("Started %d servers for %d pairs\n", topo.GetServerCount(), topo.GetTestCount())
+	fmt.Printf("Pairs file: %s\n", pairsFile)
+	return nil
+}
+
+// startClients runs the start clients phase for the pairs in pairsFile and,
+// if wait is set, the wait and collect phases
+func startClients(configPath, pairsFile string, wait, includeForeign, overwrite bool) error {
+	ctx := context.Background()
+	session, err := openPhaseSession(ctx, configPath)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	filter, err := buildResultFilter(session.cfg, session.nodes)
+	if err != nil {
+		return err
+	}
+	files := newOutputFiles(session.cfg, overwrite)
+	if wait {
+		if err := files.check(session.cfg); err != nil {
+			return err
+		}
+	}
+
+	plan, err := readPairsFile(pairsFile)
+	if err != nil {
+		return err
+	}
+	topo, err := topology.FromPlan(plan, session.nodes, session.profiles)
+	if err != nil {
+		return fmt.Errorf("invalid pairs file %s: %w", pairsFile, err)
+	}
+
+	// Queued tests are started by the controller as running ones finish
+	if limit := session.cfg.Controller.Concurrency.MaxConcurrentTests; !wait && limit > 0 && topo.GetClientWeight() > limit {
+		return fmt.Errorf("the pairs have %d client processes, bidirectional ones counted twice, more than max_concurrent_tests (%d); pass --wait so the rest can be started as tests finish",
+			topo.GetClientWeight(), limit)
+	}
+	logConcurrency(session.cfg, topo)
+
+	if err := session.orch.Initialize(ctx, topo); err != nil {
+		return fmt.Errorf("initialization failed: %w", err)
+	}
+	if err := session.orch.StartClients(ctx, topo.ClientTests); err != nil {
+		return fmt.Errorf("starting clients failed: %w", err)
+	}
+	fmt.Printf("Started clients for %d pairs\n", topo.GetTestCount())
+
+	if !wait {
+		return nil
+	}
+
+	if err := session.orch.Wait(ctx, topo.ClientTests); err != nil {
+		return err
+	}
+	if err := session.orch.Collect(ctx); err != nil {
+		return err
+	}
+
+	cfg := session.cfg
+	agg := aggregator.NewAggregator()
+	configureAnalysis(agg, cfg)
+	agg.SetTestProfiles(topo.TestProfiles())
+	agg.SetTestFamilies(topo.TestFamilies())
+	agg.SetTestInterfaces(topo.TestInterfaces())
+	agg.SetTestHops(topo.TestHops())
+	agg.SetTestGroupPairs(topo.TestGroupPairs())
+	agg.SetTestRepetitions(topo.TestRepetitions())
+	agg.SetTestLabels(topo.TestLabels())
+	agg.SetBackgroundTests(topo.BackgroundTests())
+	agg.SetBisection(topo.Bisection != nil)
+	agg.SetTestSources(topo.TestSources())
+	agg.SetTestDestinations(topo.TestDestinations())
+	if !includeForeign {
+		agg.SetExpectedTests(topo.TestIDs())
+	}
+	if err := agg.CollectResults(ctx, session.pool); err != nil {
+		return fmt.Errorf("failed to collect results: %w", err)
+	}
+	warnForeignResults(agg)
+
+	writer := output.NewWriter(cfg.Controller.Output.JSONFile, cfg.Controller.Output.CSVFile)
+	files.apply(writer)
+	configureWriter(writer, cfg)
+	metadata := runMetadata(session.orch, session.pool)
+	partialErr := reportCollectionErrors(agg, writer, metadata)
+	writer.SetMetadata(metadata)
+	writer.SetFilter(filter)
+	writer.SetForeignResults(agg.GetForeignResults())
+	writer.SetTimeline(agg.Timeline(), cfg.Controller.Output.TimelineCSVFile)
+	flakiness := pairFlakiness(cfg, writer, agg.GetResults(), false)
+	pairRepetitions(writer, agg.GetResults(), false)
+	writer.SetSchemaFile(cfg.Controller.Output.SchemaFile)
+	embedConfig(writer, cfg)
+	summary := agg.GetSummary()
+	summary.ExcludedTests = topo.Excluded
+	summary.ReverseSkippedTests = topo.ReverseSkipped
+	if err := writer.WriteAll(summary, agg.GetResults()); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	logOutputPaths(writer)
+
+	updateCoverage(cfg, agg.GetResults())
+
+	fmt.Printf("Completed: %d, Failed: %d\n", summary.CompletedTests, summary.FailedTests)
+	printFlakyPairs(flakiness)
+	if partialErr != nil {
+		return partialErr
+	}
+	return checkSuccessRatio(flakiness, cfg.Controller.Analysis.MinSuccessRatio)
+}
+
+// writePairsFile writes planned pairs as indented JSON
+func writePairsFile(path string, plan []topology.PlannedPair) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pairs: %w", err)
+	}
+	if err := output.WriteFileAtomic(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write pairs file: %w", err)
+	}
+	return nil
+}
+
+// readPairsFile reads planned pairs written by writePairsFile
+func readPairsFile(path string) ([]topology.PlannedPair, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Pairs file path is provided by user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pairs file: %w", err)
+	}
+	var plan []topology.PlannedPair
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, output.ParseError("pairs file", path, data, err)
+	}
+	return plan, nil
+}
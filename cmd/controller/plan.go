@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// planOptions are the flags of the plan command
+type planOptions struct {
+	configPath string
+	planPath   string
+	exportPath string
+	dotPath    string // Graphviz DOT file of the topology
+	pairsPath  string // Plain-text pair list of the topology
+}
+
+func newPlanCommand() *cobra.Command {
+	var opts planOptions
+
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Show or export the test plan of a configuration",
+		Long: `plan generates the topology of the configuration, or imports the plan file
+given with --plan, and reports its size without contacting any daemon.
+
+With --export it writes the topology as a plan file, in YAML if the name
+ends in .yaml or .yml and in JSON otherwise. "run --plan" runs such a file
+exactly as written, so other tools can produce plans to run.
+
+To preview a run, --dot writes the topology as a Graphviz DOT graph, with
+each node's server ports and each pair's profile and ports, and --pairs as
+a plain-text list of the pairs and the server ports of each node.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return showPlan(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+	cmd.Flags().StringVar(&opts.planPath, "plan", "",
+		"import and validate this plan file instead of generating the topology")
+	cmd.Flags().StringVar(&opts.exportPath, "export", "",
+		"write the topology to this plan file")
+	cmd.Flags().StringVar(&opts.dotPath, "dot", "",
+		"write the topology to this Graphviz DOT file")
+	cmd.Flags().StringVar(&opts.pairsPath, "pairs", "",
+		"write the topology's pairs and server ports to this text file")
+	if err := cmd.MarkFlagRequired("config"); err != nil {
+		panic(err) // This should never happen during initialization
+	}
+
+	return cmd
+}
+
+// showPlan generates or imports the topology of a configuration, and
+// exports it to the files of opts that are set
+func showPlan(opts planOptions) error {
+	cfg, err := config.LoadControllerConfig(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg.SetDefaults()
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Warning: %s", warning)
+	}
+	plan, _, err := loadPlan(cfg, opts.planPath)
+	if err != nil {
+		return err
+	}
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		return err
+	}
+	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		return fmt.Errorf("failed to get default profile: %w", err)
+	}
+	coverage, err := loadCoverage(cfg)
+	if err != nil {
+		return err
+	}
+	topo, err := planTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, coverage, plan)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Plan: %d pairs, %d groups, %d client processes\n",
+		topo.GetTestCount(), len(topo.Groups), topo.GetClientCount())
+	printNodeLoads(topo)
+	if opts.dotPath != "" {
+		if err := writeTopologyFile(opts.dotPath, "DOT graph", topo.ExportDOT); err != nil {
+			return err
+		}
+	}
+	if opts.pairsPath != "" {
+		if err := writeTopologyFile(opts.pairsPath, "pair list", topo.WritePairList); err != nil {
+			return err
+		}
+	}
+	if opts.exportPath == "" {
+		return nil
+	}
+	if err := topology.WritePlanFile(opts.exportPath, topo.Export()); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote plan to %s\n", opts.exportPath)
+	return nil
+}
+
+// writeTopologyFile writes a rendering of the topology, named what, to
+// path
+func writeTopologyFile(path, what string, write func(io.Writer) error) error {
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		return fmt.Errorf("failed to render %s: %w", what, err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	fmt.Printf("Wrote %s to %s\n", what, path)
+	return nil
+}
+
+// printNodeLoads prints the iperf3 processes sending and receiving on each
+// node, and the daemon process slots they need
+func printNodeLoads(topo *topology.Topology) {
+	loads := topo.NodeLoads()
+	nodeIDs := make([]string, 0, len(loads))
+	for nodeID := range loads {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	fmt.Println("Per-node load (iperf3 processes; bidirectional ones send and receive on both nodes):")
+	for _, nodeID := range nodeIDs {
+		load := loads[nodeID]
+		fmt.Printf("  %s: %d sending, %d receiving, %d process slots\n", nodeID, load.Send, load.Receive, load.Slots())
+	}
+}
+
+// loadPlan reads the plan file at path, checking that its pairs run between
+// configured nodes, and returns it with its source for the run metadata.
+// Without a path it returns nil.
+func loadPlan(cfg *config.ControllerConfig, path string) (*topology.PlanFile, *output.PlanSource, error) {
+	if path == "" {
+		return nil, nil, nil
+	}
+
+	plan, sum, err := topology.ReadPlanFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	configured := make(map[string]bool, len(cfg.Controller.Nodes))
+	for _, node := range cfg.Controller.Nodes {
+		configured[node.ID] = true
+	}
+	for _, nodeID := range plan.Nodes() {
+		if !configured[nodeID] {
+			return nil, nil, fmt.Errorf("plan file %s: node %s is not configured", path, nodeID)
+		}
+	}
+
+	log.Printf("Loaded plan %s: %d pairs, %d groups (sha256 %s)", path, len(plan.Pairs), len(plan.Groups), sum)
+	source := &output.PlanSource{
+		Path:    path,
+		SHA256:  sum,
+		Version: plan.Version,
+		Pairs:   len(plan.Pairs),
+	}
+	return plan, source, nil
+}
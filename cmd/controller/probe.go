@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+type statusOptions struct {
+	configPath string
+	probeData  bool
+	probe      client.ProbeOptions
+}
+
+// validate checks the probe bounds, which keep a probe interactive
+func (o statusOptions) validate() error {
+	if !o.probeData {
+		return nil
+	}
+	if o.probe.Timeout <= 0 || o.probe.Budget <= 0 {
+		return fmt.Errorf("--probe-timeout and --probe-budget must be positive")
+	}
+	if o.probe.Concurrent < 1 {
+		return fmt.Errorf("--probe-concurrency must be at least 1")
+	}
+	return nil
+}
+
+// probeTargets returns the data address of every node and the port probed
+// on it: its first running iperf3 server port, or else the first port a
+// run would allocate
+func probeTargets(cfg *config.ControllerConfig, nodes []*models.Node, statuses map[string]*pb.DaemonStatus) []client.ProbeTarget {
+	firstPort := int32(topology.DefaultPortStart)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		firstPort = int32(pr.Start) // #nosec G115 -- Ports are validated to be in valid range
+	}
+
+	targets := make([]client.ProbeTarget, 0, len(nodes))
+	for _, node := range nodes {
+		target := client.ProbeTarget{NodeID: node.ID, Address: node.DataAddress(), Port: firstPort}
+		if ports := statuses[node.ID].GetServerPorts(); len(ports) > 0 {
+			target.Port = ports[0]
+		}
+		targets = append(targets, target)
+	}
+	return targets
+}
+
+// probeData probes data-plane reachability between every pair of nodes and
+// prints it as a matrix, followed by the pairs reachable only one way
+func probeData(ctx context.Context, cfg *config.ControllerConfig, pool *client.Pool,
+	nodes []*models.Node, statuses map[string]*pb.DaemonStatus, opts client.ProbeOptions) {
+	targets := probeTargets(cfg, nodes, statuses)
+	fmt.Printf("Data-plane reachability (probing for up to %v):\n", opts.Budget)
+	fmt.Println(strings.Repeat("-", 80))
+	for i, target := range targets {
+		fmt.Printf("%3d  %-20s  %s:%d\n", i+1, target.NodeID, target.Address, target.Port)
+	}
+	fmt.Println()
+
+	matrix, failed := pool.ProbeData(ctx, targets, opts)
+	asymmetric := make(map[[2]string]bool)
+	for _, pair := range matrix.Asymmetric() {
+		asymmetric[pair] = true
+		asymmetric[[2]string{pair[1], pair[0]}] = true
+	}
+
+	// Rows are sources and columns destinations, numbered as listed above
+	const cell = 9
+	fmt.Printf("%-5s", "from")
+	for i := range targets {
+		fmt.Printf("%*d", cell, i+1)
+	}
+	fmt.Println()
+	for i, source := range targets {
+		fmt.Printf("%-5d", i+1)
+		row, probed := matrix[source.NodeID]
+		for _, dest := range targets {
+			text := "-"
+			switch {
+			case dest.NodeID == source.NodeID:
+			case !probed:
+				text = "n/a"
+			default:
+				text = reachabilityCell(row[dest.NodeID])
+				if asymmetric[[2]string{source.NodeID, dest.NodeID}] {
+					text += "!"
+				}
+			}
+			fmt.Printf("%*s", cell, text)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	fmt.Println("Cells are connect times in ms; * the node answered but nothing listens on the port,")
+	fmt.Println("x no answer, ? not probed within the budget, n/a the source could not probe,")
+	fmt.Println("! reachable only one way.")
+
+	failedIDs := make([]string, 0, len(failed))
+	for nodeID := range failed {
+		failedIDs = append(failedIDs, nodeID)
+	}
+	sort.Strings(failedIDs)
+	for _, nodeID := range failedIDs {
+		fmt.Printf("  %s could not probe: %s\n", nodeID, failed[nodeID])
+	}
+
+	if pairs := matrix.Asymmetric(); len(pairs) > 0 {
+		fmt.Println()
+		fmt.Println("❌ Asymmetric reachability, usually a one-way firewall rule:")
+		for _, pair := range pairs {
+			reverse := matrix[pair[1]][pair[0]]
+			fmt.Printf("  %s -> %s reachable, %s -> %s not: %s\n", pair[0], pair[1], pair[1], pair[0], reverse.Error)
+		}
+	}
+	fmt.Println()
+}
+
+// reachabilityCell formats the outcome of one probe for the matrix
+func reachabilityCell(r *client.Reachability) string {
+	switch {
+	case r == nil || !r.Probed:
+		return "?"
+	case !r.Reachable:
+		return "x"
+	}
+	text := fmt.Sprintf("%.1f", float64(r.Latency)/float64(time.Millisecond))
+	if r.Refused {
+		text += "*"
+	}
+	return text
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+)
+
+// Values of the run command's --progress-format
+const (
+	progressFormatText = "text"
+	progressFormatJSON = "json"
+)
+
+// openProgressEvents opens the destination of JSON progress events: the
+// file --progress-file names or, without one, stdout, in which case the
+// human-readable output moves to stderr so the two never interleave. It
+// returns a nil writer for text progress, and a function closing the file.
+func openProgressEvents(opts runOptions) (*orchestrator.EventWriter, func(), error) {
+	switch opts.progressFormat {
+	case progressFormatText:
+		if opts.progressFile != "" {
+			return nil, nil, fmt.Errorf("--progress-file needs --progress-format %s", progressFormatJSON)
+		}
+		return nil, func() {}, nil
+	case progressFormatJSON:
+	default:
+		return nil, nil, fmt.Errorf("unknown --progress-format %q (valid: %s, %s)",
+			opts.progressFormat, progressFormatText, progressFormatJSON)
+	}
+
+	if opts.progressFile == "" {
+		if opts.useTUI {
+			return nil, nil, fmt.Errorf("--tui cannot share stdout with --progress-format %s; add --progress-file",
+				progressFormatJSON)
+		}
+		events := orchestrator.NewEventWriter(os.Stdout)
+		os.Stdout = os.Stderr
+		return events, func() {}, nil
+	}
+
+	file, err := os.Create(opts.progressFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create progress file: %w", err)
+	}
+	closeFile := func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close progress file: %v", closeErr)
+		}
+	}
+	return orchestrator.NewEventWriter(file), closeFile, nil
+}
+
+// endProgressEvents sends the run_end event, carrying the run's error if it
+// failed, and warns if any event could not be written
+func endProgressEvents(orch *orchestrator.Orchestrator, events *orchestrator.EventWriter, runErr error) {
+	message := ""
+	if runErr != nil {
+		message = runErr.Error()
+	}
+	orch.Progress().Emit(orchestrator.EventRunEnd, "", 0, message)
+	if err := events.Err(); err != nil {
+		log.Printf("Warning: failed to write progress events: %v", err)
+	}
+}
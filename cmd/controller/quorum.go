@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+)
+
+// exitQuorumNotMet is the exit status when too few nodes were healthy for
+// the run to start
+const exitQuorumNotMet = 3
+
+// errQuorumNotMet is returned, wrapped, when a run did not start because
+// fewer nodes than its quorum were healthy
+var errQuorumNotMet = errors.New("node quorum not met")
+
+// applyQuorum checks health against the configured quorum. A run short of
+// it fails with errQuorumNotMet before anything starts; one that meets it
+// goes ahead without the missing nodes, so the returned registry holds only
+// the nodes the run uses.
+func applyQuorum(cfg *config.ControllerConfig, pool *client.Pool, registry *models.NodeRegistry, health *client.NodeHealth) (*models.NodeRegistry, error) {
+	quorum := cfg.Controller.Quorum
+	if !quorum.Enabled() {
+		return registry, nil
+	}
+
+	required := quorum.Required(health.Total)
+	if health.Healthy < required {
+		return nil, fmt.Errorf("%w: %d of %d nodes are healthy, %d required; missing: %s",
+			errQuorumNotMet, health.Healthy, health.Total, required, health.MissingList())
+	}
+	if len(health.Missing) == 0 {
+		return registry, nil
+	}
+
+	log.Printf("Warning: quorum met with %d of %d nodes (%d required); running without %s",
+		health.Healthy, health.Total, required, health.MissingList())
+	missing := make([]string, 0, len(health.Missing))
+	for nodeID := range health.Missing {
+		missing = append(missing, nodeID)
+	}
+	sort.Strings(missing)
+	return leaveOutNodes(cfg, pool, missing)
+}
+
+// errLivenessFailed is returned, wrapped, when nodes did not answer the
+// liveness check and the run could not go ahead without them
+var errLivenessFailed = errors.New("liveness check failed")
+
+// applyLiveness checks that every node of the run answers a status request
+// within the configured timeout, just before the run's first state-changing
+// request. Nodes that do not are left out if the quorum, counted over total
+// nodes, is still met; otherwise the run fails before anything starts. The
+// returned registry holds only the nodes the run uses.
+func applyLiveness(ctx context.Context, cfg *config.ControllerConfig, pool *client.Pool, registry *models.NodeRegistry,
+	total int, noQuorum bool) (*models.NodeRegistry, error) {
+	if cfg.Controller.Concurrency.LivenessTimeoutSeconds < 0 {
+		return registry, nil
+	}
+
+	timeout := time.Duration(cfg.Controller.Concurrency.LivenessTimeoutSeconds) * time.Second
+	liveness := pool.CheckLiveness(ctx, timeout)
+	if len(liveness.Missing) == 0 {
+		log.Printf("All %d nodes answered the liveness check in %d ms", liveness.Total, liveness.ElapsedMs)
+		return registry, nil
+	}
+
+	quorum := cfg.Controller.Quorum
+	if noQuorum || !quorum.Enabled() {
+		return nil, fmt.Errorf("%w: %d of %d nodes did not answer within %s, nothing was started: %s",
+			errLivenessFailed, len(liveness.Missing), liveness.Total, timeout, liveness.MissingList())
+	}
+	required := quorum.Required(total)
+	if liveness.Responded < required {
+		return nil, fmt.Errorf("%w: %d of %d nodes answered the liveness check, %d required; missing: %s",
+			errQuorumNotMet, liveness.Responded, total, required, liveness.MissingList())
+	}
+
+	log.Printf("Warning: %d nodes did not answer the liveness check within %s; running without %s",
+		len(liveness.Missing), timeout, liveness.MissingList())
+	return leaveOutNodes(cfg, pool, liveness.MissingIDs())
+}
+
+// leaveOutNodes removes the missing nodes from cfg and pool, returning the
+// registry of the nodes left
+func leaveOutNodes(cfg *config.ControllerConfig, pool *client.Pool, missing []string) (*models.NodeRegistry, error) {
+	warnings, err := cfg.SelectNodes(config.NodeSubset{Exclude: missing})
+	if err != nil {
+		return nil, fmt.Errorf("failed to leave out missing nodes: %w", err)
+	}
+	for _, warning := range warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	for _, nodeID := range missing {
+		if err := pool.Remove(nodeID); err != nil {
+			log.Printf("Warning: failed to disconnect from node %s: %v", nodeID, err)
+		}
+	}
+	return setup.BuildNodeRegistry(cfg)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+)
+
+func newTagsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tags",
+		Short: "Inspect the tags of the configured nodes",
+	}
+
+	cmd.AddCommand(newTagsListCommand())
+
+	return cmd
+}
+
+func newTagsListCommand() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List every distinct node tag with the nodes carrying it",
+		Long: `list prints every distinct tag of the configured nodes, after normalization,
+with the nodes that carry it. Tags that differ only in case are marked, as
+they are likely one tag spelled two ways.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listTags(configPath)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "./controller.yaml",
+		"path to configuration file")
+
+	return cmd
+}
+
+// listTags prints the distinct node tags of a configuration
+func listTags(configPath string) error {
+	cfg, err := config.LoadControllerConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	tags := cfg.TagUsage()
+	if len(tags) == 0 {
+		fmt.Println("No node has tags")
+		return nil
+	}
+
+	width := len("TAG")
+	for _, usage := range tags {
+		width = max(width, len(usage.Tag))
+	}
+	fmt.Printf("%-*s  %s\n", width, "TAG", "NODES")
+	for _, usage := range tags {
+		line := fmt.Sprintf("%-*s  %s", width, usage.Tag, strings.Join(usage.Nodes, ", "))
+		if len(usage.Similar) > 0 {
+			line += fmt.Sprintf("  ⚠ differs only in case from %s", strings.Join(usage.Similar, ", "))
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
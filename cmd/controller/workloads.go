@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/output"
+)
+
+func newWorkloadsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workloads",
+		Short: "Inspect the workload presets run --workload selects",
+	}
+
+	cmd.AddCommand(newWorkloadsListCommand())
+
+	return cmd
+}
+
+func newWorkloadsListCommand() *cobra.Command {
+	var configPath string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the built-in and configured workloads with what each sets",
+		Long: `list prints every workload run --workload accepts: the built-in ones and,
+with --config, those defined under controller.workloads, which replace
+built-in ones of the same name. Each is shown with the settings it applies
+to the fields a configuration leaves unset.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listWorkloads(configPath)
+		},
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "c", "",
+		"path to a configuration file whose workloads are listed beside the built-in ones")
+
+	return cmd
+}
+
+// listWorkloads prints the built-in workloads and those of a configuration
+func listWorkloads(configPath string) error {
+	cfg := &config.ControllerConfig{}
+	if configPath != "" {
+		var err error
+		if cfg, err = config.LoadWorkloads(configPath); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+	}
+
+	for i, name := range cfg.WorkloadNames() {
+		// Applied to an empty configuration, every setting shows
+		preview := &config.ControllerConfig{}
+		preview.Controller.Workloads = cfg.Controller.Workloads
+		expansion, err := preview.ApplyWorkload(name)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Println()
+		}
+		source := "configured"
+		if expansion.BuiltIn {
+			source = "built-in"
+		}
+		fmt.Printf("%s (%s)\n", name, source)
+		if expansion.Description != "" {
+			fmt.Printf("  %s\n", expansion.Description)
+		}
+		for _, setting := range expansion.Settings {
+			fmt.Printf("  %s: %s\n", setting.Field, setting.Value)
+		}
+	}
+	return nil
+}
+
+// printWorkload prints what selecting a workload set, and which of its
+// settings the configuration overrides
+func printWorkload(expansion *config.WorkloadExpansion) {
+	fmt.Printf("Workload: %s\n", expansion.Name)
+	for _, setting := range expansion.Settings {
+		if setting.Applied {
+			fmt.Printf("  %s: %s\n", setting.Field, setting.Value)
+		} else {
+			fmt.Printf("  %s: %s (not applied, set in the configuration)\n", setting.Field, setting.Value)
+		}
+	}
+	fmt.Println()
+}
+
+// workload records a run's workload for the JSON output
+func workload(expansion *config.WorkloadExpansion) *output.Workload {
+	if expansion == nil {
+		return nil
+	}
+	return &output.Workload{
+		Name:    expansion.Name,
+		Applied: expansion.Applied(),
+		Kept:    expansion.Kept(),
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc"
@@ -17,7 +19,14 @@ import (
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
 	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/debughttp"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+	"github.com/bensons/iperf-cnc/internal/daemon/allowlist"
+	daemoncapture "github.com/bensons/iperf-cnc/internal/daemon/capture"
+	"github.com/bensons/iperf-cnc/internal/daemon/collector"
+	"github.com/bensons/iperf-cnc/internal/daemon/port"
 	"github.com/bensons/iperf-cnc/internal/daemon/server"
+	"github.com/bensons/iperf-cnc/internal/daemon/simulate"
 )
 
 var (
@@ -33,8 +42,15 @@ func main() {
 	}
 }
 
+// daemonOptions holds the command-line flags of the daemon
+type daemonOptions struct {
+	configPath string
+	simulate   bool
+	listenPort int
+}
+
 func newRootCommand() *cobra.Command {
-	var configPath string
+	var opts daemonOptions
 
 	rootCmd := &cobra.Command{
 		Use:   "iperf-daemon",
@@ -43,16 +59,43 @@ func newRootCommand() *cobra.Command {
 for distributed network performance testing.`,
 		Version: fmt.Sprintf("%s (commit: %s, built: %s)", version, commit, date),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return runDaemon(configPath)
+			return runDaemon(opts)
 		},
 	}
 
-	rootCmd.Flags().StringVarP(&configPath, "config", "c", "./daemon.yaml",
+	rootCmd.Flags().StringVarP(&opts.configPath, "config", "c", "./daemon.yaml",
 		"path to configuration file")
+	rootCmd.Flags().BoolVar(&opts.simulate, "simulate", false,
+		"run no iperf3 processes and return synthetic results shaped by the simulate section, for testing the controller")
+	rootCmd.Flags().IntVar(&opts.listenPort, "listen-port", 0,
+		"override listen_port, e.g. to run several simulated daemons on one host")
+
+	rootCmd.AddCommand(newExplainCommand())
 
 	return rootCmd
 }
 
+func newExplainCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain <field>",
+		Short: "Describe a configuration field",
+		Long: `explain prints the type, default, validation rules and an example of a
+field of the daemon configuration, given by its path from the top of the
+file. "iperf-controller explain --list" lists every field.`,
+		Example: `  iperf-daemon explain daemon.port_range
+  iperf-daemon explain daemon.result_overflow`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := config.Explain(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Print(info.Format())
+			return nil
+		},
+	}
+}
+
 // panicRecoveryInterceptor recovers from panics in gRPC handlers
 func panicRecoveryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
@@ -66,12 +109,12 @@ func panicRecoveryInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
-func runDaemon(configPath string) error {
+func runDaemon(opts daemonOptions) error {
 	fmt.Printf("iperf-daemon version %s\n", version)
-	fmt.Printf("Loading configuration from: %s\n", configPath)
+	fmt.Printf("Loading configuration from: %s\n", opts.configPath)
 
 	// Load configuration
-	cfg, err := config.LoadDaemonConfig(configPath)
+	cfg, err := config.LoadDaemonConfig(opts.configPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
@@ -79,16 +122,58 @@ func runDaemon(configPath string) error {
 	// Set defaults
 	cfg.SetDefaults()
 
+	if opts.listenPort != 0 {
+		if opts.listenPort < 1 || opts.listenPort > 65535 {
+			return fmt.Errorf("--listen-port must be between 1 and 65535")
+		}
+		cfg.Daemon.ListenPort = opts.listenPort
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+	}
+
+	portStrategy, err := port.ParseStrategy(cfg.Daemon.PortAllocation)
+	if err != nil {
+		return err
+	}
+
 	// Create daemon server
 	serverConfig := &server.Config{
 		ListenPort:     cfg.Daemon.ListenPort,
 		PortRangeStart: cfg.Daemon.PortRange.Start,
 		PortRangeEnd:   cfg.Daemon.PortRange.End,
+		PortStrategy:   portStrategy,
+		PortCooldown:   time.Duration(max(cfg.Daemon.PortCooldownSeconds, 0)) * time.Second,
 		MaxProcesses:   cfg.Daemon.MaxProcesses,
 		CPUAffinity:    cfg.Daemon.CPUAffinity,
 		LogLevel:       cfg.Daemon.LogLevel,
 		ResultDir:      cfg.Daemon.ResultDir,
 		IperfPath:      "iperf3",
+		Version:        version,
+		StopTimeout:    time.Duration(max(cfg.Daemon.TimeoutConfig.ProcessStop, 0)) * time.Second,
+		ResultLimits: collector.Limits{
+			MaxResults: max(cfg.Daemon.MaxStoredResults, 0),
+			MaxBytes:   max(cfg.Daemon.MaxTotalResultBytes, 0),
+			Overflow:   cfg.Daemon.ResultOverflow,
+		},
+	}
+	if cfg.Daemon.Debug.Enabled {
+		serverConfig.ExcludedPorts = []int{cfg.Daemon.Debug.Port}
+	}
+	if capture := cfg.Daemon.Capture; capture.Enabled {
+		serverConfig.Capture = &daemoncapture.Config{
+			Interface:   capture.Interface,
+			Snaplen:     capture.Snaplen,
+			MaxBytes:    int64(capture.MaxMB) << 20,
+			TcpdumpPath: capture.TcpdumpPath,
+		}
+	}
+	if opts.simulate {
+		simulateConfig, err := simulateSettings(cfg.Daemon.Simulate)
+		if err != nil {
+			return err
+		}
+		serverConfig.Simulate = simulateConfig
 	}
 
 	daemonServer, err := server.NewDaemonServer(serverConfig)
@@ -96,17 +181,42 @@ func runDaemon(configPath string) error {
 		return fmt.Errorf("failed to create daemon server: %w", err)
 	}
 
+	allowed, err := allowlist.New(cfg.Daemon.AllowedCIDRs)
+	if err != nil {
+		return fmt.Errorf("invalid allowed_cidrs: %w", err)
+	}
+
 	// Create gRPC server with increased message size limits and recovery
-	// Default is 4MB, but iperf3 JSON results can be large with many tests
+	// Default is 4MB, but iperf3 JSON results can be large with many tests.
+	// Calls from outside allowed_cidrs are refused before any handler runs.
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(100*1024*1024), // 100MB max receive
 		grpc.MaxSendMsgSize(100*1024*1024), // 100MB max send
 		grpc.ChainUnaryInterceptor(
+			allowed.UnaryInterceptor(),
 			panicRecoveryInterceptor(),
 		),
+		grpc.ChainStreamInterceptor(
+			allowed.StreamInterceptor(),
+		),
 	)
 	pb.RegisterDaemonServiceServer(grpcServer, daemonServer)
 
+	if cfg.Daemon.Debug.Enabled {
+		debugServer, err := debughttp.Start(cfg.Daemon.Debug.Address, cfg.Daemon.Debug.Port)
+		if err != nil {
+			return fmt.Errorf("failed to start debug listener: %w", err)
+		}
+		defer func() {
+			if closeErr := debugServer.Close(); closeErr != nil {
+				log.Printf("Warning: failed to close debug listener: %v", closeErr)
+			}
+		}()
+		debughttp.Publish("daemon", func() interface{} { return daemonServer.DebugStats() })
+		debugServer.SetState(func() interface{} { return daemonServer.DebugStats() })
+		fmt.Printf("Debug listener on http://%s/debug/\n", debugServer.Addr())
+	}
+
 	// Start listening
 	listenAddr := fmt.Sprintf(":%d", cfg.Daemon.ListenPort)
 	listener, err := net.Listen("tcp", listenAddr)
@@ -115,8 +225,23 @@ func runDaemon(configPath string) error {
 	}
 
 	fmt.Printf("Daemon listening on %s\n", listenAddr)
+	if len(cfg.Daemon.AllowedCIDRs) > 0 {
+		fmt.Printf("Allowed controller networks: %s\n", strings.Join(cfg.Daemon.AllowedCIDRs, ", "))
+	}
 	fmt.Printf("Port range: %d-%d\n", cfg.Daemon.PortRange.Start, cfg.Daemon.PortRange.End)
+	if portStrategy == port.StrategySequential {
+		fmt.Printf("Port allocation: %s\n", portStrategy)
+	} else {
+		fmt.Printf("Port allocation: %s, avoiding ports released in the last %s\n",
+			portStrategy, serverConfig.PortCooldown)
+	}
 	fmt.Printf("Max processes: %d\n", cfg.Daemon.MaxProcesses)
+	if opts.simulate {
+		sim := cfg.Daemon.Simulate
+		fmt.Printf("SIMULATION MODE: no iperf3 processes are run, results are synthetic "+
+			"(throughput %s ± %s, failure rate %g, RTT %gms)\n",
+			sim.ThroughputMean, sim.ThroughputStddev, sim.FailureRate, sim.RTTMs)
+	}
 
 	// Handle graceful shutdown
 	go func() {
@@ -135,3 +260,24 @@ func runDaemon(configPath string) error {
 
 	return nil
 }
+
+// simulateSettings converts the simulate section of the configuration
+func simulateSettings(settings config.SimulateConfig) (*simulate.Config, error) {
+	mean, err := units.ParseBitRate(settings.ThroughputMean)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulate.throughput_mean: %w", err)
+	}
+	stddev, err := units.ParseBitRate(settings.ThroughputStddev)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulate.throughput_stddev: %w", err)
+	}
+
+	return &simulate.Config{
+		ThroughputMeanBps:   mean,
+		ThroughputStddevBps: stddev,
+		FailureRate:         settings.FailureRate,
+		RTT:                 time.Duration(settings.RTTMs * float64(time.Millisecond)),
+		StartLatency:        time.Duration(settings.StartLatencyMs) * time.Millisecond,
+		Seed:                settings.Seed,
+	}, nil
+}
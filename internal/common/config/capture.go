@@ -0,0 +1,49 @@
+package config
+
+import "fmt"
+
+// CaptureConfig enables packet captures of the client tests whose profile
+// sets capture_on. Capturing runs tcpdump, which needs privileges, so it is
+// off unless enabled here.
+type CaptureConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Interface   string `yaml:"interface,omitempty"`    // Default: any
+	Snaplen     int    `yaml:"snaplen,omitempty"`      // Bytes kept of each packet (default: 128)
+	MaxMB       int    `yaml:"max_mb,omitempty"`       // Size of each capture file (default: 100)
+	TcpdumpPath string `yaml:"tcpdump_path,omitempty"` // Default: tcpdump
+}
+
+// Defaults of the capture bounds
+const (
+	DefaultCaptureSnaplen = 128
+	DefaultCaptureMaxMB   = 100
+	// MaxCaptureMB bounds max_mb, as every capture may grow to it
+	MaxCaptureMB = 2048
+)
+
+// validate checks the capture bounds
+func (c *CaptureConfig) validate() error {
+	if c.Snaplen < 0 || c.Snaplen > 262144 {
+		return fmt.Errorf("capture.snaplen must be between 0 and 262144")
+	}
+	if c.MaxMB < 0 || c.MaxMB > MaxCaptureMB {
+		return fmt.Errorf("capture.max_mb must be between 0 and %d", MaxCaptureMB)
+	}
+	return nil
+}
+
+// setDefaults fills in the bounds of unset fields
+func (c *CaptureConfig) setDefaults() {
+	if c.Interface == "" {
+		c.Interface = "any"
+	}
+	if c.Snaplen == 0 {
+		c.Snaplen = DefaultCaptureSnaplen
+	}
+	if c.MaxMB == 0 {
+		c.MaxMB = DefaultCaptureMaxMB
+	}
+	if c.TcpdumpPath == "" {
+		c.TcpdumpPath = "tcpdump"
+	}
+}
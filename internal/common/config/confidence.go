@@ -0,0 +1,46 @@
+package config
+
+import "fmt"
+
+// ConfidenceConfig tunes the measurement hygiene rules that rate each
+// completed result high, medium or low confidence. IgnoreLow has check
+// report a low-confidence result as unknown instead of comparing it to the
+// thresholds.
+type ConfidenceConfig struct {
+	DisabledRules []string `yaml:"disabled_rules,omitempty"`
+	CPUPercent    float64  `yaml:"cpu_percent,omitempty"` // Sender iperf3 CPU usage (default: 90)
+	MinOverlap    float64  `yaml:"min_overlap,omitempty"` // Share of a test inside its group's common window (default: 0.5)
+	IgnoreLow     bool     `yaml:"ignore_low,omitempty"`
+}
+
+// Defaults of the confidence rules
+const (
+	DefaultConfidenceCPUPercent = 90
+	DefaultConfidenceMinOverlap = 0.5
+)
+
+// validate checks the rule names and thresholds
+func (c *ConfidenceConfig) validate() error {
+	for _, rule := range c.DisabledRules {
+		if err := checkValue("confidence rule", "controller.analysis.confidence.disabled_rules", rule); err != nil {
+			return err
+		}
+	}
+	if c.CPUPercent < 0 {
+		return fmt.Errorf("analysis confidence.cpu_percent cannot be negative")
+	}
+	if c.MinOverlap < 0 || c.MinOverlap > 1 {
+		return fmt.Errorf("analysis confidence.min_overlap must be between 0 and 1")
+	}
+	return nil
+}
+
+// setDefaults sets the thresholds
+func (c *ConfidenceConfig) setDefaults() {
+	if c.CPUPercent == 0 {
+		c.CPUPercent = DefaultConfidenceCPUPercent
+	}
+	if c.MinOverlap == 0 {
+		c.MinOverlap = DefaultConfidenceMinOverlap
+	}
+}
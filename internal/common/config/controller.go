@@ -2,9 +2,18 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/units"
 )
 
 // ControllerConfig represents the controller configuration
@@ -19,26 +28,96 @@ type ControllerSettings struct {
 	Topology     TopologyConfig         `yaml:"topology"`
 	Output       OutputConfig           `yaml:"output"`
 	Concurrency  ConcurrencyConfig      `yaml:"concurrency"`
+	Analysis     AnalysisConfig         `yaml:"analysis"`
+	PortRange    *PortRangeConfig       `yaml:"port_range,omitempty"` // Server port sub-range for this run
+	// DaemonPortRange mirrors the daemons' own port_range so that validate
+	// can check topologies offline; nodes may override it
+	DaemonPortRange *PortRangeConfig `yaml:"daemon_port_range,omitempty"`
+	OnFailure       OnFailureConfig  `yaml:"on_failure"`
+	NodeCache       NodeCacheConfig  `yaml:"node_cache"`
+	Debug           DebugConfig      `yaml:"debug"`
+	Logging         LoggingConfig    `yaml:"logging"`
+	// CongestionControlPolicy decides what happens when a profile requests a
+	// congestion control algorithm a daemon's kernel does not have: "reject"
+	// (default) fails the prepare phase, "warn" only logs it
+	CongestionControlPolicy string `yaml:"congestion_control_policy,omitempty"`
+	// ResourceSampling has daemons record host CPU, memory and NIC counters
+	// during the run
+	ResourceSampling ResourceSamplingConfig `yaml:"resource_sampling,omitempty"`
+	// TagPolicy controls how node tags are normalized
+	TagPolicy TagPolicyConfig `yaml:"tag_policy,omitempty"`
+	// Quorum is the share of nodes that must be healthy for a run to start
+	Quorum QuorumConfig `yaml:"quorum,omitempty"`
+	// Workloads are named presets of topology, profile and analysis
+	// settings, selected with run --workload, beside the built-in ones
+	Workloads map[string]WorkloadConfig `yaml:"workloads,omitempty"`
+}
+
+// LoggingConfig controls how much the controller logs about each node
+type LoggingConfig struct {
+	Verbose                 bool `yaml:"verbose"`                   // Log every node's progress in each phase
+	ProgressIntervalSeconds int  `yaml:"progress_interval_seconds"` // How often aggregated progress is logged (default: 5)
 }
 
 // NodeConfig represents a node in the cluster
 type NodeConfig struct {
-	Hostname string   `yaml:"hostname"`
-	IP       string   `yaml:"ip"`
-	Port     int      `yaml:"port"`
-	ID       string   `yaml:"id,omitempty"` // Optional, defaults to hostname
-	Tags     []string `yaml:"tags,omitempty"`
+	Hostname string `yaml:"hostname"`
+	IP       string `yaml:"ip"`
+	DataIP   string `yaml:"data_ip,omitempty"` // Address for iperf3 traffic, if not ip
+	// Further IP addresses for iperf3 traffic, such as the IPv6 address of a
+	// dual-stack node; used by topology address_families
+	DataIPs []string `yaml:"data_ips,omitempty"`
+	Port    int      `yaml:"port"`
+	ID      string   `yaml:"id,omitempty"` // Optional, defaults to hostname
+	Tags    []string `yaml:"tags,omitempty"`
+	// DaemonPortRange mirrors this node's daemon port_range, if it differs
+	// from the controller-wide daemon_port_range
+	DaemonPortRange *PortRangeConfig `yaml:"daemon_port_range,omitempty"`
+	// Daily windows in which tests involving this node are deferred
+	Blackout []BlackoutConfig `yaml:"blackout,omitempty"`
+	// Named network interfaces of a multi-homed node, each tested as its
+	// own endpoint by topology per_interface
+	Interfaces []InterfaceConfig `yaml:"interfaces,omitempty"`
+}
+
+// InterfaceConfig is a named network interface of a node, such as its
+// frontend or backend NIC. Tests over it bind to ip on both nodes.
+type InterfaceConfig struct {
+	Name string `yaml:"name"`
+	IP   string `yaml:"ip"`
+}
+
+// BlackoutConfig is a daily window in which a node must not be tested, such
+// as while it runs its backups
+type BlackoutConfig struct {
+	Start    string `yaml:"start"`              // HH:MM
+	End      string `yaml:"end"`                // HH:MM; before start for windows spanning midnight
+	Timezone string `yaml:"timezone,omitempty"` // IANA name (default: the controller's local time)
+}
+
+// BlackoutWindows parses the node's blackout windows
+func (n NodeConfig) BlackoutWindows() ([]models.BlackoutWindow, error) {
+	windows := make([]models.BlackoutWindow, 0, len(n.Blackout))
+	for i, blackout := range n.Blackout {
+		window, err := models.ParseBlackoutWindow(blackout.Start, blackout.End, blackout.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("blackout[%d]: %w", i, err)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
 }
 
 // TestProfile contains iperf3 test parameters
 type TestProfile struct {
-	Duration          int               `yaml:"duration"`
+	Duration          float64           `yaml:"duration"`           // Seconds; fractional values such as 0.5 are allowed
 	Protocol          string            `yaml:"protocol,omitempty"` // "tcp" or "udp" (default: tcp)
 	Bandwidth         string            `yaml:"bandwidth,omitempty"`
 	WindowSize        string            `yaml:"window_size,omitempty"`
 	Parallel          int               `yaml:"parallel"`
 	Bidirectional     bool              `yaml:"bidirectional"`
 	Reverse           bool              `yaml:"reverse"`
+	SeparateReverse   bool              `yaml:"separate_reverse,omitempty"` // Bidirectional as two one-way tests, not --bidir
 	BufferLength      int               `yaml:"buffer_length,omitempty"`
 	CongestionControl string            `yaml:"congestion_control,omitempty"` // TCP only
 	MSS               int               `yaml:"mss,omitempty"`                // TCP only
@@ -46,46 +125,447 @@ type TestProfile struct {
 	TOS               int               `yaml:"tos,omitempty"`
 	ZeroCopy          bool              `yaml:"zerocopy"`
 	OmitSeconds       int               `yaml:"omit_seconds,omitempty"`
+	ProcessesPerPair  int               `yaml:"processes_per_pair,omitempty"` // iperf3 processes (and ports) per pair
+	PortsPerPair      int               `yaml:"ports_per_pair,omitempty"`     // Server ports per pair, splitting parallel among them
+	Burst             *BurstConfig      `yaml:"burst,omitempty"`              // UDP only
 	ExtraFlags        map[string]string `yaml:"extra_flags,omitempty"`
+	LintIgnore        []string          `yaml:"lint_ignore,omitempty"` // Lint rules the profile breaks on purpose
+	// CaptureOn keeps a packet capture of the profile's tests that fail
+	// (failure) or measure below capture_threshold (threshold), on daemons
+	// with captures enabled
+	CaptureOn        []string `yaml:"capture_on,omitempty"`
+	CaptureThreshold string   `yaml:"capture_threshold,omitempty"` // Bit rate, such as 500M
+}
+
+// BurstConfig defines a UDP microburst schedule: repeat bursts of on_ms at
+// the profile bandwidth, each followed by off_ms of idle time
+type BurstConfig struct {
+	OnMs   int `yaml:"on_ms"`
+	OffMs  int `yaml:"off_ms"`
+	Repeat int `yaml:"repeat"`
 }
 
 // TopologyConfig defines the test topology
 type TopologyConfig struct {
-	Type           string             `yaml:"type"` // "full_mesh", "custom"
+	Type           string             `yaml:"type"` // "full_mesh", "custom", "rotating", "star", "ring", "one_to_many", "many_to_one", "partial_mesh", "bisection", "chain"
 	DefaultProfile string             `yaml:"default_profile"`
 	Overrides      []TopologyOverride `yaml:"overrides,omitempty"`
+	Groups         []GroupConfig      `yaml:"groups,omitempty"` // When set, only group pairs are tested
+	// Exclusions select pairs the way overrides do, which mesh topologies
+	// leave out entirely and custom topologies must not list
+	Exclusions []TopologyOverride `yaml:"exclusions,omitempty"`
+	// Rotating topologies test the pairs_per_run mesh pairs that have gone
+	// longest without a measurement, as recorded in coverage_file
+	PairsPerRun  int    `yaml:"pairs_per_run,omitempty"`
+	CoverageFile string `yaml:"coverage_file,omitempty"` // Default: coverage.json next to json_file
+	// Star topologies test only the pairs between the hub, a node ID or a
+	// tag, and every other node, to_hub, from_hub or both (default) ways
+	Hub       string `yaml:"hub,omitempty"`
+	Direction string `yaml:"direction,omitempty"`
+	// Ring topologies test each node against the next, nodes listed in
+	// order first and the rest in node order, and with bidirectional_ring
+	// the next node back as well
+	Order             []string `yaml:"order,omitempty"`
+	BidirectionalRing bool     `yaml:"bidirectional_ring,omitempty"`
+	// Chain topologies test each hop between adjacent nodes of order, and
+	// only those nodes, both ways, and with include_end_to_end the first
+	// node sending to the last as well
+	IncludeEndToEnd bool `yaml:"include_end_to_end,omitempty"`
+	// Custom topologies test exactly the listed pairs
+	Pairs []CustomPair `yaml:"pairs,omitempty"`
+	// One-to-many topologies test the source node sending to every other
+	// node at once
+	Source string `yaml:"source,omitempty"`
+	// Many-to-one topologies test every other node sending to the
+	// destination node at once, reproducing incast congestion
+	Destination string `yaml:"destination,omitempty"`
+	// Partial mesh topologies test a random share, density, of the full
+	// mesh pairs, every node sending and receiving at least once. The same
+	// seed selects the same pairs; unset, a new one is picked per run.
+	// max_tests instead tests that many pairs, each node sending about as
+	// many as the others.
+	Density  float64 `yaml:"density,omitempty"`
+	MaxTests int     `yaml:"max_tests,omitempty"`
+	Seed     int64   `yaml:"seed,omitempty"`
+	// Bisection topologies pair each node of side_a with a distinct node of
+	// side_b, in listed order, all sending from side_a at once. Sides list
+	// node IDs or tag:<tag> entries; sides of different sizes are refused
+	// unless allow_unbalanced leaves the extra nodes untested.
+	SideA           []string `yaml:"side_a,omitempty"`
+	SideB           []string `yaml:"side_b,omitempty"`
+	AllowUnbalanced bool     `yaml:"allow_unbalanced,omitempty"`
+	// Full mesh topologies with source_tags and destination_tags test only
+	// the pairs from a node with a source tag to a node with a destination
+	// tag; a node with both is tested both ways
+	SourceTags      []string `yaml:"source_tags,omitempty"`
+	DestinationTags []string `yaml:"destination_tags,omitempty"`
+	// Full mesh topologies with group_by group nodes by the value of their
+	// key:value tag with that key, such as a1 of rack:a1, and test only the
+	// pairs within a group, with group_mode intra_group, or across groups,
+	// with inter_group. Nodes without such a tag are left out.
+	GroupBy   string `yaml:"group_by,omitempty"`
+	GroupMode string `yaml:"group_mode,omitempty"`
+	// Full and partial mesh topologies with directed false test each
+	// unordered pair of nodes once, from the node listed first, rather than
+	// both ways; a bidirectional profile still covers both directions
+	Directed *bool `yaml:"directed,omitempty"`
+	// Runs of more than max_total_tests pairs are refused, and runs of more
+	// than confirm_tests_above need confirming at a terminal; negative
+	// values disable either
+	MaxTotalTests     int `yaml:"max_total_tests,omitempty"`     // Default: 5000
+	ConfirmTestsAbove int `yaml:"confirm_tests_above,omitempty"` // Default: 1000
+	// AddressFamilies, v4 and/or v6, runs each pair once per family both
+	// nodes have a data address in; unset runs it once over the data address
+	AddressFamilies []string `yaml:"address_families,omitempty"`
+	// Schedule rounds runs the pairs in rounds, one after the other, in
+	// which each node is the source or destination of at most one pair, to
+	// measure each link rather than contention. Unset, or concurrent, runs
+	// them all at once.
+	Schedule string `yaml:"schedule,omitempty"`
+	// PerInterface runs each pair once per interface name both nodes have,
+	// between the addresses of that interface, to test every path between
+	// multi-homed nodes
+	PerInterface bool `yaml:"per_interface,omitempty"`
+	// File is a YAML file of further topology settings, such as the pairs
+	// of a custom topology, merged in when the configuration is loaded. A
+	// relative path is resolved against the configuration's directory.
+	File string `yaml:"file,omitempty"`
+
+	from *topologySource // Set once File is merged
+}
+
+// CustomPair is one test of a custom topology, with a profile that takes
+// precedence over overrides and the default profile
+type CustomPair struct {
+	Source      string `yaml:"source"`
+	Destination string `yaml:"destination"`
+	Profile     string `yaml:"profile,omitempty"`
+	Repeat      int    `yaml:"repeat,omitempty"` // Times the pair runs, each as its own test
+}
+
+// Defaults of the topology size safety limits
+const (
+	DefaultMaxTotalTests     = 5000
+	DefaultConfirmTestsAbove = 1000
+)
+
+// Topology schedules
+const (
+	ScheduleConcurrent = "concurrent"
+	ScheduleRounds     = "rounds"
+)
+
+// GroupConfig is a named set of pairs run after the groups it depends on.
+// Each pairs entry selects pairs the same way as an override and may set
+// their profile and timing.
+type GroupConfig struct {
+	Name       string             `yaml:"name"`
+	After      []string           `yaml:"after,omitempty"`
+	Background bool               `yaml:"background,omitempty"` // Keep running while dependent groups execute
+	Pairs      []TopologyOverride `yaml:"pairs"`
 }
 
 // TopologyOverride allows specific node pairs to use different profiles
 type TopologyOverride struct {
 	SourceNodes      []string `yaml:"source_nodes,omitempty"`
 	DestinationNodes []string `yaml:"destination_nodes,omitempty"`
-	Nodes            []string `yaml:"nodes,omitempty"` // For symmetric overrides
+	Nodes            []string `yaml:"nodes,omitempty"`            // For symmetric overrides
+	SourceTags       []string `yaml:"source_tags,omitempty"`      // Adds the nodes with any of the tags to source_nodes
+	DestinationTags  []string `yaml:"destination_tags,omitempty"` // Adds the nodes with any of the tags to destination_nodes
 	Profile          string   `yaml:"profile"`
+	Duration         float64  `yaml:"duration,omitempty"`       // Per-pair duration in seconds, overriding the profile
+	StartDelayMs     int      `yaml:"start_delay_ms,omitempty"` // Delay before the pair's clients start
+	Background       bool     `yaml:"background,omitempty"`     // Run as unmeasured load for the whole run
+	Repeat           int      `yaml:"repeat,omitempty"`         // Times each pair runs, each as its own test
+}
+
+// Pairs returns the ordered source/destination pairs the override covers
+// among nodes: every pair among nodes, plus every pair from source_nodes or
+// a node with a source tag to destination_nodes or a node with a destination
+// tag. A "tag:<tag>" entry of the node lists stands for the nodes with the
+// tag. Each pair is returned once.
+func (o TopologyOverride) Pairs(nodes []NodeConfig) [][2]string {
+	pairs := make([][2]string, 0)
+	seen := make(map[[2]string]bool)
+	add := func(src, dst string) {
+		pair := [2]string{src, dst}
+		if src != dst && !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	symmetric := expandNodeList(nodes, o.Nodes)
+	for _, src := range symmetric {
+		for _, dst := range symmetric {
+			add(src, dst)
+		}
+	}
+	sources := append(expandNodeList(nodes, o.SourceNodes), taggedNodeIDs(nodes, o.SourceTags)...)
+	destinations := append(expandNodeList(nodes, o.DestinationNodes), taggedNodeIDs(nodes, o.DestinationTags)...)
+	for _, src := range sources {
+		for _, dst := range destinations {
+			add(src, dst)
+		}
+	}
+	return pairs
 }
 
 // OutputConfig defines output settings
 type OutputConfig struct {
-	JSONFile          string `yaml:"json_file"`
-	CSVFile           string `yaml:"csv_file,omitempty"`
-	SchemaFile        string `yaml:"schema_file,omitempty"`
-	Compress          bool   `yaml:"compress"`
-	SaveDaemonResults bool   `yaml:"save_daemon_results"`        // Instruct daemons to save local copies
-	SaveRawResults    bool   `yaml:"save_raw_results"`           // Save raw results from all daemons
-	RawResultsFile    string `yaml:"raw_results_file,omitempty"` // File for raw results (default: raw_results_<timestamp>.json)
+	JSONFile          string         `yaml:"json_file"`
+	CSVFile           string         `yaml:"csv_file,omitempty"`
+	SchemaFile        string         `yaml:"schema_file,omitempty"`
+	BurstRows         bool           `yaml:"burst_rows"` // Include per-burst rows for burst profiles
+	Compress          bool           `yaml:"compress"`
+	SaveDaemonResults bool           `yaml:"save_daemon_results"`        // Instruct daemons to save local copies
+	SaveRawResults    bool           `yaml:"save_raw_results"`           // Save raw results from all daemons
+	RawResultsFile    string         `yaml:"raw_results_file,omitempty"` // File for raw results (default: raw_results_<timestamp>.json)
+	Filters           *OutputFilters `yaml:"filters,omitempty"`
+	EmbedConfig       bool           `yaml:"embed_config"`                // Include the effective, redacted config in the JSON output
+	TimelineCSVFile   string         `yaml:"timeline_csv_file,omitempty"` // Aggregate throughput over time, one row per bucket
+	TimestampSuffix   bool           `yaml:"timestamp_suffix"`            // Add the run's start time to every output file name
+	// FlakinessCSVFile is written with the flakiness of each pair, one row
+	// per pair, when some pair ran more than once
+	FlakinessCSVFile string `yaml:"flakiness_csv_file,omitempty"`
+
+	// Runs of more tests than StreamingThreshold aggregate their results as
+	// they are collected, writing each to StreamFile as one JSON object per
+	// line, and keep only a sample of them in the JSON output, so that the
+	// controller's memory stays bounded. Negative disables streaming.
+	StreamingThreshold int    `yaml:"streaming_threshold"`   // Default: 20000
+	StreamFile         string `yaml:"stream_file,omitempty"` // Default: the JSON file with an .ndjson extension
+}
+
+// DefaultStreamingThreshold is the number of tests above which a run
+// streams its results unless configured otherwise
+const DefaultStreamingThreshold = 20000
+
+// OutputFilters selects the results written to the JSON and CSV files.
+// Each rule is a list of key=value terms that must all match, as parsed by
+// output.NewFilter; the summary always covers every result.
+type OutputFilters struct {
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
 }
 
 // ConcurrencyConfig controls parallelism and batching
 type ConcurrencyConfig struct {
-	MaxConcurrentNodes   int `yaml:"max_concurrent_nodes"`
+	MaxConcurrentNodes int `yaml:"max_concurrent_nodes"`
+	// Client processes running at once across all nodes, bidirectional ones
+	// counted twice; larger runs start their tests in waves as earlier ones
+	// finish. Negative is unbounded.
 	MaxConcurrentTests   int `yaml:"max_concurrent_tests"`
 	ClientStartBatchSize int `yaml:"client_start_batch_size"`
 	ConnectionTimeout    int `yaml:"connection_timeout_seconds"`
 	RPCTimeout           int `yaml:"rpc_timeout_seconds"`
+
+	// Just before the run's first state-changing request, every node must
+	// answer a status request within this long; negative skips the check
+	LivenessTimeoutSeconds int `yaml:"liveness_timeout_seconds"`
+
+	// Once tests are past their expected end, the wait phase gives up on
+	// those still running when none has finished for this long. Negative
+	// waits a fixed time instead.
+	StallWindowSeconds int  `yaml:"stall_window_seconds"`
+	StopStalledTests   bool `yaml:"stop_stalled_tests"` // Stop stalled tests instead of leaving them to cleanup
+
+	// Cleanup waits this long for the daemons' processes to exit, then
+	// force-stops those left and waits as long again. Negative skips the
+	// check.
+	ProcessStopSeconds int `yaml:"process_stop_seconds"`
+
+	// Status polls vary their interval by up to this percentage either way,
+	// so that pollers started together drift apart. Negative disables it.
+	PollJitterPercent int `yaml:"poll_jitter_percent"`
+	// Nodes whose status polls fail are polled at exponentially growing
+	// intervals up to this long. Negative disables the backoff.
+	MaxPollBackoffSeconds int `yaml:"max_poll_backoff_seconds"`
+	// Upper bound on status polls per second across all nodes; polling
+	// slows down to respect it. 0 is unbounded.
+	MaxPollRate float64 `yaml:"max_poll_rate"`
+
+	// Tests involving a node in a blackout window wait for it to end, unless
+	// that takes longer than this; then they are skipped. Negative skips
+	// them without waiting.
+	MaxBlackoutWaitSeconds int `yaml:"max_blackout_wait_seconds"`
+}
+
+// DefaultLivenessTimeoutSeconds is how long each node has to answer the
+// liveness check before a run unless configured otherwise
+const DefaultLivenessTimeoutSeconds = 5
+
+// DefaultMaxBlackoutWaitSeconds is how long tests wait for blackout windows
+// of their nodes to end unless configured otherwise
+const DefaultMaxBlackoutWaitSeconds = 7200
+
+// PortRangeConfig restricts this run's server ports to a sub-range of the
+// daemons' port range, so runs sharing a daemon fleet do not collide
+type PortRangeConfig struct {
+	Start int    `yaml:"start"`
+	End   int    `yaml:"end"`
+	Owner string `yaml:"owner,omitempty"` // Identifies this run on the daemons (default: hostname/pid)
+}
+
+// OnFailureConfig controls cleanup when a run fails part-way. Unset flags
+// take their defaults: stop_all and collect_partial on, clear_results off.
+type OnFailureConfig struct {
+	StopAll            *bool  `yaml:"stop_all,omitempty"`             // Stop all iperf3 processes on every daemon
+	ClearResults       *bool  `yaml:"clear_results,omitempty"`        // Drop results stored on the daemons
+	CollectPartial     *bool  `yaml:"collect_partial,omitempty"`      // Collect whatever results exist
+	PartialResultsFile string `yaml:"partial_results_file,omitempty"` // Default: partial-results.json next to json_file
+}
+
+// Size returns the number of ports in the range
+func (r *PortRangeConfig) Size() int {
+	return r.End - r.Start + 1
+}
+
+// validate checks an optional range, naming it by key in errors
+func (r *PortRangeConfig) validate(key string) error {
+	if r == nil {
+		return nil
+	}
+	if r.Start < 1 || r.Start > 65535 || r.End < 1 || r.End > 65535 {
+		return fmt.Errorf("%s start and end must be between 1 and 65535", key)
+	}
+	if r.Start > r.End {
+		return fmt.Errorf("%s start must not exceed end", key)
+	}
+	return nil
+}
+
+// NodeDaemonPortRange returns the mirrored daemon port range of the node
+// with the given ID, or nil if none is configured
+func (c *ControllerConfig) NodeDaemonPortRange(nodeID string) *PortRangeConfig {
+	for _, node := range c.Controller.Nodes {
+		if node.ID == nodeID && node.DaemonPortRange != nil {
+			return node.DaemonPortRange
+		}
+	}
+	return c.Controller.DaemonPortRange
+}
+
+// DirectedEnabled reports whether a mesh tests each pair of nodes both ways
+func (t *TopologyConfig) DirectedEnabled() bool {
+	return t.Directed == nil || *t.Directed
+}
+
+// StopAllEnabled reports whether processes are stopped after a failure
+func (c OnFailureConfig) StopAllEnabled() bool {
+	return c.StopAll == nil || *c.StopAll
+}
+
+// ClearResultsEnabled reports whether daemon results are cleared after a failure
+func (c OnFailureConfig) ClearResultsEnabled() bool {
+	return c.ClearResults != nil && *c.ClearResults
+}
+
+// CollectPartialEnabled reports whether partial results are collected after a failure
+func (c OnFailureConfig) CollectPartialEnabled() bool {
+	return c.CollectPartial == nil || *c.CollectPartial
+}
+
+// NodeCacheConfig controls the cache of daemon capabilities and facts kept
+// between runs
+type NodeCacheConfig struct {
+	Disabled   bool   `yaml:"disabled,omitempty"`
+	File       string `yaml:"file,omitempty"`        // Default: iperf-cnc/node-cache.json in the user cache dir
+	TTLSeconds int    `yaml:"ttl_seconds,omitempty"` // Default: 86400
+}
+
+// DefaultControllerDebugPort is the controller's debug listener port when
+// unset, distinct from the daemon's so both can run on one host
+const DefaultControllerDebugPort = 6061
+
+// DefaultNodeCacheTTL is the node cache entry lifetime when ttl_seconds is unset
+const DefaultNodeCacheTTL = 24 * 60 * 60
+
+// AnalysisConfig controls post-collection analysis of results
+type AnalysisConfig struct {
+	// DiscrepancyThresholdPercent flags tests whose sender- and
+	// receiver-reported throughput differ by more than this percentage
+	// (default: DefaultDiscrepancyThresholdPercent); 0 flags any difference
+	DiscrepancyThresholdPercent *float64 `yaml:"discrepancy_threshold_percent,omitempty"`
+	// RetransmitThresholdPerMB flags tests with more TCP retransmits per MB
+	// sent than this; 0 disables flagging
+	RetransmitThresholdPerMB float64 `yaml:"retransmit_threshold_per_mb"`
+	// TimelineBucketSeconds is the width of the buckets of the aggregate
+	// throughput timeline (default: 1)
+	TimelineBucketSeconds float64 `yaml:"timeline_bucket_seconds,omitempty"`
+	// V6LagThresholdPercent flags dual-stack pairs whose IPv6 throughput is
+	// more than this percentage below their IPv4 throughput (default: 10);
+	// negative disables flagging
+	V6LagThresholdPercent float64 `yaml:"v6_lag_threshold_percent,omitempty"`
+	// MTUHeuristics probes low-throughput TCP pairs after the run at each
+	// of MTUProbeMSS for MTUProbeSeconds, reporting pairs degraded only at
+	// larger segment sizes. The probes must finish within
+	// MTUProbeBudgetSeconds; negative lifts the limit.
+	MTUHeuristics         bool    `yaml:"mtu_heuristics,omitempty"`
+	MTUProbeMSS           []int   `yaml:"mtu_probe_mss,omitempty"`            // Default: 1400, 4000, 8900
+	MTUProbeSeconds       float64 `yaml:"mtu_probe_seconds,omitempty"`        // Default: 2
+	MTUProbeBudgetSeconds int     `yaml:"mtu_probe_budget_seconds,omitempty"` // Default: 60
+	// MinSuccessRatio fails the run, after its output is written, if any
+	// pair completed a smaller share of its attempts; 0 disables the check
+	MinSuccessRatio float64 `yaml:"min_success_ratio,omitempty"`
+	// Confidence tunes the rules rating how far each result can be trusted
+	Confidence ConfidenceConfig `yaml:"confidence,omitempty"`
+}
+
+// Defaults of the MTU heuristics
+const (
+	DefaultMTUProbeSeconds       = 2
+	DefaultMTUProbeBudgetSeconds = 60
+)
+
+// DefaultMTUProbeMSS are the MSS values MTU heuristics probe by default:
+// below a standard 1500 byte MTU, between, and near a 9000 byte jumbo MTU
+var DefaultMTUProbeMSS = []int{1400, 4000, 8900}
+
+// DefaultDiscrepancyThresholdPercent is the discrepancy threshold when
+// discrepancy_threshold_percent is unset
+const DefaultDiscrepancyThresholdPercent = 5.0
+
+// DiscrepancyThreshold returns the discrepancy threshold percentage, which
+// is kept when set to 0
+func (a AnalysisConfig) DiscrepancyThreshold() float64 {
+	if a.DiscrepancyThresholdPercent == nil {
+		return DefaultDiscrepancyThresholdPercent
+	}
+	return *a.DiscrepancyThresholdPercent
 }
 
 // LoadControllerConfig loads controller configuration from a YAML file
 func LoadControllerConfig(path string) (*ControllerConfig, error) {
+	config, _, err := LoadControllerConfigWorkload(path, "")
+	return config, err
+}
+
+// LoadControllerConfigWorkload loads controller configuration like
+// LoadControllerConfig, first applying the named workload, if any, to the
+// fields the file leaves unset
+func LoadControllerConfigWorkload(path, workload string) (*ControllerConfig, *WorkloadExpansion, error) {
+	config, err := readControllerConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var expansion *WorkloadExpansion
+	if workload != "" {
+		if expansion, err = config.ApplyWorkload(workload); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return config, expansion, nil
+}
+
+// readControllerConfig parses a controller configuration file without
+// validating it
+func readControllerConfig(path string) (*ControllerConfig, error) {
 	data, err := os.ReadFile(path) // #nosec G304 -- Config file path is provided by user
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -95,11 +575,9 @@ func LoadControllerConfig(path string) (*ControllerConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
-
-	if err := config.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid configuration: %w", err)
+	if err := config.loadTopologyFile(path); err != nil {
+		return nil, err
 	}
-
 	return &config, nil
 }
 
@@ -111,6 +589,7 @@ func (c *ControllerConfig) Validate() error {
 
 	// Validate nodes
 	nodeIDs := make(map[string]bool)
+	addresses := make(map[string]string) // ip:port -> node ID
 	for i, node := range c.Controller.Nodes {
 		if node.Hostname == "" {
 			return fmt.Errorf("node[%d]: hostname cannot be empty", i)
@@ -131,6 +610,25 @@ func (c *ControllerConfig) Validate() error {
 			return fmt.Errorf("duplicate node ID: %s", id)
 		}
 		nodeIDs[id] = true
+
+		// Two entries for one daemon would give it both nodes' assignments
+		address := net.JoinHostPort(node.IP, strconv.Itoa(node.Port))
+		if other, exists := addresses[address]; exists {
+			return fmt.Errorf("nodes %s and %s have the same daemon address %s", other, id, address)
+		}
+		addresses[address] = id
+
+		for _, addr := range node.DataIPs {
+			if net.ParseIP(addr) == nil {
+				return fmt.Errorf("node[%d]: data_ips: %q is not an IP address", i, addr)
+			}
+		}
+		if err := validateInterfaces(node.Interfaces); err != nil {
+			return fmt.Errorf("node[%d]: interfaces: %w", i, err)
+		}
+	}
+	if err := c.normalizeTags(); err != nil {
+		return err
 	}
 
 	// Validate test profiles
@@ -149,40 +647,866 @@ func (c *ControllerConfig) Validate() error {
 		return fmt.Errorf("topology type cannot be empty")
 	}
 
-	validTopologyTypes := map[string]bool{
-		"full_mesh": true,
-		"custom":    true,
+	if err := checkValue("topology type", "controller.topology.type", c.Controller.Topology.Type); err != nil {
+		return err
 	}
-	if !validTopologyTypes[c.Controller.Topology.Type] {
-		return fmt.Errorf("topology type must be one of: full_mesh, custom")
+
+	if c.Controller.Topology.Type == "rotating" {
+		if c.Controller.Topology.PairsPerRun < 1 {
+			return fmt.Errorf("rotating topology requires pairs_per_run of at least 1")
+		}
+		if len(c.Controller.Topology.Groups) > 0 {
+			return fmt.Errorf("rotating topology cannot be combined with groups")
+		}
+	} else if c.Controller.Topology.PairsPerRun != 0 {
+		return fmt.Errorf("pairs_per_run is only valid for rotating topologies")
+	}
+
+	if err := c.validateStar(); err != nil {
+		return err
+	}
+	if err := c.validateRing(); err != nil {
+		return err
+	}
+	if err := c.validateChain(); err != nil {
+		return err
+	}
+	if err := c.validateTagSelection(); err != nil {
+		return err
+	}
+	if err := c.validateTagGroups(); err != nil {
+		return err
+	}
+	if err := c.validateOneToMany(); err != nil {
+		return err
+	}
+	if err := c.validateManyToOne(); err != nil {
+		return err
+	}
+	if err := c.validatePartialMesh(); err != nil {
+		return err
+	}
+	if err := c.validateBisection(); err != nil {
+		return err
+	}
+	if err := c.validateCustom(); err != nil {
+		return err
+	}
+	if err := c.validateExclusions(); err != nil {
+		return err
+	}
+	if err := c.validateSchedule(); err != nil {
+		return err
+	}
+	if err := c.validatePerInterface(); err != nil {
+		return err
+	}
+	if err := c.validateDirected(); err != nil {
+		return err
 	}
 
 	if c.Controller.Topology.DefaultProfile == "" {
 		return fmt.Errorf("topology default_profile cannot be empty")
 	}
 
+	families := make(map[models.AddressFamily]bool)
+	for _, name := range c.Controller.Topology.AddressFamilies {
+		family, err := models.ParseAddressFamily(name)
+		if err != nil {
+			return fmt.Errorf("topology address_families: %w", err)
+		}
+		if families[family] {
+			return fmt.Errorf("topology address_families: %s is listed twice", family)
+		}
+		families[family] = true
+	}
+
 	if _, exists := c.Controller.TestProfiles[c.Controller.Topology.DefaultProfile]; !exists {
 		return fmt.Errorf("default_profile '%s' not found in test_profiles", c.Controller.Topology.DefaultProfile)
 	}
 
+	if c.Controller.Logging.ProgressIntervalSeconds < 0 {
+		return fmt.Errorf("logging progress_interval_seconds cannot be negative")
+	}
+
+	if policy := c.Controller.CongestionControlPolicy; policy != "" {
+		if err := checkValue("congestion_control_policy", "controller.congestion_control_policy", policy); err != nil {
+			return err
+		}
+	}
+
+	if c.Controller.NodeCache.TTLSeconds < 0 {
+		return fmt.Errorf("node_cache: ttl_seconds cannot be negative")
+	}
+
+	topo := &c.Controller.Topology
+	for i, override := range topo.Overrides {
+		if err := c.normalizeSelectorTags(&topo.Overrides[i]); err != nil {
+			return fmt.Errorf("%s: %w", topo.entry("override", i), err)
+		}
+		if err := c.checkSelectorProfile(override.Profile); err != nil {
+			return fmt.Errorf("%s: %w", topo.entry("override", i), err)
+		}
+		if err := validateOverrideDuration(override.Duration); err != nil {
+			return fmt.Errorf("%s: %w", topo.entry("override", i), err)
+		}
+		if override.StartDelayMs < 0 {
+			return fmt.Errorf("%s: start_delay_ms cannot be negative", topo.entry("override", i))
+		}
+		if override.Repeat < 0 {
+			return fmt.Errorf("%s: repeat cannot be negative", topo.entry("override", i))
+		}
+		if override.Repeat > 1 && override.Background {
+			return fmt.Errorf("%s: background pairs cannot be repeated", topo.entry("override", i))
+		}
+	}
+
+	groupNames := make(map[string]bool)
+	for i, group := range c.Controller.Topology.Groups {
+		if group.Name == "" {
+			return fmt.Errorf("topology group[%d]: name cannot be empty", i)
+		}
+		if groupNames[group.Name] {
+			return fmt.Errorf("duplicate topology group name: %s", group.Name)
+		}
+		groupNames[group.Name] = true
+
+		pairCount := 0
+		for j := range group.Pairs {
+			if err := c.normalizeSelectorTags(&group.Pairs[j]); err != nil {
+				return fmt.Errorf("%s: %w", topo.group(group.Name), err)
+			}
+			selector := group.Pairs[j]
+			if err := c.checkSelectorProfile(selector.Profile); err != nil {
+				return fmt.Errorf("%s: %w", topo.group(group.Name), err)
+			}
+			if err := validateOverrideDuration(selector.Duration); err != nil {
+				return fmt.Errorf("%s: %w", topo.group(group.Name), err)
+			}
+			if selector.StartDelayMs < 0 {
+				return fmt.Errorf("%s: start_delay_ms cannot be negative", topo.group(group.Name))
+			}
+			if selector.Repeat < 0 {
+				return fmt.Errorf("%s: repeat cannot be negative", topo.group(group.Name))
+			}
+			if selector.Background {
+				return fmt.Errorf("%s: pairs cannot be background; set background on the group", topo.group(group.Name))
+			}
+			pairCount += len(selector.Pairs(c.Controller.Nodes))
+		}
+		if pairCount == 0 {
+			return fmt.Errorf("%s: pairs must select at least one node pair", topo.group(group.Name))
+		}
+	}
+	for _, group := range c.Controller.Topology.Groups {
+		for _, dep := range group.After {
+			if !groupNames[dep] {
+				return fmt.Errorf("%s: unknown group %q in after", topo.group(group.Name), dep)
+			}
+		}
+	}
+
 	// Validate output
 	if c.Controller.Output.JSONFile == "" {
 		return fmt.Errorf("output json_file cannot be empty")
 	}
 
+	// Validate port ranges
+	if err := c.Controller.PortRange.validate("port_range"); err != nil {
+		return err
+	}
+	if err := c.Controller.DaemonPortRange.validate("daemon_port_range"); err != nil {
+		return err
+	}
+	for i, node := range c.Controller.Nodes {
+		if err := node.DaemonPortRange.validate("daemon_port_range"); err != nil {
+			return fmt.Errorf("node[%d]: %w", i, err)
+		}
+		if _, err := node.BlackoutWindows(); err != nil {
+			return fmt.Errorf("node[%d]: %w", i, err)
+		}
+	}
+
+	// Validate analysis
+	if c.Controller.Analysis.DiscrepancyThreshold() < 0 {
+		return fmt.Errorf("analysis discrepancy_threshold_percent cannot be negative")
+	}
+	if c.Controller.Analysis.RetransmitThresholdPerMB < 0 {
+		return fmt.Errorf("analysis retransmit_threshold_per_mb cannot be negative")
+	}
+	if c.Controller.Analysis.TimelineBucketSeconds < 0 {
+		return fmt.Errorf("analysis timeline_bucket_seconds cannot be negative")
+	}
+	if ratio := c.Controller.Analysis.MinSuccessRatio; ratio < 0 || ratio > 1 {
+		return fmt.Errorf("analysis min_success_ratio must be between 0 and 1")
+	}
+	for _, mss := range c.Controller.Analysis.MTUProbeMSS {
+		if mss < 88 || mss > 65495 {
+			return fmt.Errorf("analysis mtu_probe_mss: %d is outside the TCP MSS range 88-65495", mss)
+		}
+	}
+	if probes := c.Controller.Analysis.MTUProbeMSS; probes != nil && len(probes) < 2 {
+		return fmt.Errorf("analysis mtu_probe_mss needs at least 2 values to compare")
+	}
+	if seconds := c.Controller.Analysis.MTUProbeSeconds; seconds != 0 && units.Seconds(seconds) < models.MinDuration {
+		return fmt.Errorf("analysis mtu_probe_seconds must be at least %s", units.FormatSeconds(models.MinDuration))
+	}
+	if err := c.Controller.Analysis.Confidence.validate(); err != nil {
+		return err
+	}
+	if err := c.Controller.ResourceSampling.validate(); err != nil {
+		return err
+	}
+	if err := c.Controller.Quorum.validate(len(c.Controller.Nodes)); err != nil {
+		return err
+	}
+	if err := c.Controller.Concurrency.validatePolling(); err != nil {
+		return err
+	}
+	if err := c.validateWorkloads(); err != nil {
+		return err
+	}
+
+	if err := c.Controller.Debug.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateStar checks the hub and direction of a star topology, which must
+// name a node or a tag of one, normalizing a hub tag like node tags
+func (c *ControllerConfig) validateStar() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "star" {
+		if topo.Hub != "" || topo.Direction != "" {
+			return fmt.Errorf("hub and direction are only valid for star topologies")
+		}
+		return nil
+	}
+
+	if topo.Hub == "" {
+		return fmt.Errorf("star topology requires a hub")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("star topology cannot be combined with groups")
+	}
+	if topo.Direction != "" {
+		if err := checkValue("topology direction", "controller.topology.direction", topo.Direction); err != nil {
+			return err
+		}
+	}
+
+	for _, node := range c.Controller.Nodes {
+		if node.nodeID() == topo.Hub {
+			return nil
+		}
+	}
+	tag := models.NormalizeTag(topo.Hub, c.Controller.TagPolicy.Lowercase)
+	for _, node := range c.Controller.Nodes {
+		if contains(node.Tags, tag) {
+			topo.Hub = tag
+			return nil
+		}
+	}
+	return fmt.Errorf("star hub %q is neither a node ID nor a tag of a node", topo.Hub)
+}
+
+// validateRing checks the node order of a ring topology, which must only
+// list configured nodes, once each
+func (c *ControllerConfig) validateRing() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "ring" {
+		if topo.BidirectionalRing {
+			return fmt.Errorf("bidirectional_ring is only valid for ring topologies")
+		}
+		if len(topo.Order) > 0 && topo.Type != "chain" {
+			return fmt.Errorf("order is only valid for ring and chain topologies")
+		}
+		return nil
+	}
+
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("ring topology cannot be combined with groups")
+	}
+	return c.checkOrder("ring")
+}
+
+// validateChain checks the order of a chain topology, which must list at
+// least 2 configured nodes, each once
+func (c *ControllerConfig) validateChain() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "chain" {
+		if topo.IncludeEndToEnd {
+			return fmt.Errorf("include_end_to_end is only valid for chain topologies")
+		}
+		return nil
+	}
+
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("chain topology cannot be combined with groups")
+	}
+	if len(topo.Order) < 2 {
+		return fmt.Errorf("chain topology requires an order of at least 2 nodes")
+	}
+	return c.checkOrder("chain")
+}
+
+// checkOrder checks that the topology order names configured nodes, each
+// once, naming the topology kind in errors
+func (c *ControllerConfig) checkOrder(kind string) error {
+	nodeIDs := make(map[string]bool, len(c.Controller.Nodes))
+	for _, node := range c.Controller.Nodes {
+		nodeIDs[node.nodeID()] = true
+	}
+	listed := make(map[string]bool, len(c.Controller.Topology.Order))
+	for _, id := range c.Controller.Topology.Order {
+		if !nodeIDs[id] {
+			return fmt.Errorf("%s order names unknown node %q", kind, id)
+		}
+		if listed[id] {
+			return fmt.Errorf("%s order lists node %q more than once", kind, id)
+		}
+		listed[id] = true
+	}
+	return nil
+}
+
+// validateTagSelection checks the source and destination tags of a full
+// mesh topology, which must be set together and carried by nodes
+func (c *ControllerConfig) validateTagSelection() error {
+	topo := &c.Controller.Topology
+	if len(topo.SourceTags) == 0 && len(topo.DestinationTags) == 0 {
+		return nil
+	}
+	if topo.Type != "full_mesh" {
+		return fmt.Errorf("source_tags and destination_tags are only valid for full_mesh topologies")
+	}
+	if len(topo.SourceTags) == 0 || len(topo.DestinationTags) == 0 {
+		return fmt.Errorf("topology source_tags and destination_tags must be set together")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("topology source_tags and destination_tags cannot be combined with groups")
+	}
+	if err := c.normalizeTagList("topology source_tags", topo.SourceTags); err != nil {
+		return err
+	}
+	return c.normalizeTagList("topology destination_tags", topo.DestinationTags)
+}
+
+// validateTagGroups checks the tag key and mode a mesh topology groups
+// nodes by, which must be set together and the key carried by a node
+func (c *ControllerConfig) validateTagGroups() error {
+	topo := &c.Controller.Topology
+	if topo.GroupBy == "" && topo.GroupMode == "" {
+		return nil
+	}
+	switch topo.Type {
+	case "full_mesh", "partial_mesh", "rotating":
+	default:
+		return fmt.Errorf("group_by and group_mode are only valid for full_mesh, partial_mesh and rotating topologies")
+	}
+	if topo.GroupBy == "" || topo.GroupMode == "" {
+		return fmt.Errorf("topology group_by and group_mode must be set together")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("topology group_by cannot be combined with groups")
+	}
+	if err := checkValue("topology group_mode", "controller.topology.group_mode", topo.GroupMode); err != nil {
+		return err
+	}
+
+	topo.GroupBy = models.NormalizeTag(topo.GroupBy, c.Controller.TagPolicy.Lowercase)
+	if strings.Contains(topo.GroupBy, ":") {
+		return fmt.Errorf("topology group_by %q must be a tag key, such as rack for rack:a1 tags", topo.GroupBy)
+	}
+	if err := models.ValidateTag(topo.GroupBy); err != nil {
+		return fmt.Errorf("topology group_by: %w", err)
+	}
+	for _, node := range c.Controller.Nodes {
+		for _, tag := range node.Tags {
+			if key, _, isPair := strings.Cut(tag, ":"); isPair && key == topo.GroupBy {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("topology group_by: no node has a %s:<value> tag", topo.GroupBy)
+}
+
+// validateSchedule checks how the topology's pairs are scheduled. Rounds
+// are groups of their own, so they cannot be combined with configured ones.
+func (c *ControllerConfig) validateSchedule() error {
+	topo := &c.Controller.Topology
+	if topo.Schedule == "" {
+		return nil
+	}
+	if err := checkValue("topology schedule", "controller.topology.schedule", topo.Schedule); err != nil {
+		return err
+	}
+	if topo.Schedule == ScheduleRounds && len(topo.Groups) > 0 {
+		return fmt.Errorf("topology schedule rounds cannot be combined with groups")
+	}
+	return nil
+}
+
+// validateDirected checks that only mesh topologies are undirected; the
+// other types list their pairs in one direction already
+func (c *ControllerConfig) validateDirected() error {
+	topo := &c.Controller.Topology
+	if topo.DirectedEnabled() {
+		return nil
+	}
+	if topo.Type != "full_mesh" && topo.Type != "partial_mesh" {
+		return fmt.Errorf("directed is only valid for full_mesh and partial_mesh topologies")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("topology directed cannot be combined with groups")
+	}
+	return nil
+}
+
+// validateInterfaces checks the interfaces of a node: each needs a name,
+// used in test IDs, that no other interface of the node has, and an IP
+// address
+func validateInterfaces(interfaces []InterfaceConfig) error {
+	names := make(map[string]bool, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Name == "" {
+			return fmt.Errorf("name cannot be empty")
+		}
+		if strings.IndexFunc(iface.Name, invalidInterfaceRune) >= 0 {
+			return fmt.Errorf("name %q may only contain letters, digits, '.', '_' and '-'", iface.Name)
+		}
+		if names[iface.Name] {
+			return fmt.Errorf("%s is listed twice", iface.Name)
+		}
+		names[iface.Name] = true
+		if net.ParseIP(iface.IP) == nil {
+			return fmt.Errorf("%s: %q is not an IP address", iface.Name, iface.IP)
+		}
+	}
+	return nil
+}
+
+// invalidInterfaceRune reports whether r cannot appear in interface names
+func invalidInterfaceRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '_' && r != '-'
+}
+
+// validatePerInterface checks that a per_interface topology has nodes to
+// expand: every node needs interfaces, or its pairs would silently vanish.
+// Interfaces already choose the addresses, so address_families cannot be
+// set too.
+func (c *ControllerConfig) validatePerInterface() error {
+	topo := &c.Controller.Topology
+	if !topo.PerInterface {
+		return nil
+	}
+	if len(topo.AddressFamilies) > 0 {
+		return fmt.Errorf("topology per_interface cannot be combined with address_families")
+	}
+	for _, node := range c.Controller.Nodes {
+		if len(node.Interfaces) == 0 {
+			return fmt.Errorf("topology per_interface: node %s has no interfaces", node.nodeID())
+		}
+	}
 	return nil
 }
 
+// validateOneToMany checks the source of a one-to-many topology, which must
+// name a configured node
+func (c *ControllerConfig) validateOneToMany() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "one_to_many" {
+		if topo.Source != "" {
+			return fmt.Errorf("source is only valid for one_to_many topologies")
+		}
+		return nil
+	}
+
+	if topo.Source == "" {
+		return fmt.Errorf("one_to_many topology requires a source")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("one_to_many topology cannot be combined with groups")
+	}
+	for _, node := range c.Controller.Nodes {
+		if node.nodeID() == topo.Source {
+			return nil
+		}
+	}
+	return fmt.Errorf("one_to_many source %q is not a node ID", topo.Source)
+}
+
+// validateManyToOne checks the destination of a many-to-one topology,
+// which must name a configured node
+func (c *ControllerConfig) validateManyToOne() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "many_to_one" {
+		if topo.Destination != "" {
+			return fmt.Errorf("destination is only valid for many_to_one topologies")
+		}
+		return nil
+	}
+
+	if topo.Destination == "" {
+		return fmt.Errorf("many_to_one topology requires a destination")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("many_to_one topology cannot be combined with groups")
+	}
+	for _, node := range c.Controller.Nodes {
+		if node.nodeID() == topo.Destination {
+			return nil
+		}
+	}
+	return fmt.Errorf("many_to_one destination %q is not a node ID", topo.Destination)
+}
+
+// validatePartialMesh checks the density or test budget of a partial mesh
+// topology
+func (c *ControllerConfig) validatePartialMesh() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "partial_mesh" {
+		if topo.Density != 0 || topo.MaxTests != 0 || topo.Seed != 0 {
+			return fmt.Errorf("density, max_tests and seed are only valid for partial_mesh topologies")
+		}
+		return nil
+	}
+
+	if topo.MaxTests != 0 {
+		if topo.Density != 0 {
+			return fmt.Errorf("partial_mesh topology takes a density or max_tests, not both")
+		}
+		if topo.MaxTests < 0 {
+			return fmt.Errorf("partial_mesh max_tests must be positive")
+		}
+	} else if topo.Density <= 0 || topo.Density > 1 {
+		return fmt.Errorf("partial_mesh topology requires a density greater than 0 and at most 1, or max_tests")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("partial_mesh topology cannot be combined with groups")
+	}
+	return nil
+}
+
+// validateBisection checks the sides of a bisection topology, which must
+// select disjoint sets of nodes of the same size unless allow_unbalanced
+func (c *ControllerConfig) validateBisection() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "bisection" {
+		if len(topo.SideA) > 0 || len(topo.SideB) > 0 || topo.AllowUnbalanced {
+			return fmt.Errorf("side_a, side_b and allow_unbalanced are only valid for bisection topologies")
+		}
+		return nil
+	}
+
+	if len(topo.SideA) == 0 || len(topo.SideB) == 0 {
+		return fmt.Errorf("bisection topology requires side_a and side_b")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("bisection topology cannot be combined with groups")
+	}
+	if err := c.normalizeNodeList("topology side_a", topo.SideA); err != nil {
+		return err
+	}
+	if err := c.normalizeNodeList("topology side_b", topo.SideB); err != nil {
+		return err
+	}
+
+	sideA, sideB := topo.BisectionSides(c.Controller.Nodes)
+	onSideA := toSet(sideA)
+	for _, id := range sideB {
+		if onSideA[id] {
+			return fmt.Errorf("bisection node %q is on both sides", id)
+		}
+	}
+	if len(sideA) != len(sideB) && !topo.AllowUnbalanced {
+		return fmt.Errorf("bisection sides have %d and %d nodes; set allow_unbalanced to leave the extra nodes untested",
+			len(sideA), len(sideB))
+	}
+	return nil
+}
+
+// BisectionSides returns the node IDs of the two sides of a bisection
+// topology among nodes, tag: entries expanded, each node once per side
+func (t TopologyConfig) BisectionSides(nodes []NodeConfig) ([]string, []string) {
+	side := func(entries []string) []string {
+		ids := make([]string, 0, len(entries))
+		seen := make(map[string]bool)
+		for _, id := range expandNodeList(nodes, entries) {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	return side(t.SideA), side(t.SideB)
+}
+
+// checkSelectorProfile checks that the profile of an override or group
+// pairs entry, if set, names a test profile
+func (c *ControllerConfig) checkSelectorProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	if _, exists := c.Controller.TestProfiles[name]; !exists {
+		return fmt.Errorf("profile '%s' not found in test_profiles", name)
+	}
+	return nil
+}
+
+// validateCustom checks the pairs of a custom topology, which must name
+// configured nodes and profiles
+func (c *ControllerConfig) validateCustom() error {
+	topo := &c.Controller.Topology
+	if topo.Type != "custom" {
+		if len(topo.Pairs) > 0 {
+			return fmt.Errorf("pairs are only valid for custom topologies")
+		}
+		return nil
+	}
+
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("custom topology cannot be combined with groups")
+	}
+	if len(topo.Pairs) == 0 {
+		return fmt.Errorf("custom topology requires at least one entry in pairs")
+	}
+	nodeIDs := make(map[string]bool, len(c.Controller.Nodes))
+	for _, node := range c.Controller.Nodes {
+		nodeIDs[node.nodeID()] = true
+	}
+	for i, pair := range topo.Pairs {
+		for _, id := range []string{pair.Source, pair.Destination} {
+			if !nodeIDs[id] {
+				return fmt.Errorf("%s: unknown node %q", topo.entry("pairs", i), id)
+			}
+		}
+		if pair.Source == pair.Destination {
+			return fmt.Errorf("%s: source and destination must differ", topo.entry("pairs", i))
+		}
+		if pair.Profile != "" {
+			if _, exists := c.Controller.TestProfiles[pair.Profile]; !exists {
+				return fmt.Errorf("%s: profile '%s' not found in test_profiles", topo.entry("pairs", i), pair.Profile)
+			}
+		}
+		if pair.Repeat < 0 {
+			return fmt.Errorf("%s: repeat cannot be negative", topo.entry("pairs", i))
+		}
+	}
+	return nil
+}
+
+// validateExclusions checks the pairs excluded from the topology, which are
+// selected like override pairs but take no settings, and that a custom
+// topology lists none of them
+func (c *ControllerConfig) validateExclusions() error {
+	topo := &c.Controller.Topology
+	if len(topo.Exclusions) == 0 {
+		return nil
+	}
+	switch topo.Type {
+	case "full_mesh", "partial_mesh", "rotating", "custom":
+	default:
+		return fmt.Errorf("exclusions are only valid for full_mesh, partial_mesh, rotating and custom topologies")
+	}
+	if len(topo.Groups) > 0 {
+		return fmt.Errorf("topology exclusions cannot be combined with groups")
+	}
+
+	excluded := make(map[[2]string]bool)
+	for i := range topo.Exclusions {
+		exclusion := &topo.Exclusions[i]
+		if err := c.normalizeSelectorTags(exclusion); err != nil {
+			return fmt.Errorf("%s: %w", topo.entry("exclusion", i), err)
+		}
+		if exclusion.Profile != "" || exclusion.Duration != 0 || exclusion.StartDelayMs != 0 || exclusion.Background ||
+			exclusion.Repeat != 0 {
+			return fmt.Errorf("%s: only selects pairs; profile, duration, start_delay_ms, background and repeat do not apply",
+				topo.entry("exclusion", i))
+		}
+		pairs := exclusion.Pairs(c.Controller.Nodes)
+		if len(pairs) == 0 {
+			return fmt.Errorf("%s: must select at least one node pair", topo.entry("exclusion", i))
+		}
+		for _, pair := range pairs {
+			excluded[pair] = true
+		}
+	}
+	for i, pair := range topo.Pairs {
+		if excluded[[2]string{pair.Source, pair.Destination}] {
+			return fmt.Errorf("%s: %s -> %s is excluded by topology exclusions", topo.entry("pairs", i), pair.Source, pair.Destination)
+		}
+	}
+	return nil
+}
+
+// validateOverrideDuration checks a per-pair duration, where 0 keeps the
+// profile duration
+func validateOverrideDuration(seconds float64) error {
+	if seconds < 0 {
+		return fmt.Errorf("duration cannot be negative")
+	}
+	if seconds > 0 && units.Seconds(seconds) < models.MinDuration {
+		return fmt.Errorf("duration must be at least %s seconds", units.FormatSeconds(models.MinDuration))
+	}
+	return nil
+}
+
+// Warnings returns problems that do not make the configuration invalid but
+// likely make results meaningless, such as omitting the whole measurement
+func (c *ControllerConfig) Warnings() []string {
+	warnings := c.nodeWarnings()
+	warnings = append(warnings, c.tagWarnings()...)
+
+	names := make([]string, 0, len(c.Controller.TestProfiles))
+	for name := range c.Controller.TestProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		profile := c.Controller.TestProfiles[name]
+		if profile.Burst == nil && profile.OmitSeconds > 0 && float64(profile.OmitSeconds) >= profile.Duration {
+			warnings = append(warnings, fmt.Sprintf(
+				"profile '%s': omit_seconds (%d) is not shorter than the duration (%gs); nothing will be measured",
+				name, profile.OmitSeconds, profile.Duration))
+		}
+	}
+
+	for i, override := range c.Controller.Topology.Overrides {
+		profile, exists := c.Controller.TestProfiles[override.Profile]
+		if override.Profile == "" {
+			profile, exists = c.Controller.TestProfiles[c.Controller.Topology.DefaultProfile]
+		}
+		if !exists || profile.Burst != nil || override.Duration == 0 {
+			continue
+		}
+		if profile.OmitSeconds > 0 && float64(profile.OmitSeconds) >= override.Duration {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s: omit_seconds (%d) is not shorter than the duration (%gs); nothing will be measured",
+				c.Controller.Topology.entry("override", i), profile.OmitSeconds, override.Duration))
+		}
+	}
+
+	return warnings
+}
+
+// nodeWarnings reports node entries that probably describe the same host:
+// the same IP with different daemon ports, or the same hostname
+func (c *ControllerConfig) nodeWarnings() []string {
+	warnings := make([]string, 0)
+	byIP := make(map[string]string)
+	byHostname := make(map[string]string)
+
+	for _, node := range c.Controller.Nodes {
+		id := node.ID
+		if id == "" {
+			id = node.Hostname
+		}
+
+		if other, exists := byIP[node.IP]; exists {
+			warnings = append(warnings, fmt.Sprintf(
+				"nodes %s and %s share IP %s on different ports; tests against one host are counted twice", other, id, node.IP))
+		} else {
+			byIP[node.IP] = id
+		}
+
+		hostname := strings.ToLower(node.Hostname)
+		if other, exists := byHostname[hostname]; exists {
+			warnings = append(warnings, fmt.Sprintf(
+				"nodes %s and %s have the same hostname %s", other, id, node.Hostname))
+		} else {
+			byHostname[hostname] = id
+		}
+	}
+
+	return warnings
+}
+
 // validateTestProfile checks if a test profile is valid
 func validateTestProfile(name string, profile TestProfile) error {
-	if profile.Duration < 1 {
-		return fmt.Errorf("profile '%s': duration must be at least 1 second", name)
+	if units.Seconds(profile.Duration) < models.MinDuration && profile.Burst == nil {
+		return fmt.Errorf("profile '%s': duration must be at least %s seconds", name, units.FormatSeconds(models.MinDuration))
 	}
 
 	if profile.Parallel < 1 {
 		return fmt.Errorf("profile '%s': parallel must be at least 1", name)
 	}
 
+	if profile.Protocol != "" {
+		if err := checkValue("protocol", "controller.test_profiles.protocol", profile.Protocol); err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+	}
+
+	if profile.Bandwidth != "" {
+		if _, err := units.ParseBitRate(profile.Bandwidth); err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+	}
+
+	if profile.WindowSize != "" {
+		if _, err := units.ParseBytes(profile.WindowSize); err != nil {
+			return fmt.Errorf("profile '%s': window_size: %w", name, err)
+		}
+	}
+
+	if profile.ProcessesPerPair < 0 {
+		return fmt.Errorf("profile '%s': processes_per_pair cannot be negative", name)
+	}
+
+	if err := models.CheckPortsPerPair(profile.PortsPerPair, profile.ProcessesPerPair, profile.Parallel); err != nil {
+		return fmt.Errorf("profile '%s': %w", name, err)
+	}
+
+	if profile.SeparateReverse && !profile.Bidirectional {
+		return fmt.Errorf("profile '%s': separate_reverse requires bidirectional", name)
+	}
+
+	for _, rule := range profile.LintIgnore {
+		if !contains(LintRules(), rule) {
+			return fmt.Errorf("profile '%s': unknown lint rule '%s' in lint_ignore (known: %s)",
+				name, rule, strings.Join(LintRules(), ", "))
+		}
+	}
+
+	for _, trigger := range profile.CaptureOn {
+		if err := checkValue("capture_on", "controller.test_profiles.capture_on", trigger); err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+	}
+	if profile.CaptureThreshold != "" {
+		if !contains(profile.CaptureOn, "threshold") {
+			return fmt.Errorf("profile '%s': capture_threshold requires capture_on threshold", name)
+		}
+		if _, err := units.ParseBitRate(profile.CaptureThreshold); err != nil {
+			return fmt.Errorf("profile '%s': capture_threshold: %w", name, err)
+		}
+	} else if contains(profile.CaptureOn, "threshold") {
+		return fmt.Errorf("profile '%s': capture_on threshold requires a capture_threshold", name)
+	}
+
+	if profile.Burst != nil {
+		if profile.Protocol != "udp" {
+			return fmt.Errorf("profile '%s': burst requires protocol udp", name)
+		}
+		if profile.Bandwidth == "" || profile.Bandwidth == "0" {
+			return fmt.Errorf("profile '%s': burst requires a bandwidth", name)
+		}
+		if profile.Burst.OnMs < 1 {
+			return fmt.Errorf("profile '%s': burst on_ms must be at least 1", name)
+		}
+		if profile.Burst.OffMs < 0 {
+			return fmt.Errorf("profile '%s': burst off_ms cannot be negative", name)
+		}
+		if profile.Burst.Repeat < 1 {
+			return fmt.Errorf("profile '%s': burst repeat must be at least 1", name)
+		}
+	}
+
 	return nil
 }
 
@@ -202,6 +1526,12 @@ func (c *ControllerConfig) SetDefaults() {
 	if c.Controller.Concurrency.MaxConcurrentNodes == 0 {
 		c.Controller.Concurrency.MaxConcurrentNodes = 100
 	}
+	if c.Controller.Topology.MaxTotalTests == 0 {
+		c.Controller.Topology.MaxTotalTests = DefaultMaxTotalTests
+	}
+	if c.Controller.Topology.ConfirmTestsAbove == 0 {
+		c.Controller.Topology.ConfirmTestsAbove = DefaultConfirmTestsAbove
+	}
 	if c.Controller.Concurrency.MaxConcurrentTests == 0 {
 		c.Controller.Concurrency.MaxConcurrentTests = 1000
 	}
@@ -214,4 +1544,87 @@ func (c *ControllerConfig) SetDefaults() {
 	if c.Controller.Concurrency.RPCTimeout == 0 {
 		c.Controller.Concurrency.RPCTimeout = 60
 	}
+	if c.Controller.Concurrency.LivenessTimeoutSeconds == 0 {
+		c.Controller.Concurrency.LivenessTimeoutSeconds = DefaultLivenessTimeoutSeconds
+	}
+	if c.Controller.Concurrency.StallWindowSeconds == 0 {
+		c.Controller.Concurrency.StallWindowSeconds = 30
+	}
+	if c.Controller.Concurrency.ProcessStopSeconds == 0 {
+		c.Controller.Concurrency.ProcessStopSeconds = 10
+	}
+	if c.Controller.Concurrency.PollJitterPercent == 0 {
+		c.Controller.Concurrency.PollJitterPercent = 20
+	}
+	if c.Controller.Concurrency.MaxPollBackoffSeconds == 0 {
+		c.Controller.Concurrency.MaxPollBackoffSeconds = 30
+	}
+	if c.Controller.Concurrency.MaxBlackoutWaitSeconds == 0 {
+		c.Controller.Concurrency.MaxBlackoutWaitSeconds = DefaultMaxBlackoutWaitSeconds
+	}
+
+	// Identify this run to the daemons holding its port range
+	if c.Controller.PortRange != nil && c.Controller.PortRange.Owner == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "controller"
+		}
+		c.Controller.PortRange.Owner = fmt.Sprintf("%s/%d", hostname, os.Getpid())
+	}
+
+	if c.Controller.Output.StreamingThreshold == 0 {
+		c.Controller.Output.StreamingThreshold = DefaultStreamingThreshold
+	}
+	if c.Controller.Output.StreamFile == "" {
+		jsonFile := c.Controller.Output.JSONFile
+		if jsonFile == "" {
+			jsonFile = "results.json"
+		}
+		c.Controller.Output.StreamFile = strings.TrimSuffix(jsonFile, filepath.Ext(jsonFile)) + ".ndjson"
+	}
+
+	if c.Controller.NodeCache.File == "" {
+		// Fall back to the directory of the run's output without a user cache dir
+		cacheDir, err := os.UserCacheDir()
+		if err != nil {
+			cacheDir = filepath.Dir(c.Controller.Output.JSONFile)
+		}
+		c.Controller.NodeCache.File = filepath.Join(cacheDir, "iperf-cnc", "node-cache.json")
+	}
+	if c.Controller.NodeCache.TTLSeconds == 0 {
+		c.Controller.NodeCache.TTLSeconds = DefaultNodeCacheTTL
+	}
+
+	if c.Controller.Topology.Type == "rotating" && c.Controller.Topology.CoverageFile == "" {
+		c.Controller.Topology.CoverageFile = filepath.Join(
+			filepath.Dir(c.Controller.Output.JSONFile), "coverage.json")
+	}
+
+	if c.Controller.Logging.ProgressIntervalSeconds == 0 {
+		c.Controller.Logging.ProgressIntervalSeconds = 5
+	}
+
+	// Write partial results next to the main results file
+	if c.Controller.OnFailure.PartialResultsFile == "" {
+		c.Controller.OnFailure.PartialResultsFile = filepath.Join(
+			filepath.Dir(c.Controller.Output.JSONFile), "partial-results.json")
+	}
+
+	// Set analysis defaults
+	if c.Controller.Analysis.V6LagThresholdPercent == 0 {
+		c.Controller.Analysis.V6LagThresholdPercent = 10
+	}
+	if c.Controller.Analysis.MTUProbeMSS == nil {
+		c.Controller.Analysis.MTUProbeMSS = append([]int(nil), DefaultMTUProbeMSS...)
+	}
+	if c.Controller.Analysis.MTUProbeSeconds == 0 {
+		c.Controller.Analysis.MTUProbeSeconds = DefaultMTUProbeSeconds
+	}
+	if c.Controller.Analysis.MTUProbeBudgetSeconds == 0 {
+		c.Controller.Analysis.MTUProbeBudgetSeconds = DefaultMTUProbeBudgetSeconds
+	}
+	c.Controller.Analysis.Confidence.setDefaults()
+	c.Controller.ResourceSampling.setDefaults()
+
+	c.Controller.Debug.setDefaults(DefaultControllerDebugPort)
 }
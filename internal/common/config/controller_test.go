@@ -0,0 +1,919 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// newNodesConfig returns a valid configuration over the given nodes
+func newNodesConfig(nodes ...NodeConfig) *ControllerConfig {
+	return &ControllerConfig{Controller: ControllerSettings{
+		Nodes:        nodes,
+		TestProfiles: map[string]TestProfile{"default": {Duration: 10, Parallel: 1}},
+		Topology:     TopologyConfig{Type: "full_mesh", DefaultProfile: "default"},
+		Output:       OutputConfig{JSONFile: "results.json"},
+	}}
+}
+
+func TestValidate_DuplicateAddress(t *testing.T) {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+		NodeConfig{Hostname: "host-b", IP: "10.0.0.1", Port: 50051},
+	)
+
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "host-a and host-b have the same daemon address 10.0.0.1:50051") {
+		t.Errorf("Validate() error = %v, want duplicate daemon address", err)
+	}
+}
+
+func TestWarnings_OverlappingNodes(t *testing.T) {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+		NodeConfig{Hostname: "host-b", IP: "10.0.0.1", Port: 50052},
+		NodeConfig{Hostname: "Host-A", IP: "10.0.0.3", Port: 50051, ID: "node3"},
+		NodeConfig{Hostname: "host-d", IP: "10.0.0.4", Port: 50051},
+	)
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	warnings := cfg.Warnings()
+	want := []string{
+		"nodes host-a and host-b share IP 10.0.0.1 on different ports",
+		"nodes host-a and node3 have the same hostname Host-A",
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("Warnings() = %q, want %d warnings", warnings, len(want))
+	}
+	for i := range want {
+		if !strings.HasPrefix(warnings[i], want[i]) {
+			t.Errorf("Warnings()[%d] = %q, want %q", i, warnings[i], want[i])
+		}
+	}
+}
+
+func TestValidate_Tags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		lowercase bool
+		want      []string
+		wantErr   string
+	}{
+		{"trimmed", []string{" zone:us-east ", "prod"}, false, []string{"zone:us-east", "prod"}, ""},
+		{"lowercased", []string{"Zone:US-East"}, true, []string{"zone:us-east"}, ""},
+		{"case kept", []string{"Zone:us-east", "zone:us-east"}, false, []string{"Zone:us-east", "zone:us-east"}, ""},
+		{"duplicate after lowercasing", []string{"Zone:us-east", "zone:us-east"}, true, nil, "duplicate tag"},
+		{"invalid character", []string{"zone us-east"}, false, nil, "invalid character"},
+		{"empty value", []string{"zone:"}, false, nil, "value"},
+		{"reserved", []string{"not"}, false, nil, "reserved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: tt.tags},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			cfg.Controller.TagPolicy.Lowercase = tt.lowercase
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := strings.Join(cfg.Controller.Nodes[0].Tags, ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("tags = %s, want %s", got, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestValidate_Blackout(t *testing.T) {
+	tests := []struct {
+		name     string
+		blackout []BlackoutConfig
+		wantErr  string
+	}{
+		{"valid", []BlackoutConfig{{Start: "01:00", End: "03:00"}, {Start: "23:00", End: "00:30", Timezone: "UTC"}}, ""},
+		{"bad time", []BlackoutConfig{{Start: "1:00am", End: "03:00"}}, "node[0]: blackout[0]: start"},
+		{"empty window", []BlackoutConfig{{Start: "01:00", End: "01:00"}}, "the same time"},
+		{"unknown time zone", []BlackoutConfig{{Start: "01:00", End: "03:00", Timezone: "Nowhere/Place"}}, "timezone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Blackout: tt.blackout},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Star(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+		wantHub  string
+	}{
+		{"hub by ID", TopologyConfig{Type: "star", Hub: "host-a", Direction: "to_hub"}, "", "host-a"},
+		{"hub by tag", TopologyConfig{Type: "star", Hub: " Aggregation "}, "", "aggregation"},
+		{"missing hub", TopologyConfig{Type: "star"}, "requires a hub", ""},
+		{"unknown hub", TopologyConfig{Type: "star", Hub: "host-z"}, "neither a node ID nor a tag", ""},
+		{"bad direction", TopologyConfig{Type: "star", Hub: "host-a", Direction: "inbound"}, "topology direction must be one of", ""},
+		{"hub without star", TopologyConfig{Type: "full_mesh", Hub: "host-a"}, "only valid for star", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: []string{"Aggregation"}},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			cfg.Controller.TagPolicy.Lowercase = true
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				if cfg.Controller.Topology.Hub != tt.wantHub {
+					t.Errorf("Hub = %q, want %q", cfg.Controller.Topology.Hub, tt.wantHub)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Ring(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"config order", TopologyConfig{Type: "ring", BidirectionalRing: true}, ""},
+		{"explicit order", TopologyConfig{Type: "ring", Order: []string{"host-b", "host-a"}}, ""},
+		{"unknown node", TopologyConfig{Type: "ring", Order: []string{"host-z"}}, "unknown node"},
+		{"repeated node", TopologyConfig{Type: "ring", Order: []string{"host-a", "host-a"}}, "more than once"},
+		{"order without ring", TopologyConfig{Type: "full_mesh", Order: []string{"host-a"}}, "only valid for ring"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Chain(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"chain", TopologyConfig{Type: "chain", Order: []string{"host-b", "host-a"}, IncludeEndToEnd: true}, ""},
+		{"no order", TopologyConfig{Type: "chain"}, "order of at least 2 nodes"},
+		{"one node", TopologyConfig{Type: "chain", Order: []string{"host-a"}}, "order of at least 2 nodes"},
+		{"unknown node", TopologyConfig{Type: "chain", Order: []string{"host-a", "host-z"}}, `chain order names unknown node "host-z"`},
+		{"repeated node", TopologyConfig{Type: "chain", Order: []string{"host-a", "host-b", "host-a"}}, "more than once"},
+		{"end to end without chain", TopologyConfig{Type: "ring", IncludeEndToEnd: true}, "only valid for chain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_TagSelection(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"tags", TopologyConfig{Type: "full_mesh", SourceTags: []string{"Rack-A"}, DestinationTags: []string{"rack-b"}}, ""},
+		{"source tags only", TopologyConfig{Type: "full_mesh", SourceTags: []string{"rack-a"}}, "must be set together"},
+		{"unknown tag", TopologyConfig{Type: "full_mesh", SourceTags: []string{"rack-a"}, DestinationTags: []string{"rack-z"}}, `no node is tagged "rack-z"`},
+		{"tags without full mesh", TopologyConfig{Type: "ring", SourceTags: []string{"rack-a"}, DestinationTags: []string{"rack-b"}}, "only valid for full_mesh"},
+		{"override tags", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{SourceTags: []string{"rack-a"}, DestinationNodes: []string{"host-c"}, Profile: "default"}}}, ""},
+		{"unknown override tag", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{SourceTags: []string{"rack-z"}, DestinationTags: []string{"rack-a"}}}}, "override[0]: source_tags"},
+		{"override node tag", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{SourceNodes: []string{"tag:Rack-A"}, DestinationNodes: []string{"host-b"}, Profile: "default"}}}, ""},
+		{"unknown override node tag", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{SourceNodes: []string{"tag:rack-z"}, DestinationNodes: []string{"host-b"}}}}, `source_nodes: no node is tagged "rack-z"`},
+		{"unknown override node", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{SourceNodes: []string{"host-a"}, DestinationNodes: []string{"host-z"}}}}, `override[0]: destination_nodes: unknown node "host-z"`},
+		{"unknown override profile", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{{Nodes: []string{"host-a", "host-b"}, Profile: "fast"}}}, "override[0]: profile 'fast'"},
+		{"unknown group profile", TopologyConfig{Type: "full_mesh", Groups: []GroupConfig{{Name: "a", Pairs: []TopologyOverride{{Nodes: []string{"host-a", "host-b"}, Profile: "fast"}}}}}, "group a: profile 'fast'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: []string{"rack-a"}},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Tags: []string{"rack-b"}},
+				NodeConfig{Hostname: "host-c", IP: "10.0.0.3", Port: 50051, Tags: []string{"rack-a", "rack-b"}},
+			)
+			cfg.Controller.TagPolicy.Lowercase = true
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_TagGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"intra group", TopologyConfig{Type: "full_mesh", GroupBy: "Rack", GroupMode: "intra_group"}, ""},
+		{"inter group partial mesh", TopologyConfig{Type: "partial_mesh", Density: 0.5, GroupBy: "rack", GroupMode: "inter_group"}, ""},
+		{"no mode", TopologyConfig{Type: "full_mesh", GroupBy: "rack"}, "must be set together"},
+		{"unknown mode", TopologyConfig{Type: "full_mesh", GroupBy: "rack", GroupMode: "cross"}, "group_mode must be one of"},
+		{"tag as key", TopologyConfig{Type: "full_mesh", GroupBy: "rack:a1", GroupMode: "intra_group"}, "must be a tag key"},
+		{"unknown key", TopologyConfig{Type: "full_mesh", GroupBy: "row", GroupMode: "intra_group"}, "no node has a row:<value> tag"},
+		{"star", TopologyConfig{Type: "star", Hub: "host-a", GroupBy: "rack", GroupMode: "intra_group"}, "only valid for full_mesh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: []string{"rack:a1"}},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Tags: []string{"rack:a2"}},
+				NodeConfig{Hostname: "host-c", IP: "10.0.0.3", Port: 50051, Tags: []string{"spare"}},
+			)
+			cfg.Controller.TagPolicy.Lowercase = true
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTopologyOverride_PairsByTag(t *testing.T) {
+	nodes := []NodeConfig{
+		{Hostname: "host-a", Tags: []string{"rack-a"}},
+		{Hostname: "host-b", Tags: []string{"rack-b"}},
+		{Hostname: "host-c", Tags: []string{"rack-a", "rack-b"}},
+	}
+	override := TopologyOverride{
+		SourceTags:      []string{"rack-a"},
+		DestinationTags: []string{"rack-b"},
+		Nodes:           []string{"host-a", "host-c"},
+	}
+
+	got := make([]string, 0)
+	for _, pair := range override.Pairs(nodes) {
+		got = append(got, pair[0]+">"+pair[1])
+	}
+	// host-c is on both sides but not paired with itself, and host-a>host-c
+	// is selected by nodes and by tags but returned once
+	want := "host-a>host-c,host-c>host-a,host-a>host-b,host-c>host-b"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Pairs() = %s, want %s", strings.Join(got, ","), want)
+	}
+
+	// tag: entries of the node lists stand for the tagged nodes
+	override = TopologyOverride{SourceNodes: []string{"tag:rack-b"}, DestinationNodes: []string{"host-a"}}
+	got = got[:0]
+	for _, pair := range override.Pairs(nodes) {
+		got = append(got, pair[0]+">"+pair[1])
+	}
+	if want := "host-b>host-a,host-c>host-a"; strings.Join(got, ",") != want {
+		t.Errorf("Pairs() with tag: entries = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestValidate_FanTopologies(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"source", TopologyConfig{Type: "one_to_many", Source: "host-a"}, ""},
+		{"no source", TopologyConfig{Type: "one_to_many"}, "requires a source"},
+		{"unknown source", TopologyConfig{Type: "one_to_many", Source: "host-z"}, `"host-z" is not a node ID`},
+		{"source without one_to_many", TopologyConfig{Type: "full_mesh", Source: "host-a"}, "only valid for one_to_many"},
+		{"destination", TopologyConfig{Type: "many_to_one", Destination: "host-b"}, ""},
+		{"no destination", TopologyConfig{Type: "many_to_one"}, "requires a destination"},
+		{"unknown destination", TopologyConfig{Type: "many_to_one", Destination: "host-z"}, `"host-z" is not a node ID`},
+		{"destination without many_to_one", TopologyConfig{Type: "one_to_many", Source: "host-a", Destination: "host-b"}, "only valid for many_to_one"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_PartialMesh(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"density", TopologyConfig{Type: "partial_mesh", Density: 0.1}, ""},
+		{"density and seed", TopologyConfig{Type: "partial_mesh", Density: 1, Seed: 42}, ""},
+		{"no density", TopologyConfig{Type: "partial_mesh"}, "requires a density"},
+		{"density above 1", TopologyConfig{Type: "partial_mesh", Density: 1.5}, "requires a density"},
+		{"max_tests", TopologyConfig{Type: "partial_mesh", MaxTests: 200, Seed: 42}, ""},
+		{"max_tests and density", TopologyConfig{Type: "partial_mesh", Density: 0.5, MaxTests: 200}, "not both"},
+		{"negative max_tests", TopologyConfig{Type: "partial_mesh", MaxTests: -1}, "must be positive"},
+		{"max_tests without partial_mesh", TopologyConfig{Type: "full_mesh", MaxTests: 10}, "only valid for partial_mesh"},
+		{"seed without partial_mesh", TopologyConfig{Type: "full_mesh", Seed: 42}, "only valid for partial_mesh"},
+		{"groups", TopologyConfig{Type: "partial_mesh", Density: 0.5, Groups: []GroupConfig{{Name: "a"}}}, "cannot be combined with groups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Bisection(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"tags", TopologyConfig{Type: "bisection", SideA: []string{"tag:Rack:A"}, SideB: []string{"tag:rack:b"}}, ""},
+		{"node IDs", TopologyConfig{Type: "bisection", SideA: []string{"host-a", "host-b"}, SideB: []string{"host-c", "host-d"}}, ""},
+		{"unbalanced", TopologyConfig{Type: "bisection", SideA: []string{"tag:rack:a"}, SideB: []string{"host-c"}}, "allow_unbalanced"},
+		{"unbalanced allowed", TopologyConfig{Type: "bisection", SideA: []string{"tag:rack:a"}, SideB: []string{"host-c"}, AllowUnbalanced: true}, ""},
+		{"no side_b", TopologyConfig{Type: "bisection", SideA: []string{"tag:rack:a"}}, "requires side_a and side_b"},
+		{"node on both sides", TopologyConfig{Type: "bisection", SideA: []string{"tag:rack:a"}, SideB: []string{"host-b", "host-c"}}, "on both sides"},
+		{"unknown node", TopologyConfig{Type: "bisection", SideA: []string{"host-x"}, SideB: []string{"host-c"}}, "unknown node"},
+		{"unknown tag", TopologyConfig{Type: "bisection", SideA: []string{"tag:rack:z"}, SideB: []string{"host-c"}}, "no node is tagged"},
+		{"sides without bisection", TopologyConfig{Type: "full_mesh", SideA: []string{"host-a"}}, "only valid for bisection"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: []string{"rack:a"}},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Tags: []string{"rack:a"}},
+				NodeConfig{Hostname: "host-c", IP: "10.0.0.3", Port: 50051, Tags: []string{"rack:b"}},
+				NodeConfig{Hostname: "host-d", IP: "10.0.0.4", Port: 50051, Tags: []string{"rack:b"}},
+			)
+			cfg.Controller.TagPolicy.Lowercase = true
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Custom(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"pairs", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-b", Profile: "default"}}}, ""},
+		{"no pairs", TopologyConfig{Type: "custom"}, "at least one entry in pairs"},
+		{"unknown node", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-z"}}}, `unknown node "host-z"`},
+		{"same node", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-a"}}}, "must differ"},
+		{"unknown profile", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-b", Profile: "fast"}}}, "profile 'fast'"},
+		{"pairs without custom", TopologyConfig{Type: "full_mesh", Pairs: []CustomPair{{Source: "host-a", Destination: "host-b"}}}, "only valid for custom"},
+		{"repeat", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-b", Repeat: 3}}}, ""},
+		{"negative repeat", TopologyConfig{Type: "custom", Pairs: []CustomPair{{Source: "host-a", Destination: "host-b", Repeat: -1}}}, "repeat cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Exclusions(t *testing.T) {
+	aToB := TopologyOverride{SourceNodes: []string{"host-a"}, DestinationNodes: []string{"host-b"}}
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"full mesh", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{aToB}}, ""},
+		{"by tag", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{{Nodes: []string{"tag:vlan:isolated", "host-a"}}}}, ""},
+		{"custom without the pair", TopologyConfig{Type: "custom", Exclusions: []TopologyOverride{aToB},
+			Pairs: []CustomPair{{Source: "host-b", Destination: "host-a"}}}, ""},
+		{"custom listing the pair", TopologyConfig{Type: "custom", Exclusions: []TopologyOverride{aToB},
+			Pairs: []CustomPair{{Source: "host-a", Destination: "host-b"}}}, "host-a -> host-b is excluded"},
+		{"unknown node", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{{Nodes: []string{"host-a", "host-z"}}}}, `unknown node "host-z"`},
+		{"no pairs", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{{Nodes: []string{"host-a"}}}}, "at least one node pair"},
+		{"profile", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{{Nodes: []string{"host-a", "host-b"}, Profile: "default"}}}, "only selects pairs"},
+		{"repeat", TopologyConfig{Type: "full_mesh", Exclusions: []TopologyOverride{{Nodes: []string{"host-a", "host-b"}, Repeat: 2}}}, "only selects pairs"},
+		{"star", TopologyConfig{Type: "star", Hub: "host-a", Exclusions: []TopologyOverride{aToB}}, "only valid for full_mesh"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Tags: []string{"vlan:isolated"}},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Repeat(t *testing.T) {
+	aToB := func(repeat int, background bool) TopologyOverride {
+		return TopologyOverride{SourceNodes: []string{"host-a"}, DestinationNodes: []string{"host-b"},
+			Repeat: repeat, Background: background}
+	}
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"override", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{aToB(5, false)}}, ""},
+		{"background once", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{aToB(1, true)}}, ""},
+		{"negative", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{aToB(-2, false)}}, "override[0]: repeat cannot be negative"},
+		{"background", TopologyConfig{Type: "full_mesh", Overrides: []TopologyOverride{aToB(2, true)}}, "background pairs cannot be repeated"},
+		{"group", TopologyConfig{Type: "full_mesh", Groups: []GroupConfig{{Name: "g", Pairs: []TopologyOverride{aToB(3, false)}}}}, ""},
+		{"negative in group", TopologyConfig{Type: "full_mesh", Groups: []GroupConfig{{Name: "g", Pairs: []TopologyOverride{aToB(-1, false)}}}},
+			"group g: repeat cannot be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Schedule(t *testing.T) {
+	group := GroupConfig{Name: "g", Pairs: []TopologyOverride{{SourceNodes: []string{"host-a"}, DestinationNodes: []string{"host-b"}}}}
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"rounds", TopologyConfig{Type: "full_mesh", Schedule: "rounds"}, ""},
+		{"concurrent", TopologyConfig{Type: "ring", Schedule: "concurrent"}, ""},
+		{"unknown", TopologyConfig{Type: "full_mesh", Schedule: "serial"}, "topology schedule"},
+		{"rounds with groups", TopologyConfig{Type: "full_mesh", Schedule: "rounds", Groups: []GroupConfig{group}},
+			"cannot be combined with groups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Directed(t *testing.T) {
+	undirected := false
+	group := GroupConfig{Name: "g", Pairs: []TopologyOverride{{SourceNodes: []string{"host-a"}, DestinationNodes: []string{"host-b"}}}}
+	tests := []struct {
+		name     string
+		topology TopologyConfig
+		wantErr  string
+	}{
+		{"undirected full mesh", TopologyConfig{Type: "full_mesh", Directed: &undirected}, ""},
+		{"undirected partial mesh", TopologyConfig{Type: "partial_mesh", Density: 0.5, Directed: &undirected}, ""},
+		{"undirected ring", TopologyConfig{Type: "ring", Directed: &undirected}, "only valid for full_mesh and partial_mesh"},
+		{"undirected with groups", TopologyConfig{Type: "full_mesh", Directed: &undirected, Groups: []GroupConfig{group}},
+			"cannot be combined with groups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidate_PerInterface(t *testing.T) {
+	frontend := InterfaceConfig{Name: "frontend", IP: "10.0.0.10"}
+	backend := InterfaceConfig{Name: "backend", IP: "192.168.0.10"}
+	tests := []struct {
+		name       string
+		interfaces []InterfaceConfig
+		topology   TopologyConfig
+		wantErr    string
+	}{
+		{"per interface", []InterfaceConfig{frontend, backend}, TopologyConfig{Type: "full_mesh", PerInterface: true}, ""},
+		{"interfaces without per_interface", []InterfaceConfig{frontend}, TopologyConfig{Type: "full_mesh"}, ""},
+		{"no interfaces", nil, TopologyConfig{Type: "full_mesh", PerInterface: true}, "node host-a has no interfaces"},
+		{"with address families", []InterfaceConfig{frontend},
+			TopologyConfig{Type: "full_mesh", PerInterface: true, AddressFamilies: []string{"v4"}}, "cannot be combined with address_families"},
+		{"empty name", []InterfaceConfig{{IP: "10.0.0.10"}}, TopologyConfig{Type: "full_mesh"}, "name cannot be empty"},
+		{"invalid name", []InterfaceConfig{{Name: "eth0#1", IP: "10.0.0.10"}}, TopologyConfig{Type: "full_mesh"}, "may only contain"},
+		{"duplicate name", []InterfaceConfig{frontend, {Name: "frontend", IP: "10.0.0.11"}}, TopologyConfig{Type: "full_mesh"},
+			"frontend is listed twice"},
+		{"invalid ip", []InterfaceConfig{{Name: "frontend", IP: "frontend.example"}}, TopologyConfig{Type: "full_mesh"},
+			"is not an IP address"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Interfaces: tt.interfaces},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Interfaces: []InterfaceConfig{{Name: "frontend", IP: "10.0.0.20"}}},
+			)
+			tt.topology.DefaultProfile = cfg.Controller.Topology.DefaultProfile
+			cfg.Controller.Topology = tt.topology
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTestProfile_Capture(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile TestProfile
+		wantErr string
+	}{
+		{"failure", TestProfile{CaptureOn: []string{"failure"}}, ""},
+		{"threshold", TestProfile{CaptureOn: []string{"failure", "threshold"}, CaptureThreshold: "500M"}, ""},
+		{"unknown trigger", TestProfile{CaptureOn: []string{"retransmits"}}, "capture_on must be one of"},
+		{"threshold without rate", TestProfile{CaptureOn: []string{"threshold"}}, "requires a capture_threshold"},
+		{"rate without threshold", TestProfile{CaptureOn: []string{"failure"}, CaptureThreshold: "500M"}, "requires capture_on threshold"},
+		{"bad rate", TestProfile{CaptureOn: []string{"threshold"}, CaptureThreshold: "fast"}, "capture_threshold"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.profile.Duration = 10
+			tt.profile.Parallel = 1
+			err := validateTestProfile("p", tt.profile)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateTestProfile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTestProfile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTestProfile_SeparateReverse(t *testing.T) {
+	profile := TestProfile{Duration: 10, Parallel: 1, SeparateReverse: true}
+	if err := validateTestProfile("p", profile); err == nil || !strings.Contains(err.Error(), "requires bidirectional") {
+		t.Errorf("validateTestProfile() error = %v, want separate_reverse to require bidirectional", err)
+	}
+	profile.Bidirectional = true
+	if err := validateTestProfile("p", profile); err != nil {
+		t.Errorf("validateTestProfile() error = %v", err)
+	}
+}
+
+func TestValidateTestProfile_PortsPerPair(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile TestProfile
+		wantErr string
+	}{
+		{"split", TestProfile{Duration: 10, Parallel: 16, PortsPerPair: 4}, ""},
+		{"uneven", TestProfile{Duration: 10, Parallel: 10, PortsPerPair: 4}, "multiple of ports_per_pair"},
+		{"negative", TestProfile{Duration: 10, Parallel: 1, PortsPerPair: -1}, "cannot be negative"},
+		{"with processes", TestProfile{Duration: 10, Parallel: 8, PortsPerPair: 2, ProcessesPerPair: 2}, "cannot be combined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTestProfile("p", tt.profile)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateTestProfile() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateTestProfile() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTagUsage(t *testing.T) {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051, Tags: []string{"zone:us-east", "prod"}},
+		NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051, Tags: []string{"Zone:us-east", "prod"}},
+	)
+
+	tags := cfg.TagUsage()
+	if len(tags) != 3 {
+		t.Fatalf("TagUsage() returned %d tags, want 3", len(tags))
+	}
+	if tags[0].Tag != "Zone:us-east" || strings.Join(tags[0].Similar, ",") != "zone:us-east" {
+		t.Errorf("TagUsage()[0] = %+v, want Zone:us-east similar to zone:us-east", *tags[0])
+	}
+	if tags[1].Tag != "prod" || strings.Join(tags[1].Nodes, ",") != "host-a,host-b" {
+		t.Errorf("TagUsage()[1] = %+v, want prod on host-a,host-b", *tags[1])
+	}
+	if warnings := cfg.tagWarnings(); len(warnings) != 1 {
+		t.Errorf("tagWarnings() = %q, want one warning", warnings)
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		name     string
+		quorum   QuorumConfig
+		wantErr  string
+		required int // Of 10 nodes
+	}{
+		{name: "unset", required: 0},
+		{name: "min nodes", quorum: QuorumConfig{MinNodes: 3}, required: 3},
+		{name: "fraction rounds up", quorum: QuorumConfig{MinHealthyFraction: 0.75}, required: 8},
+		{name: "larger of both", quorum: QuorumConfig{MinNodes: 4, MinHealthyFraction: 0.3}, required: 4},
+		{name: "too many nodes", quorum: QuorumConfig{MinNodes: 5}, wantErr: "exceeds the 4 configured nodes"},
+		{name: "fraction above 1", quorum: QuorumConfig{MinHealthyFraction: 1.5}, wantErr: "between 0 and 1"},
+		{name: "negative", quorum: QuorumConfig{MinNodes: -1}, wantErr: "cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "b", IP: "10.0.0.2", Port: 50051},
+				NodeConfig{Hostname: "c", IP: "10.0.0.3", Port: 50051},
+				NodeConfig{Hostname: "d", IP: "10.0.0.4", Port: 50051},
+			)
+			cfg.Controller.Quorum = tt.quorum
+
+			err := cfg.Validate()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if got := tt.quorum.Required(10); got != tt.required {
+				t.Errorf("Required(10) = %d, want %d", got, tt.required)
+			}
+			if tt.quorum.Enabled() != (tt.required > 0) {
+				t.Errorf("Enabled() = %v", tt.quorum.Enabled())
+			}
+		})
+	}
+}
+
+func TestValidate_Confidence(t *testing.T) {
+	tests := []struct {
+		name       string
+		confidence ConfidenceConfig
+		wantErr    string
+	}{
+		{name: "rules disabled", confidence: ConfidenceConfig{DisabledRules: []string{"cpu_limited", "low_overlap"}}},
+		{name: "unknown rule", confidence: ConfidenceConfig{DisabledRules: []string{"clock_skew"}}, wantErr: "confidence rule must be one of"},
+		{name: "negative cpu", confidence: ConfidenceConfig{CPUPercent: -1}, wantErr: "cpu_percent cannot be negative"},
+		{name: "overlap above one", confidence: ConfidenceConfig{MinOverlap: 1.5}, wantErr: "min_overlap must be between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			cfg.Controller.Analysis.Confidence = tt.confidence
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnalysis_DiscrepancyThreshold(t *testing.T) {
+	tests := []struct {
+		yaml    string
+		want    float64
+		wantErr string
+	}{
+		{"{}", DefaultDiscrepancyThresholdPercent, ""},
+		{"discrepancy_threshold_percent: 0", 0, ""},
+		{"discrepancy_threshold_percent: 2.5", 2.5, ""},
+		{"discrepancy_threshold_percent: -1", -1, "discrepancy_threshold_percent cannot be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.yaml, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			if err := yaml.Unmarshal([]byte(tt.yaml), &cfg.Controller.Analysis); err != nil {
+				t.Fatalf("yaml.Unmarshal() error = %v", err)
+			}
+			cfg.SetDefaults()
+			if got := cfg.Controller.Analysis.DiscrepancyThreshold(); got != tt.want {
+				t.Errorf("DiscrepancyThreshold() = %v, want %v", got, tt.want)
+			}
+			err := cfg.Validate()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Validate() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
 
 	"gopkg.in/yaml.v3"
@@ -21,8 +22,46 @@ type DaemonSettings struct {
 	LogLevel      string        `yaml:"log_level"`
 	ResultDir     string        `yaml:"result_dir"`
 	TimeoutConfig TimeoutConfig `yaml:"timeout"`
+	Debug         DebugConfig   `yaml:"debug"`
+	// PortAllocation is how free ports are chosen: "sequential" (lowest
+	// first), "round_robin" or "random". The latter two avoid ports released
+	// less than port_cooldown_seconds ago; negative disables the cool-down.
+	PortAllocation      string `yaml:"port_allocation"`
+	PortCooldownSeconds int    `yaml:"port_cooldown_seconds"`
+	// AllowedCIDRs are the networks controllers may connect from; calls
+	// from other addresses are refused. Empty allows every address.
+	AllowedCIDRs []string `yaml:"allowed_cidrs,omitempty"`
+	// Caps on the results kept in memory until a controller retrieves them;
+	// 0 uses the default and a negative value disables the cap
+	MaxStoredResults    int   `yaml:"max_stored_results"`
+	MaxTotalResultBytes int64 `yaml:"max_total_result_bytes"`
+	// ResultOverflow is what happens to a result that would exceed a cap:
+	// "reject" it, or "evict_oldest" stored results to make room
+	ResultOverflow string `yaml:"result_overflow"`
+	// Simulate shapes the synthetic results of --simulate mode and is
+	// ignored otherwise
+	Simulate SimulateConfig `yaml:"simulate"`
+	// Capture enables bounded packet captures of tests that ask for one
+	Capture CaptureConfig `yaml:"capture"`
 }
 
+// DefaultDaemonDebugPort is the daemon's debug listener port when unset
+const DefaultDaemonDebugPort = 6060
+
+// Defaults of the caps on stored results
+const (
+	DefaultMaxStoredResults    = 10000
+	DefaultMaxTotalResultBytes = 512 << 20
+	DefaultResultOverflow      = "reject"
+)
+
+// Defaults of port allocation; the cool-down matches Linux's 60 second
+// TIME_WAIT
+const (
+	DefaultPortAllocation      = "sequential"
+	DefaultPortCooldownSeconds = 60
+)
+
 // PortRange defines the range of ports available for iperf3 servers
 type PortRange struct {
 	Start int `yaml:"start"`
@@ -73,24 +112,69 @@ func (c *DaemonConfig) Validate() error {
 		return fmt.Errorf("port_range.start must be less than port_range.end")
 	}
 
+	if err := c.Daemon.checkOwnPorts(); err != nil {
+		return err
+	}
+
+	if allocation := c.Daemon.PortAllocation; allocation != "" {
+		if err := checkValue("port_allocation", "daemon.port_allocation", allocation); err != nil {
+			return err
+		}
+	}
+
+	for _, cidr := range c.Daemon.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("allowed_cidrs: %q is not a CIDR such as 10.0.0.0/8", cidr)
+		}
+	}
+
 	if c.Daemon.MaxProcesses < 1 {
 		return fmt.Errorf("max_processes must be at least 1")
 	}
 
-	validLogLevels := map[string]bool{
-		"debug": true,
-		"info":  true,
-		"warn":  true,
-		"error": true,
-	}
-	if !validLogLevels[c.Daemon.LogLevel] {
-		return fmt.Errorf("log_level must be one of: debug, info, warn, error")
+	if err := checkValue("log_level", "daemon.log_level", c.Daemon.LogLevel); err != nil {
+		return err
 	}
 
 	if c.Daemon.ResultDir == "" {
 		return fmt.Errorf("result_dir cannot be empty")
 	}
 
+	if overflow := c.Daemon.ResultOverflow; overflow != "" {
+		if err := checkValue("result_overflow", "daemon.result_overflow", overflow); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Daemon.Debug.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Daemon.Simulate.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Daemon.Capture.validate(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// checkOwnPorts rejects a listen or debug port inside the iperf3 port
+// range, where an iperf3 server could be assigned it
+func (s *DaemonSettings) checkOwnPorts() error {
+	inRange := func(port int) bool {
+		return port >= s.PortRange.Start && port <= s.PortRange.End
+	}
+	if inRange(s.ListenPort) {
+		return fmt.Errorf("listen_port %d is inside port_range %d-%d; move one of them",
+			s.ListenPort, s.PortRange.Start, s.PortRange.End)
+	}
+	if s.Debug.Enabled && inRange(s.Debug.Port) {
+		return fmt.Errorf("debug.port %d is inside port_range %d-%d; move one of them",
+			s.Debug.Port, s.PortRange.Start, s.PortRange.End)
+	}
 	return nil
 }
 
@@ -108,6 +192,14 @@ func (c *DaemonConfig) SetDefaults() {
 		c.Daemon.PortRange.End = 5400
 	}
 
+	if c.Daemon.PortAllocation == "" {
+		c.Daemon.PortAllocation = DefaultPortAllocation
+	}
+
+	if c.Daemon.PortCooldownSeconds == 0 {
+		c.Daemon.PortCooldownSeconds = DefaultPortCooldownSeconds
+	}
+
 	if c.Daemon.MaxProcesses == 0 {
 		c.Daemon.MaxProcesses = 200
 	}
@@ -131,4 +223,20 @@ func (c *DaemonConfig) SetDefaults() {
 	if c.Daemon.TimeoutConfig.TestExecution == 0 {
 		c.Daemon.TimeoutConfig.TestExecution = 300
 	}
+
+	if c.Daemon.MaxStoredResults == 0 {
+		c.Daemon.MaxStoredResults = DefaultMaxStoredResults
+	}
+
+	if c.Daemon.MaxTotalResultBytes == 0 {
+		c.Daemon.MaxTotalResultBytes = DefaultMaxTotalResultBytes
+	}
+
+	if c.Daemon.ResultOverflow == "" {
+		c.Daemon.ResultOverflow = DefaultResultOverflow
+	}
+
+	c.Daemon.Debug.setDefaults(DefaultDaemonDebugPort)
+	c.Daemon.Simulate.setDefaults()
+	c.Daemon.Capture.setDefaults()
 }
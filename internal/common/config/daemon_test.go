@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDaemonValidate_OwnPorts(t *testing.T) {
+	tests := []struct {
+		name       string
+		listenPort int
+		debug      DebugConfig
+		wantErr    string
+	}{
+		{"below range", 5200, DebugConfig{}, ""},
+		{"range start", 5201, DebugConfig{}, "listen_port 5201 is inside port_range 5201-5400"},
+		{"inside range", 5250, DebugConfig{}, "listen_port 5250 is inside port_range"},
+		{"range end", 5400, DebugConfig{}, "listen_port 5400 is inside port_range"},
+		{"above range", 5401, DebugConfig{}, ""},
+		{"debug port inside range", 50051, DebugConfig{Enabled: true, Port: 5300}, "debug.port 5300 is inside port_range"},
+		{"disabled debug port inside range", 50051, DebugConfig{Port: 5300}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &DaemonConfig{}
+			cfg.SetDefaults()
+			cfg.Daemon.ListenPort = tt.listenPort
+			cfg.Daemon.Debug = tt.debug
+
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDaemonValidate_ResultOverflow(t *testing.T) {
+	cfg := &DaemonConfig{}
+	cfg.SetDefaults()
+	if cfg.Daemon.ResultOverflow != DefaultResultOverflow || cfg.Daemon.MaxStoredResults != DefaultMaxStoredResults {
+		t.Errorf("defaults = %q, %d", cfg.Daemon.ResultOverflow, cfg.Daemon.MaxStoredResults)
+	}
+	cfg.Daemon.ResultOverflow = "drop_newest"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "result_overflow") {
+		t.Errorf("Validate() error = %v, want one about result_overflow", err)
+	}
+}
+
+func TestDaemonValidate_PortAllocation(t *testing.T) {
+	cfg := &DaemonConfig{}
+	cfg.SetDefaults()
+	if cfg.Daemon.PortAllocation != DefaultPortAllocation || cfg.Daemon.PortCooldownSeconds != DefaultPortCooldownSeconds {
+		t.Errorf("defaults = %q, %d", cfg.Daemon.PortAllocation, cfg.Daemon.PortCooldownSeconds)
+	}
+	cfg.Daemon.PortAllocation = "lowest"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "port_allocation") {
+		t.Errorf("Validate() error = %v, want one about port_allocation", err)
+	}
+}
+
+func TestDaemonValidate_AllowedCIDRs(t *testing.T) {
+	cfg := &DaemonConfig{}
+	cfg.SetDefaults()
+	cfg.Daemon.AllowedCIDRs = []string{"10.20.0.0/16", "fd00:20::/32"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cfg.Daemon.AllowedCIDRs = append(cfg.Daemon.AllowedCIDRs, "10.20.0.1")
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "allowed_cidrs") {
+		t.Errorf("Validate() error = %v, want one about allowed_cidrs", err)
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "fmt"
+
+// DebugConfig controls the opt-in diagnostics HTTP listener serving pprof,
+// expvar counters and a state dump
+type DebugConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Address string `yaml:"address,omitempty"` // Defaults to 127.0.0.1
+	Port    int    `yaml:"port,omitempty"`
+}
+
+// validate checks the listener port when the listener is enabled
+func (c *DebugConfig) validate() error {
+	if c.Enabled && (c.Port < 0 || c.Port > 65535) {
+		return fmt.Errorf("debug port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// setDefaults fills in the given default port
+func (c *DebugConfig) setDefaults(port int) {
+	if c.Port == 0 {
+		c.Port = port
+	}
+}
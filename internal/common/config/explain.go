@@ -0,0 +1,362 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldInfo describes a configuration field: its type from the config
+// types, its default as set by SetDefaults, and its documentation from the
+// field registry
+type FieldInfo struct {
+	Path        string
+	Type        string
+	Description string
+	Default     string
+	Rules       string
+	Values      []string // Allowed values, when limited to a set
+	Example     string
+	Fields      []string // The fields of a section
+}
+
+// configRoots are the top-level keys of the controller and daemon config
+// files and the types they hold
+var configRoots = map[string]reflect.Type{
+	"controller": reflect.TypeOf(ControllerSettings{}),
+	"daemon":     reflect.TypeOf(DaemonSettings{}),
+}
+
+// Explain describes the field at path, such as
+// "controller.topology.overrides". List indexes and map keys, as in
+// "controller.test_profiles.default.duration", may be given or left out.
+// Unknown paths are rejected with the closest known ones.
+func Explain(path string) (*FieldInfo, error) {
+	canonical, t, ok := resolvePath(path)
+	if !ok {
+		err := fmt.Errorf("unknown configuration field %q", path)
+		if matches := closeMatches(path, FieldPaths()); len(matches) > 0 {
+			err = fmt.Errorf("%w; did you mean %s?", err, strings.Join(matches, ", "))
+		}
+		return nil, err
+	}
+
+	doc := fieldDocs[canonical]
+	info := &FieldInfo{
+		Path:        canonical,
+		Type:        typeName(t),
+		Description: doc.Description,
+		Default:     doc.Default,
+		Rules:       doc.Rules,
+		Values:      doc.Values,
+		Example:     doc.Example,
+	}
+	if info.Default == "" {
+		info.Default = defaultOf(canonical)
+	}
+	if s := sectionType(t); s != nil {
+		for i := 0; i < s.NumField(); i++ {
+			if name := yamlName(s.Field(i)); name != "" {
+				info.Fields = append(info.Fields, name)
+			}
+		}
+	}
+	return info, nil
+}
+
+// Format renders the description for a terminal
+func (f *FieldInfo) Format() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (%s)\n", f.Path, f.Type)
+	if f.Description != "" {
+		fmt.Fprintf(&b, "  %s\n", f.Description)
+	}
+	if f.Default != "" {
+		fmt.Fprintf(&b, "  Default: %s\n", f.Default)
+	}
+	if len(f.Values) > 0 {
+		fmt.Fprintf(&b, "  Values:  %s\n", strings.Join(f.Values, ", "))
+	}
+	if f.Rules != "" {
+		fmt.Fprintf(&b, "  Rules:   %s\n", f.Rules)
+	}
+	if len(f.Fields) > 0 {
+		fmt.Fprintf(&b, "  Fields:  %s\n", strings.Join(f.Fields, ", "))
+	}
+	if f.Example != "" {
+		b.WriteString("  Example:\n")
+		for _, line := range strings.Split(f.Example, "\n") {
+			fmt.Fprintf(&b, "    %s\n", line)
+		}
+	}
+	return b.String()
+}
+
+// FieldPaths returns the paths of every configuration field, sorted
+func FieldPaths() []string {
+	paths := make([]string, 0)
+	for root, t := range configRoots {
+		paths = append(paths, root)
+		walkFields(root, t, func(path string, _ reflect.Type) {
+			paths = append(paths, path)
+		})
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// walkFields calls visit with the path and type of every field below t.
+// The fields of list and map elements are under the list's or map's path.
+func walkFields(prefix string, t reflect.Type, visit func(path string, t reflect.Type)) {
+	s := sectionType(t)
+	if s == nil {
+		return
+	}
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Field(i)
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+		path := prefix + "." + name
+		visit(path, field.Type)
+		walkFields(path, field.Type, visit)
+	}
+}
+
+// sectionType returns the struct type t holds, directly or as the element
+// of a pointer, list or map, or nil if it holds none
+func sectionType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// yamlName returns the YAML key of a field, or "" if it has none
+func yamlName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// resolvePath returns the canonical path and type of the field path names,
+// skipping list indexes and map keys
+func resolvePath(path string) (string, reflect.Type, bool) {
+	segments := strings.Split(strings.TrimSpace(path), ".")
+	t, ok := configRoots[segments[0]]
+	if !ok {
+		return "", nil, false
+	}
+	canonical := []string{segments[0]}
+	for _, segment := range segments[1:] {
+		segment, _, _ = strings.Cut(segment, "[")
+		s := sectionType(t)
+		if s == nil {
+			return "", nil, false
+		}
+		field, found := fieldByYAMLName(s, segment)
+		if !found {
+			if t.Kind() == reflect.Map {
+				continue // A map key, such as a profile name
+			}
+			return "", nil, false
+		}
+		canonical = append(canonical, segment)
+		t = field.Type
+	}
+	return strings.Join(canonical, "."), t, true
+}
+
+// fieldByYAMLName returns the field of struct type s with the YAML key name
+func fieldByYAMLName(s reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < s.NumField(); i++ {
+		if yamlName(s.Field(i)) == name {
+			return s.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// typeName describes t the way it is written in YAML
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.Slice:
+		return "list of " + typeName(t.Elem()) + "s"
+	case reflect.Map:
+		return "map of name to " + typeName(t.Elem())
+	case reflect.Struct:
+		return "section"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "integer"
+	}
+}
+
+// defaultOf returns the value SetDefaults gives the field at path of an
+// otherwise empty configuration, or "" if it leaves it unset. Lists and
+// maps get one empty element, so that defaults of their fields show.
+func defaultOf(path string) string {
+	segments := strings.Split(path, ".")
+	var v reflect.Value
+	switch segments[0] {
+	case "controller":
+		cfg := &ControllerConfig{}
+		fillSample(reflect.ValueOf(&cfg.Controller).Elem())
+		cfg.SetDefaults()
+		v = reflect.ValueOf(cfg.Controller)
+	case "daemon":
+		cfg := &DaemonConfig{}
+		fillSample(reflect.ValueOf(&cfg.Daemon).Elem())
+		cfg.SetDefaults()
+		v = reflect.ValueOf(cfg.Daemon)
+	}
+
+	for _, segment := range segments[1:] {
+		v = sampleElem(v)
+		field, _ := fieldByYAMLName(v.Type(), segment)
+		v = v.FieldByIndex(field.Index)
+	}
+	if sectionType(v.Type()) != nil {
+		return "" // Sections have no value of their own
+	}
+	return formatValue(v)
+}
+
+// fillSample gives every list and map below v one empty element and every
+// pointer to a section an empty section
+func fillSample(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fillSample(v.Field(i))
+			}
+		}
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			v.Set(reflect.New(v.Type().Elem()))
+			fillSample(v.Elem())
+		}
+	case reflect.Slice:
+		if sectionType(v.Type()) != nil {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			fillSample(elem)
+			v.Set(reflect.Append(v, elem))
+		}
+	case reflect.Map:
+		if sectionType(v.Type()) != nil {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			fillSample(elem)
+			v.Set(reflect.MakeMap(v.Type()))
+			v.SetMapIndex(reflect.Zero(v.Type().Key()), elem)
+		}
+	}
+}
+
+// sampleElem returns the section v holds, taking the first element of
+// lists and maps
+func sampleElem(v reflect.Value) reflect.Value {
+	for {
+		switch v.Kind() {
+		case reflect.Ptr:
+			v = v.Elem()
+		case reflect.Slice:
+			v = v.Index(0)
+		case reflect.Map:
+			v = v.MapIndex(v.MapKeys()[0])
+		default:
+			return v
+		}
+	}
+}
+
+// formatValue formats a default, or returns "" for a zero value
+func formatValue(v reflect.Value) string {
+	if v.IsZero() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		return formatValue(v.Elem())
+	case reflect.Slice:
+		items := make([]string, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, fmt.Sprint(v.Index(i).Interface()))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// closeMatches returns up to 5 of paths close to path: those ending in its
+// last key, then those within a few edits of it
+func closeMatches(path string, paths []string) []string {
+	const limit = 5
+	last := path[strings.LastIndex(path, ".")+1:]
+	matches := make([]string, 0)
+	for _, candidate := range paths {
+		if strings.HasSuffix(candidate, "."+last) {
+			matches = append(matches, candidate)
+		}
+	}
+
+	type scored struct {
+		path     string
+		distance int
+	}
+	near := make([]scored, 0)
+	maxDistance := max(2, len(last)/3)
+	for _, candidate := range paths {
+		if d := editDistance(path, candidate); d <= maxDistance && !contains(matches, candidate) {
+			near = append(near, scored{candidate, d})
+		}
+	}
+	sort.SliceStable(near, func(i, j int) bool { return near[i].distance < near[j].distance })
+	for _, s := range near {
+		matches = append(matches, s.path)
+	}
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFieldDocs_CoverConfig(t *testing.T) {
+	paths := FieldPaths()
+	for _, path := range paths {
+		if fieldDocs[path].Description == "" {
+			t.Errorf("%s has no description in fieldDocs", path)
+		}
+	}
+	for path := range fieldDocs {
+		if !contains(paths, path) {
+			t.Errorf("fieldDocs documents %s, which is not a configuration field", path)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		path, canonical, typ, def string
+	}{
+		{"controller.topology.overrides", "controller.topology.overrides", "list of sections", ""},
+		{"controller.test_profiles.default.duration", "controller.test_profiles.duration", "number", ""},
+		{"controller.nodes[1].port", "controller.nodes.port", "integer", "50051"},
+		{"controller.analysis.mtu_probe_mss", "controller.analysis.mtu_probe_mss", "list of integers", "[1400, 4000, 8900]"},
+		{"daemon.port_range.start", "daemon.port_range.start", "integer", "5201"},
+		{"controller.on_failure.stop_all", "controller.on_failure.stop_all", "boolean", "true"},
+	}
+	for _, tt := range tests {
+		info, err := Explain(tt.path)
+		if err != nil {
+			t.Errorf("Explain(%s) error = %v", tt.path, err)
+			continue
+		}
+		if info.Path != tt.canonical || info.Type != tt.typ || info.Default != tt.def {
+			t.Errorf("Explain(%s) = %s (%s), default %q; want %s (%s), default %q",
+				tt.path, info.Path, info.Type, info.Default, tt.canonical, tt.typ, tt.def)
+		}
+	}
+
+	info, err := Explain("controller.quorum")
+	if err != nil {
+		t.Fatalf("Explain(controller.quorum) error = %v", err)
+	}
+	if strings.Join(info.Fields, ",") != "min_nodes,min_healthy_fraction" || info.Example == "" {
+		t.Errorf("Explain(controller.quorum) = fields %v, example %q; want its fields and an example", info.Fields, info.Example)
+	}
+}
+
+func TestExplain_Unknown(t *testing.T) {
+	_, err := Explain("controller.topology.overide")
+	if err == nil || !strings.Contains(err.Error(), "did you mean controller.topology.overrides?") {
+		t.Errorf("Explain() of a typo error = %v, want it to suggest overrides", err)
+	}
+	_, err = Explain("bandwidth")
+	if err == nil || !strings.Contains(err.Error(), "controller.test_profiles.bandwidth") {
+		t.Errorf("Explain() of a bare key error = %v, want it to suggest the profile field", err)
+	}
+	if _, err := Explain("controller.output.json_file.extra"); err == nil {
+		t.Errorf("Explain() below a plain field error = nil, want unknown")
+	}
+}
+
+func TestValidate_RegisteredValues(t *testing.T) {
+	cfg := &ControllerConfig{Controller: ControllerSettings{
+		Nodes: []NodeConfig{
+			{Hostname: "node1", IP: "10.0.0.1", Port: 50051},
+			{Hostname: "node2", IP: "10.0.0.2", Port: 50051},
+		},
+		TestProfiles: map[string]TestProfile{"default": {Duration: 10, Parallel: 1, Protocol: "sctp"}},
+		Topology:     TopologyConfig{Type: "full_mesh", DefaultProfile: "default"},
+		Output:       OutputConfig{JSONFile: "results.json"},
+	}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "protocol must be one of: tcp, udp") {
+		t.Errorf("Validate() error = %v, want the protocol's registered values", err)
+	}
+
+	cfg.Controller.TestProfiles["default"] = TestProfile{Duration: 10, Parallel: 1}
+	cfg.Controller.Topology.Type = "tree"
+	err = cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "topology type must be one of: full_mesh, custom, rotating, star, ring, one_to_many, many_to_one, partial_mesh") {
+		t.Errorf("Validate() error = %v, want the topology types", err)
+	}
+}
@@ -0,0 +1,1041 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldDoc documents a configuration field. Its type comes from the config
+// types and its default from SetDefaults; Default here replaces defaults
+// that depend on the environment or other fields, or are applied elsewhere.
+type fieldDoc struct {
+	Description string
+	Default     string
+	Rules       string
+	Values      []string // Allowed values, checked by validation
+	Example     string
+}
+
+// fieldDocs documents every field of the controller and daemon config
+// files, by path. Fields of list and map elements are under the path of the
+// list or map.
+var fieldDocs = map[string]fieldDoc{
+	"controller": {
+		Description: "Settings of iperf-controller, which plans and runs tests on the daemons.",
+	},
+	"controller.nodes": {
+		Description: "The daemons to test between, one entry per node.",
+		Rules:       "At least 2 nodes; IDs and daemon addresses must be unique.",
+		Example: `nodes:
+  - hostname: node1.example.com
+    ip: 192.168.1.10
+    port: 50051
+    tags: [zone:a]`,
+	},
+	"controller.nodes.hostname": {
+		Description: "Hostname of the node, and its ID unless id is set.",
+		Rules:       "Required.",
+	},
+	"controller.nodes.ip": {
+		Description: "Address of the node's daemon, and of its iperf3 servers unless data_ip is set.",
+		Rules:       "Required.",
+	},
+	"controller.nodes.data_ip": {
+		Description: "Address iperf3 traffic to the node is sent to, when it differs from ip.",
+	},
+	"controller.nodes.data_ips": {
+		Description: "Further addresses for iperf3 traffic, such as the IPv6 address of a dual-stack node; used by topology.address_families.",
+		Rules:       "Each must be an IP address.",
+		Example:     `data_ips: ["2001:db8::10"]`,
+	},
+	"controller.nodes.port": {
+		Description: "Port of the node's daemon.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"controller.nodes.id": {
+		Description: "Name of the node in topologies, results and commands.",
+		Default:     "the hostname",
+		Rules:       "Unique across nodes.",
+	},
+	"controller.nodes.tags": {
+		Description: "Labels selecting the node in --tag expressions and output filters, such as zone:us-east.",
+		Rules:       "A node cannot list a tag twice.",
+		Example:     `tags: [zone:us-east, rack:r12]`,
+	},
+	"controller.nodes.daemon_port_range": {
+		Description: "Mirror of this node's daemon port_range, when it differs from daemon_port_range.",
+	},
+	"controller.nodes.daemon_port_range.start": {
+		Description: "First port of the node's daemon port range.",
+		Rules:       "Between 1 and 65535, at most end.",
+	},
+	"controller.nodes.daemon_port_range.end": {
+		Description: "Last port of the node's daemon port range.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"controller.nodes.daemon_port_range.owner": {
+		Description: "Unused in daemon port ranges.",
+	},
+	"controller.nodes.blackout": {
+		Description: "Daily windows in which the node must not be tested, such as during its backups. Tests involving the node are deferred until the window ends, or skipped if that takes longer than concurrency.max_blackout_wait_seconds.",
+		Example: `blackout:
+  - start: "01:00"
+    end: "03:00"
+    timezone: Europe/Berlin`,
+	},
+	"controller.nodes.blackout.start": {
+		Description: "Time of day the window starts, as HH:MM.",
+		Rules:       "Required; differs from end.",
+	},
+	"controller.nodes.blackout.end": {
+		Description: "Time of day the window ends, as HH:MM; before start for windows spanning midnight.",
+		Rules:       "Required.",
+	},
+	"controller.nodes.blackout.timezone": {
+		Description: "IANA time zone of start and end.",
+		Default:     "the controller's local time",
+	},
+	"controller.nodes.interfaces": {
+		Description: "Named network interfaces of a multi-homed node, such as its frontend and backend NICs; each is tested as its own endpoint by topology.per_interface.",
+		Example: `interfaces:
+  - {name: frontend, ip: 10.0.0.10}
+  - {name: backend, ip: 192.168.0.10}`,
+	},
+	"controller.nodes.interfaces.name": {
+		Description: "Name of the interface, matched across nodes and used in test IDs.",
+		Rules:       "Required; letters, digits, '.', '_' and '-' only; unique within the node.",
+	},
+	"controller.nodes.interfaces.ip": {
+		Description: "Address of the interface. Servers and clients of tests over it bind to it (iperf3 -B).",
+		Rules:       "Must be an IP address.",
+	},
+	"controller.test_profiles": {
+		Description: "Named sets of iperf3 parameters that pairs run with.",
+		Rules:       "At least one profile.",
+		Example: `test_profiles:
+  default:
+    duration: 10
+    protocol: tcp
+    parallel: 1`,
+	},
+	"controller.test_profiles.duration": {
+		Description: "Length of each test in seconds; fractions such as 0.5 are allowed.",
+		Rules:       "At least the minimum iperf3 duration, unless the profile has a burst schedule.",
+	},
+	"controller.test_profiles.protocol": {
+		Description: "Transport protocol of the test.",
+		Default:     "tcp",
+		Values:      []string{"tcp", "udp"},
+	},
+	"controller.test_profiles.bandwidth": {
+		Description: "Target bit rate per stream, as for iperf3 -b.",
+		Rules:       "A bit rate such as 10M or 1G.",
+	},
+	"controller.test_profiles.window_size": {
+		Description: "Socket buffer size, as for iperf3 -w.",
+		Rules:       "A size such as 256K.",
+	},
+	"controller.test_profiles.parallel": {
+		Description: "Parallel streams per iperf3 process, as for iperf3 -P.",
+		Rules:       "At least 1.",
+	},
+	"controller.test_profiles.bidirectional": {
+		Description: "Send in both directions at once, as for iperf3 --bidir.",
+	},
+	"controller.test_profiles.reverse": {
+		Description: "Have the server send, as for iperf3 -R.",
+	},
+	"controller.test_profiles.separate_reverse": {
+		Description: "Run a bidirectional profile as two one-way tests, the reverse one with its own test ID and a server port on the source, instead of one iperf3 --bidir test.",
+		Rules:       "Requires bidirectional.",
+	},
+	"controller.test_profiles.buffer_length": {
+		Description: "Length of the read and write buffer in bytes, as for iperf3 -l.",
+	},
+	"controller.test_profiles.congestion_control": {
+		Description: "TCP congestion control algorithm, as for iperf3 -C; see congestion_control_policy for daemons lacking it.",
+	},
+	"controller.test_profiles.mss": {
+		Description: "TCP maximum segment size, as for iperf3 -M.",
+	},
+	"controller.test_profiles.no_delay": {
+		Description: "Disable Nagle's algorithm, as for iperf3 -N.",
+	},
+	"controller.test_profiles.tos": {
+		Description: "IP type of service, as for iperf3 -S.",
+	},
+	"controller.test_profiles.zerocopy": {
+		Description: "Send with zero-copy, as for iperf3 -Z.",
+	},
+	"controller.test_profiles.omit_seconds": {
+		Description: "Seconds at the start of the test left out of the results, as for iperf3 -O.",
+		Rules:       "Should be shorter than the duration.",
+	},
+	"controller.test_profiles.processes_per_pair": {
+		Description: "iperf3 processes, each with its own server port, run for every pair.",
+		Default:     "1",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.test_profiles.ports_per_pair": {
+		Description: "Server ports allocated to every pair, each with its own iperf3 client and server running an equal share of parallel, so that high stream counts are not limited by a single iperf3 server. The processes' results are summed into one result per pair.",
+		Default:     "1",
+		Rules:       "Cannot be negative, nor combined with processes_per_pair; parallel must be a multiple of it.",
+		Example:     "ports_per_pair: 4",
+	},
+	"controller.test_profiles.burst": {
+		Description: "UDP microburst schedule: bursts of on_ms at the profile bandwidth, each followed by off_ms idle.",
+		Rules:       "Requires protocol udp and a bandwidth.",
+		Example: `burst:
+  on_ms: 100
+  off_ms: 900
+  repeat: 10`,
+	},
+	"controller.test_profiles.burst.on_ms": {
+		Description: "Length of each burst in milliseconds.",
+		Rules:       "At least 1.",
+	},
+	"controller.test_profiles.burst.off_ms": {
+		Description: "Idle time after each burst in milliseconds.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.test_profiles.burst.repeat": {
+		Description: "Number of bursts.",
+		Rules:       "At least 1.",
+	},
+	"controller.test_profiles.extra_flags": {
+		Description: "Further iperf3 flags and their values, passed through unchecked.",
+		Example:     `extra_flags: {"--fq-rate": "5G"}`,
+	},
+	"controller.test_profiles.lint_ignore": {
+		Description: "Lint rules the profile breaks on purpose, left out of lint warnings.",
+		Rules:       "Each must name a known lint rule.",
+	},
+	"controller.test_profiles.capture_on": {
+		Description: "Keep a packet capture of the tests that fail, or that measure below capture_threshold; only daemons with capture enabled capture.",
+		Values:      []string{"failure", "threshold"},
+		Example:     `capture_on: [failure, threshold]`,
+	},
+	"controller.test_profiles.capture_threshold": {
+		Description: "Throughput below which capture_on threshold keeps a capture, in either direction.",
+		Rules:       "A bit rate such as 500M; required by capture_on threshold.",
+	},
+	"controller.topology": {
+		Description: "Which pairs of nodes are tested, and with which profiles.",
+		Example: `topology:
+  type: full_mesh
+  default_profile: default`,
+	},
+	"controller.topology.type": {
+		Description: "How pairs are chosen: every ordered pair, only those listed in pairs, a rotating share of the mesh, those between a hub and every other node, each node and its neighbor around a ring, one source sending to every other node at once, every other node sending to one destination at once, a random share of the mesh, each node of one half of the cluster sending to a partner in the other, or each hop between adjacent nodes of a chain.",
+		Rules:       "Required, unless set by the workload selected with run --workload.",
+		Values:      []string{"full_mesh", "custom", "rotating", "star", "ring", "one_to_many", "many_to_one", "partial_mesh", "bisection", "chain"},
+	},
+	"controller.topology.default_profile": {
+		Description: "Profile of the pairs no override or group sets one for.",
+		Rules:       "Required; must name a test profile.",
+	},
+	"controller.topology.overrides": {
+		Description: "Pairs that run with another profile or timing, or as background load.",
+		Example: `overrides:
+  - nodes: [node1, node2]
+    profile: high_bandwidth
+  - source_nodes: [node3]
+    destination_nodes: [node4]
+    duration: 30`,
+	},
+	"controller.topology.overrides.source_nodes": {
+		Description: "Sources of the pairs selected, with each of destination_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.overrides.destination_nodes": {
+		Description: "Destinations of the pairs selected, with each of source_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.overrides.source_tags": {
+		Description: "Tags whose nodes are added to source_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.overrides.destination_tags": {
+		Description: "Tags whose nodes are added to destination_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.overrides.nodes": {
+		Description: "Nodes whose pairs among each other are selected, in both directions.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.overrides.profile": {
+		Description: "Profile of the selected pairs.",
+		Default:     "topology.default_profile",
+		Rules:       "Must name a test profile.",
+	},
+	"controller.topology.overrides.duration": {
+		Description: "Duration of the selected pairs in seconds, replacing the profile's.",
+		Rules:       "Cannot be negative; when set, at least the minimum iperf3 duration.",
+	},
+	"controller.topology.overrides.start_delay_ms": {
+		Description: "Delay before the selected pairs' clients start, in milliseconds.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.topology.overrides.background": {
+		Description: "Run the selected pairs as unmeasured load for the whole run.",
+	},
+	"controller.topology.overrides.repeat": {
+		Description: "Times each selected pair runs in one run, as tests suffixed -rep1, -rep2 and so on, summarized together under repetitions in the JSON output.",
+		Default:     "1",
+		Rules:       "Cannot be negative; not with background.",
+	},
+	"controller.topology.groups": {
+		Description: "Named sets of pairs run one after another; when set, only group pairs are tested.",
+		Rules:       "Names must be unique; not with rotating topologies.",
+		Example: `groups:
+  - name: baseline
+    pairs:
+      - nodes: [node1, node2]
+  - name: load
+    after: [baseline]
+    pairs:
+      - source_nodes: [node3]
+        destination_nodes: [node4]`,
+	},
+	"controller.topology.schedule": {
+		Description: "How the pairs run: all at once, or in rounds one after the other, in which each node sends or receives in at most one pair, so each link is measured without contention.",
+		Rules:       "Default concurrent; rounds cannot be combined with groups.",
+		Values:      []string{ScheduleConcurrent, ScheduleRounds},
+		Example:     "schedule: rounds",
+	},
+	"controller.topology.directed": {
+		Description: "Set to false to test each unordered pair of nodes of a mesh once, from the node listed first, rather than both ways, halving the tests of symmetric links. A profile with bidirectional still measures both directions in that one test.",
+		Rules:       "false is only valid for full_mesh and partial_mesh, without groups.",
+		Default:     "true",
+		Example:     "directed: false",
+	},
+	"controller.topology.per_interface": {
+		Description: "Runs each pair once per interface name both nodes have, between the addresses of that interface, with the interface name as a test ID suffix.",
+		Rules:       "Every node needs interfaces; cannot be combined with address_families.",
+		Default:     "false",
+		Example:     "per_interface: true",
+	},
+	"controller.topology.file": {
+		Description: "YAML file of further topology settings, such as a custom topology's pairs, kept apart from the controller configuration. Its lists are appended to the configuration's; its other settings fill those left unset.",
+		Rules:       "Relative to the configuration's directory; unknown fields are refused, and settings both files set must match. Cannot itself set file.",
+		Example:     "file: ./plan.yaml",
+	},
+	"controller.topology.groups.name": {
+		Description: "Name of the group, referenced by after and reported in results.",
+		Rules:       "Required and unique.",
+	},
+	"controller.topology.groups.after": {
+		Description: "Groups that must finish before this one starts.",
+		Rules:       "Each must name a group.",
+	},
+	"controller.topology.groups.background": {
+		Description: "Keep the group running while the groups that depend on it execute.",
+	},
+	"controller.topology.groups.pairs": {
+		Description: "Pair selections of the group, each like an override.",
+		Rules:       "Must select at least one pair; pairs cannot be background.",
+	},
+	"controller.topology.groups.pairs.source_nodes": {
+		Description: "Sources of the pairs selected, with each of destination_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.groups.pairs.destination_nodes": {
+		Description: "Destinations of the pairs selected, with each of source_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.groups.pairs.source_tags": {
+		Description: "Tags whose nodes are added to source_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.groups.pairs.destination_tags": {
+		Description: "Tags whose nodes are added to destination_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.groups.pairs.nodes": {
+		Description: "Nodes whose pairs among each other are selected, in both directions.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.groups.pairs.profile": {
+		Description: "Profile of the selected pairs.",
+		Default:     "topology.default_profile",
+		Rules:       "Must name a test profile.",
+	},
+	"controller.topology.groups.pairs.duration": {
+		Description: "Duration of the selected pairs in seconds, replacing the profile's.",
+		Rules:       "Cannot be negative; when set, at least the minimum iperf3 duration.",
+	},
+	"controller.topology.groups.pairs.start_delay_ms": {
+		Description: "Delay before the selected pairs' clients start, in milliseconds.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.topology.groups.pairs.background": {
+		Description: "Not allowed in groups; set background on the group instead.",
+	},
+	"controller.topology.groups.pairs.repeat": {
+		Description: "Times each selected pair runs in the group; see overrides.repeat.",
+		Default:     "1",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.topology.exclusions": {
+		Description: "Pairs left out of the topology entirely, such as nodes on isolated networks that always fail; the run summary counts the tests left out.",
+		Rules:       "Only with full_mesh, partial_mesh, rotating and custom topologies, and not with groups; a custom topology must not list an excluded pair.",
+		Example: `exclusions:
+  - source_nodes: [node1]
+    destination_nodes: [tag:vlan:isolated]`,
+	},
+	"controller.topology.exclusions.source_nodes": {
+		Description: "Sources of the pairs excluded, with each of destination_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.exclusions.destination_nodes": {
+		Description: "Destinations of the pairs excluded, with each of source_nodes.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.exclusions.source_tags": {
+		Description: "Tags whose nodes are added to source_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.exclusions.destination_tags": {
+		Description: "Tags whose nodes are added to destination_nodes.",
+		Rules:       "Each must be a tag of a node.",
+	},
+	"controller.topology.exclusions.nodes": {
+		Description: "Nodes whose pairs among each other are excluded, in both directions.",
+		Rules:       "Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.exclusions.profile": {
+		Description: "Unused; exclusions take no settings.",
+		Rules:       "Not allowed; exclusions only select pairs.",
+	},
+	"controller.topology.exclusions.duration": {
+		Description: "Unused; exclusions take no settings.",
+		Rules:       "Not allowed; exclusions only select pairs.",
+	},
+	"controller.topology.exclusions.start_delay_ms": {
+		Description: "Unused; exclusions take no settings.",
+		Rules:       "Not allowed; exclusions only select pairs.",
+	},
+	"controller.topology.exclusions.background": {
+		Description: "Unused; exclusions take no settings.",
+		Rules:       "Not allowed; exclusions only select pairs.",
+	},
+	"controller.topology.exclusions.repeat": {
+		Description: "Unused; exclusions take no settings.",
+		Rules:       "Not allowed; exclusions only select pairs.",
+	},
+	"controller.topology.pairs_per_run": {
+		Description: "Mesh pairs a rotating topology tests per run, those longest without a measurement first.",
+		Rules:       "At least 1 for rotating topologies; only valid for them.",
+	},
+	"controller.topology.coverage_file": {
+		Description: "File recording when a rotating topology last measured each pair.",
+		Default:     "coverage.json next to output.json_file",
+	},
+	"controller.topology.hub": {
+		Description: "Node a star topology tests every other node against, by ID or by tag; nodes sharing a hub tag are not tested against each other.",
+		Rules:       "Required for star topologies; only valid for them. Must be a node ID or a tag of a node.",
+		Example:     "hub: aggregation1",
+	},
+	"controller.topology.direction": {
+		Description: "Which way the pairs of a star topology send traffic.",
+		Default:     "both",
+		Rules:       "Only valid for star topologies.",
+		Values:      []string{"to_hub", "from_hub", "both"},
+	},
+	"controller.topology.order": {
+		Description: "Order of the nodes around a ring topology, where nodes not listed follow in configuration order, or along a chain topology, which tests only the nodes listed.",
+		Rules:       "Only valid for ring and chain topologies; at least 2 nodes for chain. Each must be a node ID, listed once.",
+		Example:     "order: [rack1-a, rack2-a, rack1-b, rack2-b]",
+	},
+	"controller.topology.bidirectional_ring": {
+		Description: "Also test each node's next node sending back to it, two tests per node.",
+		Rules:       "Only valid for ring topologies.",
+	},
+	"controller.topology.include_end_to_end": {
+		Description: "Also test the first node of a chain sending to the last, across every hop.",
+		Rules:       "Only valid for chain topologies.",
+		Example:     "include_end_to_end: true",
+	},
+	"controller.topology.pairs": {
+		Description: "The pairs a custom topology tests, each once per address family.",
+		Rules:       "Required for custom topologies; only valid for them.",
+		Example: `pairs:
+  - source: node1
+    destination: node3
+    profile: high_bandwidth`,
+	},
+	"controller.topology.pairs.source": {
+		Description: "ID of the node running the client.",
+		Rules:       "Must be a node ID.",
+	},
+	"controller.topology.pairs.destination": {
+		Description: "ID of the node running the server.",
+		Rules:       "Must be a node ID other than source.",
+	},
+	"controller.topology.pairs.profile": {
+		Description: "Profile of the pair, taking precedence over overrides; unset uses the override's or the default profile.",
+		Rules:       "Must name a test profile.",
+	},
+	"controller.topology.pairs.repeat": {
+		Description: "Times the pair runs in one run, taking precedence over overrides; see overrides.repeat.",
+		Default:     "1",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.topology.source_tags": {
+		Description: "Limit a full mesh to the pairs from a node with any of these tags to a node with any of destination_tags; a node with tags of both is tested both ways.",
+		Rules:       "Only valid for full_mesh topologies, with destination_tags and without groups. Each must be a tag of a node.",
+		Example:     "source_tags: [rack-a]",
+	},
+	"controller.topology.destination_tags": {
+		Description: "Limit a full mesh to the pairs to a node with any of these tags from a node with any of source_tags.",
+		Rules:       "Only valid for full_mesh topologies, with source_tags and without groups. Each must be a tag of a node.",
+		Example:     "destination_tags: [rack-b]",
+	},
+	"controller.topology.group_by": {
+		Description: "Tag key grouping the nodes of a mesh by the value of their key:value tag, such as a1 of rack:a1, to test only the pairs within or across groups as group_mode selects. Nodes without such a tag are left out, and the summary reports each group pair, such as a1->a2, separately.",
+		Rules:       "Only valid for full_mesh, partial_mesh and rotating topologies, with group_mode and without groups. Must be a tag key some node has a tag of.",
+		Example:     "group_by: rack",
+	},
+	"controller.topology.group_mode": {
+		Description: "Which pairs of the groups of group_by a mesh tests: those within a group, or those across groups.",
+		Rules:       "Only valid with group_by.",
+		Values:      []string{"intra_group", "inter_group"},
+		Example:     "group_mode: inter_group",
+	},
+	"controller.topology.source": {
+		Description: "Node a one_to_many topology sends from, to every other node at once; its daemon must have a process slot per destination.",
+		Rules:       "Required for one_to_many topologies; only valid for them. Must be a node ID.",
+		Example:     "source: edge1",
+	},
+	"controller.topology.destination": {
+		Description: "Node every other node of a many_to_one topology sends to at once; its daemon must have a server port and process slot per source.",
+		Rules:       "Required for many_to_one topologies; only valid for them. Must be a node ID.",
+		Example:     "destination: storage1",
+	},
+	"controller.topology.density": {
+		Description: "Share of the full mesh pairs a partial_mesh topology tests, chosen at random; every node still sends and receives at least once.",
+		Rules:       "Required for partial_mesh topologies unless max_tests is set; only valid for them. Greater than 0, at most 1.",
+		Example:     "density: 0.1",
+	},
+	"controller.topology.max_tests": {
+		Description: "Number of full mesh pairs a partial_mesh topology tests, chosen at random with each node sending max_tests/N of them, give or take one. The selected pairs are recorded in the JSON output; a budget of the whole mesh or more tests it all.",
+		Rules:       "Only valid for partial_mesh topologies, instead of density. Positive.",
+		Example:     "max_tests: 200",
+	},
+	"controller.topology.seed": {
+		Description: "Seed of the random selection of a partial_mesh topology; the same seed and nodes select the same pairs. Unset or 0 picks a new seed per run, recorded in the JSON output.",
+		Rules:       "Only valid for partial_mesh topologies.",
+	},
+	"controller.topology.side_a": {
+		Description: "Nodes of the sending side of a bisection topology, each paired in order with a distinct node of side_b.",
+		Rules:       "Required for bisection topologies; only valid for them. Each must be a node ID, or tag:<tag> for the nodes with the tag; no node may be on both sides.",
+		Example: `side_a: [tag:rack:a]
+side_b: [tag:rack:b]`,
+	},
+	"controller.topology.side_b": {
+		Description: "Nodes of the receiving side of a bisection topology.",
+		Rules:       "Required for bisection topologies; only valid for them. Each must be a node ID, or tag:<tag> for the nodes with the tag.",
+	},
+	"controller.topology.allow_unbalanced": {
+		Description: "Run a bisection topology whose sides differ in size, leaving the extra nodes of the larger side untested.",
+		Rules:       "Only valid for bisection topologies.",
+	},
+	"controller.topology.max_total_tests": {
+		Description: "Runs of more pairs are refused; negative disables the limit.",
+	},
+	"controller.topology.confirm_tests_above": {
+		Description: "Runs of more pairs need confirming at a terminal; negative disables the prompt.",
+	},
+	"controller.topology.address_families": {
+		Description: "Run each pair once per address family both nodes have a data address in.",
+		Rules:       "Each family at most once.",
+		Values:      []string{"v4", "v6"},
+		Example:     `address_families: [v4, v6]`,
+	},
+	"controller.output": {
+		Description: "Files the results are written to.",
+		Example: `output:
+  json_file: ./results.json
+  csv_file: ./results.csv`,
+	},
+	"controller.output.json_file": {
+		Description: "File the results, summary and metadata are written to as JSON.",
+		Rules:       "Required.",
+	},
+	"controller.output.csv_file": {
+		Description: "File the results are written to as CSV, one row per test.",
+	},
+	"controller.output.schema_file": {
+		Description: "File a JSON Schema of the JSON output is written to.",
+	},
+	"controller.output.burst_rows": {
+		Description: "Add a row per burst of burst profiles to the results.",
+	},
+	"controller.output.compress": {
+		Description: "Reserved for compressed output; currently has no effect.",
+	},
+	"controller.output.save_daemon_results": {
+		Description: "Have the daemons keep local copies of their results.",
+	},
+	"controller.output.save_raw_results": {
+		Description: "Save the raw iperf3 results of all daemons.",
+	},
+	"controller.output.raw_results_file": {
+		Description: "File the raw results are saved to.",
+		Default:     "raw_results_<timestamp>.json",
+	},
+	"controller.output.filters": {
+		Description: "Select the results written to the JSON and CSV files; the summary covers every result.",
+		Example: `filters:
+  include: ["status=completed"]
+  exclude: ["source=node1"]`,
+	},
+	"controller.output.filters.include": {
+		Description: "Rules of key=value terms; results matching none of them are left out.",
+	},
+	"controller.output.filters.exclude": {
+		Description: "Rules of key=value terms; results matching any of them are left out.",
+	},
+	"controller.output.embed_config": {
+		Description: "Include the effective configuration, with secrets redacted, in the JSON output.",
+	},
+	"controller.output.timeline_csv_file": {
+		Description: "File the aggregate throughput over time is written to, one row per bucket.",
+	},
+	"controller.output.flakiness_csv_file": {
+		Description: "File the flakiness of each pair is written to, one row per pair, when some pair ran more than once.",
+	},
+	"controller.output.timestamp_suffix": {
+		Description: "Add the run's start time to every output file name instead of refusing existing files.",
+	},
+	"controller.output.streaming_threshold": {
+		Description: "Number of tests above which results are streamed to stream_file as they are collected, with running statistics and only a sample of the results in the JSON output.",
+		Rules:       "Negative disables streaming.",
+	},
+	"controller.output.stream_file": {
+		Description: "File every result of a streaming run is written to, one JSON object per line.",
+		Default:     "the JSON file with an .ndjson extension",
+	},
+	"controller.concurrency": {
+		Description: "Parallelism, batching and timeouts of the controller's calls to the daemons.",
+	},
+	"controller.concurrency.max_concurrent_nodes": {
+		Description: "Daemons called at once in each phase.",
+	},
+	"controller.concurrency.max_concurrent_tests": {
+		Description: "Client processes running at once across all nodes, bidirectional ones counted twice; larger runs start in waves. Negative is unbounded.",
+	},
+	"controller.concurrency.client_start_batch_size": {
+		Description: "Clients started per call to a daemon.",
+	},
+	"controller.concurrency.connection_timeout_seconds": {
+		Description: "Timeout for connecting to a daemon.",
+	},
+	"controller.concurrency.rpc_timeout_seconds": {
+		Description: "Timeout of each call to a daemon.",
+	},
+	"controller.concurrency.liveness_timeout_seconds": {
+		Description: "Just before a run's first state-changing request, every node must answer a status request within this long. Nodes that do not are left out if the quorum allows it; otherwise the run fails before anything starts. Negative skips the check.",
+		Default:     "5",
+	},
+	"controller.concurrency.stall_window_seconds": {
+		Description: "Past their expected end, tests still running are given up on when none finished for this long. Negative waits a fixed time instead.",
+	},
+	"controller.concurrency.stop_stalled_tests": {
+		Description: "Stop stalled tests instead of leaving them to cleanup.",
+	},
+	"controller.concurrency.process_stop_seconds": {
+		Description: "Cleanup waits this long for the daemons' processes to exit before force-stopping them. Negative skips the check.",
+	},
+	"controller.concurrency.poll_jitter_percent": {
+		Description: "Status polls vary their interval by up to this percentage either way. Negative disables it.",
+		Rules:       "At most 100.",
+	},
+	"controller.concurrency.max_poll_backoff_seconds": {
+		Description: "Nodes whose status polls fail are polled at growing intervals up to this long. Negative disables the backoff.",
+	},
+	"controller.concurrency.max_poll_rate": {
+		Description: "Status polls per second across all nodes; 0 is unbounded.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.concurrency.max_blackout_wait_seconds": {
+		Description: "Longest a test waits for blackout windows of its nodes to end before it is skipped. Negative skips such tests without waiting.",
+		Default:     "7200",
+	},
+	"controller.analysis": {
+		Description: "Checks run on the collected results.",
+	},
+	"controller.analysis.discrepancy_threshold_percent": {
+		Description: "Flag tests whose sender- and receiver-reported throughput differ by more than this percentage; 0 flags any difference.",
+		Default:     "5",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.analysis.retransmit_threshold_per_mb": {
+		Description: "Flag tests with more TCP retransmits per MB sent; 0 disables flagging.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.analysis.timeline_bucket_seconds": {
+		Description: "Width of the buckets of the aggregate throughput timeline.",
+		Default:     "1",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.analysis.v6_lag_threshold_percent": {
+		Description: "Flag dual-stack pairs whose IPv6 throughput is this far below IPv4; negative disables flagging.",
+	},
+	"controller.analysis.min_success_ratio": {
+		Description: "Fail the run, after writing its output, if any pair completed a smaller share of its attempts; 0 disables the check.",
+		Rules:       "Between 0 and 1.",
+	},
+	"controller.analysis.confidence": {
+		Description: "Rules rating each completed result high, medium or low confidence, with the reasons, in its confidence and confidence_reasons. cpu_limited makes a result low confidence; of the other rules, one makes it medium and two low. The summary counts results by confidence.",
+		Example: `confidence:
+  disabled_rules: [low_overlap]
+  cpu_percent: 95`,
+	},
+	"controller.analysis.confidence.disabled_rules": {
+		Description: "Rules not applied: cpu_limited (the sender's iperf3 process used cpu_percent CPU), low_overlap (less than min_overlap of the test ran while every test of its group did), retransmit_storm (flagged for retransmit_threshold_per_mb) and throughput_fallback (throughput not read from iperf3's totals).",
+		Values:      []string{"cpu_limited", "low_overlap", "retransmit_storm", "throughput_fallback"},
+	},
+	"controller.analysis.confidence.cpu_percent": {
+		Description: "Sender iperf3 CPU usage at which cpu_limited applies. iperf3 reports per-process usage, which can pass 100 for multi-threaded versions.",
+		Rules:       "Cannot be negative.",
+		Default:     "90",
+	},
+	"controller.analysis.confidence.min_overlap": {
+		Description: "Share of a test's run time inside the window every concurrently started test of its group ran in, below which low_overlap applies.",
+		Rules:       "Between 0 and 1.",
+		Default:     "0.5",
+	},
+	"controller.analysis.confidence.ignore_low": {
+		Description: "Have check report a low-confidence result as UNKNOWN, with the reasons, instead of comparing it to --warn and --crit.",
+	},
+	"controller.analysis.mtu_heuristics": {
+		Description: "Probe low-throughput TCP pairs after the run at each mtu_probe_mss to spot MTU mismatches.",
+	},
+	"controller.analysis.mtu_probe_mss": {
+		Description: "MSS values the MTU heuristics probe, one after the other.",
+		Rules:       "At least 2 values, each between 88 and 65495.",
+	},
+	"controller.analysis.mtu_probe_seconds": {
+		Description: "Duration of each MTU probe.",
+		Rules:       "At least the minimum iperf3 duration.",
+	},
+	"controller.analysis.mtu_probe_budget_seconds": {
+		Description: "Probing is skipped when it would take longer; negative lifts the limit.",
+	},
+	"controller.port_range": {
+		Description: "Sub-range of the daemons' port range this run's servers use, so runs sharing daemons do not collide.",
+		Example: `port_range:
+  start: 5201
+  end: 5300`,
+	},
+	"controller.port_range.start": {
+		Description: "First server port of the run.",
+		Rules:       "Between 1 and 65535, at most end.",
+	},
+	"controller.port_range.end": {
+		Description: "Last server port of the run.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"controller.port_range.owner": {
+		Description: "Identifies this run to the daemons holding its port range.",
+		Default:     "hostname/pid of the controller",
+	},
+	"controller.daemon_port_range": {
+		Description: "Mirror of the daemons' own port_range, so that validate can check topologies offline.",
+	},
+	"controller.daemon_port_range.start": {
+		Description: "First port of the daemons' port range.",
+		Rules:       "Between 1 and 65535, at most end.",
+	},
+	"controller.daemon_port_range.end": {
+		Description: "Last port of the daemons' port range.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"controller.daemon_port_range.owner": {
+		Description: "Unused in daemon port ranges.",
+	},
+	"controller.on_failure": {
+		Description: "Cleanup when a run fails part-way.",
+	},
+	"controller.on_failure.stop_all": {
+		Description: "Stop all iperf3 processes on every daemon.",
+		Default:     "true",
+	},
+	"controller.on_failure.clear_results": {
+		Description: "Drop the results stored on the daemons.",
+		Default:     "false",
+	},
+	"controller.on_failure.collect_partial": {
+		Description: "Collect whatever results exist.",
+		Default:     "true",
+	},
+	"controller.on_failure.partial_results_file": {
+		Description: "File partial results are written to.",
+		Default:     "partial-results.json next to output.json_file",
+	},
+	"controller.node_cache": {
+		Description: "Cache of daemon capabilities and facts kept between runs.",
+	},
+	"controller.node_cache.disabled": {
+		Description: "Query every daemon on every run.",
+	},
+	"controller.node_cache.file": {
+		Description: "File the cache is kept in.",
+		Default:     "iperf-cnc/node-cache.json in the user cache directory",
+	},
+	"controller.node_cache.ttl_seconds": {
+		Description: "Lifetime of cache entries.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.debug": {
+		Description: "Opt-in diagnostics HTTP listener serving pprof, expvar counters and a state dump.",
+	},
+	"controller.debug.enabled": {
+		Description: "Start the listener.",
+	},
+	"controller.debug.address": {
+		Description: "Address the listener binds to.",
+		Default:     "127.0.0.1",
+	},
+	"controller.debug.port": {
+		Description: "Port the listener binds to.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"controller.logging": {
+		Description: "How much the controller logs about each node.",
+	},
+	"controller.logging.verbose": {
+		Description: "Log every node's progress in each phase.",
+	},
+	"controller.logging.progress_interval_seconds": {
+		Description: "How often aggregated progress is logged.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.congestion_control_policy": {
+		Description: "What happens when a profile requests a congestion control algorithm a daemon's kernel lacks: fail the prepare phase, or only log it.",
+		Default:     "reject",
+		Values:      []string{"reject", "warn"},
+	},
+	"controller.resource_sampling": {
+		Description: "Have daemons sample host CPU, memory and NIC counters during the run.",
+		Example: `resource_sampling:
+  interval_seconds: 1`,
+	},
+	"controller.resource_sampling.interval_seconds": {
+		Description: "Sampling interval; 0 disables sampling.",
+		Rules:       "0 or at least 0.1.",
+	},
+	"controller.resource_sampling.cpu_threshold_percent": {
+		Description: "Flag nodes whose CPU was busier than this while their tests ran.",
+		Rules:       "Between 0 and 100.",
+	},
+	"controller.tag_policy": {
+		Description: "How node tags are normalized; tags are always trimmed.",
+	},
+	"controller.tag_policy.lowercase": {
+		Description: "Lowercase tags, and those of --tag expressions and output filters.",
+	},
+	"controller.quorum": {
+		Description: "How many nodes must be healthy for a run to start; the run leaves the others out. Unset, every node is required.",
+		Example: `quorum:
+  min_healthy_fraction: 0.9`,
+	},
+	"controller.quorum.min_nodes": {
+		Description: "Healthy nodes required.",
+		Rules:       "Cannot be negative or exceed the configured nodes.",
+	},
+	"controller.quorum.min_healthy_fraction": {
+		Description: "Share of the run's nodes required to be healthy.",
+		Rules:       "Between 0 and 1.",
+	},
+	"controller.workloads": {
+		Description: "Named presets of topology, profile and analysis settings, selected with run --workload. Each setting applies only where the configuration leaves the field unset. A workload named like a built-in one (fabric-acceptance, incast-stress, quick-check) replaces it; iperf-controller workloads list shows them all.",
+		Example: `workloads:
+  nightly:
+    description: Full mesh at line rate
+    topology: full_mesh
+    profile: line-rate
+    min_success_ratio: 1`,
+	},
+	"controller.workloads.description": {
+		Description: "What the workload tests, shown by workloads list.",
+	},
+	"controller.workloads.topology": {
+		Description: "Topology type the workload selects.",
+		Values:      []string{"full_mesh", "custom", "rotating", "star", "ring", "one_to_many", "many_to_one", "partial_mesh", "bisection", "chain"},
+	},
+	"controller.workloads.profile": {
+		Description: "Test profile the workload makes the default profile.",
+		Rules:       "Must name a profile in test_profiles.",
+	},
+	"controller.workloads.discrepancy_threshold_percent": {
+		Description: "Sets analysis.discrepancy_threshold_percent.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.workloads.retransmit_threshold_per_mb": {
+		Description: "Sets analysis.retransmit_threshold_per_mb.",
+		Rules:       "Cannot be negative.",
+	},
+	"controller.workloads.min_success_ratio": {
+		Description: "Sets analysis.min_success_ratio.",
+		Rules:       "Between 0 and 1.",
+	},
+	"controller.workloads.mtu_heuristics": {
+		Description: "Turns on analysis.mtu_heuristics.",
+	},
+	"daemon": {
+		Description: "Settings of iperf-daemon, which runs iperf3 processes for the controller.",
+	},
+	"daemon.listen_port": {
+		Description: "Port the daemon's gRPC server listens on.",
+		Rules:       "Between 1 and 65535, outside port_range.",
+	},
+	"daemon.port_range": {
+		Description: "Ports the daemon's iperf3 servers use.",
+		Example: `port_range:
+  start: 5201
+  end: 5400`,
+	},
+	"daemon.port_range.start": {
+		Description: "First iperf3 server port.",
+		Rules:       "Between 1 and 65535, less than end.",
+	},
+	"daemon.port_range.end": {
+		Description: "Last iperf3 server port.",
+		Rules:       "Between 1 and 65535.",
+	},
+	"daemon.port_allocation": {
+		Description: "How free ports are chosen: the lowest first, continuing after the last one allocated, or at random. round_robin and random avoid recently released ports, whose sockets may be in TIME_WAIT.",
+		Default:     "sequential",
+		Values:      []string{"sequential", "round_robin", "random"},
+	},
+	"daemon.port_cooldown_seconds": {
+		Description: "Seconds a released port is avoided by the round_robin and random strategies, unless no other port is free; negative disables the cool-down.",
+		Default:     "60",
+	},
+	"daemon.allowed_cidrs": {
+		Description: "Networks controllers may connect from; calls from other addresses are refused with PermissionDenied and logged. Connections over a Unix socket are always allowed.",
+		Default:     "every address",
+		Rules:       "Each must be a CIDR, IPv4 or IPv6.",
+		Example:     "allowed_cidrs: [10.20.0.0/16, fd00:20::/32]",
+	},
+	"daemon.max_processes": {
+		Description: "iperf3 processes the daemon runs at once.",
+		Rules:       "At least 1.",
+	},
+	"daemon.cpu_affinity": {
+		Description: "Reserved for pinning iperf3 processes to CPUs; currently has no effect.",
+	},
+	"daemon.log_level": {
+		Description: "Least severe level logged.",
+		Values:      []string{"debug", "info", "warn", "error"},
+	},
+	"daemon.result_dir": {
+		Description: "Directory local copies of results are saved in.",
+		Rules:       "Cannot be empty.",
+	},
+	"daemon.timeout": {
+		Description: "Timeouts of iperf3 process operations.",
+	},
+	"daemon.timeout.process_start_seconds": {
+		Description: "Reserved for a process start timeout; currently has no effect.",
+	},
+	"daemon.timeout.process_stop_seconds": {
+		Description: "Stopped iperf3 processes get SIGTERM, then SIGKILL after this long.",
+	},
+	"daemon.timeout.test_execution_seconds": {
+		Description: "Reserved for a test execution timeout; currently has no effect.",
+	},
+	"daemon.debug": {
+		Description: "Opt-in diagnostics HTTP listener serving pprof, expvar counters and a state dump.",
+	},
+	"daemon.debug.enabled": {
+		Description: "Start the listener.",
+	},
+	"daemon.debug.address": {
+		Description: "Address the listener binds to.",
+		Default:     "127.0.0.1",
+	},
+	"daemon.debug.port": {
+		Description: "Port the listener binds to.",
+		Rules:       "Between 1 and 65535, outside port_range.",
+	},
+	"daemon.max_stored_results": {
+		Description: "Results kept until a controller retrieves them; negative disables the cap.",
+	},
+	"daemon.max_total_result_bytes": {
+		Description: "Bytes of results kept until a controller retrieves them; negative disables the cap.",
+	},
+	"daemon.result_overflow": {
+		Description: "What happens to a result that would exceed a cap: reject it, or evict the oldest stored results to make room.",
+		Values:      []string{"reject", "evict_oldest"},
+	},
+	"daemon.simulate": {
+		Description: "Shape of the synthetic results of --simulate mode; ignored otherwise.",
+		Example: `simulate:
+  throughput_mean: 9.4G
+  failure_rate: 0.01`,
+	},
+	"daemon.simulate.throughput_mean": {
+		Description: "Mean throughput of simulated tests.",
+		Rules:       "A bit rate such as 9.4G.",
+	},
+	"daemon.simulate.throughput_stddev": {
+		Description: "Standard deviation of the throughput of simulated tests.",
+		Rules:       "A bit rate such as 200M.",
+	},
+	"daemon.simulate.failure_rate": {
+		Description: "Share of simulated tests that fail.",
+		Rules:       "Between 0 and 1.",
+	},
+	"daemon.simulate.rtt_ms": {
+		Description: "Mean RTT reported for simulated TCP streams.",
+		Rules:       "Cannot be negative.",
+	},
+	"daemon.simulate.start_latency_ms": {
+		Description: "Delay before a simulated test starts sending.",
+		Rules:       "Cannot be negative.",
+	},
+	"daemon.simulate.seed": {
+		Description: "Random seed; 0 seeds from the clock.",
+	},
+	"daemon.capture": {
+		Description: "Packet captures, with tcpdump, of the tests whose profile sets capture_on; needs privileges to capture.",
+		Example: `capture:
+  enabled: true
+  interface: eth1
+  max_mb: 50`,
+	},
+	"daemon.capture.enabled": {
+		Description: "Capture the tests that ask for it; otherwise they run without a capture.",
+	},
+	"daemon.capture.interface": {
+		Description: "Interface captured on; any captures on all of them.",
+		Default:     "any",
+	},
+	"daemon.capture.snaplen": {
+		Description: "Bytes kept of each packet, enough for the headers by default.",
+		Default:     "128",
+		Rules:       "Between 0 and 262144; 0 uses the default.",
+	},
+	"daemon.capture.max_mb": {
+		Description: "Size of each capture file; later packets are dropped. A capture also ends with its test.",
+		Default:     "100",
+		Rules:       "Between 0 and 2048; 0 uses the default.",
+	},
+	"daemon.capture.tcpdump_path": {
+		Description: "tcpdump binary to capture with.",
+		Default:     "tcpdump",
+	},
+}
+
+// checkValue rejects a value of the field at path that is not one of the
+// values the registry allows, naming the field as key
+func checkValue(key, path, value string) error {
+	values := fieldDocs[path].Values
+	if contains(values, value) {
+		return nil
+	}
+	return fmt.Errorf("%s must be one of: %s", key, strings.Join(values, ", "))
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+)
+
+// maxIperfStreams is the most parallel streams iperf3 accepts per process
+const maxIperfStreams = 128
+
+// ProfileFinding is a combination of test profile settings that is valid
+// but that iperf3 treats specially, with the profiles using it
+type ProfileFinding struct {
+	Rule        string
+	Explanation string
+	Profiles    []string
+}
+
+func (f *ProfileFinding) String() string {
+	label := "profile"
+	if len(f.Profiles) > 1 {
+		label = "profiles"
+	}
+	return fmt.Sprintf("%s %s: %s (%s)", label, strings.Join(f.Profiles, ", "), f.Explanation, f.Rule)
+}
+
+// LintEnv is what is known of the nodes the profiles run on
+type LintEnv struct {
+	// Facts holds the nodes' last known facts; nodes without any are not
+	// checked
+	Facts map[string]*models.NodeFacts
+}
+
+// profileRule is a lint rule on a single profile. check returns why the
+// profile triggers the rule, or "" if it doesn't.
+type profileRule struct {
+	name  string
+	check func(profile TestProfile, env LintEnv) string
+}
+
+// profileRules are the lint rules, in the order findings are reported.
+// Profiles list the rules they mean to break under lint_ignore.
+var profileRules = []profileRule{
+	{
+		name: "bidir-reverse",
+		check: func(profile TestProfile, env LintEnv) string {
+			if profile.Bidirectional && !profile.SeparateReverse && profile.Reverse {
+				return "iperf3 rejects bidirectional together with reverse, so every test fails; bidirectional already measures both directions"
+			}
+			return ""
+		},
+	},
+	{
+		name: "max-streams",
+		check: func(profile TestProfile, env LintEnv) string {
+			if profile.Parallel/max(profile.PortsPerPair, 1) > maxIperfStreams {
+				return fmt.Sprintf("iperf3 refuses more than %d parallel streams, so every test fails; use ports_per_pair to split them among processes",
+					maxIperfStreams)
+			}
+			return ""
+		},
+	},
+	{
+		name: "udp-zerocopy",
+		check: func(profile TestProfile, env LintEnv) string {
+			if profile.Protocol == "udp" && profile.ZeroCopy {
+				return "zerocopy is a TCP sending method; UDP tests ignore it or, with some iperf3 versions, fail"
+			}
+			return ""
+		},
+	},
+	{
+		name: "tcp-bandwidth",
+		check: func(profile TestProfile, env LintEnv) string {
+			if profile.Protocol != "udp" && profile.Bandwidth != "" && profile.Bandwidth != "0" {
+				return fmt.Sprintf("bandwidth %s caps TCP tests, which then measure the cap rather than the path", profile.Bandwidth)
+			}
+			return ""
+		},
+	},
+	{
+		name: "window-above-rmem-max",
+		check: func(profile TestProfile, env LintEnv) string {
+			if profile.WindowSize == "" {
+				return ""
+			}
+			window, err := units.ParseBytes(profile.WindowSize)
+			if err != nil {
+				return ""
+			}
+			nodes := make([]string, 0)
+			limit := int64(0)
+			for nodeID, facts := range env.Facts {
+				if facts == nil || facts.RmemMax == 0 || facts.RmemMax >= window {
+					continue
+				}
+				nodes = append(nodes, nodeID)
+				if limit == 0 || facts.RmemMax < limit {
+					limit = facts.RmemMax
+				}
+			}
+			if len(nodes) == 0 {
+				return ""
+			}
+			sort.Strings(nodes)
+			return fmt.Sprintf("window_size %s exceeds net.core.rmem_max (as low as %d bytes) on %s; the kernel caps the socket buffer there",
+				profile.WindowSize, limit, strings.Join(nodes, ", "))
+		},
+	},
+}
+
+// LintRules returns the names of the lint rules
+func LintRules() []string {
+	names := make([]string, len(profileRules))
+	for i, rule := range profileRules {
+		names[i] = rule.name
+	}
+	return names
+}
+
+// LintProfiles checks the test profiles against the lint rules. Profiles
+// triggering a rule with the same explanation share a finding.
+func (c *ControllerConfig) LintProfiles(env LintEnv) []*ProfileFinding {
+	names := make([]string, 0, len(c.Controller.TestProfiles))
+	for name := range c.Controller.TestProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	findings := make([]*ProfileFinding, 0)
+	for _, rule := range profileRules {
+		byExplanation := make(map[string]*ProfileFinding)
+		for _, name := range names {
+			profile := c.Controller.TestProfiles[name]
+			if contains(profile.LintIgnore, rule.name) {
+				continue
+			}
+			explanation := rule.check(profile, env)
+			if explanation == "" {
+				continue
+			}
+			finding, exists := byExplanation[explanation]
+			if !exists {
+				finding = &ProfileFinding{Rule: rule.name, Explanation: explanation}
+				byExplanation[explanation] = finding
+				findings = append(findings, finding)
+			}
+			finding.Profiles = append(finding.Profiles, name)
+		}
+	}
+	return findings
+}
+
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestLintProfiles(t *testing.T) {
+	clean := TestProfile{Duration: 10, Parallel: 4, WindowSize: "128K"}
+	env := LintEnv{Facts: map[string]*models.NodeFacts{
+		"node1": {RmemMax: 212992},
+		"node2": {RmemMax: 8 << 20},
+	}}
+
+	tests := []struct {
+		rule    string
+		profile TestProfile
+	}{
+		{rule: "bidir-reverse", profile: TestProfile{Duration: 10, Parallel: 1, Bidirectional: true, Reverse: true}},
+		{rule: "max-streams", profile: TestProfile{Duration: 10, Parallel: 200}},
+		{rule: "udp-zerocopy", profile: TestProfile{Duration: 10, Parallel: 1, Protocol: "udp", Bandwidth: "1G", ZeroCopy: true}},
+		{rule: "tcp-bandwidth", profile: TestProfile{Duration: 10, Parallel: 1, Bandwidth: "5G"}},
+		{rule: "window-above-rmem-max", profile: TestProfile{Duration: 10, Parallel: 1, WindowSize: "4M"}},
+	}
+
+	if len(tests) != len(LintRules()) {
+		t.Fatalf("%d rules tested, want all %d", len(tests), len(LintRules()))
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			cfg := &ControllerConfig{}
+			cfg.Controller.TestProfiles = map[string]TestProfile{"clean": clean, "crafted": tt.profile}
+
+			findings := cfg.LintProfiles(env)
+			if len(findings) != 1 {
+				t.Fatalf("LintProfiles() = %v, want one finding", findings)
+			}
+			if findings[0].Rule != tt.rule || len(findings[0].Profiles) != 1 || findings[0].Profiles[0] != "crafted" {
+				t.Errorf("LintProfiles() = %v, want %s on the crafted profile", findings[0], tt.rule)
+			}
+			if err := validateTestProfile("crafted", tt.profile); err != nil {
+				t.Errorf("validateTestProfile() error = %v, want lint findings to stay valid", err)
+			}
+
+			// Listing the rule under lint_ignore silences it
+			tt.profile.LintIgnore = []string{tt.rule}
+			cfg.Controller.TestProfiles["crafted"] = tt.profile
+			if findings := cfg.LintProfiles(env); len(findings) != 0 {
+				t.Errorf("LintProfiles() with lint_ignore = %v, want none", findings)
+			}
+		})
+	}
+}
+
+func TestLintProfiles_PortsPerPairSplitsStreams(t *testing.T) {
+	cfg := &ControllerConfig{}
+	cfg.Controller.TestProfiles = map[string]TestProfile{"split": {Duration: 10, Parallel: 256, PortsPerPair: 4}}
+	if findings := cfg.LintProfiles(LintEnv{}); len(findings) != 0 {
+		t.Errorf("LintProfiles() = %v, want none for 64 streams per port", findings)
+	}
+}
+
+func TestLintProfiles_WindowNamesNodes(t *testing.T) {
+	cfg := &ControllerConfig{}
+	cfg.Controller.TestProfiles = map[string]TestProfile{
+		"a": {Duration: 10, Parallel: 1, WindowSize: "1M"},
+		"b": {Duration: 10, Parallel: 1, WindowSize: "1M"},
+	}
+
+	// Without facts the window can't be checked
+	if findings := cfg.LintProfiles(LintEnv{}); len(findings) != 0 {
+		t.Errorf("LintProfiles() without facts = %v, want none", findings)
+	}
+
+	findings := cfg.LintProfiles(LintEnv{Facts: map[string]*models.NodeFacts{"node2": {RmemMax: 4096}, "node1": {RmemMax: 8192}}})
+	if len(findings) != 1 {
+		t.Fatalf("LintProfiles() = %v, want profiles sharing one finding", findings)
+	}
+	got := findings[0].String()
+	for _, want := range []string{"profiles a, b", "as low as 4096 bytes", "node1, node2"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("finding %q does not mention %q", got, want)
+		}
+	}
+}
+
+func TestValidateTestProfile_UnknownLintRule(t *testing.T) {
+	profile := TestProfile{Duration: 10, Parallel: 1, LintIgnore: []string{"no-such-rule"}}
+	if err := validateTestProfile("p", profile); err == nil || !strings.Contains(err.Error(), "no-such-rule") {
+		t.Errorf("validateTestProfile() error = %v, want the unknown rule named", err)
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/poll"
+)
+
+// PollPolicy returns the status poll pacing of the settings, which must
+// have their defaults set
+func (c ConcurrencyConfig) PollPolicy() poll.Policy {
+	return poll.Policy{
+		Jitter:     float64(max(c.PollJitterPercent, 0)) / 100,
+		MaxBackoff: time.Duration(max(c.MaxPollBackoffSeconds, 0)) * time.Second,
+		MaxRate:    c.MaxPollRate,
+	}
+}
+
+// validatePolling checks the status poll settings
+func (c ConcurrencyConfig) validatePolling() error {
+	if c.PollJitterPercent > 100 {
+		return fmt.Errorf("concurrency.poll_jitter_percent must be at most 100")
+	}
+	if c.MaxPollRate < 0 {
+		return fmt.Errorf("concurrency.max_poll_rate cannot be negative")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"math"
+)
+
+// QuorumConfig sets how many of the run's nodes must be reachable and
+// healthy before it starts. With a quorum set, a run that has one leaves the
+// missing nodes out; without one, every node is required as before.
+type QuorumConfig struct {
+	MinNodes           int     `yaml:"min_nodes,omitempty"`
+	MinHealthyFraction float64 `yaml:"min_healthy_fraction,omitempty"` // Of the run's nodes, e.g. 0.9
+}
+
+// Enabled reports whether a quorum is set
+func (c QuorumConfig) Enabled() bool {
+	return c.MinNodes > 0 || c.MinHealthyFraction > 0
+}
+
+// Required returns the number of healthy nodes, out of total, the quorum
+// requires: the larger of min_nodes and min_healthy_fraction of total
+func (c QuorumConfig) Required(total int) int {
+	required := int(math.Ceil(c.MinHealthyFraction * float64(total)))
+	return max(required, c.MinNodes)
+}
+
+// validate checks the node count and fraction
+func (c *QuorumConfig) validate(nodes int) error {
+	if c.MinNodes < 0 {
+		return fmt.Errorf("quorum.min_nodes cannot be negative")
+	}
+	if c.MinNodes > nodes {
+		return fmt.Errorf("quorum.min_nodes (%d) exceeds the %d configured nodes", c.MinNodes, nodes)
+	}
+	if c.MinHealthyFraction < 0 || c.MinHealthyFraction > 1 {
+		return fmt.Errorf("quorum.min_healthy_fraction must be between 0 and 1")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactedValue replaces secrets in redacted configurations
+const RedactedValue = "REDACTED"
+
+// sensitiveFlagWords mark extra_flags whose values are credentials or point
+// at them, such as iperf3's --username and --rsa-public-key-path
+var sensitiveFlagWords = []string{"user", "password", "passwd", "token", "secret", "key", "credential"}
+
+// Redacted returns a copy of the configuration with secrets replaced by
+// RedactedValue, safe to store alongside results
+func (c *ControllerConfig) Redacted() *ControllerConfig {
+	redacted := *c
+
+	profiles := make(map[string]TestProfile, len(c.Controller.TestProfiles))
+	for name, profile := range c.Controller.TestProfiles {
+		if profile.ExtraFlags != nil {
+			flags := make(map[string]string, len(profile.ExtraFlags))
+			for flag, value := range profile.ExtraFlags {
+				if isSensitiveFlag(flag) && value != "" {
+					value = RedactedValue
+				}
+				flags[flag] = value
+			}
+			profile.ExtraFlags = flags
+		}
+		profiles[name] = profile
+	}
+	redacted.Controller.TestProfiles = profiles
+
+	return &redacted
+}
+
+// isSensitiveFlag reports whether an extra flag may carry a secret
+func isSensitiveFlag(flag string) bool {
+	flag = strings.ToLower(flag)
+	for _, word := range sensitiveFlagWords {
+		if strings.Contains(flag, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToMap returns the configuration as a map keyed by its YAML field names,
+// so it can be embedded in JSON output in the same form it is written
+func (c *ControllerConfig) ToMap() (map[string]interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode config: %w", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := &ControllerConfig{Controller: ControllerSettings{
+		TestProfiles: map[string]TestProfile{
+			"auth": {Duration: 10, Parallel: 1, ExtraFlags: map[string]string{
+				"username":              "alice",
+				"--rsa-public-key-path": "/etc/iperf/public.pem",
+				"authorized-users-path": "/etc/iperf/users.csv",
+				"api_token":             "s3cr3t-token",
+				"Password":              "hunter2",
+				"bind-dev":              "eth1",
+			}},
+			"plain": {Duration: 10, Parallel: 1},
+		},
+		Topology: TopologyConfig{Type: "full_mesh", DefaultProfile: "plain"},
+	}}
+
+	redacted := cfg.Redacted()
+	flags := redacted.Controller.TestProfiles["auth"].ExtraFlags
+	for flag, value := range flags {
+		want := RedactedValue
+		if flag == "bind-dev" {
+			want = "eth1"
+		}
+		if value != want {
+			t.Errorf("Redacted() extra_flags[%s] = %q, want %q", flag, value, want)
+		}
+	}
+	if got := cfg.Controller.TestProfiles["auth"].ExtraFlags["username"]; got != "alice" {
+		t.Errorf("Redacted() modified the original config: username = %q", got)
+	}
+
+	values, err := redacted.ToMap()
+	if err != nil {
+		t.Fatalf("ToMap() error = %v", err)
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, secret := range []string{"alice", "public.pem", "users.csv", "s3cr3t-token", "hunter2"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("embedded config contains %q: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), `"default_profile":"plain"`) {
+		t.Errorf("embedded config lacks YAML field names: %s", data)
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import "fmt"
+
+// ResourceSamplingConfig asks daemons to sample their CPU, memory and NIC
+// byte counters while the run's tests execute
+type ResourceSamplingConfig struct {
+	IntervalSeconds float64 `yaml:"interval_seconds,omitempty"` // 0 disables sampling
+	// CPUThresholdPercent flags nodes whose CPU was busier than this while
+	// their tests ran (default: 90)
+	CPUThresholdPercent float64 `yaml:"cpu_threshold_percent,omitempty"`
+}
+
+// DefaultCPUThresholdPercent is the CPU usage above which nodes are flagged
+const DefaultCPUThresholdPercent = 90
+
+// minSampleIntervalSeconds is the shortest interval daemons sample at
+const minSampleIntervalSeconds = 0.1
+
+// Enabled reports whether daemons are asked to sample
+func (c ResourceSamplingConfig) Enabled() bool {
+	return c.IntervalSeconds > 0
+}
+
+// validate checks the interval and threshold
+func (c *ResourceSamplingConfig) validate() error {
+	if c.IntervalSeconds < 0 || (c.IntervalSeconds > 0 && c.IntervalSeconds < minSampleIntervalSeconds) {
+		return fmt.Errorf("resource_sampling.interval_seconds must be 0 or at least %g", minSampleIntervalSeconds)
+	}
+	if c.CPUThresholdPercent < 0 || c.CPUThresholdPercent > 100 {
+		return fmt.Errorf("resource_sampling.cpu_threshold_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// setDefaults sets the CPU threshold
+func (c *ResourceSamplingConfig) setDefaults() {
+	if c.CPUThresholdPercent == 0 {
+		c.CPUThresholdPercent = DefaultCPUThresholdPercent
+	}
+}
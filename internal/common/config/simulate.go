@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/units"
+)
+
+// SimulateConfig describes the synthetic results of a daemon started with
+// --simulate, which runs no iperf3 processes
+type SimulateConfig struct {
+	ThroughputMean   string  `yaml:"throughput_mean,omitempty"`   // e.g. "9.4G"
+	ThroughputStddev string  `yaml:"throughput_stddev,omitempty"` // e.g. "200M"
+	FailureRate      float64 `yaml:"failure_rate,omitempty"`      // Share of tests that fail, 0-1
+	RTTMs            float64 `yaml:"rtt_ms,omitempty"`            // Mean RTT reported for TCP streams
+	StartLatencyMs   int     `yaml:"start_latency_ms,omitempty"`  // Delay before a test starts sending
+	Seed             int64   `yaml:"seed,omitempty"`              // Random seed; 0 seeds from the clock
+}
+
+// Defaults of the simulated result distribution
+const (
+	DefaultSimulateThroughputMean   = "9.4G"
+	DefaultSimulateThroughputStddev = "200M"
+	DefaultSimulateRTTMs            = 0.2
+	DefaultSimulateStartLatencyMs   = 50
+)
+
+// validate checks the rates and the failure rate
+func (c *SimulateConfig) validate() error {
+	rates := []struct{ name, value string }{
+		{"throughput_mean", c.ThroughputMean},
+		{"throughput_stddev", c.ThroughputStddev},
+	}
+	for _, rate := range rates {
+		if rate.value == "" {
+			continue
+		}
+		if _, err := units.ParseBitRate(rate.value); err != nil {
+			return fmt.Errorf("simulate.%s: %w", rate.name, err)
+		}
+	}
+	if c.FailureRate < 0 || c.FailureRate > 1 {
+		return fmt.Errorf("simulate.failure_rate must be between 0 and 1")
+	}
+	if c.RTTMs < 0 {
+		return fmt.Errorf("simulate.rtt_ms cannot be negative")
+	}
+	if c.StartLatencyMs < 0 {
+		return fmt.Errorf("simulate.start_latency_ms cannot be negative")
+	}
+	return nil
+}
+
+// setDefaults fills in a 10G-like link
+func (c *SimulateConfig) setDefaults() {
+	if c.ThroughputMean == "" {
+		c.ThroughputMean = DefaultSimulateThroughputMean
+	}
+	if c.ThroughputStddev == "" {
+		c.ThroughputStddev = DefaultSimulateThroughputStddev
+	}
+	if c.RTTMs == 0 {
+		c.RTTMs = DefaultSimulateRTTMs
+	}
+	if c.StartLatencyMs == 0 {
+		c.StartLatencyMs = DefaultSimulateStartLatencyMs
+	}
+}
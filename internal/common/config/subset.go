@@ -0,0 +1,185 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// NodeSubset restricts a run to some of the configured nodes. A node is kept
+// if it is listed in Nodes or its tags satisfy any of the tag expressions in
+// Tags, or if both are empty, and is not listed in Exclude.
+type NodeSubset struct {
+	Nodes   []string
+	Tags    []string
+	Exclude []string
+}
+
+// IsEmpty reports whether the subset keeps every node
+func (s NodeSubset) IsEmpty() bool {
+	return len(s.Nodes) == 0 && len(s.Tags) == 0 && len(s.Exclude) == 0
+}
+
+// String formats the subset as the run flags that select it
+func (s NodeSubset) String() string {
+	parts := make([]string, 0, 3)
+	if len(s.Nodes) > 0 {
+		parts = append(parts, "--nodes "+strings.Join(s.Nodes, ","))
+	}
+	if len(s.Tags) > 0 {
+		parts = append(parts, "--tag "+strings.Join(s.Tags, ","))
+	}
+	if len(s.Exclude) > 0 {
+		parts = append(parts, "--exclude-nodes "+strings.Join(s.Exclude, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// nodeID returns the ID a configured node is registered under
+func (n NodeConfig) nodeID() string {
+	if n.ID != "" {
+		return n.ID
+	}
+	return n.Hostname
+}
+
+// SelectNodes reduces the configuration to the nodes in subset. Overrides,
+// exclusions and group pairs that reference dropped nodes lose those nodes,
+// and are dropped when no pair is left; each change is returned as a
+// warning. At least two nodes must remain.
+func (c *ControllerConfig) SelectNodes(subset NodeSubset) ([]string, error) {
+	if subset.IsEmpty() {
+		return nil, nil
+	}
+
+	configured := make(map[string]bool, len(c.Controller.Nodes))
+	for _, node := range c.Controller.Nodes {
+		configured[node.nodeID()] = true
+	}
+	for _, id := range append(append([]string{}, subset.Nodes...), subset.Exclude...) {
+		if !configured[id] {
+			return nil, fmt.Errorf("unknown node %q", id)
+		}
+	}
+
+	tagExprs, err := c.ParseTagExprs(subset.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := toSet(subset.Nodes)
+	excluded := toSet(subset.Exclude)
+	kept := make([]NodeConfig, 0, len(c.Controller.Nodes))
+	keptIDs := make(map[string]bool)
+	for _, node := range c.Controller.Nodes {
+		id := node.nodeID()
+		selected := len(subset.Nodes) == 0 && len(subset.Tags) == 0
+		selected = selected || listed[id] || models.MatchesAnyTagExpr(tagExprs, node.Tags)
+		if selected && !excluded[id] {
+			kept = append(kept, node)
+			keptIDs[id] = true
+		}
+	}
+	if len(kept) < 2 {
+		return nil, fmt.Errorf("%s selects %d nodes; at least 2 are required", subset, len(kept))
+	}
+	c.Controller.Nodes = kept
+
+	warnings := make([]string, 0)
+	overrides := make([]TopologyOverride, 0, len(c.Controller.Topology.Overrides))
+	for i, override := range c.Controller.Topology.Overrides {
+		name := c.Controller.Topology.entry("override", i)
+		if restricted, ok := restrictOverride(name, override, kept, keptIDs, &warnings); ok {
+			overrides = append(overrides, restricted)
+		}
+	}
+	c.Controller.Topology.Overrides = overrides
+
+	exclusions := make([]TopologyOverride, 0, len(c.Controller.Topology.Exclusions))
+	for i, exclusion := range c.Controller.Topology.Exclusions {
+		name := c.Controller.Topology.entry("exclusion", i)
+		if restricted, ok := restrictOverride(name, exclusion, kept, keptIDs, &warnings); ok {
+			exclusions = append(exclusions, restricted)
+		}
+	}
+	c.Controller.Topology.Exclusions = exclusions
+
+	groups := make([]GroupConfig, 0, len(c.Controller.Topology.Groups))
+	droppedGroups := make(map[string]bool)
+	for _, group := range c.Controller.Topology.Groups {
+		pairs := make([]TopologyOverride, 0, len(group.Pairs))
+		for i, selector := range group.Pairs {
+			name := fmt.Sprintf("topology group %s pairs[%d]", group.Name, i)
+			if restricted, ok := restrictOverride(name, selector, kept, keptIDs, &warnings); ok {
+				pairs = append(pairs, restricted)
+			}
+		}
+		if len(pairs) == 0 {
+			warnings = append(warnings, fmt.Sprintf("topology group %s: dropped, no selected pairs remain", group.Name))
+			droppedGroups[group.Name] = true
+			continue
+		}
+		group.Pairs = pairs
+		groups = append(groups, group)
+	}
+	for i := range groups {
+		after := make([]string, 0, len(groups[i].After))
+		for _, dep := range groups[i].After {
+			if !droppedGroups[dep] {
+				after = append(after, dep)
+			}
+		}
+		groups[i].After = after
+	}
+	c.Controller.Topology.Groups = groups
+
+	return warnings, nil
+}
+
+// restrictOverride removes unselected nodes from an override, adding a
+// warning naming them; tag: entries are kept and select among the remaining
+// nodes. It returns false if no pair is left.
+func restrictOverride(name string, override TopologyOverride, nodes []NodeConfig, selected map[string]bool, warnings *[]string) (TopologyOverride, bool) {
+	dropped := make(map[string]bool)
+	keep := func(ids []string) []string {
+		kept := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if selected[id] || strings.HasPrefix(id, nodeTagPrefix) {
+				kept = append(kept, id)
+			} else {
+				dropped[id] = true
+			}
+		}
+		return kept
+	}
+	override.Nodes = keep(override.Nodes)
+	override.SourceNodes = keep(override.SourceNodes)
+	override.DestinationNodes = keep(override.DestinationNodes)
+	if len(dropped) == 0 {
+		return override, true
+	}
+
+	ids := make([]string, 0, len(dropped))
+	for id := range dropped {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	if len(override.Pairs(nodes)) == 0 {
+		*warnings = append(*warnings, fmt.Sprintf("%s: dropped, it only covers pairs with unselected nodes %s",
+			name, strings.Join(ids, ", ")))
+		return override, false
+	}
+	*warnings = append(*warnings, fmt.Sprintf("%s: ignoring unselected nodes %s", name, strings.Join(ids, ", ")))
+	return override, true
+}
+
+// toSet returns the values as a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
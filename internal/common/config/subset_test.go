@@ -0,0 +1,108 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func newSubsetConfig() *ControllerConfig {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "a1", IP: "10.0.0.1", Port: 50051, Tags: []string{"rack-a"}},
+		NodeConfig{Hostname: "a2", IP: "10.0.0.2", Port: 50051, Tags: []string{"rack-a"}},
+		NodeConfig{Hostname: "b1", IP: "10.0.1.1", Port: 50051, Tags: []string{"rack-b"}},
+		NodeConfig{Hostname: "b2", IP: "10.0.1.2", Port: 50051, Tags: []string{"rack-b"}},
+	)
+	cfg.Controller.Topology.Overrides = []TopologyOverride{
+		{Nodes: []string{"a1", "a2", "b1"}, Profile: "default"},
+		{SourceNodes: []string{"b1"}, DestinationNodes: []string{"b2"}, Profile: "default"},
+	}
+	cfg.Controller.Topology.Groups = []GroupConfig{
+		{Name: "cross", Pairs: []TopologyOverride{{SourceNodes: []string{"a1"}, DestinationNodes: []string{"b1"}}}},
+		{Name: "local", After: []string{"cross"}, Pairs: []TopologyOverride{{Nodes: []string{"a1", "a2"}}}},
+	}
+	return cfg
+}
+
+func nodeIDs(cfg *ControllerConfig) string {
+	ids := make([]string, 0, len(cfg.Controller.Nodes))
+	for _, node := range cfg.Controller.Nodes {
+		ids = append(ids, node.nodeID())
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestSelectNodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		subset   NodeSubset
+		want     string
+		warnings int
+	}{
+		{"empty", NodeSubset{}, "a1,a2,b1,b2", 0},
+		{"nodes", NodeSubset{Nodes: []string{"a1", "b1", "b2"}}, "a1,b1,b2", 3},
+		{"tag", NodeSubset{Tags: []string{"rack-a"}}, "a1,a2", 4},
+		{"tag and node", NodeSubset{Tags: []string{"rack-b"}, Nodes: []string{"a1"}}, "a1,b1,b2", 3},
+		{"tag expression", NodeSubset{Tags: []string{"not rack-b"}}, "a1,a2", 4},
+		{"exclude", NodeSubset{Exclude: []string{"b2"}}, "a1,a2,b1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newSubsetConfig()
+			warnings, err := cfg.SelectNodes(tt.subset)
+			if err != nil {
+				t.Fatalf("SelectNodes() error = %v", err)
+			}
+			if got := nodeIDs(cfg); got != tt.want {
+				t.Errorf("SelectNodes() kept %s, want %s", got, tt.want)
+			}
+			if len(warnings) != tt.warnings {
+				t.Errorf("SelectNodes() warnings = %q, want %d", warnings, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestSelectNodes_DropsOverridesAndGroups(t *testing.T) {
+	cfg := newSubsetConfig()
+	if _, err := cfg.SelectNodes(NodeSubset{Tags: []string{"rack-a"}}); err != nil {
+		t.Fatalf("SelectNodes() error = %v", err)
+	}
+
+	overrides := cfg.Controller.Topology.Overrides
+	if len(overrides) != 1 || strings.Join(overrides[0].Nodes, ",") != "a1,a2" {
+		t.Errorf("overrides = %+v, want only a1,a2 of the first", overrides)
+	}
+	groups := cfg.Controller.Topology.Groups
+	if len(groups) != 1 || groups[0].Name != "local" || len(groups[0].After) != 0 {
+		t.Errorf("groups = %+v, want local without its dependency on the dropped group", groups)
+	}
+
+	cfg = newSubsetConfig()
+	cfg.Controller.Topology.Exclusions = []TopologyOverride{
+		{SourceNodes: []string{"a1"}, DestinationNodes: []string{"a2", "b1"}},
+		{Nodes: []string{"b1", "b2"}},
+	}
+	if _, err := cfg.SelectNodes(NodeSubset{Tags: []string{"rack-a"}}); err != nil {
+		t.Fatalf("SelectNodes() error = %v", err)
+	}
+	exclusions := cfg.Controller.Topology.Exclusions
+	if len(exclusions) != 1 || strings.Join(exclusions[0].DestinationNodes, ",") != "a2" {
+		t.Errorf("exclusions = %+v, want only a1 to a2 of the first", exclusions)
+	}
+}
+
+func TestSelectNodes_Invalid(t *testing.T) {
+	for _, subset := range []NodeSubset{
+		{Nodes: []string{"a1", "c1"}},
+		{Exclude: []string{"c1"}},
+		{Nodes: []string{"a1"}},
+		{Tags: []string{"rack-c"}},
+		{Tags: []string{"rack-a and"}},
+		{Tags: []string{"rack-a"}, Exclude: []string{"a2"}},
+	} {
+		if _, err := newSubsetConfig().SelectNodes(subset); err == nil {
+			t.Errorf("SelectNodes(%s) expected error", subset)
+		}
+	}
+}
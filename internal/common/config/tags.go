@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// TagPolicyConfig controls how node tags are normalized when loaded. Tags
+// are always trimmed of surrounding space.
+type TagPolicyConfig struct {
+	// Lowercase lowercases tags, and the tags of --tag expressions and output
+	// filters, so that "Zone:us-east" and "zone:us-east" are the same tag
+	Lowercase bool `yaml:"lowercase"`
+}
+
+// normalizeTags normalizes every node's tags according to the tag policy
+// and checks their syntax, rejecting tags a node lists twice
+func (c *ControllerConfig) normalizeTags() error {
+	lowercase := c.Controller.TagPolicy.Lowercase
+	for i := range c.Controller.Nodes {
+		node := &c.Controller.Nodes[i]
+		seen := make(map[string]bool, len(node.Tags))
+		for j, tag := range node.Tags {
+			tag = models.NormalizeTag(tag, lowercase)
+			if err := models.ValidateTag(tag); err != nil {
+				return fmt.Errorf("node %s: tag %q: %w", node.nodeID(), tag, err)
+			}
+			if seen[tag] {
+				return fmt.Errorf("node %s: duplicate tag %q", node.nodeID(), tag)
+			}
+			seen[tag] = true
+			node.Tags[j] = tag
+		}
+	}
+	return nil
+}
+
+// ParseTagExprs parses tag expressions, such as those of --tag, normalizing
+// their tags according to the tag policy
+func (c *ControllerConfig) ParseTagExprs(texts []string) ([]*models.TagExpr, error) {
+	exprs := make([]*models.TagExpr, 0, len(texts))
+	for _, text := range texts {
+		expr, err := models.ParseTagExpr(text, c.Controller.TagPolicy.Lowercase)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	return exprs, nil
+}
+
+// TagUsage is a distinct tag and the nodes carrying it
+type TagUsage struct {
+	Tag   string
+	Nodes []string
+	// Similar lists other tags that differ from this one only in case
+	Similar []string
+}
+
+// TagUsage returns every distinct node tag, sorted, with the nodes
+// carrying it
+func (c *ControllerConfig) TagUsage() []*TagUsage {
+	byTag := make(map[string]*TagUsage)
+	byFolded := make(map[string][]string)
+	for _, node := range c.Controller.Nodes {
+		for _, tag := range node.Tags {
+			usage, exists := byTag[tag]
+			if !exists {
+				usage = &TagUsage{Tag: tag}
+				byTag[tag] = usage
+				folded := strings.ToLower(tag)
+				byFolded[folded] = append(byFolded[folded], tag)
+			}
+			usage.Nodes = append(usage.Nodes, node.nodeID())
+		}
+	}
+
+	tags := make([]*TagUsage, 0, len(byTag))
+	for tag, usage := range byTag {
+		for _, other := range byFolded[strings.ToLower(tag)] {
+			if other != tag {
+				usage.Similar = append(usage.Similar, other)
+			}
+		}
+		sort.Strings(usage.Similar)
+		tags = append(tags, usage)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags
+}
+
+// tagWarnings reports tags that differ only in case, which are likely the
+// same tag spelled two ways
+func (c *ControllerConfig) tagWarnings() []string {
+	warnings := make([]string, 0)
+	reported := make(map[string]bool)
+	for _, usage := range c.TagUsage() {
+		folded := strings.ToLower(usage.Tag)
+		if len(usage.Similar) == 0 || reported[folded] {
+			continue
+		}
+		reported[folded] = true
+		variants := append([]string{usage.Tag}, usage.Similar...)
+		sort.Strings(variants)
+		warnings = append(warnings, fmt.Sprintf(
+			"tags %s differ only in case; set tag_policy.lowercase to treat them as one tag",
+			strings.Join(variants, ", ")))
+	}
+	return warnings
+}
+
+// normalizeTagList normalizes tags that select nodes according to the tag
+// policy, rejecting those no node carries
+func (c *ControllerConfig) normalizeTagList(field string, tags []string) error {
+	for i, tag := range tags {
+		tag = models.NormalizeTag(tag, c.Controller.TagPolicy.Lowercase)
+		if len(taggedNodeIDs(c.Controller.Nodes, []string{tag})) == 0 {
+			return fmt.Errorf("%s: no node is tagged %q", field, tag)
+		}
+		tags[i] = tag
+	}
+	return nil
+}
+
+// normalizeSelectorTags normalizes the tags an override or group pairs
+// entry selects nodes by, in its tag lists and as tag: entries of its node
+// lists, and checks that the other node list entries are node IDs
+func (c *ControllerConfig) normalizeSelectorTags(selector *TopologyOverride) error {
+	if err := c.normalizeTagList("source_tags", selector.SourceTags); err != nil {
+		return err
+	}
+	if err := c.normalizeTagList("destination_tags", selector.DestinationTags); err != nil {
+		return err
+	}
+	for _, list := range []struct {
+		field string
+		ids   []string
+	}{
+		{"nodes", selector.Nodes},
+		{"source_nodes", selector.SourceNodes},
+		{"destination_nodes", selector.DestinationNodes},
+	} {
+		if err := c.normalizeNodeList(list.field, list.ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nodeTagPrefix marks an entry of an override's node lists that selects the
+// nodes with a tag, such as "tag:edge"
+const nodeTagPrefix = "tag:"
+
+// normalizeNodeList checks that the entries of a node list are node IDs,
+// normalizing the tags of its tag: entries like normalizeTagList does
+func (c *ControllerConfig) normalizeNodeList(field string, ids []string) error {
+	for i, id := range ids {
+		if tag, ok := strings.CutPrefix(id, nodeTagPrefix); ok {
+			tags := []string{tag}
+			if err := c.normalizeTagList(field, tags); err != nil {
+				return err
+			}
+			ids[i] = nodeTagPrefix + tags[0]
+			continue
+		}
+		if !c.hasNode(id) {
+			return fmt.Errorf("%s: unknown node %q", field, id)
+		}
+	}
+	return nil
+}
+
+// hasNode reports whether id is the ID of a configured node
+func (c *ControllerConfig) hasNode(id string) bool {
+	for _, node := range c.Controller.Nodes {
+		if node.nodeID() == id {
+			return true
+		}
+	}
+	return false
+}
+
+// expandNodeList returns the node IDs of a node list, replacing each tag:
+// entry with the IDs of the nodes carrying the tag, in node order
+func expandNodeList(nodes []NodeConfig, ids []string) []string {
+	expanded := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if tag, ok := strings.CutPrefix(id, nodeTagPrefix); ok {
+			expanded = append(expanded, taggedNodeIDs(nodes, []string{tag})...)
+			continue
+		}
+		expanded = append(expanded, id)
+	}
+	return expanded
+}
+
+// taggedNodeIDs returns the IDs of the nodes with any of the tags, in node
+// order
+func taggedNodeIDs(nodes []NodeConfig, tags []string) []string {
+	ids := make([]string, 0)
+	for _, node := range nodes {
+		for _, tag := range tags {
+			if contains(node.Tags, tag) {
+				ids = append(ids, node.nodeID())
+				break
+			}
+		}
+	}
+	return ids
+}
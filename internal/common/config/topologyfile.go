@@ -0,0 +1,126 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// topologySource records which entries of a topology were read from its
+// topology file, so that errors about them can name it
+type topologySource struct {
+	file string // Path of the topology file, resolved
+	// first maps each list, by the name its entries are reported under, to
+	// the index of its first entry from the file
+	first  map[string]int
+	groups map[string]bool // Names of the groups from the file
+}
+
+// loadTopologyFile merges the topology file the configuration at
+// configPath refers to, if any, into its topology. A relative path is
+// resolved against the directory of configPath. The file holds topology
+// settings: its lists are appended to the configuration's, and its other
+// settings fill those the configuration leaves unset, or must match them.
+// File is cleared, so that the merged configuration stands on its own.
+func (c *ControllerConfig) loadTopologyFile(configPath string) error {
+	topo := &c.Controller.Topology
+	if topo.File == "" {
+		return nil
+	}
+	path := topo.File
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Topology file path is provided by the config
+	if err != nil {
+		return fmt.Errorf("failed to read topology file: %w", err)
+	}
+	var file TopologyConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse topology file %s: %w", path, err)
+	}
+	if file.File != "" {
+		return fmt.Errorf("topology file %s: cannot refer to another topology file", path)
+	}
+
+	topo.File = ""
+	topo.from = &topologySource{
+		file: path,
+		first: map[string]int{
+			"pairs":     len(topo.Pairs),
+			"override":  len(topo.Overrides),
+			"exclusion": len(topo.Exclusions),
+		},
+		groups: make(map[string]bool, len(file.Groups)),
+	}
+	for _, group := range file.Groups {
+		topo.from.groups[group.Name] = true
+	}
+	return mergeTopology(topo, &file, configPath, path)
+}
+
+// mergeTopology merges the settings of file into topo, appending lists and
+// setting the other fields topo leaves unset. A field both set differently
+// is an error naming both files.
+func mergeTopology(topo, file *TopologyConfig, configPath, filePath string) error {
+	into := reflect.ValueOf(topo).Elem()
+	from := reflect.ValueOf(file).Elem()
+	for i := 0; i < into.NumField(); i++ {
+		field := into.Type().Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+		dst, src := into.Field(i), from.Field(i)
+		switch {
+		case src.IsZero():
+		case field.Type.Kind() == reflect.Slice:
+			dst.Set(reflect.AppendSlice(dst, src))
+		case dst.IsZero():
+			dst.Set(src)
+		case !reflect.DeepEqual(dst.Interface(), src.Interface()):
+			return fmt.Errorf("topology %s is set both in %s and in topology file %s", name, configPath, filePath)
+		}
+	}
+	return nil
+}
+
+// SourceFile returns the path of the topology file merged into the
+// topology, or "" if there is none
+func (t *TopologyConfig) SourceFile() string {
+	if t.from == nil {
+		return ""
+	}
+	return t.from.file
+}
+
+// entry names the index'th entry of a topology list in errors, such as
+// "topology pairs[2]". Entries from the topology file are named by their
+// index in it, and the file.
+func (t *TopologyConfig) entry(list string, index int) string {
+	if t.from != nil {
+		if first, exists := t.from.first[list]; exists && index >= first {
+			return fmt.Sprintf("topology %s[%d] in %s", list, index-first, t.from.file)
+		}
+	}
+	return fmt.Sprintf("topology %s[%d]", list, index)
+}
+
+// group names a topology group in errors, with the topology file it is
+// from, if any
+func (t *TopologyConfig) group(name string) string {
+	if t.from != nil && t.from.groups[name] {
+		return fmt.Sprintf("topology group %s in %s", name, t.from.file)
+	}
+	return "topology group " + name
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadControllerConfig_TopologyFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	controller := func(topology string) string {
+		return write("controller.yaml", `controller:
+  nodes:
+    - {hostname: host-a, ip: 10.0.0.1, port: 50051}
+    - {hostname: host-b, ip: 10.0.0.2, port: 50051}
+    - {hostname: host-c, ip: 10.0.0.3, port: 50051}
+  test_profiles:
+    default: {duration: 10, parallel: 1}
+  topology:
+`+topology+`
+  output:
+    json_file: results.json
+`)
+	}
+
+	plan := write("plans/plan.yaml", `type: custom
+pairs:
+  - {source: host-a, destination: host-b}
+  - {source: host-b, destination: host-c, repeat: 2}
+`)
+	path := controller(`    default_profile: default
+    file: plans/plan.yaml
+    pairs:
+      - {source: host-c, destination: host-a}`)
+	cfg, err := LoadControllerConfig(path)
+	if err != nil {
+		t.Fatalf("LoadControllerConfig() error = %v", err)
+	}
+	topo := cfg.Controller.Topology
+	if topo.Type != "custom" || len(topo.Pairs) != 3 || topo.Pairs[2].Repeat != 2 {
+		t.Errorf("merged topology = %+v, want the file's type and its pairs after the config's", topo)
+	}
+	if topo.File != "" || topo.SourceFile() != plan {
+		t.Errorf("File = %q, SourceFile() = %q; want the file merged from %s", topo.File, topo.SourceFile(), plan)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{"entry from the file", "type: custom\npairs:\n  - {source: host-a, destination: host-z}\n",
+			`topology pairs[0] in ` + plan + `: unknown node "host-z"`},
+		{"conflicting setting", "type: full_mesh\n", "topology type is set both in"},
+		{"unknown field", "type: custom\npiars: []\n", "field piars not found"},
+		{"nested file", "type: custom\nfile: other.yaml\n", "cannot refer to another topology file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write("plans/plan.yaml", tt.file)
+			_, err := LoadControllerConfig(controller(`    type: custom
+    default_profile: default
+    file: plans/plan.yaml
+    pairs:
+      - {source: host-c, destination: host-a}`))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadControllerConfig() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Entries of the configuration itself keep their index
+	write("plans/plan.yaml", "pairs:\n  - {source: host-a, destination: host-b}\n")
+	_, err = LoadControllerConfig(controller(`    type: custom
+    default_profile: default
+    file: plans/plan.yaml
+    pairs:
+      - {source: host-c, destination: host-c}`))
+	if err == nil || !strings.Contains(err.Error(), "topology pairs[0]: source and destination must differ") {
+		t.Errorf("LoadControllerConfig() error = %v, want the config's pairs[0]", err)
+	}
+}
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WorkloadConfig is a named preset of topology, profile and analysis
+// settings, selected with run --workload. Each setting only applies where
+// the configuration leaves the field unset.
+type WorkloadConfig struct {
+	Description string `yaml:"description,omitempty"`
+	Topology    string `yaml:"topology,omitempty"` // Topology type
+	Profile     string `yaml:"profile,omitempty"`  // Default profile
+	// Analysis settings, as under analysis
+	DiscrepancyThresholdPercent float64 `yaml:"discrepancy_threshold_percent,omitempty"`
+	RetransmitThresholdPerMB    float64 `yaml:"retransmit_threshold_per_mb,omitempty"`
+	MinSuccessRatio             float64 `yaml:"min_success_ratio,omitempty"`
+	MTUHeuristics               bool    `yaml:"mtu_heuristics,omitempty"`
+
+	// testProfile is the profile a built-in workload ships, added to the
+	// test profiles as Profile unless one of that name is configured
+	testProfile *TestProfile
+}
+
+// builtinWorkloads are the workloads available without configuring any
+var builtinWorkloads = map[string]WorkloadConfig{
+	"fabric-acceptance": {
+		Description:              "Every pair at line rate with 8 streams, failing on any failed pair and flagging retransmits and MTU problems",
+		Topology:                 "full_mesh",
+		Profile:                  "fabric-acceptance",
+		RetransmitThresholdPerMB: 1,
+		MinSuccessRatio:          1,
+		MTUHeuristics:            true,
+		testProfile:              &TestProfile{Duration: 30, Protocol: "tcp", Parallel: 8, OmitSeconds: 2},
+	},
+	"incast-stress": {
+		Description:                 "Every node sending UDP bursts to one destination at once, flagging pairs losing more than 2%; set topology.destination",
+		Topology:                    "many_to_one",
+		Profile:                     "incast-stress",
+		DiscrepancyThresholdPercent: 2,
+		MinSuccessRatio:             0.9,
+		testProfile: &TestProfile{Protocol: "udp", Bandwidth: "1G", Parallel: 1,
+			Burst: &BurstConfig{OnMs: 50, OffMs: 450, Repeat: 20}},
+	},
+	"quick-check": {
+		Description:     "Each node against its ring neighbor for 5 seconds, failing on any failed pair",
+		Topology:        "ring",
+		Profile:         "quick-check",
+		MinSuccessRatio: 1,
+		testProfile:     &TestProfile{Duration: 5, Protocol: "tcp", Parallel: 1},
+	},
+}
+
+// WorkloadSetting is one setting of a workload, by its path below
+// controller, and whether it applied or the configuration sets the field
+type WorkloadSetting struct {
+	Field   string
+	Value   string
+	Applied bool
+}
+
+// WorkloadExpansion is what selecting a workload did to a configuration
+type WorkloadExpansion struct {
+	Name        string
+	Description string
+	BuiltIn     bool
+	Settings    []WorkloadSetting
+}
+
+// Applied returns the settings the workload applied, by field
+func (e *WorkloadExpansion) Applied() map[string]string {
+	applied := make(map[string]string)
+	for _, setting := range e.Settings {
+		if setting.Applied {
+			applied[setting.Field] = setting.Value
+		}
+	}
+	return applied
+}
+
+// Kept returns the fields of the workload the configuration sets itself
+func (e *WorkloadExpansion) Kept() []string {
+	kept := make([]string, 0)
+	for _, setting := range e.Settings {
+		if !setting.Applied {
+			kept = append(kept, setting.Field)
+		}
+	}
+	return kept
+}
+
+// Workload returns the workload of the given name: one defined in the
+// configuration or, failing that, a built-in one
+func (c *ControllerConfig) Workload(name string) (WorkloadConfig, bool, error) {
+	if workload, exists := c.Controller.Workloads[name]; exists {
+		return workload, false, nil
+	}
+	if workload, exists := builtinWorkloads[name]; exists {
+		return workload, true, nil
+	}
+	return WorkloadConfig{}, false, fmt.Errorf("unknown workload %q (available: %s)",
+		name, strings.Join(c.WorkloadNames(), ", "))
+}
+
+// WorkloadNames returns the names of the configured and built-in
+// workloads, sorted
+func (c *ControllerConfig) WorkloadNames() []string {
+	names := make([]string, 0, len(builtinWorkloads)+len(c.Controller.Workloads))
+	for name := range builtinWorkloads {
+		names = append(names, name)
+	}
+	for name := range c.Controller.Workloads {
+		if _, builtin := builtinWorkloads[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyWorkload fills the fields the configuration leaves unset with the
+// settings of the named workload, returning every setting and whether it
+// applied. It must be called before SetDefaults, which fills unset fields
+// with defaults that would hide them.
+func (c *ControllerConfig) ApplyWorkload(name string) (*WorkloadExpansion, error) {
+	workload, builtIn, err := c.Workload(name)
+	if err != nil {
+		return nil, err
+	}
+	expansion := &WorkloadExpansion{Name: name, Description: workload.Description, BuiltIn: builtIn}
+	set := func(field, value string, unset bool, apply func()) {
+		if unset {
+			apply()
+		}
+		expansion.Settings = append(expansion.Settings, WorkloadSetting{Field: field, Value: value, Applied: unset})
+	}
+	number := func(value float64) string { return strconv.FormatFloat(value, 'g', -1, 64) }
+
+	topo := &c.Controller.Topology
+	if workload.Topology != "" {
+		set("topology.type", workload.Topology, topo.Type == "", func() { topo.Type = workload.Topology })
+	}
+	if workload.testProfile != nil {
+		_, configured := c.Controller.TestProfiles[workload.Profile]
+		set("test_profiles."+workload.Profile, workload.testProfile.summary(), !configured, func() {
+			if c.Controller.TestProfiles == nil {
+				c.Controller.TestProfiles = make(map[string]TestProfile)
+			}
+			c.Controller.TestProfiles[workload.Profile] = *workload.testProfile
+		})
+	}
+	if workload.Profile != "" {
+		set("topology.default_profile", workload.Profile, topo.DefaultProfile == "",
+			func() { topo.DefaultProfile = workload.Profile })
+	}
+
+	analysis := &c.Controller.Analysis
+	if workload.DiscrepancyThresholdPercent != 0 {
+		set("analysis.discrepancy_threshold_percent", number(workload.DiscrepancyThresholdPercent),
+			analysis.DiscrepancyThresholdPercent == nil,
+			func() {
+				threshold := workload.DiscrepancyThresholdPercent
+				analysis.DiscrepancyThresholdPercent = &threshold
+			})
+	}
+	if workload.RetransmitThresholdPerMB != 0 {
+		set("analysis.retransmit_threshold_per_mb", number(workload.RetransmitThresholdPerMB),
+			analysis.RetransmitThresholdPerMB == 0,
+			func() { analysis.RetransmitThresholdPerMB = workload.RetransmitThresholdPerMB })
+	}
+	if workload.MinSuccessRatio != 0 {
+		set("analysis.min_success_ratio", number(workload.MinSuccessRatio), analysis.MinSuccessRatio == 0,
+			func() { analysis.MinSuccessRatio = workload.MinSuccessRatio })
+	}
+	if workload.MTUHeuristics {
+		set("analysis.mtu_heuristics", "true", !analysis.MTUHeuristics, func() { analysis.MTUHeuristics = true })
+	}
+	return expansion, nil
+}
+
+// summary describes a workload's profile in one line, as iperf3 settings
+func (p *TestProfile) summary() string {
+	parts := []string{p.Protocol}
+	if p.Duration > 0 {
+		parts[0] += fmt.Sprintf(" %gs", p.Duration)
+	}
+	parts = append(parts, fmt.Sprintf("parallel %d", p.Parallel))
+	if p.Bandwidth != "" {
+		parts = append(parts, "bandwidth "+p.Bandwidth)
+	}
+	if p.OmitSeconds > 0 {
+		parts = append(parts, fmt.Sprintf("omit %ds", p.OmitSeconds))
+	}
+	if p.Burst != nil {
+		parts = append(parts, fmt.Sprintf("burst %dx%dms/%dms", p.Burst.Repeat, p.Burst.OnMs, p.Burst.OffMs))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validateWorkloads checks the workloads defined in the configuration
+func (c *ControllerConfig) validateWorkloads() error {
+	for name, workload := range c.Controller.Workloads {
+		if workload.Topology != "" {
+			if err := checkValue("topology type", "controller.topology.type", workload.Topology); err != nil {
+				return fmt.Errorf("workload '%s': %w", name, err)
+			}
+		}
+		if workload.Profile != "" {
+			if _, exists := c.Controller.TestProfiles[workload.Profile]; !exists {
+				return fmt.Errorf("workload '%s': profile '%s' not found in test_profiles", name, workload.Profile)
+			}
+		}
+		if workload.DiscrepancyThresholdPercent < 0 || workload.RetransmitThresholdPerMB < 0 {
+			return fmt.Errorf("workload '%s': thresholds cannot be negative", name)
+		}
+		if workload.MinSuccessRatio < 0 || workload.MinSuccessRatio > 1 {
+			return fmt.Errorf("workload '%s': min_success_ratio must be between 0 and 1", name)
+		}
+	}
+	return nil
+}
+
+// LoadWorkloads loads the workloads defined in a controller configuration
+// file, checking only them, so that the workloads of a configuration that
+// needs one to be valid can be listed
+func LoadWorkloads(path string) (*ControllerConfig, error) {
+	config, err := readControllerConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.validateWorkloads(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyWorkload_BuiltIn(t *testing.T) {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+		NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+	)
+	cfg.Controller.Topology = TopologyConfig{}
+	cfg.Controller.Analysis.MinSuccessRatio = 0.5
+
+	expansion, err := cfg.ApplyWorkload("fabric-acceptance")
+	if err != nil {
+		t.Fatalf("ApplyWorkload() error = %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if !expansion.BuiltIn {
+		t.Error("BuiltIn = false, want true")
+	}
+	if cfg.Controller.Topology.Type != "full_mesh" || cfg.Controller.Topology.DefaultProfile != "fabric-acceptance" {
+		t.Errorf("topology = %+v, want full_mesh with the fabric-acceptance profile", cfg.Controller.Topology)
+	}
+	if profile := cfg.Controller.TestProfiles["fabric-acceptance"]; profile.Parallel != 8 || profile.Duration != 30 {
+		t.Errorf("fabric-acceptance profile = %+v, want 30s with 8 streams", profile)
+	}
+	if !cfg.Controller.Analysis.MTUHeuristics || cfg.Controller.Analysis.RetransmitThresholdPerMB != 1 {
+		t.Errorf("analysis = %+v, want MTU heuristics and a retransmit threshold", cfg.Controller.Analysis)
+	}
+	// The configured ratio wins over the workload's
+	if cfg.Controller.Analysis.MinSuccessRatio != 0.5 {
+		t.Errorf("min_success_ratio = %v, want the configured 0.5", cfg.Controller.Analysis.MinSuccessRatio)
+	}
+	if kept := expansion.Kept(); !reflect.DeepEqual(kept, []string{"analysis.min_success_ratio"}) {
+		t.Errorf("Kept() = %v, want analysis.min_success_ratio", kept)
+	}
+	if applied := expansion.Applied(); applied["topology.type"] != "full_mesh" || applied["analysis.min_success_ratio"] != "" {
+		t.Errorf("Applied() = %v", applied)
+	}
+}
+
+func TestApplyWorkload_ConfiguredOverridesBuiltIn(t *testing.T) {
+	cfg := newNodesConfig(
+		NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+		NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+	)
+	cfg.Controller.Topology.Type = ""
+	cfg.Controller.Workloads = map[string]WorkloadConfig{
+		"quick-check": {Topology: "star", Profile: "default"},
+	}
+
+	expansion, err := cfg.ApplyWorkload("quick-check")
+	if err != nil {
+		t.Fatalf("ApplyWorkload() error = %v", err)
+	}
+	if expansion.BuiltIn {
+		t.Error("BuiltIn = true, want the configured workload")
+	}
+	if cfg.Controller.Topology.Type != "star" {
+		t.Errorf("topology type = %q, want star", cfg.Controller.Topology.Type)
+	}
+	if _, added := cfg.Controller.TestProfiles["quick-check"]; added {
+		t.Error("configured workload added the built-in profile")
+	}
+	// default_profile was already set, so the workload's is kept out
+	if kept := expansion.Kept(); !reflect.DeepEqual(kept, []string{"topology.default_profile"}) {
+		t.Errorf("Kept() = %v, want topology.default_profile", kept)
+	}
+}
+
+func TestApplyWorkload_Unknown(t *testing.T) {
+	cfg := newNodesConfig()
+	cfg.Controller.Workloads = map[string]WorkloadConfig{"nightly": {}}
+
+	_, err := cfg.ApplyWorkload("nope")
+	if err == nil || !strings.Contains(err.Error(), "fabric-acceptance, incast-stress, nightly, quick-check") {
+		t.Errorf("ApplyWorkload() error = %v, want the available workloads", err)
+	}
+}
+
+func TestValidate_Workloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		workload WorkloadConfig
+		wantErr  string
+	}{
+		{name: "valid", workload: WorkloadConfig{Topology: "ring", Profile: "default", MinSuccessRatio: 1}},
+		{name: "unknown topology", workload: WorkloadConfig{Topology: "tree"}, wantErr: "workload 'w'"},
+		{name: "unknown profile", workload: WorkloadConfig{Profile: "missing"}, wantErr: "profile 'missing' not found"},
+		{name: "negative threshold", workload: WorkloadConfig{RetransmitThresholdPerMB: -1}, wantErr: "cannot be negative"},
+		{name: "ratio above one", workload: WorkloadConfig{MinSuccessRatio: 2}, wantErr: "between 0 and 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newNodesConfig(
+				NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+				NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+			)
+			cfg.Controller.Workloads = map[string]WorkloadConfig{"w": tt.workload}
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadControllerConfigWorkload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "controller.yaml")
+	data := `controller:
+  nodes:
+    - {hostname: host-a, ip: 10.0.0.1, port: 50051}
+    - {hostname: host-b, ip: 10.0.0.2, port: 50051}
+  test_profiles:
+    default: {duration: 10, parallel: 1}
+  output:
+    json_file: results.json
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadControllerConfig(path); err == nil {
+		t.Error("LoadControllerConfig() without a topology type expected error")
+	}
+	cfg, expansion, err := LoadControllerConfigWorkload(path, "quick-check")
+	if err != nil {
+		t.Fatalf("LoadControllerConfigWorkload() error = %v", err)
+	}
+	if cfg.Controller.Topology.Type != "ring" || expansion.Name != "quick-check" {
+		t.Errorf("topology type = %q, expansion = %+v, want quick-check's ring", cfg.Controller.Topology.Type, expansion)
+	}
+}
+
+func TestBuiltinWorkloads_Valid(t *testing.T) {
+	for name := range builtinWorkloads {
+		cfg := newNodesConfig(
+			NodeConfig{Hostname: "host-a", IP: "10.0.0.1", Port: 50051},
+			NodeConfig{Hostname: "host-b", IP: "10.0.0.2", Port: 50051},
+		)
+		cfg.Controller.Topology = TopologyConfig{}
+		if _, err := cfg.ApplyWorkload(name); err != nil {
+			t.Fatalf("ApplyWorkload(%q) error = %v", name, err)
+		}
+		if cfg.Controller.Topology.Type == "many_to_one" {
+			// incast-stress leaves the destination to the configuration
+			cfg.Controller.Topology.Destination = "host-a"
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("workload %s: Validate() error = %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+// Package debughttp serves opt-in diagnostics over HTTP: net/http/pprof
+// profiles, expvar counters and a JSON dump of the caller's current state.
+package debughttp
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultAddress is the address the listener binds to unless configured
+// otherwise, so profiles are not exposed beyond the host by default
+const DefaultAddress = "127.0.0.1"
+
+var (
+	varsMu sync.RWMutex
+	vars   = make(map[string]func() interface{})
+)
+
+func init() {
+	Publish("goroutines", func() interface{} { return runtime.NumGoroutine() })
+}
+
+// Publish exposes the value returned by fn under name in /debug/vars.
+// Publishing a name again replaces its function.
+func Publish(name string, fn func() interface{}) {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+
+	if _, exists := vars[name]; !exists {
+		// expvar panics on duplicate names, so each name is registered once
+		// and looks up its current function on every read
+		expvar.Publish(name, expvar.Func(func() interface{} {
+			varsMu.RLock()
+			current := vars[name]
+			varsMu.RUnlock()
+			return current()
+		}))
+	}
+	vars[name] = fn
+}
+
+// Server is a running debug listener
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+
+	mu    sync.RWMutex
+	state func() interface{}
+}
+
+// Start listens on address:port and serves /debug/pprof/, /debug/vars and
+// /debug/state until Close is called
+func Start(address string, port int) (*Server, error) {
+	if address == "" {
+		address = DefaultAddress
+	}
+	addr := net.JoinHostPort(address, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := &Server{listener: listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("/debug/state", s.serveState)
+
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Warning: debug listener stopped: %v", err)
+		}
+	}()
+
+	return s, nil
+}
+
+// Addr returns the address the listener is bound to
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// SetState sets the function whose result /debug/state returns as JSON
+func (s *Server) SetState(fn func() interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = fn
+}
+
+// Close stops the listener
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// serveState writes the current state as JSON
+func (s *Server) serveState(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	state := s.state
+	s.mu.RUnlock()
+
+	if state == nil {
+		http.Error(w, "no state available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(state()); err != nil {
+		log.Printf("Warning: failed to encode debug state: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package debughttp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func get(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url) // #nosec G107 -- Test URL on a local listener
+	if err != nil {
+		t.Fatalf("GET %s error = %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading %s error = %v", url, err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestServer(t *testing.T) {
+	server, err := Start("", 0)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Close()
+
+	base := "http://" + server.Addr()
+
+	if code, _ := get(t, base+"/debug/state"); code != http.StatusNotFound {
+		t.Errorf("/debug/state without state = %d, want %d", code, http.StatusNotFound)
+	}
+
+	server.SetState(func() interface{} { return map[string]string{"phase": "running"} })
+	code, body := get(t, base+"/debug/state")
+	if code != http.StatusOK {
+		t.Fatalf("/debug/state = %d, want %d", code, http.StatusOK)
+	}
+	var state map[string]string
+	if err := json.Unmarshal(body, &state); err != nil || state["phase"] != "running" {
+		t.Errorf("/debug/state = %s, want phase running", body)
+	}
+
+	// Republishing a name replaces its value rather than panicking
+	Publish("test_counter", func() interface{} { return 1 })
+	Publish("test_counter", func() interface{} { return 2 })
+	_, body = get(t, base+"/debug/vars")
+	var vars map[string]interface{}
+	if err := json.Unmarshal(body, &vars); err != nil {
+		t.Fatalf("/debug/vars is not JSON: %v", err)
+	}
+	if vars["test_counter"] != float64(2) {
+		t.Errorf("test_counter = %v, want 2", vars["test_counter"])
+	}
+	if _, exists := vars["goroutines"]; !exists {
+		t.Error("/debug/vars missing goroutines")
+	}
+
+	if code, _ := get(t, base+"/debug/pprof/"); code != http.StatusOK {
+		t.Errorf("/debug/pprof/ = %d, want %d", code, http.StatusOK)
+	}
+}
@@ -0,0 +1,219 @@
+package iperf
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ThroughputSource names the part of iperf3's end section a throughput was
+// read from. Each side's rate is looked up in order:
+//
+//  1. end.sum_sent or end.sum_received, the totals iperf3 reports for each
+//     role; for --bidir tests the reverse direction's are in
+//     end.sum_sent_bidir_reverse and end.sum_received_bidir_reverse
+//  2. end.sum, the only totals of UDP tests before iperf 3.10, and
+//     end.sum_bidir_reverse for --bidir. It is the client's view: the
+//     sender's, or the receiver's with -R and in the reverse direction.
+//  3. end.streams, summed over the streams of the direction, for output
+//     that omits the totals, such as some single-stream tests
+//
+// Only the first is exact; the others are lower-confidence extractions.
+type ThroughputSource string
+
+const (
+	SourceSumSent     ThroughputSource = "sum_sent"
+	SourceSumReceived ThroughputSource = "sum_received"
+	SourceSum         ThroughputSource = "sum"
+	SourceStreams     ThroughputSource = "streams"
+)
+
+// Exact reports whether the throughput was read from the totals iperf3
+// reports for its role
+func (s ThroughputSource) Exact() bool {
+	return s == SourceSumSent || s == SourceSumReceived
+}
+
+// Throughput is the rate of one direction of a test, as seen by its sender
+// and its receiver. A source is empty if the output has no view of that
+// side.
+type Throughput struct {
+	SenderBps      float64
+	SenderSource   ThroughputSource
+	ReceiverBps    float64
+	ReceiverSource ThroughputSource
+}
+
+// Throughputs are the rates of a test's directions. Reverse is set for
+// --bidir tests only.
+type Throughputs struct {
+	Forward Throughput
+	Reverse *Throughput
+}
+
+// throughputOutput is the part of iperf3 JSON output throughput is read from
+type throughputOutput struct {
+	Start struct {
+		TestStart struct {
+			Reverse int `json:"reverse"`
+			Bidir   int `json:"bidir"`
+		} `json:"test_start"`
+	} `json:"start"`
+	End struct {
+		SumSent                 *rateStats      `json:"sum_sent"`
+		SumReceived             *rateStats      `json:"sum_received"`
+		SumSentBidirReverse     *rateStats      `json:"sum_sent_bidir_reverse"`
+		SumReceivedBidirReverse *rateStats      `json:"sum_received_bidir_reverse"`
+		Sum                     *rateStats      `json:"sum"`
+		SumBidirReverse         *rateStats      `json:"sum_bidir_reverse"`
+		Streams                 []streamSummary `json:"streams"`
+	} `json:"end"`
+}
+
+// rateStats is an end-section total or per-stream summary
+type rateStats struct {
+	BitsPerSecond *float64 `json:"bits_per_second"`
+	// Sender is set by iperf 3.7 and later on per-stream summaries: whether
+	// the reporting side sent the stream
+	Sender *bool `json:"sender"`
+}
+
+// streamSummary is an entry of end.streams: TCP streams have both views,
+// UDP streams only the client's
+type streamSummary struct {
+	Sender   *rateStats `json:"sender"`
+	Receiver *rateStats `json:"receiver"`
+	UDP      *rateStats `json:"udp"`
+}
+
+// ParseThroughput reads the sender and receiver throughput of each direction
+// from iperf3 JSON output, following the fallback chain documented on
+// ThroughputSource. It fails if neither side's rate of the forward
+// direction can be found.
+func ParseThroughput(jsonOutput string) (*Throughputs, error) {
+	var output throughputOutput
+	if err := json.Unmarshal([]byte(jsonOutput), &output); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	end := output.End
+	testStart := output.Start.TestStart
+	bidir := testStart.Bidir != 0 || end.SumSentBidirReverse != nil ||
+		end.SumReceivedBidirReverse != nil || end.SumBidirReverse != nil
+
+	// The client is the receiver when the server sends
+	throughputs := &Throughputs{
+		Forward: direction(end.SumSent, end.SumReceived, end.Sum, testStart.Reverse != 0, forwardStreams(end.Streams, bidir)),
+	}
+	if throughputs.Forward.SenderSource == "" && throughputs.Forward.ReceiverSource == "" {
+		return nil, fmt.Errorf("no throughput in the 'end' section")
+	}
+	if bidir {
+		reverse := direction(end.SumSentBidirReverse, end.SumReceivedBidirReverse, end.SumBidirReverse, true, reverseStreams(end.Streams))
+		throughputs.Reverse = &reverse
+	}
+	return throughputs, nil
+}
+
+// direction reads one direction's rates from its totals, falling back to
+// the client's sum and then to its streams
+func direction(sent, received, sum *rateStats, clientIsReceiver bool, streams []streamSummary) Throughput {
+	var throughput Throughput
+	throughput.SenderBps, throughput.SenderSource = firstRate(
+		rateFrom(sent, SourceSumSent),
+		rateFrom(sideOf(sum, !clientIsReceiver), SourceSum),
+		streamRate(streams, true, clientIsReceiver),
+	)
+	throughput.ReceiverBps, throughput.ReceiverSource = firstRate(
+		rateFrom(received, SourceSumReceived),
+		rateFrom(sideOf(sum, clientIsReceiver), SourceSum),
+		streamRate(streams, false, clientIsReceiver),
+	)
+	return throughput
+}
+
+// sourcedRate is a candidate rate; ok is false if the output lacks it
+type sourcedRate struct {
+	bps    float64
+	source ThroughputSource
+	ok     bool
+}
+
+// firstRate returns the first candidate found
+func firstRate(candidates ...sourcedRate) (float64, ThroughputSource) {
+	for _, candidate := range candidates {
+		if candidate.ok {
+			return candidate.bps, candidate.source
+		}
+	}
+	return 0, ""
+}
+
+// rateFrom returns the rate of a total, if it has one
+func rateFrom(stats *rateStats, source ThroughputSource) sourcedRate {
+	if stats == nil || stats.BitsPerSecond == nil {
+		return sourcedRate{}
+	}
+	return sourcedRate{bps: *stats.BitsPerSecond, source: source, ok: true}
+}
+
+// sideOf returns stats if it is the view of the wanted side, or nil
+func sideOf(stats *rateStats, wanted bool) *rateStats {
+	if !wanted {
+		return nil
+	}
+	return stats
+}
+
+// streamRate sums the sender's or the receiver's rate over streams. UDP
+// streams only carry the client's view, which is the receiver's when
+// clientIsReceiver is set.
+func streamRate(streams []streamSummary, sender, clientIsReceiver bool) sourcedRate {
+	total, found := 0.0, false
+	for _, stream := range streams {
+		stats := stream.Receiver
+		if sender {
+			stats = stream.Sender
+		}
+		if stream.UDP != nil && sender != clientIsReceiver {
+			stats = stream.UDP
+		}
+		if rate := rateFrom(stats, SourceStreams); rate.ok {
+			total += rate.bps
+			found = true
+		}
+	}
+	return sourcedRate{bps: total, source: SourceStreams, ok: found}
+}
+
+// forwardStreams returns the streams of the forward direction: all of them,
+// or for --bidir tests those the client sent
+func forwardStreams(streams []streamSummary, bidir bool) []streamSummary {
+	if !bidir {
+		return streams
+	}
+	return streamsSentByClient(streams, true)
+}
+
+// reverseStreams returns the streams of the reverse direction of a --bidir
+// test, those the server sent
+func reverseStreams(streams []streamSummary) []streamSummary {
+	return streamsSentByClient(streams, false)
+}
+
+// streamsSentByClient returns the streams the client sent, or those it
+// received. Streams that don't say are left out.
+func streamsSentByClient(streams []streamSummary, sent bool) []streamSummary {
+	selected := make([]streamSummary, 0, len(streams))
+	for _, stream := range streams {
+		var flag *bool
+		for _, stats := range []*rateStats{stream.Sender, stream.UDP} {
+			if stats != nil && stats.Sender != nil {
+				flag = stats.Sender
+				break
+			}
+		}
+		if flag != nil && *flag == sent {
+			selected = append(selected, stream)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,95 @@
+package iperf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseThroughput(t *testing.T) {
+	tests := []struct {
+		fixture string
+		forward Throughput
+		reverse *Throughput
+	}{
+		{
+			fixture: "iperf-3.1-tcp.json",
+			forward: Throughput{SenderBps: 9.3e9, SenderSource: SourceSumSent, ReceiverBps: 9.28e9, ReceiverSource: SourceSumReceived},
+		},
+		{
+			// UDP before 3.10 has only the client's end.sum, the sender's view
+			fixture: "iperf-3.1-udp.json",
+			forward: Throughput{SenderBps: 1e8, SenderSource: SourceSum},
+		},
+		{
+			fixture: "iperf-3.7-tcp-bidir.json",
+			forward: Throughput{SenderBps: 5e9, SenderSource: SourceSumSent, ReceiverBps: 4.98e9, ReceiverSource: SourceSumReceived},
+			reverse: &Throughput{SenderBps: 3e9, SenderSource: SourceSumSent, ReceiverBps: 2.97e9, ReceiverSource: SourceSumReceived},
+		},
+		{
+			// With -R the client receives, so end.sum is the receiver's view
+			fixture: "iperf-3.7-udp-reverse.json",
+			forward: Throughput{ReceiverBps: 9.5e7, ReceiverSource: SourceSum},
+		},
+		{
+			fixture: "iperf-3.14-udp.json",
+			forward: Throughput{SenderBps: 1e8, SenderSource: SourceSumSent, ReceiverBps: 9.95e7, ReceiverSource: SourceSumReceived},
+		},
+		{
+			fixture: "iperf-3.14-tcp-streams-only.json",
+			forward: Throughput{SenderBps: 9.4e9, SenderSource: SourceStreams, ReceiverBps: 9.39e9, ReceiverSource: SourceStreams},
+		},
+		{
+			fixture: "completed.json",
+			forward: Throughput{SenderBps: 9415826248.3, SenderSource: SourceSumSent, ReceiverBps: 9411311025.1, ReceiverSource: SourceSumReceived},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			throughputs, err := ParseThroughput(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseThroughput() error = %v", err)
+			}
+			if throughputs.Forward != tt.forward {
+				t.Errorf("Forward = %+v, want %+v", throughputs.Forward, tt.forward)
+			}
+			if !reflect.DeepEqual(throughputs.Reverse, tt.reverse) {
+				t.Errorf("Reverse = %+v, want %+v", throughputs.Reverse, tt.reverse)
+			}
+		})
+	}
+}
+
+func TestParseThroughput_BidirStreams(t *testing.T) {
+	// Without the totals, each direction sums the streams it sent
+	output := `{"start": {"test_start": {"protocol": "TCP", "bidir": 1}}, "end": {"streams": [
+		{"sender": {"bits_per_second": 5, "sender": true}, "receiver": {"bits_per_second": 4, "sender": true}},
+		{"sender": {"bits_per_second": 6, "sender": true}, "receiver": {"bits_per_second": 6, "sender": true}},
+		{"sender": {"bits_per_second": 3, "sender": false}, "receiver": {"bits_per_second": 2, "sender": false}}]}}`
+
+	throughputs, err := ParseThroughput(output)
+	if err != nil {
+		t.Fatalf("ParseThroughput() error = %v", err)
+	}
+	want := Throughput{SenderBps: 11, SenderSource: SourceStreams, ReceiverBps: 10, ReceiverSource: SourceStreams}
+	if throughputs.Forward != want {
+		t.Errorf("Forward = %+v, want %+v", throughputs.Forward, want)
+	}
+	want = Throughput{SenderBps: 3, SenderSource: SourceStreams, ReceiverBps: 2, ReceiverSource: SourceStreams}
+	if throughputs.Reverse == nil || *throughputs.Reverse != want {
+		t.Errorf("Reverse = %+v, want %+v", throughputs.Reverse, want)
+	}
+}
+
+func TestParseThroughput_Missing(t *testing.T) {
+	for _, fixture := range []string{"truncated.json", "control_socket_closed.json"} {
+		if _, err := ParseThroughput(readFixture(t, fixture)); err == nil {
+			t.Errorf("ParseThroughput(%s) succeeded, want error", fixture)
+		}
+	}
+	if bps, unit, err := ExtractThroughput(map[string]interface{}{"end": map[string]interface{}{
+		"sum": map[string]interface{}{"bits_per_second": 2e9},
+	}}); err != nil || bps != 2 || unit != "Gbps" {
+		t.Errorf("ExtractThroughput() of end.sum = %v %s, %v, want 2 Gbps", bps, unit, err)
+	}
+}
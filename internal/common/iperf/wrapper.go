@@ -4,9 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/units"
 )
 
 // Mode represents iperf3 operation mode
@@ -34,8 +41,10 @@ type Config struct {
 	Mode              Mode
 	Protocol          Protocol // TCP or UDP (default: TCP)
 	Port              int
-	Host              string // For client mode
-	Duration          int
+	Host              string        // For client mode
+	IPVersion         int           // 4 or 6 passes -4 or -6; 0 passes neither
+	BindIP            string        // Local address to bind to (-B), to test through one interface
+	Duration          time.Duration // Passed to -t in seconds, fractional if needed
 	Bandwidth         string
 	WindowSize        string
 	Parallel          int
@@ -48,8 +57,34 @@ type Config struct {
 	TOS               int
 	ZeroCopy          bool
 	OmitSeconds       int
-	LogFile           string // Path to save iperf3 output (--logfile)
+	Bytes             int64         // Bytes to transmit (-n); overrides Duration when set
+	Burst             *Burst        // UDP microburst schedule, run as repeated short tests
+	LogFile           string        // Path to save iperf3 output (--logfile)
+	StartDelay        time.Duration // Wait before launching; applied by the caller, not an iperf3 flag
 	ExtraArgs         []string
+	// Started, if set, is called with the iperf3 process once it runs, so
+	// that the caller can kill it; not an iperf3 flag
+	Started func(*os.Process)
+}
+
+// Burst describes a UDP microburst schedule: Repeat bursts of OnMs at the
+// configured bandwidth, each followed by OffMs of idle time
+type Burst struct {
+	OnMs   int `json:"on_ms"`
+	OffMs  int `json:"off_ms"`
+	Repeat int `json:"repeat"`
+}
+
+// Length returns the total length of the burst schedule
+func (b *Burst) Length() time.Duration {
+	return time.Duration(b.Repeat*(b.OnMs+b.OffMs)) * time.Millisecond
+}
+
+// BurstOutput is the JSON document stored for a burst test. It contains the
+// schedule and the raw iperf3 JSON output of each burst in order.
+type BurstOutput struct {
+	Burst  Burst             `json:"burst"`
+	Bursts []json.RawMessage `json:"bursts"`
 }
 
 // Result contains iperf3 execution result
@@ -65,7 +100,8 @@ type Result struct {
 
 // Wrapper wraps iperf3 command execution
 type Wrapper struct {
-	iperfPath string
+	iperfPath   string
+	stopTimeout time.Duration
 }
 
 // NewWrapper creates a new iperf3 wrapper
@@ -78,6 +114,50 @@ func NewWrapper(iperfPath string) *Wrapper {
 	}
 }
 
+// SetStopTimeout sets how long an iperf3 process has to exit after being
+// sent SIGTERM, once its context is done, before it is killed. 0 waits for
+// it indefinitely.
+func (w *Wrapper) SetStopTimeout(timeout time.Duration) {
+	w.stopTimeout = timeout
+}
+
+// command returns an iperf3 command that is sent SIGTERM when ctx is done,
+// and killed if it has not exited within the stop timeout
+func (w *Wrapper) command(ctx context.Context, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, w.iperfPath, args...) // #nosec G204 -- iperf3 path is controlled, args are validated
+	cmd.Cancel = func() error {
+		return Terminate(cmd.Process)
+	}
+	cmd.WaitDelay = w.stopTimeout
+	return cmd
+}
+
+// Terminate asks a process to exit with SIGTERM, killing it on platforms
+// without signals
+func Terminate(process *os.Process) error {
+	if err := process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return process.Kill()
+	}
+	return nil
+}
+
+// Check verifies that iperf3 can be run by looking it up and running
+// iperf3 --version
+func Check(ctx context.Context, iperfPath string) error {
+	if iperfPath == "" {
+		iperfPath = "iperf3"
+	}
+	path, err := exec.LookPath(iperfPath)
+	if err != nil {
+		return err
+	}
+	output, err := exec.CommandContext(ctx, path, "--version").CombinedOutput() // #nosec G204 -- iperf3 path is controlled
+	if err != nil && len(output) == 0 {
+		return fmt.Errorf("%s --version failed: %w", path, err)
+	}
+	return nil
+}
+
 // BuildCommand builds the iperf3 command arguments
 func (w *Wrapper) BuildCommand(config *Config) ([]string, error) {
 	if config == nil {
@@ -96,21 +176,35 @@ func (w *Wrapper) BuildCommand(config *Config) ([]string, error) {
 	case ModeServer:
 		args = append(args, "-s")
 		args = append(args, "-p", fmt.Sprintf("%d", config.Port))
+		if config.BindIP != "" {
+			args = append(args, "-B", config.BindIP)
+		}
 	case ModeClient:
 		if config.Host == "" {
 			return nil, fmt.Errorf("host is required for client mode")
 		}
 		args = append(args, "-c", config.Host)
 		args = append(args, "-p", fmt.Sprintf("%d", config.Port))
+		switch config.IPVersion {
+		case 4:
+			args = append(args, "-4")
+		case 6:
+			args = append(args, "-6")
+		}
+		if config.BindIP != "" {
+			args = append(args, "-B", config.BindIP)
+		}
 
-		// Duration (only for client)
-		if config.Duration > 0 {
-			args = append(args, "-t", fmt.Sprintf("%d", config.Duration))
+		// Duration or byte count (only for client)
+		if config.Bytes > 0 {
+			args = append(args, "-n", fmt.Sprintf("%d", config.Bytes))
+		} else if config.Duration > 0 {
+			args = append(args, "-t", units.FormatSeconds(config.Duration))
 		}
 
 		// Bandwidth
 		if config.Bandwidth != "" {
-			args = append(args, "-b", config.Bandwidth)
+			args = append(args, "-b", iperfBitRate(config.Bandwidth))
 		}
 
 		// Parallel streams
@@ -163,13 +257,17 @@ func (w *Wrapper) BuildCommand(config *Config) ([]string, error) {
 			args = append(args, "-O", fmt.Sprintf("%d", config.OmitSeconds))
 		}
 
+		// The server's results, against which the controller reconciles
+		// the client's
+		args = append(args, "--get-server-output")
+
 	default:
 		return nil, fmt.Errorf("invalid mode: %s", config.Mode)
 	}
 
 	// Window size (both modes)
 	if config.WindowSize != "" {
-		args = append(args, "-w", config.WindowSize)
+		args = append(args, "-w", iperfBytes(config.WindowSize))
 	}
 
 	// JSON output
@@ -204,13 +302,18 @@ func (w *Wrapper) Run(ctx context.Context, config *Config) (*Result, error) {
 		StartTime: time.Now(),
 	}
 
-	cmd := exec.CommandContext(ctx, w.iperfPath, args...) // #nosec G204 -- iperf3 path is controlled, args are validated
+	cmd := w.command(ctx, args)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err = cmd.Run()
+	if err = cmd.Start(); err == nil {
+		if config.Started != nil {
+			config.Started(cmd.Process)
+		}
+		err = cmd.Wait()
+	}
 	result.EndTime = time.Now()
 	result.Duration = result.EndTime.Sub(result.StartTime)
 
@@ -240,20 +343,72 @@ func (w *Wrapper) Run(ctx context.Context, config *Config) (*Result, error) {
 	return result, nil
 }
 
-// RunServer starts an iperf3 server that runs until context is cancelled
-func (w *Wrapper) RunServer(ctx context.Context, port int, logFile string) (*exec.Cmd, error) {
+// BurstBytes returns the number of bytes to send so that a burst at the given
+// bandwidth (e.g. "10G") lasts onMs milliseconds
+func BurstBytes(bandwidth string, onMs int) (int64, error) {
+	bps, err := ParseBandwidth(bandwidth)
+	if err != nil {
+		return 0, err
+	}
+	if bps <= 0 {
+		return 0, fmt.Errorf("burst requires a bandwidth limit")
+	}
+
+	bytes := int64(bps * float64(onMs) / 1000 / 8)
+	if bytes < 1 {
+		bytes = 1
+	}
+	return bytes, nil
+}
+
+// iperfBitRate normalizes a bandwidth to plain bits per second, since iperf3
+// would read binary prefixes such as "1Gi" as decimal. Unparseable values are
+// passed through for iperf3 to report.
+func iperfBitRate(bandwidth string) string {
+	bps, err := units.ParseBitRate(bandwidth)
+	if err != nil {
+		return bandwidth
+	}
+	return strconv.FormatFloat(bps, 'f', 0, 64)
+}
+
+// iperfBytes normalizes a size to plain bytes so iperf3 and the controller
+// agree on its value. Unparseable values are passed through.
+func iperfBytes(size string) string {
+	n, err := units.ParseBytes(size)
+	if err != nil {
+		return size
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// ParseBandwidth parses an iperf3 bandwidth string such as "10G" or "100M"
+// into bits per second. An empty bandwidth means unlimited and returns 0.
+func ParseBandwidth(bandwidth string) (float64, error) {
+	if bandwidth == "" {
+		return 0, nil
+	}
+	return units.ParseBitRate(bandwidth)
+}
+
+// RunServer starts an iperf3 server that runs until context is cancelled.
+// A non-empty bindIP limits the server to that local address.
+func (w *Wrapper) RunServer(ctx context.Context, port int, bindIP, logFile string) (*exec.Cmd, error) {
 	args := []string{
 		"-s",
 		"-p", fmt.Sprintf("%d", port),
 		"-J",
 	}
+	if bindIP != "" {
+		args = append(args, "-B", bindIP)
+	}
 
 	// Add logfile if specified
 	if logFile != "" {
 		args = append(args, "--logfile", logFile)
 	}
 
-	cmd := exec.CommandContext(ctx, w.iperfPath, args...) // #nosec G204 -- iperf3 path is controlled, args are validated
+	cmd := w.command(ctx, args)
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start iperf3 server: %w", err)
@@ -280,40 +435,94 @@ func ParseResult(jsonOutput string) (map[string]interface{}, error) {
 	return result, nil
 }
 
-// ExtractThroughput extracts throughput information from parsed result
+// ExtractThroughput extracts the sender throughput of the forward direction
+// from parsed result, or the receiver's if the output has no sender view.
+// See ParseThroughput for the sections it is read from.
 func ExtractThroughput(result map[string]interface{}) (float64, string, error) {
-	end, ok := result["end"].(map[string]interface{})
-	if !ok {
-		return 0, "", fmt.Errorf("missing 'end' section in result")
+	data, err := json.Marshal(result)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to encode result: %w", err)
 	}
-
-	sumSent, ok := end["sum_sent"].(map[string]interface{})
-	if !ok {
-		return 0, "", fmt.Errorf("missing 'sum_sent' section in result")
+	throughputs, err := ParseThroughput(string(data))
+	if err != nil {
+		return 0, "", err
 	}
 
-	bitsPerSecond, ok := sumSent["bits_per_second"].(float64)
-	if !ok {
-		return 0, "", fmt.Errorf("missing 'bits_per_second' in result")
+	bitsPerSecond := throughputs.Forward.SenderBps
+	if throughputs.Forward.SenderSource == "" {
+		bitsPerSecond = throughputs.Forward.ReceiverBps
 	}
 
 	// Convert to human-readable format
-	var throughput float64
-	var unit string
-
-	if bitsPerSecond >= 1e9 {
-		throughput = bitsPerSecond / 1e9
-		unit = "Gbps"
-	} else if bitsPerSecond >= 1e6 {
-		throughput = bitsPerSecond / 1e6
-		unit = "Mbps"
-	} else if bitsPerSecond >= 1e3 {
-		throughput = bitsPerSecond / 1e3
-		unit = "Kbps"
-	} else {
-		throughput = bitsPerSecond
-		unit = "bps"
-	}
+	throughput, unit := units.ScaleBitRate(bitsPerSecond)
 
 	return throughput, unit, nil
 }
+
+// ExtractConnection returns the local and remote addresses of the first
+// connection in iperf3 JSON output. For burst output, the connection of the
+// first burst is used.
+func ExtractConnection(jsonOutput string) (local, remote string, err error) {
+	var burstOutput BurstOutput
+	if err := json.Unmarshal([]byte(jsonOutput), &burstOutput); err == nil && len(burstOutput.Bursts) > 0 {
+		jsonOutput = string(burstOutput.Bursts[0])
+	}
+
+	var output struct {
+		Start struct {
+			Connected []struct {
+				LocalHost  string `json:"local_host"`
+				RemoteHost string `json:"remote_host"`
+			} `json:"connected"`
+		} `json:"start"`
+	}
+	if err := json.Unmarshal([]byte(jsonOutput), &output); err != nil {
+		return "", "", fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	if len(output.Start.Connected) == 0 {
+		return "", "", fmt.Errorf("missing 'connected' section in result")
+	}
+
+	connection := output.Start.Connected[0]
+	return connection.LocalHost, connection.RemoteHost, nil
+}
+
+// CheckOutput reports problems that iperf3 records in its JSON output even
+// when it exits 0: a top-level "error" message, such as "control socket has
+// closed unexpectedly", or a missing "end" section from a truncated run. For
+// burst output, every burst is checked.
+func CheckOutput(jsonOutput string) error {
+	var burstOutput BurstOutput
+	if err := json.Unmarshal([]byte(jsonOutput), &burstOutput); err == nil && burstOutput.Bursts != nil {
+		problems := make([]string, 0)
+		for i, burst := range burstOutput.Bursts {
+			if err := checkOutput(burst); err != nil {
+				problems = append(problems, fmt.Sprintf("burst %d: %v", i, err))
+			}
+		}
+		if len(problems) > 0 {
+			return errors.New(strings.Join(problems, "; "))
+		}
+		return nil
+	}
+
+	return checkOutput([]byte(jsonOutput))
+}
+
+// checkOutput checks a single iperf3 JSON output
+func checkOutput(data []byte) error {
+	var output struct {
+		Error string          `json:"error"`
+		End   json.RawMessage `json:"end"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return fmt.Errorf("invalid JSON output: %w", err)
+	}
+	if output.Error != "" {
+		return fmt.Errorf("iperf3 reported error: %s", output.Error)
+	}
+	if len(output.End) == 0 || string(output.End) == "null" || string(output.End) == "{}" {
+		return fmt.Errorf("iperf3 output has no 'end' section")
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package iperf
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestCheckOutput(t *testing.T) {
+	tests := []struct {
+		fixture string
+		wantErr string
+	}{
+		{"completed.json", ""},
+		{"control_socket_closed.json", "control socket has closed unexpectedly"},
+		{"truncated.json", "no 'end' section"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			err := CheckOutput(readFixture(t, tt.fixture))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckOutput() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckOutput() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckOutput_Bursts(t *testing.T) {
+	output := BurstOutput{
+		Burst: Burst{OnMs: 10, OffMs: 90, Repeat: 2},
+		Bursts: []json.RawMessage{
+			json.RawMessage(readFixture(t, "completed.json")),
+			json.RawMessage(readFixture(t, "control_socket_closed.json")),
+		},
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	err = CheckOutput(string(data))
+	if err == nil || !strings.HasPrefix(err.Error(), "burst 1:") {
+		t.Errorf("CheckOutput() error = %v, want burst 1 to fail", err)
+	}
+
+	output.Bursts = output.Bursts[:1]
+	data, _ = json.Marshal(output)
+	if err := CheckOutput(string(data)); err != nil {
+		t.Errorf("CheckOutput() error = %v for completed bursts", err)
+	}
+}
+
+func TestBurstBytes(t *testing.T) {
+	tests := []struct {
+		bandwidth string
+		onMs      int
+		want      int64
+		wantErr   string
+	}{
+		{"1G", 10, 1250000, ""},
+		{"100M", 1, 12500, ""},
+		{"1Gi", 8, 1073741, ""},
+		{"8", 1, 1, ""}, // Less than a byte still sends one
+		{"", 10, 0, "requires a bandwidth limit"},
+		{"0", 10, 0, "requires a bandwidth limit"},
+		{"fast", 10, 0, "fast"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.bandwidth, func(t *testing.T) {
+			got, err := BurstBytes(tt.bandwidth, tt.onMs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("BurstBytes() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("BurstBytes() = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck_Missing(t *testing.T) {
+	if err := Check(context.Background(), filepath.Join(t.TempDir(), "iperf3")); err == nil {
+		t.Error("Check() of a missing binary succeeded, want error")
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// BlackoutWindow is a daily period in which a node must not be tested,
+// such as while it runs its backups. A window whose end is before its
+// start spans midnight.
+type BlackoutWindow struct {
+	Start    int // Minutes since midnight
+	End      int // Minutes since midnight
+	Location *time.Location
+}
+
+// ParseBlackoutWindow parses a window from "HH:MM" start and end times in
+// the named IANA time zone, or in local time if timezone is empty
+func ParseBlackoutWindow(start, end, timezone string) (BlackoutWindow, error) {
+	var window BlackoutWindow
+	var err error
+	if window.Start, err = parseClock(start); err != nil {
+		return window, fmt.Errorf("start: %w", err)
+	}
+	if window.End, err = parseClock(end); err != nil {
+		return window, fmt.Errorf("end: %w", err)
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("start and end cannot be the same time")
+	}
+
+	window.Location = time.Local
+	if timezone != "" {
+		if window.Location, err = time.LoadLocation(timezone); err != nil {
+			return window, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	return window, nil
+}
+
+// parseClock parses an "HH:MM" time of day into minutes since midnight
+func parseClock(clock string) (int, error) {
+	parsed, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an HH:MM time", clock)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// String returns the window as "HH:MM-HH:MM", followed by its time zone
+// unless it is in local time
+func (w BlackoutWindow) String() string {
+	s := fmt.Sprintf("%02d:%02d-%02d:%02d", w.Start/60, w.Start%60, w.End/60, w.End%60)
+	if w.Location != nil && w.Location != time.Local {
+		s += " " + w.Location.String()
+	}
+	return s
+}
+
+// Overlap returns the end of an occurrence of the window that overlaps
+// from-to, and whether there is one
+func (w BlackoutWindow) Overlap(from, to time.Time) (time.Time, bool) {
+	location := w.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	// An occurrence overlapping from may have started the day before
+	year, month, date := from.In(location).Date()
+	for day := date - 1; ; day++ {
+		start := time.Date(year, month, day, w.Start/60, w.Start%60, 0, 0, location)
+		if !start.Before(to) {
+			return time.Time{}, false
+		}
+		endDay := day
+		if w.End < w.Start {
+			endDay++
+		}
+		end := time.Date(year, month, endDay, w.End/60, w.End%60, 0, 0, location)
+		if end.After(from) {
+			return end, true
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBlackoutWindow(t *testing.T) {
+	window, err := ParseBlackoutWindow("23:30", "01:15", "UTC")
+	if err != nil {
+		t.Fatalf("ParseBlackoutWindow() error = %v", err)
+	}
+	if window.Start != 23*60+30 || window.End != 75 || window.Location != time.UTC {
+		t.Errorf("ParseBlackoutWindow() = %+v", window)
+	}
+	if got := window.String(); got != "23:30-01:15 UTC" {
+		t.Errorf("String() = %q, want 23:30-01:15 UTC", got)
+	}
+
+	tests := []struct {
+		start, end, timezone, wantErr string
+	}{
+		{"1am", "03:00", "", "start"},
+		{"01:00", "24:00", "", "end"},
+		{"01:00", "01:00", "", "the same time"},
+		{"01:00", "03:00", "Mars/Olympus", "timezone"},
+	}
+	for _, tt := range tests {
+		_, err := ParseBlackoutWindow(tt.start, tt.end, tt.timezone)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseBlackoutWindow(%q, %q, %q) error = %v, want %q", tt.start, tt.end, tt.timezone, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBlackoutWindow_Overlap(t *testing.T) {
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+	backups := BlackoutWindow{Start: 60, End: 180, Location: time.UTC}
+	overnight := BlackoutWindow{Start: 23 * 60, End: 60, Location: time.UTC}
+
+	tests := []struct {
+		name     string
+		window   BlackoutWindow
+		from, to time.Time
+		wantEnd  time.Time
+		want     bool
+	}{
+		{"before", backups, at(10, 0, 0), at(10, 1, 0), time.Time{}, false},
+		{"inside", backups, at(10, 2, 0), at(10, 2, 10), at(10, 3, 0), true},
+		{"runs into it", backups, at(10, 0, 50), at(10, 1, 5), at(10, 3, 0), true},
+		{"after", backups, at(10, 3, 0), at(10, 22, 0), time.Time{}, false},
+		{"next day", backups, at(10, 23, 0), at(11, 1, 30), at(11, 3, 0), true},
+		{"spanning midnight, before it", overnight, at(10, 23, 30), at(10, 23, 40), at(11, 1, 0), true},
+		{"spanning midnight, after it", overnight, at(11, 0, 30), at(11, 0, 40), at(11, 1, 0), true},
+		{"spanning midnight, outside", overnight, at(11, 1, 0), at(11, 22, 0), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, ok := tt.window.Overlap(tt.from, tt.to)
+			if ok != tt.want || !end.Equal(tt.wantEnd) {
+				t.Errorf("Overlap() = %v, %t; want %v, %t", end, ok, tt.wantEnd, tt.want)
+			}
+		})
+	}
+}
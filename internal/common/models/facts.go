@@ -0,0 +1,24 @@
+package models
+
+// NodeFacts describes a node's environment at the time of a run, so results
+// can be interpreted later against the kernel, NIC and TCP settings in use
+type NodeFacts struct {
+	OS                   string           `json:"os,omitempty"`
+	Platform             string           `json:"platform,omitempty"`
+	KernelVersion        string           `json:"kernel_version,omitempty"`
+	Arch                 string           `json:"arch,omitempty"`
+	IperfVersion         string           `json:"iperf_version,omitempty"`
+	DefaultQdisc         string           `json:"default_qdisc,omitempty"`
+	TCPCongestionControl string           `json:"tcp_congestion_control,omitempty"`
+	RmemMax              int64            `json:"rmem_max,omitempty"`
+	WmemMax              int64            `json:"wmem_max,omitempty"`
+	Interfaces           []InterfaceFacts `json:"interfaces,omitempty"`
+}
+
+// InterfaceFacts describes a network interface
+type InterfaceFacts struct {
+	Name      string `json:"name"`
+	MTU       int    `json:"mtu"`
+	SpeedMbps int64  `json:"speed_mbps,omitempty"`
+	Driver    string `json:"driver,omitempty"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"fmt"
+	"net"
+)
+
+// AddressFamily is an IP address family a test pair runs over
+type AddressFamily string
+
+// Address families
+const (
+	FamilyV4 AddressFamily = "v4"
+	FamilyV6 AddressFamily = "v6"
+)
+
+// ParseAddressFamily parses "v4" or "v6"
+func ParseAddressFamily(s string) (AddressFamily, error) {
+	switch family := AddressFamily(s); family {
+	case FamilyV4, FamilyV6:
+		return family, nil
+	default:
+		return "", fmt.Errorf("unknown address family %q (use v4 or v6)", s)
+	}
+}
+
+// IPVersion returns 4 or 6, as passed to iperf3's -4 and -6 flags
+func (f AddressFamily) IPVersion() int {
+	if f == FamilyV6 {
+		return 6
+	}
+	return 4
+}
+
+// FamilyTestID returns the ID of the test of a pair over family
+func FamilyTestID(testID string, family AddressFamily) string {
+	return fmt.Sprintf("%s-%s", testID, family)
+}
+
+// familyOf returns the family of an IP address, or "" if addr is not one
+func familyOf(addr string) AddressFamily {
+	ip := net.ParseIP(addr)
+	switch {
+	case ip == nil:
+		return ""
+	case ip.To4() != nil:
+		return FamilyV4
+	default:
+		return FamilyV6
+	}
+}
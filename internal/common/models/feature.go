@@ -0,0 +1,83 @@
+package models
+
+// Feature names an optional daemon capability reported by GetCapabilities
+type Feature string
+
+const (
+	// FeatureCapabilities indicates the daemon implements GetCapabilities
+	FeatureCapabilities Feature = "capabilities"
+	// FeatureProcessesPerPair indicates the daemon expands processes_per_pair into sub-tests
+	FeatureProcessesPerPair Feature = "processes_per_pair"
+	// FeaturePortRange indicates the daemon reserves per-run port sub-ranges in PrepareTest
+	FeaturePortRange Feature = "port_range"
+	// FeatureFacts indicates the daemon implements GatherFacts
+	FeatureFacts Feature = "facts"
+	// FeaturePairTiming indicates the daemon honors per-target durations and start delays
+	FeaturePairTiming Feature = "pair_timing"
+	// FeatureServerStatus indicates the daemon reports its running server ports in GetStatus
+	FeatureServerStatus Feature = "server_status"
+	// FeatureFractionalDuration indicates the daemon honors millisecond durations
+	FeatureFractionalDuration Feature = "fractional_duration"
+	// FeatureMaintenance indicates the daemon implements SetMaintenance
+	FeatureMaintenance Feature = "maintenance"
+	// FeatureStopTests indicates the daemon lists its processes in GetStatus
+	// and implements StopTests
+	FeatureStopTests Feature = "stop_tests"
+	// FeatureResourceSamples indicates the daemon samples resource usage when
+	// asked to by Initialize and implements GetSamples
+	FeatureResourceSamples Feature = "resource_samples"
+	// FeatureResultFiles indicates the daemon reports the copies of results
+	// it saved when asked to by Initialize and implements DownloadFile
+	FeatureResultFiles Feature = "result_files"
+	// FeatureRunScope indicates the daemon tags results with the run ID
+	// given to Initialize and counts each run's tests in GetStatus
+	FeatureRunScope Feature = "run_scope"
+	// FeatureProbe indicates the daemon implements ProbeConnectivity
+	FeatureProbe Feature = "probe"
+	// FeatureBindAddress indicates the daemon binds servers and clients to
+	// the local addresses given in StartServers and ClientTarget
+	FeatureBindAddress Feature = "bind_address"
+	// FeatureBurst indicates the daemon runs the UDP microburst schedule
+	// given in a client's profile
+	FeatureBurst Feature = "burst"
+)
+
+// FeatureMinVersions maps each feature to the first daemon version supporting it
+var FeatureMinVersions = map[Feature]string{
+	FeatureCapabilities:       "v0.2.0",
+	FeatureProcessesPerPair:   "v0.2.0",
+	FeaturePortRange:          "v0.2.0",
+	FeatureFacts:              "v0.2.0",
+	FeaturePairTiming:         "v0.2.0",
+	FeatureServerStatus:       "v0.2.0",
+	FeatureFractionalDuration: "v0.2.0",
+	FeatureMaintenance:        "v0.2.0",
+	FeatureStopTests:          "v0.2.0",
+	FeatureResourceSamples:    "v0.2.0",
+	FeatureResultFiles:        "v0.2.0",
+	FeatureRunScope:           "v0.2.0",
+	FeatureProbe:              "v0.2.0",
+	FeatureBindAddress:        "v0.2.0",
+	FeatureBurst:              "v0.2.0",
+}
+
+// SupportedFeatures lists the features implemented by this build of the daemon
+func SupportedFeatures() []Feature {
+	return []Feature{
+		FeatureCapabilities,
+		FeatureProcessesPerPair,
+		FeaturePortRange,
+		FeatureFacts,
+		FeaturePairTiming,
+		FeatureServerStatus,
+		FeatureFractionalDuration,
+		FeatureMaintenance,
+		FeatureStopTests,
+		FeatureResourceSamples,
+		FeatureResultFiles,
+		FeatureRunScope,
+		FeatureProbe,
+		FeatureBindAddress,
+		FeatureBurst,
+	}
+}
@@ -2,16 +2,33 @@ package models
 
 import (
 	"fmt"
+	"strings"
 )
 
 // Node represents a node in the cluster
 type Node struct {
 	ID       string
 	Hostname string
-	IP       string
+	IP       string // Address the controller reaches the daemon at
+	DataIP   string // Address iperf3 traffic is sent to; IP when empty
+	// DataIPs are further addresses iperf3 traffic can be sent to, such as
+	// the IPv6 address of a dual-stack node
+	DataIPs  []string
 	Port     int
 	Capacity ProcessCapacity
 	Tags     []string
+	// Interfaces are the node's named network interfaces, tested one by one
+	// by per_interface topologies
+	Interfaces []NodeInterface
+	// Blackouts are daily periods in which the node must not be tested
+	Blackouts []BlackoutWindow
+}
+
+// NodeInterface is a named network interface of a node, such as the
+// frontend or backend NIC of a multi-homed node, and its address
+type NodeInterface struct {
+	Name string
+	IP   string
 }
 
 // ProcessCapacity represents a node's ability to run processes
@@ -25,8 +42,9 @@ type ProcessCapacity struct {
 
 // NodeRegistry manages a collection of nodes
 type NodeRegistry struct {
-	nodes    map[string]*Node
-	nodeList []*Node
+	nodes         map[string]*Node
+	nodeList      []*Node
+	lowercaseTags bool // Tags were lowercased when the nodes were loaded
 }
 
 // NewNodeRegistry creates a new node registry
@@ -75,16 +93,36 @@ func (r *NodeRegistry) Count() int {
 func (r *NodeRegistry) GetNodesByTag(tag string) []*Node {
 	result := make([]*Node, 0)
 	for _, node := range r.nodeList {
-		for _, nodeTag := range node.Tags {
-			if nodeTag == tag {
-				result = append(result, node)
-				break
-			}
+		if node.HasTag(tag) {
+			result = append(result, node)
 		}
 	}
 	return result
 }
 
+// GetNodesMatching returns all nodes whose tags satisfy expr
+func (r *NodeRegistry) GetNodesMatching(expr *TagExpr) []*Node {
+	result := make([]*Node, 0)
+	for _, node := range r.nodeList {
+		if expr.Matches(node.Tags) {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// SetLowercaseTags records that the nodes' tags were lowercased when loaded,
+// so that tag expressions parsed by the registry are lowercased too
+func (r *NodeRegistry) SetLowercaseTags(lowercase bool) {
+	r.lowercaseTags = lowercase
+}
+
+// ParseTagExpr parses a tag expression, normalizing its tags like the
+// registry's nodes
+func (r *NodeRegistry) ParseTagExpr(text string) (*TagExpr, error) {
+	return ParseTagExpr(text, r.lowercaseTags)
+}
+
 // String returns a string representation of the node
 func (n *Node) String() string {
 	return fmt.Sprintf("Node{ID: %s, Hostname: %s, IP: %s, Port: %d}",
@@ -101,7 +139,48 @@ func (n *Node) HasTag(tag string) bool {
 	return false
 }
 
+// TagValue returns the value of the node's first key:value tag with key,
+// such as "a1" for key rack and tag rack:a1
+func (n *Node) TagValue(key string) (string, bool) {
+	for _, t := range n.Tags {
+		if k, value, isPair := strings.Cut(t, ":"); isPair && k == key {
+			return value, true
+		}
+	}
+	return "", false
+}
+
 // Address returns the network address of the node
 func (n *Node) Address() string {
 	return fmt.Sprintf("%s:%d", n.IP, n.Port)
 }
+
+// DataAddress returns the address iperf3 clients connect to the node at
+func (n *Node) DataAddress() string {
+	if n.DataIP != "" {
+		return n.DataIP
+	}
+	return n.IP
+}
+
+// InterfaceAddress returns the address of the node's interface name. The
+// boolean result is false when the node has no such interface.
+func (n *Node) InterfaceAddress(name string) (string, bool) {
+	for _, iface := range n.Interfaces {
+		if iface.Name == name {
+			return iface.IP, true
+		}
+	}
+	return "", false
+}
+
+// DataAddressFor returns the first of the node's data addresses in family:
+// DataAddress, then DataIPs. The boolean result is false when it has none.
+func (n *Node) DataAddressFor(family AddressFamily) (string, bool) {
+	for _, addr := range append([]string{n.DataAddress()}, n.DataIPs...) {
+		if familyOf(addr) == family {
+			return addr, true
+		}
+	}
+	return "", false
+}
@@ -140,6 +140,20 @@ func TestNode_HasTag(t *testing.T) {
 	}
 }
 
+func TestNode_TagValue(t *testing.T) {
+	node := &Node{
+		ID:   "node1",
+		Tags: []string{"rack", "zone:us-west", "rack:a1", "rack:a2"},
+	}
+
+	if value, ok := node.TagValue("rack"); !ok || value != "a1" {
+		t.Errorf("TagValue('rack') = %q, %v, want a1", value, ok)
+	}
+	if _, ok := node.TagValue("row"); ok {
+		t.Error("TagValue('row') should not be found")
+	}
+}
+
 func TestNode_Address(t *testing.T) {
 	node := &Node{
 		ID:   "node1",
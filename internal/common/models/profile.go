@@ -2,9 +2,17 @@ package models
 
 import (
 	"fmt"
+	"math"
 	"strings"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/units"
 )
 
+// MinDuration is the shortest test duration accepted. Shorter tests end
+// before iperf3 has finished connection setup and report nothing useful.
+const MinDuration = 100 * time.Millisecond
+
 // Protocol represents the transport protocol for iperf3 tests
 type Protocol string
 
@@ -18,13 +26,14 @@ const (
 // TestProfile contains all iperf3 parameters for a test
 type TestProfile struct {
 	Name              string
-	Duration          int
-	Protocol          Protocol // TCP or UDP (default: TCP)
+	Duration          time.Duration // May be fractional seconds, e.g. 500ms for connection setup tests
+	Protocol          Protocol      // TCP or UDP (default: TCP)
 	Bandwidth         string
 	WindowSize        string
 	Parallel          int
 	Bidirectional     bool
 	Reverse           bool
+	SeparateReverse   bool // Bidirectional as two one-way tests rather than --bidir
 	BufferLength      int
 	CongestionControl string // TCP only
 	MSS               int    // TCP only
@@ -32,9 +41,32 @@ type TestProfile struct {
 	TOS               int
 	ZeroCopy          bool
 	OmitSeconds       int
+	ProcessesPerPair  int            // Number of iperf3 processes per pair (0 or 1 means one)
+	PortsPerPair      int            // Server ports per pair, each with a process running a share of Parallel
+	Burst             *BurstSchedule // UDP microburst schedule (UDP only)
+	Capture           *CapturePolicy // When daemons keep a packet capture of the test
 	ExtraFlags        map[string]string
 }
 
+// CapturePolicy is when a daemon with captures enabled keeps the packet
+// capture of a test
+type CapturePolicy struct {
+	OnFailure bool
+	BelowBps  float64 // Keep when either direction measures below this; 0 disables
+}
+
+// BurstSchedule describes repeated short UDP bursts separated by idle gaps
+type BurstSchedule struct {
+	OnMs   int
+	OffMs  int
+	Repeat int
+}
+
+// Length returns the total length of the burst schedule
+func (b *BurstSchedule) Length() time.Duration {
+	return time.Duration(b.Repeat*(b.OnMs+b.OffMs)) * time.Millisecond
+}
+
 // ProfileRegistry manages test profiles
 type ProfileRegistry struct {
 	profiles map[string]*TestProfile
@@ -86,6 +118,7 @@ func (p *TestProfile) Clone() *TestProfile {
 		Parallel:          p.Parallel,
 		Bidirectional:     p.Bidirectional,
 		Reverse:           p.Reverse,
+		SeparateReverse:   p.SeparateReverse,
 		BufferLength:      p.BufferLength,
 		CongestionControl: p.CongestionControl,
 		MSS:               p.MSS,
@@ -93,6 +126,18 @@ func (p *TestProfile) Clone() *TestProfile {
 		TOS:               p.TOS,
 		ZeroCopy:          p.ZeroCopy,
 		OmitSeconds:       p.OmitSeconds,
+		ProcessesPerPair:  p.ProcessesPerPair,
+		PortsPerPair:      p.PortsPerPair,
+	}
+
+	if p.Burst != nil {
+		burst := *p.Burst
+		clone.Burst = &burst
+	}
+
+	if p.Capture != nil {
+		capture := *p.Capture
+		clone.Capture = &capture
 	}
 
 	if p.ExtraFlags != nil {
@@ -115,7 +160,7 @@ func (p *TestProfile) ToCommandArgs() []string {
 	}
 
 	// Duration
-	args = append(args, "-t", fmt.Sprintf("%d", p.Duration))
+	args = append(args, "-t", units.FormatSeconds(p.Duration))
 
 	// Bandwidth
 	if p.Bandwidth != "" && p.Bandwidth != "0" {
@@ -127,13 +172,13 @@ func (p *TestProfile) ToCommandArgs() []string {
 		args = append(args, "-w", p.WindowSize)
 	}
 
-	// Parallel streams
-	if p.Parallel > 1 {
-		args = append(args, "-P", fmt.Sprintf("%d", p.Parallel))
+	// Parallel streams of each process
+	if p.StreamsPerProcess() > 1 {
+		args = append(args, "-P", fmt.Sprintf("%d", p.StreamsPerProcess()))
 	}
 
 	// Bidirectional
-	if p.Bidirectional {
+	if p.RunsBidir() {
 		args = append(args, "--bidir")
 	}
 
@@ -197,7 +242,7 @@ func (p *TestProfile) ToCommandArgs() []string {
 // String returns a string representation of the profile
 func (p *TestProfile) String() string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Profile{Name: %s, Duration: %ds", p.Name, p.Duration))
+	sb.WriteString(fmt.Sprintf("Profile{Name: %s, Duration: %ss", p.Name, units.FormatSeconds(p.Duration)))
 
 	if p.Bandwidth != "" {
 		sb.WriteString(fmt.Sprintf(", Bandwidth: %s", p.Bandwidth))
@@ -209,6 +254,21 @@ func (p *TestProfile) String() string {
 
 	if p.Bidirectional {
 		sb.WriteString(", Bidirectional")
+		if p.SeparateReverse {
+			sb.WriteString(" (separate reverse)")
+		}
+	}
+
+	if p.ProcessesPerPair > 1 {
+		sb.WriteString(fmt.Sprintf(", Processes: %d", p.ProcessesPerPair))
+	}
+
+	if p.PortsPerPair > 1 {
+		sb.WriteString(fmt.Sprintf(", Ports: %d", p.PortsPerPair))
+	}
+
+	if p.Burst != nil {
+		sb.WriteString(fmt.Sprintf(", Burst: %dx%dms/%dms", p.Burst.Repeat, p.Burst.OnMs, p.Burst.OffMs))
 	}
 
 	sb.WriteString("}")
@@ -217,13 +277,101 @@ func (p *TestProfile) String() string {
 
 // Validate checks if the profile is valid
 func (p *TestProfile) Validate() error {
-	if p.Duration < 1 {
-		return fmt.Errorf("duration must be at least 1 second")
+	if p.Duration < MinDuration && p.Burst == nil {
+		return fmt.Errorf("duration must be at least %s seconds", units.FormatSeconds(MinDuration))
 	}
 
 	if p.Parallel < 1 {
 		return fmt.Errorf("parallel must be at least 1")
 	}
 
+	if p.ProcessesPerPair < 0 {
+		return fmt.Errorf("processes_per_pair cannot be negative")
+	}
+
+	if err := CheckPortsPerPair(p.PortsPerPair, p.ProcessesPerPair, p.Parallel); err != nil {
+		return err
+	}
+
+	if p.Burst != nil {
+		if p.Protocol != ProtocolUDP {
+			return fmt.Errorf("burst requires the udp protocol")
+		}
+		if p.Burst.OnMs < 1 || p.Burst.OffMs < 0 || p.Burst.Repeat < 1 {
+			return fmt.Errorf("burst requires on_ms >= 1, off_ms >= 0 and repeat >= 1")
+		}
+	}
+
+	return nil
+}
+
+// Schedule returns how long a test with this profile runs. Burst profiles
+// run for their whole burst schedule.
+func (p *TestProfile) Schedule() time.Duration {
+	if p.Burst != nil {
+		return p.Burst.Length()
+	}
+	return p.Duration
+}
+
+// ScheduleSeconds returns Schedule rounded up to whole seconds
+func (p *TestProfile) ScheduleSeconds() int {
+	return int(math.Ceil(p.Schedule().Seconds()))
+}
+
+// ProcessCount returns the number of iperf3 processes to run per pair
+func (p *TestProfile) ProcessCount() int {
+	if p.PortsPerPair > 1 {
+		return p.PortsPerPair
+	}
+	if p.ProcessesPerPair < 1 {
+		return 1
+	}
+	return p.ProcessesPerPair
+}
+
+// StreamsPerProcess returns the parallel streams each iperf3 process of a
+// pair runs: all of Parallel, unless PortsPerPair splits them evenly among
+// the pair's processes
+func (p *TestProfile) StreamsPerProcess() int {
+	if p.PortsPerPair > 1 {
+		return p.Parallel / p.PortsPerPair
+	}
+	return p.Parallel
+}
+
+// CheckPortsPerPair checks that ports server ports per pair can split
+// parallel streams evenly, one process per port, and are not combined with
+// processes_per_pair
+func CheckPortsPerPair(ports, processes, parallel int) error {
+	if ports < 0 {
+		return fmt.Errorf("ports_per_pair cannot be negative")
+	}
+	if ports <= 1 {
+		return nil
+	}
+	if processes > 1 {
+		return fmt.Errorf("ports_per_pair cannot be combined with processes_per_pair")
+	}
+	if parallel%ports != 0 {
+		return fmt.Errorf("parallel %d must be a multiple of ports_per_pair %d, so each port runs as many streams", parallel, ports)
+	}
 	return nil
 }
+
+// RunsBidir reports whether the profile's tests run as iperf3 --bidir. With
+// SeparateReverse a bidirectional profile runs each direction as a test of
+// its own instead.
+func (p *TestProfile) RunsBidir() bool {
+	return p.Bidirectional && !p.SeparateReverse
+}
+
+// Weight returns the work a pair puts on each of its nodes in capacity
+// planning, in processes carrying traffic one way. Bidirectional processes
+// send and receive on both nodes at once, so they count twice.
+func (p *TestProfile) Weight() int {
+	if p.RunsBidir() {
+		return 2 * p.ProcessCount()
+	}
+	return p.ProcessCount()
+}
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag expression operators. As bare tags they are reserved.
+const (
+	tagAnd = "and"
+	tagOr  = "or"
+	tagNot = "not"
+)
+
+// NormalizeTag trims surrounding space from a tag and, with lowercase set,
+// lowercases it
+func NormalizeTag(tag string, lowercase bool) string {
+	tag = strings.TrimSpace(tag)
+	if lowercase {
+		tag = strings.ToLower(tag)
+	}
+	return tag
+}
+
+// ValidateTag checks that a tag is a bare label, such as "prod", or a
+// key:value pair, such as "zone:us-east". Keys, values and labels are made
+// of letters, digits, '.', '_', '-' and '/', and start with a letter or
+// digit. The expression operators and, or and not are reserved labels.
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("tag cannot be empty")
+	}
+	key, value, isPair := strings.Cut(tag, ":")
+	if !isPair {
+		if isTagOperator(tag) {
+			return fmt.Errorf("%q is reserved for tag expressions", tag)
+		}
+		return validateTagPart(tag)
+	}
+	if err := validateTagPart(key); err != nil {
+		return fmt.Errorf("key: %w", err)
+	}
+	if err := validateTagPart(value); err != nil {
+		return fmt.Errorf("value: %w", err)
+	}
+	return nil
+}
+
+// validateTagPart checks the characters of a label, key or value
+func validateTagPart(part string) error {
+	if part == "" {
+		return fmt.Errorf("cannot be empty")
+	}
+	for i, r := range part {
+		alphanumeric := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if i == 0 && !alphanumeric {
+			return fmt.Errorf("must start with a letter or digit")
+		}
+		if !alphanumeric && !strings.ContainsRune("._-/", r) {
+			return fmt.Errorf("invalid character %q", r)
+		}
+	}
+	return nil
+}
+
+// isTagOperator reports whether word is an expression operator
+func isTagOperator(word string) bool {
+	switch strings.ToLower(word) {
+	case tagAnd, tagOr, tagNot:
+		return true
+	}
+	return false
+}
+
+// TagExpr is a boolean expression over node tags, such as
+// "zone:us-east and not (rack:a or maintenance)". Operators are
+// case-insensitive; not binds tighter than and, and tighter than or.
+type TagExpr struct {
+	op       string // tagAnd, tagOr, tagNot, or empty for a tag
+	tag      string
+	operands []*TagExpr
+}
+
+// ParseTagExpr parses a tag expression. Tags in it are normalized like node
+// tags, with lowercase lowercasing them.
+func ParseTagExpr(text string, lowercase bool) (*TagExpr, error) {
+	p := &tagParser{tokens: tokenizeTagExpr(text), lowercase: lowercase}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("tag expression is empty")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("tag expression %q: %w", text, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("tag expression %q: unexpected %q", text, p.tokens[p.pos])
+	}
+	return expr, nil
+}
+
+// Matches reports whether a node with tags satisfies the expression
+func (e *TagExpr) Matches(tags []string) bool {
+	switch e.op {
+	case tagNot:
+		return !e.operands[0].Matches(tags)
+	case tagAnd:
+		for _, operand := range e.operands {
+			if !operand.Matches(tags) {
+				return false
+			}
+		}
+		return true
+	case tagOr:
+		for _, operand := range e.operands {
+			if operand.Matches(tags) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, tag := range tags {
+		if tag == e.tag {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the expression with explicit parentheses
+func (e *TagExpr) String() string {
+	switch e.op {
+	case tagNot:
+		return "not " + e.operands[0].String()
+	case tagAnd, tagOr:
+		parts := make([]string, 0, len(e.operands))
+		for _, operand := range e.operands {
+			parts = append(parts, operand.String())
+		}
+		return "(" + strings.Join(parts, " "+e.op+" ") + ")"
+	}
+	return e.tag
+}
+
+// MatchesAnyTagExpr reports whether tags satisfy any of exprs
+func MatchesAnyTagExpr(exprs []*TagExpr, tags []string) bool {
+	for _, expr := range exprs {
+		if expr.Matches(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenizeTagExpr splits an expression into words and parentheses
+func tokenizeTagExpr(text string) []string {
+	text = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(text)
+	return strings.Fields(text)
+}
+
+// tagParser is a recursive descent parser over expression tokens
+type tagParser struct {
+	tokens    []string
+	pos       int
+	lowercase bool
+}
+
+// peek returns the next token, lowercased, or "" at the end
+func (p *tagParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return strings.ToLower(p.tokens[p.pos])
+}
+
+// parseOr parses operands joined by or
+func (p *tagParser) parseOr() (*TagExpr, error) {
+	return p.parseJoined(tagOr, p.parseAnd)
+}
+
+// parseAnd parses operands joined by and
+func (p *tagParser) parseAnd() (*TagExpr, error) {
+	return p.parseJoined(tagAnd, p.parseUnary)
+}
+
+// parseJoined parses one or more operands joined by op
+func (p *tagParser) parseJoined(op string, operand func() (*TagExpr, error)) (*TagExpr, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*TagExpr{first}
+	for p.peek() == op {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, next)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &TagExpr{op: op, operands: operands}, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a tag
+func (p *tagParser) parseUnary() (*TagExpr, error) {
+	token := p.peek()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end")
+	case token == tagNot:
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &TagExpr{op: tagNot, operands: []*TagExpr{operand}}, nil
+	case token == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return expr, nil
+	case token == ")" || isTagOperator(token):
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+
+	tag := NormalizeTag(p.tokens[p.pos], p.lowercase)
+	if err := ValidateTag(tag); err != nil {
+		return nil, fmt.Errorf("tag %q: %w", tag, err)
+	}
+	p.pos++
+	return &TagExpr{tag: tag}, nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestParseTagExpr(t *testing.T) {
+	tags := []string{"zone:us-east", "rack:a", "prod"}
+
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"prod", true},
+		{"dev", false},
+		{"zone:us-east and rack:a", true},
+		{"zone:us-east AND rack:b", false},
+		{"rack:b or prod", true},
+		{"not dev", true},
+		{"zone:us-east and not rack:a", false},
+		{"dev or prod and rack:a", true},
+		{"(dev or prod) and not (rack:a or rack:b)", false},
+		{"not not prod", true},
+	}
+
+	for _, tt := range tests {
+		expr, err := ParseTagExpr(tt.expr, false)
+		if err != nil {
+			t.Errorf("ParseTagExpr(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := expr.Matches(tags); got != tt.want {
+			t.Errorf("%q (%s) matches = %v, want %v", tt.expr, expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseTagExpr_Lowercase(t *testing.T) {
+	expr, err := ParseTagExpr("Zone:US-East", true)
+	if err != nil {
+		t.Fatalf("ParseTagExpr() error = %v", err)
+	}
+	if !expr.Matches([]string{"zone:us-east"}) {
+		t.Errorf("%s does not match zone:us-east", expr)
+	}
+}
+
+func TestParseTagExpr_Invalid(t *testing.T) {
+	for _, text := range []string{"", "prod and", "(prod", "prod)", "and prod", "not", "zone:", "bad!tag", "prod rack:a"} {
+		if _, err := ParseTagExpr(text, false); err == nil {
+			t.Errorf("ParseTagExpr(%q) expected error", text)
+		}
+	}
+}
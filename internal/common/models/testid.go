@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SubTestSeparator separates a logical test ID from its process index
+const SubTestSeparator = "#"
+
+// SubTestID returns the ID of a single process within a multi-process test
+func SubTestID(testID string, index int) string {
+	return fmt.Sprintf("%s%s%d", testID, SubTestSeparator, index)
+}
+
+// SplitSubTestID splits a sub-test ID into its logical test ID and process index.
+// The boolean result is false when the ID does not refer to a sub-test.
+func SplitSubTestID(id string) (string, int, bool) {
+	idx := strings.LastIndex(id, SubTestSeparator)
+	if idx < 0 {
+		return id, 0, false
+	}
+
+	index, err := strconv.Atoi(id[idx+1:])
+	if err != nil || index < 0 {
+		return id, 0, false
+	}
+
+	return id[:idx], index, true
+}
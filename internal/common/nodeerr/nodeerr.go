@@ -0,0 +1,158 @@
+// Package nodeerr collects the per-node failures of an operation fanned out
+// across many nodes into a single error that keeps track of which node failed
+// and why.
+package nodeerr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// NodeError is the failure of an operation on one node
+type NodeError struct {
+	NodeID string
+	Op     string
+	Err    error
+}
+
+// Error formats the failure as "node <id>: <error>"
+func (e *NodeError) Error() string {
+	return fmt.Sprintf("node %s: %v", e.NodeID, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// nodeErrorJSON is the serialized form of a NodeError
+type nodeErrorJSON struct {
+	NodeID string `json:"node_id"`
+	Op     string `json:"op,omitempty"`
+	Error  string `json:"error"`
+}
+
+// MarshalJSON encodes the error as its node, operation and message
+func (e *NodeError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeErrorJSON{NodeID: e.NodeID, Op: e.Op, Error: e.Err.Error()})
+}
+
+// UnmarshalJSON decodes an error written by MarshalJSON. The underlying
+// error only keeps its message.
+func (e *NodeError) UnmarshalJSON(data []byte) error {
+	var decoded nodeErrorJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	e.NodeID = decoded.NodeID
+	e.Op = decoded.Op
+	e.Err = errors.New(decoded.Error)
+	return nil
+}
+
+// MultiNodeError is the failure of an operation on one or more nodes.
+// errors.Is and errors.As look through each node's error.
+type MultiNodeError struct {
+	Op     string       `json:"op"`
+	Errors []*NodeError `json:"errors"`
+}
+
+// New returns an empty error for op, to be filled with Add
+func New(op string) *MultiNodeError {
+	return &MultiNodeError{Op: op}
+}
+
+// Add records that the operation failed on nodeID
+func (e *MultiNodeError) Add(nodeID string, err error) {
+	e.Errors = append(e.Errors, &NodeError{NodeID: nodeID, Op: e.Op, Err: err})
+}
+
+// Addf records a failure on nodeID with a formatted message
+func (e *MultiNodeError) Addf(nodeID, format string, args ...interface{}) {
+	e.Add(nodeID, fmt.Errorf(format, args...))
+}
+
+// Len returns the number of failed nodes
+func (e *MultiNodeError) Len() int {
+	return len(e.Errors)
+}
+
+// ErrorOrNil returns e if any node failed and nil otherwise, so that an
+// empty MultiNodeError is never returned as a non-nil error
+func (e *MultiNodeError) ErrorOrNil() error {
+	if e == nil || len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error formats the failures as "<op> failed on N nodes: node a: ...; node b: ..."
+func (e *MultiNodeError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, nodeErr := range e.Errors {
+		messages = append(messages, nodeErr.Error())
+	}
+	noun := "nodes"
+	if len(e.Errors) == 1 {
+		noun = "node"
+	}
+	return fmt.Sprintf("%s failed on %d %s: %s", e.Op, len(e.Errors), noun, strings.Join(messages, "; "))
+}
+
+// Unwrap returns each node's error, for errors.Is and errors.As
+func (e *MultiNodeError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, nodeErr := range e.Errors {
+		errs = append(errs, nodeErr)
+	}
+	return errs
+}
+
+// Nodes returns the IDs of the failed nodes, sorted
+func (e *MultiNodeError) Nodes() []string {
+	nodes := make([]string, 0, len(e.Errors))
+	for _, nodeErr := range e.Errors {
+		nodes = append(nodes, nodeErr.NodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Failed reports whether the operation failed on nodeID
+func (e *MultiNodeError) Failed(nodeID string) bool {
+	for _, nodeErr := range e.Errors {
+		if nodeErr.NodeID == nodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// Table formats the failures as a table with one row per node, sorted by
+// node ID
+func (e *MultiNodeError) Table() string {
+	rows := append([]*NodeError(nil), e.Errors...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].NodeID < rows[j].NodeID })
+
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tOPERATION\tERROR")
+	for _, nodeErr := range rows {
+		fmt.Fprintf(w, "%s\t%s\t%v\n", nodeErr.NodeID, nodeErr.Op, nodeErr.Err)
+	}
+	_ = w.Flush()
+	return b.String()
+}
+
+// As returns the MultiNodeError in err's chain, if there is one
+func As(err error) (*MultiNodeError, bool) {
+	var multi *MultiNodeError
+	if errors.As(err, &multi) {
+		return multi, true
+	}
+	return nil, false
+}
@@ -0,0 +1,75 @@
+package nodeerr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMultiNodeError(t *testing.T) {
+	failures := New("preparation")
+	if failures.ErrorOrNil() != nil {
+		t.Fatal("ErrorOrNil() of an empty error is not nil")
+	}
+
+	failures.Add("node9", fmt.Errorf("dial: %w", context.DeadlineExceeded))
+	failures.Addf("node3", "%s", "not enough ports")
+	err := failures.ErrorOrNil()
+	if err == nil {
+		t.Fatal("ErrorOrNil() = nil, want error")
+	}
+
+	want := "preparation failed on 2 nodes: node node9: dial: context deadline exceeded; node node3: not enough ports"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if got := strings.Join(failures.Nodes(), ","); got != "node3,node9" {
+		t.Errorf("Nodes() = %s, want node3,node9", got)
+	}
+	if !failures.Failed("node3") || failures.Failed("node1") {
+		t.Error("Failed() does not match the added nodes")
+	}
+
+	wrapped := fmt.Errorf("preparation phase failed: %w", err)
+	if !errors.Is(wrapped, context.DeadlineExceeded) {
+		t.Error("errors.Is() does not find a node's error")
+	}
+	var nodeErr *NodeError
+	if !errors.As(wrapped, &nodeErr) || nodeErr.NodeID != "node9" {
+		t.Errorf("errors.As() NodeError = %v, want node9", nodeErr)
+	}
+	if multi, ok := As(wrapped); !ok || multi != failures {
+		t.Error("As() does not find the MultiNodeError")
+	}
+
+	table := failures.Table()
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "node3") || !strings.HasPrefix(lines[2], "node9") {
+		t.Errorf("Table() = %q, want a header and a row per node sorted by node", table)
+	}
+}
+
+func TestMultiNodeError_JSON(t *testing.T) {
+	failures := New("server start")
+	failures.Add("node1", errors.New("address in use"))
+
+	data, err := json.Marshal(failures)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"op":"server start","errors":[{"node_id":"node1","op":"server start","error":"address in use"}]}`
+	if string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded MultiNodeError
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if decoded.Error() != failures.Error() {
+		t.Errorf("decoded Error() = %q, want %q", decoded.Error(), failures.Error())
+	}
+}
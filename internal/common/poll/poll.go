@@ -0,0 +1,191 @@
+// Package poll paces periodic polling of many targets. Intervals are
+// jittered so that pollers started together drift apart, targets whose polls
+// fail are backed off exponentially, and the rate of polls across all
+// targets can be bounded. It has no controller dependencies, so that daemons
+// can pace their own periodic calls with it too.
+package poll
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultBase is the backoff step used before any interval was requested
+const defaultBase = time.Second
+
+// Policy controls how a Pacer spaces polls
+type Policy struct {
+	Jitter     float64       // Each interval varies by up to this fraction either way, 0-1
+	MaxBackoff time.Duration // Longest a failing target is left unpolled; 0 disables backoff
+	MaxRate    float64       // Polls per second across all targets; 0 is unbounded
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{Jitter: 0.2, MaxBackoff: 30 * time.Second}
+}
+
+// Stats counts what a Pacer did
+type Stats struct {
+	Polls     int64 `json:"polls"`
+	Failures  int64 `json:"failures"`
+	Skipped   int64 `json:"skipped"`   // Polls left out while their target backed off
+	Stretched int64 `json:"stretched"` // Intervals lengthened to stay under the rate bound
+	// BackedOff lists the targets backing off when the stats were taken
+	BackedOff []string `json:"backed_off,omitempty"`
+}
+
+// Pacer spaces the polls of a set of targets. It is safe for concurrent use.
+type Pacer struct {
+	policy Policy
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	base    time.Duration // Base interval last requested, the backoff step
+	targets map[string]*target
+	stats   Stats
+}
+
+// target is the backoff state of one polled target
+type target struct {
+	failures int       // Consecutive failed polls
+	next     time.Time // Not polled before this while failing
+}
+
+// NewPacer creates a pacer following policy
+func NewPacer(policy Policy) *Pacer {
+	return &Pacer{
+		policy:  policy,
+		rng:     rand.New(rand.NewSource(time.Now().UnixNano())), // #nosec G404 -- Jitter needs no cryptographic randomness
+		targets: make(map[string]*target),
+	}
+}
+
+// Interval returns how long to wait before the next round of polls for a
+// base interval: jittered, and stretched so that polling every known
+// target once per round stays under the rate bound
+func (p *Pacer) Interval(base time.Duration) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.base = base
+	interval := base
+	if jitter := min(max(p.policy.Jitter, 0), 1); jitter > 0 {
+		interval = time.Duration(float64(base) * (1 + jitter*(2*p.rng.Float64()-1)))
+	}
+	if p.policy.MaxRate > 0 && len(p.targets) > 0 {
+		floor := time.Duration(float64(len(p.targets)) / p.policy.MaxRate * float64(time.Second))
+		if interval < floor {
+			interval = floor
+			p.stats.Stretched++
+		}
+	}
+	return interval
+}
+
+// Due reports whether target should be polled at now. Targets backing off
+// after failed polls are not due until their backoff expires.
+func (p *Pacer) Due(name string, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.target(name)
+	if t.failures > 0 && now.Before(t.next) {
+		p.stats.Skipped++
+		return false
+	}
+	return true
+}
+
+// Record records the outcome of polling target at now. Each consecutive
+// failure doubles how long the target is left unpolled, starting from two
+// base intervals, up to the policy's MaxBackoff; a success clears it.
+func (p *Pacer) Record(name string, err error, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t := p.target(name)
+	p.stats.Polls++
+	if err == nil {
+		t.failures = 0
+		return
+	}
+	p.stats.Failures++
+	t.failures++
+	if p.policy.MaxBackoff <= 0 {
+		return
+	}
+	base := p.base
+	if base <= 0 {
+		base = defaultBase
+	}
+	backoff := p.policy.MaxBackoff
+	if t.failures < 32 {
+		backoff = min(base<<t.failures, p.policy.MaxBackoff)
+	}
+	t.next = now.Add(backoff)
+}
+
+// Stats returns what the pacer has done so far
+func (p *Pacer) Stats() Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := p.stats
+	stats.BackedOff = nil
+	for name, t := range p.targets {
+		if t.failures > 0 && p.policy.MaxBackoff > 0 {
+			stats.BackedOff = append(stats.BackedOff, name)
+		}
+	}
+	sort.Strings(stats.BackedOff)
+	return stats
+}
+
+// target returns the state of a target, adding it; the caller must hold p.mu
+func (p *Pacer) target(name string) *target {
+	t, exists := p.targets[name]
+	if !exists {
+		t = &target{}
+		p.targets[name] = t
+	}
+	return t
+}
+
+// Ticker delivers ticks like time.Ticker, but waits a fresh Interval of its
+// pacer between ticks. Ticks are dropped while the receiver is busy.
+type Ticker struct {
+	C <-chan time.Time
+
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewTicker starts a ticker around a base interval
+func (p *Pacer) NewTicker(base time.Duration) *Ticker {
+	c := make(chan time.Time, 1)
+	t := &Ticker{C: c, stop: make(chan struct{})}
+	go func() {
+		for {
+			timer := time.NewTimer(p.Interval(base))
+			select {
+			case <-t.stop:
+				timer.Stop()
+				return
+			case now := <-timer.C:
+				select {
+				case c <- now:
+				default:
+				}
+			}
+		}
+	}()
+	return t
+}
+
+// Stop ends the ticker. A tick already delivered stays in C.
+func (t *Ticker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
@@ -0,0 +1,81 @@
+package poll
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPacer_Interval(t *testing.T) {
+	p := NewPacer(Policy{Jitter: 0.2})
+	base := time.Second
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		interval := p.Interval(base)
+		if interval < 800*time.Millisecond || interval > 1200*time.Millisecond {
+			t.Fatalf("Interval() = %v, want within 20%% of %v", interval, base)
+		}
+		seen[interval] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Interval() returned %d distinct values, want jitter", len(seen))
+	}
+
+	// Ten targets at 2 polls per second need at least 5s per round
+	p = NewPacer(Policy{MaxRate: 2})
+	for i := 0; i < 10; i++ {
+		p.Due(string(rune('a'+i)), time.Now())
+	}
+	if got := p.Interval(base); got != 5*time.Second {
+		t.Errorf("Interval() with rate bound = %v, want 5s", got)
+	}
+	if got := p.Stats().Stretched; got != 1 {
+		t.Errorf("Stretched = %d, want 1", got)
+	}
+}
+
+func TestPacer_Backoff(t *testing.T) {
+	p := NewPacer(Policy{MaxBackoff: 5 * time.Second})
+	p.Interval(time.Second)
+	now := time.Now()
+	failed := errors.New("unavailable")
+
+	// Each failure doubles the backoff, from two base intervals up to the cap
+	for _, want := range []time.Duration{2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+		if !p.Due("node1", now) {
+			t.Fatalf("node1 not due at the end of its backoff")
+		}
+		p.Record("node1", failed, now)
+		if p.Due("node1", now.Add(want-time.Millisecond)) {
+			t.Errorf("node1 due before its %v backoff ended", want)
+		}
+		now = now.Add(want)
+	}
+	if got := p.Stats().BackedOff; len(got) != 1 || got[0] != "node1" {
+		t.Errorf("BackedOff = %v, want [node1]", got)
+	}
+
+	// A success clears the backoff
+	p.Record("node1", nil, now)
+	if !p.Due("node1", now) {
+		t.Errorf("node1 not due after a successful poll")
+	}
+	stats := p.Stats()
+	if stats.Polls != 5 || stats.Failures != 4 || stats.Skipped != 4 || len(stats.BackedOff) != 0 {
+		t.Errorf("Stats() = %+v, want 5 polls, 4 failures, 4 skipped and none backed off", stats)
+	}
+}
+
+func TestTicker(t *testing.T) {
+	p := NewPacer(Policy{Jitter: 0.5})
+	ticker := p.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ticker.C:
+		case <-time.After(time.Second):
+			t.Fatalf("no tick %d within 1s", i)
+		}
+	}
+}
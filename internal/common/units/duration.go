@@ -0,0 +1,19 @@
+package units
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// Seconds converts fractional seconds, as written in configuration, to a
+// duration rounded to the millisecond
+func Seconds(seconds float64) time.Duration {
+	return time.Duration(math.Round(seconds*1000)) * time.Millisecond
+}
+
+// FormatSeconds formats d as seconds without trailing zeros, the form iperf3
+// accepts for -t, e.g. "10" or "0.5"
+func FormatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
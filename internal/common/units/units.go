@@ -0,0 +1,144 @@
+// Package units parses and formats bit rates, byte sizes and durations.
+//
+// The grammar is a number followed by an optional prefix K, M, G or T (case
+// insensitive), an optional "i" marking a binary (1024-based) prefix, and an
+// optional unit: "bps" or "bit/s" for rates, "B" for sizes. Whitespace may
+// separate the number from its prefix. Following iperf3, plain prefixes are
+// decimal for rates ("10G" is 10,000,000,000 bps) and binary for sizes
+// ("416K" is 425,984 bytes).
+package units
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Decimal bit rate multipliers
+const (
+	Kbps = 1e3
+	Mbps = 1e6
+	Gbps = 1e9
+	Tbps = 1e12
+)
+
+// prefixExponents maps a prefix letter to its power of 1000 or 1024
+var prefixExponents = map[byte]float64{
+	'k': 1,
+	'm': 2,
+	'g': 3,
+	't': 4,
+}
+
+// quantity is a parsed number with its prefix
+type quantity struct {
+	value  float64
+	exp    float64 // Prefix exponent, 0 when there is no prefix
+	binary bool    // Prefix carried an explicit "i"
+}
+
+// parse splits s into number, prefix and binary marker after removing any of
+// the given unit suffixes
+func parse(s string, unitSuffixes ...string) (quantity, error) {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return quantity{}, fmt.Errorf("empty value")
+	}
+
+	lower := strings.ToLower(text)
+	for _, suffix := range unitSuffixes {
+		if strings.HasSuffix(lower, suffix) && len(lower) > len(suffix) {
+			lower = strings.TrimSpace(lower[:len(lower)-len(suffix)])
+			break
+		}
+	}
+
+	q := quantity{}
+	if n := len(lower); n >= 2 && lower[n-1] == 'i' {
+		if exp, ok := prefixExponents[lower[n-2]]; ok {
+			q.exp = exp
+			q.binary = true
+			lower = lower[:n-2]
+		}
+	} else if n >= 1 {
+		if exp, ok := prefixExponents[lower[n-1]]; ok {
+			q.exp = exp
+			lower = lower[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(lower), 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return quantity{}, fmt.Errorf("invalid number in %q", s)
+	}
+	if value < 0 {
+		return quantity{}, fmt.Errorf("negative value %q", s)
+	}
+	q.value = value
+
+	return q, nil
+}
+
+// multiplier returns the prefix multiplier, using base for plain prefixes
+func (q quantity) multiplier(base float64) float64 {
+	if q.binary {
+		base = 1024
+	}
+	return math.Pow(base, q.exp)
+}
+
+// ParseBitRate parses a bit rate such as "9.5G", "100Mbps" or "1Gi" into bits
+// per second. Plain prefixes are decimal; Ki/Mi/Gi/Ti are binary.
+func ParseBitRate(s string) (float64, error) {
+	q, err := parse(s, "bit/s", "bps")
+	if err != nil {
+		return 0, fmt.Errorf("invalid bit rate: %w", err)
+	}
+	return q.value * q.multiplier(1000), nil
+}
+
+// ParseBytes parses a size such as "416K", "2MiB" or "1500" into bytes.
+// Prefixes are binary, as iperf3 uses for sizes.
+func ParseBytes(s string) (int64, error) {
+	q, err := parse(s, "b")
+	if err != nil {
+		return 0, fmt.Errorf("invalid size: %w", err)
+	}
+
+	bytes := q.value * q.multiplier(1024)
+	if bytes > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size: %q is too large", s)
+	}
+	return int64(bytes), nil
+}
+
+// ScaleBitRate returns bps expressed in the largest decimal unit that keeps
+// the value at or above 1, along with the unit name
+func ScaleBitRate(bps float64) (float64, string) {
+	switch {
+	case bps >= Tbps:
+		return bps / Tbps, "Tbps"
+	case bps >= Gbps:
+		return bps / Gbps, "Gbps"
+	case bps >= Mbps:
+		return bps / Mbps, "Mbps"
+	case bps >= Kbps:
+		return bps / Kbps, "Kbps"
+	default:
+		return bps, "bps"
+	}
+}
+
+// FormatBitRate formats bps in an automatically chosen decimal unit, e.g.
+// FormatBitRate(9.5e9, 2) returns "9.50 Gbps"
+func FormatBitRate(bps float64, precision int) string {
+	value, unit := ScaleBitRate(bps)
+	return strconv.FormatFloat(value, 'f', precision, 64) + " " + unit
+}
+
+// FormatIn formats bps in a fixed unit without a suffix, e.g.
+// FormatIn(9.5e9, units.Mbps, 2) returns "9500.00"
+func FormatIn(bps, unit float64, precision int) string {
+	return strconv.FormatFloat(bps/unit, 'f', precision, 64)
+}
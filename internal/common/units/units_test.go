@@ -0,0 +1,158 @@
+package units
+
+import "testing"
+
+func TestParseBitRate(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"1500", 1500, false},
+		{"1.5k", 1500, false},
+		{"1.5K", 1500, false},
+		{"100M", 100e6, false},
+		{"100m", 100e6, false},
+		{"9.5G", 9.5e9, false},
+		{"10g", 10e9, false},
+		{"1T", 1e12, false},
+		{"1Ki", 1024, false},
+		{"1ki", 1024, false},
+		{"1Mi", 1024 * 1024, false},
+		{"2Gi", 2 * 1024 * 1024 * 1024, false},
+		{"1Ti", 1024 * 1024 * 1024 * 1024, false},
+		{"100Mbps", 100e6, false},
+		{"100mbps", 100e6, false},
+		{"1Gibps", 1024 * 1024 * 1024, false},
+		{"10 Gbps", 10e9, false},
+		{"25Gbit/s", 25e9, false},
+		{" 5M ", 5e6, false},
+		{"", 0, true},
+		{"G", 0, true},
+		{"bps", 0, true},
+		{"abc", 0, true},
+		{"-1M", 0, true},
+		{"10X", 0, true},
+		{"10Mb", 0, true},
+		{"1Xi", 0, true},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBitRate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBitRate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseBitRate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"1500", 1500, false},
+		{"1500B", 1500, false},
+		{"416K", 416 * 1024, false},
+		{"416k", 416 * 1024, false},
+		{"416KB", 416 * 1024, false},
+		{"416Ki", 416 * 1024, false},
+		{"416KiB", 416 * 1024, false},
+		{"2M", 2 * 1024 * 1024, false},
+		{"2MiB", 2 * 1024 * 1024, false},
+		{"1G", 1024 * 1024 * 1024, false},
+		{"1g", 1024 * 1024 * 1024, false},
+		{"1T", 1024 * 1024 * 1024 * 1024, false},
+		{"0.5K", 512, false},
+		{"", 0, true},
+		{"K", 0, true},
+		{"-1K", 0, true},
+		{"12Q", 0, true},
+		{"1e30T", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBytes(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseBytes(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBitRate(t *testing.T) {
+	tests := []struct {
+		bps       float64
+		precision int
+		want      string
+	}{
+		{0, 2, "0.00 bps"},
+		{999, 0, "999 bps"},
+		{1000, 1, "1.0 Kbps"},
+		{1.5e6, 2, "1.50 Mbps"},
+		{9.5e9, 2, "9.50 Gbps"},
+		{9.999e9, 2, "10.00 Gbps"},
+		{2.5e12, 1, "2.5 Tbps"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatBitRate(tt.bps, tt.precision); got != tt.want {
+			t.Errorf("FormatBitRate(%v, %d) = %q, want %q", tt.bps, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIn(t *testing.T) {
+	if got := FormatIn(9.5e9, Mbps, 2); got != "9500.00" {
+		t.Errorf("FormatIn(9.5e9, Mbps, 2) = %q, want %q", got, "9500.00")
+	}
+	if got := FormatIn(9.5e9, Gbps, 4); got != "9.5000" {
+		t.Errorf("FormatIn(9.5e9, Gbps, 4) = %q, want %q", got, "9.5000")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, input := range []string{"1K", "10M", "9.5G", "1T"} {
+		bps, err := ParseBitRate(input)
+		if err != nil {
+			t.Fatalf("ParseBitRate(%q) error = %v", input, err)
+		}
+		back, err := ParseBitRate(FormatBitRate(bps, 3))
+		if err != nil {
+			t.Fatalf("ParseBitRate(FormatBitRate(%v)) error = %v", bps, err)
+		}
+		if back != bps {
+			t.Errorf("round trip of %q = %v, want %v", input, back, bps)
+		}
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{10, "10"},
+		{0.5, "0.5"},
+		{0.25, "0.25"},
+		{1.0004, "1"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatSeconds(Seconds(tt.seconds)); got != tt.want {
+			t.Errorf("FormatSeconds(Seconds(%v)) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package aggregator
+
+import (
+	"net"
+	"sort"
+)
+
+// addressesDiffer reports whether a connection reached a different address
+// than intended. Only IP addresses are compared; an intended hostname, or a
+// missing address, is never flagged.
+func addressesDiffer(intended, observed string) bool {
+	intendedIP := net.ParseIP(intended)
+	observedIP := net.ParseIP(observed)
+	if intendedIP == nil || observedIP == nil {
+		return false
+	}
+	return !intendedIP.Equal(observedIP)
+}
+
+// AddressMismatches returns the completed, measured results whose connection
+// reached a different address than intended, sorted by test ID
+func AddressMismatches(results []*TestResult) []*TestResult {
+	mismatches := make([]*TestResult, 0)
+	for _, result := range results {
+		if result.AddressMismatch && !result.Background && result.Status == "TEST_STATUS_COMPLETED" {
+			mismatches = append(mismatches, result)
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].TestID < mismatches[j].TestID
+	})
+	return mismatches
+}
@@ -4,17 +4,50 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
 	"github.com/bensons/iperf-cnc/internal/controller/client"
 )
 
 // TestResult represents an aggregated test result
 type TestResult struct {
-	TestID        string                 `json:"test_id"`
-	SourceNode    string                 `json:"source_node"`
-	DestNode      string                 `json:"dest_node"`
+	TestID     string `json:"test_id"`
+	SourceNode string `json:"source_node"`
+	DestNode   string `json:"dest_node"`
+	Profile    string `json:"profile,omitempty"` // Test profile the pair ran with
+	Group      string `json:"group,omitempty"`   // Topology group the test ran in
+	// GroupPair is the tag groups of the nodes of a mesh grouped by a tag
+	// key, such as a1->a2
+	GroupPair string `json:"group_pair,omitempty"`
+	// Labels are the labels of a pair imported from a plan file
+	Labels map[string]string `json:"labels,omitempty"`
+	// RepeatOf is the test ID of the repeated pair the test is a run of
+	RepeatOf string `json:"repeat_of,omitempty"`
+	// Interface is the name of the interface of both nodes a test of a
+	// per_interface topology ran over, such as frontend
+	Interface string `json:"interface,omitempty"`
+	// Hop is the hop of a chain topology the test measured, numbered from 1
+	// between its first two nodes; EndToEnd marks its first node sending to
+	// its last, across every hop
+	Hop      int  `json:"hop,omitempty"`
+	EndToEnd bool `json:"end_to_end,omitempty"`
+	// AddressFamily is the family, v4 or v6, of a test of a dual-stack
+	// topology. An IPv6 test's V6LagPercent is how far its throughput is
+	// below its IPv4 counterpart's, flagged above the threshold.
+	AddressFamily string                 `json:"address_family,omitempty"`
+	V6LagPercent  float64                `json:"v6_lag_percent,omitempty"`
+	V6LagFlagged  bool                   `json:"v6_lag_flagged,omitempty"`
+	Background    bool                   `json:"background,omitempty"` // Unmeasured load, excluded from the summary
+	Diagnostic    bool                   `json:"diagnostic,omitempty"` // Follow-up probe, not a measurement; excluded from the summary
+	RunID         string                 `json:"run_id,omitempty"`     // Run the daemon started the test in
 	Status        string                 `json:"status"`
 	StartTime     int64                  `json:"start_time"`
 	EndTime       int64                  `json:"end_time"`
@@ -23,6 +56,91 @@ type TestResult struct {
 	IperfData     map[string]interface{} `json:"iperf_data,omitempty"`
 	ThroughputBps float64                `json:"throughput_bps,omitempty"`
 	Retransmits   int64                  `json:"retransmits,omitempty"`
+	// Retransmits normalized by the data sent; the ratio is per TCP segment
+	// and only set when the MSS is known
+	BytesSent          int64   `json:"bytes_sent,omitempty"`
+	SegmentsSent       int64   `json:"segments_sent,omitempty"`
+	RetransmitsPerMB   float64 `json:"retransmits_per_mb,omitempty"`
+	RetransmitRatio    float64 `json:"retransmit_ratio,omitempty"`
+	RetransmitsFlagged bool    `json:"retransmits_flagged,omitempty"`
+	// Sender- and receiver-reported throughput; the receiver value is the
+	// canonical ThroughputBps when available
+	SenderThroughputBps   float64 `json:"sender_throughput_bps,omitempty"`
+	ReceiverThroughputBps float64 `json:"receiver_throughput_bps,omitempty"`
+	DiscrepancyPercent    float64 `json:"discrepancy_percent,omitempty"`
+	DiscrepancyFlagged    bool    `json:"discrepancy_flagged,omitempty"`
+	// ThroughputSource is set when the throughput was not read from the
+	// totals iperf3 reports for the role but from a fallback, "sum" or
+	// "streams", and is less certain
+	ThroughputSource string `json:"throughput_source,omitempty"`
+	// ReverseThroughputBps is the throughput of the reverse direction of a
+	// --bidir test
+	ReverseThroughputBps float64        `json:"reverse_throughput_bps,omitempty"`
+	SubResults           []*TestResult  `json:"sub_results,omitempty"` // Per-process results of a multi-process pair
+	Bursts               []*BurstResult `json:"bursts,omitempty"`      // Per-burst results of a burst profile
+	WorstBurst           *BurstResult   `json:"worst_burst,omitempty"`
+	// TCP sender statistics: per-stream values, the largest congestion window
+	// and mean RTT across streams, and the cwnd/RTT throughput bound summed
+	// over streams. LimitHint compares the throughput to that bound.
+	TCPStreams      []*TCPStreamStats `json:"tcp_streams,omitempty"`
+	MaxSndCwndBytes int64             `json:"max_snd_cwnd_bytes,omitempty"`
+	MeanRTTMs       float64           `json:"mean_rtt_ms,omitempty"`
+	WindowBoundBps  float64           `json:"window_bound_bps,omitempty"`
+	LimitHint       string            `json:"limit_hint,omitempty"`
+	// Requested per-target timing, used to align timelines across pairs
+	StartDelayMs int `json:"start_delay_ms,omitempty"`
+	// DeferredByBlackout is how much later than scheduled the test started,
+	// to stay clear of blackout windows of its nodes
+	DeferredByBlackout *BlackoutDeferral `json:"deferred_by_blackout,omitempty"`
+	// SetupMs is the time the client process ran outside iperf3's intervals,
+	// mostly connection setup, and SetupFlagged marks an outlier among the
+	// run's tests; for multi-process pairs it is the slowest process's
+	SetupMs                  float64 `json:"setup_ms,omitempty"`
+	SetupFlagged             bool    `json:"setup_flagged,omitempty"`
+	RequestedDurationSeconds float64 `json:"requested_duration_seconds,omitempty"`
+	// ControlAddress is the gRPC address of the daemon that returned the
+	// result. The data-plane addresses are the destination the client was
+	// asked to reach and the addresses its connection actually used.
+	ControlAddress    string `json:"control_address,omitempty"`
+	IntendedAddress   string `json:"intended_address,omitempty"`
+	DataLocalAddress  string `json:"data_local_address,omitempty"`
+	DataRemoteAddress string `json:"data_remote_address,omitempty"`
+	AddressMismatch   bool   `json:"address_mismatch,omitempty"` // The connection reached a different address than intended
+	// Congestion control algorithm requested with -C and the one iperf3
+	// reports the sender used
+	RequestedCongestionControl string `json:"requested_congestion_control,omitempty"`
+	CongestionControl          string `json:"congestion_control,omitempty"`
+	CongestionControlMismatch  bool   `json:"congestion_control_mismatch,omitempty"`
+	// DaemonFile is the copy of the result the daemon saved with
+	// save_daemon_results. Multi-process pairs have one per sub-result.
+	DaemonFile *DaemonFile `json:"daemon_file,omitempty"`
+	// Capture is the packet capture the daemon kept of the test, per the
+	// profile's capture_on
+	Capture *DaemonFile `json:"capture,omitempty"`
+	// SenderCPUPercent is the CPU usage iperf3 reports for its sending
+	// process. Confidence rates how far a completed measurement can be
+	// trusted, high, medium or low, from the hygiene rules listed in
+	// ConfidenceReasons.
+	SenderCPUPercent  float64  `json:"sender_cpu_percent,omitempty"`
+	Confidence        string   `json:"confidence,omitempty"`
+	ConfidenceReasons []string `json:"confidence_reasons,omitempty"`
+}
+
+// DaemonFile locates a result file saved on a daemon's host
+type DaemonFile struct {
+	Node string `json:"node"` // Node whose daemon saved the file and serves it
+	Host string `json:"host"` // Hostname the daemon reported
+	Path string `json:"path"`
+}
+
+// BurstResult contains the measurements of a single UDP burst
+type BurstResult struct {
+	Index         int     `json:"index"`
+	ThroughputBps float64 `json:"throughput_bps"`
+	JitterMs      float64 `json:"jitter_ms"`
+	LostPackets   int64   `json:"lost_packets"`
+	Packets       int64   `json:"packets"`
+	LostPercent   float64 `json:"lost_percent"`
 }
 
 // Summary contains aggregate statistics
@@ -34,25 +152,237 @@ type Summary struct {
 	MinThroughput    float64 `json:"min_throughput_bps"`
 	MaxThroughput    float64 `json:"max_throughput_bps"`
 	TotalRetransmits int64   `json:"total_retransmits"`
+	DiscrepantTests  int     `json:"discrepant_tests"`           // Tests whose sender and receiver throughput disagree
+	BackgroundTests  int     `json:"background_tests"`           // Background load tests, not counted in the figures above
+	DiagnosticTests  int     `json:"diagnostic_tests,omitempty"` // Follow-up probes, not counted either
+	ExcludedTests    int     `json:"excluded_tests,omitempty"`   // Mesh tests left out by topology exclusions, never run
+	// ReverseSkippedTests counts the mesh tests an undirected mesh left
+	// out, as the pair in the other direction was tested instead
+	ReverseSkippedTests int `json:"reverse_skipped_tests,omitempty"`
+	// BisectionBps is set for a bisection topology: the throughput of all
+	// its completed tests together, which ran at once between the halves
+	BisectionBps float64 `json:"bisection_bandwidth_bps,omitempty"`
+	// Retransmit rates per MB sent over completed tests, and the number of
+	// tests above the retransmit threshold
+	AvgRetransmitsPerMB float64 `json:"avg_retransmits_per_mb"`
+	MaxRetransmitsPerMB float64 `json:"max_retransmits_per_mb"`
+	HighRetransmitTests int     `json:"high_retransmit_tests"`
+	// AddressMismatchTests counts tests whose connection reached a different
+	// address than intended, such as through NAT or unexpected routing
+	AddressMismatchTests int `json:"address_mismatch_tests"`
+	// CongestionControlMismatchTests counts tests whose sender used another
+	// congestion control algorithm than requested
+	CongestionControlMismatchTests int `json:"congestion_control_mismatch_tests,omitempty"`
+	// Completed TCP tests whose throughput is close to their cwnd/RTT bound,
+	// and those well below it, which are more likely loss- or CPU-limited
+	WindowLimitedTests    int `json:"window_limited_tests"`
+	LossOrCPULimitedTests int `json:"loss_or_cpu_limited_tests"`
+	// Connection setup times of completed tests, in milliseconds, and the
+	// number of tests whose setup time is an outlier relative to the median
+	SetupMinMs        float64 `json:"setup_min_ms,omitempty"`
+	SetupAvgMs        float64 `json:"setup_avg_ms,omitempty"`
+	SetupP95Ms        float64 `json:"setup_p95_ms,omitempty"`
+	SetupOutlierTests int     `json:"setup_outlier_tests,omitempty"`
+	// Tests started later than scheduled for blackout windows of their
+	// nodes, and tests skipped for having to wait too long for them
+	BlackoutDeferredTests int `json:"blackout_deferred_tests,omitempty"`
+	BlackoutSkippedTests  int `json:"blackout_skipped_tests,omitempty"`
+	// Highest throughput of all tests together in one timeline bucket, and
+	// the Unix time that bucket started
+	PeakAggregateBps  float64 `json:"peak_aggregate_bps,omitempty"`
+	PeakAggregateTime float64 `json:"peak_aggregate_time,omitempty"`
+	// Completed tests by confidence
+	HighConfidenceTests   int `json:"high_confidence_tests"`
+	MediumConfidenceTests int `json:"medium_confidence_tests"`
+	LowConfidenceTests    int `json:"low_confidence_tests"`
+	// FilteredResults counts results left out of the output files by the
+	// output filters; the figures above always cover every result
+	FilteredResults int `json:"filtered_results,omitempty"`
+	// Groups summarizes each topology group separately, so that the same
+	// pair measured in different groups can be compared
+	Groups map[string]*GroupSummary `json:"groups,omitempty"`
+	// Families summarizes the tests of each address family of a dual-stack
+	// topology, and V6LaggingTests counts the IPv6 tests flagged for lagging
+	// their IPv4 counterparts
+	Families       map[string]*GroupSummary `json:"families,omitempty"`
+	V6LaggingTests int                      `json:"v6_lagging_tests,omitempty"`
+	// GroupPairs summarizes the tests of each tag group pair of a mesh
+	// grouped by a tag key, such as a1->a2 and a1->a1
+	GroupPairs map[string]*GroupSummary `json:"group_pairs,omitempty"`
+	// Interfaces summarizes the tests over each interface of a
+	// per_interface topology
+	Interfaces map[string]*GroupSummary `json:"interfaces,omitempty"`
 }
 
+// ForeignResults lists results returned by daemons for test IDs that are not
+// part of the expected topology, such as stale results or results of another
+// controller's run
+type ForeignResults struct {
+	Count   int            `json:"count"`
+	ByNode  map[string]int `json:"by_node"` // Node that returned them -> count
+	Results []*TestResult  `json:"results"`
+}
+
+// GroupSummary contains aggregate statistics for one topology group
+type GroupSummary struct {
+	TotalTests     int     `json:"total_tests"`
+	CompletedTests int     `json:"completed_tests"`
+	FailedTests    int     `json:"failed_tests"`
+	AvgThroughput  float64 `json:"avg_throughput_bps"`
+}
+
+// DefaultDiscrepancyThreshold is the default percentage by which sender and
+// receiver throughput may differ before a test is flagged
+const DefaultDiscrepancyThreshold = 5.0
+
 // Aggregator collects and aggregates results from all nodes
 type Aggregator struct {
-	results map[string]*TestResult
-	mu      sync.RWMutex
+	results              map[string]*TestResult
+	discrepancyThreshold float64
+	testGroups           map[string]string            // test ID -> group name
+	testProfiles         map[string]string            // test ID -> profile name
+	testFamilies         map[string]string            // test ID -> address family
+	testLabels           map[string]map[string]string // test ID -> labels
+	testGroupPairs       map[string]string            // test ID -> tag group pair
+	testInterfaces       map[string]string            // test ID -> interface name
+	testHops             map[string]int               // test ID -> chain hop; 0 for end to end
+	testRepetitions      map[string]string            // test ID -> test ID of the repeated pair
+	testDeferrals        map[string]*BlackoutDeferral // test ID -> blackout deferral
+	v6LagThreshold       float64                      // Percent; negative disables flagging
+	backgroundTests      map[string]bool
+	expectedTests        map[string]bool // nil accepts every test ID
+	foreign              map[string]*TestResult
+	foreignNodes         map[string]int
+	testSources          map[string]string // test ID -> source node
+	testDestinations     map[string]string // test ID -> destination node
+	collectionErrors     map[string]string // node ID -> error retrieving its results
+	retransmitThreshold  float64           // Retransmits per MB; 0 disables flagging
+	timelineBucket       time.Duration
+	clockOffsets         map[string]time.Duration  // node ID -> clock ahead of the controller's
+	samples              map[string][]*UsageSample // node ID -> resource usage samples
+	cpuThreshold         float64                   // CPU percent; 0 disables flagging
+	stream               *streamState              // nil keeps every result
+	bisection            bool                      // Sum throughput as the bisection bandwidth
+	confidencePolicy     ConfidencePolicy
+	mu                   sync.RWMutex
 }
 
 // NewAggregator creates a new result aggregator
 func NewAggregator() *Aggregator {
 	return &Aggregator{
-		results: make(map[string]*TestResult),
+		results:              make(map[string]*TestResult),
+		foreign:              make(map[string]*TestResult),
+		foreignNodes:         make(map[string]int),
+		collectionErrors:     make(map[string]string),
+		discrepancyThreshold: DefaultDiscrepancyThreshold,
+		v6LagThreshold:       DefaultV6LagThreshold,
+		confidencePolicy:     DefaultConfidencePolicy(),
 	}
 }
 
-// CollectResults collects results from all nodes via the client pool
+// SetDiscrepancyThreshold sets the sender/receiver disagreement percentage
+// above which a test is flagged
+func (a *Aggregator) SetDiscrepancyThreshold(percent float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.discrepancyThreshold = percent
+}
+
+// SetTestGroups sets the group of each test ID, used to tag results
+func (a *Aggregator) SetTestGroups(groups map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testGroups = groups
+}
+
+// SetTestProfiles sets the profile of each test ID, used to tag results
+func (a *Aggregator) SetTestProfiles(profiles map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testProfiles = profiles
+}
+
+// SetTestLabels sets the labels of each labelled test ID, used to tag
+// results
+func (a *Aggregator) SetTestLabels(labels map[string]map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testLabels = labels
+}
+
+// SetTestGroupPairs sets the tag group pair of each test ID of a mesh
+// grouped by a tag key, used to tag results and summarize them by group
+// pair
+func (a *Aggregator) SetTestGroupPairs(groupPairs map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testGroupPairs = groupPairs
+}
+
+// SetTestInterfaces sets the interface each test ID of a per_interface
+// topology runs over, used to tag results and summarize them by interface
+func (a *Aggregator) SetTestInterfaces(interfaces map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testInterfaces = interfaces
+}
+
+// SetTestHops sets the hop each test ID of a chain topology measures, or 0
+// for its end-to-end test, used to tag results
+func (a *Aggregator) SetTestHops(hops map[string]int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testHops = hops
+}
+
+// hopOf returns the chain hop of a test ID, and whether it is the chain's
+// end-to-end test. The caller must hold a.mu.
+func (a *Aggregator) hopOf(testID string) (int, bool) {
+	hop, exists := a.testHops[testID]
+	return hop, exists && hop == 0
+}
+
+// SetBisection makes the summary report the throughput of all completed
+// tests together as the bisection bandwidth, for bisection topologies
+func (a *Aggregator) SetBisection(bisection bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.bisection = bisection
+}
+
+// SetBackgroundTests sets the IDs of background tests, whose results are
+// kept but excluded from the summary statistics
+func (a *Aggregator) SetBackgroundTests(tests map[string]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.backgroundTests = tests
+}
+
+// SetExpectedTests sets the IDs of the tests in the topology. Results for
+// other test IDs are kept apart as foreign results and excluded from the
+// summary. Without expected tests, every result is accepted.
+func (a *Aggregator) SetExpectedTests(tests map[string]bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.expectedTests = tests
+}
+
+// CollectResults collects results from all nodes via the client pool. Nodes
+// that fail are recorded as collection errors and the results of the others
+// are kept; an error is only returned when nothing could be collected.
 func (a *Aggregator) CollectResults(ctx context.Context, clientPool *client.Pool) error {
 	clients := clientPool.GetAllClients()
-	var errors []error
+	failures := nodeerr.New("result collection")
 
 	for _, c := range clients {
 		req := &pb.GetResultsRequest{
@@ -61,31 +391,93 @@ func (a *Aggregator) CollectResults(ctx context.Context, clientPool *client.Pool
 
 		resp, err := c.Client.GetResults(ctx, req)
 		if err != nil {
-			// Log error but continue with other nodes
-			errors = append(errors, fmt.Errorf("node %s: %w", c.Node.ID, err))
+			// Record the error but continue with other nodes
+			failures.Add(c.Node.ID, err)
+			a.AddCollectionError(c.Node.ID, err)
 			continue
 		}
 
-		// Process each result
-		for _, pbResult := range resp.Results {
-			result, err := a.convertResult(pbResult)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("node %s result conversion: %w", c.Node.ID, err))
-				continue
-			}
-
-			a.addResult(result)
+		a.ingest(c.Node, resp.Results, failures)
+		if err := a.flushStream(); err != nil {
+			return fmt.Errorf("failed to stream results: %w", err)
 		}
 	}
 
+	a.mergeSubResults()
+	a.mu.Lock()
+	a.flagSetupOutliers()
+	a.flagV6Lag()
+	a.rateResults()
+	a.mu.Unlock()
+
 	// Return error only if we failed to collect from ALL nodes
-	if len(errors) > 0 && len(a.results) == 0 {
-		return fmt.Errorf("failed to collect any results: %v", errors)
+	if failures.Len() > 0 && a.GetResultCount() == 0 {
+		return fmt.Errorf("failed to collect any results: %w", failures)
 	}
 
 	return nil
 }
 
+// AddResults aggregates results that were already retrieved from daemons,
+// such as the partial results collected after a failed run
+func (a *Aggregator) AddResults(results []*pb.TestResult) error {
+	failures := nodeerr.New("result conversion")
+	a.ingest(nil, results, failures)
+	if err := a.flushStream(); err != nil {
+		return fmt.Errorf("failed to stream results: %w", err)
+	}
+	a.mergeSubResults()
+	a.mu.Lock()
+	a.flagSetupOutliers()
+	a.flagV6Lag()
+	a.rateResults()
+	a.mu.Unlock()
+
+	return failures.ErrorOrNil()
+}
+
+// ingest converts and stores results returned by node, adding conversion
+// errors to failures. If node is nil, results are attributed to their source
+// node.
+func (a *Aggregator) ingest(node *models.Node, results []*pb.TestResult, failures *nodeerr.MultiNodeError) {
+	for _, pbResult := range results {
+		result, err := a.convertResult(pbResult)
+		if err != nil {
+			returnedBy := pbResult.SourceId
+			if node != nil {
+				returnedBy = node.ID
+			}
+			failures.Addf(returnedBy, "result conversion: %w", err)
+			continue
+		}
+		if node != nil {
+			result.ControlAddress = node.Address()
+		}
+		if pbResult.ResultFile != "" {
+			result.DaemonFile = &DaemonFile{Node: result.SourceNode, Host: pbResult.ResultHost, Path: pbResult.ResultFile}
+			if node != nil {
+				result.DaemonFile.Node = node.ID
+			}
+		}
+		if pbResult.CaptureFile != "" {
+			result.Capture = &DaemonFile{Node: result.SourceNode, Host: pbResult.ResultHost, Path: pbResult.CaptureFile}
+			if node != nil {
+				result.Capture.Node = node.ID
+			}
+		}
+
+		if !a.isExpected(result.TestID) {
+			returnedBy := result.SourceNode
+			if node != nil {
+				returnedBy = node.ID
+			}
+			a.addForeignResult(returnedBy, result)
+			continue
+		}
+		a.addResult(result)
+	}
+}
+
 // convertResult converts a protobuf result to an aggregated result
 func (a *Aggregator) convertResult(pbResult *pb.TestResult) (*TestResult, error) {
 	result := &TestResult{
@@ -97,6 +489,40 @@ func (a *Aggregator) convertResult(pbResult *pb.TestResult) (*TestResult, error)
 		EndTime:      pbResult.EndTimeUnix,
 		Duration:     pbResult.EndTimeUnix - pbResult.StartTimeUnix,
 		ErrorMessage: pbResult.ErrorMessage,
+
+		StartDelayMs:             int(pbResult.StartDelayMs),
+		RequestedDurationSeconds: float64(pbResult.DurationSeconds),
+
+		IntendedAddress:   pbResult.DestinationAddress,
+		DataLocalAddress:  pbResult.LocalAddress,
+		DataRemoteAddress: pbResult.RemoteAddress,
+
+		RequestedCongestionControl: pbResult.CongestionControl,
+		RunID:                      pbResult.RunId,
+	}
+	result.AddressMismatch = addressesDiffer(result.IntendedAddress, result.DataRemoteAddress)
+	// Daemons that support fractional durations report them in milliseconds
+	if pbResult.DurationMs > 0 {
+		result.RequestedDurationSeconds = float64(pbResult.DurationMs) / 1000
+	}
+
+	baseID, _, _ := models.SplitSubTestID(pbResult.TestId)
+	result.Profile = a.testProfiles[baseID]
+	result.Group = a.testGroups[baseID]
+	result.Labels = a.testLabels[baseID]
+	result.GroupPair = a.testGroupPairs[baseID]
+	result.RepeatOf = a.testRepetitions[baseID]
+	result.DeferredByBlackout = a.testDeferrals[baseID]
+	result.AddressFamily = a.testFamilies[baseID]
+	result.Interface = a.testInterfaces[baseID]
+	result.Hop, result.EndToEnd = a.hopOf(baseID)
+	result.Background = a.backgroundTests[baseID]
+	// Daemons do not know the node IDs of their tests
+	if result.SourceNode == "" {
+		result.SourceNode = a.testSources[baseID]
+	}
+	if result.DestNode == "" {
+		result.DestNode = a.testDestinations[baseID]
 	}
 
 	// Parse iperf JSON if available
@@ -105,21 +531,109 @@ func (a *Aggregator) convertResult(pbResult *pb.TestResult) (*TestResult, error)
 		if err := json.Unmarshal([]byte(pbResult.IperfJson), &iperfData); err == nil {
 			result.IperfData = iperfData
 
-			// Extract throughput
-			if throughput, err := extractThroughput(iperfData); err == nil {
-				result.ThroughputBps = throughput
+			// Burst tests carry one iperf3 output per burst
+			if _, isBurst := iperfData["bursts"]; isBurst {
+				applyBursts(result, extractBursts(iperfData))
+				return result, nil
 			}
 
-			// Extract retransmits
+			// Extract throughput and reconcile sender and receiver views
+			applyThroughput(result, pbResult.IperfJson)
+			a.reconcileThroughput(result)
+
+			// Extract retransmits and normalize them by the data sent
 			if retransmits, err := extractRetransmits(iperfData); err == nil {
 				result.Retransmits = retransmits
 			}
+			result.BytesSent = extractBytesSent(iperfData)
+			if mss := extractMSS(iperfData); mss > 0 {
+				result.SegmentsSent = (result.BytesSent + mss - 1) / mss
+			}
+			a.mu.RLock()
+			threshold := a.retransmitThreshold
+			a.mu.RUnlock()
+			normalizeRetransmits(result, threshold)
+
+			result.TCPStreams = extractTCPStreams(pbResult.IperfJson)
+			summarizeTCPStreams(result)
+
+			result.CongestionControl = extractCongestionControl(iperfData)
+			result.CongestionControlMismatch = congestionControlDiffers(result.RequestedCongestionControl, result.CongestionControl)
+			result.SenderCPUPercent = extractSenderCPU(iperfData)
+
+			measureSetup(result, pbResult.StartTimeUnixMs, pbResult.EndTimeUnixMs)
 		}
 	}
 
 	return result, nil
 }
 
+// reconcileThroughput makes the receiver-reported throughput canonical and
+// flags results whose sender and receiver throughput disagree by more than
+// the configured threshold, which indicates loss, truncation or
+// misattribution. When the server received, its own results are the
+// receiver's; the client's view of them is used only without them.
+func (a *Aggregator) reconcileThroughput(result *TestResult) {
+	if bps, ok := extractServerThroughput(result.IperfData); ok {
+		result.ReceiverThroughputBps = bps
+		result.ThroughputSource = ""
+	}
+	if result.ReceiverThroughputBps <= 0 {
+		return
+	}
+	result.ThroughputBps = result.ReceiverThroughputBps
+
+	a.mu.RLock()
+	threshold := a.discrepancyThreshold
+	a.mu.RUnlock()
+
+	flagDiscrepancy(result, threshold)
+}
+
+// extractServerThroughput returns the throughput the server received, from
+// the server results iperf3 adds with --get-server-output. Only tests the
+// client sends in have one; in reverse tests the client is the receiver.
+func extractServerThroughput(data map[string]interface{}) (float64, bool) {
+	start, _ := data["start"].(map[string]interface{})
+	testStart, _ := start["test_start"].(map[string]interface{})
+	if reverse, _ := testStart["reverse"].(float64); reverse != 0 {
+		return 0, false
+	}
+	if bidir, _ := testStart["bidir"].(float64); bidir != 0 {
+		return 0, false
+	}
+
+	server, ok := data["server_output_json"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	end, ok := server["end"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	// UDP servers report what they received in sum
+	for _, key := range []string{"sum_received", "sum"} {
+		if sum, ok := end[key].(map[string]interface{}); ok {
+			if bps, ok := sum["bits_per_second"].(float64); ok {
+				return bps, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// flagDiscrepancy records how far the receiver throughput deviates from the
+// sender throughput, as a percentage of the sender value
+func flagDiscrepancy(result *TestResult, threshold float64) {
+	if result.SenderThroughputBps <= 0 || result.ReceiverThroughputBps <= 0 {
+		return
+	}
+
+	diff := math.Abs(result.SenderThroughputBps - result.ReceiverThroughputBps)
+	result.DiscrepancyPercent = diff / result.SenderThroughputBps * 100
+	result.DiscrepancyFlagged = result.DiscrepancyPercent > threshold
+}
+
 // addResult adds a result to the aggregator
 func (a *Aggregator) addResult(result *TestResult) {
 	a.mu.Lock()
@@ -128,11 +642,138 @@ func (a *Aggregator) addResult(result *TestResult) {
 	a.results[result.TestID] = result
 }
 
-// GetResults returns all collected results
+// isExpected reports whether testID, or the pair it is a process of, is in
+// the expected topology
+func (a *Aggregator) isExpected(testID string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.expectedTests == nil {
+		return true
+	}
+	baseID, _, _ := models.SplitSubTestID(testID)
+	return a.expectedTests[baseID]
+}
+
+// addForeignResult keeps a result for a test ID outside the topology apart
+func (a *Aggregator) addForeignResult(nodeID string, result *TestResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.foreign[result.TestID]; !exists {
+		a.foreignNodes[nodeID]++
+	}
+	a.foreign[result.TestID] = result
+}
+
+// mergeSubResults combines the per-process results of multi-process pairs
+// (test IDs of the form testid#n) into one logical result per pair. The
+// logical result sums throughput and retransmits and keeps the individual
+// process results in SubResults.
+func (a *Aggregator) mergeSubResults() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	groups := make(map[string][]*TestResult)
+	for id, result := range a.results {
+		baseID, _, ok := models.SplitSubTestID(id)
+		if !ok {
+			continue
+		}
+		groups[baseID] = append(groups[baseID], result)
+		delete(a.results, id)
+	}
+
+	for baseID, subResults := range groups {
+		sort.Slice(subResults, func(i, j int) bool {
+			_, iIdx, _ := models.SplitSubTestID(subResults[i].TestID)
+			_, jIdx, _ := models.SplitSubTestID(subResults[j].TestID)
+			return iIdx < jIdx
+		})
+
+		merged := &TestResult{
+			TestID:        baseID,
+			SourceNode:    subResults[0].SourceNode,
+			DestNode:      subResults[0].DestNode,
+			Profile:       subResults[0].Profile,
+			Group:         subResults[0].Group,
+			GroupPair:     subResults[0].GroupPair,
+			RepeatOf:      subResults[0].RepeatOf,
+			Labels:        subResults[0].Labels,
+			AddressFamily: subResults[0].AddressFamily,
+			Interface:     subResults[0].Interface,
+			Hop:           subResults[0].Hop,
+			EndToEnd:      subResults[0].EndToEnd,
+			Background:    subResults[0].Background,
+			Status:        "TEST_STATUS_COMPLETED",
+			StartTime:     subResults[0].StartTime,
+			EndTime:       subResults[0].EndTime,
+			SubResults:    subResults,
+
+			StartDelayMs:             subResults[0].StartDelayMs,
+			RequestedDurationSeconds: subResults[0].RequestedDurationSeconds,
+			DeferredByBlackout:       subResults[0].DeferredByBlackout,
+
+			ControlAddress:    subResults[0].ControlAddress,
+			IntendedAddress:   subResults[0].IntendedAddress,
+			DataLocalAddress:  subResults[0].DataLocalAddress,
+			DataRemoteAddress: subResults[0].DataRemoteAddress,
+
+			RequestedCongestionControl: subResults[0].RequestedCongestionControl,
+			CongestionControl:          subResults[0].CongestionControl,
+			RunID:                      subResults[0].RunID,
+		}
+
+		errorMessages := make([]string, 0)
+		for _, sub := range subResults {
+			if sub.StartTime < merged.StartTime {
+				merged.StartTime = sub.StartTime
+			}
+			if sub.EndTime > merged.EndTime {
+				merged.EndTime = sub.EndTime
+			}
+			if sub.Status != "TEST_STATUS_COMPLETED" {
+				merged.Status = sub.Status
+			}
+			if sub.ErrorMessage != "" {
+				errorMessages = append(errorMessages, fmt.Sprintf("%s: %s", sub.TestID, sub.ErrorMessage))
+			}
+			merged.ThroughputBps += sub.ThroughputBps
+			merged.SenderThroughputBps += sub.SenderThroughputBps
+			merged.ReceiverThroughputBps += sub.ReceiverThroughputBps
+			merged.ReverseThroughputBps += sub.ReverseThroughputBps
+			if sub.ThroughputSource != "" {
+				merged.ThroughputSource = sub.ThroughputSource
+			}
+			merged.Retransmits += sub.Retransmits
+			merged.AddressMismatch = merged.AddressMismatch || sub.AddressMismatch
+			merged.CongestionControlMismatch = merged.CongestionControlMismatch || sub.CongestionControlMismatch
+			merged.BytesSent += sub.BytesSent
+			merged.SegmentsSent += sub.SegmentsSent
+			merged.TCPStreams = append(merged.TCPStreams, sub.TCPStreams...)
+			merged.SetupMs = math.Max(merged.SetupMs, sub.SetupMs)
+			merged.SenderCPUPercent = math.Max(merged.SenderCPUPercent, sub.SenderCPUPercent)
+		}
+
+		merged.Duration = merged.EndTime - merged.StartTime
+		merged.ErrorMessage = strings.Join(errorMessages, "; ")
+		flagDiscrepancy(merged, a.discrepancyThreshold)
+		normalizeRetransmits(merged, a.retransmitThreshold)
+		summarizeTCPStreams(merged)
+		a.results[baseID] = merged
+	}
+}
+
+// GetResults returns all collected results, or a sample of them if the
+// aggregator is streaming
 func (a *Aggregator) GetResults() []*TestResult {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
+	if a.stream != nil {
+		return a.stream.sample()
+	}
+
 	results := make([]*TestResult, 0, len(a.results))
 	for _, result := range a.results {
 		results = append(results, result)
@@ -141,79 +782,290 @@ func (a *Aggregator) GetResults() []*TestResult {
 	return results
 }
 
+// GetForeignResults returns the results for test IDs outside the expected
+// topology, sorted by test ID, or nil if there are none
+func (a *Aggregator) GetForeignResults() *ForeignResults {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if len(a.foreign) == 0 {
+		return nil
+	}
+
+	foreign := &ForeignResults{
+		Count:   len(a.foreign),
+		ByNode:  make(map[string]int, len(a.foreignNodes)),
+		Results: make([]*TestResult, 0, len(a.foreign)),
+	}
+	for nodeID, count := range a.foreignNodes {
+		foreign.ByNode[nodeID] = count
+	}
+	for _, result := range a.foreign {
+		foreign.Results = append(foreign.Results, result)
+	}
+	sort.Slice(foreign.Results, func(i, j int) bool {
+		return foreign.Results[i].TestID < foreign.Results[j].TestID
+	})
+
+	return foreign
+}
+
 // GetSummary returns aggregate statistics
 func (a *Aggregator) GetSummary() *Summary {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	summary := &Summary{
-		TotalTests:    len(a.results),
+	var summary *Summary
+	if a.stream != nil {
+		summary = a.stream.summarize()
+	} else {
+		summary = newSummary()
+		totals := newSummaryTotals()
+		setups := make([]float64, 0)
+		for _, result := range a.results {
+			if summary.add(totals, result) && hasSetup(result) {
+				setups = append(setups, result.SetupMs)
+			}
+		}
+		summary.finish(totals)
+		summarizeSetup(summary, setups)
+	}
+
+	if a.bisection {
+		summary.BisectionBps = summary.AvgThroughput * float64(summary.CompletedTests)
+	}
+
+	if timeline := a.timeline(); timeline != nil {
+		peak := timeline.peak()
+		summary.PeakAggregateBps = peak.ThroughputBps
+		summary.PeakAggregateTime = peak.Time
+	}
+
+	return summary
+}
+
+// summaryTotals are the sums a summary's averages are computed from
+type summaryTotals struct {
+	throughput, retransmitRate                                                  float64
+	groupThroughput, familyThroughput, groupPairThroughput, interfaceThroughput map[string]float64
+}
+
+// newSummaryTotals returns empty totals
+func newSummaryTotals() *summaryTotals {
+	return &summaryTotals{
+		groupThroughput:     make(map[string]float64),
+		familyThroughput:    make(map[string]float64),
+		groupPairThroughput: make(map[string]float64),
+		interfaceThroughput: make(map[string]float64),
+	}
+}
+
+// newSummary returns an empty summary to add results to
+func newSummary() *Summary {
+	return &Summary{
 		MinThroughput: -1,
 	}
+}
 
-	var totalThroughput float64
+// add counts result in the summary and its sums in totals. It reports
+// whether the result is a measurement; background tests and diagnostic
+// probes are only counted.
+func (s *Summary) add(totals *summaryTotals, result *TestResult) bool {
+	if result.Background {
+		s.BackgroundTests++
+		return false
+	}
+	if result.Diagnostic {
+		s.DiagnosticTests++
+		return false
+	}
+	s.TotalTests++
 
-	for _, result := range a.results {
-		if result.Status == "TEST_STATUS_COMPLETED" {
-			summary.CompletedTests++
+	if result.Group != "" {
+		if s.Groups == nil {
+			s.Groups = make(map[string]*GroupSummary)
+		}
+		addToGroupSummary(s.Groups, totals.groupThroughput, result.Group, result)
+	}
+	if result.AddressFamily != "" {
+		if s.Families == nil {
+			s.Families = make(map[string]*GroupSummary)
+		}
+		addToGroupSummary(s.Families, totals.familyThroughput, result.AddressFamily, result)
+	}
+	if result.GroupPair != "" {
+		if s.GroupPairs == nil {
+			s.GroupPairs = make(map[string]*GroupSummary)
+		}
+		addToGroupSummary(s.GroupPairs, totals.groupPairThroughput, result.GroupPair, result)
+	}
+	if result.Interface != "" {
+		if s.Interfaces == nil {
+			s.Interfaces = make(map[string]*GroupSummary)
+		}
+		addToGroupSummary(s.Interfaces, totals.interfaceThroughput, result.Interface, result)
+	}
+	if result.V6LagFlagged {
+		s.V6LaggingTests++
+	}
+	if result.DeferredByBlackout != nil {
+		s.BlackoutDeferredTests++
+	}
 
-			// Throughput stats
-			if result.ThroughputBps > 0 {
-				totalThroughput += result.ThroughputBps
+	if result.Status == "TEST_STATUS_COMPLETED" {
+		s.CompletedTests++
 
-				if summary.MinThroughput < 0 || result.ThroughputBps < summary.MinThroughput {
-					summary.MinThroughput = result.ThroughputBps
-				}
+		// Throughput stats
+		if result.ThroughputBps > 0 {
+			totals.throughput += result.ThroughputBps
 
-				if result.ThroughputBps > summary.MaxThroughput {
-					summary.MaxThroughput = result.ThroughputBps
-				}
+			if s.MinThroughput < 0 || result.ThroughputBps < s.MinThroughput {
+				s.MinThroughput = result.ThroughputBps
 			}
 
-			// Retransmits
-			summary.TotalRetransmits += result.Retransmits
-		} else if result.Status == "TEST_STATUS_FAILED" {
-			summary.FailedTests++
+			if result.ThroughputBps > s.MaxThroughput {
+				s.MaxThroughput = result.ThroughputBps
+			}
+		}
+
+		// Retransmits
+		s.TotalRetransmits += result.Retransmits
+		totals.retransmitRate += result.RetransmitsPerMB
+		if result.RetransmitsPerMB > s.MaxRetransmitsPerMB {
+			s.MaxRetransmitsPerMB = result.RetransmitsPerMB
+		}
+		if result.RetransmitsFlagged {
+			s.HighRetransmitTests++
+		}
+
+		if result.DiscrepancyFlagged {
+			s.DiscrepantTests++
+		}
+		if result.AddressMismatch {
+			s.AddressMismatchTests++
+		}
+		if result.CongestionControlMismatch {
+			s.CongestionControlMismatchTests++
 		}
+		if result.SetupFlagged {
+			s.SetupOutlierTests++
+		}
+		switch result.LimitHint {
+		case LimitWindow:
+			s.WindowLimitedTests++
+		case LimitLossOrCPU:
+			s.LossOrCPULimitedTests++
+		}
+		switch result.Confidence {
+		case ConfidenceHigh:
+			s.HighConfidenceTests++
+		case ConfidenceMedium:
+			s.MediumConfidenceTests++
+		case ConfidenceLow:
+			s.LowConfidenceTests++
+		}
+	} else if result.Status == "TEST_STATUS_FAILED" {
+		s.FailedTests++
+	} else if result.Status == StatusSkippedBlackout {
+		s.BlackoutSkippedTests++
+	}
+	return true
+}
+
+// finish computes the averages of the summary from totals
+func (s *Summary) finish(totals *summaryTotals) {
+	if s.CompletedTests > 0 {
+		s.AvgThroughput = totals.throughput / float64(s.CompletedTests)
+		s.AvgRetransmitsPerMB = totals.retransmitRate / float64(s.CompletedTests)
 	}
 
-	if summary.CompletedTests > 0 {
-		summary.AvgThroughput = totalThroughput / float64(summary.CompletedTests)
+	if s.MinThroughput < 0 {
+		s.MinThroughput = 0
 	}
 
-	if summary.MinThroughput < 0 {
-		summary.MinThroughput = 0
+	for name, group := range s.Groups {
+		if group.CompletedTests > 0 {
+			group.AvgThroughput = totals.groupThroughput[name] / float64(group.CompletedTests)
+		}
+	}
+	for name, family := range s.Families {
+		if family.CompletedTests > 0 {
+			family.AvgThroughput = totals.familyThroughput[name] / float64(family.CompletedTests)
+		}
+	}
+	for name, groupPair := range s.GroupPairs {
+		if groupPair.CompletedTests > 0 {
+			groupPair.AvgThroughput = totals.groupPairThroughput[name] / float64(groupPair.CompletedTests)
+		}
+	}
+	for name, iface := range s.Interfaces {
+		if iface.CompletedTests > 0 {
+			iface.AvgThroughput = totals.interfaceThroughput[name] / float64(iface.CompletedTests)
+		}
 	}
+}
 
-	return summary
+// addToGroupSummary counts result in the summary of key in summaries,
+// adding its throughput to the key's total if it completed
+func addToGroupSummary(summaries map[string]*GroupSummary, throughput map[string]float64, key string, result *TestResult) {
+	group, exists := summaries[key]
+	if !exists {
+		group = &GroupSummary{}
+		summaries[key] = group
+	}
+	group.TotalTests++
+	switch result.Status {
+	case "TEST_STATUS_COMPLETED":
+		group.CompletedTests++
+		throughput[key] += result.ThroughputBps
+	case "TEST_STATUS_FAILED":
+		group.FailedTests++
+	}
 }
 
-// GetResultCount returns the number of collected results
+// GetResultCount returns the number of collected results, streamed ones
+// included
 func (a *Aggregator) GetResultCount() int {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
+	if a.stream != nil {
+		return a.stream.streamed + len(a.results)
+	}
 	return len(a.results)
 }
 
-// extractThroughput extracts throughput from iperf JSON data
-func extractThroughput(data map[string]interface{}) (float64, error) {
-	end, ok := data["end"].(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("missing 'end' section")
+// applyThroughput sets the sender and receiver throughput of result from
+// its iperf3 output, and notes the source of the canonical throughput when
+// it is not the totals iperf3 reports for the role
+func applyThroughput(result *TestResult, iperfJSON string) {
+	throughputs, err := iperf.ParseThroughput(iperfJSON)
+	if err != nil {
+		return
 	}
 
-	sumSent, ok := end["sum_sent"].(map[string]interface{})
-	if !ok {
-		return 0, fmt.Errorf("missing 'sum_sent' section")
+	forward := throughputs.Forward
+	source := forward.SenderSource
+	if forward.SenderSource != "" {
+		result.SenderThroughputBps = forward.SenderBps
+		result.ThroughputBps = forward.SenderBps
 	}
-
-	bps, ok := sumSent["bits_per_second"].(float64)
-	if !ok {
-		return 0, fmt.Errorf("missing 'bits_per_second'")
+	if forward.ReceiverSource != "" {
+		result.ReceiverThroughputBps = forward.ReceiverBps
+		if forward.ReceiverBps > 0 {
+			source = forward.ReceiverSource // Made canonical by reconcileThroughput
+		}
+	}
+	if !source.Exact() {
+		result.ThroughputSource = string(source)
 	}
 
-	return bps, nil
+	if reverse := throughputs.Reverse; reverse != nil {
+		result.ReverseThroughputBps = reverse.SenderBps
+		if reverse.ReceiverBps > 0 {
+			result.ReverseThroughputBps = reverse.ReceiverBps
+		}
+	}
 }
 
 // extractRetransmits extracts retransmit count from iperf JSON data
@@ -235,3 +1087,62 @@ func extractRetransmits(data map[string]interface{}) (int64, error) {
 
 	return int64(retransmits), nil
 }
+
+// extractBursts extracts per-burst UDP measurements from a burst test's output
+func extractBursts(data map[string]interface{}) []*BurstResult {
+	rawBursts, ok := data["bursts"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	bursts := make([]*BurstResult, 0, len(rawBursts))
+	for i, raw := range rawBursts {
+		burstData, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		end, ok := burstData["end"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sum, ok := end["sum"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		burst := &BurstResult{Index: i}
+		burst.ThroughputBps, _ = sum["bits_per_second"].(float64)
+		burst.JitterMs, _ = sum["jitter_ms"].(float64)
+		burst.LostPercent, _ = sum["lost_percent"].(float64)
+		if lost, ok := sum["lost_packets"].(float64); ok {
+			burst.LostPackets = int64(lost)
+		}
+		if packets, ok := sum["packets"].(float64); ok {
+			burst.Packets = int64(packets)
+		}
+		bursts = append(bursts, burst)
+	}
+
+	return bursts
+}
+
+// applyBursts sets a result's burst rows, its throughput (the mean rate
+// during bursts) and its worst burst (highest loss, then highest jitter)
+func applyBursts(result *TestResult, bursts []*BurstResult) {
+	result.Bursts = bursts
+	if len(bursts) == 0 {
+		return
+	}
+
+	var total float64
+	for _, burst := range bursts {
+		total += burst.ThroughputBps
+
+		worst := result.WorstBurst
+		if worst == nil || burst.LostPercent > worst.LostPercent ||
+			(burst.LostPercent == worst.LostPercent && burst.JitterMs > worst.JitterMs) {
+			result.WorstBurst = burst
+		}
+	}
+	result.ThroughputBps = total / float64(len(bursts))
+}
@@ -0,0 +1,1145 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+)
+
+func TestAggregator_ForeignResults(t *testing.T) {
+	agg := NewAggregator()
+	agg.SetExpectedTests(map[string]bool{"t1": true, "t2": true})
+
+	results := []*pb.TestResult{
+		{TestId: "t1", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_FAILED},
+		{TestId: "t2#0", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_FAILED},
+		{TestId: "t2#1", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_FAILED},
+		{TestId: "stale", SourceId: "node2", Status: pb.TestStatus_TEST_STATUS_FAILED},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	if got := agg.GetSummary().TotalTests; got != 2 {
+		t.Errorf("summary TotalTests = %d, want 2", got)
+	}
+
+	foreign := agg.GetForeignResults()
+	if foreign == nil {
+		t.Fatal("GetForeignResults() = nil, want the stale result")
+	}
+	if foreign.Count != 1 || foreign.Results[0].TestID != "stale" {
+		t.Errorf("foreign results = %d (%v), want 1 (stale)", foreign.Count, foreign.Results[0].TestID)
+	}
+	if foreign.ByNode["node2"] != 1 {
+		t.Errorf("foreign ByNode = %v, want node2: 1", foreign.ByNode)
+	}
+}
+
+func TestAggregator_NoExpectedTests(t *testing.T) {
+	agg := NewAggregator()
+	results := []*pb.TestResult{
+		{TestId: "anything", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_FAILED},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	if foreign := agg.GetForeignResults(); foreign != nil {
+		t.Errorf("GetForeignResults() = %v, want nil", foreign)
+	}
+	if got := agg.GetSummary().TotalTests; got != 1 {
+		t.Errorf("summary TotalTests = %d, want 1", got)
+	}
+}
+
+func TestAddressesDiffer(t *testing.T) {
+	tests := []struct {
+		intended, observed string
+		want               bool
+	}{
+		{"10.0.0.2", "10.0.0.2", false},
+		{"10.0.0.2", "192.168.1.2", true},
+		{"10.0.0.2", "::ffff:10.0.0.2", false},
+		{"node2.example.com", "10.0.0.2", false},
+		{"10.0.0.2", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := addressesDiffer(tt.intended, tt.observed); got != tt.want {
+			t.Errorf("addressesDiffer(%q, %q) = %v, want %v", tt.intended, tt.observed, got, tt.want)
+		}
+	}
+}
+
+// resultsDaemon returns fixed results from GetResults, or fails
+type resultsDaemon struct {
+	pb.DaemonServiceClient
+
+	results []*pb.TestResult
+	err     error
+}
+
+func (d *resultsDaemon) GetResults(ctx context.Context, in *pb.GetResultsRequest, opts ...grpc.CallOption) (*pb.GetResultsResponse, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &pb.GetResultsResponse{Results: d.results, TotalCount: int32(len(d.results))}, nil // #nosec G115 -- Test data
+}
+
+func TestAggregator_CollectionErrors(t *testing.T) {
+	pool := client.NewPool(0)
+	pool.AddClient(&models.Node{ID: "node1"}, &resultsDaemon{results: []*pb.TestResult{
+		{TestId: "t1", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+	}})
+	down := &resultsDaemon{err: errors.New("connection refused")}
+	pool.AddClient(&models.Node{ID: "node2"}, down)
+
+	agg := NewAggregator()
+	agg.SetTestSources(map[string]string{"t1": "node1", "t2": "node2", "t3": "node2"})
+	if err := agg.CollectResults(context.Background(), pool); err != nil {
+		t.Fatalf("CollectResults() error = %v, want the results of node1", err)
+	}
+	if got := agg.GetResultCount(); got != 1 {
+		t.Errorf("GetResultCount() = %d, want 1", got)
+	}
+
+	collectionErrors := agg.GetCollectionErrors()
+	if len(collectionErrors) != 1 {
+		t.Fatalf("GetCollectionErrors() = %d errors, want 1", len(collectionErrors))
+	}
+	got := collectionErrors[0]
+	if got.Node != "node2" || got.MissingResults != 2 || !strings.Contains(got.Error, "connection refused") {
+		t.Errorf("collection error = %+v, want node2 with 2 missing results", got)
+	}
+
+	// A later collection merges the missing results into the earlier ones
+	sources := MissingTestSources(collectionErrors)
+	late := NewAggregator()
+	late.RestoreResults(agg.GetResults())
+	late.SetExpectedTests(map[string]bool{"t2": true, "t3": true})
+	late.SetTestSources(sources)
+	down.err = nil
+	down.results = []*pb.TestResult{
+		{TestId: "t2", SourceId: "node2", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+		{TestId: "t3", SourceId: "node2", Status: pb.TestStatus_TEST_STATUS_FAILED},
+	}
+	if err := late.CollectResults(context.Background(), pool); err != nil {
+		t.Fatalf("late CollectResults() error = %v", err)
+	}
+	if got := late.GetResultCount(); got != 3 {
+		t.Errorf("late GetResultCount() = %d, want 3", got)
+	}
+	if errs := late.GetCollectionErrors(); errs != nil {
+		t.Errorf("late GetCollectionErrors() = %v, want none", errs)
+	}
+}
+
+func TestAggregator_DaemonFile(t *testing.T) {
+	pool := client.NewPool(0)
+	pool.AddClient(&models.Node{ID: "node1"}, &resultsDaemon{results: []*pb.TestResult{
+		{TestId: "t1", Status: pb.TestStatus_TEST_STATUS_COMPLETED, ResultFile: "/var/results/t1.json", ResultHost: "host1"},
+		{TestId: "t2", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+		{TestId: "t3", Status: pb.TestStatus_TEST_STATUS_FAILED, CaptureFile: "/var/results/t3.pcap", ResultHost: "host1"},
+	}})
+
+	agg := NewAggregator()
+	if err := agg.CollectResults(context.Background(), pool); err != nil {
+		t.Fatalf("CollectResults() error = %v", err)
+	}
+	files := make(map[string]*DaemonFile)
+	captures := make(map[string]*DaemonFile)
+	for _, result := range agg.GetResults() {
+		files[result.TestID] = result.DaemonFile
+		captures[result.TestID] = result.Capture
+	}
+	want := DaemonFile{Node: "node1", Host: "host1", Path: "/var/results/t1.json"}
+	if files["t1"] == nil || *files["t1"] != want {
+		t.Errorf("t1 DaemonFile = %+v, want %+v", files["t1"], want)
+	}
+	if files["t2"] != nil {
+		t.Errorf("t2 DaemonFile = %+v, want none", files["t2"])
+	}
+	wantCapture := DaemonFile{Node: "node1", Host: "host1", Path: "/var/results/t3.pcap"}
+	if captures["t3"] == nil || *captures["t3"] != wantCapture || files["t3"] != nil {
+		t.Errorf("t3 Capture = %+v, DaemonFile = %+v, want %+v and none", captures["t3"], files["t3"], wantCapture)
+	}
+}
+
+func TestAggregator_TCPStreamStats(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "tcp_parallel.json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	agg := NewAggregator()
+	results := []*pb.TestResult{
+		{TestId: "single", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_COMPLETED, IperfJson: string(data)},
+		{TestId: "multi#0", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_COMPLETED, IperfJson: string(data)},
+		{TestId: "multi#1", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_COMPLETED, IperfJson: string(data)},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	byID := make(map[string]*TestResult)
+	for _, result := range agg.GetResults() {
+		byID[result.TestID] = result
+	}
+
+	// Two streams: 1 MiB cwnd at 1 ms and 512 KiB at 2 ms, bounding
+	// throughput at 8.39 + 2.10 Gbps; the test reached 9.43 Gbps
+	tests := []struct {
+		testID  string
+		streams int
+		bound   float64
+	}{
+		{"single", 2, 10485760000},
+		{"multi", 4, 2 * 10485760000},
+	}
+	for _, tt := range tests {
+		result := byID[tt.testID]
+		if result == nil {
+			t.Fatalf("no result for %s", tt.testID)
+		}
+		if len(result.TCPStreams) != tt.streams {
+			t.Errorf("%s: %d TCP streams, want %d", tt.testID, len(result.TCPStreams), tt.streams)
+		}
+		if result.MaxSndCwndBytes != 1048576 {
+			t.Errorf("%s: MaxSndCwndBytes = %d, want 1048576", tt.testID, result.MaxSndCwndBytes)
+		}
+		if result.MeanRTTMs != 1.5 {
+			t.Errorf("%s: MeanRTTMs = %v, want 1.5", tt.testID, result.MeanRTTMs)
+		}
+		if math.Abs(result.WindowBoundBps-tt.bound) > 1 {
+			t.Errorf("%s: WindowBoundBps = %.0f, want %.0f", tt.testID, result.WindowBoundBps, tt.bound)
+		}
+		if result.LimitHint != LimitWindow {
+			t.Errorf("%s: LimitHint = %q, want %q", tt.testID, result.LimitHint, LimitWindow)
+		}
+	}
+
+	if got := agg.GetSummary().WindowLimitedTests; got != 2 {
+		t.Errorf("summary WindowLimitedTests = %d, want 2", got)
+	}
+
+	// The same windows carrying a fraction of the bound point elsewhere
+	lossy := &TestResult{ThroughputBps: 2e9, TCPStreams: byID["single"].TCPStreams}
+	summarizeTCPStreams(lossy)
+	if lossy.LimitHint != LimitLossOrCPU {
+		t.Errorf("LimitHint at 2 Gbps = %q, want %q", lossy.LimitHint, LimitLossOrCPU)
+	}
+}
+
+// intervalsJSON returns iperf3 output starting at timesecs with one interval
+// per [start, end, bits_per_second] entry
+func intervalsJSON(timesecs float64, intervals ...[3]float64) string {
+	parts := make([]string, 0, len(intervals))
+	for _, iv := range intervals {
+		parts = append(parts, fmt.Sprintf(`{"sum": {"start": %g, "end": %g, "bits_per_second": %g}}`, iv[0], iv[1], iv[2]))
+	}
+	return fmt.Sprintf(`{"start": {"timestamp": {"timesecs": %g}}, "intervals": [%s], "end": {}}`,
+		timesecs, strings.Join(parts, ", "))
+}
+
+func TestAggregator_Timeline(t *testing.T) {
+	agg := NewAggregator()
+	// node2's clock is a second ahead of the controller's
+	agg.SetClockOffsets(map[string]time.Duration{"node2": time.Second})
+
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	results := []*pb.TestResult{
+		{TestId: "a", SourceId: "node1", Status: completed,
+			IperfJson: intervalsJSON(1000, [3]float64{0, 1, 1e9}, [3]float64{1, 2, 1e9})},
+		// The interval from 1 to 2 seconds is missing
+		{TestId: "b", SourceId: "node2", Status: completed,
+			IperfJson: intervalsJSON(1001, [3]float64{0, 1, 2e9}, [3]float64{2, 3, 2e9})},
+		// Straddles the first two buckets
+		{TestId: "c", SourceId: "node1", Status: completed,
+			IperfJson: intervalsJSON(1000.5, [3]float64{0, 1, 1e9})},
+		{TestId: "d", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_FAILED,
+			IperfJson: intervalsJSON(1000, [3]float64{0, 1, 5e9})},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	timeline := agg.Timeline()
+	if timeline == nil {
+		t.Fatal("Timeline() = nil")
+	}
+	want := []TimelineBucket{
+		{Time: 1000, ThroughputBps: 3.5e9, Tests: 3},
+		{Time: 1001, ThroughputBps: 1.5e9, Tests: 2},
+		{Time: 1002, ThroughputBps: 2e9, Tests: 1},
+	}
+	if len(timeline.Buckets) != len(want) {
+		t.Fatalf("Timeline() has %d buckets, want %d", len(timeline.Buckets), len(want))
+	}
+	for i, bucket := range timeline.Buckets {
+		if bucket.Time != want[i].Time || math.Abs(bucket.ThroughputBps-want[i].ThroughputBps) > 1 || bucket.Tests != want[i].Tests {
+			t.Errorf("bucket %d = %+v, want %+v", i, *bucket, want[i])
+		}
+	}
+
+	summary := agg.GetSummary()
+	if math.Abs(summary.PeakAggregateBps-3.5e9) > 1 || summary.PeakAggregateTime != 1000 {
+		t.Errorf("peak aggregate = %.0f at %v, want 3.5e9 at 1000", summary.PeakAggregateBps, summary.PeakAggregateTime)
+	}
+}
+
+func TestAggregator_CongestionControl(t *testing.T) {
+	used := func(algorithm string) string {
+		return fmt.Sprintf(`{"end": {"sum_sent": {"bits_per_second": 1e9}, "sender_tcp_congestion": %q}}`, algorithm)
+	}
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+
+	agg := NewAggregator()
+	results := []*pb.TestResult{
+		{TestId: "fallback", Status: completed, CongestionControl: "bbr", IperfJson: used("cubic")},
+		{TestId: "honored", Status: completed, CongestionControl: "bbr", IperfJson: used("bbr")},
+		{TestId: "unrequested", Status: completed, IperfJson: used("cubic")},
+		{TestId: "old-iperf", Status: completed, CongestionControl: "bbr", IperfJson: `{"end": {}}`},
+		{TestId: "multi#0", Status: completed, CongestionControl: "bbr", IperfJson: used("bbr")},
+		{TestId: "multi#1", Status: completed, CongestionControl: "bbr", IperfJson: used("cubic")},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	mismatches := CongestionControlMismatches(agg.GetResults())
+	ids := make([]string, 0, len(mismatches))
+	for _, result := range mismatches {
+		ids = append(ids, result.TestID)
+	}
+	if got := strings.Join(ids, ","); got != "fallback,multi" {
+		t.Errorf("mismatches = %s, want fallback,multi", got)
+	}
+	if got := agg.GetSummary().CongestionControlMismatchTests; got != 2 {
+		t.Errorf("summary CongestionControlMismatchTests = %d, want 2", got)
+	}
+}
+
+func TestAggregator_Blackouts(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+
+	agg := NewAggregator()
+	agg.SetTestSources(map[string]string{"skipped": "backups"})
+	agg.SetTestGroups(map[string]string{"skipped": "night"})
+	agg.SetTestDeferrals(map[string]*BlackoutDeferral{
+		"deferred": {DelaySeconds: 3600, Nodes: []string{"backups"}},
+		"multi":    {DelaySeconds: 60, Nodes: []string{"backups"}},
+	})
+	results := []*pb.TestResult{
+		{TestId: "deferred", Status: completed},
+		{TestId: "on-time", Status: completed},
+		{TestId: "multi#0", Status: completed},
+		{TestId: "multi#1", Status: completed},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+	if err := agg.AddBlackoutSkip("skipped", 3*time.Hour, []string{"backups"}); err != nil {
+		t.Fatalf("AddBlackoutSkip() error = %v", err)
+	}
+
+	byID := make(map[string]*TestResult)
+	for _, result := range agg.GetResults() {
+		byID[result.TestID] = result
+	}
+	if deferral := byID["deferred"].DeferredByBlackout; deferral == nil || deferral.DelaySeconds != 3600 {
+		t.Errorf("deferred result annotation = %+v, want a 3600s delay", deferral)
+	}
+	if byID["multi"].DeferredByBlackout == nil || byID["on-time"].DeferredByBlackout != nil {
+		t.Errorf("annotations of multi = %+v and on-time = %+v, want only multi's",
+			byID["multi"].DeferredByBlackout, byID["on-time"].DeferredByBlackout)
+	}
+	skipped := byID["skipped"]
+	if skipped == nil || skipped.Status != StatusSkippedBlackout || skipped.SourceNode != "backups" || skipped.Group != "night" {
+		t.Fatalf("skipped result = %+v, want a blackout skip from backups in night", skipped)
+	}
+	if !strings.Contains(skipped.ErrorMessage, "backups") {
+		t.Errorf("skipped result error = %q, want the node named", skipped.ErrorMessage)
+	}
+
+	summary := agg.GetSummary()
+	if summary.TotalTests != 4 || summary.CompletedTests != 3 || summary.FailedTests != 0 {
+		t.Errorf("summary tests = %d total, %d completed, %d failed; want 4, 3, 0",
+			summary.TotalTests, summary.CompletedTests, summary.FailedTests)
+	}
+	if summary.BlackoutDeferredTests != 2 || summary.BlackoutSkippedTests != 1 {
+		t.Errorf("summary blackout tests = %d deferred, %d skipped; want 2, 1",
+			summary.BlackoutDeferredTests, summary.BlackoutSkippedTests)
+	}
+}
+
+func TestAggregator_ResourceUsage(t *testing.T) {
+	agg := NewAggregator()
+	agg.SetCPUThreshold(90)
+	agg.SetBackgroundTests(map[string]bool{"bg": true})
+
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	results := []*pb.TestResult{
+		{TestId: "a", SourceId: "node1", DestinationId: "node2", Status: completed,
+			StartTimeUnix: 1000, EndTimeUnix: 1009},
+		// node3 only runs background load
+		{TestId: "bg", SourceId: "node3", DestinationId: "node1", Status: completed,
+			StartTimeUnix: 1020, EndTimeUnix: 1029},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	sample := func(ms int64, cpu float64, sent, recv uint64) *pb.ResourceSample {
+		return &pb.ResourceSample{TimeUnixMs: ms, CpuPercent: cpu,
+			Nics: []*pb.NICCounters{{Name: "eth0", BytesSent: sent, BytesRecv: recv}}}
+	}
+	// node1 is busy outside its tests, node2 while its test runs
+	agg.AddSamples("node1", []*pb.ResourceSample{
+		sample(1005000, 50, 0, 0),
+		sample(1007000, 60, 2500000, 1000),
+		sample(1025000, 99, 5000000, 500),
+	})
+	agg.AddSamples("node2", []*pb.ResourceSample{sample(1009500, 95, 0, 0)})
+	agg.AddSamples("node3", []*pb.ResourceSample{sample(1025000, 99, 0, 0)})
+
+	usage := agg.ResourceUsage()
+	if len(usage) != 3 {
+		t.Fatalf("ResourceUsage() has %d nodes, want 3", len(usage))
+	}
+	node1 := usage["node1"]
+	if node1.PeakCPUPercent != 99 || node1.PeakTestCPUPercent != 60 || node1.CPUFlagged {
+		t.Errorf("node1 = peak %v, test peak %v, flagged %v; want 99, 60, false",
+			node1.PeakCPUPercent, node1.PeakTestCPUPercent, node1.CPUFlagged)
+	}
+	if nic := node1.Samples[1].NICs[0]; nic.TxBps != 1e7 || nic.RxBps != 4000 {
+		t.Errorf("node1 NIC rates = %v tx, %v rx; want 1e7, 4000", nic.TxBps, nic.RxBps)
+	}
+	// The receive counter went backwards
+	if nic := node1.Samples[2].NICs[0]; nic.RxBps != 0 {
+		t.Errorf("node1 NIC rx rate after counter reset = %v, want 0", nic.RxBps)
+	}
+	if !usage["node2"].CPUFlagged {
+		t.Error("node2 not flagged")
+	}
+	if usage["node3"].CPUFlagged {
+		t.Error("node3 flagged for background load")
+	}
+	if got := HighCPUNodes(usage); len(got) != 1 || got[0] != "node2" {
+		t.Errorf("HighCPUNodes() = %v, want [node2]", got)
+	}
+}
+
+func TestAggregator_SetupTime(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	// Every test measured two seconds of intervals
+	data := intervalsJSON(1000, [3]float64{0, 1, 1e9}, [3]float64{1, 2, 1e9})
+	ran := func(testID string, runMs int64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: completed, IperfJson: data,
+			StartTimeUnixMs: 1000000, EndTimeUnixMs: 1000000 + runMs}
+	}
+
+	agg := NewAggregator()
+	results := []*pb.TestResult{
+		ran("a", 2010),
+		ran("b", 2020),
+		ran("c", 2030),
+		ran("slow", 2500),
+		ran("multi#0", 2015),
+		ran("multi#1", 2040),
+		// Older daemons report whole seconds only
+		{TestId: "old-daemon", Status: completed, IperfJson: data, StartTimeUnix: 1000, EndTimeUnix: 1002},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	setups := make(map[string]float64)
+	for _, result := range agg.GetResults() {
+		setups[result.TestID] = result.SetupMs
+	}
+	want := map[string]float64{"a": 10, "b": 20, "c": 30, "slow": 500, "multi": 40, "old-daemon": 0}
+	for testID, setup := range want {
+		if setups[testID] != setup {
+			t.Errorf("%s SetupMs = %v, want %v", testID, setups[testID], setup)
+		}
+	}
+
+	// 500 ms is well above the 30 ms median; 40 ms is not
+	outliers := SetupOutliers(agg.GetResults())
+	if len(outliers) != 1 || outliers[0].TestID != "slow" {
+		t.Errorf("SetupOutliers() = %v, want slow only", outliers)
+	}
+
+	summary := agg.GetSummary()
+	if summary.SetupMinMs != 10 || summary.SetupAvgMs != 120 || summary.SetupP95Ms != 500 || summary.SetupOutlierTests != 1 {
+		t.Errorf("summary setup = min %v, avg %v, p95 %v, outliers %d, want 10, 120, 500, 1",
+			summary.SetupMinMs, summary.SetupAvgMs, summary.SetupP95Ms, summary.SetupOutlierTests)
+	}
+}
+
+func TestAggregator_ThroughputSource(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	agg := NewAggregator()
+	results := []*pb.TestResult{
+		{TestId: "totals", Status: completed, IperfJson: `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 9e8}}}`},
+		{TestId: "udp-sum", Status: completed, IperfJson: `{"start": {"test_start": {"protocol": "UDP"}}, "end": {"sum": {"bits_per_second": 1e8}}}`},
+		{TestId: "streams", Status: completed, IperfJson: `{"end": {"streams": [{"sender": {"bits_per_second": 2e9}, "receiver": {"bits_per_second": 1.9e9}}]}}`},
+		{TestId: "bidir", Status: completed, IperfJson: `{"end": {"sum_sent": {"bits_per_second": 5e9}, "sum_received": {"bits_per_second": 5e9},
+			"sum_sent_bidir_reverse": {"bits_per_second": 3e9}, "sum_received_bidir_reverse": {"bits_per_second": 2.9e9}}}`},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	byID := make(map[string]*TestResult)
+	for _, result := range agg.GetResults() {
+		byID[result.TestID] = result
+	}
+	tests := []struct {
+		testID     string
+		throughput float64
+		source     string
+		reverse    float64
+	}{
+		{"totals", 9e8, "", 0},
+		{"udp-sum", 1e8, "sum", 0},
+		{"streams", 1.9e9, "streams", 0},
+		{"bidir", 5e9, "", 2.9e9},
+	}
+	for _, tt := range tests {
+		result := byID[tt.testID]
+		if result.ThroughputBps != tt.throughput || result.ThroughputSource != tt.source || result.ReverseThroughputBps != tt.reverse {
+			t.Errorf("%s: throughput = %v from %q, reverse %v; want %v from %q, reverse %v", tt.testID,
+				result.ThroughputBps, result.ThroughputSource, result.ReverseThroughputBps, tt.throughput, tt.source, tt.reverse)
+		}
+	}
+}
+
+func TestAggregator_ReconcileThroughput(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	tests := []struct {
+		name        string
+		iperfJSON   string
+		threshold   float64
+		throughput  float64
+		discrepancy float64
+		flagged     bool
+	}{
+		{"at threshold", `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 9.5e8}}}`,
+			5, 9.5e8, 5, false},
+		{"past threshold", `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 9.49e8}}}`,
+			5, 9.49e8, 5.1, true},
+		{"strict", `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 9.99e8}}}`,
+			0, 9.99e8, 0.1, true},
+		{"strict and equal", `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 1e9}}}`,
+			0, 1e9, 0, false},
+		{"no sum_received", `{"end": {"sum_sent": {"bits_per_second": 1e9}}}`,
+			5, 1e9, 0, false},
+		{"server results", `{"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 1e9}},
+			"server_output_json": {"end": {"sum_received": {"bits_per_second": 8e8}}}}`,
+			5, 8e8, 20, true},
+		{"server results without sum_received", `{"end": {"sum_sent": {"bits_per_second": 1e9}},
+			"server_output_json": {"end": {"sum_received": {"bits_per_second": 9.8e8}}}}`,
+			5, 9.8e8, 2, false},
+		{"UDP server results", `{"start": {"test_start": {"protocol": "UDP"}}, "end": {"sum": {"bits_per_second": 1e8}},
+			"server_output_json": {"end": {"sum": {"bits_per_second": 9e7}}}}`,
+			5, 9e7, 10, true},
+		{"reverse test", `{"start": {"test_start": {"reverse": 1}},
+			"end": {"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 9.9e8}},
+			"server_output_json": {"end": {"sum_received": {"bits_per_second": 1e3}}}}`,
+			5, 9.9e8, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg := NewAggregator()
+			agg.SetDiscrepancyThreshold(tt.threshold)
+			if err := agg.AddResults([]*pb.TestResult{{TestId: "t1", Status: completed, IperfJson: tt.iperfJSON}}); err != nil {
+				t.Fatalf("AddResults() error = %v", err)
+			}
+
+			result := agg.GetResults()[0]
+			if result.ThroughputBps != tt.throughput {
+				t.Errorf("ThroughputBps = %v, want %v", result.ThroughputBps, tt.throughput)
+			}
+			if math.Abs(result.DiscrepancyPercent-tt.discrepancy) > 1e-9 || result.DiscrepancyFlagged != tt.flagged {
+				t.Errorf("discrepancy = %v%%, flagged %t; want %v%%, flagged %t",
+					result.DiscrepancyPercent, result.DiscrepancyFlagged, tt.discrepancy, tt.flagged)
+			}
+			wantDiscrepant := 0
+			if tt.flagged {
+				wantDiscrepant = 1
+			}
+			if got := agg.GetSummary().DiscrepantTests; got != wantDiscrepant {
+				t.Errorf("DiscrepantTests = %d, want %d", got, wantDiscrepant)
+			}
+		})
+	}
+}
+
+func TestAggregator_AddressFamilies(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	result := func(testID string, bps float64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: completed,
+			IperfJson: fmt.Sprintf(`{"end": {"sum_received": {"bits_per_second": %g}}}`, bps)}
+	}
+
+	agg := NewAggregator()
+	agg.SetTestFamilies(map[string]string{"a-v4": "v4", "a-v6": "v6", "b-v4": "v4", "b-v6": "v6"})
+	results := []*pb.TestResult{
+		result("a-v4", 10e9),
+		result("a-v6", 7e9),
+		result("b-v4", 10e9),
+		result("b-v6", 9.5e9),
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	lagging := V6Lagging(agg.GetResults())
+	if len(lagging) != 1 || lagging[0].TestID != "a-v6" || lagging[0].V6LagPercent != 30 {
+		t.Fatalf("V6Lagging() = %v, want a-v6 30%% behind", lagging)
+	}
+
+	summary := agg.GetSummary()
+	if summary.V6LaggingTests != 1 {
+		t.Errorf("V6LaggingTests = %d, want 1", summary.V6LaggingTests)
+	}
+	v4, v6 := summary.Families["v4"], summary.Families["v6"]
+	if v4 == nil || v6 == nil || v4.CompletedTests != 2 || v4.AvgThroughput != 10e9 || v6.AvgThroughput != 8.25e9 {
+		t.Errorf("Families = v4 %+v, v6 %+v; want 2 tests each averaging 10e9 and 8.25e9", v4, v6)
+	}
+
+	// Raising the threshold clears the flag
+	agg.SetV6LagThreshold(50)
+	if err := agg.AddResults(nil); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+	if lagging := V6Lagging(agg.GetResults()); len(lagging) != 0 {
+		t.Errorf("V6Lagging() at 50%% = %v, want none", lagging)
+	}
+}
+
+func TestAggregator_GroupPairs(t *testing.T) {
+	result := func(testID string, status pb.TestStatus, bps float64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: status,
+			IperfJson: fmt.Sprintf(`{"end": {"sum_received": {"bits_per_second": %g}}}`, bps)}
+	}
+
+	agg := NewAggregator()
+	agg.SetTestGroupPairs(map[string]string{"a": "a1->a2", "b": "a1->a2", "c": "a2->a1"})
+	results := []*pb.TestResult{
+		result("a", pb.TestStatus_TEST_STATUS_COMPLETED, 8e9),
+		result("b", pb.TestStatus_TEST_STATUS_COMPLETED, 6e9),
+		result("c", pb.TestStatus_TEST_STATUS_FAILED, 0),
+		result("d", pb.TestStatus_TEST_STATUS_COMPLETED, 9e9),
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	for _, result := range agg.GetResults() {
+		if result.TestID == "a" && result.GroupPair != "a1->a2" {
+			t.Errorf("GroupPair = %q, want a1->a2", result.GroupPair)
+		}
+	}
+	summary := agg.GetSummary()
+	if len(summary.GroupPairs) != 2 {
+		t.Fatalf("GroupPairs = %v, want a1->a2 and a2->a1", summary.GroupPairs)
+	}
+	if across := summary.GroupPairs["a1->a2"]; across.CompletedTests != 2 || across.AvgThroughput != 7e9 {
+		t.Errorf("a1->a2 = %+v, want 2 completed averaging 7e9", across)
+	}
+	if back := summary.GroupPairs["a2->a1"]; back.TotalTests != 1 || back.FailedTests != 1 {
+		t.Errorf("a2->a1 = %+v, want 1 failed test", back)
+	}
+}
+
+func TestAggregator_Interfaces(t *testing.T) {
+	result := func(testID string, bps float64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: pb.TestStatus_TEST_STATUS_COMPLETED,
+			IperfJson: fmt.Sprintf(`{"end": {"sum_received": {"bits_per_second": %g}}}`, bps)}
+	}
+
+	agg := NewAggregator()
+	agg.SetTestInterfaces(map[string]string{"a-frontend": "frontend", "a-backend": "backend", "b-backend": "backend"})
+	results := []*pb.TestResult{
+		result("a-frontend", 9e9),
+		result(models.SubTestID("a-backend", 0), 12e9),
+		result(models.SubTestID("a-backend", 1), 12e9),
+		result("b-backend", 20e9),
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	for _, result := range agg.GetResults() {
+		if _, want, _ := strings.Cut(result.TestID, "-"); result.Interface != want {
+			t.Errorf("%s: Interface = %q, want %q", result.TestID, result.Interface, want)
+		}
+	}
+	summary := agg.GetSummary()
+	frontend, backend := summary.Interfaces["frontend"], summary.Interfaces["backend"]
+	if frontend == nil || backend == nil || frontend.CompletedTests != 1 || backend.CompletedTests != 2 || backend.AvgThroughput != 22e9 {
+		t.Errorf("Interfaces = frontend %+v, backend %+v; want 1 and 2 tests, backend averaging 22e9", frontend, backend)
+	}
+}
+
+func TestAggregator_ChainHops(t *testing.T) {
+	agg := NewAggregator()
+	agg.SetTestHops(map[string]int{"a-to-b": 1, "b-to-c": 2, "a-to-c": 0})
+	results := []*pb.TestResult{
+		{TestId: "a-to-b", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+		{TestId: "b-to-c", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+		{TestId: "a-to-c", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+		{TestId: "other", Status: pb.TestStatus_TEST_STATUS_COMPLETED},
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	want := map[string]string{"a-to-b": "1 false", "b-to-c": "2 false", "a-to-c": "0 true", "other": "0 false"}
+	for _, result := range agg.GetResults() {
+		if got := fmt.Sprintf("%d %t", result.Hop, result.EndToEnd); got != want[result.TestID] {
+			t.Errorf("%s: hop and end to end = %s, want %s", result.TestID, got, want[result.TestID])
+		}
+	}
+}
+
+func TestAggregator_Bisection(t *testing.T) {
+	result := func(testID string, status pb.TestStatus, bps float64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: status,
+			IperfJson: fmt.Sprintf(`{"end": {"sum_received": {"bits_per_second": %g}}}`, bps)}
+	}
+	results := []*pb.TestResult{
+		result("a", pb.TestStatus_TEST_STATUS_COMPLETED, 9e9),
+		result("b", pb.TestStatus_TEST_STATUS_COMPLETED, 7e9),
+		result("c", pb.TestStatus_TEST_STATUS_FAILED, 0),
+	}
+
+	agg := NewAggregator()
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+	if got := agg.GetSummary().BisectionBps; got != 0 {
+		t.Errorf("BisectionBps = %v without a bisection topology, want 0", got)
+	}
+	agg.SetBisection(true)
+	if got := agg.GetSummary().BisectionBps; got != 16e9 {
+		t.Errorf("BisectionBps = %v, want 16e9, the completed tests together", got)
+	}
+}
+
+func TestAggregator_Confidence(t *testing.T) {
+	result := func(testID string, end int64, iperfJSON string) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, Status: pb.TestStatus_TEST_STATUS_COMPLETED,
+			StartTimeUnix: 100, EndTimeUnix: end, IperfJson: iperfJSON}
+	}
+	totals := `"sum_sent": {"bits_per_second": 1e9}, "sum_received": {"bits_per_second": 1e9}`
+	results := []*pb.TestResult{
+		result("clean", 110, `{"end": {`+totals+`}}`),
+		result("busy", 110, `{"end": {`+totals+`, "cpu_utilization_percent": {"host_total": 97, "remote_total": 20}}}`),
+		// The sender of a reverse test is the remote side
+		result("busy-reverse", 110, `{"start": {"test_start": {"reverse": 1}},
+			"end": {`+totals+`, "cpu_utilization_percent": {"host_total": 20, "remote_total": 95}}}`),
+		result("long", 130, `{"end": {`+totals+`}}`),
+		result("long-fallback", 130, `{"end": {"streams": [{"sender": {"bits_per_second": 1e9}, "receiver": {"bits_per_second": 1e9}}]}}`),
+		{TestId: "failed", Status: pb.TestStatus_TEST_STATUS_FAILED},
+	}
+
+	rate := func(policy ConfidencePolicy) map[string]*TestResult {
+		agg := NewAggregator()
+		agg.SetConfidencePolicy(policy)
+		if err := agg.AddResults(results); err != nil {
+			t.Fatalf("AddResults() error = %v", err)
+		}
+		byID := make(map[string]*TestResult)
+		for _, result := range agg.GetResults() {
+			byID[result.TestID] = result
+		}
+		summary := agg.GetSummary()
+		if got := summary.HighConfidenceTests + summary.MediumConfidenceTests + summary.LowConfidenceTests; got != 5 {
+			t.Errorf("tests by confidence = %d, want the 5 completed", got)
+		}
+		return byID
+	}
+
+	byID := rate(DefaultConfidencePolicy())
+	want := map[string]string{
+		"clean":         ConfidenceHigh,
+		"busy":          ConfidenceLow,
+		"busy-reverse":  ConfidenceLow,
+		"long":          ConfidenceMedium, // 11 of 31 seconds in the common window
+		"long-fallback": ConfidenceLow,    // Two medium rules
+		"failed":        "",
+	}
+	for testID, confidence := range want {
+		if got := byID[testID].Confidence; got != confidence {
+			t.Errorf("%s: confidence = %q (%v), want %q", testID, got, byID[testID].ConfidenceReasons, confidence)
+		}
+	}
+	if reasons := byID["busy"].ConfidenceReasons; len(reasons) != 1 || !strings.HasPrefix(reasons[0], RuleCPULimited+": ") {
+		t.Errorf("busy: reasons = %v, want cpu_limited", reasons)
+	}
+
+	policy := DefaultConfidencePolicy()
+	policy.Disabled = map[string]bool{RuleCPULimited: true, RuleLowOverlap: true}
+	byID = rate(policy)
+	for _, testID := range []string{"busy", "long"} {
+		if got := byID[testID].Confidence; got != ConfidenceHigh {
+			t.Errorf("%s with its rule disabled: confidence = %q, want high", testID, got)
+		}
+	}
+	if got := byID["long-fallback"].Confidence; got != ConfidenceMedium {
+		t.Errorf("long-fallback with low_overlap disabled: confidence = %q, want medium", got)
+	}
+}
+
+func TestMTUHeuristics(t *testing.T) {
+	result := func(testID string, bps float64) *TestResult {
+		return &TestResult{TestID: testID, SourceNode: "node1", DestNode: "node2",
+			Status: "TEST_STATUS_COMPLETED", ThroughputBps: bps}
+	}
+	results := []*TestResult{
+		result("a", 10e9),
+		result("e", 10e9),
+		result("b", 9e9),
+		result("c", 2e9),
+		result("d", 1e9),
+		result("udp", 1e6),
+	}
+	results[2].RetransmitsFlagged = true
+	tcp := map[string]bool{"a": true, "b": true, "c": true, "d": true, "e": true}
+
+	suspects := MTUSuspects(results, tcp, 0)
+	if len(suspects) != 3 || suspects[0].TestID != "d" || suspects[1].TestID != "c" || suspects[2].TestID != "b" {
+		t.Fatalf("MTUSuspects() = %v, want d, c and b", suspects)
+	}
+	if limited := MTUSuspects(results, tcp, 1); len(limited) != 1 || limited[0].TestID != "d" {
+		t.Errorf("MTUSuspects() with limit 1 = %v, want d", limited)
+	}
+
+	// Good at the smaller MSS, degraded at the jumbo one
+	diagnosis := DiagnoseMTU(suspects[0], map[int]*TestResult{
+		1400: result("mtu1400-d", 9e9),
+		4000: result("mtu4000-d", 8e9),
+		8900: result("mtu8900-d", 1e9),
+	})
+	if !diagnosis.Suspected || diagnosis.GoodMSS != 4000 || diagnosis.DegradedMSS != 8900 {
+		t.Errorf("DiagnoseMTU() = %+v, want good at 4000 and degraded at 8900", diagnosis)
+	}
+	want := "suspected MTU issue between node1 and node2 (good at MSS 4000, degraded at 8900)"
+	if diagnosis.Summary != want {
+		t.Errorf("Summary = %q, want %q", diagnosis.Summary, want)
+	}
+
+	// A failed probe counts as degraded
+	failed := result("mtu8900-d", 0)
+	failed.Status = "TEST_STATUS_FAILED"
+	diagnosis = DiagnoseMTU(suspects[0], map[int]*TestResult{1400: result("mtu1400-d", 9e9), 8900: failed})
+	if !diagnosis.Suspected || diagnosis.DegradedMSS != 8900 {
+		t.Errorf("DiagnoseMTU() with a failed probe = %+v, want degraded at 8900", diagnosis)
+	}
+
+	// Uniformly low throughput does not depend on the MSS
+	diagnosis = DiagnoseMTU(suspects[0], map[int]*TestResult{
+		1400: result("mtu1400-d", 1e9),
+		8900: result("mtu8900-d", 0.9e9),
+	})
+	if diagnosis.Suspected || diagnosis.GoodMSS != 0 {
+		t.Errorf("DiagnoseMTU() with even probes = %+v, want no suspicion", diagnosis)
+	}
+}
+
+func TestFlakiness(t *testing.T) {
+	result := func(source, dest, status string, bps float64) *TestResult {
+		return &TestResult{SourceNode: source, DestNode: dest, Status: status, ThroughputBps: bps}
+	}
+	const completed, failed = "TEST_STATUS_COMPLETED", "TEST_STATUS_FAILED"
+	background := result("node2", "node1", failed, 0)
+	background.Background = true
+	results := []*TestResult{
+		result("node1", "node2", completed, 8e9),
+		result("node1", "node2", completed, 12e9),
+		result("node1", "node3", completed, 10e9),
+		result("node1", "node3", failed, 0),
+		result("node1", "node3", completed, 10e9),
+		result("node1", "node3", StatusSkippedBlackout, 0),
+		result("node2", "node3", completed, 5e9),
+		background,
+	}
+
+	flakiness := Flakiness(results)
+	if len(flakiness) != 3 {
+		t.Fatalf("Flakiness() returned %d pairs, want 3", len(flakiness))
+	}
+	// Ordered by success ratio, then throughput variation
+	got := make([]string, 0, len(flakiness))
+	for _, pair := range flakiness {
+		got = append(got, fmt.Sprintf("%s>%s %d/%d %.2f %.2f", pair.SourceNode, pair.DestNode,
+			pair.Failures, pair.Attempts, pair.SuccessRatio, pair.ThroughputCV))
+	}
+	want := []string{"node1>node3 1/3 0.67 0.00", "node1>node2 0/2 1.00 0.20", "node2>node3 0/1 1.00 0.00"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Flakiness() = %v, want %v", got, want)
+	}
+	if flakiness[1].AvgThroughputBps != 10e9 {
+		t.Errorf("AvgThroughputBps = %v, want 10e9", flakiness[1].AvgThroughputBps)
+	}
+
+	if !Repeated(flakiness) {
+		t.Error("Repeated() = false, want true")
+	}
+	if Repeated(Flakiness(results[6:])) {
+		t.Error("Repeated() of single attempts = true, want false")
+	}
+}
+
+func TestFlakiness_PerInterface(t *testing.T) {
+	result := func(iface, profile, status string, bps float64) *TestResult {
+		return &TestResult{SourceNode: "node1", DestNode: "node2", Interface: iface, Profile: profile,
+			Status: status, ThroughputBps: bps}
+	}
+	const completed, failed = "TEST_STATUS_COMPLETED", "TEST_STATUS_FAILED"
+	// A flaky eth1 and a steady eth0, each also run with a UDP profile
+	results := []*TestResult{
+		result("eth0", "", completed, 10e9),
+		result("eth1", "", failed, 0),
+		result("eth0", "", completed, 10e9),
+		result("eth1", "", completed, 2e9),
+		result("eth0", "udp", completed, 1e9),
+		result("eth1", "udp", completed, 1e9),
+	}
+
+	got := make([]string, 0, len(results))
+	for _, pair := range Flakiness(results) {
+		got = append(got, fmt.Sprintf("%s/%s %d/%d %.0f", pair.Interface, pair.Profile,
+			pair.Failures, pair.Attempts, pair.AvgThroughputBps))
+	}
+	want := []string{"eth1/ 1/2 2000000000", "eth0/ 0/2 10000000000", "eth0/udp 0/1 1000000000", "eth1/udp 0/1 1000000000"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("Flakiness() = %v, want %v", got, want)
+	}
+}
+
+func TestRepetitions(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	result := func(testID string, status pb.TestStatus, bps float64) *pb.TestResult {
+		return &pb.TestResult{TestId: testID, SourceId: "node1", DestinationId: "node2", Status: status,
+			IperfJson: fmt.Sprintf(`{"end": {"sum_received": {"bits_per_second": %g}}}`, bps)}
+	}
+
+	agg := NewAggregator()
+	repetitions := make(map[string]string)
+	for i := 1; i <= 10; i++ {
+		repetitions[fmt.Sprintf("a-rep%d", i)] = "a"
+	}
+	repetitions["b-rep1"], repetitions["b-rep2"] = "b", "b"
+	agg.SetTestRepetitions(repetitions)
+	results := []*pb.TestResult{
+		result("b-rep1", pb.TestStatus_TEST_STATUS_FAILED, 0),
+		result("b-rep2", pb.TestStatus_TEST_STATUS_FAILED, 0),
+		result("once", completed, 1e9),
+	}
+	for i := 1; i <= 10; i++ {
+		results = append(results, result(fmt.Sprintf("a-rep%d", i), completed, float64(i)*1e9))
+	}
+	if err := agg.AddResults(results); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+
+	pairs := Repetitions(agg.GetResults())
+	if len(pairs) != 2 {
+		t.Fatalf("Repetitions() returned %d pairs, want 2", len(pairs))
+	}
+	a, b := pairs[0], pairs[1]
+	if a.TestID != "a" || a.Repetitions != 10 || a.Completed != 10 ||
+		a.MinThroughputBps != 1e9 || a.MaxThroughputBps != 10e9 || a.AvgThroughputBps != 5.5e9 {
+		t.Errorf("Repetitions()[0] = %+v", a)
+	}
+	if a.TestIDs[1] != "a-rep2" || a.TestIDs[9] != "a-rep10" {
+		t.Errorf("TestIDs = %v, want in repetition order", a.TestIDs)
+	}
+	if b.TestID != "b" || b.Repetitions != 2 || b.Completed != 0 || b.MinThroughputBps != 0 {
+		t.Errorf("Repetitions()[1] = %+v", b)
+	}
+}
+
+// recordingSink records the results streamed to it
+type recordingSink struct {
+	results []*TestResult
+}
+
+func (s *recordingSink) WriteResult(result *TestResult) error {
+	s.results = append(s.results, result)
+	return nil
+}
+
+func TestAggregator_Streaming(t *testing.T) {
+	completed := pb.TestStatus_TEST_STATUS_COMPLETED
+	ran := func(testID string, gbps float64, runMs int64) *pb.TestResult {
+		bps := gbps * 1e9
+		data := fmt.Sprintf(`{"start": {"timestamp": {"timesecs": 1000}},
+			"intervals": [{"sum": {"start": 0, "end": 2, "bits_per_second": %g}}],
+			"end": {"sum_sent": {"bits_per_second": %g}, "sum_received": {"bits_per_second": %g}}}`, bps, bps, bps)
+		return &pb.TestResult{TestId: testID, Status: completed, IperfJson: data,
+			StartTimeUnixMs: 1000000, EndTimeUnixMs: 1000000 + runMs}
+	}
+	// Two nodes' results, the second with a multi-process pair and a failure
+	batches := [][]*pb.TestResult{
+		{ran("a", 1, 2010), ran("b", 2, 2020), ran("c", 3, 2030)},
+		{ran("d", 4, 2500), ran("multi#0", 2, 2015), ran("multi#1", 3, 2040),
+			{TestId: "failed", Status: pb.TestStatus_TEST_STATUS_FAILED}},
+	}
+
+	inMemory := NewAggregator()
+	streaming := NewAggregator()
+	sink := &recordingSink{}
+	streaming.SetStreaming(sink, 1)
+	for _, batch := range batches {
+		if err := inMemory.AddResults(batch); err != nil {
+			t.Fatalf("AddResults() error = %v", err)
+		}
+		if err := streaming.AddResults(batch); err != nil {
+			t.Fatalf("AddResults() streaming error = %v", err)
+		}
+	}
+
+	if len(sink.results) != 6 || streaming.GetResultCount() != 6 {
+		t.Errorf("streamed %d results, count %d; want 6", len(sink.results), streaming.GetResultCount())
+	}
+	// The fastest, the slowest, and the first failed or flagged result: d,
+	// whose setup time is an outlier once the second node's are in
+	sample := make([]string, 0)
+	for _, result := range streaming.GetResults() {
+		sample = append(sample, result.TestID)
+	}
+	if strings.Join(sample, ",") != "a,d,multi" {
+		t.Errorf("GetResults() = %v, want the sample a, d and multi", sample)
+	}
+
+	// Few enough setup times for the estimated percentiles to be exact
+	want, got := inMemory.GetSummary(), streaming.GetSummary()
+	if fmt.Sprintf("%+v", *got) != fmt.Sprintf("%+v", *want) {
+		t.Errorf("streaming summary = %+v, want %+v", *got, *want)
+	}
+	if len(streaming.Timeline().Buckets) != 2 || streaming.Timeline().Buckets[0].Tests != 5 {
+		t.Errorf("streaming Timeline() = %+v, want 2 buckets of 5 tests", streaming.Timeline().Buckets)
+	}
+}
+
+func TestTDigest(t *testing.T) {
+	const n = 100000
+	var digest tdigest
+	for i := 1; i <= n; i++ {
+		digest.add(float64(i))
+	}
+	// Within 0.1% of the rank
+	for _, q := range []float64{0.001, 0.01, 0.5, 0.95, 0.999} {
+		if got := digest.quantile(q); math.Abs(got-q*n) > n/1000 {
+			t.Errorf("quantile(%v) = %v, want about %v", q, got, q*n)
+		}
+	}
+	if len(digest.centroids) > 10*tdigestCompression {
+		t.Errorf("digest keeps %d centroids, want at most %d", len(digest.centroids), 10*tdigestCompression)
+	}
+}
+
+func TestAggregator_Bursts(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "udp_bursts.json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+
+	agg := NewAggregator()
+	if err := agg.AddResults([]*pb.TestResult{
+		{TestId: "burst", SourceId: "node1", Status: pb.TestStatus_TEST_STATUS_COMPLETED, IperfJson: string(data)},
+	}); err != nil {
+		t.Fatalf("AddResults() error = %v", err)
+	}
+	result := agg.GetResults()[0]
+
+	want := []BurstResult{
+		{Index: 0, ThroughputBps: 998.4e6, JitterMs: 0.011, LostPackets: 0, Packets: 864},
+		{Index: 1, ThroughputBps: 987.2e6, JitterMs: 0.048, LostPackets: 12, Packets: 864, LostPercent: 1.388889},
+		{Index: 2, ThroughputBps: 979.2e6, JitterMs: 0.093, LostPackets: 12, Packets: 864, LostPercent: 1.388889},
+	}
+	if len(result.Bursts) != len(want) {
+		t.Fatalf("got %d bursts, want %d", len(result.Bursts), len(want))
+	}
+	for i, burst := range result.Bursts {
+		if *burst != want[i] {
+			t.Errorf("burst %d = %+v, want %+v", i, *burst, want[i])
+		}
+	}
+	// Loss ties between the last two; the higher jitter breaks it
+	if result.WorstBurst != result.Bursts[2] {
+		t.Errorf("WorstBurst = %+v, want burst 2", result.WorstBurst)
+	}
+	if math.Abs(result.ThroughputBps-988.266667e6) > 1 {
+		t.Errorf("ThroughputBps = %f, want the mean of the bursts", result.ThroughputBps)
+	}
+}
+
+func TestExtractBursts(t *testing.T) {
+	sum := func(bps, lost float64) map[string]interface{} {
+		return map[string]interface{}{"end": map[string]interface{}{
+			"sum": map[string]interface{}{"bits_per_second": bps, "lost_percent": lost},
+		}}
+	}
+
+	tests := []struct {
+		name      string
+		data      map[string]interface{}
+		wantIndex []int
+		wantBps   float64
+		wantWorst int
+	}{
+		{"no bursts", map[string]interface{}{}, nil, 0, -1},
+		{"empty", map[string]interface{}{"bursts": []interface{}{}}, []int{}, 0, -1},
+		{"incomplete bursts skipped", map[string]interface{}{"bursts": []interface{}{
+			sum(100, 0), "garbage", map[string]interface{}{"start": map[string]interface{}{}},
+			map[string]interface{}{"end": map[string]interface{}{}}, sum(300, 2),
+		}}, []int{0, 4}, 200, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bursts := extractBursts(tt.data)
+			if (bursts == nil) != (tt.wantIndex == nil) || len(bursts) != len(tt.wantIndex) {
+				t.Fatalf("extractBursts() = %v, want bursts %v", bursts, tt.wantIndex)
+			}
+			for i, burst := range bursts {
+				if burst.Index != tt.wantIndex[i] {
+					t.Errorf("burst %d has index %d, want %d", i, burst.Index, tt.wantIndex[i])
+				}
+			}
+
+			result := &TestResult{}
+			applyBursts(result, bursts)
+			if result.ThroughputBps != tt.wantBps {
+				t.Errorf("ThroughputBps = %f, want %f", result.ThroughputBps, tt.wantBps)
+			}
+			if tt.wantWorst < 0 {
+				if result.WorstBurst != nil {
+					t.Errorf("WorstBurst = %+v, want none", result.WorstBurst)
+				}
+			} else if result.WorstBurst == nil || result.WorstBurst.Index != tt.wantWorst {
+				t.Errorf("WorstBurst = %+v, want burst %d", result.WorstBurst, tt.wantWorst)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// StatusSkippedBlackout is the status of a test not run because blackout
+// windows of its nodes would have held it back longer than allowed
+const StatusSkippedBlackout = "TEST_STATUS_SKIPPED_BLACKOUT"
+
+// BlackoutDeferral records that a test started later than scheduled to
+// stay clear of blackout windows of its nodes
+type BlackoutDeferral struct {
+	DelaySeconds float64  `json:"delay_seconds"`
+	Nodes        []string `json:"nodes"` // Nodes whose windows the test waited for
+}
+
+// SetTestDeferrals sets the blackout deferral of each deferred test ID,
+// used to annotate results
+func (a *Aggregator) SetTestDeferrals(deferrals map[string]*BlackoutDeferral) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testDeferrals = deferrals
+}
+
+// AddBlackoutSkip adds the result of a test skipped because blackout
+// windows of nodes would have held it back for wait
+func (a *Aggregator) AddBlackoutSkip(testID string, wait time.Duration, nodes []string) error {
+	a.mu.RLock()
+	result := &TestResult{
+		TestID:        testID,
+		SourceNode:    a.testSources[testID],
+		DestNode:      a.testDestinations[testID],
+		Profile:       a.testProfiles[testID],
+		Group:         a.testGroups[testID],
+		GroupPair:     a.testGroupPairs[testID],
+		RepeatOf:      a.testRepetitions[testID],
+		Labels:        a.testLabels[testID],
+		AddressFamily: a.testFamilies[testID],
+		Interface:     a.testInterfaces[testID],
+		Background:    a.backgroundTests[testID],
+		Status:        StatusSkippedBlackout,
+		ErrorMessage: fmt.Sprintf("skipped: blackout windows of %s would have held it back %v",
+			strings.Join(nodes, ", "), wait.Round(time.Second)),
+	}
+	result.Hop, result.EndToEnd = a.hopOf(testID)
+	a.mu.RUnlock()
+
+	a.addResult(result)
+	return a.flushStream()
+}
@@ -0,0 +1,95 @@
+package aggregator
+
+import "sort"
+
+// CollectionError records a node whose results could not be retrieved, and
+// the expected tests run from it that are missing a result as a consequence
+type CollectionError struct {
+	Node           string   `json:"node"`
+	Error          string   `json:"error"`
+	MissingResults int      `json:"missing_results"`
+	MissingTests   []string `json:"missing_tests,omitempty"`
+}
+
+// SetTestSources sets the source node of each test ID. Client results are
+// stored on the source node's daemon, so the missing results of a node that
+// could not be collected from are the tests it is the source of.
+func (a *Aggregator) SetTestSources(sources map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testSources = sources
+}
+
+// SetTestDestinations sets the destination node of each test ID
+func (a *Aggregator) SetTestDestinations(destinations map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testDestinations = destinations
+}
+
+// AddCollectionError records that the results of a node could not be
+// retrieved
+func (a *Aggregator) AddCollectionError(nodeID string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.collectionErrors[nodeID] = err.Error()
+}
+
+// RestoreResults adds results aggregated earlier, such as those read back
+// from a results file, so that late results can be merged with them
+func (a *Aggregator) RestoreResults(results []*TestResult) {
+	for _, result := range results {
+		a.addResult(result)
+	}
+}
+
+// GetCollectionErrors returns the nodes whose results could not be
+// retrieved, sorted by node, with the results still missing for each
+func (a *Aggregator) GetCollectionErrors() []*CollectionError {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if len(a.collectionErrors) == 0 {
+		return nil
+	}
+
+	missing := make(map[string][]string)
+	for testID, source := range a.testSources {
+		if _, collected := a.results[testID]; collected {
+			continue
+		}
+		if _, failed := a.collectionErrors[source]; failed {
+			missing[source] = append(missing[source], testID)
+		}
+	}
+
+	errors := make([]*CollectionError, 0, len(a.collectionErrors))
+	for nodeID, message := range a.collectionErrors {
+		tests := missing[nodeID]
+		sort.Strings(tests)
+		errors = append(errors, &CollectionError{
+			Node:           nodeID,
+			Error:          message,
+			MissingResults: len(tests),
+			MissingTests:   tests,
+		})
+	}
+	sort.Slice(errors, func(i, j int) bool { return errors[i].Node < errors[j].Node })
+
+	return errors
+}
+
+// MissingTestSources maps every test listed as missing in errors to the node
+// it is run from, for collecting the missing results later
+func MissingTestSources(errors []*CollectionError) map[string]string {
+	sources := make(map[string]string)
+	for _, collectionErr := range errors {
+		for _, testID := range collectionErr.MissingTests {
+			sources[testID] = collectionErr.Node
+		}
+	}
+	return sources
+}
@@ -0,0 +1,261 @@
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Confidence levels of a result
+const (
+	ConfidenceHigh   = "high"
+	ConfidenceMedium = "medium"
+	ConfidenceLow    = "low"
+)
+
+// Names of the confidence rules, as disabled in ConfidencePolicy
+const (
+	RuleCPULimited         = "cpu_limited"
+	RuleLowOverlap         = "low_overlap"
+	RuleRetransmitStorm    = "retransmit_storm"
+	RuleThroughputFallback = "throughput_fallback"
+)
+
+// Defaults of the confidence rules
+const (
+	DefaultConfidenceCPUPercent = 90.0
+	DefaultConfidenceMinOverlap = 0.5
+)
+
+// ConfidencePolicy tunes the rules that rate how far each result can be
+// trusted. CPUPercent is the sender iperf3 process CPU usage, and
+// MinOverlap the share of a test's run time inside the window all tests of
+// its group ran in, below which the rules apply.
+type ConfidencePolicy struct {
+	Disabled   map[string]bool // Rule names
+	CPUPercent float64
+	MinOverlap float64
+}
+
+// DefaultConfidencePolicy returns the policy with every rule enabled at its
+// default threshold
+func DefaultConfidencePolicy() ConfidencePolicy {
+	return ConfidencePolicy{
+		CPUPercent: DefaultConfidenceCPUPercent,
+		MinOverlap: DefaultConfidenceMinOverlap,
+	}
+}
+
+// confidenceRule is a measurement hygiene check. A result a low rule
+// applies to is low confidence; one medium rule makes it medium
+// confidence, and two make it low.
+type confidenceRule struct {
+	name  string
+	level string
+	// check returns why the rule applies to result, or "" if it does not
+	check func(result *TestResult, policy ConfidencePolicy, windows map[string]timeWindow) string
+}
+
+// confidenceRules are the checks results are rated by
+var confidenceRules = []confidenceRule{
+	{name: RuleCPULimited, level: ConfidenceLow, check: checkCPULimited},
+	{name: RuleLowOverlap, level: ConfidenceMedium, check: checkLowOverlap},
+	{name: RuleRetransmitStorm, level: ConfidenceMedium, check: checkRetransmitStorm},
+	{name: RuleThroughputFallback, level: ConfidenceMedium, check: checkThroughputFallback},
+}
+
+// ConfidenceRules returns the names of the confidence rules, in the order
+// they are checked
+func ConfidenceRules() []string {
+	names := make([]string, 0, len(confidenceRules))
+	for _, rule := range confidenceRules {
+		names = append(names, rule.name)
+	}
+	return names
+}
+
+// SetConfidencePolicy sets the rules results are rated by
+func (a *Aggregator) SetConfidencePolicy(policy ConfidencePolicy) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.confidencePolicy = policy
+}
+
+// checkCPULimited applies when the sender's iperf3 process kept a core
+// busy, so the throughput may measure the host rather than the network
+func checkCPULimited(result *TestResult, policy ConfidencePolicy, _ map[string]timeWindow) string {
+	if policy.CPUPercent <= 0 || result.SenderCPUPercent < policy.CPUPercent {
+		return ""
+	}
+	return fmt.Sprintf("sender iperf3 used %.0f%% CPU", result.SenderCPUPercent)
+}
+
+// checkLowOverlap applies when a test spent much of its time outside the
+// window all tests of its group ran in, so it ran under a different load
+// than the others
+func checkLowOverlap(result *TestResult, policy ConfidencePolicy, windows map[string]timeWindow) string {
+	window, exists := windows[result.Group]
+	if !exists || !concurrent(result) {
+		return ""
+	}
+	overlap := (window.end - window.start) / float64(result.EndTime+1-result.StartTime)
+	if overlap >= policy.MinOverlap {
+		return ""
+	}
+	return fmt.Sprintf("%.0f%% of the test overlapped the window its group ran in", overlap*100)
+}
+
+// checkRetransmitStorm applies to tests flagged for retransmits
+func checkRetransmitStorm(result *TestResult, _ ConfidencePolicy, _ map[string]timeWindow) string {
+	if !result.RetransmitsFlagged {
+		return ""
+	}
+	return fmt.Sprintf("%.1f retransmits per MB", result.RetransmitsPerMB)
+}
+
+// checkThroughputFallback applies when the throughput was not read from
+// iperf3's totals
+func checkThroughputFallback(result *TestResult, _ ConfidencePolicy, _ map[string]timeWindow) string {
+	if result.ThroughputSource == "" {
+		return ""
+	}
+	return fmt.Sprintf("throughput read from %q instead of the totals", result.ThroughputSource)
+}
+
+// concurrent reports whether result is a completed measurement meant to
+// start with the others of its group, rather than staggered or deferred
+func concurrent(result *TestResult) bool {
+	return rated(result) && result.StartDelayMs == 0 && result.DeferredByBlackout == nil &&
+		result.StartTime > 0 && result.EndTime >= result.StartTime
+}
+
+// rated reports whether result is rated: a completed measurement
+func rated(result *TestResult) bool {
+	return !result.Background && !result.Diagnostic && result.Status == "TEST_STATUS_COMPLETED"
+}
+
+// commonWindows returns, by group, the window every concurrent test of the
+// group ran in, narrowing those in windows. Test times have one-second
+// resolution, so windows extend to the end of their last second. The
+// window of a group whose tests never all ran at once, such as a throttled
+// one, is empty.
+func commonWindows(results map[string]*TestResult, windows map[string]timeWindow) map[string]timeWindow {
+	narrowed := make(map[string]timeWindow, len(windows))
+	for group, window := range windows {
+		narrowed[group] = window
+	}
+	for _, result := range results {
+		if !concurrent(result) {
+			continue
+		}
+		window := timeWindow{start: float64(result.StartTime), end: float64(result.EndTime + 1)}
+		if current, exists := narrowed[result.Group]; exists {
+			window.start = math.Max(window.start, current.start)
+			window.end = math.Min(window.end, current.end)
+		}
+		narrowed[result.Group] = window
+	}
+	return narrowed
+}
+
+// rateConfidence sets the confidence of result and the reasons for it from
+// the enabled rules
+func rateConfidence(result *TestResult, policy ConfidencePolicy, windows map[string]timeWindow) {
+	result.Confidence = ""
+	result.ConfidenceReasons = nil
+	if !rated(result) {
+		return
+	}
+
+	low, medium := false, 0
+	for _, rule := range confidenceRules {
+		if policy.Disabled[rule.name] {
+			continue
+		}
+		reason := rule.check(result, policy, windows)
+		if reason == "" {
+			continue
+		}
+		result.ConfidenceReasons = append(result.ConfidenceReasons, rule.name+": "+reason)
+		if rule.level == ConfidenceLow {
+			low = true
+		} else {
+			medium++
+		}
+	}
+
+	switch {
+	case low || medium >= 2:
+		result.Confidence = ConfidenceLow
+	case medium == 1:
+		result.Confidence = ConfidenceMedium
+	default:
+		result.Confidence = ConfidenceHigh
+	}
+}
+
+// rateResults rates the confidence of every result; the caller must hold
+// a.mu. Groups whose common window is empty are not checked for overlap.
+func (a *Aggregator) rateResults() {
+	windows := usableWindows(commonWindows(a.results, nil))
+	for _, result := range a.results {
+		rateConfidence(result, a.confidencePolicy, windows)
+	}
+}
+
+// usableWindows returns the windows of windows that are not empty
+func usableWindows(windows map[string]timeWindow) map[string]timeWindow {
+	usable := make(map[string]timeWindow, len(windows))
+	for group, window := range windows {
+		if window.end > window.start {
+			usable[group] = window
+		}
+	}
+	return usable
+}
+
+// LowConfidence returns the measured results rated low confidence, sorted
+// by test ID
+func LowConfidence(results []*TestResult) []*TestResult {
+	low := make([]*TestResult, 0)
+	for _, result := range results {
+		if result.Confidence == ConfidenceLow {
+			low = append(low, result)
+		}
+	}
+	sort.Slice(low, func(i, j int) bool { return low[i].TestID < low[j].TestID })
+	return low
+}
+
+// extractSenderCPU returns the CPU usage iperf3 reports for the sending
+// side's process, the higher of both sides for --bidir tests, or 0 when it
+// is not reported
+func extractSenderCPU(data map[string]interface{}) float64 {
+	end, ok := data["end"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	cpu, ok := end["cpu_utilization_percent"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	host, _ := cpu["host_total"].(float64)
+	remote, _ := cpu["remote_total"].(float64)
+
+	var reverse, bidir float64
+	if start, ok := data["start"].(map[string]interface{}); ok {
+		if testStart, ok := start["test_start"].(map[string]interface{}); ok {
+			reverse, _ = testStart["reverse"].(float64)
+			bidir, _ = testStart["bidir"].(float64)
+		}
+	}
+	switch {
+	case bidir != 0:
+		return math.Max(host, remote)
+	case reverse != 0:
+		return remote
+	default:
+		return host
+	}
+}
@@ -0,0 +1,38 @@
+package aggregator
+
+import "sort"
+
+// extractCongestionControl returns the congestion control algorithm iperf3
+// reports the sending side used, or "" for UDP tests and iperf3 versions
+// that do not report it
+func extractCongestionControl(data map[string]interface{}) string {
+	end, ok := data["end"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	algorithm, _ := end["sender_tcp_congestion"].(string)
+	return algorithm
+}
+
+// congestionControlDiffers reports whether the sender used another algorithm
+// than requested, as iperf3 does silently when the requested one is not
+// loaded. Unknown algorithms on either side are never flagged.
+func congestionControlDiffers(requested, used string) bool {
+	return requested != "" && used != "" && requested != used
+}
+
+// CongestionControlMismatches returns the completed, measured results whose
+// sender used a different congestion control algorithm than requested,
+// sorted by test ID
+func CongestionControlMismatches(results []*TestResult) []*TestResult {
+	mismatches := make([]*TestResult, 0)
+	for _, result := range results {
+		if result.CongestionControlMismatch && !result.Background && result.Status == "TEST_STATUS_COMPLETED" {
+			mismatches = append(mismatches, result)
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool {
+		return mismatches[i].TestID < mismatches[j].TestID
+	})
+	return mismatches
+}
@@ -0,0 +1,75 @@
+package aggregator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// DefaultV6LagThreshold is the default percentage by which a pair's IPv6
+// throughput may fall below its IPv4 throughput before it is flagged
+const DefaultV6LagThreshold = 10.0
+
+// SetTestFamilies sets the address family of each test ID run over a
+// single family, used to tag results and pair them across families
+func (a *Aggregator) SetTestFamilies(families map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testFamilies = families
+}
+
+// SetV6LagThreshold sets the percentage by which a pair's IPv6 throughput
+// may fall below its IPv4 throughput before it is flagged; negative
+// disables flagging
+func (a *Aggregator) SetV6LagThreshold(percent float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.v6LagThreshold = percent
+}
+
+// flagV6Lag compares the completed IPv6 tests of dual-stack pairs with their
+// IPv4 counterparts, setting how far the IPv6 throughput lags and flagging
+// lags above the threshold. The caller must hold a.mu.
+func (a *Aggregator) flagV6Lag() {
+	v4 := make(map[string]*TestResult)
+	for id, result := range a.results {
+		if result.AddressFamily == string(models.FamilyV4) && result.Status == "TEST_STATUS_COMPLETED" {
+			v4[strings.TrimSuffix(id, "-"+result.AddressFamily)] = result
+		}
+	}
+
+	for id, result := range a.results {
+		result.V6LagPercent = 0
+		result.V6LagFlagged = false
+		if result.AddressFamily != string(models.FamilyV6) || result.Status != "TEST_STATUS_COMPLETED" {
+			continue
+		}
+		counterpart, exists := v4[strings.TrimSuffix(id, "-"+result.AddressFamily)]
+		if !exists || counterpart.ThroughputBps <= 0 {
+			continue
+		}
+		result.V6LagPercent = (counterpart.ThroughputBps - result.ThroughputBps) / counterpart.ThroughputBps * 100
+		result.V6LagFlagged = a.v6LagThreshold >= 0 && result.V6LagPercent > a.v6LagThreshold
+	}
+}
+
+// V6Lagging returns the IPv6 results flagged for lagging their IPv4
+// counterparts, furthest behind first
+func V6Lagging(results []*TestResult) []*TestResult {
+	lagging := make([]*TestResult, 0)
+	for _, result := range results {
+		if result.V6LagFlagged {
+			lagging = append(lagging, result)
+		}
+	}
+	sort.Slice(lagging, func(i, j int) bool {
+		if lagging[i].V6LagPercent != lagging[j].V6LagPercent {
+			return lagging[i].V6LagPercent > lagging[j].V6LagPercent
+		}
+		return lagging[i].TestID < lagging[j].TestID
+	})
+	return lagging
+}
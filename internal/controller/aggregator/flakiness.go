@@ -0,0 +1,122 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+)
+
+// PairFlakiness measures how reliably a pair performed over its attempts,
+// the measured tests of the same source, destination, address family,
+// interface and profile in a run, such as the pair's tests in several
+// topology groups or the same pair listed more than once in a custom
+// topology
+type PairFlakiness struct {
+	SourceNode    string `json:"source_node"`
+	DestNode      string `json:"dest_node"`
+	AddressFamily string `json:"address_family,omitempty"`
+	Interface     string `json:"interface,omitempty"`
+	Profile       string `json:"profile,omitempty"`
+	Attempts      int    `json:"attempts"`
+	Failures      int    `json:"failures"` // Attempts that did not complete
+	// SuccessRatio is the share of attempts that completed
+	SuccessRatio float64 `json:"success_ratio"`
+	// Mean throughput of the completed attempts, and its coefficient of
+	// variation: the standard deviation relative to the mean
+	AvgThroughputBps float64 `json:"avg_throughput_bps"`
+	ThroughputCV     float64 `json:"throughput_cv"`
+}
+
+// Flakiness returns the flakiness of every pair of measured results,
+// flakiest first: lowest success ratio, then highest throughput variation.
+// Tests skipped for blackout windows were not attempted and are left out.
+func Flakiness(results []*TestResult) []*PairFlakiness {
+	type pairKey struct{ source, dest, family, iface, profile string }
+	byPair := make(map[pairKey]*PairFlakiness)
+	throughputs := make(map[pairKey][]float64)
+	for _, result := range results {
+		if result.Background || result.Diagnostic || result.Status == StatusSkippedBlackout {
+			continue
+		}
+		key := pairKey{result.SourceNode, result.DestNode, result.AddressFamily, result.Interface, result.Profile}
+		pair := byPair[key]
+		if pair == nil {
+			pair = &PairFlakiness{
+				SourceNode:    result.SourceNode,
+				DestNode:      result.DestNode,
+				AddressFamily: result.AddressFamily,
+				Interface:     result.Interface,
+				Profile:       result.Profile,
+			}
+			byPair[key] = pair
+		}
+		pair.Attempts++
+		if result.Status != "TEST_STATUS_COMPLETED" {
+			pair.Failures++
+			continue
+		}
+		throughputs[key] = append(throughputs[key], result.ThroughputBps)
+	}
+
+	flakiness := make([]*PairFlakiness, 0, len(byPair))
+	for key, pair := range byPair {
+		pair.SuccessRatio = float64(pair.Attempts-pair.Failures) / float64(pair.Attempts)
+		pair.AvgThroughputBps, pair.ThroughputCV = meanAndCV(throughputs[key])
+		flakiness = append(flakiness, pair)
+	}
+	sort.Slice(flakiness, func(i, j int) bool {
+		a, b := flakiness[i], flakiness[j]
+		if a.SuccessRatio != b.SuccessRatio {
+			return a.SuccessRatio < b.SuccessRatio
+		}
+		if a.ThroughputCV != b.ThroughputCV {
+			return a.ThroughputCV > b.ThroughputCV
+		}
+		if a.SourceNode != b.SourceNode {
+			return a.SourceNode < b.SourceNode
+		}
+		if a.DestNode != b.DestNode {
+			return a.DestNode < b.DestNode
+		}
+		if a.AddressFamily != b.AddressFamily {
+			return a.AddressFamily < b.AddressFamily
+		}
+		if a.Interface != b.Interface {
+			return a.Interface < b.Interface
+		}
+		return a.Profile < b.Profile
+	})
+	return flakiness
+}
+
+// Repeated reports whether any pair was attempted more than once, without
+// which flakiness says no more than the results themselves
+func Repeated(flakiness []*PairFlakiness) bool {
+	for _, pair := range flakiness {
+		if pair.Attempts > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// meanAndCV returns the mean of values and their coefficient of variation,
+// the population standard deviation divided by the mean; the latter is 0
+// for fewer than two values or a zero mean
+func meanAndCV(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+	if len(values) < 2 || mean == 0 {
+		return mean, 0
+	}
+	variance := 0.0
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(variance/float64(len(values))) / mean
+}
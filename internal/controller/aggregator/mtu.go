@@ -0,0 +1,125 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A completed TCP test is suspected of an MTU issue when its throughput is
+// below mtuLowThroughputFactor times the median of the run's TCP tests, or
+// its retransmit rate is flagged. A probe is degraded when its throughput is
+// below mtuDegradedFactor times the best probe of the pair.
+const (
+	mtuLowThroughputFactor = 0.5
+	mtuDegradedFactor      = 0.5
+)
+
+// MTUProbe is the outcome of one follow-up probe of a pair at one MSS
+type MTUProbe struct {
+	MSS              int     `json:"mss"`
+	TestID           string  `json:"test_id"`
+	Status           string  `json:"status"`
+	ThroughputBps    float64 `json:"throughput_bps"`
+	RetransmitsPerMB float64 `json:"retransmits_per_mb,omitempty"`
+	Degraded         bool    `json:"degraded,omitempty"`
+}
+
+// MTUDiagnosis is the MTU heuristic's finding for a low-throughput TCP
+// pair, from probing it at several MSS values. An MTU issue is suspected
+// when the pair does well at a smaller MSS and is degraded at a larger one.
+type MTUDiagnosis struct {
+	SourceNode  string      `json:"source_node"`
+	DestNode    string      `json:"dest_node"`
+	TestID      string      `json:"test_id"` // The flagged test
+	Suspected   bool        `json:"suspected"`
+	GoodMSS     int         `json:"good_mss,omitempty"`
+	DegradedMSS int         `json:"degraded_mss,omitempty"`
+	Summary     string      `json:"summary"`
+	Probes      []*MTUProbe `json:"probes"`
+}
+
+// MTUSuspects returns the completed, measured TCP tests worth probing for
+// an MTU issue, lowest throughput relative to the median first, at most
+// limit of them. tcp holds the IDs of the run's TCP tests.
+func MTUSuspects(results []*TestResult, tcp map[string]bool, limit int) []*TestResult {
+	candidates := make([]*TestResult, 0)
+	throughputs := make([]float64, 0)
+	for _, result := range results {
+		if !tcp[result.TestID] || result.Background || result.Diagnostic || result.Status != "TEST_STATUS_COMPLETED" {
+			continue
+		}
+		candidates = append(candidates, result)
+		throughputs = append(throughputs, result.ThroughputBps)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Float64s(throughputs)
+	median := percentile(throughputs, 50)
+
+	suspects := make([]*TestResult, 0)
+	for _, result := range candidates {
+		if result.RetransmitsFlagged || result.ThroughputBps < median*mtuLowThroughputFactor {
+			suspects = append(suspects, result)
+		}
+	}
+	sort.Slice(suspects, func(i, j int) bool {
+		if suspects[i].ThroughputBps != suspects[j].ThroughputBps {
+			return suspects[i].ThroughputBps < suspects[j].ThroughputBps
+		}
+		return suspects[i].TestID < suspects[j].TestID
+	})
+	if limit > 0 && len(suspects) > limit {
+		suspects = suspects[:limit]
+	}
+	return suspects
+}
+
+// DiagnoseMTU compares the probes of suspect, by MSS, marking those well
+// below the best as degraded. Failed probes count as degraded.
+func DiagnoseMTU(suspect *TestResult, probes map[int]*TestResult) *MTUDiagnosis {
+	diagnosis := &MTUDiagnosis{
+		SourceNode: suspect.SourceNode,
+		DestNode:   suspect.DestNode,
+		TestID:     suspect.TestID,
+		Probes:     make([]*MTUProbe, 0, len(probes)),
+	}
+
+	best := 0.0
+	for mss, result := range probes {
+		probe := &MTUProbe{
+			MSS:              mss,
+			TestID:           result.TestID,
+			Status:           result.Status,
+			ThroughputBps:    result.ThroughputBps,
+			RetransmitsPerMB: result.RetransmitsPerMB,
+		}
+		if result.Status == "TEST_STATUS_COMPLETED" && result.ThroughputBps > best {
+			best = result.ThroughputBps
+		}
+		diagnosis.Probes = append(diagnosis.Probes, probe)
+	}
+	sort.Slice(diagnosis.Probes, func(i, j int) bool {
+		return diagnosis.Probes[i].MSS < diagnosis.Probes[j].MSS
+	})
+
+	for _, probe := range diagnosis.Probes {
+		probe.Degraded = probe.Status != "TEST_STATUS_COMPLETED" || probe.ThroughputBps < best*mtuDegradedFactor
+		if !probe.Degraded {
+			diagnosis.GoodMSS = probe.MSS
+			diagnosis.DegradedMSS = 0
+		} else if diagnosis.GoodMSS > 0 && diagnosis.DegradedMSS == 0 {
+			diagnosis.DegradedMSS = probe.MSS
+		}
+	}
+	// A degraded probe must follow the largest good one
+	diagnosis.Suspected = best > 0 && diagnosis.GoodMSS > 0 && diagnosis.DegradedMSS > 0
+	if !diagnosis.Suspected {
+		diagnosis.GoodMSS, diagnosis.DegradedMSS = 0, 0
+		diagnosis.Summary = fmt.Sprintf("no MSS-dependent degradation between %s and %s", diagnosis.SourceNode, diagnosis.DestNode)
+		return diagnosis
+	}
+	diagnosis.Summary = fmt.Sprintf("suspected MTU issue between %s and %s (good at MSS %d, degraded at %d)",
+		diagnosis.SourceNode, diagnosis.DestNode, diagnosis.GoodMSS, diagnosis.DegradedMSS)
+	return diagnosis
+}
@@ -0,0 +1,86 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+)
+
+// PairRepetitions summarizes the runs of a pair repeated within a run, so
+// the spread of a link's throughput can be seen. Runs skipped for blackout
+// windows were not attempted and are left out.
+type PairRepetitions struct {
+	TestID        string `json:"test_id"` // Test ID of the pair, without the repetition suffix
+	SourceNode    string `json:"source_node"`
+	DestNode      string `json:"dest_node"`
+	AddressFamily string `json:"address_family,omitempty"`
+	Repetitions   int    `json:"repetitions"`
+	Completed     int    `json:"completed"`
+	// Throughput of the completed runs
+	MinThroughputBps float64 `json:"min_throughput_bps"`
+	MaxThroughputBps float64 `json:"max_throughput_bps"`
+	AvgThroughputBps float64 `json:"avg_throughput_bps"`
+	// TestIDs are the test IDs of the runs, in order
+	TestIDs []string `json:"test_ids"`
+}
+
+// SetTestRepetitions sets the test ID of the repeated pair each run of one
+// is a repetition of, used to tag results and summarize them per pair
+func (a *Aggregator) SetTestRepetitions(repetitions map[string]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.testRepetitions = repetitions
+}
+
+// Repetitions returns the throughput spread of every repeated pair of
+// results, by test ID
+func Repetitions(results []*TestResult) []*PairRepetitions {
+	byPair := make(map[string]*PairRepetitions)
+	for _, result := range results {
+		if result.RepeatOf == "" || result.Status == StatusSkippedBlackout {
+			continue
+		}
+		pair := byPair[result.RepeatOf]
+		if pair == nil {
+			pair = &PairRepetitions{
+				TestID:           result.RepeatOf,
+				SourceNode:       result.SourceNode,
+				DestNode:         result.DestNode,
+				AddressFamily:    result.AddressFamily,
+				MinThroughputBps: math.Inf(1),
+				TestIDs:          make([]string, 0),
+			}
+			byPair[result.RepeatOf] = pair
+		}
+		pair.Repetitions++
+		pair.TestIDs = append(pair.TestIDs, result.TestID)
+		if result.Status != "TEST_STATUS_COMPLETED" {
+			continue
+		}
+		pair.Completed++
+		pair.MinThroughputBps = math.Min(pair.MinThroughputBps, result.ThroughputBps)
+		pair.MaxThroughputBps = math.Max(pair.MaxThroughputBps, result.ThroughputBps)
+		pair.AvgThroughputBps += result.ThroughputBps
+	}
+
+	repetitions := make([]*PairRepetitions, 0, len(byPair))
+	for _, pair := range byPair {
+		if pair.Completed > 0 {
+			pair.AvgThroughputBps /= float64(pair.Completed)
+		} else {
+			pair.MinThroughputBps = 0
+		}
+		// The IDs differ only in their repetition number, which sorts by
+		// length first
+		sort.Slice(pair.TestIDs, func(i, j int) bool {
+			a, b := pair.TestIDs[i], pair.TestIDs[j]
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return a < b
+		})
+		repetitions = append(repetitions, pair)
+	}
+	sort.Slice(repetitions, func(i, j int) bool { return repetitions[i].TestID < repetitions[j].TestID })
+	return repetitions
+}
@@ -0,0 +1,213 @@
+package aggregator
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+)
+
+// NodeUsage is the resource usage a node's daemon sampled during the run
+type NodeUsage struct {
+	Samples        []*UsageSample `json:"samples"`
+	PeakCPUPercent float64        `json:"peak_cpu_percent"`
+	// PeakTestCPUPercent is the peak while tests the node took part in ran
+	PeakTestCPUPercent float64 `json:"peak_test_cpu_percent"`
+	CPUFlagged         bool    `json:"cpu_flagged,omitempty"` // Above the CPU threshold while its tests ran
+}
+
+// UsageSample is one resource usage sample
+type UsageSample struct {
+	Time            float64     `json:"time"` // Unix seconds, on the controller's clock
+	CPUPercent      float64     `json:"cpu_percent"`
+	MemoryUsedBytes uint64      `json:"memory_used_bytes"`
+	MemoryPercent   float64     `json:"memory_percent"`
+	NICs            []*NICUsage `json:"nics,omitempty"`
+}
+
+// NICUsage is a NIC's byte counters at a sample, and its rates since the
+// previous sample
+type NICUsage struct {
+	Name      string  `json:"name"`
+	BytesSent uint64  `json:"bytes_sent"`
+	BytesRecv uint64  `json:"bytes_recv"`
+	TxBps     float64 `json:"tx_bps"`
+	RxBps     float64 `json:"rx_bps"`
+}
+
+// SetCPUThreshold sets the CPU usage percentage above which nodes are
+// flagged while their tests run; 0 disables flagging
+func (a *Aggregator) SetCPUThreshold(percent float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cpuThreshold = percent
+}
+
+// CollectSamples retrieves the resource samples of the nodes whose daemons
+// take them. Nodes that fail are reported in the error and the samples of
+// the others are kept.
+func (a *Aggregator) CollectSamples(ctx context.Context, clientPool *client.Pool) error {
+	failures := nodeerr.New("resource sample collection")
+	for _, c := range clientPool.GetAllClients() {
+		caps, exists := clientPool.GetCapabilities(c.Node.ID)
+		if !exists || !caps.Supports(models.FeatureResourceSamples) {
+			continue
+		}
+		resp, err := c.Client.GetSamples(ctx, &pb.GetSamplesRequest{})
+		if err != nil {
+			failures.Add(c.Node.ID, err)
+			continue
+		}
+		a.AddSamples(c.Node.ID, resp.Samples)
+	}
+	return failures.ErrorOrNil()
+}
+
+// AddSamples stores the resource samples of a node, replacing any it had
+func (a *Aggregator) AddSamples(nodeID string, samples []*pb.ResourceSample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(samples) == 0 {
+		return
+	}
+	if a.samples == nil {
+		a.samples = make(map[string][]*UsageSample)
+	}
+
+	offset := a.clockOffsets[nodeID].Seconds()
+	converted := make([]*UsageSample, 0, len(samples))
+	var prev *UsageSample
+	for _, sample := range samples {
+		usage := &UsageSample{
+			Time:            float64(sample.TimeUnixMs)/1000 - offset,
+			CPUPercent:      sample.CpuPercent,
+			MemoryUsedBytes: sample.MemoryUsedBytes,
+			MemoryPercent:   sample.MemoryPercent,
+		}
+		for _, nic := range sample.Nics {
+			usage.NICs = append(usage.NICs, &NICUsage{
+				Name:      nic.Name,
+				BytesSent: nic.BytesSent,
+				BytesRecv: nic.BytesRecv,
+			})
+		}
+		if prev != nil {
+			setNICRates(prev, usage)
+		}
+		converted = append(converted, usage)
+		prev = usage
+	}
+	a.samples[nodeID] = converted
+}
+
+// setNICRates sets the rates of the NICs in cur from the change in their
+// counters since prev. Counters that went backwards, such as after a NIC was
+// reset, leave the rates at 0.
+func setNICRates(prev, cur *UsageSample) {
+	elapsed := cur.Time - prev.Time
+	if elapsed <= 0 {
+		return
+	}
+	previous := make(map[string]*NICUsage, len(prev.NICs))
+	for _, nic := range prev.NICs {
+		previous[nic.Name] = nic
+	}
+	for _, nic := range cur.NICs {
+		before, exists := previous[nic.Name]
+		if !exists {
+			continue
+		}
+		if nic.BytesSent >= before.BytesSent {
+			nic.TxBps = float64(nic.BytesSent-before.BytesSent) * 8 / elapsed
+		}
+		if nic.BytesRecv >= before.BytesRecv {
+			nic.RxBps = float64(nic.BytesRecv-before.BytesRecv) * 8 / elapsed
+		}
+	}
+}
+
+// ResourceUsage returns the sampled resource usage by node ID, or nil if no
+// node returned samples. A node is flagged when a sample taken while one of
+// its measured tests ran, as client or server, exceeds the CPU threshold.
+func (a *Aggregator) ResourceUsage() map[string]*NodeUsage {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if len(a.samples) == 0 {
+		return nil
+	}
+
+	windows := a.testWindows()
+	usage := make(map[string]*NodeUsage, len(a.samples))
+	for nodeID, samples := range a.samples {
+		node := &NodeUsage{Samples: samples}
+		for _, sample := range samples {
+			node.PeakCPUPercent = math.Max(node.PeakCPUPercent, sample.CPUPercent)
+			if windows[nodeID].contain(sample.Time) {
+				node.PeakTestCPUPercent = math.Max(node.PeakTestCPUPercent, sample.CPUPercent)
+			}
+		}
+		node.CPUFlagged = a.cpuThreshold > 0 && node.PeakTestCPUPercent > a.cpuThreshold
+		usage[nodeID] = node
+	}
+	return usage
+}
+
+// HighCPUNodes returns the IDs of the flagged nodes in usage, sorted
+func HighCPUNodes(usage map[string]*NodeUsage) []string {
+	nodes := make([]string, 0)
+	for nodeID, node := range usage {
+		if node.CPUFlagged {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// timeWindow is a span of Unix seconds on the controller's clock
+type timeWindow struct {
+	start, end float64
+}
+
+// timeWindows is a set of spans
+type timeWindows []timeWindow
+
+// contain reports whether t falls in any of the windows
+func (w timeWindows) contain(t float64) bool {
+	for _, window := range w {
+		if t >= window.start && t < window.end {
+			return true
+		}
+	}
+	return false
+}
+
+// testWindows returns, by node, when the measured tests it took part in ran;
+// the caller must hold a.mu. Test times come from the client's clock and
+// have one-second resolution, so windows extend to the end of their last
+// second.
+func (a *Aggregator) testWindows() map[string]timeWindows {
+	windows := make(map[string]timeWindows)
+	for _, result := range a.results {
+		if result.Background || result.StartTime <= 0 || result.EndTime < result.StartTime {
+			continue
+		}
+		offset := a.clockOffsets[result.SourceNode].Seconds()
+		window := timeWindow{
+			start: float64(result.StartTime) - offset,
+			end:   float64(result.EndTime) + 1 - offset,
+		}
+		windows[result.SourceNode] = append(windows[result.SourceNode], window)
+		if result.DestNode != result.SourceNode {
+			windows[result.DestNode] = append(windows[result.DestNode], window)
+		}
+	}
+	return windows
+}
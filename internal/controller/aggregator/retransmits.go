@@ -0,0 +1,91 @@
+package aggregator
+
+import (
+	"sort"
+)
+
+// bytesPerMB is the divisor for retransmits per megabyte (decimal, as iperf3
+// reports transfer sizes)
+const bytesPerMB = 1e6
+
+// SetRetransmitThreshold sets the retransmits per MB above which a test is
+// flagged; 0 disables flagging
+func (a *Aggregator) SetRetransmitThreshold(perMB float64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.retransmitThreshold = perMB
+}
+
+// normalizeRetransmits computes the retransmit rate per MB sent and, when the
+// segment count is known, the segment retransmission ratio, and flags the
+// result if the rate exceeds threshold (0 disables flagging)
+func normalizeRetransmits(result *TestResult, threshold float64) {
+	result.RetransmitsPerMB = 0
+	result.RetransmitRatio = 0
+	result.RetransmitsFlagged = false
+
+	if result.BytesSent <= 0 {
+		return
+	}
+	result.RetransmitsPerMB = float64(result.Retransmits) / (float64(result.BytesSent) / bytesPerMB)
+	if result.SegmentsSent > 0 {
+		result.RetransmitRatio = float64(result.Retransmits) / float64(result.SegmentsSent)
+	}
+	result.RetransmitsFlagged = threshold > 0 && result.RetransmitsPerMB > threshold
+}
+
+// extractBytesSent extracts the bytes sent from iperf JSON data
+func extractBytesSent(data map[string]interface{}) int64 {
+	end, ok := data["end"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	sumSent, ok := end["sum_sent"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	bytes, ok := sumSent["bytes"].(float64)
+	if !ok {
+		return 0
+	}
+	return int64(bytes)
+}
+
+// extractMSS extracts the TCP maximum segment size iperf3 used, preferring an
+// explicitly set MSS over the default
+func extractMSS(data map[string]interface{}) int64 {
+	start, ok := data["start"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	for _, key := range []string{"tcp_mss", "tcp_mss_default"} {
+		if mss, ok := start[key].(float64); ok && mss > 0 {
+			return int64(mss)
+		}
+	}
+	return 0
+}
+
+// TopRetransmitRates returns up to n measured results with the highest
+// retransmit rate per MB, highest first
+func TopRetransmitRates(results []*TestResult, n int) []*TestResult {
+	ranked := make([]*TestResult, 0, len(results))
+	for _, result := range results {
+		if !result.Background && result.RetransmitsPerMB > 0 {
+			ranked = append(ranked, result)
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].RetransmitsPerMB != ranked[j].RetransmitsPerMB {
+			return ranked[i].RetransmitsPerMB > ranked[j].RetransmitsPerMB
+		}
+		return ranked[i].TestID < ranked[j].TestID
+	})
+
+	if len(ranked) > n {
+		ranked = ranked[:n]
+	}
+	return ranked
+}
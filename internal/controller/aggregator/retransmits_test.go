@@ -0,0 +1,174 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeRetransmits(t *testing.T) {
+	tests := []struct {
+		name        string
+		result      TestResult
+		threshold   float64
+		wantPerMB   float64
+		wantRatio   float64
+		wantFlagged bool
+	}{
+		{
+			name:      "per MB sent",
+			result:    TestResult{Retransmits: 50, BytesSent: 10_000_000},
+			wantPerMB: 5,
+		},
+		{
+			name:      "ratio with segments",
+			result:    TestResult{Retransmits: 50, BytesSent: 10_000_000, SegmentsSent: 6898},
+			wantPerMB: 5,
+			wantRatio: 50.0 / 6898,
+		},
+		{
+			name:   "nothing sent",
+			result: TestResult{Retransmits: 50},
+		},
+		{
+			name:   "negative bytes",
+			result: TestResult{Retransmits: 50, BytesSent: -1, SegmentsSent: 10},
+		},
+		{
+			name:   "no retransmits",
+			result: TestResult{BytesSent: 10_000_000, SegmentsSent: 6898},
+		},
+		{
+			name:        "above threshold",
+			result:      TestResult{Retransmits: 51, BytesSent: 10_000_000},
+			threshold:   5,
+			wantPerMB:   5.1,
+			wantFlagged: true,
+		},
+		{
+			name:      "at threshold",
+			result:    TestResult{Retransmits: 50, BytesSent: 10_000_000},
+			threshold: 5,
+			wantPerMB: 5,
+		},
+		{
+			name:      "threshold 0 disables flagging",
+			result:    TestResult{Retransmits: 5000, BytesSent: 1_000_000},
+			wantPerMB: 5000,
+		},
+		{
+			// Values left from a previous normalization are reset
+			name:      "stale values cleared",
+			result:    TestResult{RetransmitsPerMB: 9, RetransmitRatio: 0.5, RetransmitsFlagged: true},
+			threshold: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.result
+			normalizeRetransmits(&result, tt.threshold)
+
+			if math.Abs(result.RetransmitsPerMB-tt.wantPerMB) > 1e-9 {
+				t.Errorf("RetransmitsPerMB = %v, want %v", result.RetransmitsPerMB, tt.wantPerMB)
+			}
+			if math.Abs(result.RetransmitRatio-tt.wantRatio) > 1e-9 {
+				t.Errorf("RetransmitRatio = %v, want %v", result.RetransmitRatio, tt.wantRatio)
+			}
+			if math.IsNaN(result.RetransmitsPerMB) || math.IsInf(result.RetransmitsPerMB, 0) {
+				t.Errorf("RetransmitsPerMB = %v, want a finite rate", result.RetransmitsPerMB)
+			}
+			if result.RetransmitsFlagged != tt.wantFlagged {
+				t.Errorf("RetransmitsFlagged = %t, want %t", result.RetransmitsFlagged, tt.wantFlagged)
+			}
+		})
+	}
+}
+
+func TestExtractBytesSent(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want int64
+	}{
+		{"sum_sent", `{"end":{"sum_sent":{"bytes":1250000000}}}`, 1250000000},
+		{"no end", `{"start":{}}`, 0},
+		{"no sum_sent", `{"end":{"sum_received":{"bytes":1000}}}`, 0},
+		{"no bytes", `{"end":{"sum_sent":{"seconds":10}}}`, 0},
+		{"bytes not a number", `{"end":{"sum_sent":{"bytes":"1000"}}}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractBytesSent(decodeJSON(t, tt.json)); got != tt.want {
+				t.Errorf("extractBytesSent() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractMSS(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want int64
+	}{
+		{"set MSS preferred", `{"start":{"tcp_mss":1400,"tcp_mss_default":1448}}`, 1400},
+		{"default MSS", `{"start":{"tcp_mss_default":1448}}`, 1448},
+		{"zero set MSS falls back", `{"start":{"tcp_mss":0,"tcp_mss_default":1448}}`, 1448},
+		{"UDP", `{"start":{"udp":{}}}`, 0},
+		{"no start", `{"end":{}}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractMSS(decodeJSON(t, tt.json)); got != tt.want {
+				t.Errorf("extractMSS() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopRetransmitRates(t *testing.T) {
+	results := []*TestResult{
+		{TestID: "low", RetransmitsPerMB: 1},
+		{TestID: "none"},
+		{TestID: "tie-b", RetransmitsPerMB: 5},
+		{TestID: "background", RetransmitsPerMB: 100, Background: true},
+		{TestID: "high", RetransmitsPerMB: 10},
+		{TestID: "tie-a", RetransmitsPerMB: 5},
+	}
+
+	tests := []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"all", 10, "high,tie-a,tie-b,low"},
+		{"top two", 2, "high,tie-a"},
+		{"none", 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			for _, result := range TopRetransmitRates(results, tt.n) {
+				ids = append(ids, result.TestID)
+			}
+			if got := strings.Join(ids, ","); got != tt.want {
+				t.Errorf("TopRetransmitRates(%d) = %s, want %s", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+// decodeJSON decodes an iperf3 JSON document for the extract functions
+func decodeJSON(t *testing.T, data string) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("invalid test JSON: %v", err)
+	}
+	return decoded
+}
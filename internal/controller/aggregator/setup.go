@@ -0,0 +1,129 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+)
+
+// A test's setup time is an outlier when it is setupOutlierFactor times the
+// median of the run and at least setupOutlierMinMs above it, so that fast
+// fabrics where every setup takes a few milliseconds don't flag noise
+const (
+	setupOutlierFactor = 3.0
+	setupOutlierMinMs  = 100.0
+)
+
+// measureSetup sets result.SetupMs to the time the client process ran
+// outside iperf3's reporting intervals: TCP connect and control-channel
+// setup before the first interval, and the result exchange after the last.
+// It needs the millisecond process times that older daemons don't report.
+func measureSetup(result *TestResult, startMs, endMs int64) {
+	if startMs <= 0 || endMs <= startMs {
+		return
+	}
+	dataSeconds, ok := extractDataSeconds(result.IperfData)
+	if !ok {
+		return
+	}
+	result.SetupMs = math.Max(float64(endMs-startMs)-dataSeconds*1000, 0)
+}
+
+// extractDataSeconds returns the end of the last iperf3 interval, relative
+// to the start of the first, omitted intervals included
+func extractDataSeconds(data map[string]interface{}) (float64, bool) {
+	raw, ok := data["intervals"].([]interface{})
+	if !ok {
+		return 0, false
+	}
+	last := 0.0
+	for _, entry := range raw {
+		interval, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if sum, ok := interval["sum"].(map[string]interface{}); ok {
+			if end, ok := sum["end"].(float64); ok {
+				last = math.Max(last, end)
+			}
+		}
+	}
+	return last, last > 0
+}
+
+// flagSetupOutliers flags the completed, measured tests whose setup time is
+// an outlier relative to the median of all of them. The caller must hold
+// a.mu.
+func (a *Aggregator) flagSetupOutliers() {
+	setups := make([]float64, 0, len(a.results))
+	for _, result := range a.results {
+		result.SetupFlagged = false
+		if hasSetup(result) {
+			setups = append(setups, result.SetupMs)
+		}
+	}
+	if len(setups) < 2 {
+		return
+	}
+	sort.Float64s(setups)
+	median := percentile(setups, 50)
+
+	for _, result := range a.results {
+		flagSetup(result, median)
+	}
+}
+
+// flagSetup flags result if its setup time is an outlier relative to median
+func flagSetup(result *TestResult, median float64) {
+	result.SetupFlagged = hasSetup(result) &&
+		result.SetupMs >= median*setupOutlierFactor && result.SetupMs-median >= setupOutlierMinMs
+}
+
+// hasSetup reports whether result is a completed, measured test with a
+// known setup time
+func hasSetup(result *TestResult) bool {
+	return !result.Background && result.Status == "TEST_STATUS_COMPLETED" && result.SetupMs > 0
+}
+
+// summarizeSetup sets the setup time statistics of summary from the setup
+// times of its tests
+func summarizeSetup(summary *Summary, setups []float64) {
+	if len(setups) == 0 {
+		return
+	}
+	sort.Float64s(setups)
+	total := 0.0
+	for _, setup := range setups {
+		total += setup
+	}
+	summary.SetupMinMs = setups[0]
+	summary.SetupAvgMs = total / float64(len(setups))
+	summary.SetupP95Ms = percentile(setups, 95)
+}
+
+// percentile returns the nearest-rank percentile p of sorted, which must not
+// be empty
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// SetupOutliers returns the results flagged for an outlying setup time,
+// slowest first
+func SetupOutliers(results []*TestResult) []*TestResult {
+	outliers := make([]*TestResult, 0)
+	for _, result := range results {
+		if result.SetupFlagged {
+			outliers = append(outliers, result)
+		}
+	}
+	sort.Slice(outliers, func(i, j int) bool {
+		if outliers[i].SetupMs != outliers[j].SetupMs {
+			return outliers[i].SetupMs > outliers[j].SetupMs
+		}
+		return outliers[i].TestID < outliers[j].TestID
+	})
+	return outliers
+}
@@ -0,0 +1,238 @@
+package aggregator
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// DefaultSampleSize is the number of results of each kind a streaming
+// aggregator keeps: the fastest, the slowest, and the failed or flagged
+const DefaultSampleSize = 100
+
+// ResultSink receives every result of a streaming aggregator as it is
+// collected
+type ResultSink interface {
+	WriteResult(result *TestResult) error
+}
+
+// streamState is the running statistics and result sample of a streaming
+// aggregator
+type streamState struct {
+	sink       ResultSink
+	sampleSize int
+	streamed   int // Results passed to the sink
+
+	running  *Summary
+	totals   *summaryTotals
+	setups   tdigest
+	setupMin float64
+	setupSum float64
+	timeline *timelineAccumulator
+
+	windows map[string]timeWindow // Common window of each group so far
+
+	fastest *rankedResults
+	slowest *rankedResults
+	notable []*TestResult // Failed and flagged results, up to sampleSize
+}
+
+// SetStreaming makes the aggregator stream results instead of keeping
+// them, for runs too large to hold in memory. The results of each node are
+// folded into running statistics and passed to sink as they are collected;
+// only the sampleSize fastest, slowest, and failed or flagged results are
+// kept, and GetResults returns that sample. Setup time percentiles are
+// estimated; setup outliers are flagged relative to the median, and
+// confidence rated relative to the common window, of the results collected
+// so far. A non-positive sampleSize keeps
+// DefaultSampleSize results of each kind.
+func (a *Aggregator) SetStreaming(sink ResultSink, sampleSize int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if sampleSize <= 0 {
+		sampleSize = DefaultSampleSize
+	}
+	a.stream = &streamState{
+		sink:       sink,
+		sampleSize: sampleSize,
+		running:    newSummary(),
+		totals:     newSummaryTotals(),
+		fastest: &rankedResults{limit: sampleSize, less: func(x, y *TestResult) bool {
+			return x.ThroughputBps < y.ThroughputBps
+		}},
+		slowest: &rankedResults{limit: sampleSize, less: func(x, y *TestResult) bool {
+			return x.ThroughputBps > y.ThroughputBps
+		}},
+	}
+}
+
+// Streaming reports whether the aggregator streams its results
+func (a *Aggregator) Streaming() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.stream != nil
+}
+
+// flushStream folds the results collected since the last flush into the
+// running statistics of a streaming aggregator, passes them to its sink in
+// test ID order and drops all but the sample. Results are flushed per node,
+// which returns every process and address family of the pairs it ran, so
+// that they are merged and compared as usual. It does nothing without
+// streaming.
+func (a *Aggregator) flushStream() error {
+	if !a.Streaming() {
+		return nil
+	}
+	a.mergeSubResults()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.flagV6Lag()
+	s := a.stream
+	if s.timeline == nil {
+		s.timeline = newTimelineAccumulator(a.timelineBucket)
+	}
+
+	testIDs := make([]string, 0, len(a.results))
+	for testID, result := range a.results {
+		testIDs = append(testIDs, testID)
+		if hasSetup(result) {
+			s.setups.add(result.SetupMs)
+		}
+	}
+	sort.Strings(testIDs)
+	s.setups.merge()
+	median := s.setups.quantile(0.5)
+	s.windows = commonWindows(a.results, s.windows)
+	windows := usableWindows(s.windows)
+
+	for _, testID := range testIDs {
+		result := a.results[testID]
+		result.SetupFlagged = false
+		if s.setups.count >= 2 {
+			flagSetup(result, median)
+		}
+		rateConfidence(result, a.confidencePolicy, windows)
+		if s.running.add(s.totals, result) && hasSetup(result) {
+			if s.setupSum == 0 || result.SetupMs < s.setupMin {
+				s.setupMin = result.SetupMs
+			}
+			s.setupSum += result.SetupMs
+		}
+		a.addToTimeline(s.timeline, result)
+
+		if err := s.sink.WriteResult(result); err != nil {
+			return err
+		}
+		s.streamed++
+		s.keep(result)
+		delete(a.results, testID)
+	}
+	return nil
+}
+
+// keep adds result to the sample if it is among the fastest or slowest
+// measured results so far, or failed or was flagged while there is room
+func (s *streamState) keep(result *TestResult) {
+	if result.Background {
+		return
+	}
+	if result.Status != "TEST_STATUS_COMPLETED" || isFlagged(result) {
+		if len(s.notable) < s.sampleSize {
+			s.notable = append(s.notable, result)
+		}
+		return
+	}
+	if result.ThroughputBps > 0 {
+		s.fastest.offer(result)
+		s.slowest.offer(result)
+	}
+}
+
+// isFlagged reports whether any analysis flagged result
+func isFlagged(result *TestResult) bool {
+	return result.RetransmitsFlagged || result.DiscrepancyFlagged || result.SetupFlagged ||
+		result.V6LagFlagged || result.AddressMismatch || result.CongestionControlMismatch ||
+		result.Confidence == ConfidenceLow
+}
+
+// summarize returns the summary of the results streamed so far
+func (s *streamState) summarize() *Summary {
+	summary := *s.running
+	summary.Groups = copyGroupSummaries(s.running.Groups)
+	summary.Families = copyGroupSummaries(s.running.Families)
+	summary.GroupPairs = copyGroupSummaries(s.running.GroupPairs)
+	summary.Interfaces = copyGroupSummaries(s.running.Interfaces)
+	summary.finish(s.totals)
+
+	if s.setups.count > 0 {
+		summary.SetupMinMs = s.setupMin
+		summary.SetupAvgMs = s.setupSum / s.setups.count
+		summary.SetupP95Ms = s.setups.quantile(0.95)
+	}
+	return &summary
+}
+
+// copyGroupSummaries returns a copy of summaries, or nil if it is nil
+func copyGroupSummaries(summaries map[string]*GroupSummary) map[string]*GroupSummary {
+	if summaries == nil {
+		return nil
+	}
+	copied := make(map[string]*GroupSummary, len(summaries))
+	for name, group := range summaries {
+		g := *group
+		copied[name] = &g
+	}
+	return copied
+}
+
+// sample returns the kept results, sorted by test ID
+func (s *streamState) sample() []*TestResult {
+	kept := make(map[string]*TestResult)
+	for _, results := range [][]*TestResult{s.notable, s.fastest.results, s.slowest.results} {
+		for _, result := range results {
+			kept[result.TestID] = result
+		}
+	}
+
+	sample := make([]*TestResult, 0, len(kept))
+	for _, result := range kept {
+		sample = append(sample, result)
+	}
+	sort.Slice(sample, func(i, j int) bool { return sample[i].TestID < sample[j].TestID })
+	return sample
+}
+
+// rankedResults keeps the limit highest-ranked results offered, as a heap
+// whose root is the lowest-ranked one kept
+type rankedResults struct {
+	results []*TestResult
+	limit   int
+	less    func(x, y *TestResult) bool // Whether x ranks below y
+}
+
+func (r *rankedResults) Len() int           { return len(r.results) }
+func (r *rankedResults) Less(i, j int) bool { return r.less(r.results[i], r.results[j]) }
+func (r *rankedResults) Swap(i, j int)      { r.results[i], r.results[j] = r.results[j], r.results[i] }
+func (r *rankedResults) Push(x interface{}) { r.results = append(r.results, x.(*TestResult)) }
+
+func (r *rankedResults) Pop() interface{} {
+	last := r.results[len(r.results)-1]
+	r.results = r.results[:len(r.results)-1]
+	return last
+}
+
+// offer keeps result if it ranks above the lowest-ranked result kept, or
+// fewer than limit are kept
+func (r *rankedResults) offer(result *TestResult) {
+	if len(r.results) < r.limit {
+		heap.Push(r, result)
+		return
+	}
+	if r.less(r.results[0], result) {
+		r.results[0] = result
+		heap.Fix(r, 0)
+	}
+}
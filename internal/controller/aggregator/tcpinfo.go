@@ -0,0 +1,84 @@
+package aggregator
+
+import (
+	"encoding/json"
+)
+
+// windowLimitedRatio is the share of the cwnd/RTT bound above which a test's
+// throughput is attributed to its congestion window
+const windowLimitedRatio = 0.8
+
+// Limit hints for TCP results, from comparing throughput to cwnd/RTT
+const (
+	LimitWindow    = "window"      // Throughput is close to cwnd/RTT
+	LimitLossOrCPU = "loss_or_cpu" // Throughput is well below cwnd/RTT
+)
+
+// TCPStreamStats holds the sender statistics iperf3 reports for one TCP
+// stream at the end of a test
+type TCPStreamStats struct {
+	MaxSndCwnd int64 `json:"max_snd_cwnd"` // Bytes
+	MaxRTTUs   int64 `json:"max_rtt"`
+	MinRTTUs   int64 `json:"min_rtt"`
+	MeanRTTUs  int64 `json:"mean_rtt"`
+}
+
+// extractTCPStreams parses the sender statistics of each stream in the end
+// section of iperf3 JSON output. UDP tests and older iperf3 versions report
+// none.
+func extractTCPStreams(iperfJSON string) []*TCPStreamStats {
+	var output struct {
+		End struct {
+			Streams []struct {
+				Sender *TCPStreamStats `json:"sender"`
+			} `json:"streams"`
+		} `json:"end"`
+	}
+	if err := json.Unmarshal([]byte(iperfJSON), &output); err != nil {
+		return nil
+	}
+
+	streams := make([]*TCPStreamStats, 0, len(output.End.Streams))
+	for _, stream := range output.End.Streams {
+		if stream.Sender != nil && stream.Sender.MaxSndCwnd > 0 && stream.Sender.MeanRTTUs > 0 {
+			streams = append(streams, stream.Sender)
+		}
+	}
+	if len(streams) == 0 {
+		return nil
+	}
+	return streams
+}
+
+// summarizeTCPStreams sets the per-test TCP statistics of result from its
+// streams: the largest congestion window, the mean RTT across streams, and
+// whether the throughput is close to the cwnd/RTT bound summed over streams
+func summarizeTCPStreams(result *TestResult) {
+	result.MaxSndCwndBytes = 0
+	result.MeanRTTMs = 0
+	result.WindowBoundBps = 0
+	result.LimitHint = ""
+
+	if len(result.TCPStreams) == 0 {
+		return
+	}
+
+	var totalRTTUs int64
+	for _, stream := range result.TCPStreams {
+		if stream.MaxSndCwnd > result.MaxSndCwndBytes {
+			result.MaxSndCwndBytes = stream.MaxSndCwnd
+		}
+		totalRTTUs += stream.MeanRTTUs
+		result.WindowBoundBps += float64(stream.MaxSndCwnd*8) / (float64(stream.MeanRTTUs) / 1e6)
+	}
+	result.MeanRTTMs = float64(totalRTTUs) / float64(len(result.TCPStreams)) / 1000
+
+	if result.ThroughputBps <= 0 {
+		return
+	}
+	if result.ThroughputBps >= windowLimitedRatio*result.WindowBoundBps {
+		result.LimitHint = LimitWindow
+	} else {
+		result.LimitHint = LimitLossOrCPU
+	}
+}
@@ -0,0 +1,80 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+)
+
+// tdigestCompression bounds the number of centroids a digest keeps, which
+// grows with the logarithm of the number of values to a few times it
+const tdigestCompression = 100
+
+// tdigest estimates quantiles of a stream of values in bounded memory. It
+// is a merging t-digest: values are merged into weighted centroids, small
+// near the tails and large around the median, so high and low percentiles
+// stay accurate. Below 200 values every value is its own centroid and
+// quantiles are exact nearest-rank percentiles.
+type tdigest struct {
+	centroids []centroid // Sorted by mean
+	buffer    []centroid // Values added since the last merge
+	count     float64
+}
+
+// centroid is the mean of weight values
+type centroid struct {
+	mean, weight float64
+}
+
+// add adds a value to the digest
+func (t *tdigest) add(value float64) {
+	t.buffer = append(t.buffer, centroid{mean: value, weight: 1})
+	t.count++
+	if len(t.buffer) >= 5*tdigestCompression {
+		t.merge()
+	}
+}
+
+// merge folds the buffered values into the centroids. A centroid may grow
+// to 4·n·q·(1-q)/compression values, where q is its quantile.
+func (t *tdigest) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := []centroid{all[0]}
+	before := 0.0 // Weight of the centroids before the last merged one
+	for _, c := range all[1:] {
+		last := &merged[len(merged)-1]
+		weight := last.weight + c.weight
+		q := (before + weight/2) / t.count
+		if weight <= 4*t.count*q*(1-q)/tdigestCompression {
+			last.mean += (c.mean - last.mean) * c.weight / weight
+			last.weight = weight
+			continue
+		}
+		before += last.weight
+		merged = append(merged, c)
+	}
+	t.centroids = merged
+	t.buffer = t.buffer[:0]
+}
+
+// quantile returns the estimated nearest-rank quantile q, between 0 and 1,
+// or 0 if the digest is empty
+func (t *tdigest) quantile(q float64) float64 {
+	t.merge()
+	if len(t.centroids) == 0 {
+		return 0
+	}
+	rank := math.Max(math.Ceil(q*t.count), 1)
+	cumulative := 0.0
+	for _, c := range t.centroids {
+		cumulative += c.weight
+		if cumulative >= rank {
+			return c.mean
+		}
+	}
+	return t.centroids[len(t.centroids)-1].mean
+}
@@ -0,0 +1,252 @@
+package aggregator
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultTimelineBucket is the width of the aggregate throughput buckets
+// unless configured otherwise
+const DefaultTimelineBucket = time.Second
+
+// Timeline is the throughput of all tests together over wall-clock time,
+// in fixed-width buckets
+type Timeline struct {
+	BucketSeconds float64           `json:"bucket_seconds"`
+	Buckets       []*TimelineBucket `json:"buckets"`
+}
+
+// TimelineBucket is the aggregate throughput over one bucket
+type TimelineBucket struct {
+	Time          float64 `json:"time"` // Unix seconds at the start of the bucket
+	ThroughputBps float64 `json:"throughput_bps"`
+	Tests         int     `json:"tests"` // Tests that reported data in the bucket
+}
+
+// timelineInterval is one iperf3 reporting interval in wall-clock time
+type timelineInterval struct {
+	start, end    float64 // Unix seconds
+	throughputBps float64
+}
+
+// SetTimelineBucket sets the width of the aggregate throughput buckets
+func (a *Aggregator) SetTimelineBucket(bucket time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.timelineBucket = bucket
+}
+
+// SetClockOffsets sets how far each node's clock is ahead of the
+// controller's. Interval timestamps, which come from the clock of the node
+// running the iperf3 client, are shifted back by the offset of that node.
+func (a *Aggregator) SetClockOffsets(offsets map[string]time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.clockOffsets = offsets
+}
+
+// Timeline returns the aggregate throughput of all completed tests,
+// background tests included, over time, or nil if no test reported
+// intervals. Each iperf3 interval is spread over the buckets it overlaps, so
+// missing intervals only lower the buckets they fall in.
+func (a *Aggregator) Timeline() *Timeline {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.timeline()
+}
+
+// timeline builds the timeline; the caller must hold a.mu
+func (a *Aggregator) timeline() *Timeline {
+	if a.stream != nil {
+		if a.stream.timeline == nil {
+			return nil // Nothing streamed yet
+		}
+		return a.stream.timeline.build()
+	}
+
+	// Visit tests in order so that float sums do not depend on map order
+	testIDs := make([]string, 0, len(a.results))
+	for testID := range a.results {
+		testIDs = append(testIDs, testID)
+	}
+	sort.Strings(testIDs)
+
+	acc := newTimelineAccumulator(a.timelineBucket)
+	for _, testID := range testIDs {
+		a.addToTimeline(acc, a.results[testID])
+	}
+	return acc.build()
+}
+
+// addToTimeline adds the intervals of a completed result to acc; the caller
+// must hold a.mu
+func (a *Aggregator) addToTimeline(acc *timelineAccumulator, result *TestResult) {
+	if result.Status == "TEST_STATUS_COMPLETED" {
+		acc.add(a.resultIntervals(result))
+	}
+}
+
+// timelineAccumulator sums the intervals of tests into buckets as they are
+// added, so that a timeline can be built without keeping the results.
+// Buckets are keyed by their start time divided by the width.
+type timelineAccumulator struct {
+	width       float64
+	first, last float64 // Unix seconds spanned by the intervals added
+	buckets     map[int64]*TimelineBucket
+}
+
+// newTimelineAccumulator returns an empty accumulator of buckets of the
+// given width, or of the default width if it is not positive
+func newTimelineAccumulator(bucket time.Duration) *timelineAccumulator {
+	if bucket <= 0 {
+		bucket = DefaultTimelineBucket
+	}
+	return &timelineAccumulator{
+		width:   bucket.Seconds(),
+		first:   math.Inf(1),
+		last:    math.Inf(-1),
+		buckets: make(map[int64]*TimelineBucket),
+	}
+}
+
+// add spreads the intervals of one test over the buckets they overlap
+func (t *timelineAccumulator) add(intervals []timelineInterval) {
+	seen := make(map[int64]bool)
+	for _, iv := range intervals {
+		t.first = math.Min(t.first, iv.start)
+		t.last = math.Max(t.last, iv.end)
+		for key := int64(math.Floor(iv.start / t.width)); float64(key)*t.width < iv.end; key++ {
+			start := float64(key) * t.width
+			overlap := math.Min(iv.end, start+t.width) - math.Max(iv.start, start)
+			if overlap <= 0 {
+				continue
+			}
+			b, exists := t.buckets[key]
+			if !exists {
+				b = &TimelineBucket{Time: start}
+				t.buckets[key] = b
+			}
+			b.ThroughputBps += iv.throughputBps * overlap / t.width
+			if !seen[key] {
+				seen[key] = true
+				b.Tests++
+			}
+		}
+	}
+}
+
+// build returns the timeline of the intervals added, with empty buckets
+// for the gaps between them, or nil if none were
+func (t *timelineAccumulator) build() *Timeline {
+	if len(t.buckets) == 0 {
+		return nil
+	}
+	origin := math.Floor(t.first / t.width)
+	count := int(math.Ceil((t.last - origin*t.width) / t.width))
+	if count < 1 {
+		count = 1
+	}
+
+	timeline := &Timeline{BucketSeconds: t.width, Buckets: make([]*TimelineBucket, count)}
+	for i := range timeline.Buckets {
+		key := int64(origin) + int64(i)
+		if b, exists := t.buckets[key]; exists {
+			copied := *b
+			timeline.Buckets[i] = &copied
+			continue
+		}
+		timeline.Buckets[i] = &TimelineBucket{Time: float64(key) * t.width}
+	}
+	return timeline
+}
+
+// resultIntervals returns the wall-clock intervals of a result, or of each
+// process of a multi-process pair. Burst results are not included.
+func (a *Aggregator) resultIntervals(result *TestResult) []timelineInterval {
+	if len(result.SubResults) > 0 {
+		intervals := make([]timelineInterval, 0)
+		for _, sub := range result.SubResults {
+			intervals = append(intervals, a.resultIntervals(sub)...)
+		}
+		return intervals
+	}
+	if result.IperfData == nil {
+		return nil
+	}
+
+	start := float64(result.StartTime)
+	if timestamp, ok := extractStartTimestamp(result.IperfData); ok {
+		start = timestamp
+	}
+	start -= a.clockOffsets[result.SourceNode].Seconds()
+
+	return extractIntervals(result.IperfData, start)
+}
+
+// extractStartTimestamp returns the Unix time iperf3 reports the test
+// started at
+func extractStartTimestamp(data map[string]interface{}) (float64, bool) {
+	start, ok := data["start"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	timestamp, ok := start["timestamp"].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	timesecs, ok := timestamp["timesecs"].(float64)
+	return timesecs, ok && timesecs > 0
+}
+
+// extractIntervals returns the non-omitted intervals of iperf3 output, offset
+// by start. Bidirectional tests count both directions.
+func extractIntervals(data map[string]interface{}, start float64) []timelineInterval {
+	raw, ok := data["intervals"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	intervals := make([]timelineInterval, 0, len(raw))
+	for _, entry := range raw {
+		interval, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"sum", "sum_bidir_reverse"} {
+			sum, ok := interval[key].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if omitted, _ := sum["omitted"].(bool); omitted {
+				continue
+			}
+			begin, _ := sum["start"].(float64)
+			end, _ := sum["end"].(float64)
+			bps, _ := sum["bits_per_second"].(float64)
+			if end <= begin {
+				continue
+			}
+			intervals = append(intervals, timelineInterval{
+				start:         start + begin,
+				end:           start + end,
+				throughputBps: bps,
+			})
+		}
+	}
+	return intervals
+}
+
+// peak returns the bucket with the highest aggregate throughput
+func (t *Timeline) peak() *TimelineBucket {
+	var peak *TimelineBucket
+	for _, b := range t.Buckets {
+		if peak == nil || b.ThroughputBps > peak.ThroughputBps {
+			peak = b
+		}
+	}
+	return peak
+}
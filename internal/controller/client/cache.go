@@ -0,0 +1,229 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// CacheStats counts node cache lookups made during a run
+type CacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+}
+
+// NodeCache persists daemon capabilities and facts between runs. Entries are
+// keyed by node ID and only used while the daemon version matches and they
+// are younger than the TTL. A nil *NodeCache is valid and caches nothing.
+type NodeCache struct {
+	path    string
+	ttl     time.Duration
+	refresh bool
+
+	mu      sync.Mutex
+	entries map[string]*nodeCacheEntry
+	stats   CacheStats
+}
+
+// nodeCacheEntry is the cached data of one node
+type nodeCacheEntry struct {
+	Version        string            `json:"version"`
+	Features       []models.Feature  `json:"features,omitempty"`
+	CapabilitiesAt time.Time         `json:"capabilities_at,omitempty"`
+	Facts          *models.NodeFacts `json:"facts,omitempty"`
+	FactsAt        time.Time         `json:"facts_at,omitempty"`
+}
+
+// nodeCacheFile is the on-disk format of the cache
+type nodeCacheFile struct {
+	Nodes map[string]*nodeCacheEntry `json:"nodes"`
+}
+
+// LoadNodeCache reads the cache at path. A missing, unreadable or corrupt
+// file yields an empty cache so that nodes are simply queried again.
+func LoadNodeCache(path string, ttl time.Duration) *NodeCache {
+	cache := &NodeCache{
+		path:    path,
+		ttl:     ttl,
+		entries: make(map[string]*nodeCacheEntry),
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Cache path is from config
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: ignoring node cache %s: %v", path, err)
+		}
+		return cache
+	}
+
+	var file nodeCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		log.Printf("Warning: ignoring corrupt node cache %s: %v", path, err)
+		return cache
+	}
+	for nodeID, entry := range file.Nodes {
+		if entry != nil {
+			cache.entries[nodeID] = entry
+		}
+	}
+
+	return cache
+}
+
+// SetRefresh makes every lookup miss so that all nodes are queried again;
+// the fresh answers still replace the cached ones
+func (c *NodeCache) SetRefresh(refresh bool) {
+	if c == nil {
+		return
+	}
+	c.refresh = refresh
+}
+
+// Save writes the cache to disk, replacing the previous file atomically
+func (c *NodeCache) Save() error {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	data, err := json.MarshalIndent(nodeCacheFile{Nodes: c.entries}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode node cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return fmt.Errorf("failed to create node cache directory: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write node cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to replace node cache: %w", err)
+	}
+
+	return nil
+}
+
+// Stats returns the lookup counts so far
+func (c *NodeCache) Stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// lookup returns the node's entry if it matches version and at is within the
+// TTL, counting the hit or miss. An empty version always misses.
+func (c *NodeCache) lookup(nodeID, version string, at func(*nodeCacheEntry) time.Time) (*nodeCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[nodeID]
+	if c.refresh || !exists || version == "" || entry.Version != version || time.Since(at(entry)) > c.ttl {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	return entry, true
+}
+
+// entry returns the node's entry for version, replacing a stale-version one
+func (c *NodeCache) entry(nodeID, version string) *nodeCacheEntry {
+	entry, exists := c.entries[nodeID]
+	if !exists || entry.Version != version {
+		entry = &nodeCacheEntry{Version: version}
+		c.entries[nodeID] = entry
+	}
+	return entry
+}
+
+// capabilities returns cached features for the node at version
+func (c *NodeCache) capabilities(nodeID, version string) ([]models.Feature, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	entry, ok := c.lookup(nodeID, version, func(e *nodeCacheEntry) time.Time { return e.CapabilitiesAt })
+	if !ok {
+		return nil, false
+	}
+	return entry.Features, true
+}
+
+// storeCapabilities caches the node's features
+func (c *NodeCache) storeCapabilities(nodeID string, caps *NodeCapabilities) {
+	if c == nil || caps.Version == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entry(nodeID, caps.Version)
+	entry.Features = make([]models.Feature, 0, len(caps.Features))
+	for feature, supported := range caps.Features {
+		if supported {
+			entry.Features = append(entry.Features, feature)
+		}
+	}
+	entry.CapabilitiesAt = time.Now()
+}
+
+// facts returns cached facts for the node at version
+func (c *NodeCache) facts(nodeID, version string) (*models.NodeFacts, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	entry, ok := c.lookup(nodeID, version, func(e *nodeCacheEntry) time.Time { return e.FactsAt })
+	if !ok || entry.Facts == nil {
+		return nil, false
+	}
+	return entry.Facts, true
+}
+
+// KnownFacts returns the last facts cached for each node, whatever their
+// age or daemon version, for checks made before the daemons are contacted.
+// The lookups are not counted in the stats.
+func (c *NodeCache) KnownFacts() map[string]*models.NodeFacts {
+	if c == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	facts := make(map[string]*models.NodeFacts)
+	for nodeID, entry := range c.entries {
+		if entry.Facts != nil {
+			facts[nodeID] = entry.Facts
+		}
+	}
+	return facts
+}
+
+// storeFacts caches the node's facts
+func (c *NodeCache) storeFacts(nodeID, version string, facts *models.NodeFacts) {
+	if c == nil || version == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := c.entry(nodeID, version)
+	entry.Facts = facts
+	entry.FactsAt = time.Now()
+}
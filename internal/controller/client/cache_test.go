@@ -0,0 +1,83 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestNodeCache_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "nodes.json")
+
+	cache := LoadNodeCache(path, time.Hour)
+	cache.storeCapabilities("node1", &NodeCapabilities{
+		Version:  "v0.2.0",
+		Features: map[models.Feature]bool{models.FeatureFacts: true},
+	})
+	cache.storeFacts("node1", "v0.2.0", &models.NodeFacts{OS: "linux"})
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded := LoadNodeCache(path, time.Hour)
+	features, ok := loaded.capabilities("node1", "v0.2.0")
+	if !ok || len(features) != 1 || features[0] != models.FeatureFacts {
+		t.Errorf("capabilities() = %v, %t; want [facts], true", features, ok)
+	}
+	if facts, ok := loaded.facts("node1", "v0.2.0"); !ok || facts.OS != "linux" {
+		t.Errorf("facts() = %v, %t; want linux facts", facts, ok)
+	}
+
+	// A different version, unknown version or unknown node must be re-queried
+	if _, ok := loaded.capabilities("node1", "v0.3.0"); ok {
+		t.Error("capabilities() hit for a different daemon version")
+	}
+	if _, ok := loaded.facts("node1", ""); ok {
+		t.Error("facts() hit for an unknown daemon version")
+	}
+	if _, ok := loaded.capabilities("node2", "v0.2.0"); ok {
+		t.Error("capabilities() hit for an unknown node")
+	}
+
+	if stats := loaded.Stats(); stats.Hits != 2 || stats.Misses != 3 {
+		t.Errorf("Stats() = %+v, want 2 hits and 3 misses", stats)
+	}
+
+	loaded.SetRefresh(true)
+	if _, ok := loaded.capabilities("node1", "v0.2.0"); ok {
+		t.Error("capabilities() hit with refresh set")
+	}
+}
+
+func TestNodeCache_Expired(t *testing.T) {
+	cache := LoadNodeCache(filepath.Join(t.TempDir(), "nodes.json"), time.Hour)
+	cache.storeFacts("node1", "v0.2.0", &models.NodeFacts{OS: "linux"})
+	cache.entries["node1"].FactsAt = time.Now().Add(-2 * time.Hour)
+
+	if _, ok := cache.facts("node1", "v0.2.0"); ok {
+		t.Error("facts() hit for an expired entry")
+	}
+}
+
+func TestNodeCache_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := LoadNodeCache(path, time.Hour)
+	if _, ok := cache.capabilities("node1", "v0.2.0"); ok {
+		t.Error("capabilities() hit from a corrupt cache")
+	}
+
+	var nilCache *NodeCache
+	if _, ok := nilCache.facts("node1", "v0.2.0"); ok {
+		t.Error("facts() hit from a nil cache")
+	}
+	if err := nilCache.Save(); err != nil {
+		t.Errorf("Save() on nil cache error = %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+)
+
+// NodeCapabilities describes what a connected daemon supports
+type NodeCapabilities struct {
+	Version  string
+	Features map[models.Feature]bool
+	// Downgrades lists the RPCs that returned Unimplemented and were
+	// replaced by an older call pattern
+	Downgrades []string
+}
+
+// Supports reports whether the daemon supports a feature
+func (c *NodeCapabilities) Supports(feature models.Feature) bool {
+	return c.Features[feature]
+}
+
+// CompatibilityMode reports whether the node needed any fallback
+func (c *NodeCapabilities) CompatibilityMode() bool {
+	return len(c.Downgrades) > 0
+}
+
+// isUnimplemented reports whether an RPC error means the daemon lacks the method
+func isUnimplemented(err error) bool {
+	return status.Code(err) == codes.Unimplemented
+}
+
+// callWithFallback invokes call and, when the daemon does not implement the
+// method, records the downgrade for the node and invokes fallback instead
+func (p *Pool) callWithFallback(nodeID, method string, call, fallback func() error) error {
+	err := call()
+	if err == nil || !isUnimplemented(err) {
+		return err
+	}
+
+	p.recordDowngrade(nodeID, method)
+	return fallback()
+}
+
+// recordDowngrade notes that a node is operating in compatibility mode for a method
+func (p *Pool) recordDowngrade(nodeID, method string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	caps, exists := p.capabilities[nodeID]
+	if !exists {
+		caps = &NodeCapabilities{Features: make(map[models.Feature]bool)}
+		p.capabilities[nodeID] = caps
+	}
+
+	for _, m := range caps.Downgrades {
+		if m == method {
+			return
+		}
+	}
+	caps.Downgrades = append(caps.Downgrades, method)
+
+	log.Printf("Node %s: daemon does not implement %s, using compatibility fallback", nodeID, method)
+}
+
+// DetectCapabilities queries every connected daemon for its version and
+// features. Daemons predating GetCapabilities fall back to GetStatus for the
+// version and are assumed to support no optional features. Nodes with a
+// fresh cache entry for their version are not queried.
+func (p *Pool) DetectCapabilities(ctx context.Context) error {
+	clients := p.GetAllClients()
+	failures := nodeerr.New("capability detection")
+
+	for _, client := range clients {
+		caps := &NodeCapabilities{Features: make(map[models.Feature]bool)}
+
+		version := p.daemonVersion(client.Node.ID)
+		if features, ok := p.cache.capabilities(client.Node.ID, version); ok {
+			caps.Version = version
+			for _, feature := range features {
+				caps.Features[feature] = true
+			}
+			p.setCapabilities(client.Node.ID, caps)
+			continue
+		}
+
+		err := p.callWithFallback(client.Node.ID, "GetCapabilities",
+			func() error {
+				resp, err := client.Client.GetCapabilities(ctx, &pb.GetCapabilitiesRequest{})
+				if err != nil {
+					return err
+				}
+				caps.Version = resp.Version
+				for _, feature := range resp.Features {
+					caps.Features[models.Feature(feature)] = true
+				}
+				return nil
+			},
+			func() error {
+				resp, err := client.Client.GetStatus(ctx, &pb.GetStatusRequest{})
+				if err != nil {
+					return err
+				}
+				if resp.Status != nil {
+					caps.Version = resp.Status.Version
+				}
+				return nil
+			},
+		)
+		if err != nil {
+			failures.Add(client.Node.ID, err)
+			continue
+		}
+
+		p.setCapabilities(client.Node.ID, caps)
+		p.cache.storeCapabilities(client.Node.ID, caps)
+	}
+
+	if failures.Len() > 0 {
+		return failures
+	}
+
+	return nil
+}
+
+// setCapabilities records a node's capabilities, keeping earlier downgrades
+func (p *Pool) setCapabilities(nodeID string, caps *NodeCapabilities) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if previous, exists := p.capabilities[nodeID]; exists {
+		caps.Downgrades = previous.Downgrades
+	}
+	p.capabilities[nodeID] = caps
+}
+
+// GetCapabilities returns the detected capabilities of a node
+func (p *Pool) GetCapabilities(nodeID string) (*NodeCapabilities, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	caps, exists := p.capabilities[nodeID]
+	return caps, exists
+}
+
+// RequireFeature returns an error naming every node in nodeIDs whose daemon
+// does not support a feature that has no fallback. Nodes whose capabilities
+// have not been detected are treated as unsupported.
+func (p *Pool) RequireFeature(feature models.Feature, nodeIDs []string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	missing := make([]string, 0)
+	for _, nodeID := range nodeIDs {
+		caps, exists := p.capabilities[nodeID]
+		if !exists || !caps.Supports(feature) {
+			missing = append(missing, nodeID)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+	return fmt.Errorf("feature %q requires daemon version %s or later; unsupported on nodes: %s",
+		feature, models.FeatureMinVersions[feature], strings.Join(missing, ", "))
+}
+
+// CompatibilityNodes returns the nodes operating in compatibility mode,
+// mapped to the RPCs that were downgraded for each
+func (p *Pool) CompatibilityNodes() map[string][]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	nodes := make(map[string][]string)
+	for nodeID, caps := range p.capabilities {
+		if caps.CompatibilityMode() {
+			nodes[nodeID] = append([]string(nil), caps.Downgrades...)
+		}
+	}
+
+	return nodes
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// oldDaemon is a daemon predating GetCapabilities, GatherFacts, StopTests
+// and the DownloadFile stream, which it answers with Unimplemented
+type oldDaemon struct {
+	pb.DaemonServiceClient
+	calls   map[string]int
+	results []*pb.TestResult
+}
+
+func newOldDaemon() *oldDaemon {
+	return &oldDaemon{calls: make(map[string]int)}
+}
+
+func (d *oldDaemon) GetCapabilities(context.Context, *pb.GetCapabilitiesRequest, ...grpc.CallOption) (*pb.GetCapabilitiesResponse, error) {
+	d.calls["GetCapabilities"]++
+	return nil, status.Error(codes.Unimplemented, "unknown method GetCapabilities")
+}
+
+func (d *oldDaemon) GetStatus(context.Context, *pb.GetStatusRequest, ...grpc.CallOption) (*pb.GetStatusResponse, error) {
+	d.calls["GetStatus"]++
+	return &pb.GetStatusResponse{Status: &pb.DaemonStatus{Version: "v0.1.0"}}, nil
+}
+
+func (d *oldDaemon) GatherFacts(context.Context, *pb.GatherFactsRequest, ...grpc.CallOption) (*pb.GatherFactsResponse, error) {
+	d.calls["GatherFacts"]++
+	return nil, status.Error(codes.Unimplemented, "unknown method GatherFacts")
+}
+
+func (d *oldDaemon) StopTests(context.Context, *pb.StopTestsRequest, ...grpc.CallOption) (*pb.StopTestsResponse, error) {
+	d.calls["StopTests"]++
+	return nil, status.Error(codes.Unimplemented, "unknown method StopTests")
+}
+
+func (d *oldDaemon) StopAll(context.Context, *pb.StopAllRequest, ...grpc.CallOption) (*pb.StopAllResponse, error) {
+	d.calls["StopAll"]++
+	return &pb.StopAllResponse{}, nil
+}
+
+func (d *oldDaemon) DownloadFile(context.Context, *pb.DownloadFileRequest, ...grpc.CallOption) (grpc.ServerStreamingClient[pb.FileChunk], error) {
+	d.calls["DownloadFile"]++
+	// Like gRPC, report the missing method on the first Recv
+	return &failedStream{err: status.Error(codes.Unimplemented, "unknown method DownloadFile")}, nil
+}
+
+func (d *oldDaemon) GetResults(context.Context, *pb.GetResultsRequest, ...grpc.CallOption) (*pb.GetResultsResponse, error) {
+	d.calls["GetResults"]++
+	return &pb.GetResultsResponse{Results: d.results}, nil
+}
+
+// failedStream is a DownloadFile stream whose Recv fails with err
+type failedStream struct {
+	grpc.ServerStreamingClient[pb.FileChunk]
+	err error
+}
+
+func (s *failedStream) Recv() (*pb.FileChunk, error) {
+	return nil, s.err
+}
+
+// newOldPool returns a pool of two old daemons with detected capabilities
+func newOldPool(t *testing.T) (*Pool, map[string]*oldDaemon) {
+	t.Helper()
+	pool := NewPool(time.Second)
+	daemons := map[string]*oldDaemon{"node1": newOldDaemon(), "node2": newOldDaemon()}
+	for id, daemon := range daemons {
+		pool.AddClient(&models.Node{ID: id}, daemon)
+	}
+	if err := pool.DetectCapabilities(context.Background()); err != nil {
+		t.Fatalf("DetectCapabilities() error = %v", err)
+	}
+	return pool, daemons
+}
+
+func TestPool_DetectCapabilitiesFallback(t *testing.T) {
+	pool, daemons := newOldPool(t)
+
+	for id, daemon := range daemons {
+		if daemon.calls["GetStatus"] != 1 {
+			t.Errorf("%s: GetStatus called %d times, want 1 as the fallback", id, daemon.calls["GetStatus"])
+		}
+		caps, exists := pool.GetCapabilities(id)
+		if !exists || caps.Version != "v0.1.0" || len(caps.Features) != 0 {
+			t.Errorf("%s capabilities = %+v, want version v0.1.0 and no features", id, caps)
+		}
+	}
+
+	// Detecting again records the downgrade only once
+	if err := pool.DetectCapabilities(context.Background()); err != nil {
+		t.Fatalf("DetectCapabilities() error = %v", err)
+	}
+	nodes := pool.CompatibilityNodes()
+	if len(nodes) != 2 || strings.Join(nodes["node1"], ",") != "GetCapabilities" {
+		t.Errorf("CompatibilityNodes() = %v, want GetCapabilities once for both nodes", nodes)
+	}
+}
+
+func TestPool_StopTestsFallback(t *testing.T) {
+	pool, daemons := newOldPool(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		stopped, messages, err := pool.StopTests(ctx, "node1", []string{"test-1", "test-2"})
+		if err != nil {
+			t.Fatalf("StopTests() error = %v", err)
+		}
+		if strings.Join(stopped, ",") != "test-1,test-2" || len(messages) != 0 {
+			t.Errorf("StopTests() = %v, %v; want both tests stopped by StopAll", stopped, messages)
+		}
+	}
+	if calls := daemons["node1"].calls; calls["StopTests"] != 2 || calls["StopAll"] != 2 {
+		t.Errorf("calls = %v, want StopAll after each StopTests", calls)
+	}
+	if daemons["node2"].calls["StopAll"] != 0 {
+		t.Error("StopTests on node1 stopped node2")
+	}
+
+	nodes := pool.CompatibilityNodes()
+	if got := strings.Join(nodes["node1"], ","); got != "GetCapabilities,StopTests" {
+		t.Errorf("node1 downgrades = %s, want GetCapabilities,StopTests", got)
+	}
+	if got := strings.Join(nodes["node2"], ","); got != "GetCapabilities" {
+		t.Errorf("node2 downgrades = %s, want only GetCapabilities", got)
+	}
+}
+
+func TestPool_DownloadFileFallback(t *testing.T) {
+	pool, daemons := newOldPool(t)
+	daemons["node1"].results = []*pb.TestResult{
+		{TestId: "test-1", ResultFile: "/var/results/test-1.json", IperfJson: `{"end":{}}`},
+	}
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	written, err := pool.DownloadFile(ctx, "node1", "/var/results/test-1.json", &buf)
+	if err != nil {
+		t.Fatalf("DownloadFile() error = %v", err)
+	}
+	if buf.String() != `{"end":{}}` || written != int64(buf.Len()) {
+		t.Errorf("DownloadFile() wrote %d bytes %q, want the result's iperf3 output", written, buf.String())
+	}
+
+	// Files the daemon no longer holds a result for cannot be recovered
+	_, err = pool.DownloadFile(ctx, "node1", "/var/results/test-1.pcap", io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "upgrade it to "+models.FeatureMinVersions[models.FeatureResultFiles]) {
+		t.Errorf("DownloadFile() of a capture error = %v, want the version to upgrade to", err)
+	}
+	if got := strings.Join(pool.CompatibilityNodes()["node1"], ","); got != "GetCapabilities,DownloadFile" {
+		t.Errorf("node1 downgrades = %s, want GetCapabilities,DownloadFile", got)
+	}
+}
+
+func TestPool_RequireFeature(t *testing.T) {
+	pool, _ := newOldPool(t)
+	pool.AddClient(&models.Node{ID: "node3"}, &statusClient{})
+	pool.setCapabilities("node3", &NodeCapabilities{
+		Version:  "v0.2.0",
+		Features: map[models.Feature]bool{models.FeatureMaintenance: true},
+	})
+
+	if err := pool.RequireFeature(models.FeatureMaintenance, []string{"node3"}); err != nil {
+		t.Errorf("RequireFeature() on a supporting node error = %v", err)
+	}
+
+	err := pool.RequireFeature(models.FeatureMaintenance, []string{"node3", "node2", "node1", "node4"})
+	if err == nil {
+		t.Fatal("RequireFeature() succeeded on old daemons")
+	}
+	want := `feature "maintenance" requires daemon version ` + models.FeatureMinVersions[models.FeatureMaintenance] +
+		` or later; unsupported on nodes: node1, node2, node4`
+	if err.Error() != want {
+		t.Errorf("RequireFeature() error = %q, want %q", err, want)
+	}
+}
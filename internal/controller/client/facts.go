@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"log"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// GatherFacts collects environment facts from every connected daemon.
+// Daemons predating GatherFacts are recorded as downgraded and omitted;
+// failures on individual nodes are logged and do not fail the run. Nodes
+// with a fresh cache entry for their version are not queried.
+func (p *Pool) GatherFacts(ctx context.Context) map[string]*models.NodeFacts {
+	clients := p.GetAllClients()
+	result := make(map[string]*models.NodeFacts)
+
+	for _, client := range clients {
+		version := p.daemonVersion(client.Node.ID)
+		if facts, ok := p.cache.facts(client.Node.ID, version); ok {
+			result[client.Node.ID] = facts
+			continue
+		}
+
+		var resp *pb.GatherFactsResponse
+		err := p.callWithFallback(client.Node.ID, "GatherFacts",
+			func() error {
+				var err error
+				resp, err = client.Client.GatherFacts(ctx, &pb.GatherFactsRequest{})
+				return err
+			},
+			func() error { return nil },
+		)
+		if err != nil {
+			log.Printf("Warning: failed to gather facts from node %s: %v", client.Node.ID, err)
+			continue
+		}
+		if resp == nil || resp.Facts == nil {
+			continue
+		}
+
+		result[client.Node.ID] = factsFromProto(resp.Facts)
+		p.cache.storeFacts(client.Node.ID, version, result[client.Node.ID])
+	}
+
+	return result
+}
+
+// factsFromProto converts protobuf NodeFacts to the model type
+func factsFromProto(f *pb.NodeFacts) *models.NodeFacts {
+	facts := &models.NodeFacts{
+		OS:                   f.Os,
+		Platform:             f.Platform,
+		KernelVersion:        f.KernelVersion,
+		Arch:                 f.Arch,
+		IperfVersion:         f.IperfVersion,
+		DefaultQdisc:         f.DefaultQdisc,
+		TCPCongestionControl: f.TcpCongestionControl,
+		RmemMax:              f.RmemMax,
+		WmemMax:              f.WmemMax,
+	}
+
+	for _, iface := range f.Interfaces {
+		facts.Interfaces = append(facts.Interfaces, models.InterfaceFacts{
+			Name:      iface.Name,
+			MTU:       int(iface.Mtu),
+			SpeedMbps: iface.SpeedMbps,
+			Driver:    iface.Driver,
+		})
+	}
+
+	return facts
+}
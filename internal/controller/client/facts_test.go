@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// factsClient answers GatherFacts with facts; other methods are not used
+type factsClient struct {
+	pb.DaemonServiceClient
+	facts *pb.NodeFacts
+}
+
+func (c *factsClient) GatherFacts(context.Context, *pb.GatherFactsRequest, ...grpc.CallOption) (*pb.GatherFactsResponse, error) {
+	return &pb.GatherFactsResponse{Facts: c.facts}, nil
+}
+
+func TestPool_GatherFacts(t *testing.T) {
+	pool := NewPool(time.Second)
+	old := newOldDaemon()
+	pool.AddClient(&models.Node{ID: "old"}, old)
+	pool.AddClient(&models.Node{ID: "new"}, &factsClient{facts: &pb.NodeFacts{
+		Os:            "linux",
+		KernelVersion: "6.8.0",
+		RmemMax:       67108864,
+		Interfaces:    []*pb.InterfaceFacts{{Name: "eth0", Mtu: 9000, SpeedMbps: 25000, Driver: "mlx5_core"}, {Name: "veth0", Mtu: 1500}},
+	}})
+
+	for i := 0; i < 2; i++ {
+		facts := pool.GatherFacts(context.Background())
+		if _, exists := facts["old"]; exists || len(facts) != 1 {
+			t.Fatalf("GatherFacts() = %v, want only the new daemon's facts", facts)
+		}
+
+		want := &models.NodeFacts{
+			OS:            "linux",
+			KernelVersion: "6.8.0",
+			RmemMax:       67108864,
+			Interfaces: []models.InterfaceFacts{
+				{Name: "eth0", MTU: 9000, SpeedMbps: 25000, Driver: "mlx5_core"},
+				{Name: "veth0", MTU: 1500},
+			},
+		}
+		if !reflect.DeepEqual(facts["new"], want) {
+			t.Errorf("facts = %+v, want %+v", facts["new"], want)
+		}
+	}
+	if old.calls["GatherFacts"] != 2 {
+		t.Errorf("old daemon asked %d times, want 2", old.calls["GatherFacts"])
+	}
+	if nodes := pool.CompatibilityNodes(); len(nodes) != 1 || strings.Join(nodes["old"], ",") != "GatherFacts" {
+		t.Errorf("CompatibilityNodes() = %v, want GatherFacts once for old", nodes)
+	}
+}
+
+func TestFactsFromProto_OmitsEmptyFields(t *testing.T) {
+	facts := factsFromProto(&pb.NodeFacts{
+		Os:         "linux",
+		Interfaces: []*pb.InterfaceFacts{{Name: "eth0", Mtu: 1500}},
+	})
+	data, err := json.Marshal(facts)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	want := `{"os":"linux","interfaces":[{"name":"eth0","mtu":1500}]}`
+	if string(data) != want {
+		t.Errorf("facts = %s, want %s", data, want)
+	}
+
+	if data, _ := json.Marshal(factsFromProto(&pb.NodeFacts{})); string(data) != "{}" {
+		t.Errorf("empty facts = %s, want {}", data)
+	}
+}
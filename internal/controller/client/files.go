@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// DownloadFile copies a result file saved on a node's daemon to w and
+// returns the number of bytes copied. Daemons predating the DownloadFile
+// stream are asked for the result the file is a copy of with unary
+// GetResults instead, which only works while they still hold it.
+func (p *Pool) DownloadFile(ctx context.Context, nodeID, path string, w io.Writer) (int64, error) {
+	c, err := p.GetClient(nodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	err = p.callWithFallback(nodeID, "DownloadFile",
+		func() error {
+			var err error
+			written, err = streamFile(ctx, c.Client, path, w)
+			return err
+		},
+		func() error {
+			var err error
+			written, err = resultFile(ctx, c.Client, path, w)
+			return err
+		},
+	)
+	return written, err
+}
+
+// streamFile copies a file from the DownloadFile stream to w. Unimplemented
+// is returned as is, so that nothing has been written when it is.
+func streamFile(ctx context.Context, client pb.DaemonServiceClient, path string, w io.Writer) (int64, error) {
+	stream, err := client.DownloadFile(ctx, &pb.DownloadFileRequest{Path: path})
+	if isUnimplemented(err) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to download %s: %w", path, err)
+	}
+	var written int64
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return written, nil
+		}
+		if isUnimplemented(err) && written == 0 {
+			return 0, err
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to download %s: %w", path, err)
+		}
+		n, err := w.Write(chunk.Data)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// resultFile writes the iperf3 output of the result whose saved copy is
+// path to w. Captures and results already retrieved with clearing cannot
+// be recovered this way.
+func resultFile(ctx context.Context, client pb.DaemonServiceClient, path string, w io.Writer) (int64, error) {
+	resp, err := client.GetResults(ctx, &pb.GetResultsRequest{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to download %s: %w", path, err)
+	}
+	for _, result := range resp.Results {
+		if result.ResultFile == path {
+			n, err := io.WriteString(w, result.IperfJson)
+			return int64(n), err
+		}
+	}
+	return 0, fmt.Errorf("failed to download %s: daemon does not support file downloads and no longer holds the result; upgrade it to %s or later",
+		path, models.FeatureMinVersions[models.FeatureResultFiles])
+}
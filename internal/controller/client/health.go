@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeHealth counts the nodes of a run that were reachable and healthy when
+// it started
+type NodeHealth struct {
+	Total   int `json:"total"`
+	Healthy int `json:"healthy"`
+	// Missing gives why each node that is not healthy was left out, by ID
+	Missing map[string]string `json:"missing,omitempty"`
+}
+
+// MissingList formats the missing nodes, sorted, with why they are missing
+func (h *NodeHealth) MissingList() string {
+	nodeIDs := make([]string, 0, len(h.Missing))
+	for nodeID := range h.Missing {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	missing := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		missing = append(missing, fmt.Sprintf("%s (%s)", nodeID, h.Missing[nodeID]))
+	}
+	return strings.Join(missing, ", ")
+}
+
+// CheckNodeHealth polls every connected node and counts those that are
+// reachable, healthy, not in maintenance and able to run iperf3. The result
+// is kept for NodeHealth.
+func (p *Pool) CheckNodeHealth(ctx context.Context) *NodeHealth {
+	clients := p.GetAllClients()
+	statuses, _ := p.CheckHealth(ctx)
+
+	health := &NodeHealth{Total: len(clients), Missing: make(map[string]string)}
+	for _, client := range clients {
+		status, reached := statuses[client.Node.ID]
+		switch {
+		case !reached:
+			health.Missing[client.Node.ID] = "unreachable"
+		case !status.Healthy:
+			health.Missing[client.Node.ID] = "unhealthy"
+		case status.Maintenance:
+			health.Missing[client.Node.ID] = "in maintenance"
+		case status.IperfError != "":
+			health.Missing[client.Node.ID] = "iperf3 not found"
+		default:
+			health.Healthy++
+		}
+	}
+
+	p.mu.Lock()
+	p.health = health
+	p.mu.Unlock()
+	return health
+}
+
+// NodeHealth returns the result of the last CheckNodeHealth, or nil
+func (p *Pool) NodeHealth() *NodeHealth {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.health
+}
+
+// Remove disconnects from a node and leaves it out of later calls
+func (p *Pool) Remove(nodeID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	client, exists := p.clients[nodeID]
+	if !exists {
+		return nil
+	}
+	delete(p.clients, nodeID)
+	if client.Conn == nil {
+		return nil
+	}
+	return client.Conn.Close()
+}
@@ -0,0 +1,220 @@
+package client
+
+import (
+	"context"
+	"math"
+	"path"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// maxLatencySamples bounds the latencies kept per node and method for the
+// p95, so that the status polls of a long run don't grow them without bound.
+// Counts, averages and extremes cover every call.
+const maxLatencySamples = 1024
+
+// A node's average latency for a method is slow when it is slowRPCFactor
+// times the fleet median and at least slowRPCMinMs above it, so that nodes a
+// few milliseconds slower on a fast network aren't flagged
+const (
+	slowRPCFactor = 3.0
+	slowRPCMinMs  = 100.0
+	// slowRPCMinNodes is the fewest nodes a median is taken over
+	slowRPCMinNodes = 3
+)
+
+// LatencyStats summarizes the latency of a set of RPCs, failed calls included
+type LatencyStats struct {
+	Calls  int64   `json:"calls"`
+	Errors int64   `json:"errors,omitempty"`
+	MinMs  float64 `json:"min_ms"`
+	AvgMs  float64 `json:"avg_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// SlowRPCNode is a node whose average latency for a method is a multiple of
+// the fleet median
+type SlowRPCNode struct {
+	NodeID        string  `json:"node_id"`
+	Method        string  `json:"method"`
+	AvgMs         float64 `json:"avg_ms"`
+	FleetMedianMs float64 `json:"fleet_median_ms"`
+}
+
+// RPCStats is the latency of daemon RPCs by method, over the whole fleet and
+// per node. Each phase of a run issues its own method, so methods also
+// localize slowness to a phase.
+type RPCStats struct {
+	Methods   map[string]*LatencyStats            `json:"methods"`
+	Nodes     map[string]map[string]*LatencyStats `json:"nodes"` // Node ID -> method -> stats
+	SlowNodes []*SlowRPCNode                      `json:"slow_nodes,omitempty"`
+}
+
+// latencySamples accumulates the latencies of one node's calls of a method
+type latencySamples struct {
+	calls   int64
+	errors  int64
+	totalMs float64
+	minMs   float64
+	maxMs   float64
+	recent  []float64 // The latest maxLatencySamples latencies
+	next    int       // Index in recent overwritten next once it is full
+}
+
+// add records a call that took ms milliseconds
+func (s *latencySamples) add(ms float64, failed bool) {
+	if s.calls == 0 || ms < s.minMs {
+		s.minMs = ms
+	}
+	s.maxMs = math.Max(s.maxMs, ms)
+	s.calls++
+	s.totalMs += ms
+	if failed {
+		s.errors++
+	}
+	if len(s.recent) < maxLatencySamples {
+		s.recent = append(s.recent, ms)
+		return
+	}
+	s.recent[s.next] = ms
+	s.next = (s.next + 1) % maxLatencySamples
+}
+
+// instrumentRPC returns an interceptor that counts the RPCs to a node by
+// method and records their latency
+func (p *Pool) instrumentRPC(nodeID string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		p.recordRPC(nodeID, path.Base(method), time.Since(start), err != nil)
+		return err
+	}
+}
+
+// recordRPC records a call of method to a node
+func (p *Pool) recordRPC(nodeID, method string, elapsed time.Duration, failed bool) {
+	p.rpcMu.Lock()
+	defer p.rpcMu.Unlock()
+
+	p.rpcCounts[method]++
+	methods, exists := p.rpcLatency[nodeID]
+	if !exists {
+		methods = make(map[string]*latencySamples)
+		p.rpcLatency[nodeID] = methods
+	}
+	samples, exists := methods[method]
+	if !exists {
+		samples = &latencySamples{}
+		methods[method] = samples
+	}
+	samples.add(float64(elapsed)/float64(time.Millisecond), failed)
+}
+
+// RPCStats returns the latency of the daemon RPCs issued so far, or nil if
+// none were. Clients added with AddClient are not measured.
+func (p *Pool) RPCStats() *RPCStats {
+	p.rpcMu.Lock()
+	defer p.rpcMu.Unlock()
+
+	if len(p.rpcLatency) == 0 {
+		return nil
+	}
+	stats := &RPCStats{
+		Methods: make(map[string]*LatencyStats),
+		Nodes:   make(map[string]map[string]*LatencyStats),
+	}
+	fleet := make(map[string][]*latencySamples)
+	for nodeID, methods := range p.rpcLatency {
+		stats.Nodes[nodeID] = make(map[string]*LatencyStats)
+		for method, samples := range methods {
+			stats.Nodes[nodeID][method] = summarizeLatency(samples)
+			fleet[method] = append(fleet[method], samples)
+		}
+	}
+	for method, samples := range fleet {
+		stats.Methods[method] = summarizeLatency(samples...)
+	}
+	stats.SlowNodes = slowRPCNodes(stats.Nodes)
+	return stats
+}
+
+// summarizeLatency merges the latencies of samples
+func summarizeLatency(samples ...*latencySamples) *LatencyStats {
+	stats := &LatencyStats{}
+	total := 0.0
+	var recent []float64
+	for _, s := range samples {
+		if s.calls == 0 {
+			continue
+		}
+		if stats.Calls == 0 || s.minMs < stats.MinMs {
+			stats.MinMs = s.minMs
+		}
+		stats.MaxMs = math.Max(stats.MaxMs, s.maxMs)
+		stats.Calls += s.calls
+		stats.Errors += s.errors
+		total += s.totalMs
+		recent = append(recent, s.recent...)
+	}
+	if stats.Calls == 0 {
+		return stats
+	}
+	stats.AvgMs = total / float64(stats.Calls)
+	sort.Float64s(recent)
+	stats.P95Ms = percentile(recent, 95)
+	return stats
+}
+
+// slowRPCNodes flags, for each method, the nodes whose average latency is
+// slow relative to the median of every node's average, slowest first
+func slowRPCNodes(nodes map[string]map[string]*LatencyStats) []*SlowRPCNode {
+	averages := make(map[string][]float64)
+	for _, methods := range nodes {
+		for method, stats := range methods {
+			averages[method] = append(averages[method], stats.AvgMs)
+		}
+	}
+	medians := make(map[string]float64)
+	for method, avgs := range averages {
+		if len(avgs) < slowRPCMinNodes {
+			continue
+		}
+		sort.Float64s(avgs)
+		medians[method] = percentile(avgs, 50)
+	}
+
+	slow := make([]*SlowRPCNode, 0)
+	for nodeID, methods := range nodes {
+		for method, stats := range methods {
+			median, ok := medians[method]
+			if !ok || stats.AvgMs < median*slowRPCFactor || stats.AvgMs-median < slowRPCMinMs {
+				continue
+			}
+			slow = append(slow, &SlowRPCNode{NodeID: nodeID, Method: method, AvgMs: stats.AvgMs, FleetMedianMs: median})
+		}
+	}
+	sort.Slice(slow, func(i, j int) bool {
+		if slow[i].AvgMs != slow[j].AvgMs {
+			return slow[i].AvgMs > slow[j].AvgMs
+		}
+		if slow[i].NodeID != slow[j].NodeID {
+			return slow[i].NodeID < slow[j].NodeID
+		}
+		return slow[i].Method < slow[j].Method
+	})
+	return slow
+}
+
+// percentile returns the nearest-rank percentile p of sorted, which must not
+// be empty
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPool_RPCStats(t *testing.T) {
+	pool := NewPool(time.Second)
+	if stats := pool.RPCStats(); stats != nil {
+		t.Fatalf("RPCStats() = %+v before any RPC, want nil", stats)
+	}
+
+	for _, nodeID := range []string{"node1", "node2", "node3"} {
+		pool.recordRPC(nodeID, "StartServers", 200*time.Millisecond, false)
+		pool.recordRPC(nodeID, "GetStatus", 5*time.Millisecond, false)
+	}
+	pool.recordRPC("node4", "StartServers", 9*time.Second, false)
+	pool.recordRPC("node4", "StartServers", 7*time.Second, true)
+	// A few milliseconds over a fast median is not slow
+	pool.recordRPC("node4", "GetStatus", 20*time.Millisecond, false)
+
+	stats := pool.RPCStats()
+	node4 := stats.Nodes["node4"]["StartServers"]
+	if node4.Calls != 2 || node4.Errors != 1 || node4.MinMs != 7000 || node4.MaxMs != 9000 || node4.AvgMs != 8000 {
+		t.Errorf("node4 StartServers = %+v, want 2 calls, 1 error, min 7000, avg 8000, max 9000", node4)
+	}
+	fleet := stats.Methods["StartServers"]
+	if fleet.Calls != 5 || fleet.MinMs != 200 || fleet.P95Ms != 9000 {
+		t.Errorf("fleet StartServers = %+v, want 5 calls, min 200, p95 9000", fleet)
+	}
+	if got := pool.RPCCounts()["GetStatus"]; got != 4 {
+		t.Errorf("RPCCounts()[GetStatus] = %d, want 4", got)
+	}
+
+	if len(stats.SlowNodes) != 1 {
+		t.Fatalf("SlowNodes = %d, want 1", len(stats.SlowNodes))
+	}
+	slow := stats.SlowNodes[0]
+	if slow.NodeID != "node4" || slow.Method != "StartServers" || slow.FleetMedianMs != 200 {
+		t.Errorf("SlowNodes[0] = %+v, want node4 StartServers against a 200 ms median", slow)
+	}
+}
+
+func TestLatencySamples_Bounded(t *testing.T) {
+	samples := &latencySamples{}
+	for i := 0; i < maxLatencySamples+10; i++ {
+		samples.add(float64(i), false)
+	}
+	if len(samples.recent) != maxLatencySamples {
+		t.Errorf("kept %d samples, want %d", len(samples.recent), maxLatencySamples)
+	}
+	if stats := summarizeLatency(samples); stats.Calls != maxLatencySamples+10 || stats.MinMs != 0 {
+		t.Errorf("summarizeLatency() = %+v, want every call counted, min 0", stats)
+	}
+}
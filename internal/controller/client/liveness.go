@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// Liveness records which nodes answered the liveness barrier, a status
+// request to every node just before a run sends its first state-changing
+// request. A connection only fails once used, so a node that went away
+// since connecting would otherwise be found deep in a phase.
+type Liveness struct {
+	Total     int   `json:"total"`
+	Responded int   `json:"responded"`
+	TimeoutMs int64 `json:"timeout_ms"`
+	ElapsedMs int64 `json:"elapsed_ms"`
+	// Missing gives why each node that did not answer failed, by ID
+	Missing map[string]string `json:"missing,omitempty"`
+}
+
+// MissingList formats the nodes that did not answer, sorted, with why
+func (l *Liveness) MissingList() string {
+	nodeIDs := l.MissingIDs()
+	missing := make([]string, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		missing = append(missing, fmt.Sprintf("%s (%s)", nodeID, l.Missing[nodeID]))
+	}
+	return strings.Join(missing, ", ")
+}
+
+// MissingIDs returns the IDs of the nodes that did not answer, sorted
+func (l *Liveness) MissingIDs() []string {
+	nodeIDs := make([]string, 0, len(l.Missing))
+	for nodeID := range l.Missing {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	return nodeIDs
+}
+
+// CheckLiveness sends a status request to every connected node at once,
+// each with its own timeout, and records which answered. Unlike status
+// polls it ignores poll backoff, since every node must be asked. The result
+// is kept for Liveness.
+func (p *Pool) CheckLiveness(ctx context.Context, timeout time.Duration) *Liveness {
+	clients := p.GetAllClients()
+	liveness := &Liveness{
+		Total:     len(clients),
+		TimeoutMs: timeout.Milliseconds(),
+		Missing:   make(map[string]string),
+	}
+
+	start := time.Now()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *NodeClient) {
+			defer wg.Done()
+			callCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			_, err := client.Client.GetStatus(callCtx, &pb.GetStatusRequest{})
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				liveness.Missing[client.Node.ID] = err.Error()
+				return
+			}
+			liveness.Responded++
+		}(client)
+	}
+	wg.Wait()
+	liveness.ElapsedMs = time.Since(start).Milliseconds()
+
+	p.mu.Lock()
+	p.liveness = liveness
+	p.mu.Unlock()
+	return liveness
+}
+
+// Liveness returns the result of the last CheckLiveness, or nil
+func (p *Pool) Liveness() *Liveness {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.liveness
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"google.golang.org/grpc"
+)
+
+// statusClient answers GetStatus after delay; other methods are not used
+type statusClient struct {
+	pb.DaemonServiceClient
+	delay time.Duration
+}
+
+func (c *statusClient) GetStatus(ctx context.Context, _ *pb.GetStatusRequest, _ ...grpc.CallOption) (*pb.GetStatusResponse, error) {
+	select {
+	case <-time.After(c.delay):
+		return &pb.GetStatusResponse{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestPool_CheckLiveness(t *testing.T) {
+	pool := NewPool(time.Second)
+	if pool.Liveness() != nil {
+		t.Fatal("Liveness() before a check, want nil")
+	}
+	pool.AddClient(&models.Node{ID: "node1"}, &statusClient{})
+	pool.AddClient(&models.Node{ID: "node2"}, &statusClient{delay: time.Minute})
+	pool.AddClient(&models.Node{ID: "node3"}, &statusClient{delay: time.Millisecond})
+
+	start := time.Now()
+	liveness := pool.CheckLiveness(context.Background(), 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CheckLiveness took %s, want it bounded by the timeout", elapsed)
+	}
+	if liveness.Total != 3 || liveness.Responded != 2 || liveness.TimeoutMs != 50 {
+		t.Errorf("liveness = %+v, want 2 of 3 answered within 50 ms", liveness)
+	}
+	if ids := liveness.MissingIDs(); len(ids) != 1 || ids[0] != "node2" {
+		t.Errorf("MissingIDs() = %v, want [node2]", ids)
+	}
+	if pool.Liveness() != liveness {
+		t.Error("Liveness() does not return the last check")
+	}
+}
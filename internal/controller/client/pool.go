@@ -3,14 +3,22 @@ package client
 import (
 	"context"
 	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
 	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/common/poll"
 )
 
 // NodeClient wraps a gRPC connection to a daemon
@@ -22,9 +30,24 @@ type NodeClient struct {
 
 // Pool manages gRPC connections to multiple daemons
 type Pool struct {
-	clients map[string]*NodeClient
-	mu      sync.RWMutex
-	timeout time.Duration
+	clients      map[string]*NodeClient
+	capabilities map[string]*NodeCapabilities
+	versions     map[string]string       // nodeID -> daemon version reported by Initialize
+	simulated    map[string]bool         // nodes whose daemon runs in --simulate mode
+	nodeInfo     map[string]*pb.NodeInfo // nodeID -> node info reported by Initialize
+	cache        *NodeCache
+	mu           sync.RWMutex
+	timeout      time.Duration
+	runID        string // Run started by Initialize, whose tests status polls count
+	health       *NodeHealth
+	liveness     *Liveness
+
+	rpcMu      sync.Mutex
+	rpcCounts  map[string]int64                      // RPC method -> calls, for diagnostics
+	rpcLatency map[string]map[string]*latencySamples // Node ID -> RPC method -> latencies
+
+	// pacer spaces status polls and backs off nodes whose polls fail
+	pacer *poll.Pacer
 }
 
 // NewPool creates a new client pool
@@ -34,11 +57,58 @@ func NewPool(timeout time.Duration) *Pool {
 	}
 
 	return &Pool{
-		clients: make(map[string]*NodeClient),
-		timeout: timeout,
+		clients:      make(map[string]*NodeClient),
+		capabilities: make(map[string]*NodeCapabilities),
+		versions:     make(map[string]string),
+		simulated:    make(map[string]bool),
+		nodeInfo:     make(map[string]*pb.NodeInfo),
+		timeout:      timeout,
+		rpcCounts:    make(map[string]int64),
+		rpcLatency:   make(map[string]map[string]*latencySamples),
+		pacer:        poll.NewPacer(poll.DefaultPolicy()),
 	}
 }
 
+// SetPollPolicy sets how status polls are spaced and backed off
+func (p *Pool) SetPollPolicy(policy poll.Policy) {
+	p.pacer = poll.NewPacer(policy)
+}
+
+// PollTicker returns a ticker for polling the pool's nodes around a base
+// interval, jittered and bounded by the poll policy
+func (p *Pool) PollTicker(base time.Duration) *poll.Ticker {
+	return p.pacer.NewTicker(base)
+}
+
+// PollStats returns the status polls made so far and how they were paced
+func (p *Pool) PollStats() poll.Stats {
+	return p.pacer.Stats()
+}
+
+// SetCache sets the cache consulted for capabilities and facts
+func (p *Pool) SetCache(cache *NodeCache) {
+	p.cache = cache
+}
+
+// Cache returns the pool's node cache, or nil if none is set
+func (p *Pool) Cache() *NodeCache {
+	return p.cache
+}
+
+// daemonVersion returns the node's daemon version, or "" if not yet known
+func (p *Pool) daemonVersion(nodeID string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if version, exists := p.versions[nodeID]; exists {
+		return version
+	}
+	if caps, exists := p.capabilities[nodeID]; exists {
+		return caps.Version
+	}
+	return ""
+}
+
 // Connect establishes a connection to a node
 func (p *Pool) Connect(ctx context.Context, node *models.Node) error {
 	p.mu.Lock()
@@ -58,6 +128,7 @@ func (p *Pool) Connect(ctx context.Context, node *models.Node) error {
 			grpc.MaxCallRecvMsgSize(100*1024*1024), // 100MB max receive
 			grpc.MaxCallSendMsgSize(100*1024*1024), // 100MB max send
 		),
+		grpc.WithChainUnaryInterceptor(p.instrumentRPC(node.ID)),
 	}
 	conn, err := grpc.NewClient(addr, opts...)
 	if err != nil {
@@ -77,18 +148,30 @@ func (p *Pool) Connect(ctx context.Context, node *models.Node) error {
 	return nil
 }
 
+// AddClient registers an existing daemon client for a node, e.g. an
+// in-process implementation used in tests
+func (p *Pool) AddClient(node *models.Node, client pb.DaemonServiceClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clients[node.ID] = &NodeClient{
+		Node:   node,
+		Client: client,
+	}
+}
+
 // ConnectAll establishes connections to all nodes
 func (p *Pool) ConnectAll(ctx context.Context, nodes []*models.Node) error {
-	errors := make([]error, 0)
+	failures := nodeerr.New("connection")
 
 	for _, node := range nodes {
 		if err := p.Connect(ctx, node); err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", node.ID, err))
+			failures.Add(node.ID, err)
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("failed to connect to %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		return failures
 	}
 
 	return nil
@@ -123,86 +206,291 @@ func (p *Pool) GetAllClients() []*NodeClient {
 // Initialize initializes all connected daemons
 func (p *Pool) Initialize(ctx context.Context, config *pb.InitializeRequest) error {
 	clients := p.GetAllClients()
-	errors := make([]error, 0)
+	failures := nodeerr.New("initialization")
+
+	p.mu.Lock()
+	p.runID = config.RunId
+	p.mu.Unlock()
 
 	for _, client := range clients {
 		resp, err := client.Client.Initialize(ctx, config)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", client.Node.ID, err))
+			failures.Add(client.Node.ID, err)
 			continue
 		}
 
 		if !resp.Success {
-			errors = append(errors, fmt.Errorf("node %s: %s", client.Node.ID, resp.Message))
+			failures.Addf(client.Node.ID, "%s", resp.Message)
+			continue
+		}
+		// Its tests would otherwise fail only once they were due to run
+		if iperfErr := resp.GetNodeInfo().GetIperfError(); iperfErr != "" {
+			failures.Addf(client.Node.ID, "iperf3 not found: %s", iperfErr)
+			continue
+		}
+
+		// Simulated daemons are usually run many to a host
+		if !resp.GetNodeInfo().GetSimulated() {
+			checkHostname(client.Node, resp.GetNodeInfo().GetHostname())
 		}
+
+		if version := resp.GetNodeInfo().GetVersion(); version != "" {
+			p.mu.Lock()
+			p.versions[client.Node.ID] = version
+			p.mu.Unlock()
+		}
+		if resp.GetNodeInfo().GetSimulated() {
+			p.mu.Lock()
+			p.simulated[client.Node.ID] = true
+			p.mu.Unlock()
+		}
+		if info := resp.GetNodeInfo(); info != nil {
+			p.mu.Lock()
+			p.nodeInfo[client.Node.ID] = info
+			p.mu.Unlock()
+		}
+	}
+
+	if simulated := p.SimulatedNodes(); len(simulated) > 0 {
+		log.Printf("Warning: %d daemons run in simulation mode and return synthetic results: %s",
+			len(simulated), strings.Join(simulated, ", "))
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("initialization failed on %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		return failures
 	}
 
 	return nil
 }
 
+// SimulatedNodes returns the nodes whose daemons reported simulation mode
+// when initialized, sorted
+func (p *Pool) SimulatedNodes() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	nodes := make([]string, 0, len(p.simulated))
+	for nodeID := range p.simulated {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// NodeInfos returns the node info each daemon reported when initialized,
+// by node ID
+func (p *Pool) NodeInfos() map[string]*pb.NodeInfo {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	infos := make(map[string]*pb.NodeInfo, len(p.nodeInfo))
+	for nodeID, info := range p.nodeInfo {
+		infos[nodeID] = info
+	}
+	return infos
+}
+
+// checkHostname warns when a daemon reports a different hostname than its
+// node is configured with, which usually means the node's IP was copied from
+// another entry. Short and fully qualified forms of a name match, and nodes
+// configured with an address as hostname are not checked.
+func checkHostname(node *models.Node, reported string) {
+	if reported == "" || net.ParseIP(node.Hostname) != nil || sameHost(node.Hostname, reported) {
+		return
+	}
+	log.Printf("Warning: %s config says %s but daemon reports %s", node.ID, node.Hostname, reported)
+}
+
+// sameHost reports whether two hostnames name the same host, ignoring case
+// and any domain
+func sameHost(a, b string) bool {
+	a, _, _ = strings.Cut(strings.ToLower(a), ".")
+	b, _, _ = strings.Cut(strings.ToLower(b), ".")
+	return a == b
+}
+
 // CheckHealth checks the health of all connected nodes
 func (p *Pool) CheckHealth(ctx context.Context) (map[string]*pb.DaemonStatus, error) {
+	return p.getStatus(ctx, &pb.GetStatusRequest{})
+}
+
+// ProcessStatus is CheckHealth with each daemon's running processes listed.
+// Daemons without the stop_tests feature list none.
+func (p *Pool) ProcessStatus(ctx context.Context) (map[string]*pb.DaemonStatus, error) {
+	return p.getStatus(ctx, &pb.GetStatusRequest{IncludeProcesses: true})
+}
+
+// getStatus sends req to every connected node. Nodes backing off after
+// failed polls are left out and reported as failures.
+func (p *Pool) getStatus(ctx context.Context, req *pb.GetStatusRequest) (map[string]*pb.DaemonStatus, error) {
 	clients := p.GetAllClients()
 	statuses := make(map[string]*pb.DaemonStatus)
-	errors := make([]error, 0)
+	failures := nodeerr.New("health check")
+
+	// Daemons that know the run also count its tests apart from earlier ones
+	p.mu.RLock()
+	req.RunId = p.runID
+	p.mu.RUnlock()
 
 	for _, client := range clients {
-		resp, err := client.Client.GetStatus(ctx, &pb.GetStatusRequest{})
+		if !p.pacer.Due(client.Node.ID, time.Now()) {
+			failures.Addf(client.Node.ID, "not polled: backing off after failed polls")
+			continue
+		}
+		resp, err := client.Client.GetStatus(ctx, req)
+		p.pacer.Record(client.Node.ID, err, time.Now())
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", client.Node.ID, err))
+			failures.Add(client.Node.ID, err)
 			continue
 		}
 
 		statuses[client.Node.ID] = resp.Status
 	}
 
-	if len(errors) > 0 {
-		return statuses, fmt.Errorf("health check failed on %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		return statuses, failures
 	}
 
 	return statuses, nil
 }
 
-// StopAll stops all processes on all nodes
-func (p *Pool) StopAll(ctx context.Context) error {
+// StopAll stops the processes of owner's run on all nodes and releases the
+// ports reserved and allocated for it, leaving other runs sharing the
+// daemons running; an empty owner stops every process and releases every
+// port. Processes get SIGTERM and are killed by the daemon if they have not
+// exited within its process stop timeout.
+func (p *Pool) StopAll(ctx context.Context, owner string) error {
 	clients := p.GetAllClients()
-	errors := make([]error, 0)
+	failures := nodeerr.New("stop")
 
 	for _, client := range clients {
-		_, err := client.Client.StopAll(ctx, &pb.StopAllRequest{Force: true})
+		_, err := client.Client.StopAll(ctx, &pb.StopAllRequest{Owner: owner})
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", client.Node.ID, err))
+			failures.Add(client.Node.ID, err)
 		}
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("stop failed on %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		return failures
 	}
 
 	return nil
 }
 
+// ForceStop kills the processes of owner's run on the given nodes, or all of
+// them for an empty owner, including those still exiting after an earlier
+// StopAll
+func (p *Pool) ForceStop(ctx context.Context, nodeIDs []string, owner string) error {
+	failures := nodeerr.New("force stop")
+
+	for _, nodeID := range nodeIDs {
+		client, err := p.GetClient(nodeID)
+		if err != nil {
+			failures.Add(nodeID, err)
+			continue
+		}
+		if _, err := client.Client.StopAll(ctx, &pb.StopAllRequest{Force: true, Owner: owner}); err != nil {
+			failures.Add(nodeID, err)
+		}
+	}
+
+	if failures.Len() > 0 {
+		return failures
+	}
+
+	return nil
+}
+
+// StopTests stops the processes of the given tests on a node and returns
+// the IDs stopped, with messages for those that were not running. Daemons
+// predating StopTests stop all their processes with StopAll instead, which
+// stops every one of the tests.
+func (p *Pool) StopTests(ctx context.Context, nodeID string, testIDs []string) ([]string, []string, error) {
+	client, err := p.GetClient(nodeID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stopped, messages []string
+	err = p.callWithFallback(nodeID, "StopTests",
+		func() error {
+			resp, err := client.Client.StopTests(ctx, &pb.StopTestsRequest{TestIds: testIDs})
+			if err != nil {
+				return err
+			}
+			stopped, messages = resp.StoppedTestIds, resp.Errors
+			return nil
+		},
+		func() error {
+			if _, err := client.Client.StopAll(ctx, &pb.StopAllRequest{}); err != nil {
+				return err
+			}
+			stopped = append([]string(nil), testIDs...)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stopped, messages, nil
+}
+
+// SetMaintenance enables or disables maintenance mode on all nodes. Daemons
+// in maintenance refuse new tests but let running ones finish.
+func (p *Pool) SetMaintenance(ctx context.Context, enabled bool, reason string) error {
+	clients := p.GetAllClients()
+	failures := nodeerr.New("maintenance")
+
+	for _, client := range clients {
+		_, err := client.Client.SetMaintenance(ctx, &pb.SetMaintenanceRequest{Enabled: enabled, Reason: reason})
+		if status.Code(err) == codes.Unimplemented {
+			err = fmt.Errorf("daemon does not support maintenance mode (requires version %s or later)",
+				models.FeatureMinVersions[models.FeatureMaintenance])
+		}
+		if err != nil {
+			failures.Add(client.Node.ID, err)
+		}
+	}
+
+	if failures.Len() > 0 {
+		return failures
+	}
+
+	return nil
+}
+
+// RPCCounts returns the number of daemon RPCs issued so far, by method
+func (p *Pool) RPCCounts() map[string]int64 {
+	p.rpcMu.Lock()
+	defer p.rpcMu.Unlock()
+
+	counts := make(map[string]int64, len(p.rpcCounts))
+	for method, count := range p.rpcCounts {
+		counts[method] = count
+	}
+	return counts
+}
+
 // Close closes all connections
 func (p *Pool) Close() error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	errors := make([]error, 0)
+	failures := nodeerr.New("close")
 
 	for nodeID, client := range p.clients {
+		if client.Conn == nil {
+			continue
+		}
 		if err := client.Conn.Close(); err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", nodeID, err))
+			failures.Add(nodeID, err)
 		}
 	}
 
 	p.clients = make(map[string]*NodeClient)
 
-	if len(errors) > 0 {
-		return fmt.Errorf("failed to close %d connections: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		return failures
 	}
 
 	return nil
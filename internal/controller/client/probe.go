@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// ProbeTarget is a node's data address and the port probed on it
+type ProbeTarget struct {
+	NodeID  string
+	Address string
+	Port    int32
+}
+
+// ProbeOptions bounds a data-plane probe so that it stays interactive on
+// large meshes
+type ProbeOptions struct {
+	Timeout    time.Duration // Per connection attempt
+	Concurrent int           // Attempts each daemon has in flight at once
+	Budget     time.Duration // Total time of the probe
+}
+
+// Reachability is the outcome of probing one node's data port from another
+type Reachability struct {
+	Probed    bool // False when the budget ran out first
+	Reachable bool
+	Refused   bool // Reachable, but nothing listens on the port
+	Latency   time.Duration
+	Error     string
+}
+
+// ReachabilityMatrix maps source node ID -> destination node ID -> outcome
+type ReachabilityMatrix map[string]map[string]*Reachability
+
+// ProbeData asks every connected daemon to attempt a TCP connection to each
+// target other than itself, all daemons at once. It returns the outcomes,
+// and why each daemon that could not probe did not, by node ID; daemons
+// predating ProbeConnectivity are recorded as downgraded.
+func (p *Pool) ProbeData(ctx context.Context, targets []ProbeTarget, opts ProbeOptions) (ReachabilityMatrix, map[string]string) {
+	ctx, cancel := context.WithTimeout(ctx, opts.Budget+p.timeout)
+	defer cancel()
+
+	matrix := make(ReachabilityMatrix)
+	failed := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, client := range p.GetAllClients() {
+		req := &pb.ProbeConnectivityRequest{
+			TimeoutMs:     int32(opts.Timeout.Milliseconds()), // #nosec G115 -- Probe timeouts are a few seconds
+			MaxConcurrent: int32(opts.Concurrent),             // #nosec G115 -- Probe concurrency is small
+			BudgetMs:      int32(opts.Budget.Milliseconds()),  // #nosec G115 -- Probe budgets are a few minutes at most
+		}
+		for _, target := range targets {
+			if target.NodeID != client.Node.ID {
+				req.Targets = append(req.Targets, &pb.ProbeTarget{
+					NodeId:  target.NodeID,
+					Address: target.Address,
+					Port:    target.Port,
+				})
+			}
+		}
+
+		wg.Add(1)
+		go func(client *NodeClient) {
+			defer wg.Done()
+			var resp *pb.ProbeConnectivityResponse
+			err := p.callWithFallback(client.Node.ID, "ProbeConnectivity",
+				func() error {
+					var err error
+					resp, err = client.Client.ProbeConnectivity(ctx, req)
+					return err
+				},
+				func() error { return fmt.Errorf("daemon does not support data-plane probes") },
+			)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[client.Node.ID] = err.Error()
+				return
+			}
+			row := make(map[string]*Reachability, len(resp.Results))
+			for _, result := range resp.Results {
+				row[result.GetTarget().GetNodeId()] = &Reachability{
+					Probed:    result.Probed,
+					Reachable: result.Reachable,
+					Refused:   result.Refused,
+					Latency:   time.Duration(result.LatencyUs) * time.Microsecond,
+					Error:     result.Error,
+				}
+			}
+			matrix[client.Node.ID] = row
+		}(client)
+	}
+	wg.Wait()
+
+	return matrix, failed
+}
+
+// Asymmetric returns the pairs of nodes where the first reaches the second
+// but not the other way round, which usually means a one-way firewall
+// rule. Pairs not probed both ways are left out.
+func (m ReachabilityMatrix) Asymmetric() [][2]string {
+	pairs := make([][2]string, 0)
+	for source, row := range m {
+		for dest, forward := range row {
+			reverse, ok := m[dest][source]
+			if ok && forward.Probed && reverse.Probed && forward.Reachable && !reverse.Reachable {
+				pairs = append(pairs, [2]string{source, dest})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	return pairs
+}
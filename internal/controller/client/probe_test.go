@@ -0,0 +1,26 @@
+package client
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestReachabilityMatrix_Asymmetric(t *testing.T) {
+	reachable := &Reachability{Probed: true, Reachable: true}
+	refused := &Reachability{Probed: true, Reachable: true, Refused: true}
+	blocked := &Reachability{Probed: true, Error: "i/o timeout"}
+	unprobed := &Reachability{}
+
+	matrix := ReachabilityMatrix{
+		"a": {"b": reachable, "c": refused, "d": reachable},
+		"b": {"a": blocked, "c": reachable},
+		"c": {"a": reachable, "b": blocked},
+		"d": {"a": unprobed},
+	}
+
+	// a-d is left out as d did not get to probe a
+	got := fmt.Sprint(matrix.Asymmetric())
+	if want := "[[a b] [b c]]"; got != want {
+		t.Errorf("Asymmetric() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// BlackoutSkip is a test pair not run because blackout windows of its
+// nodes would have held it back longer than the max blackout wait
+type BlackoutSkip struct {
+	Pair  *topology.TestPair
+	Wait  time.Duration // How long the pair would have waited
+	Nodes []string      // Nodes whose windows it would have waited for
+}
+
+// blackouts defers the start of test pairs that would overlap a blackout
+// window of their nodes until it ends, and skips those that would wait
+// longer than maxWait in all
+type blackouts struct {
+	maxWait time.Duration
+	waiting map[string]time.Time // Test ID -> when a held back pair was due to start
+	skipped map[string]*BlackoutSkip
+}
+
+// newBlackouts returns blackout handling that skips held back pairs without
+// waiting until a max wait is set
+func newBlackouts() *blackouts {
+	return &blackouts{
+		waiting: make(map[string]time.Time),
+		skipped: make(map[string]*BlackoutSkip),
+	}
+}
+
+// SetMaxBlackoutWait sets how long a test pair waits for blackout windows
+// of its nodes to end before it is skipped. 0 or negative skips such pairs
+// without waiting.
+func (o *Orchestrator) SetMaxBlackoutWait(wait time.Duration) {
+	o.blackouts.maxWait = max(wait, 0)
+}
+
+// BlackoutSkipped returns the test pairs skipped for blackout windows, in
+// test ID order
+func (o *Orchestrator) BlackoutSkipped() []*BlackoutSkip {
+	skipped := make([]*BlackoutSkip, 0, len(o.blackouts.skipped))
+	for _, skip := range o.blackouts.skipped {
+		skipped = append(skipped, skip)
+	}
+	sort.Slice(skipped, func(i, j int) bool { return skipped[i].Pair.TestID < skipped[j].Pair.TestID })
+	return skipped
+}
+
+// deferStarts lengthens the start delays of clientTests started now so that
+// none overlaps a blackout window of its nodes, leaving out and recording
+// those that would wait too long
+func (b *blackouts) deferStarts(clientTests map[string][]*topology.TestPair, now time.Time) map[string][]*topology.TestPair {
+	deferred := make(map[string][]*topology.TestPair, len(clientTests))
+	for nodeID, pairs := range clientTests {
+		for _, pair := range pairs {
+			start := now.Add(time.Duration(pair.StartDelayMs) * time.Millisecond)
+			clear, nodes, ok := pair.ClearOfBlackouts(start, b.maxWait)
+			if !ok {
+				b.skip(pair, clear.Sub(start), nodes)
+				continue
+			}
+			if delay := clear.Sub(start); delay > 0 {
+				delay = delay.Truncate(time.Millisecond) + time.Millisecond
+				pair.StartDelayMs += int(delay.Milliseconds())
+				pair.BlackoutDelay = delay
+				pair.BlackoutNodes = nodes
+				log.Printf("Test %s deferred %v for blackout windows of %s",
+					pair.TestID, delay.Round(time.Second), strings.Join(nodes, ", "))
+			}
+			deferred[nodeID] = append(deferred[nodeID], pair)
+		}
+	}
+	return deferred
+}
+
+// hold reports whether pair, due to start at start, is held back by a
+// blackout window of its nodes, and whether it is skipped for having to
+// wait too long since it was first due. A pair started after being held
+// back records the delay.
+func (b *blackouts) hold(pair *topology.TestPair, start time.Time) (held, skipped bool) {
+	due, waiting := b.waiting[pair.TestID]
+	if !waiting {
+		due = start
+	}
+	clear, nodes, ok := pair.ClearOfBlackouts(start, b.maxWait-start.Sub(due))
+	if !ok {
+		delete(b.waiting, pair.TestID)
+		b.skip(pair, clear.Sub(due), addNodes(pair.BlackoutNodes, nodes))
+		return false, true
+	}
+	if clear.After(start) {
+		if !waiting {
+			log.Printf("Test %s held back until %s for blackout windows of %s",
+				pair.TestID, clear.Format("15:04:05"), strings.Join(nodes, ", "))
+		}
+		b.waiting[pair.TestID] = due
+		pair.BlackoutNodes = addNodes(pair.BlackoutNodes, nodes)
+		return true, false
+	}
+	if waiting {
+		delete(b.waiting, pair.TestID)
+		pair.BlackoutDelay = start.Sub(due)
+	}
+	return false, false
+}
+
+// skip records pair as skipped for blackout windows of nodes
+func (b *blackouts) skip(pair *topology.TestPair, wait time.Duration, nodes []string) {
+	log.Printf("Warning: skipping test %s: blackout windows of %s would hold it back %v, longer than the max blackout wait (%v)",
+		pair.TestID, strings.Join(nodes, ", "), wait.Round(time.Second), b.maxWait)
+	b.skipped[pair.TestID] = &BlackoutSkip{Pair: pair, Wait: wait, Nodes: nodes}
+}
+
+// isSkipped reports whether pair was skipped for blackout windows
+func (b *blackouts) isSkipped(pair *topology.TestPair) bool {
+	_, skipped := b.skipped[pair.TestID]
+	return skipped
+}
+
+// addNodes returns the sorted union of two sorted node lists
+func addNodes(nodes, more []string) []string {
+	union := make(map[string]bool, len(nodes)+len(more))
+	for _, list := range [][]string{nodes, more} {
+		for _, nodeID := range list {
+			union[nodeID] = true
+		}
+	}
+	merged := make([]string, 0, len(union))
+	for nodeID := range union {
+		merged = append(merged, nodeID)
+	}
+	sort.Strings(merged)
+	return merged
+}
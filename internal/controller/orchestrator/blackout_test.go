@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// blackoutPairs returns pairs from a node with a 01:00-03:00 UTC blackout
+// window, and one between nodes without
+func blackoutPairs() (deferred, free *topology.TestPair) {
+	backups := &models.Node{ID: "backups", Blackouts: []models.BlackoutWindow{{Start: 60, End: 180, Location: time.UTC}}}
+	node1 := &models.Node{ID: "node1"}
+	node2 := &models.Node{ID: "node2"}
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second}
+	deferred = &topology.TestPair{TestID: "deferred", Source: backups, Destination: node1, Profile: profile}
+	free = &topology.TestPair{TestID: "free", Source: node1, Destination: node2, Profile: profile}
+	return deferred, free
+}
+
+func TestBlackouts_DeferStarts(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 2, 0, 0, 0, time.UTC)
+
+	deferred, free := blackoutPairs()
+	b := newBlackouts()
+	b.maxWait = 2 * time.Hour
+	started := b.deferStarts(map[string][]*topology.TestPair{"backups": {deferred}, "node1": {free}}, now)
+	if len(started["backups"]) != 1 || len(started["node1"]) != 1 {
+		t.Fatalf("deferStarts() = %v, want both pairs", started)
+	}
+	if deferred.BlackoutDelay < time.Hour || deferred.StartDelayMs < 3600000 || deferred.BlackoutNodes[0] != "backups" {
+		t.Errorf("deferred pair delay = %v, start delay %dms, nodes %v; want an hour for backups",
+			deferred.BlackoutDelay, deferred.StartDelayMs, deferred.BlackoutNodes)
+	}
+	if free.BlackoutDelay != 0 || free.StartDelayMs != 0 {
+		t.Errorf("free pair delay = %v, start delay %dms; want none", free.BlackoutDelay, free.StartDelayMs)
+	}
+
+	// Waiting longer than allowed skips the pair
+	deferred, _ = blackoutPairs()
+	b = newBlackouts()
+	b.maxWait = 30 * time.Minute
+	started = b.deferStarts(map[string][]*topology.TestPair{"backups": {deferred}}, now)
+	if len(started["backups"]) != 0 || !b.isSkipped(deferred) {
+		t.Fatalf("deferStarts() = %v, want the pair skipped", started)
+	}
+	if skip := b.skipped["deferred"]; skip.Wait < time.Hour || skip.Nodes[0] != "backups" {
+		t.Errorf("skip = %+v, want an hour's wait for backups", skip)
+	}
+}
+
+func TestThrottle_Blackouts(t *testing.T) {
+	now := time.Date(2026, time.March, 10, 2, 0, 0, 0, time.UTC)
+
+	deferred, free := blackoutPairs()
+	throttle := newThrottle(2, 0)
+	throttle.blackouts = newBlackouts()
+	throttle.blackouts.maxWait = 2 * time.Hour
+	if err := throttle.add(map[string][]*topology.TestPair{"backups": {deferred}, "node1": {free}}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+
+	// The held back pair stays queued while the other starts
+	started, _ := throttle.next(now)
+	if len(started) != 1 || len(started["node1"]) != 1 || throttle.queued() != 1 {
+		t.Fatalf("next() in the window = %v, want only the free pair", started)
+	}
+	if _, queued := throttle.expectedEnd([]*topology.TestPair{deferred, free}); !queued {
+		t.Error("expectedEnd() = not queued, want the deferred pair queued")
+	}
+
+	// It starts once the window has ended, recording how long it waited
+	throttle.setFinished(1)
+	started, _ = throttle.next(now.Add(time.Hour))
+	if len(started["backups"]) != 1 || deferred.BlackoutDelay != time.Hour {
+		t.Errorf("next() after the window = %v, delay %v; want the deferred pair after an hour", started, deferred.BlackoutDelay)
+	}
+
+	// A pair that would wait too long is skipped and no longer expected
+	deferred, _ = blackoutPairs()
+	throttle = newThrottle(2, 0)
+	throttle.blackouts = newBlackouts()
+	if err := throttle.add(map[string][]*topology.TestPair{"backups": {deferred}}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if started, _ := throttle.next(now); len(started) != 0 || throttle.queued() != 0 {
+		t.Errorf("next() without a blackout wait = %v, %d queued; want the pair skipped", started, throttle.queued())
+	}
+	if _, queued := throttle.expectedEnd([]*topology.TestPair{deferred}); queued {
+		t.Error("expectedEnd() = queued, want the skipped pair left out")
+	}
+}
@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// nodeCapacity returns the process slots and server ports a daemon
+// reported free. Older daemons do not report their ports.
+func nodeCapacity(processes *pb.ProcessCapacity, portRange *pb.PortRange, total, available int32) topology.NodeCapacity {
+	return topology.NodeCapacity{
+		Slots: int(processes.GetAvailableProcesses()),
+		Ports: topology.PortCapacity{
+			Start:     portRange.GetStart(),
+			End:       portRange.GetEnd(),
+			Total:     int(total),
+			Available: int(available),
+		},
+	}
+}
+
+// validateCapacity checks topo against the capacity each daemon reported
+// when it was initialized, so that a topology the nodes cannot hold fails
+// before any of them is asked to prepare it
+func (o *Orchestrator) validateCapacity(topo *topology.Topology) error {
+	capacities := make(map[string]topology.NodeCapacity)
+	for nodeID, info := range o.clientPool.NodeInfos() {
+		if info.GetCapacity() != nil {
+			capacities[nodeID] = nodeCapacity(info.Capacity, info.PortRange, info.TotalPorts, info.AvailablePorts)
+		}
+	}
+	return topo.Validate(capacities)
+}
+
+// CheckCapacity checks topo against the capacity the connected daemons
+// report now, without initializing them or reserving anything
+func (o *Orchestrator) CheckCapacity(ctx context.Context, topo *topology.Topology) error {
+	statuses, err := o.clientPool.CheckHealth(ctx)
+	if err != nil {
+		return err
+	}
+	capacities := make(map[string]topology.NodeCapacity, len(statuses))
+	for nodeID, status := range statuses {
+		if status.GetCurrentCapacity() != nil {
+			capacities[nodeID] = nodeCapacity(status.CurrentCapacity, status.PortRange, status.TotalPorts, status.AvailablePorts)
+		}
+	}
+	return topo.Validate(capacities)
+}
+
+// describeCapacity summarizes what a daemon's process slots and server
+// ports are used by, for explaining why it cannot take a topology
+func describeCapacity(b *pb.CapacityBreakdown) string {
+	slots := fmt.Sprintf("slots %d/%d used: %d servers, %d clients",
+		b.UsedSlots, b.MaxSlots, b.RunningServers, b.RunningClients)
+	if b.UnaccountedSlots != 0 {
+		slots += fmt.Sprintf(", %d held by no process", b.UnaccountedSlots)
+	}
+	if b.StoppingProcesses > 0 {
+		slots += fmt.Sprintf(" (%d stopped processes still exiting)", b.StoppingProcesses)
+	}
+
+	ports := fmt.Sprintf("ports %d/%d free: %d with servers, %d allocated",
+		b.FreePorts, b.TotalPorts, b.ServerPorts, b.AllocatedPorts)
+	if b.ReservedPorts > 0 {
+		ports += fmt.Sprintf(", %d in run reservations", b.ReservedPorts)
+	}
+
+	parts := []string{slots, ports}
+	if len(b.Divergences) > 0 {
+		parts = append(parts, "accounting diverged: "+strings.Join(b.Divergences, "; "))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,21 @@
+package orchestrator
+
+import (
+	"testing"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+func TestDescribeCapacity(t *testing.T) {
+	got := describeCapacity(&pb.CapacityBreakdown{
+		MaxSlots: 40, UsedSlots: 28, RunningServers: 20, RunningClients: 6, UnaccountedSlots: 2,
+		TotalPorts: 200, ServerPorts: 20, FreePorts: 180, ReservedPorts: 50,
+		Divergences: []string{"28 slots are used but only 26 processes run"},
+	})
+	want := "slots 28/40 used: 20 servers, 6 clients, 2 held by no process; " +
+		"ports 180/200 free: 20 with servers, 0 allocated, 50 in run reservations; " +
+		"accounting diverged: 28 slots are used but only 26 processes run"
+	if got != want {
+		t.Errorf("describeCapacity() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// defaultProcessStopTimeout matches the daemons' default process stop
+// timeout, after which they kill processes that ignored SIGTERM
+const defaultProcessStopTimeout = 10 * time.Second
+
+// LingeringNode is a node whose processes were still running after cleanup
+// had stopped and then force-stopped them
+type LingeringNode struct {
+	Node      string   `json:"node"`
+	Processes int32    `json:"processes"`
+	PIDs      []int32  `json:"pids,omitempty"` // Empty for daemons that don't list their processes
+	TestIDs   []string `json:"test_ids,omitempty"`
+}
+
+// SetProcessStopTimeout sets how long cleanup waits for the daemons'
+// processes to exit before force-stopping them, and again after. 0 or
+// negative skips the check.
+func (o *Orchestrator) SetProcessStopTimeout(timeout time.Duration) {
+	o.processStopTimeout = max(timeout, 0)
+}
+
+// LingeringProcesses returns the nodes whose processes survived cleanup, or
+// nil
+func (o *Orchestrator) LingeringProcesses() []*LingeringNode {
+	return o.lingering
+}
+
+// verifyCleanup checks that the processes stopped at cleanup have exited.
+// Nodes still running processes of this run after the process stop timeout
+// are force-stopped, and those left after that are recorded as lingering, as
+// they would interfere with the next run. Processes of other runs sharing
+// the daemons are left alone.
+func (o *Orchestrator) verifyCleanup(ctx context.Context) {
+	if o.processStopTimeout == 0 {
+		return
+	}
+
+	running := o.awaitStopped(ctx, nil)
+	if len(running) == 0 {
+		return
+	}
+	nodeIDs := make([]string, 0, len(running))
+	for nodeID := range running {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	log.Printf("Warning: processes still running after %v on %s; force-stopping them",
+		o.processStopTimeout, strings.Join(nodeIDs, ", "))
+	if err := o.clientPool.ForceStop(ctx, nodeIDs, o.stopOwner()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	running = o.awaitStopped(ctx, running)
+	o.lingering = lingeringNodes(running)
+	for _, node := range o.lingering {
+		log.Printf("Warning: node %s still runs %d processes after force stop%s", node.Node, node.Processes, formatPIDs(node.PIDs))
+	}
+}
+
+// awaitStopped polls the daemons until none of nodes runs processes or the
+// process stop timeout passes, and returns the statuses of those still
+// running them. A nil nodes checks every node; nodes that fail to answer
+// are not waited for, as their errors surface elsewhere.
+func (o *Orchestrator) awaitStopped(ctx context.Context, nodes map[string]*pb.DaemonStatus) map[string]*pb.DaemonStatus {
+	deadline := time.Now().Add(o.processStopTimeout)
+	ticker := o.clientPool.PollTicker(o.cleanupPollInterval)
+	defer ticker.Stop()
+
+	for {
+		statuses, _ := o.clientPool.ProcessStatus(ctx)
+		running := make(map[string]*pb.DaemonStatus)
+		for nodeID, status := range statuses {
+			if _, checked := nodes[nodeID]; nodes != nil && !checked {
+				continue
+			}
+			if status = ownStatus(status, o.stopOwner()); status.GetRunningProcesses() > 0 {
+				running[nodeID] = status
+			}
+		}
+		if len(running) == 0 || !time.Now().Before(deadline) {
+			return running
+		}
+
+		select {
+		case <-ctx.Done():
+			return running
+		case <-ticker.C:
+		}
+	}
+}
+
+// ownStatus narrows a daemon's status to the processes of owner's run. An
+// empty owner, or a daemon that does not list its processes, keeps them all.
+func ownStatus(status *pb.DaemonStatus, owner string) *pb.DaemonStatus {
+	if owner == "" || len(status.GetProcesses()) == 0 {
+		return status
+	}
+	own := &pb.DaemonStatus{}
+	for _, process := range status.Processes {
+		if process.Owner == owner {
+			own.Processes = append(own.Processes, process)
+		}
+	}
+	own.RunningProcesses = int32(len(own.Processes)) // #nosec G115 -- Process count is reasonable
+	return own
+}
+
+// lingeringNodes lists the processes of the nodes in running, by node
+func lingeringNodes(running map[string]*pb.DaemonStatus) []*LingeringNode {
+	if len(running) == 0 {
+		return nil
+	}
+	lingering := make([]*LingeringNode, 0, len(running))
+	for nodeID, status := range running {
+		node := &LingeringNode{Node: nodeID, Processes: status.GetRunningProcesses()}
+		for _, process := range status.GetProcesses() {
+			if process.Pid != 0 {
+				node.PIDs = append(node.PIDs, process.Pid)
+			}
+			node.TestIDs = append(node.TestIDs, process.TestId)
+		}
+		lingering = append(lingering, node)
+	}
+	sort.Slice(lingering, func(i, j int) bool { return lingering[i].Node < lingering[j].Node })
+	return lingering
+}
+
+// formatPIDs formats pids for a log message, or returns "" if there are none
+func formatPIDs(pids []int32) string {
+	if len(pids) == 0 {
+		return ""
+	}
+	formatted := make([]string, len(pids))
+	for i, pid := range pids {
+		formatted[i] = fmt.Sprint(pid)
+	}
+	return " (PIDs " + strings.Join(formatted, ", ") + ")"
+}
@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+func TestCleanup_VerifiesProcessesExit(t *testing.T) {
+	tests := []struct {
+		name          string
+		unkillable    bool
+		wantLingering bool
+	}{
+		{name: "force stop ends them", unkillable: false, wantLingering: false},
+		{name: "unkillable processes are reported", unkillable: true, wantLingering: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch, daemons, _ := newFailingOrchestrator(t, "")
+			orch.SetProcessStopTimeout(50 * time.Millisecond)
+			orch.cleanupPollInterval = 10 * time.Millisecond
+			daemons[0].lingering = 2
+			daemons[0].unkillable = tt.unkillable
+
+			if err := orch.Cleanup(context.Background()); err != nil {
+				t.Fatalf("Cleanup() error = %v", err)
+			}
+			if daemons[0].forceStops != 1 || daemons[1].forceStops != 0 {
+				t.Errorf("force stops = %d, %d; want only the lingering node force-stopped",
+					daemons[0].forceStops, daemons[1].forceStops)
+			}
+
+			lingering := orch.LingeringProcesses()
+			if !tt.wantLingering {
+				if lingering != nil {
+					t.Errorf("LingeringProcesses() = %+v, want none", lingering)
+				}
+				return
+			}
+			if len(lingering) != 1 || lingering[0].Node != "node1" || lingering[0].Processes != 2 {
+				t.Errorf("LingeringProcesses() = %+v, want node1 with 2 processes", lingering)
+			}
+		})
+	}
+}
+
+func TestCleanup_ReleasesOwnPorts(t *testing.T) {
+	orch, daemons, _ := newFailingOrchestrator(t, "")
+	orch.SetPortRange("team-a", 5201, 5300)
+
+	if err := orch.Cleanup(context.Background()); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	for i, daemon := range daemons {
+		if len(daemon.stopOwners) != 1 || daemon.stopOwners[0] != "team-a" {
+			t.Errorf("node%d: StopAll owners = %q, want team-a's ports released", i+1, daemon.stopOwners)
+		}
+	}
+}
+
+func TestExecuteTest_OwnedWithoutPortRange(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+
+	// Without a port range the run ID owns the run's processes and ports
+	if err := orch.ExecuteTest(context.Background(), topo); err != nil {
+		t.Fatalf("ExecuteTest() error = %v", err)
+	}
+	for i, daemon := range daemons {
+		if len(daemon.startOwners) == 0 {
+			t.Errorf("node%d: no requests recorded", i+1)
+		}
+		for _, owner := range daemon.startOwners {
+			if owner != orch.RunID() {
+				t.Errorf("node%d: request owner = %q, want run ID %q", i+1, owner, orch.RunID())
+			}
+		}
+		for _, owner := range daemon.stopOwners {
+			if owner != orch.RunID() {
+				t.Errorf("node%d: StopAll owner = %q, want run ID %q", i+1, owner, orch.RunID())
+			}
+		}
+	}
+}
+
+func TestOwnStatus(t *testing.T) {
+	status := &pb.DaemonStatus{
+		RunningProcesses: 3,
+		Processes: []*pb.ProcessStatus{
+			{TestId: "server-5201", Server: true, Owner: "team-a"},
+			{TestId: "test-1", Owner: "team-a"},
+			{TestId: "server-5211", Server: true, Owner: "team-b"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		status *pb.DaemonStatus
+		owner  string
+		want   int32
+	}{
+		{"own processes", status, "team-a", 2},
+		{"other run's processes", status, "team-c", 0},
+		{"every run", status, "", 3},
+		{"daemon without a process list", &pb.DaemonStatus{RunningProcesses: 3}, "team-a", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			own := ownStatus(tt.status, tt.owner)
+			if own.RunningProcesses != tt.want {
+				t.Errorf("ownStatus(%q) runs %d processes, want %d", tt.owner, own.RunningProcesses, tt.want)
+			}
+			for _, process := range own.Processes {
+				if tt.owner != "" && process.Owner != tt.owner {
+					t.Errorf("ownStatus(%q) kept %s of %s", tt.owner, process.TestId, process.Owner)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Kinds of progress event, the "event" field of a ProgressEvent
+const (
+	EventRunStart  = "run_start"  // The run's totals are known and execution begins
+	EventPhase     = "phase"      // A phase began
+	EventNode      = "node"       // A node finished the current phase
+	EventNodeError = "node_error" // A node failed the current phase
+	EventCounters  = "counters"   // Periodic counters while tests run
+	EventError     = "error"      // The run failed in the current phase
+	EventRunEnd    = "run_end"    // The run finished, with its error as message if it failed
+)
+
+// ProgressEvent is one update of the progress tracker, written as a JSON
+// line by the run command's --progress-format json
+type ProgressEvent struct {
+	Timestamp time.Time        `json:"timestamp"`
+	RunID     string           `json:"run_id"`
+	Event     string           `json:"event"`
+	Phase     string           `json:"phase"`
+	Node      string           `json:"node,omitempty"`
+	Count     int              `json:"count,omitempty"` // Servers, tests or results the node reported
+	Message   string           `json:"message,omitempty"`
+	Counters  ProgressCounters `json:"counters"`
+}
+
+// ProgressCounters are the progress counters at the time of an event
+type ProgressCounters struct {
+	TotalNodes       int `json:"total_nodes"`
+	TotalTests       int `json:"total_tests"`
+	ConnectedNodes   int `json:"connected_nodes"`
+	PreparedNodes    int `json:"prepared_nodes"`
+	StartedServers   int `json:"started_servers"`
+	StartedClients   int `json:"started_clients"`
+	CompletedTests   int `json:"completed_tests"`
+	FailedTests      int `json:"failed_tests"`
+	CollectedResults int `json:"collected_results"`
+}
+
+// EventSink receives progress events; it is called from the goroutines that
+// update the tracker and must be safe for concurrent use
+type EventSink func(ProgressEvent)
+
+// SetEventSink passes every progress event of the run to sink
+func (o *Orchestrator) SetEventSink(sink EventSink) {
+	o.progress.mu.Lock()
+	defer o.progress.mu.Unlock()
+	o.progress.runID = o.runID
+	o.progress.sink = sink
+}
+
+// Emit sends an event of the given kind, with the current phase and
+// counters, to the event sink. It does nothing without a sink.
+func (p *Progress) Emit(kind, node string, count int, message string) {
+	if p == nil {
+		return
+	}
+	p.mu.RLock()
+	sink := p.sink
+	event := p.event(kind)
+	p.mu.RUnlock()
+
+	if sink == nil {
+		return
+	}
+	event.Node = node
+	event.Count = count
+	event.Message = message
+	sink(event)
+}
+
+// event returns an event of the given kind with the current phase and
+// counters; the caller must hold p.mu
+func (p *Progress) event(kind string) ProgressEvent {
+	return ProgressEvent{
+		Timestamp: time.Now().UTC(),
+		RunID:     p.runID,
+		Event:     kind,
+		Phase:     p.CurrentPhase,
+		Counters: ProgressCounters{
+			TotalNodes:       p.TotalNodes,
+			TotalTests:       p.TotalTests,
+			ConnectedNodes:   p.ConnectedNodes,
+			PreparedNodes:    p.PreparedNodes,
+			StartedServers:   p.StartedServers,
+			StartedClients:   p.StartedClients,
+			CompletedTests:   p.CompletedTests,
+			FailedTests:      p.FailedTests,
+			CollectedResults: p.CollectedResults,
+		},
+	}
+}
+
+// EventWriter writes progress events to w as JSON lines
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewEventWriter creates an event writer on w
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes one event; after a failed write the rest are dropped and
+// Err reports the failure
+func (w *EventWriter) Write(event ProgressEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = w.enc.Encode(event)
+	}
+}
+
+// Err returns the error of the first failed write
+func (w *EventWriter) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordEvents decodes the JSON lines an event writer wrote
+func recordEvents(t *testing.T, buf *bytes.Buffer) []ProgressEvent {
+	t.Helper()
+	events := make([]ProgressEvent, 0)
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var event ProgressEvent
+		if err := dec.Decode(&event); err != nil {
+			t.Fatalf("decoding event: %v", err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// sequence lists events as "event phase", e.g. "node preparing", leaving
+// out counters, which are logged at the progress interval
+func sequence(events []ProgressEvent) string {
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		if event.Event != EventCounters {
+			lines = append(lines, strings.TrimSpace(event.Event+" "+event.Phase))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestExecuteTest_Events(t *testing.T) {
+	orch, _, topo := newFailingOrchestrator(t, "")
+	orch.SetStallPolicy(time.Second, false)
+	orch.SetProgressLogInterval(0) // Counters at every poll
+	for _, pair := range topo.Pairs {
+		pair.Duration = time.Millisecond
+	}
+	var buf bytes.Buffer
+	writer := NewEventWriter(&buf)
+	orch.SetEventSink(writer.Write)
+
+	if err := orch.ExecuteTest(context.Background(), topo); err != nil {
+		t.Fatalf("ExecuteTest() error = %v", err)
+	}
+	orch.Progress().Emit(EventRunEnd, "", 0, "")
+	if err := writer.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	events := recordEvents(t, &buf)
+	want := strings.Join([]string{
+		"run_start",
+		"phase connecting",
+		"phase preparing",
+		"node preparing",
+		"node preparing",
+		"phase starting_servers",
+		"node starting_servers",
+		"node starting_servers",
+		"phase starting_clients",
+		"node starting_clients",
+		"node starting_clients",
+		"phase running",
+		"phase collecting",
+		"node collecting",
+		"node collecting",
+		"phase complete",
+		"run_end complete",
+	}, "\n")
+	if got := sequence(events); got != want {
+		t.Errorf("event sequence:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Each node reports each phase once, in either order
+	nodePhases := make(map[string]int)
+	counters := 0
+	for _, event := range events {
+		switch event.Event {
+		case EventNode:
+			nodePhases[event.Node]++
+		case EventCounters:
+			if event.Phase != string(StateRunning) {
+				t.Errorf("counters event in phase %s, want running", event.Phase)
+			}
+			counters++
+		}
+	}
+	if nodePhases["node1"] != 4 || nodePhases["node2"] != 4 {
+		t.Errorf("node events by node = %v, want 4 each", nodePhases)
+	}
+	if counters == 0 {
+		t.Error("no counters events while the tests ran")
+	}
+
+	for _, event := range events {
+		if event.RunID != orch.RunID() || event.Timestamp.IsZero() {
+			t.Errorf("%s event: run_id %q, timestamp %v", event.Event, event.RunID, event.Timestamp)
+		}
+	}
+	first, last := events[0], events[len(events)-1]
+	if first.Counters.TotalNodes != 2 || first.Counters.TotalTests != 2 {
+		t.Errorf("run_start counters = %+v, want 2 nodes and 2 tests", first.Counters)
+	}
+	if last.Counters.StartedClients != 2 || last.Counters.CompletedTests != 2 {
+		t.Errorf("run_end counters = %+v, want 2 clients started and 2 tests completed", last.Counters)
+	}
+}
+
+func TestExecuteTest_FailureEvents(t *testing.T) {
+	orch, _, topo := newFailingOrchestrator(t, "StartClients")
+	var buf bytes.Buffer
+	orch.SetEventSink(NewEventWriter(&buf).Write)
+
+	if err := orch.ExecuteTest(context.Background(), topo); err == nil {
+		t.Fatal("ExecuteTest() expected error")
+	}
+
+	var nodeError, runError *ProgressEvent
+	for _, event := range recordEvents(t, &buf) {
+		switch event.Event {
+		case EventNodeError:
+			nodeError = &event
+		case EventError:
+			runError = &event
+		}
+	}
+	if nodeError == nil || nodeError.Node != "node1" || nodeError.Phase != string(StateStartingClients) {
+		t.Errorf("node_error event = %+v, want node1 in starting_clients", nodeError)
+	}
+	if runError == nil || !strings.Contains(runError.Message, "start clients phase failed") {
+		t.Errorf("error event = %+v, want the start clients failure", runError)
+	}
+}
@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+)
+
+// failureCleanupTimeout bounds the RPCs issued after a failed run. A fresh
+// context is used because the run's own context may already be cancelled.
+const failureCleanupTimeout = 30 * time.Second
+
+// FailurePolicy controls what happens on the daemons when a run fails part-way
+type FailurePolicy struct {
+	StopAll        bool // Stop all iperf3 processes
+	ClearResults   bool // Drop results stored on the daemons
+	CollectPartial bool // Retrieve whatever results exist for partial output
+}
+
+// DefaultFailurePolicy stops processes and collects partial results while
+// leaving the daemons' stored results in place
+func DefaultFailurePolicy() FailurePolicy {
+	return FailurePolicy{
+		StopAll:        true,
+		CollectPartial: true,
+	}
+}
+
+// SetFailurePolicy sets the policy applied when a phase fails
+func (o *Orchestrator) SetFailurePolicy(policy FailurePolicy) {
+	o.failurePolicy = policy
+}
+
+// FailedPhase returns the phase that was running when the test failed, or ""
+func (o *Orchestrator) FailedPhase() string {
+	return o.failedPhase
+}
+
+// FailedNodes returns the nodes on which the failed phase failed, or nil if
+// the run did not fail on particular nodes
+func (o *Orchestrator) FailedNodes() *nodeerr.MultiNodeError {
+	return o.failedNodes
+}
+
+// PartialResults returns the results collected after a failed run
+func (o *Orchestrator) PartialResults() []*pb.TestResult {
+	return o.partialResults
+}
+
+// applyFailurePolicy stops processes and collects or clears results
+// according to the failure policy
+func (o *Orchestrator) applyFailurePolicy() {
+	policy := o.failurePolicy
+	log.Printf("Run failed during %s; applying failure policy (stop_all=%t, collect_partial=%t, clear_results=%t)",
+		o.failedPhase, policy.StopAll, policy.CollectPartial, policy.ClearResults)
+	unreachable := o.unreachableNodes()
+	if o.failedNodes != nil {
+		log.Printf("Failed nodes: %s", strings.Join(o.failedNodes.Nodes(), ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), failureCleanupTimeout)
+	defer cancel()
+
+	if policy.StopAll {
+		if err := o.Cleanup(ctx); err != nil {
+			log.Printf("Warning: cleanup after failure had errors: %v", err)
+		}
+	}
+
+	if !policy.CollectPartial && !policy.ClearResults {
+		return
+	}
+
+	for _, c := range o.clientPool.GetAllClients() {
+		if unreachable[c.Node.ID] {
+			log.Printf("Warning: not retrieving results from unreachable node %s", c.Node.ID)
+			continue
+		}
+		resp, err := c.Client.GetResults(ctx, &pb.GetResultsRequest{
+			ClearAfterRetrieval: policy.ClearResults,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to retrieve results from node %s after failure: %v", c.Node.ID, err)
+			continue
+		}
+
+		if policy.CollectPartial {
+			o.partialResults = append(o.partialResults, resp.Results...)
+		}
+	}
+
+	if policy.CollectPartial {
+		log.Printf("Collected %d partial results", len(o.partialResults))
+	}
+}
+
+// unreachableNodes returns the nodes whose daemons could not be reached in the
+// failed phase, which are not asked for results again
+func (o *Orchestrator) unreachableNodes() map[string]bool {
+	unreachable := make(map[string]bool)
+	if o.failedNodes == nil {
+		return unreachable
+	}
+	for _, nodeErr := range o.failedNodes.Errors {
+		if status.Code(nodeErr.Err) == codes.Unavailable {
+			unreachable[nodeErr.NodeID] = true
+		}
+	}
+	return unreachable
+}
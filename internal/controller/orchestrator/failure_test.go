@@ -0,0 +1,380 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// fakeDaemon is an in-process daemon client that fails one RPC on demand
+type fakeDaemon struct {
+	pb.DaemonServiceClient
+
+	failOn string
+
+	mu           sync.Mutex
+	stopAllCalls int
+	stopOwners   []string // Owner of each StopAll request
+	startOwners  []string // Owner of each PrepareTest, StartServers and StartClients request
+	resultCalls  []*pb.GetResultsRequest
+	started      map[string]bool // Client test IDs, which complete immediately
+	servers      []int32
+
+	// running, stored and completed are reported by GetStatus, as left by
+	// another run; started tests that are not stuck add to completed
+	running   int32
+	stored    int32
+	completed int32
+
+	// totalPorts, if set, is the size of the port range PrepareTest reports
+	totalPorts int32
+
+	// starts, if set, records client test IDs in start order across daemons
+	starts *startLog
+
+	// iperfError, if set, is reported by Initialize as iperf3 being missing
+	iperfError string
+
+	// stuck client tests are listed as running processes until stopped
+	stuck   map[string]bool
+	stopped []string
+
+	// lingering processes survive StopAll without force, and with it too if
+	// unkillable is set
+	lingering  int32
+	unkillable bool
+	forceStops int
+
+	// runScope, if set, makes GetStatus count the started tests of the run
+	// Initialize last named apart from completed
+	runScope bool
+	runID    string
+}
+
+// startLog records client starts shared by several fake daemons
+type startLog struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (l *startLog) add(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ids = append(l.ids, id)
+}
+
+func (f *fakeDaemon) fail(method string) error {
+	if f.failOn == method {
+		return errors.New("injected failure")
+	}
+	return nil
+}
+
+func (f *fakeDaemon) Initialize(ctx context.Context, in *pb.InitializeRequest, opts ...grpc.CallOption) (*pb.InitializeResponse, error) {
+	if err := f.fail("Initialize"); err != nil {
+		return nil, err
+	}
+	if f.iperfError != "" {
+		return &pb.InitializeResponse{Success: true, NodeInfo: &pb.NodeInfo{IperfError: f.iperfError}}, nil
+	}
+	if f.runScope {
+		f.mu.Lock()
+		f.runID = in.RunId
+		f.mu.Unlock()
+	}
+	return &pb.InitializeResponse{Success: true, RunId: in.RunId}, nil
+}
+
+func (f *fakeDaemon) GetCapabilities(ctx context.Context, in *pb.GetCapabilitiesRequest, opts ...grpc.CallOption) (*pb.GetCapabilitiesResponse, error) {
+	features := make([]string, 0)
+	for _, feature := range models.SupportedFeatures() {
+		features = append(features, string(feature))
+	}
+	return &pb.GetCapabilitiesResponse{Version: "test", Features: features}, nil
+}
+
+func (f *fakeDaemon) GatherFacts(ctx context.Context, in *pb.GatherFactsRequest, opts ...grpc.CallOption) (*pb.GatherFactsResponse, error) {
+	return &pb.GatherFactsResponse{Facts: &pb.NodeFacts{Os: "linux"}}, nil
+}
+
+func (f *fakeDaemon) GetStatus(ctx context.Context, in *pb.GetStatusRequest, opts ...grpc.CallOption) (*pb.GetStatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status := &pb.DaemonStatus{
+		Healthy:          true,
+		ServerPorts:      f.servers,
+		RunningProcesses: f.running,
+		StoredResults:    f.stored,
+		CompletedTests:   f.completed,
+	}
+	for id := range f.started {
+		if !f.stuck[id] {
+			status.CompletedTests++
+		}
+	}
+	if f.runScope && f.runID != "" && in.RunId == f.runID {
+		status.RunId = f.runID
+		status.RunCompletedTests = status.CompletedTests - f.completed
+	}
+	if in.IncludeProcesses {
+		for id := range f.stuck {
+			status.Processes = append(status.Processes, &pb.ProcessStatus{TestId: id, StartTimeUnixMs: time.Now().UnixMilli()})
+		}
+	}
+	return &pb.GetStatusResponse{Status: status}, nil
+}
+
+func (f *fakeDaemon) StopTests(ctx context.Context, in *pb.StopTestsRequest, opts ...grpc.CallOption) (*pb.StopTestsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &pb.StopTestsResponse{}
+	for _, id := range in.TestIds {
+		if !f.stuck[id] {
+			resp.Errors = append(resp.Errors, fmt.Sprintf("test %s is not running", id))
+			continue
+		}
+		delete(f.stuck, id)
+		f.stopped = append(f.stopped, id)
+		resp.StoppedTestIds = append(resp.StoppedTestIds, id)
+	}
+	return resp, nil
+}
+
+func (f *fakeDaemon) PrepareTest(ctx context.Context, in *pb.PrepareTestRequest, opts ...grpc.CallOption) (*pb.PrepareTestResponse, error) {
+	if err := f.fail("PrepareTest"); err != nil {
+		return &pb.PrepareTestResponse{CanHandle: false, Message: err.Error()}, nil
+	}
+	f.mu.Lock()
+	f.startOwners = append(f.startOwners, in.Owner)
+	f.mu.Unlock()
+	if f.totalPorts > 0 {
+		return &pb.PrepareTestResponse{
+			CanHandle:      false,
+			Message:        "insufficient ports",
+			PortRange:      &pb.PortRange{Start: 5201, End: 5201 + f.totalPorts - 1},
+			TotalPorts:     f.totalPorts,
+			AvailablePorts: f.totalPorts,
+		}, nil
+	}
+	return &pb.PrepareTestResponse{CanHandle: true}, nil
+}
+
+func (f *fakeDaemon) StartServers(ctx context.Context, in *pb.StartServersRequest, opts ...grpc.CallOption) (*pb.StartServersResponse, error) {
+	if err := f.fail("StartServers"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.startOwners = append(f.startOwners, in.Owner)
+	f.servers = append(f.servers, in.Ports...)
+	return &pb.StartServersResponse{Success: true, StartedPorts: in.Ports}, nil
+}
+
+func (f *fakeDaemon) StartClients(ctx context.Context, in *pb.StartClientsRequest, opts ...grpc.CallOption) (*pb.StartClientsResponse, error) {
+	if err := f.fail("StartClients"); err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.startOwners = append(f.startOwners, in.Owner)
+	if f.started == nil {
+		f.started = make(map[string]bool)
+	}
+	ids := make([]string, 0, len(in.Targets))
+	for _, target := range in.Targets {
+		f.started[target.TestId] = true
+		ids = append(ids, target.TestId)
+		if f.starts != nil {
+			f.starts.add(target.TestId)
+		}
+	}
+	return &pb.StartClientsResponse{Success: true, StartedTestIds: ids}, nil
+}
+
+func (f *fakeDaemon) StopAll(ctx context.Context, in *pb.StopAllRequest, opts ...grpc.CallOption) (*pb.StopAllResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopAllCalls++
+	f.stopOwners = append(f.stopOwners, in.Owner)
+	f.servers = nil
+	f.running = 0
+	if in.Force {
+		f.forceStops++
+	}
+	if f.lingering > 0 && (!in.Force || f.unkillable) {
+		f.running = f.lingering
+	}
+	return &pb.StopAllResponse{Success: true}, nil
+}
+
+func (f *fakeDaemon) GetResults(ctx context.Context, in *pb.GetResultsRequest, opts ...grpc.CallOption) (*pb.GetResultsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Completion polls ask for specific tests and are not recorded
+	if len(in.TestIds) > 0 {
+		results := make([]*pb.TestResult, 0, len(in.TestIds))
+		for _, id := range in.TestIds {
+			if f.started[id] {
+				results = append(results, &pb.TestResult{TestId: id, Status: pb.TestStatus_TEST_STATUS_COMPLETED})
+			}
+		}
+		return &pb.GetResultsResponse{Results: results, TotalCount: int32(len(results))}, nil
+	}
+
+	f.resultCalls = append(f.resultCalls, in)
+	if in.ClearAfterRetrieval {
+		f.stored = 0
+	}
+	return &pb.GetResultsResponse{
+		Results:    []*pb.TestResult{{TestId: "partial", Status: pb.TestStatus_TEST_STATUS_COMPLETED}},
+		TotalCount: 1,
+	}, nil
+}
+
+// newFailingOrchestrator builds an orchestrator over two fake daemons, the
+// first of which fails the given RPC
+func newFailingOrchestrator(t *testing.T, failOn string) (*Orchestrator, []*fakeDaemon, *topology.Topology) {
+	t.Helper()
+
+	nodes := models.NewNodeRegistry()
+	pool := client.NewPool(0)
+	daemons := make([]*fakeDaemon, 0, 2)
+	for i := 1; i <= 2; i++ {
+		node := &models.Node{ID: fmt.Sprintf("node%d", i), Hostname: fmt.Sprintf("node%d", i), IP: "127.0.0.1", Port: 50051}
+		if err := nodes.AddNode(node); err != nil {
+			t.Fatalf("AddNode() error = %v", err)
+		}
+
+		daemon := &fakeDaemon{}
+		if i == 1 {
+			daemon.failOn = failOn
+		}
+		daemons = append(daemons, daemon)
+		pool.AddClient(node, daemon)
+	}
+
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	profiles := models.NewProfileRegistry()
+	if err := profiles.AddProfile(profile); err != nil {
+		t.Fatalf("AddProfile() error = %v", err)
+	}
+
+	topo, err := topology.NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	orch := NewOrchestrator(pool, false, false, "")
+	orch.serverStartDelay = 0
+
+	return orch, daemons, topo
+}
+
+func TestExecuteTest_FailurePolicy(t *testing.T) {
+	phases := []struct {
+		failOn string
+		phase  TestState
+	}{
+		{"Initialize", StateConnecting},
+		{"PrepareTest", StatePreparing},
+		{"StartServers", StateStartingServers},
+		{"StartClients", StateStartingClients},
+	}
+
+	policies := []struct {
+		name   string
+		policy FailurePolicy
+	}{
+		{"default", DefaultFailurePolicy()},
+		{"clear only", FailurePolicy{ClearResults: true}},
+		{"nothing", FailurePolicy{}},
+	}
+
+	for _, tt := range phases {
+		for _, p := range policies {
+			t.Run(tt.failOn+"/"+p.name, func(t *testing.T) {
+				orch, daemons, topo := newFailingOrchestrator(t, tt.failOn)
+				orch.SetFailurePolicy(p.policy)
+
+				if err := orch.ExecuteTest(context.Background(), topo); err == nil {
+					t.Fatal("ExecuteTest() expected error")
+				}
+
+				if orch.GetState() != StateFailed {
+					t.Errorf("GetState() = %s, want %s", orch.GetState(), StateFailed)
+				}
+				if orch.FailedPhase() != string(tt.phase) {
+					t.Errorf("FailedPhase() = %q, want %q", orch.FailedPhase(), tt.phase)
+				}
+				if failed := orch.FailedNodes(); failed == nil || fmt.Sprint(failed.Nodes()) != "[node1]" {
+					t.Errorf("FailedNodes() = %v, want node1", failed)
+				}
+
+				for i, daemon := range daemons {
+					wantStops := 0
+					if p.policy.StopAll {
+						wantStops = 1
+					}
+					if daemon.stopAllCalls != wantStops {
+						t.Errorf("node%d: StopAll called %d times, want %d", i+1, daemon.stopAllCalls, wantStops)
+					}
+
+					wantRetrievals := 0
+					if p.policy.CollectPartial || p.policy.ClearResults {
+						wantRetrievals = 1
+					}
+					if len(daemon.resultCalls) != wantRetrievals {
+						t.Fatalf("node%d: GetResults called %d times, want %d", i+1, len(daemon.resultCalls), wantRetrievals)
+					}
+					if wantRetrievals > 0 && daemon.resultCalls[0].ClearAfterRetrieval != p.policy.ClearResults {
+						t.Errorf("node%d: ClearAfterRetrieval = %t, want %t",
+							i+1, daemon.resultCalls[0].ClearAfterRetrieval, p.policy.ClearResults)
+					}
+				}
+
+				wantPartial := 0
+				if p.policy.CollectPartial {
+					wantPartial = len(daemons)
+				}
+				if len(orch.PartialResults()) != wantPartial {
+					t.Errorf("PartialResults() has %d results, want %d", len(orch.PartialResults()), wantPartial)
+				}
+			})
+		}
+	}
+}
+
+func TestExecuteTest_IperfMissing(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	daemons[0].iperfError = `exec: "iperf3": executable file not found in $PATH`
+
+	err := orch.ExecuteTest(context.Background(), topo)
+	if err == nil || !strings.Contains(err.Error(), "iperf3 not found") {
+		t.Fatalf("ExecuteTest() error = %v, want iperf3 not found", err)
+	}
+	if orch.FailedPhase() != string(StateConnecting) {
+		t.Errorf("FailedPhase() = %q, want %q", orch.FailedPhase(), StateConnecting)
+	}
+	if failed := orch.FailedNodes(); failed == nil || fmt.Sprint(failed.Nodes()) != "[node1]" {
+		t.Errorf("FailedNodes() = %v, want node1", failed)
+	}
+	for i, daemon := range daemons {
+		if len(daemon.servers) > 0 {
+			t.Errorf("node%d: servers started %v, want none", i+1, daemon.servers)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// groupRun tracks the execution of one group
+type groupRun struct {
+	group    *topology.Group
+	started  bool
+	done     bool
+	deadline time.Time // After this the group is treated as done even if results are missing
+}
+
+// runGroups starts each group once its dependencies allow it and waits for
+// every group to finish. A group may start when each group it depends on has
+// finished, or merely started if that group runs in the background.
+func (o *Orchestrator) runGroups(ctx context.Context) error {
+	runs := make([]*groupRun, 0, len(o.topology.Groups))
+	byName := make(map[string]*groupRun, len(o.topology.Groups))
+	for _, group := range o.topology.Groups {
+		run := &groupRun{group: group}
+		runs = append(runs, run)
+		byName[group.Name] = run
+	}
+
+	ticker := o.clientPool.PollTicker(o.groupPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, run := range runs {
+			if run.started || !groupReady(run.group, byName) {
+				continue
+			}
+			if err := o.startGroup(ctx, run.group); err != nil {
+				return fmt.Errorf("group %s: %w", run.group.Name, err)
+			}
+			run.started = true
+			schedule := run.group.Schedule()
+			run.deadline = time.Now().Add(schedule + waitGrace(schedule))
+		}
+
+		o.setState(StateRunning)
+		remaining := 0
+		for _, run := range runs {
+			if run.started && !run.done {
+				run.done = o.groupFinished(ctx, run)
+			}
+			if !run.done {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			log.Println("All groups complete")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			o.updateTestProgress(ctx)
+			if err := o.startQueued(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// groupReady reports whether every dependency of group allows it to start
+func groupReady(group *topology.Group, runs map[string]*groupRun) bool {
+	for _, dep := range group.After {
+		run := runs[dep]
+		if !run.started || (!run.done && !run.group.Background) {
+			return false
+		}
+	}
+	return true
+}
+
+// startGroup starts the servers and then the clients of a group
+func (o *Orchestrator) startGroup(ctx context.Context, group *topology.Group) error {
+	mode := ""
+	if group.Background {
+		mode = " (background)"
+	}
+	log.Printf("Starting group %s%s: %d test pairs", group.Name, mode, len(group.Pairs))
+
+	if err := o.waitWhilePaused(ctx); err != nil {
+		return fmt.Errorf("aborted while paused: %w", err)
+	}
+	if err := o.StartServers(ctx, group.ServerPorts(), group.ServerBinds()); err != nil {
+		return fmt.Errorf("start servers phase failed: %w", err)
+	}
+
+	if err := o.waitWhilePaused(ctx); err != nil {
+		return fmt.Errorf("aborted while paused: %w", err)
+	}
+	if err := o.StartClients(ctx, group.ClientTests()); err != nil {
+		return fmt.Errorf("start clients phase failed: %w", err)
+	}
+
+	return nil
+}
+
+// groupFinished reports whether every test of a running group has a result
+// on its source node, or the group's deadline has passed. Tests held back by
+// max_concurrent_tests push the deadline back until they start.
+func (o *Orchestrator) groupFinished(ctx context.Context, run *groupRun) bool {
+	if o.throttle != nil {
+		end, queued := o.throttle.expectedEnd(run.group.Pairs)
+		if queued {
+			return false
+		}
+		if deadline := end.Add(waitGrace(run.group.Schedule())); deadline.After(run.deadline) {
+			run.deadline = deadline
+		}
+	}
+	for nodeID, pairs := range run.group.ClientTests() {
+		testIDs := make([]string, 0, len(pairs))
+		for _, pair := range pairs {
+			if !o.blackouts.isSkipped(pair) {
+				testIDs = append(testIDs, pair.ExpandedTestIDs()...)
+			}
+		}
+
+		c, err := o.clientPool.GetClient(nodeID)
+		if err != nil {
+			continue
+		}
+		resp, err := c.Client.GetResults(ctx, &pb.GetResultsRequest{TestIds: testIDs})
+		if err == nil && len(resp.Results) >= len(testIDs) {
+			continue
+		}
+
+		if time.Now().After(run.deadline) {
+			log.Printf("Warning: group %s did not report all results in time; continuing", run.group.Name)
+			return true
+		}
+		return false
+	}
+
+	log.Printf("Group %s complete", run.group.Name)
+	return true
+}
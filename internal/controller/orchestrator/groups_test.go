@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+func TestExecuteTest_Groups(t *testing.T) {
+	nodes := models.NewNodeRegistry()
+	pool := client.NewPool(0)
+	starts := &startLog{}
+	for i := 1; i <= 3; i++ {
+		node := &models.Node{ID: fmt.Sprintf("node%d", i), Hostname: fmt.Sprintf("node%d", i), IP: "127.0.0.1", Port: 50051}
+		if err := nodes.AddNode(node); err != nil {
+			t.Fatalf("AddNode() error = %v", err)
+		}
+		pool.AddClient(node, &fakeDaemon{starts: starts})
+	}
+
+	profile := &models.TestProfile{Name: "default", Duration: 1 * time.Second, Parallel: 1}
+	profiles := models.NewProfileRegistry()
+	if err := profiles.AddProfile(profile); err != nil {
+		t.Fatalf("AddProfile() error = %v", err)
+	}
+
+	gen := topology.NewGenerator(nodes, profiles, profile)
+	gen.AddGroup(topology.GroupSpec{
+		Name:  "baseline",
+		Pairs: []topology.GroupPairSpec{{SourceID: "node1", DestID: "node2"}},
+	})
+	gen.AddGroup(topology.GroupSpec{
+		Name:       "load",
+		After:      []string{"baseline"},
+		Background: true,
+		Pairs:      []topology.GroupPairSpec{{SourceID: "node3", DestID: "node2"}},
+	})
+	gen.AddGroup(topology.GroupSpec{
+		Name:  "victim",
+		After: []string{"load"},
+		Pairs: []topology.GroupPairSpec{{SourceID: "node1", DestID: "node2"}},
+	})
+	topo, err := gen.GenerateGroups()
+	if err != nil {
+		t.Fatalf("GenerateGroups() error = %v", err)
+	}
+
+	orch := NewOrchestrator(pool, false, false, "")
+	orch.serverStartDelay = 0
+	orch.groupPollInterval = 10 * time.Millisecond
+
+	if err := orch.ExecuteTest(context.Background(), topo); err != nil {
+		t.Fatalf("ExecuteTest() error = %v", err)
+	}
+
+	want := []string{topo.Groups[0].Pairs[0].TestID, topo.Groups[1].Pairs[0].TestID, topo.Groups[2].Pairs[0].TestID}
+	if fmt.Sprint(starts.ids) != fmt.Sprint(want) {
+		t.Errorf("client start order = %v, want %v", starts.ids, want)
+	}
+}
@@ -3,12 +3,20 @@ package orchestrator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
 	"github.com/bensons/iperf-cnc/internal/controller/client"
 	"github.com/bensons/iperf-cnc/internal/controller/topology"
 )
@@ -28,6 +36,15 @@ const (
 	StateFailed          TestState = "failed"
 )
 
+// Bounds on the grace period added to expected test schedules and on the
+// interval daemon status is polled at while waiting
+const (
+	minWaitGrace    = 2 * time.Second
+	maxWaitGrace    = 10 * time.Second
+	minPollInterval = 250 * time.Millisecond
+	maxPollInterval = 2 * time.Second
+)
+
 // Orchestrator manages the execution of distributed tests
 type Orchestrator struct {
 	clientPool        *client.Pool
@@ -37,10 +54,63 @@ type Orchestrator struct {
 	saveDaemonResults bool
 	saveRawResults    bool
 	rawResultsDir     string
+	progress          *Progress
+	portRange         *pb.PortRange // Optional server port sub-range for this run
+	portOwner         string        // Owns this run's processes and ports on the daemons
+	stopEveryRun      bool          // Cleanup stops the processes of every run, not only this one's
+	serverStartDelay  time.Duration
+	groupPollInterval time.Duration
+	nodeFacts         map[string]*models.NodeFacts
+	force             bool   // Stop and clear busy daemons instead of refusing to run
+	ccPolicy          string // What daemons do about unavailable congestion control
+
+	// Daemon resource sampling interval; 0 disables sampling
+	sampleInterval time.Duration
+
+	// runID identifies this run to the daemons, which count its tests apart
+	// from those of other runs. Daemons without the run_scope feature are
+	// counted from their test counters before this run's clients started.
+	runID        string
+	testBaseline map[string]NodeTests
+
+	// Global cap on client processes in flight; 0 is unbounded. throttle
+	// queues the tests of a run that exceeds it.
+	maxConcurrentTests int
+	throttle           *throttle
+	queuePollInterval  time.Duration
+
+	// Deferring and skipping tests for blackout windows of their nodes
+	blackouts *blackouts
+
+	// Giving up on tests that stall past their expected end
+	stallWindow  time.Duration
+	stopStalled  bool
+	stalledTests []*StalledTest
+
+	// Verifying the daemons' processes exit at cleanup; 0 skips it
+	processStopTimeout  time.Duration
+	cleanupPollInterval time.Duration
+	lingering           []*LingeringNode
+
+	// Logging of per-node phase progress
+	verbose             bool
+	progressLogInterval time.Duration
+
+	// Failure handling
+	failurePolicy  FailurePolicy
+	failedPhase    string
+	failedNodes    *nodeerr.MultiNodeError // Nil unless the failure was per node
+	partialResults []*pb.TestResult
+
+	// paused holds back the next start phase until Resume is called
+	pauseMu sync.Mutex
+	paused  bool
+	resume  chan struct{}
 }
 
 // NewOrchestrator creates a new test orchestrator
 func NewOrchestrator(clientPool *client.Pool, saveDaemonResults bool, saveRawResults bool, rawResultsDir string) *Orchestrator {
+	runID := models.NewRunID()
 	return &Orchestrator{
 		clientPool:        clientPool,
 		state:             StateInit,
@@ -48,94 +118,368 @@ func NewOrchestrator(clientPool *client.Pool, saveDaemonResults bool, saveRawRes
 		saveDaemonResults: saveDaemonResults,
 		saveRawResults:    saveRawResults,
 		rawResultsDir:     rawResultsDir,
+		progress:          NewProgress(),
+		serverStartDelay:  2 * time.Second,
+		groupPollInterval: time.Second,
+		queuePollInterval: time.Second,
+		blackouts:         newBlackouts(),
+		failurePolicy:     DefaultFailurePolicy(),
+		runID:             runID,
+		portOwner:         runID, // Until SetPortRange names one
+
+		progressLogInterval: DefaultProgressLogInterval,
+		processStopTimeout:  defaultProcessStopTimeout,
+		cleanupPollInterval: 500 * time.Millisecond,
+	}
+}
+
+// SetPortRange asks daemons to reserve start-end for this run, identified by
+// owner rather than the run ID if owner is set
+func (o *Orchestrator) SetPortRange(owner string, start, end int32) {
+	if owner != "" {
+		o.portOwner = owner
+	}
+	o.portRange = &pb.PortRange{Start: start, End: end}
+}
+
+// StopEveryRun makes cleanup stop every process on the daemons and release
+// every port, rather than only this run's, such as for servers started by
+// an earlier invocation
+func (o *Orchestrator) StopEveryRun() {
+	o.stopEveryRun = true
+}
+
+// stopOwner returns the owner cleanup stops the processes of; "" stops all
+func (o *Orchestrator) stopOwner() string {
+	if o.stopEveryRun {
+		return ""
+	}
+	return o.portOwner
+}
+
+// SetCongestionControlPolicy sets whether daemons refuse ("reject", the
+// default) or only report ("warn") profiles requesting congestion control
+// algorithms their kernel does not have
+func (o *Orchestrator) SetCongestionControlPolicy(policy string) {
+	o.ccPolicy = policy
+}
+
+// SetSampleInterval asks daemons to sample their resource usage every
+// interval while the run's tests execute
+func (o *Orchestrator) SetSampleInterval(interval time.Duration) {
+	o.sampleInterval = interval
+}
+
+// RunID returns the ID the daemons tag this run's results with
+func (o *Orchestrator) RunID() string {
+	return o.runID
+}
+
+// Progress returns the progress tracker updated as the test executes
+func (o *Orchestrator) Progress() *Progress {
+	return o.progress
+}
+
+// Pause holds back the next server or client start phase. Phases already in
+// flight run to completion.
+func (o *Orchestrator) Pause() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+
+	if !o.paused {
+		o.paused = true
+		o.resume = make(chan struct{})
+	}
+}
+
+// Resume releases a paused orchestrator
+func (o *Orchestrator) Resume() {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+
+	if o.paused {
+		o.paused = false
+		close(o.resume)
+	}
+}
+
+// Paused reports whether new start phases are being held back
+func (o *Orchestrator) Paused() bool {
+	o.pauseMu.Lock()
+	defer o.pauseMu.Unlock()
+	return o.paused
+}
+
+// waitWhilePaused blocks until the orchestrator is resumed or ctx is done
+func (o *Orchestrator) waitWhilePaused(ctx context.Context) error {
+	o.pauseMu.Lock()
+	paused, resume := o.paused, o.resume
+	o.pauseMu.Unlock()
+
+	if !paused {
+		return nil
+	}
+
+	log.Println("Paused; waiting to resume before starting the next phase")
+	select {
+	case <-resume:
+		log.Println("Resumed")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-// ExecuteTest executes a complete test workflow
+// setState records the current state and mirrors it into the progress tracker
+func (o *Orchestrator) setState(state TestState) {
+	o.state = state
+	if state != StateFailed {
+		o.progress.SetPhase(string(state))
+	}
+}
+
+// ExecuteTest executes a complete test workflow, composing the phase methods
+// below. If a phase fails, the failure policy decides whether processes are
+// stopped and partial results collected before the error is returned.
 func (o *Orchestrator) ExecuteTest(ctx context.Context, topo *topology.Topology) error {
 	o.topology = topo
+	o.progress.SetTotals(o.clientPool.Count(), topo.GetTestCount(), topo.GetServerCount(), topo.GetClientCount())
 
 	log.Printf("Starting test execution with %d test pairs", topo.GetTestCount())
+	o.progress.Emit(EventRunStart, "", 0, "")
+
+	if err := o.runPhases(ctx); err != nil {
+		o.progress.Emit(EventError, "", 0, err.Error())
+		o.failedPhase = o.progress.Snapshot().CurrentPhase
+		o.failedNodes, _ = nodeerr.As(err)
+		o.setState(StateFailed)
+		if !errors.Is(err, ErrDaemonsBusy) {
+			o.applyFailurePolicy()
+		}
+		return err
+	}
+
+	// Phase 7: Cleanup
+	if background := len(topo.BackgroundTests()); background > 0 {
+		log.Printf("Stopping %d background tests", background)
+	}
+	if err := o.Cleanup(ctx); err != nil {
+		log.Printf("Warning: cleanup phase had errors: %v", err)
+	}
+
+	o.setState(StateComplete)
+	log.Println("Test execution complete")
+
+	return nil
+}
 
+// runPhases runs phases 1 through 6, stopping at the first failure
+func (o *Orchestrator) runPhases(ctx context.Context) error {
 	// Phase 1: Initialize all daemons
-	if err := o.initializePhase(ctx); err != nil {
+	if err := o.Initialize(ctx, o.topology); err != nil {
 		return fmt.Errorf("initialization phase failed: %w", err)
 	}
 
+	// Refuse to interleave with a run still in flight on any daemon
+	if err := o.Preflight(ctx); err != nil {
+		return fmt.Errorf("pre-flight check failed: %w", err)
+	}
+
 	// Phase 2: Prepare test on all nodes
-	if err := o.preparePhase(ctx); err != nil {
+	if err := o.Prepare(ctx, o.topology); err != nil {
 		return fmt.Errorf("prepare phase failed: %w", err)
 	}
 
-	// Phase 3: Start servers on all nodes
-	if err := o.startServersPhase(ctx); err != nil {
-		return fmt.Errorf("start servers phase failed: %w", err)
-	}
+	if len(o.topology.Groups) > 0 {
+		// Phases 3 to 5 run once per group, in dependency order
+		if err := o.runGroups(ctx); err != nil {
+			return err
+		}
+	} else {
+		// Phase 3: Start servers on all nodes
+		if err := o.waitWhilePaused(ctx); err != nil {
+			return fmt.Errorf("aborted while paused: %w", err)
+		}
+		if err := o.StartServers(ctx, o.topology.ServerPorts, o.topology.ServerBinds()); err != nil {
+			return fmt.Errorf("start servers phase failed: %w", err)
+		}
 
-	// Phase 4: Start clients on all nodes
-	if err := o.startClientsPhase(ctx); err != nil {
-		return fmt.Errorf("start clients phase failed: %w", err)
-	}
+		// Phase 4: Start clients on all nodes
+		if err := o.waitWhilePaused(ctx); err != nil {
+			return fmt.Errorf("aborted while paused: %w", err)
+		}
+		if err := o.StartClients(ctx, o.topology.ClientTests); err != nil {
+			return fmt.Errorf("start clients phase failed: %w", err)
+		}
 
-	// Phase 5: Wait for tests to complete
-	if err := o.waitPhase(ctx); err != nil {
-		return fmt.Errorf("wait phase failed: %w", err)
+		// Phase 5: Wait for tests to complete
+		if err := o.Wait(ctx, o.topology.ClientTests); err != nil {
+			return fmt.Errorf("wait phase failed: %w", err)
+		}
 	}
 
 	// Phase 6: Collect results
-	if err := o.collectPhase(ctx); err != nil {
+	if err := o.Collect(ctx); err != nil {
 		return fmt.Errorf("collect phase failed: %w", err)
 	}
 
-	// Phase 7: Cleanup
-	if err := o.cleanupPhase(ctx); err != nil {
-		log.Printf("Warning: cleanup phase had errors: %v", err)
-	}
-
-	o.state = StateComplete
-	log.Println("Test execution complete")
-
 	return nil
 }
 
-// initializePhase initializes all daemons
-func (o *Orchestrator) initializePhase(ctx context.Context) error {
-	o.state = StateConnecting
-	log.Println("Phase 1: Initializing daemons...")
+// Initialize initializes all daemons, detects their capabilities and facts,
+// and verifies that the nodes of topo support the features it relies on
+func (o *Orchestrator) Initialize(ctx context.Context, topo *topology.Topology) error {
+	o.setState(StateConnecting)
+	log.Printf("Phase 1: Initializing daemons for run %s...", o.runID)
 
 	req := &pb.InitializeRequest{
 		MaxProcesses: 200,
 		LogLevel:     "info",
 		SaveResults:  o.saveDaemonResults,
+		RunId:        o.runID,
+	}
+	if o.sampleInterval > 0 {
+		req.SampleIntervalMs = int32(o.sampleInterval.Milliseconds()) // #nosec G115 -- Intervals are configured in seconds
 	}
 
 	if err := o.clientPool.Initialize(ctx, req); err != nil {
-		o.state = StateFailed
+		o.setState(StateFailed)
 		return err
 	}
 
+	o.progress.IncrementConnected(o.clientPool.Count())
 	log.Printf("Successfully initialized %d daemons", o.clientPool.Count())
 	if o.saveDaemonResults {
 		log.Println("Daemons will save local copies of results")
 	}
+
+	if err := o.clientPool.DetectCapabilities(ctx); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
+
+	// Record each node's environment for later interpretation of results
+	o.nodeFacts = o.clientPool.GatherFacts(ctx)
+	log.Printf("Gathered environment facts from %d nodes", len(o.nodeFacts))
+
+	for nodeID, methods := range o.clientPool.CompatibilityNodes() {
+		log.Printf("Node %s: operating in compatibility mode (%v)", nodeID, methods)
+	}
+
+	if err := o.checkRequiredFeatures(topo); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
+
 	return nil
 }
 
-// preparePhase validates capacity on all nodes
-func (o *Orchestrator) preparePhase(ctx context.Context) error {
-	o.state = StatePreparing
+// checkRequiredFeatures verifies that every node involved in a test supports
+// the daemon features the topology relies on and that have no fallback
+func (o *Orchestrator) checkRequiredFeatures(topo *topology.Topology) error {
+	required := make(map[models.Feature]map[string]bool)
+	for _, pair := range topo.Pairs {
+		if pair.Profile.ProcessCount() > 1 {
+			if required[models.FeatureProcessesPerPair] == nil {
+				required[models.FeatureProcessesPerPair] = make(map[string]bool)
+			}
+			required[models.FeatureProcessesPerPair][pair.Source.ID] = true
+			required[models.FeatureProcessesPerPair][pair.Destination.ID] = true
+		}
+		// Only clients apply timing, and the wait would be wrong if ignored
+		if pair.Duration > 0 || pair.StartDelayMs > 0 {
+			if required[models.FeaturePairTiming] == nil {
+				required[models.FeaturePairTiming] = make(map[string]bool)
+			}
+			required[models.FeaturePairTiming][pair.Source.ID] = true
+		}
+		// Older daemons round durations up to whole seconds, which only
+		// matters for measured pairs; background load runs longer anyway
+		if !pair.Background && pairDuration(pair)%time.Second != 0 {
+			if required[models.FeatureFractionalDuration] == nil {
+				required[models.FeatureFractionalDuration] = make(map[string]bool)
+			}
+			required[models.FeatureFractionalDuration][pair.Source.ID] = true
+		}
+		// Without binding, tests would silently run over whichever
+		// interface routing picks
+		if pair.Interface != "" {
+			if required[models.FeatureBindAddress] == nil {
+				required[models.FeatureBindAddress] = make(map[string]bool)
+			}
+			required[models.FeatureBindAddress][pair.Source.ID] = true
+			required[models.FeatureBindAddress][pair.Destination.ID] = true
+		}
+		// Older daemons ignore the schedule and run a sustained test,
+		// whose results would be reported as bursts
+		if pair.Profile.Burst != nil {
+			if required[models.FeatureBurst] == nil {
+				required[models.FeatureBurst] = make(map[string]bool)
+			}
+			required[models.FeatureBurst][pair.Source.ID] = true
+		}
+	}
+
+	// Daemons without port range support would silently ignore the range
+	if o.portRange != nil {
+		nodeSet := make(map[string]bool)
+		for _, pair := range topo.Pairs {
+			nodeSet[pair.Destination.ID] = true
+		}
+		required[models.FeaturePortRange] = nodeSet
+	}
+
+	for feature, nodeSet := range required {
+		nodeIDs := make([]string, 0, len(nodeSet))
+		for nodeID := range nodeSet {
+			nodeIDs = append(nodeIDs, nodeID)
+		}
+		if err := o.clientPool.RequireFeature(feature, nodeIDs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// pairDuration returns the duration the pair's clients run for
+func pairDuration(pair *topology.TestPair) time.Duration {
+	if pair.Duration > 0 {
+		return pair.Duration
+	}
+	return pair.Profile.Duration
+}
+
+// Prepare validates capacity for topo on all nodes and reserves the run's
+// port range, if one is set. Each daemon allocates the server ports of the
+// pairs it receives from its own range, and topo's ports are replaced with
+// them; older daemons, and plan files that set ports, keep topo's. Nodes
+// whose daemons cannot provide the server ports or process slots topo
+// needs are reported together in a single error. Slots are checked here
+// too, as older daemons count bidirectional tests once.
+func (o *Orchestrator) Prepare(ctx context.Context, topo *topology.Topology) error {
+	o.setState(StatePreparing)
 	log.Println("Phase 2: Preparing test topology...")
 
+	if err := o.validateCapacity(topo); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
+
 	// Generate per-node topologies
-	nodeTopologies, err := topology.GenerateNodeTopologies(o.topology)
+	nodeTopologies, err := topology.GenerateNodeTopologies(topo)
 	if err != nil {
-		o.state = StateFailed
+		o.setState(StateFailed)
 		return fmt.Errorf("failed to generate node topologies: %w", err)
 	}
 
 	// Send prepare request to each node
 	clients := o.clientPool.GetAllClients()
-	errors := make([]error, 0)
+	failures := nodeerr.New("preparation")
+	portCapacity := make(map[string]topology.PortCapacity)
+	allocatedPorts := make(map[string][]int32)
+	slotCapacity := make(map[string]int)
+	progress := o.newPhaseLog("Prepared", "pairs", len(nodeTopologies))
 
 	for _, c := range clients {
 		nodeTopology, exists := nodeTopologies[c.Node.ID]
@@ -144,45 +488,105 @@ func (o *Orchestrator) preparePhase(ctx context.Context) error {
 		}
 
 		req := &pb.PrepareTestRequest{
-			Topology: nodeTopology,
+			Topology:  nodeTopology,
+			PortRange: o.portRange,
+			Owner:     o.portOwner,
+
+			CongestionControlPolicy: o.ccPolicy,
+			AllocatePorts:           !topo.PinnedPorts,
 		}
 
 		resp, err := c.Client.PrepareTest(ctx, req)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", c.Node.ID, err))
+			failures.Add(c.Node.ID, err)
+			progress.Fail(c.Node.ID, err)
 			continue
 		}
 
+		// Older daemons do not report their port range
+		if resp.TotalPorts > 0 {
+			portCapacity[c.Node.ID] = topology.PortCapacity{
+				Start:     resp.GetPortRange().GetStart(),
+				End:       resp.GetPortRange().GetEnd(),
+				Total:     int(resp.TotalPorts),
+				Available: int(resp.AvailablePorts),
+			}
+		}
+
+		if unavailable := resp.UnavailableCongestionControl; len(unavailable) > 0 && resp.CanHandle {
+			log.Printf("Warning: node %s does not have congestion control %s; its tests will use the default algorithm",
+				c.Node.ID, strings.Join(unavailable, ", "))
+		}
+
+		// Older daemons do not account for their capacity
+		if breakdown := resp.GetBreakdown(); breakdown != nil {
+			if !resp.CanHandle {
+				log.Printf("Node %s capacity: %s", c.Node.ID, describeCapacity(breakdown))
+			} else if len(breakdown.Divergences) > 0 {
+				log.Printf("Warning: node %s capacity accounting diverged: %s",
+					c.Node.ID, strings.Join(breakdown.Divergences, "; "))
+			}
+		}
+
 		if !resp.CanHandle {
-			errors = append(errors, fmt.Errorf("node %s: %s", c.Node.ID, resp.Message))
+			failures.Addf(c.Node.ID, "%s", resp.Message)
+			progress.Fail(c.Node.ID, fmt.Errorf("%s", resp.Message))
 		} else {
-			log.Printf("Node %s: ready (%d servers, %d clients)",
-				c.Node.ID,
+			if available := resp.GetAvailableCapacity(); available != nil {
+				slotCapacity[c.Node.ID] = int(available.AvailableProcesses)
+			}
+			if len(resp.AllocatedPorts) > 0 {
+				allocatedPorts[c.Node.ID] = resp.AllocatedPorts
+			}
+			o.progress.IncrementPrepared(1)
+			progress.Node(c.Node.ID, len(nodeTopology.ClientAssignments), "ready (%d servers, %d clients)",
 				len(nodeTopology.ServerAssignments),
 				len(nodeTopology.ClientAssignments))
 		}
 	}
+	progress.Done()
+
+	if err := topo.CheckPortCapacity(portCapacity); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
+	if err := topo.CheckSlotCapacity(slotCapacity); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
 
-	if len(errors) > 0 {
-		o.state = StateFailed
-		return fmt.Errorf("preparation failed on %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		o.setState(StateFailed)
+		return failures
+	}
+
+	if err := topo.AssignServerPorts(allocatedPorts); err != nil {
+		o.setState(StateFailed)
+		return err
 	}
 
 	log.Println("All nodes prepared successfully")
 	return nil
 }
 
-// startServersPhase starts iperf3 servers on all nodes
-func (o *Orchestrator) startServersPhase(ctx context.Context) error {
-	o.state = StateStartingServers
+// StartServers starts iperf3 servers on the given ports of each node. Ports
+// in serverBinds are bound to the given local address, for pairs run over
+// one interface; the others listen on every address.
+func (o *Orchestrator) StartServers(ctx context.Context, serverPorts map[string][]int32, serverBinds map[string]map[int32]string) error {
+	if len(serverPorts) == 0 {
+		return fmt.Errorf("no server ports to start")
+	}
+
+	o.setState(StateStartingServers)
 	log.Println("Phase 3: Starting iperf3 servers...")
 
 	clients := o.clientPool.GetAllClients()
-	errors := make([]error, 0)
+	failures := nodeerr.New("server start")
 	totalServers := 0
+	progress := o.newPhaseLog("Started servers on", "servers", len(serverPorts))
 
 	for _, c := range clients {
-		ports, exists := o.topology.ServerPorts[c.Node.ID]
+		ports, exists := serverPorts[c.Node.ID]
 		if !exists || len(ports) == 0 {
 			continue
 		}
@@ -190,47 +594,91 @@ func (o *Orchestrator) startServersPhase(ctx context.Context) error {
 		req := &pb.StartServersRequest{
 			Ports:          ports,
 			TimeoutSeconds: 30,
+			BindIps:        serverBinds[c.Node.ID],
+			Owner:          o.portOwner,
 		}
 
 		resp, err := c.Client.StartServers(ctx, req)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", c.Node.ID, err))
+			failures.Add(c.Node.ID, err)
+			progress.Fail(c.Node.ID, err)
 			continue
 		}
 
 		if !resp.Success {
-			errors = append(errors, fmt.Errorf("node %s: %s", c.Node.ID, resp.Message))
+			failures.Addf(c.Node.ID, "%s", resp.Message)
+			progress.Fail(c.Node.ID, fmt.Errorf("%s", resp.Message))
 		} else {
 			totalServers += len(resp.StartedPorts)
-			log.Printf("Node %s: started %d servers on ports %v",
-				c.Node.ID, len(resp.StartedPorts), resp.StartedPorts)
+			o.progress.IncrementStartedServers(len(resp.StartedPorts))
+			progress.Node(c.Node.ID, len(resp.StartedPorts), "started %d servers on ports %v",
+				len(resp.StartedPorts), resp.StartedPorts)
 		}
 	}
+	progress.Done()
 
-	if len(errors) > 0 {
-		o.state = StateFailed
-		return fmt.Errorf("server start failed on %d nodes: %v", len(errors), errors)
+	if failures.Len() > 0 {
+		o.setState(StateFailed)
+		return failures
 	}
 
 	log.Printf("Started %d servers across all nodes", totalServers)
 
 	// Give servers time to start
-	time.Sleep(2 * time.Second)
+	time.Sleep(o.serverStartDelay)
 
 	return nil
 }
 
-// startClientsPhase starts iperf3 clients on all nodes
-func (o *Orchestrator) startClientsPhase(ctx context.Context) error {
-	o.state = StateStartingClients
+// StartClients starts the given iperf3 client tests of each node. Every
+// destination must already run servers on the ports its tests connect to.
+func (o *Orchestrator) StartClients(ctx context.Context, clientTests map[string][]*topology.TestPair) error {
+	if len(clientTests) == 0 {
+		return fmt.Errorf("no client tests to start")
+	}
+	if err := o.checkServersRunning(ctx, clientTests); err != nil {
+		return err
+	}
+
+	o.setState(StateStartingClients)
 	log.Println("Phase 4: Starting iperf3 clients...")
+	o.recordTestBaseline(ctx)
+
+	var elapsed time.Duration
+	if err := o.queueClients(ctx, clientTests); err != nil {
+		o.setState(StateFailed)
+		return err
+	}
+	if o.throttle != nil {
+		clientTests, elapsed = o.throttle.next(time.Now())
+	} else {
+		clientTests = o.blackouts.deferStarts(clientTests, time.Now())
+	}
+
+	totalClients, err := o.startClientTests(ctx, clientTests, elapsed)
+	if err != nil {
+		o.setState(StateFailed)
+		return err
+	}
 
+	log.Printf("Started %d client tests across all nodes", totalClients)
+	if o.throttle != nil && o.throttle.queued() > 0 {
+		log.Printf("%d more test pairs start as running tests finish (max_concurrent_tests %d)",
+			o.throttle.queued(), o.throttle.limit)
+	}
+	return nil
+}
+
+// startClientTests starts the given client tests of each node, shortening
+// their start delays by elapsed, and returns the tests started
+func (o *Orchestrator) startClientTests(ctx context.Context, clientTests map[string][]*topology.TestPair, elapsed time.Duration) (int, error) {
 	clients := o.clientPool.GetAllClients()
-	errors := make([]error, 0)
+	failures := nodeerr.New("client start")
 	totalClients := 0
+	progress := o.newPhaseLog("Started clients on", "tests", len(clientTests))
 
 	for _, c := range clients {
-		testPairs, exists := o.topology.ClientTests[c.Node.ID]
+		testPairs, exists := clientTests[c.Node.ID]
 		if !exists || len(testPairs) == 0 {
 			continue
 		}
@@ -238,16 +686,27 @@ func (o *Orchestrator) startClientsPhase(ctx context.Context) error {
 		// Build client targets
 		targets := make([]*pb.ClientTarget, 0, len(testPairs))
 		for _, pair := range testPairs {
-			destPorts := o.topology.ServerPorts[pair.Destination.ID]
-			if len(destPorts) == 0 {
+			if len(pair.Ports) == 0 {
 				continue
 			}
 
+			durationSeconds, durationMs := topology.ProtoDuration(pair.Duration)
+			var ipVersion int32
+			if pair.Family != "" {
+				ipVersion = int32(pair.Family.IPVersion()) // #nosec G115 -- IP version is 4 or 6
+			}
+			bindIP, _ := pair.BindAddress()
 			targets = append(targets, &pb.ClientTarget{
-				TestId:          pair.TestID,
-				DestinationIp:   pair.Destination.IP,
-				DestinationPort: destPorts[0],
-				Profile:         topology.ConvertProfileToProto(pair.Profile),
+				TestId:           pair.TestID,
+				DestinationIp:    pair.DestinationAddress(),
+				DestinationPort:  pair.Ports[0],
+				DestinationPorts: pair.Ports,
+				Profile:          topology.ConvertProfileToProto(pair.Profile),
+				DurationSeconds:  durationSeconds,
+				DurationMs:       durationMs,
+				StartDelayMs:     int32(startDelay(pair, elapsed).Milliseconds()), // #nosec G115 -- Delay is validated
+				IpVersion:        ipVersion,
+				BindIp:           bindIP,
 			})
 		}
 
@@ -257,61 +716,263 @@ func (o *Orchestrator) startClientsPhase(ctx context.Context) error {
 
 		req := &pb.StartClientsRequest{
 			Targets: targets,
+			Owner:   o.portOwner,
 		}
 
 		resp, err := c.Client.StartClients(ctx, req)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("node %s: %w", c.Node.ID, err))
+			failures.Add(c.Node.ID, err)
+			progress.Fail(c.Node.ID, err)
 			continue
 		}
 
 		if !resp.Success {
-			errors = append(errors, fmt.Errorf("node %s: %s", c.Node.ID, resp.Message))
+			failures.Addf(c.Node.ID, "%s", resp.Message)
+			progress.Fail(c.Node.ID, fmt.Errorf("%s", resp.Message))
 		} else {
 			totalClients += len(resp.StartedTestIds)
-			log.Printf("Node %s: started %d client tests", c.Node.ID, len(resp.StartedTestIds))
+			o.progress.IncrementStartedClients(len(resp.StartedTestIds))
+			o.progress.AddExpectedTests(c.Node.ID, measuredTests(testPairs))
+			progress.Node(c.Node.ID, len(resp.StartedTestIds), "started %d client tests", len(resp.StartedTestIds))
 		}
 	}
+	progress.Done()
+
+	if failures.Len() > 0 {
+		return totalClients, failures
+	}
+	return totalClients, nil
+}
 
-	if len(errors) > 0 {
-		o.state = StateFailed
-		return fmt.Errorf("client start failed on %d nodes: %v", len(errors), errors)
+// checkServersRunning verifies that each destination of clientTests runs
+// servers on the ports its tests connect to, so that starting clients before
+// servers fails clearly instead of as connection errors. Destinations whose
+// daemons do not report server ports are not checked.
+func (o *Orchestrator) checkServersRunning(ctx context.Context, clientTests map[string][]*topology.TestPair) error {
+	wanted := make(map[string][]int32)
+	for _, pairs := range clientTests {
+		for _, pair := range pairs {
+			wanted[pair.Destination.ID] = append(wanted[pair.Destination.ID], pair.Ports...)
+		}
 	}
 
-	log.Printf("Started %d client tests across all nodes", totalClients)
+	missing := make([]string, 0)
+	for nodeID, ports := range wanted {
+		caps, exists := o.clientPool.GetCapabilities(nodeID)
+		if !exists || !caps.Supports(models.FeatureServerStatus) {
+			continue
+		}
+		c, err := o.clientPool.GetClient(nodeID)
+		if err != nil {
+			return err
+		}
+		resp, err := c.Client.GetStatus(ctx, &pb.GetStatusRequest{})
+		if err != nil {
+			return fmt.Errorf("node %s: %w", nodeID, err)
+		}
+
+		running := make(map[int32]bool, len(resp.Status.GetServerPorts()))
+		for _, port := range resp.Status.GetServerPorts() {
+			running[port] = true
+		}
+		notRunning := make([]int32, 0)
+		for _, port := range ports {
+			if !running[port] {
+				notRunning = append(notRunning, port)
+			}
+		}
+		if len(notRunning) > 0 {
+			missing = append(missing, fmt.Sprintf("%s %v", nodeID, notRunning))
+		}
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no iperf3 servers running on %s; start servers before clients", strings.Join(missing, ", "))
+	}
 	return nil
 }
 
-// waitPhase waits for all tests to complete
-func (o *Orchestrator) waitPhase(ctx context.Context) error {
-	o.state = StateRunning
+// Wait waits until the given client tests are expected to have completed.
+// With a stall policy, and daemons that list their processes, it returns once
+// the tests have finished or stalled instead.
+func (o *Orchestrator) Wait(ctx context.Context, clientTests map[string][]*topology.TestPair) error {
+	o.setState(StateRunning)
 	log.Println("Phase 5: Waiting for tests to complete...")
 
-	// Calculate wait time based on longest test duration
-	maxDuration := 10 // Default 10 seconds
-	for _, pair := range o.topology.Pairs {
-		if pair.Profile.Duration > maxDuration {
-			maxDuration = pair.Profile.Duration
+	if err := o.waitForQueue(ctx); err != nil {
+		return err
+	}
+
+	maxSchedule := o.clientSchedule(clientTests)
+
+	// Daemons that list their processes let the wait end when the tests do
+	if o.stallWindow > 0 {
+		if waited := o.waitedTests(clientTests); waited != nil {
+			return o.waitAdaptive(ctx, waited, maxSchedule)
 		}
 	}
 
 	// Add buffer for test setup and teardown
-	waitTime := time.Duration(maxDuration+10) * time.Second
+	waitTime := maxSchedule + waitGrace(maxSchedule)
 
 	log.Printf("Waiting %v for tests to complete...", waitTime)
-	time.Sleep(waitTime)
 
-	log.Println("Test execution window complete")
-	return nil
+	// Poll daemon status while waiting so progress reflects finished tests
+	deadline := time.NewTimer(waitTime)
+	defer deadline.Stop()
+	ticker := o.clientPool.PollTicker(pollInterval(waitTime))
+	defer ticker.Stop()
+
+	lastLog := time.Now()
+	for {
+		select {
+		case <-deadline.C:
+			log.Println("Test execution window complete")
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			o.updateTestProgress(ctx)
+			o.logTestProgress(&lastLog)
+		}
+	}
+}
+
+// clientSchedule returns how long from now the given client tests are
+// expected to finish: the schedule of the node whose clients finish last,
+// counting each client's start delay plus its duration
+func (o *Orchestrator) clientSchedule(clientTests map[string][]*topology.TestPair) time.Duration {
+	// Queued tests started at different times
+	if o.throttle != nil {
+		return o.throttle.remaining(time.Now())
+	}
+
+	var maxSchedule time.Duration
+	for nodeID, pairs := range clientTests {
+		var nodeSchedule time.Duration
+		for _, pair := range pairs {
+			// Background load is stopped in cleanup rather than waited for
+			if pair.Background {
+				continue
+			}
+			if schedule := pair.Schedule(); schedule > nodeSchedule {
+				nodeSchedule = schedule
+			}
+		}
+		if nodeSchedule > maxSchedule {
+			maxSchedule = nodeSchedule
+			if o.verbose {
+				log.Printf("Node %s: clients finish %v after start", nodeID, nodeSchedule)
+			}
+		}
+	}
+	return maxSchedule
+}
+
+// waitGrace returns the time allowed beyond a schedule for client setup,
+// teardown and storing results. It scales with the schedule so sub-second
+// tests are not swamped by it, within minWaitGrace and maxWaitGrace.
+func waitGrace(schedule time.Duration) time.Duration {
+	return clampDuration(schedule/2, minWaitGrace, maxWaitGrace)
+}
+
+// pollInterval returns how often to poll daemon status during a wait
+func pollInterval(wait time.Duration) time.Duration {
+	return clampDuration(wait/10, minPollInterval, maxPollInterval)
+}
+
+// clampDuration limits d to the range [lower, upper]
+func clampDuration(d, lower, upper time.Duration) time.Duration {
+	if d < lower {
+		return lower
+	}
+	if d > upper {
+		return upper
+	}
+	return d
 }
 
-// collectPhase verifies results are ready on all nodes and optionally saves raw results
-func (o *Orchestrator) collectPhase(ctx context.Context) error {
-	o.state = StateCollecting
+// updateTestProgress refreshes completed and failed test counts from daemon status
+func (o *Orchestrator) updateTestProgress(ctx context.Context) {
+	statuses, _ := o.clientPool.CheckHealth(ctx)
+	o.setTestCounts(statuses)
+}
+
+// setTestCounts sets the completed and failed test counts of this run, in
+// total and per node, from daemon statuses, returning the total finished
+func (o *Orchestrator) setTestCounts(statuses map[string]*pb.DaemonStatus) int {
+	completed, failed := 0, 0
+	for nodeID, status := range statuses {
+		if status == nil {
+			continue
+		}
+		nodeCompleted, nodeFailed := o.nodeTestCounts(nodeID, status)
+		o.progress.SetNodeTestCounts(nodeID, nodeCompleted, nodeFailed)
+		completed += nodeCompleted
+		failed += nodeFailed
+	}
+
+	o.progress.SetTestCounts(completed, failed)
+	if o.throttle != nil {
+		o.throttle.setFinished(completed + failed)
+	}
+	return completed + failed
+}
+
+// nodeTestCounts returns the tests of this run a daemon's status reports as
+// completed and failed
+func (o *Orchestrator) nodeTestCounts(nodeID string, status *pb.DaemonStatus) (int, int) {
+	if status.RunId != "" && status.RunId == o.runID {
+		return int(status.RunCompletedTests), int(status.RunFailedTests)
+	}
+
+	// Counters drop below the baseline if results are cleared meanwhile
+	baseline := o.testBaseline[nodeID]
+	return max(int(status.CompletedTests)-baseline.Completed, 0),
+		max(int(status.FailedTests)-baseline.Failed, 0)
+}
+
+// recordTestBaseline records the daemons' test counters before the run's
+// first clients start. Daemons count every result they store, so results
+// kept from earlier runs would otherwise count as this run's progress.
+func (o *Orchestrator) recordTestBaseline(ctx context.Context) {
+	if o.testBaseline != nil {
+		return
+	}
+	statuses, _ := o.clientPool.CheckHealth(ctx)
+	o.testBaseline = make(map[string]NodeTests, len(statuses))
+	for nodeID, status := range statuses {
+		if status == nil {
+			continue
+		}
+		o.testBaseline[nodeID] = NodeTests{
+			Completed: int(status.CompletedTests),
+			Failed:    int(status.FailedTests),
+		}
+	}
+}
+
+// measuredTests counts the client processes of pairs that are not
+// background load
+func measuredTests(pairs []*topology.TestPair) int {
+	count := 0
+	for _, pair := range pairs {
+		if !pair.Background {
+			count += len(pair.ExpandedTestIDs())
+		}
+	}
+	return count
+}
+
+// Collect verifies results are ready on all nodes and optionally saves raw results
+func (o *Orchestrator) Collect(ctx context.Context) error {
+	o.setState(StateCollecting)
 	log.Println("Phase 6: Collecting results...")
 
 	clients := o.clientPool.GetAllClients()
 	totalResults := 0
+	progress := o.newPhaseLog("Collected results from", "results", len(clients))
 
 	// Create result directory if saving raw results
 	if o.saveRawResults && o.rawResultsDir != "" {
@@ -332,7 +993,8 @@ func (o *Orchestrator) collectPhase(ctx context.Context) error {
 		}
 
 		totalResults += int(resp.TotalCount)
-		log.Printf("Node %s: collected %d results", c.Node.ID, resp.TotalCount)
+		o.progress.IncrementCollected(int(resp.TotalCount))
+		progress.Node(c.Node.ID, int(resp.TotalCount), "collected %d results", resp.TotalCount)
 
 		// Save raw results to individual file if enabled
 		if o.saveRawResults {
@@ -342,15 +1004,19 @@ func (o *Orchestrator) collectPhase(ctx context.Context) error {
 		}
 	}
 
+	progress.Done()
 	log.Printf("Collected %d total results", totalResults)
 	return nil
 }
 
-// cleanupPhase stops all processes and cleans up
-func (o *Orchestrator) cleanupPhase(ctx context.Context) error {
+// Cleanup stops the run's processes on all nodes and releases its port
+// reservations
+func (o *Orchestrator) Cleanup(ctx context.Context) error {
 	log.Println("Phase 7: Cleanup...")
 
-	if err := o.clientPool.StopAll(ctx); err != nil {
+	err := o.clientPool.StopAll(ctx, o.stopOwner())
+	o.verifyCleanup(ctx)
+	if err != nil {
 		return err
 	}
 
@@ -358,6 +1024,11 @@ func (o *Orchestrator) cleanupPhase(ctx context.Context) error {
 	return nil
 }
 
+// NodeFacts returns the environment facts gathered during initialization
+func (o *Orchestrator) NodeFacts() map[string]*models.NodeFacts {
+	return o.nodeFacts
+}
+
 // GetState returns the current orchestrator state
 func (o *Orchestrator) GetState() TestState {
 	return o.state
@@ -368,6 +1039,22 @@ func (o *Orchestrator) GetErrors() []error {
 	return o.errors
 }
 
+// createUnique creates a new file at path or, if it exists, at path with
+// "-2", "-3", ... inserted before the extension. Timestamped names have
+// one-second resolution, so runs started within the same second would
+// otherwise replace each other's files.
+func createUnique(path string) (*os.File, string, error) {
+	ext := filepath.Ext(path)
+	candidate := path
+	for n := 2; ; n++ {
+		file, err := os.OpenFile(candidate, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644) // #nosec G302 G304 -- Filename is generated internally
+		if !errors.Is(err, fs.ErrExist) {
+			return file, candidate, err
+		}
+		candidate = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
+	}
+}
+
 // saveNodeRawResults saves raw results from a single node to a file
 func (o *Orchestrator) saveNodeRawResults(nodeID string, resp *pb.GetResultsResponse) error {
 	// Generate filename with node ID and timestamp
@@ -379,8 +1066,7 @@ func (o *Orchestrator) saveNodeRawResults(nodeID string, resp *pb.GetResultsResp
 		filename = fmt.Sprintf("%s/%s", o.rawResultsDir, filename)
 	}
 
-	// Create file
-	file, err := os.Create(filename) // #nosec G304 -- Filename is generated internally
+	file, filename, err := createUnique(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create raw results file: %w", err)
 	}
@@ -0,0 +1,140 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DefaultProgressLogInterval is how often aggregated phase progress is
+// logged unless configured otherwise
+const DefaultProgressLogInterval = 5 * time.Second
+
+// SetVerbose makes phases log a line for every node instead of periodic
+// aggregated progress
+func (o *Orchestrator) SetVerbose(verbose bool) {
+	o.verbose = verbose
+}
+
+// SetProgressLogInterval sets how often aggregated phase progress is logged
+func (o *Orchestrator) SetProgressLogInterval(interval time.Duration) {
+	o.progressLogInterval = interval
+}
+
+// phaseLog reports the per-node progress of a phase. Node details are only
+// logged in verbose mode; otherwise a line such as "Started clients on
+// 120/300 nodes, 4,812 tests" is logged at most once per interval and once
+// when the phase is done. Failures are always logged immediately.
+type phaseLog struct {
+	progress *Progress // Receives a node event for each node
+	verbose  bool
+	interval time.Duration
+	action   string // e.g. "Started clients on"
+	unit     string // Plural of what each node reports, e.g. "tests"
+	total    int    // Nodes taking part in the phase
+	nodes    int
+	items    int
+	lastLog  time.Time
+}
+
+// newPhaseLog starts reporting a phase over total nodes
+func (o *Orchestrator) newPhaseLog(action, unit string, total int) *phaseLog {
+	return &phaseLog{
+		progress: o.progress,
+		verbose:  o.verbose,
+		interval: o.progressLogInterval,
+		action:   action,
+		unit:     unit,
+		total:    total,
+		lastLog:  time.Now(),
+	}
+}
+
+// Node records that a node finished with items, logging the node's details
+// in verbose mode and aggregated progress otherwise
+func (l *phaseLog) Node(nodeID string, items int, format string, args ...interface{}) {
+	l.nodes++
+	l.items += items
+
+	detail := fmt.Sprintf(format, args...)
+	l.progress.Emit(EventNode, nodeID, items, detail)
+	if l.verbose {
+		log.Printf("Node %s: %s", nodeID, detail)
+		return
+	}
+	if time.Since(l.lastLog) >= l.interval {
+		l.logProgress()
+	}
+}
+
+// Fail logs a node's failure immediately
+func (l *phaseLog) Fail(nodeID string, err error) {
+	log.Printf("Error: node %s: %v", nodeID, err)
+	l.progress.Emit(EventNodeError, nodeID, 0, err.Error())
+}
+
+// Done logs the final aggregated progress, unless every node was logged
+func (l *phaseLog) Done() {
+	if !l.verbose {
+		l.logProgress()
+	}
+}
+
+// logProgress logs the aggregated progress so far
+func (l *phaseLog) logProgress() {
+	l.lastLog = time.Now()
+	log.Printf("%s %d/%d nodes, %s %s", l.action, l.nodes, l.total, formatCount(l.items), l.unit)
+}
+
+// logTestProgress logs how many of the run's measured client tests have
+// finished, and in verbose mode each node's counts such as "Node node12:
+// 14/19 done", at most once per progress interval since lastLog
+func (o *Orchestrator) logTestProgress(lastLog *time.Time) {
+	if time.Since(*lastLog) < o.progressLogInterval {
+		return
+	}
+	*lastLog = time.Now()
+	o.progress.Emit(EventCounters, "", 0, "")
+
+	snap := o.progress.Snapshot()
+	nodeIDs := make([]string, 0, len(snap.Nodes))
+	expected, done, failed := 0, 0, 0
+	for nodeID, node := range snap.Nodes {
+		nodeIDs = append(nodeIDs, nodeID)
+		expected += node.Expected
+		done += node.Done()
+		failed += node.Failed
+	}
+	log.Printf("Tests done: %s/%s, %s failed", formatCount(done), formatCount(expected), formatCount(failed))
+
+	if !o.verbose {
+		return
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		node := snap.Nodes[nodeID]
+		if node.Expected > 0 {
+			log.Printf("Node %s: %d/%d done, %d failed", nodeID, node.Done(), node.Expected, node.Failed)
+		}
+	}
+}
+
+// formatCount formats n with thousands separators, e.g. 4,812
+func formatCount(n int) string {
+	digits := strconv.Itoa(n)
+	start := 0
+	if n < 0 {
+		start = 1
+	}
+
+	formatted := digits[:start]
+	for i := start; i < len(digits); i++ {
+		if i > start && (len(digits)-i)%3 == 0 {
+			formatted += ","
+		}
+		formatted += string(digits[i])
+	}
+	return formatted
+}
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatCount(t *testing.T) {
+	tests := map[int]string{
+		0:       "0",
+		999:     "999",
+		4812:    "4,812",
+		40000:   "40,000",
+		1234567: "1,234,567",
+		-4812:   "-4,812",
+	}
+
+	for n, want := range tests {
+		if got := formatCount(n); got != want {
+			t.Errorf("formatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestPhaseLog_Aggregates(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	orch := &Orchestrator{progressLogInterval: time.Hour}
+	progress := orch.newPhaseLog("Started clients on", "tests", 300)
+	for i := 0; i < 120; i++ {
+		progress.Node(fmt.Sprintf("node%d", i), 40, "started %d client tests", 40)
+	}
+	progress.Fail("node120", fmt.Errorf("connection refused"))
+	progress.Done()
+
+	output := buf.String()
+	if strings.Contains(output, "Node node0") {
+		t.Errorf("per-node line logged without verbose:\n%s", output)
+	}
+	if !strings.Contains(output, "Error: node node120: connection refused") {
+		t.Errorf("failure not logged:\n%s", output)
+	}
+	if !strings.Contains(output, "Started clients on 120/300 nodes, 4,800 tests") {
+		t.Errorf("aggregated progress not logged:\n%s", output)
+	}
+
+	buf.Reset()
+	orch.SetVerbose(true)
+	progress = orch.newPhaseLog("Started clients on", "tests", 1)
+	progress.Node("node0", 40, "started %d client tests", 40)
+	progress.Done()
+	if got := buf.String(); !strings.Contains(got, "Node node0: started 40 client tests") {
+		t.Errorf("verbose node line not logged:\n%s", got)
+	}
+}
@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestStartClients_BeforeServers(t *testing.T) {
+	orch, _, topo := newFailingOrchestrator(t, "")
+	ctx := context.Background()
+
+	if err := orch.Initialize(ctx, topo); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	err := orch.StartClients(ctx, topo.ClientTests)
+	if err == nil || !strings.Contains(err.Error(), "start servers before clients") {
+		t.Fatalf("StartClients() before StartServers() error = %v, want servers not running", err)
+	}
+
+	if err := orch.StartServers(ctx, topo.ServerPorts, topo.ServerBinds()); err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if err := orch.StartClients(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("StartClients() after StartServers() error = %v", err)
+	}
+
+	if err := orch.Cleanup(ctx); err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if err := orch.StartClients(ctx, topo.ClientTests); err == nil {
+		t.Error("StartClients() after Cleanup() succeeded, want error")
+	}
+}
+
+func TestPrepare_PortExhaustion(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	for _, daemon := range daemons {
+		daemon.totalPorts = 1
+	}
+	// Each node receives one pair; give node2 a second server port
+	for _, pair := range topo.Pairs {
+		if pair.Destination.ID == "node2" {
+			topo.ServerPorts["node2"] = append(topo.ServerPorts["node2"], pair.Ports...)
+		}
+	}
+
+	err := orch.Prepare(context.Background(), topo)
+	if err == nil {
+		t.Fatal("Prepare() expected error")
+	}
+	want := "node2 needs 2 server ports, daemon range 5201-5201 provides 1 (1 short)"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("Prepare() error = %v, want it to contain %q", err, want)
+	}
+	if strings.Contains(err.Error(), "node1 needs") {
+		t.Errorf("Prepare() error = %v, want node1 not reported", err)
+	}
+}
+
+func TestWait_NodeProgress(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	ctx := context.Background()
+	// Results another run left on node1 must not count as progress
+	daemons[0].completed = 5
+	orch.SetStallPolicy(time.Second, false)
+
+	if err := orch.Initialize(ctx, topo); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if err := orch.StartServers(ctx, topo.ServerPorts, topo.ServerBinds()); err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if err := orch.StartClients(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("StartClients() error = %v", err)
+	}
+	for _, pair := range topo.Pairs {
+		pair.Duration = time.Millisecond
+	}
+	if err := orch.Wait(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	snap := orch.Progress().Snapshot()
+	if snap.CompletedTests != 2 {
+		t.Errorf("CompletedTests = %d, want 2", snap.CompletedTests)
+	}
+	for _, nodeID := range []string{"node1", "node2"} {
+		if node := snap.Nodes[nodeID]; node.Expected != 1 || node.Done() != 1 {
+			t.Errorf("%s progress = %d/%d done, want 1/1", nodeID, node.Done(), node.Expected)
+		}
+	}
+}
+
+func TestWait_RunScopedProgress(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	ctx := context.Background()
+	for _, daemon := range daemons {
+		daemon.runScope = true
+	}
+	daemons[0].completed = 5
+	orch.SetStallPolicy(time.Second, false)
+
+	if err := orch.Initialize(ctx, topo); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if daemons[0].runID != orch.RunID() {
+		t.Fatalf("daemon run ID = %q, want %q", daemons[0].runID, orch.RunID())
+	}
+	if err := orch.StartServers(ctx, topo.ServerPorts, topo.ServerBinds()); err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if err := orch.StartClients(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("StartClients() error = %v", err)
+	}
+	// Another controller clears node1's earlier results, which drops its
+	// lifetime counters below the baseline recorded when clients started
+	daemons[0].mu.Lock()
+	daemons[0].completed = 0
+	daemons[0].mu.Unlock()
+	for _, pair := range topo.Pairs {
+		pair.Duration = time.Millisecond
+	}
+	if err := orch.Wait(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	snap := orch.Progress().Snapshot()
+	if snap.CompletedTests != 2 {
+		t.Errorf("CompletedTests = %d, want 2", snap.CompletedTests)
+	}
+	if len(orch.StalledTests()) != 0 {
+		t.Errorf("StalledTests() = %v, want none", orch.StalledTests())
+	}
+}
+
+// burstlessDaemon is a fake daemon predating burst support
+type burstlessDaemon struct {
+	*fakeDaemon
+}
+
+func (d burstlessDaemon) GetCapabilities(ctx context.Context, in *pb.GetCapabilitiesRequest, opts ...grpc.CallOption) (*pb.GetCapabilitiesResponse, error) {
+	resp, err := d.fakeDaemon.GetCapabilities(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	features := make([]string, 0, len(resp.Features))
+	for _, feature := range resp.Features {
+		if feature != string(models.FeatureBurst) {
+			features = append(features, feature)
+		}
+	}
+	resp.Features = features
+	return resp, nil
+}
+
+func TestInitialize_BurstRequiresFeature(t *testing.T) {
+	orch, _, topo := newFailingOrchestrator(t, "")
+	orch.clientPool.AddClient(topo.Pairs[0].Source, burstlessDaemon{&fakeDaemon{}})
+
+	// Sustained profiles run on any daemon
+	if err := orch.Initialize(context.Background(), topo); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+
+	burst := *topo.Pairs[0].Profile
+	burst.Burst = &models.BurstSchedule{OnMs: 10, OffMs: 90, Repeat: 5}
+	topo.Pairs[0].Profile = &burst
+	err := orch.Initialize(context.Background(), topo)
+	want := `feature "burst" requires daemon version ` + models.FeatureMinVersions[models.FeatureBurst] +
+		" or later; unsupported on nodes: " + topo.Pairs[0].Source.ID
+	if err == nil || err.Error() != want {
+		t.Errorf("Initialize() error = %v, want %q", err, want)
+	}
+}
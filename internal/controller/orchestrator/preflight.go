@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// ErrDaemonsBusy is returned when daemons still run processes or hold
+// uncollected results from another run. The failure policy is not applied,
+// since stopping processes or clearing results would disturb that run.
+var ErrDaemonsBusy = errors.New("daemons are busy with another run")
+
+// SetForce makes the pre-flight check stop processes and clear results left
+// on busy daemons instead of refusing to start
+func (o *Orchestrator) SetForce(force bool) {
+	o.force = force
+}
+
+// Preflight verifies that no daemon is running processes or holding
+// uncollected results, so that a new run does not interleave with another.
+// With force set, busy daemons are stopped and cleared instead.
+func (o *Orchestrator) Preflight(ctx context.Context) error {
+	log.Println("Checking daemons are idle...")
+
+	statuses, err := o.clientPool.CheckHealth(ctx)
+	if err != nil {
+		return err
+	}
+
+	busy := make([]string, 0)
+	busyNodes := make([]string, 0)
+	for nodeID, status := range statuses {
+		if status.GetRunningProcesses() == 0 && status.GetStoredResults() == 0 {
+			continue
+		}
+		busyNodes = append(busyNodes, nodeID)
+		busy = append(busy, fmt.Sprintf("%s (%d running processes, %d uncollected results)",
+			nodeID, status.GetRunningProcesses(), status.GetStoredResults()))
+	}
+	if len(busy) == 0 {
+		return nil
+	}
+	sort.Strings(busy)
+
+	if !o.force {
+		return fmt.Errorf("%w: %s; wait for it to finish or use --force to stop it",
+			ErrDaemonsBusy, strings.Join(busy, ", "))
+	}
+
+	log.Printf("Warning: stopping processes and clearing results on busy daemons: %s", strings.Join(busy, ", "))
+	if err := o.clientPool.ForceStop(ctx, busyNodes, ""); err != nil {
+		return err
+	}
+	for _, nodeID := range busyNodes {
+		c, err := o.clientPool.GetClient(nodeID)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Client.GetResults(ctx, &pb.GetResultsRequest{ClearAfterRetrieval: true}); err != nil {
+			return fmt.Errorf("node %s: failed to clear results: %w", nodeID, err)
+		}
+	}
+
+	return nil
+}
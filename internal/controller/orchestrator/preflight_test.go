@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExecuteTest_BusyDaemons(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	daemons[1].running = 3
+	daemons[1].stored = 2
+
+	err := orch.ExecuteTest(context.Background(), topo)
+	if !errors.Is(err, ErrDaemonsBusy) {
+		t.Fatalf("ExecuteTest() error = %v, want ErrDaemonsBusy", err)
+	}
+	if !strings.Contains(err.Error(), "node2 (3 running processes, 2 uncollected results)") {
+		t.Errorf("ExecuteTest() error = %v, want busy node listed", err)
+	}
+
+	// The other run must be left untouched
+	for i, daemon := range daemons {
+		if daemon.stopAllCalls != 0 {
+			t.Errorf("node%d: StopAll called %d times, want 0", i+1, daemon.stopAllCalls)
+		}
+		if len(daemon.resultCalls) != 0 {
+			t.Errorf("node%d: GetResults called %d times, want 0", i+1, len(daemon.resultCalls))
+		}
+		if len(daemon.started) != 0 {
+			t.Errorf("node%d: started %d clients, want 0", i+1, len(daemon.started))
+		}
+	}
+}
+
+func TestPreflight_Force(t *testing.T) {
+	orch, daemons, _ := newFailingOrchestrator(t, "")
+	orch.SetForce(true)
+	daemons[1].running = 3
+	daemons[1].stored = 2
+
+	if err := orch.Preflight(context.Background()); err != nil {
+		t.Fatalf("Preflight() error = %v", err)
+	}
+
+	busy := daemons[1]
+	if busy.stopAllCalls == 0 {
+		t.Error("StopAll not called on busy daemon")
+	}
+	if len(busy.resultCalls) != 1 || !busy.resultCalls[0].ClearAfterRetrieval {
+		t.Errorf("GetResults calls = %v, want one clearing call", busy.resultCalls)
+	}
+	if busy.running != 0 || busy.stored != 0 {
+		t.Errorf("busy daemon left with %d processes and %d results", busy.running, busy.stored)
+	}
+
+	// The cleared daemons now pass the check without force
+	orch.SetForce(false)
+	if err := orch.Preflight(context.Background()); err != nil {
+		t.Errorf("Preflight() after forced clear error = %v", err)
+	}
+}
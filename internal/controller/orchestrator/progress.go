@@ -32,6 +32,45 @@ type Progress struct {
 
 	// Errors
 	Errors []string
+
+	// Per-node client test counts of this run
+	nodeTests map[string]*NodeTests
+
+	// Receiver of progress events, if any, and the run they belong to
+	sink  EventSink
+	runID string
+}
+
+// NodeTests counts the client tests of one node
+type NodeTests struct {
+	Expected  int // Measured client processes started on the node
+	Completed int
+	Failed    int
+}
+
+// Done returns the number of tests that finished either way
+func (n NodeTests) Done() int {
+	return n.Completed + n.Failed
+}
+
+// ProgressSnapshot is a point-in-time copy of the progress counters
+type ProgressSnapshot struct {
+	TotalNodes       int
+	TotalTests       int
+	TotalServers     int
+	TotalClients     int
+	ConnectedNodes   int
+	PreparedNodes    int
+	StartedServers   int
+	StartedClients   int
+	CompletedTests   int
+	FailedTests      int
+	CollectedResults int
+	StartTime        time.Time
+	CurrentPhase     string
+	PhaseStart       time.Time
+	ErrorCount       int
+	Nodes            map[string]NodeTests // Client test counts by node
 }
 
 // NewProgress creates a new progress tracker
@@ -39,6 +78,7 @@ func NewProgress() *Progress {
 	return &Progress{
 		StartTime: time.Now(),
 		Errors:    make([]string, 0),
+		nodeTests: make(map[string]*NodeTests),
 	}
 }
 
@@ -56,10 +96,11 @@ func (p *Progress) SetTotals(nodes, tests, servers, clients int) {
 // SetPhase sets the current phase
 func (p *Progress) SetPhase(phase string) {
 	p.mu.Lock()
-	defer p.mu.Unlock()
-
 	p.CurrentPhase = phase
 	p.PhaseStart = time.Now()
+	p.mu.Unlock()
+
+	p.Emit(EventPhase, "", 0, "")
 }
 
 // IncrementConnected increments connected nodes count
@@ -104,6 +145,42 @@ func (p *Progress) IncrementFailed(count int) {
 	p.FailedTests += count
 }
 
+// SetTestCounts replaces the completed and failed test counts with values
+// reported by the daemons
+func (p *Progress) SetTestCounts(completed, failed int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.CompletedTests = completed
+	p.FailedTests = failed
+}
+
+// AddExpectedTests adds to the number of measured client tests expected to
+// finish on a node
+func (p *Progress) AddExpectedTests(nodeID string, count int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.node(nodeID).Expected += count
+}
+
+// SetNodeTestCounts sets the completed and failed test counts of a node
+func (p *Progress) SetNodeTestCounts(nodeID string, completed, failed int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	node := p.node(nodeID)
+	node.Completed = completed
+	node.Failed = failed
+}
+
+// node returns the test counts of a node; the caller must hold p.mu
+func (p *Progress) node(nodeID string) *NodeTests {
+	node, exists := p.nodeTests[nodeID]
+	if !exists {
+		node = &NodeTests{}
+		p.nodeTests[nodeID] = node
+	}
+	return node
+}
+
 // IncrementCollected increments collected results count
 func (p *Progress) IncrementCollected(count int) {
 	p.mu.Lock()
@@ -118,6 +195,36 @@ func (p *Progress) AddError(err string) {
 	p.Errors = append(p.Errors, err)
 }
 
+// Snapshot returns a consistent copy of the current counters
+func (p *Progress) Snapshot() ProgressSnapshot {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	nodes := make(map[string]NodeTests, len(p.nodeTests))
+	for nodeID, node := range p.nodeTests {
+		nodes[nodeID] = *node
+	}
+
+	return ProgressSnapshot{
+		TotalNodes:       p.TotalNodes,
+		TotalTests:       p.TotalTests,
+		TotalServers:     p.TotalServers,
+		TotalClients:     p.TotalClients,
+		ConnectedNodes:   p.ConnectedNodes,
+		PreparedNodes:    p.PreparedNodes,
+		StartedServers:   p.StartedServers,
+		StartedClients:   p.StartedClients,
+		CompletedTests:   p.CompletedTests,
+		FailedTests:      p.FailedTests,
+		CollectedResults: p.CollectedResults,
+		StartTime:        p.StartTime,
+		CurrentPhase:     p.CurrentPhase,
+		PhaseStart:       p.PhaseStart,
+		ErrorCount:       len(p.Errors),
+		Nodes:            nodes,
+	}
+}
+
 // GetSummary returns a summary string
 func (p *Progress) GetSummary() string {
 	p.mu.RLock()
@@ -202,6 +309,16 @@ func (p *Progress) GetPhasePercent() float64 {
 			return 0
 		}
 		return float64(p.StartedClients) / float64(p.TotalClients) * 100
+	case "running":
+		expected, done := 0, 0
+		for _, node := range p.nodeTests {
+			expected += node.Expected
+			done += min(node.Done(), node.Expected)
+		}
+		if expected == 0 {
+			return 0
+		}
+		return float64(done) / float64(expected) * 100
 	case "collecting":
 		if p.TotalTests == 0 {
 			return 0
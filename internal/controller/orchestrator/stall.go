@@ -0,0 +1,180 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// StalledTest is a client test that was still running when the wait phase
+// stopped waiting for it
+type StalledTest struct {
+	TestID         string  `json:"test_id"`
+	Node           string  `json:"node"`
+	RunningSeconds float64 `json:"running_seconds"`
+	Stopped        bool    `json:"stopped"` // Stopped rather than left running until cleanup
+}
+
+// SetStallPolicy makes Wait give up on tests once they are past their
+// expected end and none has finished for window, stopping them if stop is
+// set. A zero window waits a fixed time instead.
+func (o *Orchestrator) SetStallPolicy(window time.Duration, stop bool) {
+	o.stallWindow = window
+	o.stopStalled = stop
+}
+
+// StalledTests returns the tests Wait gave up on, or nil
+func (o *Orchestrator) StalledTests() []*StalledTest {
+	return o.stalledTests
+}
+
+// waitedTests returns, per source node, the IDs of the processes Wait waits
+// for, or nil if a node cannot list its processes
+func (o *Orchestrator) waitedTests(clientTests map[string][]*topology.TestPair) map[string]map[string]bool {
+	waited := make(map[string]map[string]bool, len(clientTests))
+	for nodeID, pairs := range clientTests {
+		caps, exists := o.clientPool.GetCapabilities(nodeID)
+		if !exists || !caps.Supports(models.FeatureStopTests) {
+			return nil
+		}
+		ids := make(map[string]bool)
+		for _, pair := range pairs {
+			if pair.Background {
+				continue
+			}
+			for _, id := range pair.ExpandedTestIDs() {
+				ids[id] = true
+			}
+		}
+		waited[nodeID] = ids
+	}
+	return waited
+}
+
+// waitAdaptive polls the daemons' processes until the waited tests have
+// finished, or until they are past schedule and none has finished for the
+// stall window, in which case the rest are recorded as stalled
+func (o *Orchestrator) waitAdaptive(ctx context.Context, waited map[string]map[string]bool, schedule time.Duration) error {
+	start := time.Now()
+	expectedEnd := start.Add(schedule)
+	log.Printf("Waiting for tests to complete, expected in %v...", schedule)
+
+	ticker := o.clientPool.PollTicker(pollInterval(schedule + o.stallWindow))
+	defer ticker.Stop()
+
+	lastProgress, lastLog := start, start
+	finished, remaining := 0, -1
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		statuses, err := o.clientPool.ProcessStatus(ctx)
+		if err != nil && o.verbose {
+			log.Printf("Warning: %v", err)
+		}
+		done := o.setTestCounts(statuses)
+		o.logTestProgress(&lastLog)
+		running := runningTests(statuses, waited)
+		count := 0
+		for _, processes := range running {
+			count += len(processes)
+		}
+		// Unreachable nodes may still be running tests
+		if count == 0 && err == nil {
+			log.Println("All tests complete")
+			return nil
+		}
+
+		now := time.Now()
+		if done > finished || (remaining >= 0 && count < remaining) {
+			lastProgress = now
+		}
+		finished, remaining = done, count
+		if now.Before(expectedEnd) {
+			continue
+		}
+		idle := now.Sub(lastProgress)
+		if lastProgress.Before(expectedEnd) {
+			idle = now.Sub(expectedEnd)
+		}
+		if idle < o.stallWindow {
+			continue
+		}
+
+		log.Printf("Warning: no test finished for %v past the expected end; giving up on %d still running",
+			idle.Round(time.Second), count)
+		o.recordStalled(ctx, running, now)
+		return nil
+	}
+}
+
+// runningTests returns the waited-for client processes still running, by
+// node. Processes already stopped but not yet exited are left out.
+func runningTests(statuses map[string]*pb.DaemonStatus, waited map[string]map[string]bool) map[string][]*pb.ProcessStatus {
+	running := make(map[string][]*pb.ProcessStatus)
+	for nodeID, ids := range waited {
+		for _, process := range statuses[nodeID].GetProcesses() {
+			if !process.Server && !process.Stopping && ids[process.TestId] {
+				running[nodeID] = append(running[nodeID], process)
+			}
+		}
+	}
+	return running
+}
+
+// recordStalled logs the stalled tests with how long they have been running,
+// stops them if configured to, and keeps them for the run report
+func (o *Orchestrator) recordStalled(ctx context.Context, running map[string][]*pb.ProcessStatus, now time.Time) {
+	nodeIDs := make([]string, 0, len(running))
+	for nodeID := range running {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	for _, nodeID := range nodeIDs {
+		stopped := make(map[string]bool)
+		if o.stopStalled {
+			stopped = o.stopTests(ctx, nodeID, running[nodeID])
+		}
+		for _, process := range running[nodeID] {
+			stalled := &StalledTest{
+				TestID:         process.TestId,
+				Node:           nodeID,
+				RunningSeconds: now.Sub(time.UnixMilli(process.StartTimeUnixMs)).Seconds(),
+				Stopped:        stopped[process.TestId],
+			}
+			o.stalledTests = append(o.stalledTests, stalled)
+			log.Printf("Warning: test %s on %s stalled after running %.0fs",
+				stalled.TestID, nodeID, stalled.RunningSeconds)
+		}
+	}
+}
+
+// stopTests stops processes on a node, returning the IDs that were stopped
+func (o *Orchestrator) stopTests(ctx context.Context, nodeID string, processes []*pb.ProcessStatus) map[string]bool {
+	stopped := make(map[string]bool)
+	ids := make([]string, 0, len(processes))
+	for _, process := range processes {
+		ids = append(ids, process.TestId)
+	}
+	stoppedIDs, messages, err := o.clientPool.StopTests(ctx, nodeID, ids)
+	if err != nil {
+		log.Printf("Warning: failed to stop stalled tests on %s: %v", nodeID, err)
+		return stopped
+	}
+	for _, msg := range messages {
+		log.Printf("Warning: %s: %s", nodeID, msg)
+	}
+	for _, id := range stoppedIDs {
+		stopped[id] = true
+	}
+	return stopped
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWait_Stall(t *testing.T) {
+	tests := []struct {
+		name        string
+		stuck       bool
+		stop        bool
+		wantStopped bool
+	}{
+		{"all finish", false, true, false},
+		{"stuck left running", true, false, false},
+		{"stuck stopped", true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch, daemons, topo := newFailingOrchestrator(t, "")
+			for _, pair := range topo.Pairs {
+				pair.Duration = time.Millisecond
+			}
+			ctx := context.Background()
+			if err := orch.Initialize(ctx, topo); err != nil {
+				t.Fatalf("Initialize() error = %v", err)
+			}
+			orch.SetStallPolicy(300*time.Millisecond, tt.stop)
+
+			stuckID := topo.ClientTests["node1"][0].TestID
+			if tt.stuck {
+				daemons[0].stuck = map[string]bool{stuckID: true}
+			}
+
+			start := time.Now()
+			if err := orch.Wait(ctx, topo.ClientTests); err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+			// The fixed wait would take at least the minimum grace
+			if elapsed := time.Since(start); elapsed >= minWaitGrace {
+				t.Errorf("Wait() took %v, want it to end with the tests", elapsed)
+			}
+
+			stalled := orch.StalledTests()
+			if !tt.stuck {
+				if len(stalled) != 0 {
+					t.Errorf("StalledTests() = %v, want none", stalled)
+				}
+				return
+			}
+			if len(stalled) != 1 || stalled[0].TestID != stuckID || stalled[0].Node != "node1" {
+				t.Fatalf("StalledTests() = %+v, want %s on node1", stalled, stuckID)
+			}
+			if stalled[0].Stopped != tt.wantStopped {
+				t.Errorf("Stopped = %v, want %v", stalled[0].Stopped, tt.wantStopped)
+			}
+			if stopped := len(daemons[0].stopped) > 0; stopped != tt.wantStopped {
+				t.Errorf("StopTests stopped %v, want stopped = %v", daemons[0].stopped, tt.wantStopped)
+			}
+		})
+	}
+}
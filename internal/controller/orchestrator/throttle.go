@@ -0,0 +1,292 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// SetMaxConcurrentTests caps the client processes running at once across
+// the fleet, counting bidirectional ones twice. Runs with more client tests
+// start them in waves as earlier ones finish. 0 or negative is unbounded.
+func (o *Orchestrator) SetMaxConcurrentTests(limit int) {
+	o.maxConcurrentTests = max(limit, 0)
+}
+
+// queueClients queues clientTests on the throttle, creating it for a run
+// whose client processes exceed the cap. Runs within the cap are not
+// throttled.
+func (o *Orchestrator) queueClients(ctx context.Context, clientTests map[string][]*topology.TestPair) error {
+	if o.throttle == nil {
+		total := clientTests
+		if o.topology != nil {
+			total = o.topology.ClientTests
+		}
+		if o.maxConcurrentTests == 0 || clientWeight(total) <= o.maxConcurrentTests {
+			return nil
+		}
+		statuses, _ := o.clientPool.CheckHealth(ctx)
+		o.throttle = newThrottle(o.maxConcurrentTests, o.setTestCounts(statuses))
+		o.throttle.blackouts = o.blackouts
+	}
+	return o.throttle.add(clientTests)
+}
+
+// startQueued starts the queued client tests that fit within the cap now
+func (o *Orchestrator) startQueued(ctx context.Context) error {
+	if o.throttle == nil || o.throttle.queued() == 0 {
+		return nil
+	}
+	clientTests, elapsed := o.throttle.next(time.Now())
+	if len(clientTests) == 0 {
+		return nil
+	}
+	started, err := o.startClientTests(ctx, clientTests, elapsed)
+	if err != nil {
+		return fmt.Errorf("failed to start queued clients: %w", err)
+	}
+	if o.verbose || o.throttle.queued() == 0 {
+		log.Printf("Started %d more client tests, %d test pairs queued", started, o.throttle.queued())
+	}
+	return nil
+}
+
+// waitForQueue polls daemon status, starting queued client tests as running
+// ones finish, until none is left queued
+func (o *Orchestrator) waitForQueue(ctx context.Context) error {
+	if o.throttle == nil || o.throttle.queued() == 0 {
+		return nil
+	}
+
+	ticker := o.clientPool.PollTicker(o.queuePollInterval)
+	defer ticker.Stop()
+
+	lastLog := time.Now()
+	for o.throttle.queued() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			o.updateTestProgress(ctx)
+			o.logTestProgress(&lastLog)
+			if err := o.startQueued(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// throttle holds back client tests so that at most limit client processes
+// run at once across the fleet, weighing each pair by its profile's Weight,
+// as bidirectional processes load both nodes both ways. Queued tests start
+// in start delay order as earlier ones finish, which is observed from the
+// daemons' test counters on each status poll. A started test whose expected end and grace have passed
+// stops counting even if its result never shows, so that a lost result
+// can't hold the queue forever. Pairs held back by blackout windows of
+// their nodes stay queued while later ones start.
+type throttle struct {
+	limit        int
+	started      time.Time // When the first tests started; zero until then
+	queue        []*topology.TestPair
+	running      []*throttledPair
+	dispatched   int                  // Client processes started
+	finishedBase int                  // Tests of the run finished before the throttle existed
+	finished     int                  // Tests of the run finished since, as last polled
+	ends         map[string]time.Time // Test ID -> expected end of started tests
+	blackouts    *blackouts           // Nil ignores blackout windows
+}
+
+// throttledPair is a started pair holding its share of the limit
+type throttledPair struct {
+	processes int
+	cost      int
+	expires   time.Time // After this the pair no longer counts; zero for background pairs
+}
+
+// newThrottle returns a throttle allowing limit client processes at once,
+// given the tests of the run already finished
+func newThrottle(limit, finished int) *throttle {
+	return &throttle{
+		limit:        limit,
+		finishedBase: finished,
+		ends:         make(map[string]time.Time),
+	}
+}
+
+// clientWeight returns the weight of the client processes of clientTests
+func clientWeight(clientTests map[string][]*topology.TestPair) int {
+	weight := 0
+	for _, pairs := range clientTests {
+		for _, pair := range pairs {
+			weight += pair.Profile.Weight()
+		}
+	}
+	return weight
+}
+
+// add queues clientTests. Background pairs go first, as they hold their
+// share of the limit for the whole run; it fails if they would hold all of
+// it, as nothing else could ever start.
+func (t *throttle) add(clientTests map[string][]*topology.TestPair) error {
+	background := 0
+	for _, running := range t.running {
+		if running.expires.IsZero() {
+			background += running.cost
+		}
+	}
+	nodeIDs := make([]string, 0, len(clientTests))
+	for nodeID := range clientTests {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+	for _, nodeID := range nodeIDs {
+		for _, pair := range clientTests[nodeID] {
+			if pair.Background {
+				background += t.cost(pair)
+			}
+			t.queue = append(t.queue, pair)
+		}
+	}
+	if background >= t.limit {
+		return fmt.Errorf("background tests need %d client processes, leaving none of max_concurrent_tests (%d) for measured tests",
+			background, t.limit)
+	}
+
+	sort.SliceStable(t.queue, func(i, j int) bool {
+		if t.queue[i].Background != t.queue[j].Background {
+			return t.queue[i].Background
+		}
+		return t.queue[i].StartDelayMs < t.queue[j].StartDelayMs
+	})
+	return nil
+}
+
+// cost returns the share of the limit a pair holds while it runs. A pair
+// weighing more than the limit takes all of it.
+func (t *throttle) cost(pair *topology.TestPair) int {
+	return min(pair.Profile.Weight(), t.limit)
+}
+
+// setFinished records the total tests of the run finished so far
+func (t *throttle) setFinished(total int) {
+	t.finished = max(total-t.finishedBase, 0)
+}
+
+// inFlight returns the share of the limit held by the pairs started and not
+// yet finished, leaving out pairs past their expiry. The daemons only count
+// finished processes, which are taken to be those of the pairs started
+// first; background pairs never finish.
+func (t *throttle) inFlight(now time.Time) int {
+	unfinished := max(t.dispatched-t.finished, 0)
+	held := 0
+	hold := func(running *throttledPair) {
+		processes := min(running.processes, unfinished)
+		unfinished -= processes
+		held += (running.cost*processes + running.processes - 1) / running.processes
+	}
+	for _, running := range t.running {
+		if running.expires.IsZero() {
+			hold(running)
+		}
+	}
+	for i := len(t.running) - 1; i >= 0 && unfinished > 0; i-- {
+		if running := t.running[i]; running.expires.After(now) {
+			hold(running)
+		}
+	}
+	return held
+}
+
+// next takes the queued pairs that fit within the limit now, in queue
+// order, by source node, passing over those held back by blackout windows.
+// elapsed is the time since the first tests started, which later pairs'
+// start delays are shortened by.
+func (t *throttle) next(now time.Time) (clientTests map[string][]*topology.TestPair, elapsed time.Duration) {
+	if t.started.IsZero() {
+		t.started = now
+	}
+	elapsed = now.Sub(t.started)
+	clientTests = make(map[string][]*topology.TestPair)
+	inFlight := t.inFlight(now)
+	for i := 0; i < len(t.queue); {
+		pair := t.queue[i]
+		cost := t.cost(pair)
+		if inFlight+cost > t.limit {
+			break
+		}
+		if t.blackouts != nil {
+			held, skipped := t.blackouts.hold(pair, now.Add(startDelay(pair, elapsed)))
+			if held {
+				i++
+				continue
+			}
+			if skipped {
+				t.queue = append(t.queue[:i], t.queue[i+1:]...)
+				continue
+			}
+		}
+		t.queue = append(t.queue[:i], t.queue[i+1:]...)
+		inFlight += cost
+		t.dispatched += pair.Profile.ProcessCount()
+		clientTests[pair.Source.ID] = append(clientTests[pair.Source.ID], pair)
+
+		running := &throttledPair{processes: pair.Profile.ProcessCount(), cost: cost}
+		if !pair.Background {
+			shortened := time.Duration(pair.StartDelayMs)*time.Millisecond - startDelay(pair, elapsed)
+			schedule := pair.Schedule() - shortened
+			running.expires = now.Add(schedule + waitGrace(schedule))
+			t.ends[pair.TestID] = now.Add(schedule)
+		}
+		t.running = append(t.running, running)
+	}
+	return clientTests, elapsed
+}
+
+// queued returns the number of pairs not yet started
+func (t *throttle) queued() int {
+	return len(t.queue)
+}
+
+// expectedEnd returns when the last of pairs is expected to finish, and
+// whether any of them is still queued. Pairs skipped for blackout windows
+// are left out.
+func (t *throttle) expectedEnd(pairs []*topology.TestPair) (time.Time, bool) {
+	var end time.Time
+	for _, pair := range pairs {
+		if t.blackouts != nil && t.blackouts.isSkipped(pair) {
+			continue
+		}
+		pairEnd, started := t.ends[pair.TestID]
+		if !started && !pair.Background {
+			return time.Time{}, true
+		}
+		if pairEnd.After(end) {
+			end = pairEnd
+		}
+	}
+	return end, false
+}
+
+// remaining returns how long until the last started measured test is
+// expected to finish
+func (t *throttle) remaining(now time.Time) time.Duration {
+	var last time.Time
+	for _, end := range t.ends {
+		if end.After(last) {
+			last = end
+		}
+	}
+	return max(last.Sub(now), 0)
+}
+
+// startDelay returns the start delay of a pair started elapsed after the
+// first tests, which keeps its place in the schedule as far as the limit
+// allows
+func startDelay(pair *topology.TestPair, elapsed time.Duration) time.Duration {
+	return max(time.Duration(pair.StartDelayMs)*time.Millisecond-elapsed, 0)
+}
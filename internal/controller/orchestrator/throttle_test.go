@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+func TestStartClients_MaxConcurrentTests(t *testing.T) {
+	orch, daemons, topo := newFailingOrchestrator(t, "")
+	ctx := context.Background()
+	starts := &startLog{}
+	for _, daemon := range daemons {
+		daemon.starts = starts
+	}
+	for _, pair := range topo.Pairs {
+		pair.Duration = time.Millisecond
+	}
+	orch.SetMaxConcurrentTests(1)
+	orch.SetStallPolicy(time.Second, false)
+	orch.queuePollInterval = 10 * time.Millisecond
+
+	if err := orch.Initialize(ctx, topo); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	if err := orch.StartServers(ctx, topo.ServerPorts, topo.ServerBinds()); err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if err := orch.StartClients(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("StartClients() error = %v", err)
+	}
+	if len(starts.ids) != 1 {
+		t.Fatalf("StartClients() started %v, want 1 test within the cap", starts.ids)
+	}
+
+	// The second test starts once the first is seen to finish
+	if err := orch.Wait(ctx, topo.ClientTests); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(starts.ids) != 2 {
+		t.Errorf("started %v after Wait(), want both tests", starts.ids)
+	}
+	if snap := orch.Progress().Snapshot(); snap.CompletedTests != 2 {
+		t.Errorf("CompletedTests = %d, want 2", snap.CompletedTests)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	node1 := &models.Node{ID: "node1"}
+	node2 := &models.Node{ID: "node2"}
+	profile := &models.TestProfile{Name: "default", Duration: time.Second}
+	pair := func(id string, delayMs int, background bool) *topology.TestPair {
+		return &topology.TestPair{TestID: id, Source: node1, Destination: node2, Profile: profile,
+			StartDelayMs: delayMs, Background: background}
+	}
+
+	throttle := newThrottle(2, 0)
+	err := throttle.add(map[string][]*topology.TestPair{
+		"node1": {pair("late", 3000, false), pair("early", 0, false), pair("load", 0, true)},
+	})
+	if err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+
+	// Background load first, then tests by start delay
+	now := time.Now()
+	started, _ := throttle.next(now)
+	if got := started["node1"]; len(got) != 2 || got[0].TestID != "load" || got[1].TestID != "early" {
+		t.Fatalf("next() = %v, want load and early", got)
+	}
+	if started, _ := throttle.next(now); len(started) != 0 {
+		t.Errorf("next() at the cap = %v, want nothing", started)
+	}
+
+	// A finished test frees its share; the late test keeps what is left of
+	// its start delay
+	throttle.setFinished(1)
+	started, elapsed := throttle.next(now.Add(time.Second))
+	if len(started["node1"]) != 1 || startDelay(started["node1"][0], elapsed) != 2*time.Second {
+		t.Errorf("next() after a finish = %v, elapsed %v; want late with 2s left of its delay", started, elapsed)
+	}
+
+	// A test whose result never shows stops counting once past its expiry
+	throttle = newThrottle(1, 0)
+	if err := throttle.add(map[string][]*topology.TestPair{"node1": {pair("lost", 0, false), pair("next", 0, false)}}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	throttle.next(now)
+	if started, _ := throttle.next(now.Add(time.Minute)); len(started["node1"]) != 1 {
+		t.Errorf("next() past the expiry = %v, want next", started)
+	}
+
+	// Bidirectional and multi-process pairs take a larger share; finished
+	// processes free the share of the pairs started first
+	bidir := &models.TestProfile{Name: "bidir", Duration: time.Second, Bidirectional: true}
+	reverse := &models.TestProfile{Name: "reverse", Duration: time.Second, Reverse: true}
+	multi := &models.TestProfile{Name: "multi", Duration: time.Second, ProcessesPerPair: 2}
+	mixed := []*topology.TestPair{
+		{TestID: "bidir-a", Source: node1, Destination: node2, Profile: bidir},
+		{TestID: "plain-b", Source: node1, Destination: node2, Profile: profile, StartDelayMs: 100},
+		{TestID: "reverse-c", Source: node1, Destination: node2, Profile: reverse, StartDelayMs: 200},
+		{TestID: "bidir-d", Source: node1, Destination: node2, Profile: bidir, StartDelayMs: 300},
+		{TestID: "multi-e", Source: node1, Destination: node2, Profile: multi, StartDelayMs: 400},
+	}
+	throttle = newThrottle(4, 0)
+	if err := throttle.add(map[string][]*topology.TestPair{"node1": mixed}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	waves := make([]string, 0)
+	for _, finished := range []int{0, 1, 3} {
+		throttle.setFinished(finished)
+		started, _ := throttle.next(now)
+		ids := make([]string, 0)
+		for _, pair := range started["node1"] {
+			ids = append(ids, pair.TestID)
+		}
+		waves = append(waves, strings.Join(ids, ","))
+	}
+	if got := strings.Join(waves, " | "); got != "bidir-a,plain-b,reverse-c | bidir-d | multi-e" {
+		t.Errorf("waves = %s, want bidir-a,plain-b,reverse-c | bidir-d | multi-e", got)
+	}
+
+	// Background load taking the whole cap would leave nothing to measure
+	throttle = newThrottle(1, 0)
+	err = throttle.add(map[string][]*topology.TestPair{"node1": {pair("load", 0, true)}})
+	if err == nil || !strings.Contains(err.Error(), "max_concurrent_tests (1)") {
+		t.Errorf("add() with background load at the cap error = %v", err)
+	}
+}
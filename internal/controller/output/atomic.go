@@ -0,0 +1,137 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// atomicFile is an output file written to a temporary file in the same
+// directory and renamed into place once complete, so that a crash or kill
+// never leaves a truncated file under the output's name
+type atomicFile struct {
+	*os.File
+	path      string
+	overwrite bool
+	done      bool
+}
+
+// tempPrefix returns the prefix of the temporary files written for path
+func tempPrefix(path string) string {
+	return "." + filepath.Base(path) + ".tmp-"
+}
+
+// createAtomic starts writing the file at path, removing the temporary
+// files of earlier writes of it that were interrupted
+func createAtomic(path string, overwrite bool, perm fs.FileMode) (*atomicFile, error) {
+	removeStaleTemps(path)
+	file, err := os.CreateTemp(filepath.Dir(path), tempPrefix(path)+"*")
+	if err != nil {
+		return nil, err
+	}
+	if err := file.Chmod(perm); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return &atomicFile{File: file, path: path, overwrite: overwrite}, nil
+}
+
+// removeStaleTemps removes temporary files left by interrupted writes of
+// path. Failures are ignored, as they only leave clutter behind.
+func removeStaleTemps(path string) {
+	dir := filepath.Dir(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	prefix := tempPrefix(path)
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasPrefix(entry.Name(), prefix) {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+}
+
+// Commit syncs the file to disk and moves it into place. Without overwrite,
+// an existing file at the path is left alone and an error returned.
+func (f *atomicFile) Commit() error {
+	f.done = true
+	tmp := f.Name()
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if f.overwrite {
+		if err := os.Rename(tmp, f.path); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+	// A hard link fails rather than replace a file created meanwhile
+	err := os.Link(tmp, f.path)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		_ = os.Remove(tmp)
+		if err != nil {
+			return fmt.Errorf("%s already exists; use --overwrite to replace it", f.path)
+		}
+		return nil
+	}
+	// Filesystems without hard links
+	if _, err := os.Lstat(f.path); err == nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("%s already exists; use --overwrite to replace it", f.path)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// Abort discards the file unless it was committed, so that it can be
+// deferred right after createAtomic
+func (f *atomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+// WriteFileAtomic writes data to path the way output files are written:
+// through a temporary file renamed into place, replacing any existing file
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	file, err := createAtomic(path, true, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Abort()
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Commit()
+}
+
+// ParseError describes the failure to parse the JSON file at path, what
+// it holds. Files cut short, as by a run killed before output was written
+// atomically, are reported as incomplete rather than malformed.
+func ParseError(what, path string, data []byte, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Offset >= int64(len(data)) {
+		return fmt.Errorf("%s %s appears incomplete — was the run interrupted? (%w)", what, path, err)
+	}
+	return fmt.Errorf("failed to parse %s %s: %w", what, path, err)
+}
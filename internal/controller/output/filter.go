@@ -0,0 +1,259 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+// Filter selects which results are written to output files.
+//
+// Filters are built from include and exclude rules. A rule is a list of
+// whitespace-separated terms that must all match. A term is key=values or
+// key!=values, where values is a comma-separated list of alternatives. For
+// example, "source_tag=rack-a dest_tag!=rack-a status=failed" matches failed
+// tests from rack-a to other racks.
+//
+// Keys are source, dest and node (either end) for node IDs; tag, source_tag
+// and dest_tag for node tags; profile; group; and status, where statuses may
+// be given as "failed" or "TEST_STATUS_FAILED".
+//
+// A result is kept if it matches any include rule, or there are none, and
+// matches no exclude rule.
+type Filter struct {
+	include []filterRule
+	exclude []filterRule
+	nodes   *models.NodeRegistry
+}
+
+// filterRule is a conjunction of terms
+type filterRule []filterTerm
+
+// filterTerm matches one attribute of a result against a set of values
+type filterTerm struct {
+	key    string
+	values []string
+	negate bool
+	tags   []*models.TagExpr // The values of tag keys
+}
+
+// filterKeys lists the keys a term may use
+var filterKeys = map[string]bool{
+	"source":     true,
+	"dest":       true,
+	"node":       true,
+	"tag":        true,
+	"source_tag": true,
+	"dest_tag":   true,
+	"profile":    true,
+	"group":      true,
+	"family":     true,
+	"status":     true,
+}
+
+// NewFilter parses include and exclude rules. Tags are looked up in nodes,
+// and normalized like their tags; if nodes is nil, tag terms match no node.
+func NewFilter(include, exclude []string, nodes *models.NodeRegistry) (*Filter, error) {
+	f := &Filter{nodes: nodes}
+
+	for _, text := range include {
+		rule, err := f.parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("include filter %q: %w", text, err)
+		}
+		f.include = append(f.include, rule)
+	}
+	for _, text := range exclude {
+		rule, err := f.parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("exclude filter %q: %w", text, err)
+		}
+		f.exclude = append(f.exclude, rule)
+	}
+
+	return f, nil
+}
+
+// parseRule parses a rule, including the tags of its tag terms
+func (f *Filter) parseRule(text string) (filterRule, error) {
+	rule, err := parseFilterRule(text)
+	if err != nil {
+		return nil, err
+	}
+	for i, term := range rule {
+		if !strings.HasSuffix(term.key, "tag") {
+			continue
+		}
+		for _, value := range term.values {
+			var expr *models.TagExpr
+			if f.nodes != nil {
+				expr, err = f.nodes.ParseTagExpr(value)
+			} else {
+				expr, err = models.ParseTagExpr(value, false)
+			}
+			if err != nil {
+				return nil, err
+			}
+			rule[i].tags = append(rule[i].tags, expr)
+		}
+	}
+	return rule, nil
+}
+
+// parseFilterRule parses a whitespace-separated list of terms
+func parseFilterRule(text string) (filterRule, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("rule is empty")
+	}
+
+	rule := make(filterRule, 0, len(fields))
+	for _, field := range fields {
+		term, err := parseFilterTerm(field)
+		if err != nil {
+			return nil, err
+		}
+		rule = append(rule, term)
+	}
+	return rule, nil
+}
+
+// parseFilterTerm parses key=values or key!=values
+func parseFilterTerm(text string) (filterTerm, error) {
+	key, values, found := strings.Cut(text, "=")
+	if !found {
+		return filterTerm{}, fmt.Errorf("term %q is not key=value", text)
+	}
+
+	term := filterTerm{}
+	if strings.HasSuffix(key, "!") {
+		term.negate = true
+		key = strings.TrimSuffix(key, "!")
+	}
+	key = strings.ToLower(key)
+	if !filterKeys[key] {
+		return filterTerm{}, fmt.Errorf("unknown filter key %q", key)
+	}
+	term.key = key
+
+	for _, value := range strings.Split(values, ",") {
+		if value == "" {
+			return filterTerm{}, fmt.Errorf("term %q has an empty value", text)
+		}
+		if key == "status" {
+			value = normalizeStatus(value)
+		}
+		term.values = append(term.values, value)
+	}
+
+	return term, nil
+}
+
+// normalizeStatus maps "failed" and "TEST_STATUS_FAILED" to the same form
+func normalizeStatus(status string) string {
+	return strings.TrimPrefix(strings.ToUpper(status), "TEST_STATUS_")
+}
+
+// Match reports whether result should be written
+func (f *Filter) Match(result *aggregator.TestResult) bool {
+	if f == nil {
+		return true
+	}
+
+	included := len(f.include) == 0
+	for _, rule := range f.include {
+		if f.matchRule(rule, result) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+
+	for _, rule := range f.exclude {
+		if f.matchRule(rule, result) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the results that match and the number filtered out
+func (f *Filter) Apply(results []*aggregator.TestResult) ([]*aggregator.TestResult, int) {
+	if f == nil {
+		return results, 0
+	}
+
+	kept := make([]*aggregator.TestResult, 0, len(results))
+	for _, result := range results {
+		if f.Match(result) {
+			kept = append(kept, result)
+		}
+	}
+	return kept, len(results) - len(kept)
+}
+
+// matchRule reports whether every term of rule matches result
+func (f *Filter) matchRule(rule filterRule, result *aggregator.TestResult) bool {
+	for _, term := range rule {
+		if f.matchTerm(term, result) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// matchTerm reports whether the term's attribute of result has any of its
+// values, ignoring negation
+func (f *Filter) matchTerm(term filterTerm, result *aggregator.TestResult) bool {
+	switch term.key {
+	case "source":
+		return containsAny(term.values, result.SourceNode)
+	case "dest":
+		return containsAny(term.values, result.DestNode)
+	case "node":
+		return containsAny(term.values, result.SourceNode, result.DestNode)
+	case "tag":
+		return f.nodeHasTag(result.SourceNode, term.tags) || f.nodeHasTag(result.DestNode, term.tags)
+	case "source_tag":
+		return f.nodeHasTag(result.SourceNode, term.tags)
+	case "dest_tag":
+		return f.nodeHasTag(result.DestNode, term.tags)
+	case "profile":
+		return containsAny(term.values, result.Profile)
+	case "group":
+		return containsAny(term.values, result.Group)
+	case "family":
+		return containsAny(term.values, result.AddressFamily)
+	case "status":
+		return containsAny(term.values, normalizeStatus(result.Status))
+	}
+	return false
+}
+
+// nodeHasTag reports whether the node's tags satisfy any of the tags
+func (f *Filter) nodeHasTag(nodeID string, tags []*models.TagExpr) bool {
+	if f.nodes == nil {
+		return false
+	}
+	node, err := f.nodes.GetNode(nodeID)
+	if err != nil {
+		return false
+	}
+	return models.MatchesAnyTagExpr(tags, node.Tags)
+}
+
+// containsAny reports whether any of candidates is in values
+func containsAny(values []string, candidates ...string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
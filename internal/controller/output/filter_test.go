@@ -0,0 +1,90 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+func newFilterNodes(t *testing.T) *models.NodeRegistry {
+	t.Helper()
+
+	nodes := models.NewNodeRegistry()
+	for _, node := range []*models.Node{
+		{ID: "a1", IP: "10.0.0.1", Port: 50051, Tags: []string{"rack-a"}},
+		{ID: "a2", IP: "10.0.0.2", Port: 50051, Tags: []string{"rack-a"}},
+		{ID: "b1", IP: "10.0.1.1", Port: 50051, Tags: []string{"rack-b"}},
+	} {
+		if err := nodes.AddNode(node); err != nil {
+			t.Fatalf("AddNode(%s) error = %v", node.ID, err)
+		}
+	}
+	return nodes
+}
+
+func TestFilter_Match(t *testing.T) {
+	nodes := newFilterNodes(t)
+	results := []*aggregator.TestResult{
+		{TestID: "a1-a2", SourceNode: "a1", DestNode: "a2", Profile: "default", Status: "TEST_STATUS_COMPLETED"},
+		{TestID: "a1-b1", SourceNode: "a1", DestNode: "b1", Profile: "default", Status: "TEST_STATUS_FAILED"},
+		{TestID: "b1-a2", SourceNode: "b1", DestNode: "a2", Profile: "udp", Status: "TEST_STATUS_COMPLETED"},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"no rules", nil, nil, []string{"a1-a2", "a1-b1", "b1-a2"}},
+		{"status", []string{"status=failed"}, nil, []string{"a1-b1"}},
+		{"full status", []string{"status=TEST_STATUS_FAILED"}, nil, []string{"a1-b1"}},
+		{"inter-rack", []string{"source_tag=rack-a dest_tag!=rack-a", "source_tag=rack-b dest_tag!=rack-b"}, nil,
+			[]string{"a1-b1", "b1-a2"}},
+		{"either end", []string{"node=b1"}, nil, []string{"a1-b1", "b1-a2"}},
+		{"alternatives", []string{"dest=a2,b1"}, []string{"source=b1"}, []string{"a1-a2", "a1-b1"}},
+		{"exclude only", nil, []string{"profile=udp"}, []string{"a1-a2", "a1-b1"}},
+		{"tag", []string{"tag=rack-b"}, []string{"status=failed"}, []string{"b1-a2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := NewFilter(tt.include, tt.exclude, nodes)
+			if err != nil {
+				t.Fatalf("NewFilter() error = %v", err)
+			}
+
+			kept, filtered := filter.Apply(results)
+			if filtered != len(results)-len(tt.want) {
+				t.Errorf("Apply() filtered = %d, want %d", filtered, len(results)-len(tt.want))
+			}
+			if len(kept) != len(tt.want) {
+				t.Fatalf("Apply() kept %d results, want %d", len(kept), len(tt.want))
+			}
+			for i, result := range kept {
+				if result.TestID != tt.want[i] {
+					t.Errorf("Apply() kept[%d] = %s, want %s", i, result.TestID, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewFilter_Invalid(t *testing.T) {
+	for _, rule := range []string{"", "source", "colour=red", "dest=a1,", "status="} {
+		if _, err := NewFilter([]string{rule}, nil, nil); err == nil {
+			t.Errorf("NewFilter(%q) expected error", rule)
+		}
+	}
+}
+
+func TestFilter_Nil(t *testing.T) {
+	var filter *Filter
+	results := []*aggregator.TestResult{{TestID: "t1"}}
+
+	kept, filtered := filter.Apply(results)
+	if len(kept) != 1 || filtered != 0 {
+		t.Errorf("nil Filter.Apply() = %d kept, %d filtered, want 1, 0", len(kept), filtered)
+	}
+}
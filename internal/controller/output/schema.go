@@ -0,0 +1,237 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+)
+
+// schemaDialect is the JSON Schema version of generated schemas
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document, or a subschema of one. Only the
+// keywords needed to describe the JSON output are supported.
+type Schema struct {
+	Dialect     string `json:"$schema,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	// Type is a type name, or a list of them for values that may be null
+	Type       interface{}        `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is false for structs, and the schema of the
+	// values for maps
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// marshalerSchemas describes the types written by their own MarshalJSON,
+// whose encoding can't be derived from their fields
+var marshalerSchemas = map[reflect.Type]*Schema{
+	reflect.TypeOf(nodeerr.NodeError{}): {
+		Type: "object",
+		Properties: map[string]*Schema{
+			"node_id": {Type: "string"},
+			"op":      {Type: "string"},
+			"error":   {Type: "string"},
+		},
+		Required:             []string{"node_id", "error"},
+		AdditionalProperties: false,
+	},
+}
+
+// NewSchema returns a JSON Schema for the JSON output, generated from the
+// OutputData type. The optional sections written for data, such as the
+// timeline or resource usage, are required by the schema and those that
+// weren't are left out of it, so the schema reflects the features enabled
+// for the run.
+func NewSchema(data *OutputData) *Schema {
+	g := &schemaGenerator{
+		defs:  make(map[string]*Schema),
+		names: make(map[reflect.Type]string),
+	}
+	schema := g.object(reflect.TypeOf(OutputData{}))
+	schema.Dialect = schemaDialect
+	schema.Title = "iperf-cnc results"
+	schema.Defs = g.defs
+
+	value := reflect.ValueOf(data).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		name, omitempty, ok := jsonField(value.Type().Field(i))
+		if !ok || !omitempty {
+			continue
+		}
+		if isEmptyValue(value.Field(i)) {
+			delete(schema.Properties, name)
+		} else {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// schemaGenerator builds schemas from Go types, defining each struct type
+// once under $defs so that recursive types such as TestResult can be
+// described
+type schemaGenerator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+}
+
+// schemaFor returns the schema of values of type t
+func (g *schemaGenerator) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if schema, ok := marshalerSchemas[t]; ok {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return &Schema{Ref: "#/$defs/" + g.define(t)}
+	default:
+		return &Schema{} // Any value, such as iperf3's own JSON
+	}
+}
+
+// define adds the schema of struct type t to $defs, if it isn't there yet,
+// and returns its name
+func (g *schemaGenerator) define(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.defs[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	g.defs[name] = &Schema{} // Reserve the name while the fields are described
+	g.defs[name] = g.object(t)
+	return name
+}
+
+// object returns the schema of struct type t. Fields without omitempty are
+// required, and may be null if they are pointers, slices or maps.
+func (g *schemaGenerator) object(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: false,
+	}
+	g.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the fields of struct type t, and of the structs it embeds,
+// to schema
+func (g *schemaGenerator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			g.addFields(schema, field.Type)
+			continue
+		}
+		name, omitempty, ok := jsonField(field)
+		if !ok {
+			continue
+		}
+		fieldSchema := g.schemaFor(field.Type)
+		if !omitempty {
+			schema.Required = append(schema.Required, name)
+			switch field.Type.Kind() {
+			case reflect.Ptr, reflect.Slice, reflect.Map:
+				fieldSchema = nullable(fieldSchema)
+			}
+		}
+		schema.Properties[name] = fieldSchema
+	}
+}
+
+// nullable returns a schema that also accepts null
+func nullable(schema *Schema) *Schema {
+	if typeName, ok := schema.Type.(string); ok && schema.Ref == "" {
+		copied := *schema
+		copied.Type = []string{typeName, "null"}
+		return &copied
+	}
+	return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+}
+
+// jsonField returns the JSON name of a struct field and whether it is
+// omitted when empty. ok is false for fields that aren't encoded.
+func jsonField(field reflect.StructField) (name string, omitempty bool, ok bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range strings.Split(options, ",") {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
+
+// isEmptyValue reports whether encoding/json omits v from an omitempty field
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// WriteSchema writes the JSON Schema of data to the schema file
+func (w *Writer) WriteSchema(data *OutputData) error {
+	schemaPath := w.SchemaPath()
+	if schemaPath == "" {
+		return nil // Schema output not requested
+	}
+
+	file, err := w.create(schemaPath)
+	if err != nil {
+		return fmt.Errorf("failed to create schema file: %w", err)
+	}
+	defer file.Abort()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(NewSchema(data)); err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to save schema file: %w", err)
+	}
+	return nil
+}
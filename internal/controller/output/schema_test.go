@@ -0,0 +1,244 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+func TestSchema_ValidatesOutput(t *testing.T) {
+	// Every field is set, so every type in the output is checked against
+	// the schema
+	data := &OutputData{}
+	fill(reflect.ValueOf(data).Elem(), 0)
+	data.Metadata.FailedNodes = []*nodeerr.NodeError{{NodeID: "node1", Op: "start", Err: errors.New("refused")}}
+
+	dir := t.TempDir()
+	writer := NewWriter(filepath.Join(dir, "results.json"), "")
+	writer.SetSchemaFile(filepath.Join(dir, "schema.json"))
+	writer.SetMetadata(data.Metadata)
+	writer.SetForeignResults(data.ForeignResults)
+	writer.SetCollectionErrors(data.CollectionErrors)
+	writer.SetTimeline(data.Timeline, "")
+	writer.SetResourceUsage(data.ResourceUsage)
+	writer.SetConfig(data.Config)
+	writer.SetBurstRows(true)
+	if err := writer.WriteAll(data.Summary, data.Results); err != nil {
+		t.Fatalf("WriteAll() error = %v", err)
+	}
+
+	schema := readJSONFile(t, writer.SchemaPath())
+	document := readJSONFile(t, writer.JSONPath())
+	if err := validate(schema, schema, document, "$"); err != nil {
+		t.Errorf("results do not validate against the schema: %v", err)
+	}
+
+	// Sections written for the run are required
+	required := fmt.Sprint(schema["required"])
+	for _, section := range []string{"metadata", "timeline", "resource_usage", "foreign_results", "config"} {
+		if !strings.Contains(required, section) {
+			t.Errorf("schema does not require %s: required = %s", section, required)
+		}
+	}
+
+	// The validator rejects what the schema doesn't describe
+	document["unexpected"] = true
+	if err := validate(schema, schema, document, "$"); err == nil {
+		t.Errorf("validate() accepted an undescribed property")
+	}
+	delete(document, "unexpected")
+	document["summary"].(map[string]interface{})["total_tests"] = "many"
+	if err := validate(schema, schema, document, "$"); err == nil {
+		t.Errorf("validate() accepted a string for an integer")
+	}
+}
+
+func TestSchema_OptionalSections(t *testing.T) {
+	data := &OutputData{
+		Summary: &aggregator.Summary{TotalTests: 1},
+		Results: []*aggregator.TestResult{{TestID: "t1", Status: "completed"}},
+	}
+	schema := NewSchema(data)
+
+	if got, want := schema.Required, []string{"schema_version", "summary", "results"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Required = %v, want %v", got, want)
+	}
+	for _, section := range []string{"timeline", "resource_usage", "metadata"} {
+		if _, ok := schema.Properties[section]; ok {
+			t.Errorf("schema describes %s, which the run did not write", section)
+		}
+	}
+	if schema.Defs["TestResult"] == nil || schema.Defs["TestResult"].Properties["sub_results"].Items.Ref != "#/$defs/TestResult" {
+		t.Errorf("TestResult is not defined recursively")
+	}
+}
+
+// fill sets every field reachable from v to a non-zero value. Recursive
+// types are filled to a few levels, below which slices and maps are left
+// empty.
+func fill(v reflect.Value, depth int) {
+	if depth > 4 && (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i), depth)
+			}
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 1, 1))
+		fill(v.Index(0), depth+1)
+	case reflect.Map:
+		key := reflect.New(v.Type().Key()).Elem()
+		fill(key, depth)
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fill(elem, depth+1)
+		v.Set(reflect.MakeMap(v.Type()))
+		v.SetMapIndex(key, elem)
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf("value"))
+		}
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.String:
+		v.SetString("value")
+	}
+}
+
+func readJSONFile(t *testing.T, path string) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(path) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return decoded
+}
+
+// validate checks value against the JSON Schema keywords the generated
+// schemas use. root resolves $ref.
+func validate(root, schema map[string]interface{}, value interface{}, at string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/$defs/")
+		def, ok := root["$defs"].(map[string]interface{})[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: unresolved $ref %s", at, ref)
+		}
+		return validate(root, def, value, at)
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		for _, option := range anyOf {
+			if validate(root, option.(map[string]interface{}), value, at) == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s: matches none of anyOf", at)
+	}
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		return fmt.Errorf("%s: %v is not of type %v", at, value, types)
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, ok := value[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required property %s", at, name)
+			}
+		}
+		names := make([]string, 0, len(value))
+		for name := range value {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				additional, isSchema := schema["additionalProperties"].(map[string]interface{})
+				if !isSchema {
+					return fmt.Errorf("%s: unexpected property %s", at, name)
+				}
+				propertySchema = additional
+			}
+			if err := validate(root, propertySchema, value[name], at+"."+name); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range value {
+				if err := validate(root, items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether value is of the JSON Schema type, or one of
+// the types, in types
+func matchesType(types, value interface{}) bool {
+	names, ok := types.([]interface{})
+	if !ok {
+		names = []interface{}{types}
+	}
+	for _, name := range names {
+		switch name {
+		case "null":
+			if value == nil {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if number, ok := value.(float64); ok && number == float64(int64(number)) {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
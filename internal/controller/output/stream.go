@@ -0,0 +1,130 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+// ResultStream writes the results of a streaming run as they are
+// collected: each as one JSON object per line to the stream file and, if
+// CSV output is requested, as a CSV row. Its files appear under their
+// names once it is closed.
+type ResultStream struct {
+	file    *atomicFile
+	buffer  *bufio.Writer
+	encoder *json.Encoder
+	csvFile *atomicFile
+	csv     *csv.Writer
+
+	filter    *Filter
+	burstRows bool
+	written   int
+	filtered  int
+}
+
+// OpenStream starts streaming the results of a run to streamFile, and to
+// the CSV file if one is set. WriteAll then writes no CSV file, and the
+// JSON output holds the summary and a sample of the results. The filter,
+// burst rows, naming and overwrite settings must be set before.
+func (w *Writer) OpenStream(streamFile string) (*ResultStream, error) {
+	w.streamFile = streamFile
+	if err := w.resolvePaths(); err != nil {
+		return nil, err
+	}
+
+	file, err := w.create(w.StreamPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream file: %w", err)
+	}
+	buffer := bufio.NewWriter(file)
+	stream := &ResultStream{
+		file:      file,
+		buffer:    buffer,
+		encoder:   json.NewEncoder(buffer),
+		filter:    w.filter,
+		burstRows: w.burstRows,
+	}
+
+	if w.csvFile != "" {
+		stream.csvFile, err = w.create(w.CSVPath())
+		if err != nil {
+			stream.Abort()
+			return nil, fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		stream.csv = csv.NewWriter(stream.csvFile)
+		if err := stream.csv.Write(csvHeader(w.burstRows)); err != nil {
+			stream.Abort()
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	}
+
+	w.stream = stream
+	return stream, nil
+}
+
+// WriteResult writes a result, unless the output filters leave it out
+func (s *ResultStream) WriteResult(result *aggregator.TestResult) error {
+	if !s.filter.Match(result) {
+		s.filtered++
+		return nil
+	}
+
+	record := result
+	if !s.burstRows && len(result.Bursts) > 0 {
+		copied := *result
+		copied.Bursts = nil
+		record = &copied
+	}
+	if err := s.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write stream file: %w", err)
+	}
+	if s.csv != nil {
+		if err := writeCSVResult(s.csv, result, s.burstRows); err != nil {
+			return err
+		}
+	}
+	s.written++
+	return nil
+}
+
+// Written returns the number of results written
+func (s *ResultStream) Written() int {
+	return s.written
+}
+
+// Close finishes writing and moves the files into place
+func (s *ResultStream) Close() error {
+	defer s.Abort()
+
+	if err := s.buffer.Flush(); err != nil {
+		return fmt.Errorf("failed to write stream file: %w", err)
+	}
+	if s.csv != nil {
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	if err := s.file.Commit(); err != nil {
+		return fmt.Errorf("failed to save stream file: %w", err)
+	}
+	if s.csvFile != nil {
+		if err := s.csvFile.Commit(); err != nil {
+			return fmt.Errorf("failed to save CSV file: %w", err)
+		}
+	}
+	return nil
+}
+
+// Abort discards the files of a stream that was not closed
+func (s *ResultStream) Abort() {
+	s.file.Abort()
+	if s.csvFile != nil {
+		s.csvFile.Abort()
+	}
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+// Versions of the JSON output format:
+//
+//  1. The original, unversioned format. throughput_bps is the rate the
+//     sender reported.
+//  2. Unversioned. throughput_bps is the rate the receiver reported, with
+//     both sides' rates alongside, and the summary counts discrepant_tests.
+//  3. The version is recorded as schema_version.
+//
+// Sections added without changing the meaning of existing ones, such as
+// the timeline or diagnostics, do not need a new version: older files
+// simply lack them.
+const (
+	// SchemaVersion is the version of the JSON output written
+	SchemaVersion = 3
+	// MinSchemaVersion is the oldest version ReadJSON migrates
+	MinSchemaVersion = 1
+)
+
+// migrations upgrade output read at version i+1 to version i+2
+var migrations = []func(*OutputData){
+	migrateSenderThroughput,
+	func(*OutputData) {}, // Version 3 only records the version
+}
+
+// schemaVersion returns the version of JSON output, detecting that of the
+// unversioned formats from the fields they were written with
+func schemaVersion(data []byte) (int, error) {
+	var header struct {
+		SchemaVersion *int                       `json:"schema_version"`
+		Summary       map[string]json.RawMessage `json:"summary"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	switch {
+	case header.SchemaVersion != nil:
+		return *header.SchemaVersion, nil
+	case header.Summary["discrepant_tests"] != nil:
+		return 2, nil
+	}
+	return 1, nil
+}
+
+// migrate upgrades output read at version to SchemaVersion
+func migrate(output *OutputData, version int) error {
+	if version > SchemaVersion {
+		return fmt.Errorf("schema version %d is newer than this build reads (%d to %d); upgrade iperf-controller",
+			version, MinSchemaVersion, SchemaVersion)
+	}
+	if version < MinSchemaVersion {
+		return fmt.Errorf("schema version %d is older than this build reads (%d to %d)",
+			version, MinSchemaVersion, SchemaVersion)
+	}
+	for ; version < SchemaVersion; version++ {
+		migrations[version-1](output)
+	}
+	output.SchemaVersion = SchemaVersion
+	return nil
+}
+
+// migrateSenderThroughput keeps the sender's rate, which version 1 wrote as
+// throughput_bps, as such, marking where the throughput was read from
+func migrateSenderThroughput(output *OutputData) {
+	var migrate func(results []*aggregator.TestResult)
+	migrate = func(results []*aggregator.TestResult) {
+		for _, result := range results {
+			if result.ThroughputBps > 0 && result.SenderThroughputBps == 0 {
+				result.SenderThroughputBps = result.ThroughputBps
+				result.ThroughputSource = string(iperf.SourceSumSent)
+			}
+			migrate(result.SubResults)
+		}
+	}
+	migrate(output.Results)
+}
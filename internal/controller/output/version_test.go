@@ -0,0 +1,57 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The testdata results files are frozen samples of each schema version,
+// trimmed to a few results. They must stay readable: a format change that
+// breaks one needs a new version and a migration instead.
+func TestReadJSON_SchemaVersions(t *testing.T) {
+	tests := []struct {
+		file           string
+		wantResults    int
+		wantThroughput float64 // Of the first result
+		wantSender     float64
+		wantSource     string
+	}{
+		{"results_v1.json", 2, 9412345678.5, 9412345678.5, "sum_sent"},
+		{"results_v2.json", 2, 5185217390.401164, 5185217390.401164, ""},
+		{"results_v3.json", 2, 5185217390.401164, 5185217390.401164, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := ReadJSON(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatalf("ReadJSON() error = %v", err)
+			}
+			if data.SchemaVersion != SchemaVersion {
+				t.Errorf("SchemaVersion = %d, want %d", data.SchemaVersion, SchemaVersion)
+			}
+			if len(data.Results) != tt.wantResults {
+				t.Fatalf("read %d results, want %d", len(data.Results), tt.wantResults)
+			}
+			first := data.Results[0]
+			if first.ThroughputBps != tt.wantThroughput || first.SenderThroughputBps != tt.wantSender ||
+				first.ThroughputSource != tt.wantSource {
+				t.Errorf("first result throughput %v, sender %v, source %q; want %v, %v, %q",
+					first.ThroughputBps, first.SenderThroughputBps, first.ThroughputSource,
+					tt.wantThroughput, tt.wantSender, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestReadJSON_NewerSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, []byte(`{"schema_version": 99, "summary": {}, "results": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJSON(path); err == nil || !strings.Contains(err.Error(), "newer than this build reads") {
+		t.Errorf("ReadJSON() error = %v, want a newer version error", err)
+	}
+}
@@ -5,20 +5,202 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/nodeerr"
+	"github.com/bensons/iperf-cnc/internal/common/poll"
+	"github.com/bensons/iperf-cnc/internal/common/units"
 	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
 )
 
 // OutputData contains all data to be written
 type OutputData struct {
-	Summary *aggregator.Summary      `json:"summary"`
-	Results []*aggregator.TestResult `json:"results"`
+	// SchemaVersion is the version of the output format; files read with
+	// ReadJSON are migrated to the current one
+	SchemaVersion int                      `json:"schema_version"`
+	Metadata      *RunMetadata             `json:"metadata,omitempty"`
+	Summary       *aggregator.Summary      `json:"summary"`
+	Results       []*aggregator.TestResult `json:"results"`
+	// ForeignResults are results for test IDs outside the run's topology,
+	// excluded from the summary and the results above
+	ForeignResults *aggregator.ForeignResults `json:"foreign_results,omitempty"`
+	// CollectionErrors lists the nodes whose results could not be
+	// retrieved, with the results missing because of them
+	CollectionErrors []*aggregator.CollectionError `json:"collection_errors,omitempty"`
+	// Timeline is the throughput of all tests together over time
+	Timeline *aggregator.Timeline `json:"timeline,omitempty"`
+	// Flakiness is how reliably each pair performed over its attempts,
+	// flakiest first, when some pair ran more than once
+	Flakiness []*aggregator.PairFlakiness `json:"flakiness,omitempty"`
+	// Repetitions is the throughput spread of each pair repeated within
+	// the run, when some pair was
+	Repetitions []*aggregator.PairRepetitions `json:"repetitions,omitempty"`
+	// ResourceUsage is the CPU, memory and NIC usage sampled on each node
+	// during the run, when resource sampling is enabled
+	ResourceUsage map[string]*aggregator.NodeUsage `json:"resource_usage,omitempty"`
+	// Diagnostics are the findings of the follow-up probes run after the
+	// tests, when any are enabled
+	Diagnostics *Diagnostics `json:"diagnostics,omitempty"`
+	// Config is the effective controller configuration of the run with
+	// secrets redacted, when output.embed_config is set
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+// Diagnostics are the findings of follow-up probes. The probes' results
+// are among the results, marked as diagnostic.
+type Diagnostics struct {
+	// MTU holds a diagnosis per pair probed by the MTU heuristics
+	MTU []*aggregator.MTUDiagnosis `json:"mtu"`
+}
+
+// RunMetadata describes the circumstances of a run
+type RunMetadata struct {
+	// RunID identifies the run to the daemons, which tag its results with it
+	RunID string `json:"run_id,omitempty"`
+	// CompatibilityNodes maps nodes whose daemons lacked newer RPCs to the
+	// RPCs that were replaced by an older call pattern
+	CompatibilityNodes map[string][]string `json:"compatibility_nodes,omitempty"`
+	// NodeFacts records each node's environment at the time of the run
+	NodeFacts map[string]*models.NodeFacts `json:"node_facts,omitempty"`
+	// Partial is set when the run failed, or results could not be retrieved
+	// from every node, and only some results were collected
+	Partial     bool   `json:"partial,omitempty"`
+	FailedPhase string `json:"failed_phase,omitempty"`
+	// NodeSubset is set when the run used only some of the configured nodes
+	NodeSubset *NodeSubset `json:"node_subset,omitempty"`
+	// Plan is set when the run imported its pairs from a plan file
+	Plan *PlanSource `json:"plan,omitempty"`
+	// PartialMesh is set when the run tested a random share of the full
+	// mesh, with the seed that selects the same pairs again
+	PartialMesh *PartialMesh `json:"partial_mesh,omitempty"`
+	// Bisection is set when the run tested a bisection topology
+	Bisection *Bisection `json:"bisection,omitempty"`
+	// Workload is set when the run selected a workload preset
+	Workload *Workload `json:"workload,omitempty"`
+	// FailedNodes lists the nodes on which the failed phase failed
+	FailedNodes []*nodeerr.NodeError `json:"failed_nodes,omitempty"`
+	// SimulatedNodes lists nodes whose daemons ran in simulation mode, so
+	// their results are synthetic
+	SimulatedNodes []string `json:"simulated_nodes,omitempty"`
+	// StalledTests lists the tests the wait phase gave up on, with how long
+	// they had been running
+	StalledTests []*orchestrator.StalledTest `json:"stalled_tests,omitempty"`
+	// LingeringProcesses lists the nodes whose processes were still running
+	// after cleanup, force-stop included
+	LingeringProcesses []*orchestrator.LingeringNode `json:"lingering_processes,omitempty"`
+	// NodeCache counts capability and fact lookups answered from the node cache
+	NodeCache *client.CacheStats `json:"node_cache,omitempty"`
+	// Polling counts the daemon status polls and how they were paced
+	Polling *poll.Stats `json:"polling,omitempty"`
+	// NodeHealth counts the nodes that were healthy when the run started
+	NodeHealth *client.NodeHealth `json:"node_health,omitempty"`
+	// Liveness records the nodes that answered the liveness check just
+	// before the run's first state-changing request; those missing were
+	// left out of the run
+	Liveness *client.Liveness `json:"liveness,omitempty"`
+	// RPCLatency is the latency of the daemon RPCs of the run, by method and
+	// node, with the nodes that were slow relative to the fleet
+	RPCLatency *client.RPCStats `json:"rpc_latency,omitempty"`
+	// Aggregation records whether results were kept in memory or streamed
+	Aggregation *Aggregation `json:"aggregation,omitempty"`
+}
+
+// Aggregation modes
+const (
+	AggregationInMemory  = "in_memory"
+	AggregationStreaming = "streaming"
+)
+
+// Aggregation records how a run's results were aggregated. A streaming
+// run's results are all in the stream file, and the results of the JSON
+// output are only a sample of them.
+type Aggregation struct {
+	Mode       string `json:"mode"`
+	StreamFile string `json:"stream_file,omitempty"`
+	Results    int    `json:"results"` // Results collected
+	Sampled    int    `json:"sampled,omitempty"`
+}
+
+// NodeSubset records the nodes a run was restricted to, so that its results
+// are not mistaken for a run over every configured node
+type NodeSubset struct {
+	Selection       string   `json:"selection"` // The run flags, e.g. "--tag rack-a --exclude-nodes node3"
+	Nodes           []string `json:"nodes"`
+	ConfiguredNodes int      `json:"configured_nodes"`
+}
+
+// PlanSource records the plan file a run imported, so that its results can
+// be traced to the exact plan
+type PlanSource struct {
+	Path    string `json:"path"`
+	SHA256  string `json:"sha256"`
+	Version int    `json:"version"`
+	Pairs   int    `json:"pairs"` // Pairs run; fewer than planned when nodes were left out
+}
+
+// PartialMesh records the selection of a partial mesh topology, so that a
+// run can be repeated with exactly its pairs by setting topology.seed
+type PartialMesh struct {
+	Density   float64    `json:"density"`
+	MaxTests  int        `json:"max_tests,omitempty"`
+	Seed      int64      `json:"seed"`
+	Pairs     int        `json:"pairs"`      // Node pairs selected
+	MeshPairs int        `json:"mesh_pairs"` // Node pairs of the full mesh
+	Selected  []MeshPair `json:"selected,omitempty"`
+}
+
+// MeshPair is a node pair a partial mesh topology selected
+type MeshPair struct {
+	SourceNode string `json:"source_node"`
+	DestNode   string `json:"dest_node"`
+}
+
+// Bisection records the sides of a bisection topology
+type Bisection struct {
+	SideA    []string `json:"side_a"`
+	SideB    []string `json:"side_b"`
+	Unpaired []string `json:"unpaired,omitempty"` // Nodes of the larger side left untested
+}
+
+// Workload records the workload preset a run selected and what it set
+type Workload struct {
+	Name    string            `json:"name"`
+	Applied map[string]string `json:"applied,omitempty"` // Settings applied, by field
+	Kept    []string          `json:"kept,omitempty"`    // Fields the configuration set itself
 }
 
 // Writer handles output generation
 type Writer struct {
-	jsonFile string
-	csvFile  string
+	jsonFile         string
+	csvFile          string
+	metadata         *RunMetadata
+	burstRows        bool
+	filter           *Filter
+	foreign          *aggregator.ForeignResults
+	config           map[string]interface{}
+	collectionErrors []*aggregator.CollectionError
+	timeline         *aggregator.Timeline
+	timelineCSVFile  string
+	flakiness        []*aggregator.PairFlakiness
+	flakinessCSVFile string
+	repetitions      []*aggregator.PairRepetitions
+	resourceUsage    map[string]*aggregator.NodeUsage
+	diagnostics      *Diagnostics
+	schemaFile       string
+	streamFile       string
+	stream           *ResultStream // Set once a streaming run's results are streamed
+
+	// Existing output files are refused unless overwrite is set; suffix is
+	// inserted before the extension of every file written
+	overwrite bool
+	suffix    string
+	resolved  bool // Paths checked, and the suffix final
 }
 
 // NewWriter creates a new output writer
@@ -29,21 +211,232 @@ func NewWriter(jsonFile, csvFile string) *Writer {
 	}
 }
 
+// SetMetadata sets the run metadata included in the JSON output
+func (w *Writer) SetMetadata(metadata *RunMetadata) {
+	w.metadata = metadata
+}
+
+// SetBurstRows controls whether per-burst rows of burst profiles are written
+func (w *Writer) SetBurstRows(enabled bool) {
+	w.burstRows = enabled
+}
+
+// SetFilter sets the filter selecting which results are written. The
+// summary is written unfiltered, with the number of filtered results.
+func (w *Writer) SetFilter(filter *Filter) {
+	w.filter = filter
+}
+
+// SetForeignResults sets the results for test IDs outside the run's
+// topology, written to a separate section of the JSON output
+func (w *Writer) SetForeignResults(foreign *aggregator.ForeignResults) {
+	w.foreign = foreign
+}
+
+// SetCollectionErrors sets the nodes whose results could not be retrieved
+func (w *Writer) SetCollectionErrors(errors []*aggregator.CollectionError) {
+	w.collectionErrors = errors
+}
+
+// SetConfig sets the configuration embedded in the JSON output. It should
+// already be redacted.
+func (w *Writer) SetConfig(config map[string]interface{}) {
+	w.config = config
+}
+
+// SetTimeline sets the aggregate throughput timeline included in the JSON
+// output and, if csvFile is set, written to it with one row per bucket
+func (w *Writer) SetTimeline(timeline *aggregator.Timeline, csvFile string) {
+	w.timeline = timeline
+	w.timelineCSVFile = csvFile
+}
+
+// SetFlakiness sets the per-pair flakiness included in the JSON output and,
+// if csvFile is set, written to it with one row per pair
+func (w *Writer) SetFlakiness(flakiness []*aggregator.PairFlakiness, csvFile string) {
+	w.flakiness = flakiness
+	w.flakinessCSVFile = csvFile
+}
+
+// SetRepetitions sets the per-pair repetitions included in the JSON output
+func (w *Writer) SetRepetitions(repetitions []*aggregator.PairRepetitions) {
+	w.repetitions = repetitions
+}
+
+// SetResourceUsage sets the per-node resource usage included in the JSON
+// output
+func (w *Writer) SetResourceUsage(usage map[string]*aggregator.NodeUsage) {
+	w.resourceUsage = usage
+}
+
+// SetDiagnostics sets the findings of follow-up probes included in the JSON
+// output
+func (w *Writer) SetDiagnostics(diagnostics *Diagnostics) {
+	w.diagnostics = diagnostics
+}
+
+// SetSchemaFile sets the file the JSON Schema of the JSON output is written
+// to, alongside it
+func (w *Writer) SetSchemaFile(schemaFile string) {
+	w.schemaFile = schemaFile
+}
+
+// SetOverwrite controls whether existing output files are replaced. By
+// default writing to an existing file fails, so that an earlier run's
+// results are not lost.
+func (w *Writer) SetOverwrite(overwrite bool) {
+	w.overwrite = overwrite
+}
+
+// SetFileSuffix sets a suffix, such as RunSuffix's, inserted before the
+// extension of every output file, so that "results.json" is written as
+// "results-20250102T150405.json". Without overwrite, a counter is appended
+// to the suffix while any of the files it names already exists.
+func (w *Writer) SetFileSuffix(suffix string) {
+	w.suffix = suffix
+}
+
+// RunSuffix returns a file suffix identifying a run started at t
+func RunSuffix(t time.Time) string {
+	return "-" + t.Format("20060102T150405")
+}
+
+// JSONPath returns the path the JSON output is written to, or "" if JSON
+// output is not requested
+func (w *Writer) JSONPath() string {
+	return withSuffix(w.jsonFile, w.suffix)
+}
+
+// CSVPath returns the path the CSV output is written to, or ""
+func (w *Writer) CSVPath() string {
+	return withSuffix(w.csvFile, w.suffix)
+}
+
+// TimelineCSVPath returns the path the timeline CSV is written to, or ""
+func (w *Writer) TimelineCSVPath() string {
+	if w.timeline == nil {
+		return ""
+	}
+	return withSuffix(w.timelineCSVFile, w.suffix)
+}
+
+// FlakinessCSVPath returns the path the flakiness CSV is written to, or ""
+func (w *Writer) FlakinessCSVPath() string {
+	if w.flakiness == nil {
+		return ""
+	}
+	return withSuffix(w.flakinessCSVFile, w.suffix)
+}
+
+// SchemaPath returns the path the JSON Schema is written to, or "" if it
+// is not requested or there is no JSON output for it to describe
+func (w *Writer) SchemaPath() string {
+	if w.jsonFile == "" {
+		return ""
+	}
+	return withSuffix(w.schemaFile, w.suffix)
+}
+
+// StreamPath returns the path results are streamed to, or "" if they are
+// not
+func (w *Writer) StreamPath() string {
+	return withSuffix(w.streamFile, w.suffix)
+}
+
+// paths returns the paths of the files WriteAll and OpenStream write
+func (w *Writer) paths() []string {
+	return nonEmpty(w.JSONPath(), w.CSVPath(), w.TimelineCSVPath(), w.FlakinessCSVPath(), w.SchemaPath(), w.StreamPath())
+}
+
+// resolvePaths checks, before anything is written, that none of the output
+// files exists. With a suffix set, a counter is added to it until none does.
+// Once resolved, as by OpenStream, the paths are kept.
+func (w *Writer) resolvePaths() error {
+	if w.overwrite || w.resolved {
+		return nil
+	}
+	if w.suffix == "" {
+		if err := CheckOutputFiles(w.paths()...); err != nil {
+			return err
+		}
+	}
+	base := w.suffix
+	for n := 2; w.suffix != "" && len(existingFiles(w.paths())) > 0; n++ {
+		w.suffix = fmt.Sprintf("%s-%d", base, n)
+	}
+	w.resolved = true
+	return nil
+}
+
+// CheckOutputFiles returns an error naming those of paths that already
+// exist, so that a run can refuse to start rather than run and then refuse
+// to write its results. Empty paths are ignored.
+func CheckOutputFiles(paths ...string) error {
+	existing := existingFiles(nonEmpty(paths...))
+	if len(existing) == 0 {
+		return nil
+	}
+	what := "output file %s already exists"
+	if len(existing) > 1 {
+		what = "output files %s already exist"
+	}
+	return fmt.Errorf(what+"; use --overwrite to replace them or set output.timestamp_suffix",
+		strings.Join(existing, ", "))
+}
+
+// existingFiles returns the paths that exist
+func existingFiles(paths []string) []string {
+	existing := make([]string, 0)
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	return existing
+}
+
+// nonEmpty returns paths without the empty ones
+func nonEmpty(paths ...string) []string {
+	kept := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path != "" {
+			kept = append(kept, path)
+		}
+	}
+	return kept
+}
+
+// withSuffix inserts suffix before the extension of path
+func withSuffix(path, suffix string) string {
+	if path == "" || suffix == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + suffix + ext
+}
+
+// create starts writing an output file, failing if it exists unless
+// overwrite is set. The file appears under its name only once committed.
+func (w *Writer) create(path string) (*atomicFile, error) {
+	if !w.overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return nil, fmt.Errorf("%s already exists; use --overwrite to replace it", path)
+		}
+	}
+	return createAtomic(path, w.overwrite, 0644)
+}
+
 // WriteJSON writes results to a JSON file
 func (w *Writer) WriteJSON(data *OutputData) error {
 	if w.jsonFile == "" {
 		return nil // JSON output not requested
 	}
 
-	file, err := os.Create(w.jsonFile)
+	file, err := w.create(w.JSONPath())
 	if err != nil {
 		return fmt.Errorf("failed to create JSON file: %w", err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("Warning: failed to close JSON file: %v\n", err)
-		}
-	}()
+	defer file.Abort()
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
@@ -52,86 +445,405 @@ func (w *Writer) WriteJSON(data *OutputData) error {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to save JSON file: %w", err)
+	}
 	return nil
 }
 
+// ReadJSON reads results written by WriteJSON, by this or an earlier
+// version, migrating them to the current format
+func ReadJSON(path string) (*OutputData, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Results path is from config or user
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results file: %w", err)
+	}
+
+	version, err := schemaVersion(data)
+	if err != nil {
+		return nil, ParseError("results file", path, data, err)
+	}
+	var output OutputData
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, ParseError("results file", path, data, err)
+	}
+	if output.Summary == nil {
+		return nil, fmt.Errorf("results file %s has no summary", path)
+	}
+	if err := migrate(&output, version); err != nil {
+		return nil, fmt.Errorf("results file %s: %w", path, err)
+	}
+	return &output, nil
+}
+
 // WriteCSV writes results to a CSV file
 func (w *Writer) WriteCSV(results []*aggregator.TestResult) error {
 	if w.csvFile == "" {
 		return nil // CSV output not requested
 	}
 
-	file, err := os.Create(w.csvFile)
+	file, err := w.create(w.CSVPath())
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %w", err)
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("Warning: failed to close CSV file: %v\n", err)
-		}
-	}()
+	defer file.Abort()
 
 	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	if err := writer.Write(csvHeader(w.burstRows)); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, result := range results {
+		if err := writeCSVResult(writer, result, w.burstRows); err != nil {
+			return err
+		}
+	}
 
-	// Write header
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to save CSV file: %w", err)
+	}
+	return nil
+}
+
+// csvHeader returns the header row of the CSV output
+func csvHeader(burstRows bool) []string {
 	header := []string{
 		"test_id",
 		"source_node",
 		"dest_node",
+		"group",
+		"address_family",
+		"v6_lag_percent",
+		"background",
+		"diagnostic",
 		"status",
 		"start_time",
 		"end_time",
 		"duration_seconds",
+		"setup_ms",
+		"blackout_delay_seconds",
 		"throughput_bps",
 		"throughput_mbps",
 		"throughput_gbps",
 		"retransmits",
+		"bytes_sent",
+		"retransmits_per_mb",
+		"discrepancy_percent",
+		"max_snd_cwnd_bytes",
+		"mean_rtt_ms",
+		"limit_hint",
+		"intended_address",
+		"data_remote_address",
+		"address_mismatch",
+		"congestion_control",
+		"congestion_control_mismatch",
+		"worst_burst_loss_percent",
+		"worst_burst_jitter_ms",
+		"confidence",
+		"confidence_reasons",
 		"error_message",
 	}
+	if burstRows {
+		header = append(header, "burst_index")
+	}
+	return header
+}
 
-	if err := writer.Write(header); err != nil {
-		return fmt.Errorf("failed to write CSV header: %w", err)
+// writeCSVResult writes the row of a result, and its burst rows if
+// burstRows is set
+func writeCSVResult(writer *csv.Writer, result *aggregator.TestResult, burstRows bool) error {
+	worstLoss, worstJitter := "", ""
+	if result.WorstBurst != nil {
+		worstLoss = fmt.Sprintf("%.2f", result.WorstBurst.LostPercent)
+		worstJitter = fmt.Sprintf("%.3f", result.WorstBurst.JitterMs)
 	}
 
-	// Write results
-	for _, result := range results {
+	row := []string{
+		result.TestID,
+		result.SourceNode,
+		result.DestNode,
+		result.Group,
+		result.AddressFamily,
+		formatV6Lag(result),
+		fmt.Sprintf("%t", result.Background),
+		fmt.Sprintf("%t", result.Diagnostic),
+		result.Status,
+		fmt.Sprintf("%d", result.StartTime),
+		fmt.Sprintf("%d", result.EndTime),
+		fmt.Sprintf("%d", result.Duration),
+		fmt.Sprintf("%.0f", result.SetupMs),
+		formatBlackoutDelay(result),
+		fmt.Sprintf("%.0f", result.ThroughputBps),
+		units.FormatIn(result.ThroughputBps, units.Mbps, 2),
+		units.FormatIn(result.ThroughputBps, units.Gbps, 4),
+		fmt.Sprintf("%d", result.Retransmits),
+		fmt.Sprintf("%d", result.BytesSent),
+		fmt.Sprintf("%.3f", result.RetransmitsPerMB),
+		fmt.Sprintf("%.2f", result.DiscrepancyPercent),
+		fmt.Sprintf("%d", result.MaxSndCwndBytes),
+		fmt.Sprintf("%.3f", result.MeanRTTMs),
+		result.LimitHint,
+		result.IntendedAddress,
+		result.DataRemoteAddress,
+		fmt.Sprintf("%t", result.AddressMismatch),
+		result.CongestionControl,
+		fmt.Sprintf("%t", result.CongestionControlMismatch),
+		worstLoss,
+		worstJitter,
+		result.Confidence,
+		strings.Join(result.ConfidenceReasons, "; "),
+		result.ErrorMessage,
+	}
+	if burstRows {
+		row = append(row, "")
+	}
+
+	if err := writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
+	}
+
+	if burstRows {
+		if err := writeBurstRows(writer, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatBlackoutDelay formats how much later than scheduled a result's
+// test started for blackout windows, or returns "" if it was not deferred
+func formatBlackoutDelay(result *aggregator.TestResult) string {
+	if result.DeferredByBlackout == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f", result.DeferredByBlackout.DelaySeconds)
+}
+
+// formatV6Lag formats how far an IPv6 result lags its IPv4 counterpart, or
+// returns "" for other results
+func formatV6Lag(result *aggregator.TestResult) string {
+	if result.AddressFamily != string(models.FamilyV6) || result.V6LagPercent == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%.2f", result.V6LagPercent)
+}
+
+// writeBurstRows writes one CSV row per burst of a burst test
+func writeBurstRows(writer *csv.Writer, result *aggregator.TestResult) error {
+	for _, burst := range result.Bursts {
 		row := []string{
 			result.TestID,
 			result.SourceNode,
 			result.DestNode,
+			result.Group,
+			result.AddressFamily,
+			"",
+			fmt.Sprintf("%t", result.Background),
+			fmt.Sprintf("%t", result.Diagnostic),
 			result.Status,
-			fmt.Sprintf("%d", result.StartTime),
-			fmt.Sprintf("%d", result.EndTime),
-			fmt.Sprintf("%d", result.Duration),
-			fmt.Sprintf("%.0f", result.ThroughputBps),
-			fmt.Sprintf("%.2f", result.ThroughputBps/1e6),
-			fmt.Sprintf("%.4f", result.ThroughputBps/1e9),
-			fmt.Sprintf("%d", result.Retransmits),
-			result.ErrorMessage,
+			"",
+			"",
+			"",
+			"",
+			"",
+			fmt.Sprintf("%.0f", burst.ThroughputBps),
+			units.FormatIn(burst.ThroughputBps, units.Mbps, 2),
+			units.FormatIn(burst.ThroughputBps, units.Gbps, 4),
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			"",
+			fmt.Sprintf("%.2f", burst.LostPercent),
+			fmt.Sprintf("%.3f", burst.JitterMs),
+			"",
+			"",
+			"",
+			fmt.Sprintf("%d", burst.Index),
+		}
+
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV burst row: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// withoutBurstRows returns shallow copies of results with per-burst rows removed
+func withoutBurstRows(results []*aggregator.TestResult) []*aggregator.TestResult {
+	stripped := make([]*aggregator.TestResult, 0, len(results))
+	for _, result := range results {
+		if len(result.Bursts) == 0 {
+			stripped = append(stripped, result)
+			continue
+		}
+		copied := *result
+		copied.Bursts = nil
+		stripped = append(stripped, &copied)
+	}
+	return stripped
+}
+
+// WriteTimelineCSV writes the aggregate throughput timeline to its CSV file
+func (w *Writer) WriteTimelineCSV() error {
+	if w.timelineCSVFile == "" || w.timeline == nil {
+		return nil // Timeline CSV output not requested, or nothing to write
+	}
+
+	file, err := w.create(w.TimelineCSVPath())
+	if err != nil {
+		return fmt.Errorf("failed to create timeline CSV file: %w", err)
+	}
+	defer file.Abort()
+
+	writer := csv.NewWriter(file)
+
+	if err := writer.Write([]string{"time", "throughput_bps", "throughput_gbps", "tests"}); err != nil {
+		return fmt.Errorf("failed to write timeline CSV header: %w", err)
+	}
+	for _, bucket := range w.timeline.Buckets {
+		row := []string{
+			strconv.FormatFloat(bucket.Time, 'f', -1, 64),
+			fmt.Sprintf("%.2f", bucket.ThroughputBps),
+			fmt.Sprintf("%.3f", bucket.ThroughputBps/units.Gbps),
+			strconv.Itoa(bucket.Tests),
 		}
+		if err := writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write timeline CSV row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write timeline CSV: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to save timeline CSV file: %w", err)
+	}
+	return nil
+}
+
+// WriteFlakinessCSV writes the per-pair flakiness to its CSV file
+func (w *Writer) WriteFlakinessCSV() error {
+	if w.flakinessCSVFile == "" || w.flakiness == nil {
+		return nil // Flakiness CSV output not requested, or nothing to write
+	}
+
+	file, err := w.create(w.FlakinessCSVPath())
+	if err != nil {
+		return fmt.Errorf("failed to create flakiness CSV file: %w", err)
+	}
+	defer file.Abort()
 
+	writer := csv.NewWriter(file)
+
+	header := []string{"source_node", "dest_node", "address_family", "interface", "profile", "attempts",
+		"failures", "success_ratio", "avg_throughput_bps", "avg_throughput_gbps", "throughput_cv"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write flakiness CSV header: %w", err)
+	}
+	for _, pair := range w.flakiness {
+		row := []string{
+			pair.SourceNode,
+			pair.DestNode,
+			pair.AddressFamily,
+			pair.Interface,
+			pair.Profile,
+			strconv.Itoa(pair.Attempts),
+			strconv.Itoa(pair.Failures),
+			fmt.Sprintf("%.3f", pair.SuccessRatio),
+			fmt.Sprintf("%.2f", pair.AvgThroughputBps),
+			fmt.Sprintf("%.3f", pair.AvgThroughputBps/units.Gbps),
+			fmt.Sprintf("%.4f", pair.ThroughputCV),
+		}
 		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
+			return fmt.Errorf("failed to write flakiness CSV row: %w", err)
 		}
 	}
 
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write flakiness CSV: %w", err)
+	}
+	if err := file.Commit(); err != nil {
+		return fmt.Errorf("failed to save flakiness CSV file: %w", err)
+	}
 	return nil
 }
 
-// WriteAll writes both JSON and CSV outputs
+// WriteAll writes the JSON and CSV outputs, and the schema, timeline and
+// flakiness files if set. Nothing is written if any of the files exists
+// and may not be overwritten. After OpenStream, results are the sample kept
+// of the streamed results.
 func (w *Writer) WriteAll(summary *aggregator.Summary, results []*aggregator.TestResult) error {
+	if err := w.resolvePaths(); err != nil {
+		return err
+	}
+
+	results, filtered := w.filter.Apply(results)
+	if w.stream != nil {
+		filtered = w.stream.filtered // The results are a sample of those streamed
+	}
+	if filtered > 0 {
+		copied := *summary
+		copied.FilteredResults = filtered
+		summary = &copied
+	}
+
+	jsonResults := results
+	if !w.burstRows {
+		jsonResults = withoutBurstRows(results)
+	}
+
 	data := &OutputData{
-		Summary: summary,
-		Results: results,
+		SchemaVersion: SchemaVersion,
+		Metadata:      w.metadata,
+		Summary:       summary,
+		Results:       jsonResults,
+
+		ForeignResults: w.foreign,
+		Config:         w.config,
+
+		CollectionErrors: w.collectionErrors,
+		Timeline:         w.timeline,
+		Flakiness:        w.flakiness,
+		Repetitions:      w.repetitions,
+		ResourceUsage:    w.resourceUsage,
+		Diagnostics:      w.diagnostics,
 	}
 
 	if err := w.WriteJSON(data); err != nil {
 		return fmt.Errorf("failed to write JSON: %w", err)
 	}
 
-	if err := w.WriteCSV(results); err != nil {
-		return fmt.Errorf("failed to write CSV: %w", err)
+	if err := w.WriteSchema(data); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+
+	// A streaming run's CSV rows were written as they were collected
+	if w.stream == nil {
+		if err := w.WriteCSV(results); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	}
+
+	if err := w.WriteTimelineCSV(); err != nil {
+		return fmt.Errorf("failed to write timeline CSV: %w", err)
+	}
+
+	if err := w.WriteFlakinessCSV(); err != nil {
+		return fmt.Errorf("failed to write flakiness CSV: %w", err)
 	}
 
 	return nil
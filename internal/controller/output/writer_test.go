@@ -0,0 +1,209 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+)
+
+func TestWriter_ExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "results.json")
+	csvFile := filepath.Join(dir, "results.csv")
+	if err := os.WriteFile(csvFile, []byte("previous run"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	summary := &aggregator.Summary{}
+
+	// An existing file refuses the write, and nothing is written
+	writer := NewWriter(jsonFile, csvFile)
+	err := writer.WriteAll(summary, nil)
+	if err == nil || !strings.Contains(err.Error(), csvFile) {
+		t.Fatalf("WriteAll() error = %v, want one naming %s", err, csvFile)
+	}
+	if _, err := os.Stat(jsonFile); !os.IsNotExist(err) {
+		t.Errorf("JSON file written despite the refusal")
+	}
+	if err := CheckOutputFiles(jsonFile, csvFile, ""); err == nil {
+		t.Errorf("CheckOutputFiles() error = nil, want the existing CSV file")
+	}
+
+	// A suffix writes beside the existing files, counting past collisions
+	for _, want := range []string{"results-run", "results-run-2"} {
+		writer = NewWriter(jsonFile, csvFile)
+		writer.SetFileSuffix("-run")
+		if err := writer.WriteAll(summary, nil); err != nil {
+			t.Fatalf("WriteAll() with suffix error = %v", err)
+		}
+		if got := writer.JSONPath(); got != filepath.Join(dir, want+".json") {
+			t.Errorf("JSONPath() = %s, want %s.json", got, want)
+		}
+		if got := writer.CSVPath(); got != filepath.Join(dir, want+".csv") {
+			t.Errorf("CSVPath() = %s, want %s.csv", got, want)
+		}
+	}
+
+	// Overwrite replaces the existing file
+	writer = NewWriter(jsonFile, csvFile)
+	writer.SetOverwrite(true)
+	if err := writer.WriteAll(summary, nil); err != nil {
+		t.Fatalf("WriteAll() with overwrite error = %v", err)
+	}
+	data, err := os.ReadFile(csvFile) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "previous run") {
+		t.Errorf("CSV file not replaced")
+	}
+}
+
+func TestWriter_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "results.json")
+	stale := filepath.Join(dir, ".results.json.tmp-123")
+	if err := os.WriteFile(stale, []byte(`{"summary": {`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Temporary files of an interrupted write are cleaned up, and none is
+	// left behind
+	writer := NewWriter(jsonFile, "")
+	if err := writer.WriteAll(&aggregator.Summary{TotalTests: 1}, nil); err != nil {
+		t.Fatalf("WriteAll() error = %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "results.json" {
+		t.Errorf("directory holds %v, want only results.json", entries)
+	}
+	if output, err := ReadJSON(jsonFile); err != nil || output.Summary.TotalTests != 1 {
+		t.Errorf("ReadJSON() = %v, %v; want the written summary", output, err)
+	}
+
+	// A truncated file is reported as incomplete
+	data, err := os.ReadFile(jsonFile) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonFile, data[:len(data)/2], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJSON(jsonFile); err == nil || !strings.Contains(err.Error(), "appears incomplete") {
+		t.Errorf("ReadJSON() of a truncated file error = %v, want it reported as incomplete", err)
+	}
+	if err := os.WriteFile(jsonFile, []byte(`{"summary": {}} trailing`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJSON(jsonFile); err == nil || strings.Contains(err.Error(), "appears incomplete") {
+		t.Errorf("ReadJSON() of a malformed file error = %v, want a parse error", err)
+	}
+}
+
+func TestWriter_Stream(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "results.json")
+	csvFile := filepath.Join(dir, "results.csv")
+	streamFile := filepath.Join(dir, "results.ndjson")
+	filter, err := NewFilter(nil, []string{"status=failed"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writer := NewWriter(jsonFile, csvFile)
+	writer.SetFilter(filter)
+	stream, err := writer.OpenStream(streamFile)
+	if err != nil {
+		t.Fatalf("OpenStream() error = %v", err)
+	}
+	results := []*aggregator.TestResult{
+		{TestID: "t1", Status: "TEST_STATUS_COMPLETED", ThroughputBps: 1e9},
+		{TestID: "t2", Status: "TEST_STATUS_FAILED"},
+		{TestID: "t3", Status: "TEST_STATUS_COMPLETED", ThroughputBps: 2e9},
+	}
+	for _, result := range results {
+		if err := stream.WriteResult(result); err != nil {
+			t.Fatalf("WriteResult() error = %v", err)
+		}
+	}
+	if _, err := os.Stat(streamFile); !os.IsNotExist(err) {
+		t.Errorf("stream file visible before Close()")
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// The JSON output holds the sample; the CSV file is left as streamed
+	if err := writer.WriteAll(&aggregator.Summary{TotalTests: 3}, results[:1]); err != nil {
+		t.Fatalf("WriteAll() error = %v", err)
+	}
+	data, err := os.ReadFile(streamFile) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"test_id":"t3"`) {
+		t.Errorf("stream file = %s, want t1 and t3", data)
+	}
+	data, err = os.ReadFile(csvFile) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := strings.Count(string(data), "\n"); rows != 3 {
+		t.Errorf("CSV file has %d rows, want a header and t1 and t3", rows)
+	}
+	output, err := ReadJSON(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Results) != 1 || output.Summary.FilteredResults != 1 {
+		t.Errorf("JSON output = %d results, %d filtered; want the sample of 1 and 1 filtered",
+			len(output.Results), output.Summary.FilteredResults)
+	}
+}
+
+func TestWriter_Flakiness(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "results.json")
+	flakinessFile := filepath.Join(dir, "flakiness.csv")
+
+	writer := NewWriter(jsonFile, "")
+	writer.SetFlakiness([]*aggregator.PairFlakiness{
+		{SourceNode: "node1", DestNode: "node2", Interface: "eth1", Attempts: 5, Failures: 1, SuccessRatio: 0.8,
+			AvgThroughputBps: 9e9, ThroughputCV: 0.125},
+	}, flakinessFile)
+	if got := writer.FlakinessCSVPath(); got != flakinessFile {
+		t.Errorf("FlakinessCSVPath() = %s, want %s", got, flakinessFile)
+	}
+	if err := writer.WriteAll(&aggregator.Summary{}, nil); err != nil {
+		t.Fatalf("WriteAll() error = %v", err)
+	}
+
+	data, err := os.ReadFile(flakinessFile) // #nosec G304 -- Test file
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "source_node,dest_node,address_family,interface,profile,attempts,failures,success_ratio,avg_throughput_bps,avg_throughput_gbps,throughput_cv\n" +
+		"node1,node2,,eth1,,5,1,0.800,9000000000.00,9.000,0.1250\n"
+	if string(data) != want {
+		t.Errorf("flakiness CSV =\n%s\nwant\n%s", data, want)
+	}
+	output, err := ReadJSON(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Flakiness) != 1 || output.Flakiness[0].Failures != 1 {
+		t.Errorf("JSON flakiness = %v, want the pair written", output.Flakiness)
+	}
+
+	// Without flakiness, nothing is written to its file
+	writer = NewWriter("", "")
+	writer.SetFlakiness(nil, filepath.Join(dir, "none.csv"))
+	if writer.FlakinessCSVPath() != "" {
+		t.Errorf("FlakinessCSVPath() without flakiness = %s, want none", writer.FlakinessCSVPath())
+	}
+}
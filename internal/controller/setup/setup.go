@@ -0,0 +1,282 @@
+// Package setup turns a controller configuration into the node and profile
+// registries and the topology that a run is driven from.
+package setup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+)
+
+// BuildNodeRegistry registers the nodes listed in the configuration
+func BuildNodeRegistry(cfg *config.ControllerConfig) (*models.NodeRegistry, error) {
+	nodeRegistry := models.NewNodeRegistry()
+	nodeRegistry.SetLowercaseTags(cfg.Controller.TagPolicy.Lowercase)
+	for _, nodeConfig := range cfg.Controller.Nodes {
+		blackouts, err := nodeConfig.BlackoutWindows()
+		if err != nil {
+			return nil, fmt.Errorf("node %s: %w", nodeConfig.ID, err)
+		}
+		node := &models.Node{
+			ID:       nodeConfig.ID,
+			Hostname: nodeConfig.Hostname,
+			IP:       nodeConfig.IP,
+			DataIP:   nodeConfig.DataIP,
+			DataIPs:  nodeConfig.DataIPs,
+			Port:     nodeConfig.Port,
+			Tags:     nodeConfig.Tags,
+
+			Blackouts: blackouts,
+		}
+		for _, iface := range nodeConfig.Interfaces {
+			node.Interfaces = append(node.Interfaces, models.NodeInterface{Name: iface.Name, IP: iface.IP})
+		}
+		if err := nodeRegistry.AddNode(node); err != nil {
+			return nil, fmt.Errorf("failed to add node: %w", err)
+		}
+	}
+	return nodeRegistry, nil
+}
+
+// BuildProfileRegistry registers the test profiles defined in the configuration
+func BuildProfileRegistry(cfg *config.ControllerConfig) (*models.ProfileRegistry, error) {
+	profileRegistry := models.NewProfileRegistry()
+	for name, profileConfig := range cfg.Controller.TestProfiles {
+		// Convert protocol string to Protocol type
+		protocol := models.ProtocolTCP // Default to TCP
+		if profileConfig.Protocol == "udp" {
+			protocol = models.ProtocolUDP
+		}
+
+		profile := &models.TestProfile{
+			Name:              name,
+			Duration:          units.Seconds(profileConfig.Duration),
+			Protocol:          protocol,
+			Bandwidth:         profileConfig.Bandwidth,
+			WindowSize:        profileConfig.WindowSize,
+			Parallel:          profileConfig.Parallel,
+			Bidirectional:     profileConfig.Bidirectional,
+			Reverse:           profileConfig.Reverse,
+			SeparateReverse:   profileConfig.SeparateReverse,
+			BufferLength:      profileConfig.BufferLength,
+			CongestionControl: profileConfig.CongestionControl,
+			MSS:               profileConfig.MSS,
+			NoDelay:           profileConfig.NoDelay,
+			TOS:               profileConfig.TOS,
+			ZeroCopy:          profileConfig.ZeroCopy,
+			OmitSeconds:       profileConfig.OmitSeconds,
+			ProcessesPerPair:  profileConfig.ProcessesPerPair,
+			PortsPerPair:      profileConfig.PortsPerPair,
+		}
+		if profileConfig.Burst != nil {
+			profile.Burst = &models.BurstSchedule{
+				OnMs:   profileConfig.Burst.OnMs,
+				OffMs:  profileConfig.Burst.OffMs,
+				Repeat: profileConfig.Burst.Repeat,
+			}
+		}
+		if len(profileConfig.CaptureOn) > 0 {
+			profile.Capture = &models.CapturePolicy{}
+			for _, trigger := range profileConfig.CaptureOn {
+				switch trigger {
+				case "failure":
+					profile.Capture.OnFailure = true
+				case "threshold":
+					belowBps, err := units.ParseBitRate(profileConfig.CaptureThreshold)
+					if err != nil {
+						return nil, fmt.Errorf("profile '%s': capture_threshold: %w", name, err)
+					}
+					profile.Capture.BelowBps = belowBps
+				}
+			}
+		}
+		if err := profileRegistry.AddProfile(profile); err != nil {
+			return nil, fmt.Errorf("failed to add profile: %w", err)
+		}
+	}
+	return profileRegistry, nil
+}
+
+// GenerateTopology generates the test topology described by the
+// configuration, applying overrides, the port range and groups
+func GenerateTopology(cfg *config.ControllerConfig, nodeRegistry *models.NodeRegistry,
+	profileRegistry *models.ProfileRegistry, defaultProfile *models.TestProfile,
+	coverage *topology.Coverage) (*topology.Topology, error) {
+	topoGen := topology.NewGenerator(nodeRegistry, profileRegistry, defaultProfile)
+	if coverage != nil {
+		topoGen.SetRotation(cfg.Controller.Topology.PairsPerRun, coverage)
+	}
+
+	// Apply overrides from config
+	for _, override := range cfg.Controller.Topology.Overrides {
+		for _, pair := range override.Pairs(cfg.Controller.Nodes) {
+			if override.Profile != "" {
+				if overrideErr := topoGen.AddOverride(pair[0], pair[1], override.Profile); overrideErr != nil {
+					return nil, fmt.Errorf("failed to add topology override: %w", overrideErr)
+				}
+			}
+			if override.Background {
+				topoGen.SetBackground(pair[0], pair[1])
+			}
+			if override.Repeat > 0 {
+				topoGen.SetRepeat(pair[0], pair[1], override.Repeat)
+			}
+			if override.Duration > 0 || override.StartDelayMs > 0 {
+				topoGen.SetPairTiming(pair[0], pair[1], topology.PairTiming{
+					Duration:     units.Seconds(override.Duration),
+					StartDelayMs: override.StartDelayMs,
+				})
+			}
+		}
+	}
+
+	for _, exclusion := range cfg.Controller.Topology.Exclusions {
+		for _, pair := range exclusion.Pairs(cfg.Controller.Nodes) {
+			topoGen.Exclude(pair[0], pair[1])
+		}
+	}
+
+	if len(cfg.Controller.Topology.AddressFamilies) > 0 {
+		families := make([]models.AddressFamily, 0, len(cfg.Controller.Topology.AddressFamilies))
+		for _, name := range cfg.Controller.Topology.AddressFamilies {
+			family, err := models.ParseAddressFamily(name)
+			if err != nil {
+				return nil, fmt.Errorf("topology address_families: %w", err)
+			}
+			families = append(families, family)
+		}
+		topoGen.SetAddressFamilies(families)
+	}
+	topoGen.SetPerInterface(cfg.Controller.Topology.PerInterface)
+	topoGen.SetUndirected(!cfg.Controller.Topology.DirectedEnabled())
+
+	if topo := cfg.Controller.Topology; len(topo.SourceTags) > 0 || len(topo.DestinationTags) > 0 {
+		topoGen.SetTagSelection(topo.SourceTags, topo.DestinationTags)
+	}
+
+	if topo := cfg.Controller.Topology; topo.GroupBy != "" {
+		topoGen.SetTagGroups(topo.GroupBy, topo.GroupMode)
+	}
+
+	if topo := &cfg.Controller.Topology; topo.Type == "partial_mesh" {
+		// The seed is kept in cfg, so that regenerating the topology
+		// selects the same pairs
+		if topo.Seed == 0 {
+			topo.Seed = time.Now().UnixNano()
+		}
+		if topo.MaxTests > 0 {
+			topoGen.SetMeshBudget(topo.MaxTests, topo.Seed)
+		} else {
+			topoGen.SetPartialMesh(topo.Density, topo.Seed)
+		}
+	}
+
+	if pr := cfg.Controller.PortRange; pr != nil {
+		topoGen.SetPortRange(int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+
+	for _, group := range cfg.Controller.Topology.Groups {
+		spec := topology.GroupSpec{
+			Name:       group.Name,
+			After:      group.After,
+			Background: group.Background,
+		}
+		for _, selector := range group.Pairs {
+			for _, pair := range selector.Pairs(cfg.Controller.Nodes) {
+				spec.Pairs = append(spec.Pairs, topology.GroupPairSpec{
+					SourceID: pair[0],
+					DestID:   pair[1],
+					Profile:  selector.Profile,
+					Timing:   topology.PairTiming{Duration: units.Seconds(selector.Duration), StartDelayMs: selector.StartDelayMs},
+					Repeat:   selector.Repeat,
+				})
+			}
+		}
+		topoGen.AddGroup(spec)
+	}
+
+	generate := topoGen.GenerateFullMesh
+	if topoGen.HasGroups() {
+		generate = topoGen.GenerateGroups
+	} else if cfg.Controller.Topology.Type == "star" {
+		direction, err := topology.ParseStarDirection(cfg.Controller.Topology.Direction)
+		if err != nil {
+			return nil, err
+		}
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateStar(cfg.Controller.Topology.Hub, direction)
+		}
+	} else if cfg.Controller.Topology.Type == "custom" {
+		specs := make([]topology.PairSpec, 0, len(cfg.Controller.Topology.Pairs))
+		for _, pair := range cfg.Controller.Topology.Pairs {
+			specs = append(specs, topology.PairSpec{
+				SourceID: pair.Source,
+				DestID:   pair.Destination,
+				Profile:  pair.Profile,
+				Repeat:   pair.Repeat,
+			})
+		}
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateCustom(specs)
+		}
+	} else if cfg.Controller.Topology.Type == "one_to_many" {
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateOneToMany(cfg.Controller.Topology.Source)
+		}
+	} else if cfg.Controller.Topology.Type == "many_to_one" {
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateManyToOne(cfg.Controller.Topology.Destination)
+		}
+	} else if cfg.Controller.Topology.Type == "bisection" {
+		sideA, sideB := cfg.Controller.Topology.BisectionSides(cfg.Controller.Nodes)
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateBisection(sideA, sideB, cfg.Controller.Topology.AllowUnbalanced)
+		}
+	} else if cfg.Controller.Topology.Type == "ring" {
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateRing(cfg.Controller.Topology.Order, cfg.Controller.Topology.BidirectionalRing)
+		}
+	} else if cfg.Controller.Topology.Type == "chain" {
+		generate = func() (*topology.Topology, error) {
+			return topoGen.GenerateChain(cfg.Controller.Topology.Order, cfg.Controller.Topology.IncludeEndToEnd)
+		}
+	}
+	topo, err := generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate topology: %w", err)
+	}
+	if cfg.Controller.Topology.Schedule == config.ScheduleRounds {
+		if err := topo.ApplyRounds(); err != nil {
+			return nil, err
+		}
+	}
+
+	return topo, nil
+}
+
+// ImportPlan builds the topology of a plan file in place of generating one,
+// allocating any server ports from the configured port range
+func ImportPlan(cfg *config.ControllerConfig, nodeRegistry *models.NodeRegistry,
+	profileRegistry *models.ProfileRegistry, defaultProfile *models.TestProfile,
+	plan *topology.PlanFile) (*topology.Topology, error) {
+	topoGen := topology.NewGenerator(nodeRegistry, profileRegistry, defaultProfile)
+	if pr := cfg.Controller.PortRange; pr != nil {
+		topoGen.SetPortRange(int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+
+	topo, err := topoGen.ImportPlan(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import plan: %w", err)
+	}
+	if cfg.Controller.Topology.Schedule == config.ScheduleRounds {
+		if err := topo.ApplyRounds(); err != nil {
+			return nil, fmt.Errorf("failed to import plan: %w", err)
+		}
+	}
+
+	return topo, nil
+}
@@ -0,0 +1,93 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// BisectionSplit records the two sides of a bisection topology and the
+// nodes of the larger side left without a partner
+type BisectionSplit struct {
+	SideA    []string
+	SideB    []string
+	Unpaired []string
+}
+
+// GenerateBisection generates a bisection topology: the nth node of sideA
+// sends to the nth node of sideB, all pairs at once, so that together they
+// measure the bandwidth between the two halves. Node IDs not in the
+// registry, such as nodes left out of the run, are skipped. Sides of
+// different sizes are an error unless allowUnbalanced, which leaves the
+// extra nodes of the larger side untested.
+func (g *Generator) GenerateBisection(sideA, sideB []string, allowUnbalanced bool) (*Topology, error) {
+	sources := g.registeredNodes(sideA)
+	destinations := g.registeredNodes(sideB)
+	if len(sources) == 0 || len(destinations) == 0 {
+		return nil, fmt.Errorf("bisection sides have %d and %d nodes; each needs at least one", len(sources), len(destinations))
+	}
+	if len(sources) != len(destinations) && !allowUnbalanced {
+		return nil, fmt.Errorf("bisection sides have %d and %d nodes; set allow_unbalanced to leave the extra nodes untested",
+			len(sources), len(destinations))
+	}
+	onSideA := make(map[string]bool, len(sources))
+	for _, node := range sources {
+		onSideA[node.ID] = true
+	}
+	for _, node := range destinations {
+		if onSideA[node.ID] {
+			return nil, fmt.Errorf("bisection node %q is on both sides", node.ID)
+		}
+	}
+
+	pairs := min(len(sources), len(destinations))
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, pairs),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+		Bisection:   &BisectionSplit{SideA: nodeIDs(sources), SideB: nodeIDs(destinations)},
+	}
+	for _, node := range append(sources[pairs:], destinations[pairs:]...) {
+		topology.Bisection.Unpaired = append(topology.Bisection.Unpaired, node.ID)
+	}
+
+	for i := 0; i < pairs; i++ {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, sources[i], destinations[i])) {
+			topology.Pairs = append(topology.Pairs, pair)
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+		}
+	}
+
+	if err := allocateServerPorts(topology, destinations[:pairs], g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
+
+// registeredNodes returns the nodes of ids in the registry, in order and
+// each once
+func (g *Generator) registeredNodes(ids []string) []*models.Node {
+	nodes := make([]*models.Node, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		node, err := g.nodes.GetNode(id)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// nodeIDs returns the IDs of nodes, in order
+func nodeIDs(nodes []*models.Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateBisection(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 5, profile)
+	gen := NewGenerator(nodes, profiles, profile)
+
+	// Unknown IDs, such as nodes left out of the run, are skipped
+	topo, err := gen.GenerateBisection([]string{"node1", "node2", "node9"}, []string{"node4", "node3"}, false)
+	if err != nil {
+		t.Fatalf("GenerateBisection() error = %v", err)
+	}
+	pairs := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID)
+	}
+	if got, want := strings.Join(pairs, ","), "node1>node4,node2>node3"; got != want {
+		t.Errorf("pairs = %s, want %s", got, want)
+	}
+	if len(topo.ServerPorts) != 2 || len(topo.ServerPorts["node4"]) != 1 || len(topo.ServerPorts["node3"]) != 1 {
+		t.Errorf("ServerPorts = %v, want one port on each of side_b", topo.ServerPorts)
+	}
+	if topo.Bisection == nil || len(topo.Bisection.Unpaired) != 0 {
+		t.Errorf("Bisection = %+v, want the sides and no unpaired nodes", topo.Bisection)
+	}
+
+	if _, err := gen.GenerateBisection([]string{"node1", "node2", "node5"}, []string{"node3", "node4"}, false); err == nil ||
+		!strings.Contains(err.Error(), "allow_unbalanced") {
+		t.Errorf("GenerateBisection() unbalanced error = %v, want allow_unbalanced hint", err)
+	}
+	topo, err = gen.GenerateBisection([]string{"node1", "node2", "node5"}, []string{"node3", "node4"}, true)
+	if err != nil {
+		t.Fatalf("GenerateBisection() unbalanced error = %v", err)
+	}
+	if topo.GetTestCount() != 2 || strings.Join(topo.Bisection.Unpaired, ",") != "node5" {
+		t.Errorf("%d tests, unpaired %v; want 2 tests and node5 unpaired", topo.GetTestCount(), topo.Bisection.Unpaired)
+	}
+
+	if _, err := gen.GenerateBisection([]string{"node1"}, []string{"node1"}, false); err == nil {
+		t.Error("GenerateBisection() with a node on both sides succeeded, want error")
+	}
+}
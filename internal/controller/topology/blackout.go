@@ -0,0 +1,81 @@
+package topology
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// BlackoutImpact is how blackout windows of its nodes hold back a pair
+type BlackoutImpact struct {
+	Pair    *TestPair
+	Delay   time.Duration // How long the pair waits for the windows to end
+	Nodes   []string      // Nodes whose windows it waits for
+	Skipped bool          // The wait is longer than allowed, so the pair is skipped
+}
+
+// ClearOfBlackouts returns the earliest time at or after start from which
+// the pair runs without overlapping a blackout window of its nodes, and the
+// nodes whose windows it waits for. ok is false if that is more than limit
+// after start.
+func (p *TestPair) ClearOfBlackouts(start time.Time, limit time.Duration) (clear time.Time, nodes []string, ok bool) {
+	run := p.Schedule() - time.Duration(p.StartDelayMs)*time.Millisecond
+	clear = start
+	for {
+		moved := false
+		for _, node := range []*models.Node{p.Source, p.Destination} {
+			for _, window := range node.Blackouts {
+				if end, overlaps := window.Overlap(clear, clear.Add(run)); overlaps {
+					clear = end
+					moved = true
+					nodes = addNode(nodes, node.ID)
+				}
+			}
+		}
+		if !moved {
+			return clear, nodes, true
+		}
+		if clear.Sub(start) > limit {
+			return clear, nodes, false
+		}
+	}
+}
+
+// addNode adds nodeID to the sorted nodes unless it is there
+func addNode(nodes []string, nodeID string) []string {
+	i := sort.SearchStrings(nodes, nodeID)
+	if i < len(nodes) && nodes[i] == nodeID {
+		return nodes
+	}
+	nodes = append(nodes, "")
+	copy(nodes[i+1:], nodes[i:])
+	nodes[i] = nodeID
+	return nodes
+}
+
+// BlackoutImpact returns the pairs that blackout windows of their nodes
+// would hold back if the run started at start, in test ID order. Pairs
+// that would wait longer than limit are skipped. Waves and groups, which
+// start pairs later, are not accounted for.
+func (t *Topology) BlackoutImpact(start time.Time, limit time.Duration) []*BlackoutImpact {
+	impact := make([]*BlackoutImpact, 0)
+	for _, pair := range t.Pairs {
+		if len(pair.Source.Blackouts) == 0 && len(pair.Destination.Blackouts) == 0 {
+			continue
+		}
+		pairStart := start.Add(time.Duration(pair.StartDelayMs) * time.Millisecond)
+		clear, nodes, ok := pair.ClearOfBlackouts(pairStart, limit)
+		if len(nodes) == 0 {
+			continue
+		}
+		impact = append(impact, &BlackoutImpact{
+			Pair:    pair,
+			Delay:   clear.Sub(pairStart),
+			Nodes:   nodes,
+			Skipped: !ok,
+		})
+	}
+	sort.Slice(impact, func(i, j int) bool { return impact[i].Pair.TestID < impact[j].Pair.TestID })
+	return impact
+}
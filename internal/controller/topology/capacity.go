@@ -0,0 +1,40 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeCapacity is what a node's daemon has free for a topology
+type NodeCapacity struct {
+	Slots int          // Free process slots
+	Ports PortCapacity // Zero when the daemon does not report its ports
+}
+
+// Validate checks the topology against the capacity of each node's daemon
+// before anything is started: the process slots its load needs there, and
+// the server ports of the pairs it receives. Nodes missing from capacities
+// are not checked. The error names every node short of slots or ports, such
+// as "node7 needs 240 server ports, daemon range 5201-5400 provides 200 (40
+// short)".
+func (t *Topology) Validate(capacities map[string]NodeCapacity) error {
+	slots := make(map[string]int, len(capacities))
+	ports := make(map[string]PortCapacity, len(capacities))
+	for nodeID, capacity := range capacities {
+		slots[nodeID] = capacity.Slots
+		if capacity.Ports.Total > 0 {
+			ports[nodeID] = capacity.Ports
+		}
+	}
+
+	short := append(t.slotShortfalls(slots), t.portShortfalls(ports)...)
+	if len(short) == 0 {
+		return nil
+	}
+	sort.Strings(short)
+
+	return fmt.Errorf("topology exceeds node capacity (bidirectional tests count twice): %s; "+
+		"test fewer pairs per node at once with groups or a rotating topology, or increase the daemons' port_range",
+		strings.Join(short, "; "))
+}
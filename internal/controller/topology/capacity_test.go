@@ -0,0 +1,51 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestTopology_Validate(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// Each node sends 3 and receives 3 processes, on 3 server ports
+	ports := PortCapacity{Start: 5201, End: 5400, Total: 200, Available: 200}
+	capacities := map[string]NodeCapacity{
+		"node1": {Slots: 6, Ports: ports},
+		"node2": {Slots: 4, Ports: ports},
+		"node3": {Slots: 10, Ports: PortCapacity{Start: 5201, End: 5202, Total: 2, Available: 2}},
+		"node4": {Slots: 2}, // Ports not reported
+	}
+	err = topo.Validate(capacities)
+	if err == nil {
+		t.Fatal("Validate() expected error")
+	}
+	for _, want := range []string{
+		"node2 needs 6 process slots, has 4 available (2 short)",
+		"node3 needs 3 server ports, daemon range 5201-5202 provides 2 (1 short)",
+		"node4 needs 6 process slots, has 2 available (4 short)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "node1") || strings.Contains(err.Error(), "node4 needs 3 server ports") {
+		t.Errorf("Validate() error = %v, want node1 and node4's ports not reported", err)
+	}
+
+	delete(capacities, "node2")
+	delete(capacities, "node3")
+	delete(capacities, "node4")
+	if err := topo.Validate(capacities); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// ChainNodes returns the nodes of a chain in order. Unlike a ring, a chain
+// is only the nodes listed, at least 2, each once.
+func ChainNodes(registry *models.NodeRegistry, order []string) ([]*models.Node, error) {
+	if len(order) < 2 {
+		return nil, fmt.Errorf("at least 2 nodes required for chain topology")
+	}
+
+	nodes := make([]*models.Node, 0, len(order))
+	listed := make(map[string]bool, len(order))
+	for _, id := range order {
+		if listed[id] {
+			return nil, fmt.Errorf("chain order lists node %q more than once", id)
+		}
+		node, err := registry.GetNode(id)
+		if err != nil {
+			return nil, fmt.Errorf("chain order: %w", err)
+		}
+		listed[id] = true
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// GenerateChain generates a chain topology, such as routed sites A-B-C-D,
+// testing each hop between adjacent nodes both ways and, with endToEnd, the
+// first node sending to the last across every hop. Pairs record the hop
+// they test, numbered from 1; the end-to-end pair has Hop 0 and EndToEnd
+// set. A chain of 2 nodes has no end-to-end pair besides its only hop.
+func (g *Generator) GenerateChain(order []string, endToEnd bool) (*Topology, error) {
+	nodes, err := ChainNodes(g.nodes, order)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, 2*len(nodes)-1),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	number := 0
+	add := func(source, dest *models.Node, hop int) {
+		number++
+		pair := g.newPair(number, source, dest)
+		pair.Hop = hop
+		pair.EndToEnd = hop == 0
+		for _, expanded := range g.expandPair(topology, pair) {
+			topology.Pairs = append(topology.Pairs, expanded)
+			topology.ClientTests[expanded.Source.ID] = append(topology.ClientTests[expanded.Source.ID], expanded)
+		}
+	}
+	for i := 0; i < len(nodes)-1; i++ {
+		add(nodes[i], nodes[i+1], i+1)
+		add(nodes[i+1], nodes[i], i+1)
+	}
+	if endToEnd && len(nodes) > 2 {
+		add(nodes[0], nodes[len(nodes)-1], 0)
+	}
+
+	// Inner nodes receive from both neighbors, the ends from one, and the
+	// last node from the first too with endToEnd
+	if err := allocateServerPorts(topology, nodes, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
@@ -0,0 +1,76 @@
+package topology
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateChain(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+
+	tests := []struct {
+		name      string
+		order     []string
+		endToEnd  bool
+		wantPairs string // source>destination:hop
+		wantPorts map[string]int
+	}{
+		{"hops", []string{"node1", "node2", "node3"}, false,
+			"node1>node2:1,node2>node1:1,node2>node3:2,node3>node2:2",
+			map[string]int{"node1": 1, "node2": 2, "node3": 1}},
+		{"end to end", []string{"node4", "node2", "node1"}, true,
+			"node4>node2:1,node2>node4:1,node2>node1:2,node1>node2:2,node4>node1:0",
+			map[string]int{"node4": 1, "node2": 2, "node1": 2}},
+		{"two nodes", []string{"node2", "node1"}, true,
+			"node2>node1:1,node1>node2:1",
+			map[string]int{"node1": 1, "node2": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, profiles := newTestRegistries(t, 4, profile)
+			topo, err := NewGenerator(nodes, profiles, profile).GenerateChain(tt.order, tt.endToEnd)
+			if err != nil {
+				t.Fatalf("GenerateChain() error = %v", err)
+			}
+
+			pairs := make([]string, 0, len(topo.Pairs))
+			for _, pair := range topo.Pairs {
+				pairs = append(pairs, fmt.Sprintf("%s>%s:%d", pair.Source.ID, pair.Destination.ID, pair.Hop))
+				if pair.EndToEnd != (pair.Hop == 0) {
+					t.Errorf("pair %s: EndToEnd = %t at hop %d", pair.TestID, pair.EndToEnd, pair.Hop)
+				}
+			}
+			if got := strings.Join(pairs, ","); got != tt.wantPairs {
+				t.Errorf("pairs = %s, want %s", got, tt.wantPairs)
+			}
+
+			for _, node := range nodes.GetAllNodes() {
+				if got := len(topo.ServerPorts[node.ID]); got != tt.wantPorts[node.ID] {
+					t.Errorf("ServerPorts[%s] has %d ports, want %d", node.ID, got, tt.wantPorts[node.ID])
+				}
+			}
+			if hops := topo.TestHops(); len(hops) != len(topo.Pairs) {
+				t.Errorf("TestHops() has %d tests, want %d", len(hops), len(topo.Pairs))
+			}
+			if _, err := GenerateNodeTopologies(topo); err != nil {
+				t.Errorf("GenerateNodeTopologies() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestChainNodes_InvalidOrder(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, _ := newTestRegistries(t, 3, profile)
+
+	for _, order := range [][]string{{"node1"}, {"node1", "node2", "node1"}, {"node1", "node9"}} {
+		if _, err := ChainNodes(nodes, order); err == nil {
+			t.Errorf("ChainNodes(%v) succeeded, want error", order)
+		}
+	}
+}
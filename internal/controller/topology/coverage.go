@@ -0,0 +1,140 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Coverage records when each node pair was last measured, so that rotating
+// topologies can test the pairs that have gone longest without measurement
+type Coverage struct {
+	path  string
+	pairs map[string]time.Time // nodePairKey -> last measured
+}
+
+// coverageFile is the on-disk format of the coverage state
+type coverageFile struct {
+	Pairs map[string]time.Time `json:"pairs"`
+}
+
+// CoverageStats summarizes how long ago a set of pairs was last measured
+type CoverageStats struct {
+	Pairs       int
+	NeverTested int
+	// Ages of the pairs that were measured before
+	Oldest time.Duration
+	Median time.Duration
+	Newest time.Duration
+}
+
+// LoadCoverage reads the coverage state at path. A missing file yields an
+// empty state, in which every pair is untested.
+func LoadCoverage(path string) (*Coverage, error) {
+	coverage := &Coverage{
+		path:  path,
+		pairs: make(map[string]time.Time),
+	}
+
+	data, err := os.ReadFile(path) // #nosec G304 -- Coverage path is from config
+	if err != nil {
+		if os.IsNotExist(err) {
+			return coverage, nil
+		}
+		return nil, fmt.Errorf("failed to read coverage file: %w", err)
+	}
+
+	var file coverageFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse coverage file %s: %w", path, err)
+	}
+	for key, at := range file.Pairs {
+		coverage.pairs[key] = at
+	}
+
+	return coverage, nil
+}
+
+// LastTested returns when the pair was last measured, or the zero time if
+// it never was
+func (c *Coverage) LastTested(sourceID, destID string) time.Time {
+	return c.pairs[fmt.Sprintf("%s:%s", sourceID, destID)]
+}
+
+// Record marks the pair as measured at the given time
+func (c *Coverage) Record(sourceID, destID string, at time.Time) {
+	c.pairs[fmt.Sprintf("%s:%s", sourceID, destID)] = at.UTC()
+}
+
+// Save writes the coverage state back to its file
+func (c *Coverage) Save() error {
+	data, err := json.MarshalIndent(coverageFile{Pairs: c.pairs}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode coverage: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return fmt.Errorf("failed to create coverage directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write coverage file: %w", err)
+	}
+	return nil
+}
+
+// Stats summarizes the coverage of the given pairs, each a source and
+// destination node ID, as of now
+func (c *Coverage) Stats(pairs [][2]string, now time.Time) CoverageStats {
+	stats := CoverageStats{Pairs: len(pairs)}
+
+	ages := make([]time.Duration, 0, len(pairs))
+	for _, pair := range pairs {
+		last := c.LastTested(pair[0], pair[1])
+		if last.IsZero() {
+			stats.NeverTested++
+			continue
+		}
+		ages = append(ages, now.Sub(last))
+	}
+	if len(ages) == 0 {
+		return stats
+	}
+
+	sort.Slice(ages, func(i, j int) bool { return ages[i] < ages[j] })
+	stats.Newest = ages[0]
+	stats.Median = ages[len(ages)/2]
+	stats.Oldest = ages[len(ages)-1]
+	return stats
+}
+
+// Stale returns the pairs not measured within maxAge of now, including those
+// never measured, stalest first
+func (c *Coverage) Stale(pairs [][2]string, now time.Time, maxAge time.Duration) [][2]string {
+	stale := make([][2]string, 0)
+	for _, pair := range pairs {
+		last := c.LastTested(pair[0], pair[1])
+		if last.IsZero() || now.Sub(last) > maxAge {
+			stale = append(stale, pair)
+		}
+	}
+	c.sortStalest(stale)
+	return stale
+}
+
+// sortStalest orders pairs by when they were last measured, never measured
+// first, breaking ties by node IDs so that selection is deterministic
+func (c *Coverage) sortStalest(pairs [][2]string) {
+	sort.SliceStable(pairs, func(i, j int) bool {
+		li := c.LastTested(pairs[i][0], pairs[i][1])
+		lj := c.LastTested(pairs[j][0], pairs[j][1])
+		if !li.Equal(lj) {
+			return li.Before(lj)
+		}
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+}
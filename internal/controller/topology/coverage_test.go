@@ -0,0 +1,77 @@
+package topology
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_Rotation(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	coverage, err := LoadCoverage(filepath.Join(t.TempDir(), "coverage.json"))
+	if err != nil {
+		t.Fatalf("LoadCoverage() error = %v", err)
+	}
+
+	// 6 mesh pairs at 4 per run are all covered within 2 runs
+	tested := make(map[string]bool)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for run := 0; run < 2; run++ {
+		gen := NewGenerator(nodes, profiles, profile)
+		gen.SetRotation(4, coverage)
+		topo, err := gen.GenerateFullMesh()
+		if err != nil {
+			t.Fatalf("run %d: GenerateFullMesh() error = %v", run, err)
+		}
+		if topo.GetTestCount() != 4 {
+			t.Fatalf("run %d: GetTestCount() = %d, want 4", run, topo.GetTestCount())
+		}
+
+		now = now.Add(time.Hour)
+		for _, pair := range topo.Pairs {
+			tested[pair.Source.ID+":"+pair.Destination.ID] = true
+			coverage.Record(pair.Source.ID, pair.Destination.ID, now)
+		}
+	}
+
+	if len(tested) != 6 {
+		t.Errorf("tested %d distinct pairs in 2 runs, want 6", len(tested))
+	}
+
+	stats := coverage.Stats(MeshPairs(nodes), now)
+	if stats.NeverTested != 0 || stats.Oldest != time.Hour || stats.Newest != 0 {
+		t.Errorf("Stats() = %+v, want all tested within the last hour", stats)
+	}
+}
+
+func TestCoverage_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "coverage.json")
+	coverage, err := LoadCoverage(path)
+	if err != nil {
+		t.Fatalf("LoadCoverage() error = %v", err)
+	}
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	coverage.Record("node1", "node2", at)
+	if err := coverage.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadCoverage(path)
+	if err != nil {
+		t.Fatalf("LoadCoverage() error = %v", err)
+	}
+	if got := loaded.LastTested("node1", "node2"); !got.Equal(at) {
+		t.Errorf("LastTested() = %v, want %v", got, at)
+	}
+
+	pairs := [][2]string{{"node1", "node2"}, {"node2", "node1"}}
+	stale := loaded.Stale(pairs, at.Add(2*time.Hour), time.Hour)
+	if len(stale) != 2 || stale[0] != pairs[1] {
+		t.Errorf("Stale() = %v, want the never measured pair first", stale)
+	}
+}
@@ -0,0 +1,77 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// PairSpec is one pair of a custom topology, with an optional profile that
+// takes precedence over the generator's overrides
+type PairSpec struct {
+	SourceID string
+	DestID   string
+	Profile  string
+	Repeat   int // Times the pair runs, taking precedence over the generator's
+}
+
+// GenerateCustom generates a topology of exactly the given pairs, in order.
+// Server ports are only allocated on nodes that receive connections.
+func (g *Generator) GenerateCustom(specs []PairSpec) (*Topology, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("custom topology requires at least one pair")
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(specs)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	receivers := make(map[string]bool)
+	for i, spec := range specs {
+		source, err := g.nodes.GetNode(spec.SourceID)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %w", i+1, err)
+		}
+		dest, err := g.nodes.GetNode(spec.DestID)
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %w", i+1, err)
+		}
+		if source.ID == dest.ID {
+			return nil, fmt.Errorf("pair %d: source and destination must differ (%s)", i+1, source.ID)
+		}
+
+		pair := g.newPair(i+1, source, dest)
+		if spec.Profile != "" {
+			profile, err := g.profiles.GetProfile(spec.Profile)
+			if err != nil {
+				return nil, fmt.Errorf("pair %d: %w", i+1, err)
+			}
+			pair.Profile = profile
+		}
+		if spec.Repeat > 0 {
+			pair.Repeat = spec.Repeat
+		}
+
+		for _, familyPair := range g.expandPair(topology, pair) {
+			topology.Pairs = append(topology.Pairs, familyPair)
+			topology.ClientTests[familyPair.Source.ID] = append(topology.ClientTests[familyPair.Source.ID], familyPair)
+			receivers[familyPair.Destination.ID] = true
+		}
+	}
+
+	destinations := make([]*models.Node, 0, len(receivers))
+	for _, node := range g.nodes.GetAllNodes() {
+		if receivers[node.ID] {
+			destinations = append(destinations, node)
+		}
+	}
+	if err := allocateServerPorts(topology, destinations, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
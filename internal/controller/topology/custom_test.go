@@ -0,0 +1,87 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateCustom(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	highbw := &models.TestProfile{Name: "highbw", Duration: 30 * time.Second, Parallel: 8}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+	if err := profiles.AddProfile(highbw); err != nil {
+		t.Fatal(err)
+	}
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateCustom([]PairSpec{
+		{SourceID: "node1", DestID: "node3", Profile: "highbw"},
+		{SourceID: "node2", DestID: "node3"},
+		{SourceID: "node3", DestID: "node1"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateCustom() error = %v", err)
+	}
+
+	pairs := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID+":"+pair.Profile.Name)
+	}
+	if got, want := strings.Join(pairs, ","), "node1>node3:highbw,node2>node3:default,node3>node1:default"; got != want {
+		t.Errorf("pairs = %s, want %s", got, want)
+	}
+
+	// Only destinations get server ports
+	wantPorts := map[string]int{"node1": 1, "node3": 2}
+	if len(topo.ServerPorts) != len(wantPorts) {
+		t.Errorf("ServerPorts has %d nodes, want %d", len(topo.ServerPorts), len(wantPorts))
+	}
+	for nodeID, want := range wantPorts {
+		if got := len(topo.ServerPorts[nodeID]); got != want {
+			t.Errorf("ServerPorts[%s] has %d ports, want %d", nodeID, got, want)
+		}
+	}
+	if _, err := GenerateNodeTopologies(topo); err != nil {
+		t.Errorf("GenerateNodeTopologies() error = %v", err)
+	}
+
+	for _, specs := range [][]PairSpec{
+		nil,
+		{{SourceID: "node1", DestID: "node9"}},
+		{{SourceID: "node1", DestID: "node1"}},
+		{{SourceID: "node1", DestID: "node2", Profile: "missing"}},
+	} {
+		if _, err := NewGenerator(nodes, profiles, profile).GenerateCustom(specs); err == nil {
+			t.Errorf("GenerateCustom(%v) succeeded, want error", specs)
+		}
+	}
+}
+
+func TestGenerator_GenerateCustomRepeat(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetRepeat("node1", "node2", 3)
+	gen.SetRepeat("node2", "node3", 2)
+	topo, err := gen.GenerateCustom([]PairSpec{
+		{SourceID: "node1", DestID: "node2", Repeat: 2}, // Takes precedence over the override
+		{SourceID: "node2", DestID: "node3"},
+		{SourceID: "node3", DestID: "node1"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateCustom() error = %v", err)
+	}
+
+	ids := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		ids = append(ids, pair.TestID)
+	}
+	want := "test-1-node1-to-node2-rep1,test-1-node1-to-node2-rep2," +
+		"test-2-node2-to-node3-rep1,test-2-node2-to-node3-rep2,test-3-node3-to-node1"
+	if got := strings.Join(ids, ","); got != want {
+		t.Errorf("test IDs = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package topology
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExportDOT writes the topology as a Graphviz digraph: a node per test node,
+// labelled with the server ports allocated on it, and an edge per pair,
+// labelled with its profile and the ports it connects to. Background pairs
+// are dashed.
+func (t *Topology) ExportDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph topology {\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	ports := t.destinationPorts()
+	for _, nodeID := range t.pairNodeIDs() {
+		label := nodeID
+		if len(ports[nodeID]) > 0 {
+			label += "\nports " + FormatPorts(ports[nodeID])
+		}
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", dotQuote(nodeID), dotQuote(label))
+	}
+
+	for _, pair := range t.Pairs {
+		label := strings.Join(pairDetails(pair), "\n")
+		style := ""
+		if pair.Background {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s%s];\n",
+			dotQuote(pair.Source.ID), dotQuote(pair.Destination.ID), dotQuote(label), style)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WritePairList writes the topology as plain text: a line per pair with its
+// profile and ports, in run order, then the server ports of each node
+func (t *Topology) WritePairList(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Pairs (%d):\n", len(t.Pairs))
+	for _, pair := range t.Pairs {
+		fmt.Fprintf(&b, "  %s: %s -> %s, %s\n", pair.TestID, pair.Source.ID, pair.Destination.ID,
+			strings.Join(pairDetails(pair), ", "))
+	}
+
+	ports := t.destinationPorts()
+	b.WriteString("Server ports:\n")
+	for _, nodeID := range t.pairNodeIDs() {
+		if len(ports[nodeID]) > 0 {
+			fmt.Fprintf(&b, "  %s: %s\n", nodeID, FormatPorts(ports[nodeID]))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// pairDetails describes a pair's profile, ports, group, family and whether
+// it is background load
+func pairDetails(pair *TestPair) []string {
+	details := []string{"profile " + pair.Profile.Name}
+	if len(pair.Ports) > 0 {
+		details = append(details, "ports "+FormatPorts(pair.Ports))
+	}
+	if pair.Group != "" {
+		details = append(details, "group "+pair.Group)
+	}
+	if pair.Family != "" {
+		details = append(details, string(pair.Family))
+	}
+	if pair.Background {
+		details = append(details, "background")
+	}
+	return details
+}
+
+// destinationPorts returns the server ports of the pairs by destination
+// node. They are taken from the pairs, which carry them for every kind of
+// topology.
+func (t *Topology) destinationPorts() map[string][]int32 {
+	ports := make(map[string][]int32)
+	for _, pair := range t.Pairs {
+		ports[pair.Destination.ID] = append(ports[pair.Destination.ID], pair.Ports...)
+	}
+	return ports
+}
+
+// pairNodeIDs returns the IDs of the nodes of the pairs, sorted
+func (t *Topology) pairNodeIDs() []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, pair := range t.Pairs {
+		for _, id := range []string{pair.Source.ID, pair.Destination.ID} {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// FormatPorts formats ports sorted and each once, with consecutive ports as
+// ranges, such as "5201-5203,5210"
+func FormatPorts(ports []int32) string {
+	sorted := make([]int32, 0, len(ports))
+	seen := make(map[int32]bool, len(ports))
+	for _, port := range ports {
+		if !seen[port] {
+			seen[port] = true
+			sorted = append(sorted, port)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	ranges := make([]string, 0)
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j > i {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d", sorted[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// dotQuote quotes s as a DOT string. Newlines become DOT's centered line
+// breaks.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
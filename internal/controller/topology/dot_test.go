@@ -0,0 +1,88 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func newDOTTopology(t *testing.T) *Topology {
+	t.Helper()
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetBackground("node3", "node1")
+	topo, err := gen.GenerateRing([]string{"node1", "node2", "node3"}, false)
+	if err != nil {
+		t.Fatalf("GenerateRing() error = %v", err)
+	}
+	return topo
+}
+
+func TestTopology_ExportDOT(t *testing.T) {
+	topo := newDOTTopology(t)
+
+	var b strings.Builder
+	if err := topo.ExportDOT(&b); err != nil {
+		t.Fatalf("ExportDOT() error = %v", err)
+	}
+	want := `digraph topology {
+	node [shape=box];
+	"node1" [label="node1\nports 5201"];
+	"node2" [label="node2\nports 5202"];
+	"node3" [label="node3\nports 5203"];
+	"node1" -> "node2" [label="profile default\nports 5202"];
+	"node2" -> "node3" [label="profile default\nports 5203"];
+	"node3" -> "node1" [label="profile default\nports 5201\nbackground", style=dashed];
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("ExportDOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestTopology_WritePairList(t *testing.T) {
+	topo := newDOTTopology(t)
+
+	var b strings.Builder
+	if err := topo.WritePairList(&b); err != nil {
+		t.Fatalf("WritePairList() error = %v", err)
+	}
+	got := b.String()
+	for _, line := range []string{
+		"Pairs (3):\n",
+		"test-1-node1-to-node2: node1 -> node2, profile default, ports 5202\n",
+		"test-3-node3-to-node1: node3 -> node1, profile default, ports 5201, background\n",
+		"Server ports:\n  node1: 5201\n  node2: 5202\n  node3: 5203\n",
+	} {
+		if !strings.Contains(got, line) {
+			t.Errorf("WritePairList() =\n%s\nmissing %q", got, line)
+		}
+	}
+}
+
+func TestFormatPorts(t *testing.T) {
+	tests := []struct {
+		ports []int32
+		want  string
+	}{
+		{nil, ""},
+		{[]int32{5201}, "5201"},
+		{[]int32{5203, 5201, 5202, 5210}, "5201-5203,5210"},
+		{[]int32{5201, 5201, 5202, 5205, 5206}, "5201-5202,5205-5206"},
+	}
+	for _, tt := range tests {
+		if got := FormatPorts(tt.ports); got != tt.want {
+			t.Errorf("FormatPorts(%v) = %q, want %q", tt.ports, got, tt.want)
+		}
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	if got, want := dotQuote("a \"b\"\\c\nd"), `"a \"b\"\\c\nd"`; got != want {
+		t.Errorf("dotQuote() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// GenerateOneToMany generates a fan-out topology of pairs from the source
+// node to every other node at once, measuring the source's aggregate send
+// rate. Each destination gets the server ports of its one pair.
+func (g *Generator) GenerateOneToMany(sourceID string) (*Topology, error) {
+	source, err := g.nodes.GetNode(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("one_to_many source: %w", err)
+	}
+
+	destinations := make([]*models.Node, 0, g.nodes.Count())
+	for _, node := range g.nodes.GetAllNodes() {
+		if node.ID != source.ID {
+			destinations = append(destinations, node)
+		}
+	}
+	if len(destinations) == 0 {
+		return nil, fmt.Errorf("one_to_many topology needs at least one node besides source %q", sourceID)
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(destinations)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	receivers := make(map[string]bool)
+	for i, dest := range destinations {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, source, dest)) {
+			topology.Pairs = append(topology.Pairs, pair)
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+			receivers[dest.ID] = true
+		}
+	}
+
+	receiving := make([]*models.Node, 0, len(receivers))
+	for _, dest := range destinations {
+		if receivers[dest.ID] {
+			receiving = append(receiving, dest)
+		}
+	}
+	if err := allocateServerPorts(topology, receiving, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
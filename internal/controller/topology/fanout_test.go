@@ -0,0 +1,47 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateOneToMany(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 4}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateOneToMany("node2")
+	if err != nil {
+		t.Fatalf("GenerateOneToMany() error = %v", err)
+	}
+
+	pairs := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID)
+	}
+	if got, want := strings.Join(pairs, ","), "node2>node1,node2>node3,node2>node4"; got != want {
+		t.Errorf("pairs = %s, want %s", got, want)
+	}
+	if got := len(topo.ClientTests["node2"]); got != 3 {
+		t.Errorf("source runs %d clients, want 3", got)
+	}
+
+	// Parallel streams share one server port, so each destination gets one
+	if len(topo.ServerPorts) != 3 {
+		t.Errorf("ServerPorts has %d nodes, want 3", len(topo.ServerPorts))
+	}
+	for _, nodeID := range []string{"node1", "node3", "node4"} {
+		if got := len(topo.ServerPorts[nodeID]); got != 1 {
+			t.Errorf("ServerPorts[%s] has %d ports, want 1", nodeID, got)
+		}
+	}
+	if _, err := GenerateNodeTopologies(topo); err != nil {
+		t.Errorf("GenerateNodeTopologies() error = %v", err)
+	}
+
+	if _, err := NewGenerator(nodes, profiles, profile).GenerateOneToMany("node9"); err == nil {
+		t.Error("GenerateOneToMany() with an unknown source succeeded, want error")
+	}
+}
@@ -2,6 +2,9 @@ package topology
 
 import (
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
 	"github.com/bensons/iperf-cnc/internal/common/models"
@@ -13,6 +16,96 @@ type TestPair struct {
 	Source      *models.Node
 	Destination *models.Node
 	Profile     *models.TestProfile
+	Ports       []int32 // Server ports on the destination, one per process
+	Group       string  // Group the pair belongs to, if the topology has groups
+	// Family is the address family the pair runs over, set when the
+	// topology runs pairs once per family
+	Family models.AddressFamily
+	// Interface is the name of the interface of both nodes the pair runs
+	// over, set when the topology runs pairs once per interface
+	Interface string
+	// Hop is the hop of a chain the pair tests, numbered from 1 between the
+	// first two nodes. The end-to-end pair of a chain, across every hop,
+	// has Hop 0 and EndToEnd set.
+	Hop      int
+	EndToEnd bool
+	// Background pairs generate load for the whole run and are not measured
+	Background bool
+	// Repeat is how many times the pair runs, each as its own test. Each
+	// run is numbered from 1 by Repetition, and RepeatOf is the test ID of
+	// the pair without the "-repN" suffix of its runs.
+	Repeat     int
+	Repetition int
+	RepeatOf   string
+	// Labels and Params are the labels and profile parameters of a pair
+	// imported from a plan file; Profile already has Params applied
+	Labels map[string]string
+	Params *PlanParams
+
+	// Per-pair timing; zero values fall back to the profile
+	Duration     time.Duration // Overrides the profile duration when > 0
+	StartDelayMs int           // Delay before the client starts
+
+	// BlackoutDelay is how much later the pair started than scheduled, to
+	// stay clear of blackout windows of BlackoutNodes
+	BlackoutDelay time.Duration
+	BlackoutNodes []string
+}
+
+// Schedule returns how long after the client start request the test is
+// expected to finish, including its start delay
+func (p *TestPair) Schedule() time.Duration {
+	schedule := p.Profile.Schedule()
+	if p.Duration > 0 && p.Profile.Burst == nil {
+		schedule = p.Duration
+	}
+	return schedule + time.Duration(p.StartDelayMs)*time.Millisecond
+}
+
+// DestinationAddress returns the address the pair's client connects to:
+// the destination's address on the pair's interface, or its data address in
+// the pair's family, if it has one
+func (p *TestPair) DestinationAddress() string {
+	if p.Interface != "" {
+		if addr, ok := p.Destination.InterfaceAddress(p.Interface); ok {
+			return addr
+		}
+	}
+	if p.Family != "" {
+		if addr, ok := p.Destination.DataAddressFor(p.Family); ok {
+			return addr
+		}
+	}
+	return p.Destination.DataAddress()
+}
+
+// BindAddress returns the local address the pair's client and server bind
+// to on their nodes: the source's and destination's addresses on the pair's
+// interface, or "" for pairs not run over an interface
+func (p *TestPair) BindAddress() (source, dest string) {
+	if p.Interface == "" {
+		return "", ""
+	}
+	source, _ = p.Source.InterfaceAddress(p.Interface)
+	dest, _ = p.Destination.InterfaceAddress(p.Interface)
+	return source, dest
+}
+
+// ScheduleSeconds returns Schedule rounded up to whole seconds
+func (p *TestPair) ScheduleSeconds() int {
+	return int(math.Ceil(p.Schedule().Seconds()))
+}
+
+// PairTiming holds per-pair duration and start delay overrides
+type PairTiming struct {
+	Duration     time.Duration
+	StartDelayMs int
+}
+
+// ProtoDuration splits d into the whole seconds older daemons read, rounded
+// up, and the milliseconds newer daemons prefer
+func ProtoDuration(d time.Duration) (seconds, ms int32) {
+	return int32(math.Ceil(d.Seconds())), int32(d.Milliseconds()) // #nosec G115 -- Durations are validated to be reasonable
 }
 
 // Topology represents the complete test topology
@@ -20,6 +113,32 @@ type Topology struct {
 	Pairs       []*TestPair
 	ServerPorts map[string][]int32     // nodeID -> ports
 	ClientTests map[string][]*TestPair // nodeID -> test pairs
+	Groups      []*Group               // Ordered groups; empty when all pairs run at once
+	// FamilySkips counts, by address family, the pairs not run in a family
+	// because one of their nodes has no data address in it
+	FamilySkips map[models.AddressFamily]int
+	// InterfaceSkips counts, by interface name, the pairs not run over an
+	// interface because only one of their nodes has it
+	InterfaceSkips map[string]int
+	// Sample is set for a partial mesh, describing the share of the full
+	// mesh it tests
+	Sample *MeshSample
+	// Excluded counts the tests of mesh pairs left out by exclusions
+	Excluded int
+	// ReverseSkipped counts the tests of mesh pairs left out of an
+	// undirected mesh because the pair in the other direction is tested
+	ReverseSkipped int
+	// Bisection is set for a bisection topology, recording its sides
+	Bisection *BisectionSplit
+	// TagGroups is set for a full mesh limited to the pairs within or
+	// across tag groups
+	TagGroups *TagGrouping
+	// Rounds is set when the pairs run in rounds, one group after the
+	// other, to the number of rounds
+	Rounds int
+	// PinnedPorts is set when a plan file sets the ports of every pair, so
+	// they are used as planned rather than allocated by the daemons
+	PinnedPorts bool
 }
 
 // Generator generates test topologies
@@ -28,8 +147,34 @@ type Generator struct {
 	profiles       *models.ProfileRegistry
 	defaultProfile *models.TestProfile
 	overrides      map[string]string // nodePairKey -> profileName
+	timings        map[string]PairTiming
+	groups         []GroupSpec
+	background     map[string]bool // nodePairKey -> runs as background load
+	repeats        map[string]int  // nodePairKey -> times the pair runs
+	excluded       map[string]bool // nodePairKey -> left out of the mesh
+	portStart      int32
+	portEnd        int32
+	pairsPerRun    int       // Pairs selected per run by a rotating topology
+	coverage       *Coverage // Set for rotating topologies
+	families       []models.AddressFamily
+	perInterface   bool     // Run each pair once per interface both nodes have
+	undirected     bool     // Test each unordered pair of the mesh once
+	sourceTags     []string // Set to limit the full mesh to tagged nodes
+	destTags       []string
+	groupKey       string // Set to limit the full mesh to pairs within or across tag groups
+	groupMode      string
+	density        float64 // Share of the full mesh tested by a partial mesh
+	maxTests       int     // Pairs of the full mesh tested by a partial mesh, instead of density
+	seed           int64
 }
 
+const (
+	// DefaultPortStart is the first server port assigned when no port range is set
+	DefaultPortStart = 5201
+	// DefaultPortEnd is the last server port assigned when no port range is set
+	DefaultPortEnd = 65535
+)
+
 // NewGenerator creates a new topology generator
 func NewGenerator(nodes *models.NodeRegistry, profiles *models.ProfileRegistry, defaultProfile *models.TestProfile) *Generator {
 	return &Generator{
@@ -37,9 +182,70 @@ func NewGenerator(nodes *models.NodeRegistry, profiles *models.ProfileRegistry,
 		profiles:       profiles,
 		defaultProfile: defaultProfile,
 		overrides:      make(map[string]string),
+		timings:        make(map[string]PairTiming),
+		background:     make(map[string]bool),
+		repeats:        make(map[string]int),
+		excluded:       make(map[string]bool),
+		portStart:      DefaultPortStart,
+		portEnd:        DefaultPortEnd,
 	}
 }
 
+// SetPairTiming overrides the duration and start delay of a node pair
+func (g *Generator) SetPairTiming(sourceID, destID string, timing PairTiming) {
+	key := fmt.Sprintf("%s:%s", sourceID, destID)
+	g.timings[key] = timing
+}
+
+// SetBackground marks a node pair as background load, which runs for the
+// whole measurement span instead of its profile duration
+func (g *Generator) SetBackground(sourceID, destID string) {
+	key := fmt.Sprintf("%s:%s", sourceID, destID)
+	g.background[key] = true
+}
+
+// SetRepeat makes a node pair run count times, as tests named after the
+// pair with a "-rep1", "-rep2", ... suffix. Each has its own server ports,
+// so the repetitions can run at once, or in turn when scheduled in rounds.
+func (g *Generator) SetRepeat(sourceID, destID string, count int) {
+	key := fmt.Sprintf("%s:%s", sourceID, destID)
+	g.repeats[key] = count
+}
+
+// Exclude leaves a node pair out of mesh topologies
+func (g *Generator) Exclude(sourceID, destID string) {
+	key := fmt.Sprintf("%s:%s", sourceID, destID)
+	g.excluded[key] = true
+}
+
+// SetPortRange restricts server ports to the inclusive range start-end
+func (g *Generator) SetPortRange(start, end int32) {
+	g.portStart = start
+	g.portEnd = end
+}
+
+// SetAddressFamilies makes every pair run once per family, named with the
+// family as a suffix. Pairs whose nodes lack a data address in a family are
+// not run in it, and are counted in the topology's FamilySkips.
+func (g *Generator) SetAddressFamilies(families []models.AddressFamily) {
+	g.families = families
+}
+
+// SetPerInterface makes every pair run once per interface name both of its
+// nodes have, between the addresses of that interface, named with the
+// interface as a suffix. Interfaces only one of the nodes has are counted in
+// the topology's InterfaceSkips.
+func (g *Generator) SetPerInterface(perInterface bool) {
+	g.perInterface = perInterface
+}
+
+// SetUndirected makes full mesh generation test each unordered pair of nodes
+// once, from the node listed first, rather than both ways. The tests of the
+// pairs left out are counted in the topology's ReverseSkipped.
+func (g *Generator) SetUndirected(undirected bool) {
+	g.undirected = undirected
+}
+
 // AddOverride adds a profile override for specific node pairs
 func (g *Generator) AddOverride(sourceID, destID, profileName string) error {
 	key := fmt.Sprintf("%s:%s", sourceID, destID)
@@ -60,51 +266,477 @@ func (g *Generator) GenerateFullMesh() (*Topology, error) {
 		ClientTests: make(map[string][]*TestPair),
 	}
 
-	testCounter := 0
+	byID := make(map[string]*models.Node, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	meshPairs := MeshPairs(g.nodes)
+	if len(g.sourceTags) > 0 || len(g.destTags) > 0 {
+		meshPairs = TagPairs(g.nodes, g.sourceTags, g.destTags)
+		if len(meshPairs) == 0 {
+			return nil, fmt.Errorf("no pairs from nodes tagged %s to nodes tagged %s",
+				strings.Join(g.sourceTags, ", "), strings.Join(g.destTags, ", "))
+		}
+	}
+	if g.groupKey != "" {
+		meshPairs, topology.TagGroups = GroupPairs(g.nodes, meshPairs, g.groupKey, g.groupMode)
+		if len(meshPairs) == 0 {
+			return nil, fmt.Errorf("no %s pairs of nodes grouped by their %s tags", g.groupMode, g.groupKey)
+		}
+	}
+	meshPairs = g.undirectedPairs(topology, byID, meshPairs)
+	if meshPairs = g.excludePairs(topology, byID, meshPairs); len(meshPairs) == 0 {
+		return nil, fmt.Errorf("topology exclusions leave no pairs to test")
+	}
+	if g.density > 0 || g.maxTests > 0 {
+		topology.Sample = &MeshSample{Density: g.density, MaxTests: g.maxTests, Seed: g.seed, MeshPairs: len(meshPairs)}
+		if g.maxTests > 0 {
+			meshPairs = SampleBudget(meshPairs, g.maxTests, g.seed)
+		} else {
+			meshPairs = SamplePairs(meshPairs, g.density, g.seed)
+		}
+		topology.Sample.Pairs = len(meshPairs)
+		topology.Sample.Selected = meshPairs
+	}
 
-	// Generate all source-destination pairs
-	for _, source := range nodes {
-		for _, dest := range nodes {
-			// Skip self-tests
-			if source.ID == dest.ID {
-				continue
+	// Generate all source-destination pairs, or those selected for this run
+	// of a rotating topology
+	for i, ids := range g.rotate(meshPairs) {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, byID[ids[0]], byID[ids[1]])) {
+			topology.Pairs = append(topology.Pairs, pair)
+
+			// Track client tests by source
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+		}
+	}
+
+	// Allocate server ports - each pair needs one port per iperf3 process
+	// on its destination, so a node receives a port for every incoming process
+	if err := allocateServerPorts(topology, nodes, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
+
+// undirectedPairs returns, for an undirected mesh, the first of pairs for
+// each unordered pair of nodes, counting the tests the others would have run
+// in topology's ReverseSkipped. A directed mesh keeps every pair.
+func (g *Generator) undirectedPairs(topology *Topology, byID map[string]*models.Node, pairs [][2]string) [][2]string {
+	if !g.undirected {
+		return pairs
+	}
+
+	seen := make(map[[2]string]bool, len(pairs))
+	kept := make([][2]string, 0, len(pairs)/2+1)
+	for _, ids := range pairs {
+		if seen[[2]string{ids[1], ids[0]}] {
+			topology.ReverseSkipped += len(g.expandPair(&Topology{}, g.newPair(0, byID[ids[0]], byID[ids[1]])))
+			continue
+		}
+		seen[ids] = true
+		kept = append(kept, ids)
+	}
+	return kept
+}
+
+// excludePairs returns the pairs not excluded, counting the tests the
+// excluded ones would have run in topology's Excluded. In an undirected mesh,
+// excluding either direction of a pair excludes it.
+func (g *Generator) excludePairs(topology *Topology, byID map[string]*models.Node, pairs [][2]string) [][2]string {
+	if len(g.excluded) == 0 {
+		return pairs
+	}
+
+	kept := make([][2]string, 0, len(pairs))
+	for _, ids := range pairs {
+		excluded := g.excluded[fmt.Sprintf("%s:%s", ids[0], ids[1])] ||
+			g.undirected && g.excluded[fmt.Sprintf("%s:%s", ids[1], ids[0])]
+		if !excluded {
+			kept = append(kept, ids)
+			continue
+		}
+		// Without the data addresses of a family, the pair would not have
+		// run in it anyway
+		topology.Excluded += len(g.expandPair(&Topology{}, g.newPair(0, byID[ids[0]], byID[ids[1]])))
+	}
+	return kept
+}
+
+// GeneratePair generates a topology with a single test from source to dest
+func (g *Generator) GeneratePair(sourceID, destID string) (*Topology, error) {
+	if sourceID == destID {
+		return nil, fmt.Errorf("source and destination must differ")
+	}
+
+	source, err := g.nodes.GetNode(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	dest, err := g.nodes.GetNode(destID)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, 2),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+	for _, pair := range g.reversePairs(g.newPair(1, source, dest)) {
+		topology.Pairs = append(topology.Pairs, pair)
+		topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+	}
+
+	if err := allocateServerPorts(topology, []*models.Node{source, dest}, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	return topology, nil
+}
+
+// newPair builds the numbered test pair from source to dest, applying any
+// profile and timing overrides
+func (g *Generator) newPair(number int, source, dest *models.Node) *TestPair {
+	pair := &TestPair{
+		TestID:      fmt.Sprintf("test-%d-%s-to-%s", number, source.ID, dest.ID),
+		Source:      source,
+		Destination: dest,
+		Profile:     g.getProfileForPair(source.ID, dest.ID),
+	}
+	if timing, exists := g.timings[fmt.Sprintf("%s:%s", source.ID, dest.ID)]; exists {
+		pair.Duration = timing.Duration
+		pair.StartDelayMs = timing.StartDelayMs
+	}
+	pair.Background = g.background[fmt.Sprintf("%s:%s", source.ID, dest.ID)]
+	pair.Repeat = g.repeats[fmt.Sprintf("%s:%s", source.ID, dest.ID)]
+	return pair
+}
+
+// expandPair returns the tests pair runs as: with a separate reverse
+// profile, pair and its reverse, each once per address family or interface
+// and as many times as it repeats
+func (g *Generator) expandPair(topology *Topology, pair *TestPair) []*TestPair {
+	pairs := make([]*TestPair, 0, 2*max(len(g.families), 1)*max(pair.Repeat, 1))
+	for _, directed := range g.reversePairs(pair) {
+		for _, familyPair := range g.familyPairs(topology, directed) {
+			for _, interfacePair := range g.interfacePairs(topology, familyPair) {
+				pairs = append(pairs, repeatPairs(interfacePair)...)
 			}
+		}
+	}
+	return pairs
+}
 
-			// Get profile for this pair
-			profile := g.getProfileForPair(source.ID, dest.ID)
+// RepeatSuffix precedes the number of each run of a repeated pair in its
+// test ID
+const RepeatSuffix = "-rep"
 
-			testCounter++
-			testID := fmt.Sprintf("test-%d-%s-to-%s", testCounter, source.ID, dest.ID)
+// repeatPairs returns pair once per repetition, or as is if it runs once
+func repeatPairs(pair *TestPair) []*TestPair {
+	if pair.Repeat <= 1 {
+		return []*TestPair{pair}
+	}
+
+	pairs := make([]*TestPair, 0, pair.Repeat)
+	for i := 1; i <= pair.Repeat; i++ {
+		repetition := *pair
+		repetition.TestID = fmt.Sprintf("%s%s%d", pair.TestID, RepeatSuffix, i)
+		repetition.Repetition = i
+		repetition.RepeatOf = pair.TestID
+		pairs = append(pairs, &repetition)
+	}
+	return pairs
+}
+
+// reversePairs returns pair and, if its profile runs bidirectional tests as
+// separate reverse tests, the reverse pair from its destination to its
+// source, named after pair with a "-reverse" suffix
+func (g *Generator) reversePairs(pair *TestPair) []*TestPair {
+	if !pair.Profile.Bidirectional || !pair.Profile.SeparateReverse {
+		return []*TestPair{pair}
+	}
+	reverse := *pair
+	reverse.TestID = pair.TestID + ReverseSuffix
+	reverse.Source, reverse.Destination = pair.Destination, pair.Source
+	return []*TestPair{pair, &reverse}
+}
 
-			pair := &TestPair{
-				TestID:      testID,
-				Source:      source,
-				Destination: dest,
-				Profile:     profile,
+// ReverseSuffix ends the test ID of the reverse pair of a profile with
+// separate_reverse
+const ReverseSuffix = "-reverse"
+
+// familyPairs returns pair once per address family both of its nodes have a
+// data address in, counting the families it is skipped in on topology.
+// Without address families it returns pair as is.
+func (g *Generator) familyPairs(topology *Topology, pair *TestPair) []*TestPair {
+	if len(g.families) == 0 {
+		return []*TestPair{pair}
+	}
+
+	pairs := make([]*TestPair, 0, len(g.families))
+	for _, family := range g.families {
+		_, sourceOK := pair.Source.DataAddressFor(family)
+		_, destOK := pair.Destination.DataAddressFor(family)
+		if !sourceOK || !destOK {
+			if topology.FamilySkips == nil {
+				topology.FamilySkips = make(map[models.AddressFamily]int)
 			}
+			topology.FamilySkips[family]++
+			continue
+		}
+		familyPair := *pair
+		familyPair.TestID = models.FamilyTestID(pair.TestID, family)
+		familyPair.Family = family
+		pairs = append(pairs, &familyPair)
+	}
+	return pairs
+}
 
-			topology.Pairs = append(topology.Pairs, pair)
+// interfacePairs returns pair once per interface name both of its nodes
+// have, in the order of the source's interfaces, counting the interfaces
+// only one of them has on topology. Without per_interface it returns pair
+// as is.
+func (g *Generator) interfacePairs(topology *Topology, pair *TestPair) []*TestPair {
+	if !g.perInterface {
+		return []*TestPair{pair}
+	}
 
-			// Track client tests by source
-			topology.ClientTests[source.ID] = append(topology.ClientTests[source.ID], pair)
+	skip := func(name string) {
+		if topology.InterfaceSkips == nil {
+			topology.InterfaceSkips = make(map[string]int)
+		}
+		topology.InterfaceSkips[name]++
+	}
+	pairs := make([]*TestPair, 0, len(pair.Source.Interfaces))
+	for _, iface := range pair.Source.Interfaces {
+		if _, ok := pair.Destination.InterfaceAddress(iface.Name); !ok {
+			skip(iface.Name)
+			continue
+		}
+		interfacePair := *pair
+		interfacePair.TestID = fmt.Sprintf("%s-%s", pair.TestID, iface.Name)
+		interfacePair.Interface = iface.Name
+		pairs = append(pairs, &interfacePair)
+	}
+	for _, iface := range pair.Destination.Interfaces {
+		if _, ok := pair.Source.InterfaceAddress(iface.Name); !ok {
+			skip(iface.Name)
+		}
+	}
+	return pairs
+}
+
+// BackgroundTailSeconds is how long background pairs keep running after the
+// last measured pair is expected to finish
+const BackgroundTailSeconds = 5
+
+// extendBackgroundPairs sets the duration of background pairs so that they
+// run until shortly after the last measured pair finishes. Burst pairs follow
+// their own schedule and are not extended.
+func extendBackgroundPairs(topology *Topology) {
+	var span time.Duration
+	for _, pair := range topology.Pairs {
+		if !pair.Background && pair.Schedule() > span {
+			span = pair.Schedule()
+		}
+	}
+
+	for _, pair := range topology.Pairs {
+		if pair.Background && pair.Profile.Burst == nil {
+			pair.Duration = span + BackgroundTailSeconds*time.Second - time.Duration(pair.StartDelayMs)*time.Millisecond
+		}
+	}
+}
+
+// GetMeasuredCount returns the number of pairs that are not background load
+func (t *Topology) GetMeasuredCount() int {
+	count := 0
+	for _, pair := range t.Pairs {
+		if !pair.Background {
+			count++
+		}
+	}
+	return count
+}
+
+// BackgroundTests returns the IDs of background test pairs
+func (t *Topology) BackgroundTests() map[string]bool {
+	tests := make(map[string]bool)
+	for _, pair := range t.Pairs {
+		if pair.Background {
+			tests[pair.TestID] = true
+		}
+	}
+	return tests
+}
+
+// TestIDs returns the IDs of all test pairs
+func (t *Topology) TestIDs() map[string]bool {
+	ids := make(map[string]bool, len(t.Pairs))
+	for _, pair := range t.Pairs {
+		ids[pair.TestID] = true
+	}
+	return ids
+}
+
+// TestSources maps each test ID to its source node, whose daemon stores the
+// test's result
+func (t *Topology) TestSources() map[string]string {
+	sources := make(map[string]string, len(t.Pairs))
+	for _, pair := range t.Pairs {
+		sources[pair.TestID] = pair.Source.ID
+	}
+	return sources
+}
+
+// TestDestinations maps each test ID to its destination node
+func (t *Topology) TestDestinations() map[string]string {
+	destinations := make(map[string]string, len(t.Pairs))
+	for _, pair := range t.Pairs {
+		destinations[pair.TestID] = pair.Destination.ID
+	}
+	return destinations
+}
+
+// TestRepetitions maps the test ID of each run of a repeated pair to the
+// test ID of the pair
+func (t *Topology) TestRepetitions() map[string]string {
+	repetitions := make(map[string]string)
+	for _, pair := range t.Pairs {
+		if pair.RepeatOf != "" {
+			repetitions[pair.TestID] = pair.RepeatOf
+		}
+	}
+	return repetitions
+}
+
+// TestFamilies maps the test ID of each pair run over a single address
+// family to the family, for tagging results
+func (t *Topology) TestFamilies() map[string]string {
+	families := make(map[string]string)
+	for _, pair := range t.Pairs {
+		if pair.Family != "" {
+			families[pair.TestID] = string(pair.Family)
+		}
+	}
+	return families
+}
+
+// TestInterfaces maps the test ID of each pair run over a single interface
+// to the interface name, for tagging results
+func (t *Topology) TestInterfaces() map[string]string {
+	interfaces := make(map[string]string)
+	for _, pair := range t.Pairs {
+		if pair.Interface != "" {
+			interfaces[pair.TestID] = pair.Interface
+		}
+	}
+	return interfaces
+}
+
+// TestHops maps the test ID of each pair of a chain to the hop it tests,
+// or 0 for the end-to-end pair, for tagging results
+func (t *Topology) TestHops() map[string]int {
+	hops := make(map[string]int)
+	for _, pair := range t.Pairs {
+		if pair.Hop > 0 || pair.EndToEnd {
+			hops[pair.TestID] = pair.Hop
+		}
+	}
+	return hops
+}
+
+// ServerBinds maps each node to the local address each of its server ports
+// binds to, for the ports of pairs run over an interface
+func (t *Topology) ServerBinds() map[string]map[int32]string {
+	return serverBinds(t.Pairs)
+}
+
+// serverBinds maps the destination of each of pairs run over an interface
+// to the address each of its server ports binds to
+func serverBinds(pairs []*TestPair) map[string]map[int32]string {
+	binds := make(map[string]map[int32]string)
+	for _, pair := range pairs {
+		_, dest := pair.BindAddress()
+		if dest == "" {
+			continue
+		}
+		if binds[pair.Destination.ID] == nil {
+			binds[pair.Destination.ID] = make(map[int32]string)
+		}
+		for _, port := range pair.Ports {
+			binds[pair.Destination.ID][port] = dest
+		}
+	}
+	return binds
+}
+
+// TestLabels maps each labelled test ID to its labels, for tagging results
+func (t *Topology) TestLabels() map[string]map[string]string {
+	labels := make(map[string]map[string]string)
+	for _, pair := range t.Pairs {
+		if len(pair.Labels) > 0 {
+			labels[pair.TestID] = pair.Labels
+		}
+	}
+	return labels
+}
+
+// TestProfiles maps each test ID to its profile name, for tagging results
+func (t *Topology) TestProfiles() map[string]string {
+	profiles := make(map[string]string, len(t.Pairs))
+	for _, pair := range t.Pairs {
+		profiles[pair.TestID] = pair.Profile.Name
+	}
+	return profiles
+}
+
+// allocateServerPorts assigns consecutive server ports from start to every
+// pair, in node order, failing if the range cannot hold them all. Destinations
+// missing from nodes, such as the sources receiving separate reverse pairs,
+// follow in the order they first receive a pair.
+func allocateServerPorts(topology *Topology, nodes []*models.Node, start, end int32) error {
+	incoming := make(map[string][]*TestPair)
+	listed := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		listed[node.ID] = true
+	}
+	for _, pair := range topology.Pairs {
+		if !listed[pair.Destination.ID] {
+			listed[pair.Destination.ID] = true
+			nodes = append(nodes[:len(nodes):len(nodes)], pair.Destination)
 		}
+		incoming[pair.Destination.ID] = append(incoming[pair.Destination.ID], pair)
+	}
+
+	needed := 0
+	for _, pair := range topology.Pairs {
+		needed += pair.Profile.ProcessCount()
+	}
+	if available := int(end-start) + 1; needed > available {
+		return fmt.Errorf("requested port range %d-%d exhausted: topology needs %d server ports, range has %d",
+			start, end, needed, available)
 	}
 
-	// Allocate server ports - each node needs one port per incoming connection
-	// For a full mesh with N nodes, each node receives N-1 incoming connections
-	portCounter := int32(5201) // Starting port
+	portCounter := start
 	for _, node := range nodes {
-		// Allocate N-1 ports for this node (one for each potential source)
-		numPorts := len(nodes) - 1
-		ports := make([]int32, numPorts)
-		for i := 0; i < numPorts; i++ {
-			ports[i] = portCounter
-			portCounter++
+		ports := make([]int32, 0)
+		for _, pair := range incoming[node.ID] {
+			pair.Ports = make([]int32, pair.Profile.ProcessCount())
+			for i := range pair.Ports {
+				pair.Ports[i] = portCounter
+				portCounter++
+			}
+			ports = append(ports, pair.Ports...)
 		}
 		topology.ServerPorts[node.ID] = ports
 	}
 
-	return topology, nil
+	return nil
 }
 
 // GenerateNodeTopologies creates per-node topology assignments
@@ -126,89 +758,24 @@ func GenerateNodeTopologies(topology *Topology) (map[string]*pb.TestTopology, er
 		}
 	}
 
-	// Build server assignments
-	// Each destination node needs to map each source node to a unique port
-	for nodeID, ports := range topology.ServerPorts {
-		if len(ports) == 0 {
-			continue
-		}
-
-		// Create a mapping from source node ID to port index
-		// We need to assign ports consistently across server and client assignments
-		sourceNodes := make([]string, 0)
-		for _, pair := range topology.Pairs {
-			if pair.Destination.ID == nodeID {
-				sourceNodes = append(sourceNodes, pair.Source.ID)
-			}
-		}
-
-		// Create source -> port mapping
-		sourceToPort := make(map[string]int32)
-		for i, sourceID := range sourceNodes {
-			if i < len(ports) {
-				sourceToPort[sourceID] = ports[i]
-			}
+	// Each pair is a server assignment on its destination and a client
+	// assignment on its source, using the first of the pair's ports. The
+	// daemon multiplies by the profile's processes_per_pair for capacity.
+	for _, pair := range topology.Pairs {
+		if len(pair.Ports) == 0 {
+			return nil, fmt.Errorf("no port allocated for source %s -> dest %s", pair.Source.ID, pair.Destination.ID)
 		}
 
-		// Find all tests where this node is the destination
-		for _, pair := range topology.Pairs {
-			if pair.Destination.ID == nodeID {
-				port, exists := sourceToPort[pair.Source.ID]
-				if !exists {
-					return nil, fmt.Errorf("no port allocated for source %s -> dest %s", pair.Source.ID, nodeID)
-				}
-
-				result[nodeID].ServerAssignments = append(result[nodeID].ServerAssignments, &pb.TestPair{
-					SourceId:        pair.Source.ID,
-					DestinationId:   pair.Destination.ID,
-					DestinationIp:   pair.Destination.IP,
-					DestinationPort: port,
-					Profile:         ConvertProfileToProto(pair.Profile),
-				})
-			}
+		assignment := &pb.TestPair{
+			SourceId:        pair.Source.ID,
+			DestinationId:   pair.Destination.ID,
+			DestinationIp:   pair.DestinationAddress(),
+			DestinationPort: pair.Ports[0],
+			Profile:         ConvertProfileToProto(pair.Profile),
 		}
-	}
-
-	// Build client assignments
-	// Each client needs to connect to the port assigned for its source->dest pair
-	for nodeID, pairs := range topology.ClientTests {
-		for _, pair := range pairs {
-			// Get the server ports for the destination
-			destPorts := topology.ServerPorts[pair.Destination.ID]
-			if len(destPorts) == 0 {
-				return nil, fmt.Errorf("no server port allocated for node %s", pair.Destination.ID)
-			}
-
-			// Find which port this source should use on the destination
-			// We need to find the index of this source in the destination's source list
-			sourceNodes := make([]string, 0)
-			for _, p := range topology.Pairs {
-				if p.Destination.ID == pair.Destination.ID {
-					sourceNodes = append(sourceNodes, p.Source.ID)
-				}
-			}
-
-			// Find the index of this source
-			portIndex := -1
-			for i, sourceID := range sourceNodes {
-				if sourceID == pair.Source.ID {
-					portIndex = i
-					break
-				}
-			}
-
-			if portIndex < 0 || portIndex >= len(destPorts) {
-				return nil, fmt.Errorf("no port index found for source %s -> dest %s", pair.Source.ID, pair.Destination.ID)
-			}
 
-			result[nodeID].ClientAssignments = append(result[nodeID].ClientAssignments, &pb.TestPair{
-				SourceId:        pair.Source.ID,
-				DestinationId:   pair.Destination.ID,
-				DestinationIp:   pair.Destination.IP,
-				DestinationPort: destPorts[portIndex],
-				Profile:         ConvertProfileToProto(pair.Profile),
-			})
-		}
+		result[pair.Destination.ID].ServerAssignments = append(result[pair.Destination.ID].ServerAssignments, assignment)
+		result[pair.Source.ID].ClientAssignments = append(result[pair.Source.ID].ClientAssignments, assignment)
 	}
 
 	return result, nil
@@ -239,14 +806,33 @@ func ConvertProfileToProto(profile *models.TestProfile) *pb.TestProfile {
 		protocol = pb.Protocol_PROTOCOL_UDP
 	}
 
+	var burst *pb.BurstSchedule
+	if profile.Burst != nil {
+		burst = &pb.BurstSchedule{
+			OnMs:   int32(profile.Burst.OnMs),   // #nosec G115 -- Burst timings are validated to be reasonable
+			OffMs:  int32(profile.Burst.OffMs),  // #nosec G115 -- Burst timings are validated to be reasonable
+			Repeat: int32(profile.Burst.Repeat), // #nosec G115 -- Burst repeat is validated to be reasonable
+		}
+	}
+
+	var capture *pb.CapturePolicy
+	if profile.Capture != nil {
+		capture = &pb.CapturePolicy{
+			OnFailure: profile.Capture.OnFailure,
+			BelowBps:  profile.Capture.BelowBps,
+		}
+	}
+
+	durationSeconds, durationMs := ProtoDuration(profile.Duration)
 	return &pb.TestProfile{
 		Name:              profile.Name,
-		DurationSeconds:   int32(profile.Duration), // #nosec G115 -- Duration is validated to be reasonable
+		DurationSeconds:   durationSeconds,
+		DurationMs:        durationMs,
 		Protocol:          protocol,
 		Bandwidth:         profile.Bandwidth,
 		WindowSize:        profile.WindowSize,
-		ParallelStreams:   int32(profile.Parallel), // #nosec G115 -- Parallel streams is validated to be reasonable
-		Bidirectional:     profile.Bidirectional,
+		ParallelStreams:   int32(profile.StreamsPerProcess()), // #nosec G115 -- Parallel streams is validated to be reasonable
+		Bidirectional:     profile.RunsBidir(),
 		Reverse:           profile.Reverse,
 		BufferLength:      int32(profile.BufferLength), // #nosec G115 -- Buffer length is validated to be reasonable
 		CongestionControl: profile.CongestionControl,
@@ -254,7 +840,10 @@ func ConvertProfileToProto(profile *models.TestProfile) *pb.TestProfile {
 		NoDelay:           profile.NoDelay,
 		Tos:               int32(profile.TOS), // #nosec G115 -- TOS is validated to be reasonable
 		Zerocopy:          profile.ZeroCopy,
-		OmitSeconds:       int32(profile.OmitSeconds), // #nosec G115 -- Omit seconds is validated to be reasonable
+		OmitSeconds:       int32(profile.OmitSeconds),    // #nosec G115 -- Omit seconds is validated to be reasonable
+		ProcessesPerPair:  int32(profile.ProcessCount()), // #nosec G115 -- Processes per pair is validated to be reasonable
+		Burst:             burst,
+		Capture:           capture,
 	}
 }
 
@@ -265,14 +854,20 @@ func (t *Topology) GetTestCount() int {
 
 // GetServerCount returns the number of servers to start
 func (t *Topology) GetServerCount() int {
-	return len(t.ServerPorts)
+	count := 0
+	for _, ports := range t.ServerPorts {
+		count += len(ports)
+	}
+	return count
 }
 
-// GetClientCount returns the number of client tests to run
+// GetClientCount returns the number of client processes to run
 func (t *Topology) GetClientCount() int {
 	count := 0
 	for _, tests := range t.ClientTests {
-		count += len(tests)
+		for _, pair := range tests {
+			count += pair.Profile.ProcessCount()
+		}
 	}
 	return count
 }
@@ -0,0 +1,593 @@
+package topology
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func newTestRegistries(t *testing.T, nodeCount int, profiles ...*models.TestProfile) (*models.NodeRegistry, *models.ProfileRegistry) {
+	t.Helper()
+
+	nodes := models.NewNodeRegistry()
+	for i := 1; i <= nodeCount; i++ {
+		node := &models.Node{
+			ID:       fmt.Sprintf("node%d", i),
+			Hostname: fmt.Sprintf("node%d.example.com", i),
+			IP:       fmt.Sprintf("192.168.1.%d", 10+i),
+			Port:     50051,
+		}
+		if err := nodes.AddNode(node); err != nil {
+			t.Fatalf("AddNode() error = %v", err)
+		}
+	}
+
+	registry := models.NewProfileRegistry()
+	for _, profile := range profiles {
+		if err := registry.AddProfile(profile); err != nil {
+			t.Fatalf("AddProfile() error = %v", err)
+		}
+	}
+
+	return nodes, registry
+}
+
+func TestGenerator_GenerateFullMesh(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	if topo.GetTestCount() != 6 {
+		t.Errorf("GetTestCount() = %d, want 6", topo.GetTestCount())
+	}
+	if topo.GetServerCount() != 6 {
+		t.Errorf("GetServerCount() = %d, want 6", topo.GetServerCount())
+	}
+
+	seen := make(map[int32]bool)
+	for _, pair := range topo.Pairs {
+		if len(pair.Ports) != 1 {
+			t.Fatalf("pair %s has %d ports, want 1", pair.TestID, len(pair.Ports))
+		}
+		if seen[pair.Ports[0]] {
+			t.Errorf("port %d assigned to more than one pair", pair.Ports[0])
+		}
+		seen[pair.Ports[0]] = true
+	}
+}
+
+func TestGenerator_ProcessesPerPair(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	multi := &models.TestProfile{Name: "multi", Duration: 10 * time.Second, Parallel: 4, ProcessesPerPair: 3}
+	nodes, profiles := newTestRegistries(t, 3, profile, multi)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	if err := gen.AddOverride("node1", "node2", "multi"); err != nil {
+		t.Fatalf("AddOverride() error = %v", err)
+	}
+
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// 5 single-process pairs plus one 3-process pair
+	if topo.GetServerCount() != 8 {
+		t.Errorf("GetServerCount() = %d, want 8", topo.GetServerCount())
+	}
+	if topo.GetClientCount() != 8 {
+		t.Errorf("GetClientCount() = %d, want 8", topo.GetClientCount())
+	}
+	if len(topo.ServerPorts["node2"]) != 4 {
+		t.Errorf("node2 has %d server ports, want 4", len(topo.ServerPorts["node2"]))
+	}
+
+	for _, pair := range topo.Pairs {
+		want := 1
+		if pair.Source.ID == "node1" && pair.Destination.ID == "node2" {
+			want = 3
+		}
+		if len(pair.Ports) != want {
+			t.Errorf("pair %s has %d ports, want %d", pair.TestID, len(pair.Ports), want)
+		}
+	}
+
+	nodeTopologies, err := GenerateNodeTopologies(topo)
+	if err != nil {
+		t.Fatalf("GenerateNodeTopologies() error = %v", err)
+	}
+	for _, assignment := range nodeTopologies["node2"].ServerAssignments {
+		if assignment.SourceId == "node1" && assignment.Profile.ProcessesPerPair != 3 {
+			t.Errorf("server assignment processes_per_pair = %d, want 3", assignment.Profile.ProcessesPerPair)
+		}
+	}
+}
+
+func TestGenerator_PortsPerPair(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	split := &models.TestProfile{Name: "split", Duration: 10 * time.Second, Parallel: 16, PortsPerPair: 4}
+	nodes, profiles := newTestRegistries(t, 2, profile, split)
+
+	gen := NewGenerator(nodes, profiles, split)
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// Two pairs of four processes each, a port per process
+	if topo.GetServerCount() != 8 || topo.GetClientCount() != 8 {
+		t.Errorf("%d servers and %d clients, want 8 of each", topo.GetServerCount(), topo.GetClientCount())
+	}
+	for _, pair := range topo.Pairs {
+		if len(pair.Ports) != 4 {
+			t.Errorf("pair %s has %d ports, want 4", pair.TestID, len(pair.Ports))
+		}
+	}
+
+	nodeTopologies, err := GenerateNodeTopologies(topo)
+	if err != nil {
+		t.Fatalf("GenerateNodeTopologies() error = %v", err)
+	}
+	for _, assignment := range nodeTopologies["node1"].ClientAssignments {
+		if assignment.Profile.ProcessesPerPair != 4 || assignment.Profile.ParallelStreams != 4 {
+			t.Errorf("client assignment runs %d processes of %d streams, want 4 of 4",
+				assignment.Profile.ProcessesPerPair, assignment.Profile.ParallelStreams)
+		}
+	}
+}
+
+func TestGenerator_SeparateReverse(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	split := &models.TestProfile{Name: "split", Duration: 10 * time.Second, Parallel: 1,
+		Bidirectional: true, SeparateReverse: true}
+	nodes, profiles := newTestRegistries(t, 3, profile, split)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	if err := gen.AddOverride("node1", "node2", "split"); err != nil {
+		t.Fatalf("AddOverride() error = %v", err)
+	}
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// The mesh's own node2 -> node1 pair runs besides the reverse test
+	if topo.GetTestCount() != 7 || topo.GetServerCount() != 7 {
+		t.Errorf("%d tests on %d server ports, want 7 of each", topo.GetTestCount(), topo.GetServerCount())
+	}
+	var reverse *TestPair
+	for _, pair := range topo.Pairs {
+		if pair.TestID == "test-1-node1-to-node2-reverse" {
+			reverse = pair
+		}
+	}
+	if reverse == nil || reverse.Source.ID != "node2" || reverse.Destination.ID != "node1" {
+		t.Fatalf("reverse pair = %+v, want node2 -> node1", reverse)
+	}
+	if len(topo.ServerPorts["node1"]) != 3 || len(topo.ClientTests["node2"]) != 3 {
+		t.Errorf("node1 has %d server ports and node2 %d client tests, want 3 each",
+			len(topo.ServerPorts["node1"]), len(topo.ClientTests["node2"]))
+	}
+
+	nodeTopologies, err := GenerateNodeTopologies(topo)
+	if err != nil {
+		t.Fatalf("GenerateNodeTopologies() error = %v", err)
+	}
+	for _, assignment := range nodeTopologies["node1"].ServerAssignments {
+		if assignment.Profile.Bidirectional {
+			t.Errorf("server assignment from %s runs --bidir, want one-way tests", assignment.SourceId)
+		}
+	}
+
+	// Sources of a many-to-one topology receive the reverse tests
+	topo, err = NewGenerator(nodes, profiles, split).GenerateManyToOne("node3")
+	if err != nil {
+		t.Fatalf("GenerateManyToOne() error = %v", err)
+	}
+	if len(topo.ServerPorts["node1"]) != 1 || len(topo.ServerPorts["node3"]) != 2 {
+		t.Errorf("server ports = %v, want 1 on node1 and 2 on node3", topo.ServerPorts)
+	}
+	if _, err := GenerateNodeTopologies(topo); err != nil {
+		t.Errorf("GenerateNodeTopologies() error = %v", err)
+	}
+}
+
+func TestGenerator_PortRange(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetPortRange(6000, 6005)
+
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	for _, pair := range topo.Pairs {
+		if pair.Ports[0] < 6000 || pair.Ports[0] > 6005 {
+			t.Errorf("pair %s port %d outside range 6000-6005", pair.TestID, pair.Ports[0])
+		}
+	}
+
+	gen.SetPortRange(6000, 6004)
+	if _, err := gen.GenerateFullMesh(); err == nil {
+		t.Error("GenerateFullMesh() expected error for exhausted port range")
+	}
+}
+
+func TestGenerator_PairTiming(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 2, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetPairTiming("node1", "node2", PairTiming{Duration: 30 * time.Second, StartDelayMs: 1500})
+
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	for _, pair := range topo.Pairs {
+		want := 10
+		if pair.Source.ID == "node1" {
+			want = 32 // 30s duration plus the 1.5s delay rounded up
+		}
+		if got := pair.ScheduleSeconds(); got != want {
+			t.Errorf("pair %s ScheduleSeconds() = %d, want %d", pair.TestID, got, want)
+		}
+	}
+}
+
+func TestGenerator_GeneratePair(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 5 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GeneratePair("node3", "node1")
+	if err != nil {
+		t.Fatalf("GeneratePair() error = %v", err)
+	}
+	if topo.GetTestCount() != 1 {
+		t.Fatalf("GetTestCount() = %d, want 1", topo.GetTestCount())
+	}
+	if pair := topo.Pairs[0]; pair.Source.ID != "node3" || pair.Destination.ID != "node1" {
+		t.Errorf("pair = %s -> %s, want node3 -> node1", pair.Source.ID, pair.Destination.ID)
+	}
+	if len(topo.ServerPorts["node1"]) != 1 || len(topo.ClientTests["node3"]) != 1 {
+		t.Errorf("ServerPorts = %v, ClientTests = %v", topo.ServerPorts, topo.ClientTests)
+	}
+
+	if _, err := NewGenerator(nodes, profiles, profile).GeneratePair("node1", "node1"); err == nil {
+		t.Error("GeneratePair() expected error for self-test")
+	}
+	if _, err := NewGenerator(nodes, profiles, profile).GeneratePair("node1", "missing"); err == nil {
+		t.Error("GeneratePair() expected error for unknown node")
+	}
+}
+
+func TestGenerator_Background(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	long := &models.TestProfile{Name: "long", Duration: 30 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile, long)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetBackground("node3", "node2")
+	if err := gen.AddOverride("node1", "node2", "long"); err != nil {
+		t.Fatalf("AddOverride() error = %v", err)
+	}
+
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	if topo.GetMeasuredCount() != 5 || len(topo.BackgroundTests()) != 1 {
+		t.Fatalf("measured = %d, background = %d; want 5 and 1", topo.GetMeasuredCount(), len(topo.BackgroundTests()))
+	}
+	for _, pair := range topo.Pairs {
+		if !pair.Background {
+			continue
+		}
+		// Outlasts the longest measured pair (30s) by the tail
+		if want := 30 + BackgroundTailSeconds; pair.ScheduleSeconds() != want {
+			t.Errorf("background ScheduleSeconds() = %d, want %d", pair.ScheduleSeconds(), want)
+		}
+	}
+
+	gen.AddGroup(GroupSpec{Name: "a", Pairs: []GroupPairSpec{{SourceID: "node3", DestID: "node2"}}})
+	if _, err := gen.GenerateGroups(); err == nil {
+		t.Error("GenerateGroups() expected error for a background pair")
+	}
+}
+
+func TestGenerator_Exclusions(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.Exclude("node1", "node3")
+	gen.Exclude("node3", "node1")
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.GetTestCount() != 4 || topo.Excluded != 2 {
+		t.Errorf("%d tests, %d excluded; want 4 and 2", topo.GetTestCount(), topo.Excluded)
+	}
+	for _, pair := range topo.Pairs {
+		if (pair.Source.ID == "node1" && pair.Destination.ID == "node3") || (pair.Source.ID == "node3" && pair.Destination.ID == "node1") {
+			t.Errorf("excluded pair %s generated", pair.TestID)
+		}
+	}
+
+	// Excluded tests count each address family the pair would have run in
+	gen.SetAddressFamilies([]models.AddressFamily{models.FamilyV4, models.FamilyV6})
+	if topo, err = gen.GenerateFullMesh(); err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.Excluded != 2 {
+		t.Errorf("Excluded = %d, want 2 (no node has an IPv6 address)", topo.Excluded)
+	}
+
+	for _, pair := range MeshPairs(nodes) {
+		gen.Exclude(pair[0], pair[1])
+	}
+	if _, err := gen.GenerateFullMesh(); err == nil || !strings.Contains(err.Error(), "leave no pairs") {
+		t.Errorf("GenerateFullMesh() error = %v, want every pair excluded", err)
+	}
+}
+
+func TestGenerator_Undirected(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetUndirected(true)
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.GetTestCount() != 6 || topo.ReverseSkipped != 6 {
+		t.Errorf("%d tests, %d reverse skipped; want 6 and 6", topo.GetTestCount(), topo.ReverseSkipped)
+	}
+	tested := make(map[string]bool)
+	for _, pair := range topo.Pairs {
+		if pair.Source.ID > pair.Destination.ID {
+			t.Errorf("pair %s runs from the node listed last", pair.TestID)
+		}
+		tested[pair.Source.ID+":"+pair.Destination.ID] = true
+	}
+	if len(tested) != 6 {
+		t.Errorf("tested %d unordered pairs, want 6", len(tested))
+	}
+
+	// Nodes receive as many ports as they have incoming pairs: none for
+	// node1, three for node4
+	if len(topo.ServerPorts["node1"]) != 0 || len(topo.ServerPorts["node4"]) != 3 {
+		t.Errorf("ServerPorts = %v, want none on node1 and 3 on node4", topo.ServerPorts)
+	}
+	assignments, err := GenerateNodeTopologies(topo)
+	if err != nil {
+		t.Fatalf("GenerateNodeTopologies() error = %v", err)
+	}
+	if node1 := assignments["node1"]; len(node1.ServerAssignments) != 0 || len(node1.ClientAssignments) != 3 {
+		t.Errorf("node1 has %d server and %d client assignments, want 0 and 3",
+			len(node1.ServerAssignments), len(node1.ClientAssignments))
+	}
+
+	// Excluding either direction excludes the pair
+	gen.Exclude("node3", "node1")
+	if topo, err = gen.GenerateFullMesh(); err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.GetTestCount() != 5 || topo.Excluded != 1 {
+		t.Errorf("%d tests, %d excluded; want 5 and 1", topo.GetTestCount(), topo.Excluded)
+	}
+}
+
+func TestTopology_PlanRoundTrip(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetPairTiming("node1", "node2", PairTiming{Duration: 30 * time.Second, StartDelayMs: 500})
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	rebuilt, err := FromPlan(topo.Plan(), nodes, profiles)
+	if err != nil {
+		t.Fatalf("FromPlan() error = %v", err)
+	}
+	if fmt.Sprint(rebuilt.Plan()) != fmt.Sprint(topo.Plan()) {
+		t.Errorf("FromPlan(Plan()) = %v, want %v", rebuilt.Plan(), topo.Plan())
+	}
+	if rebuilt.GetServerCount() != topo.GetServerCount() {
+		t.Errorf("GetServerCount() = %d, want %d", rebuilt.GetServerCount(), topo.GetServerCount())
+	}
+
+	plan := topo.Plan()
+	plan[1].TestID = plan[0].TestID
+	if _, err := FromPlan(plan, nodes, profiles); err == nil {
+		t.Error("FromPlan() with duplicate test IDs succeeded, want error")
+	}
+}
+
+func TestGenerator_SubSecondTiming(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 2, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetPairTiming("node1", "node2", PairTiming{Duration: 500 * time.Millisecond, StartDelayMs: 250})
+
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	for _, pair := range topo.Pairs {
+		if pair.Source.ID != "node1" {
+			continue
+		}
+		if got, want := pair.Schedule(), 750*time.Millisecond; got != want {
+			t.Errorf("Schedule() = %v, want %v", got, want)
+		}
+		if got := pair.ScheduleSeconds(); got != 1 {
+			t.Errorf("ScheduleSeconds() = %d, want 1", got)
+		}
+	}
+
+	seconds, ms := ProtoDuration(500 * time.Millisecond)
+	if seconds != 1 || ms != 500 {
+		t.Errorf("ProtoDuration(500ms) = %d, %d, want 1, 500", seconds, ms)
+	}
+}
+
+func TestGenerator_AddressFamilies(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+	for _, node := range nodes.GetAllNodes() {
+		if node.ID != "node3" {
+			node.DataIPs = []string{fmt.Sprintf("fd00::%s", node.ID[len("node"):])}
+		}
+	}
+
+	generator := NewGenerator(nodes, profiles, profile)
+	generator.SetAddressFamilies([]models.AddressFamily{models.FamilyV4, models.FamilyV6})
+	topo, err := generator.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// Every pair runs over IPv4; only node1 and node2 have IPv6 addresses
+	if topo.GetTestCount() != 8 {
+		t.Errorf("GetTestCount() = %d, want 8", topo.GetTestCount())
+	}
+	if topo.FamilySkips[models.FamilyV6] != 4 || topo.FamilySkips[models.FamilyV4] != 0 {
+		t.Errorf("FamilySkips = %v, want 4 skipped in v6", topo.FamilySkips)
+	}
+	for _, pair := range topo.Pairs {
+		if pair.Family != models.FamilyV6 {
+			continue
+		}
+		if pair.Source.ID == "node3" || pair.Destination.ID == "node3" {
+			t.Errorf("pair %s includes node3, which has no IPv6 address", pair.TestID)
+		}
+		if want := "fd00::" + pair.Destination.ID[len("node"):]; pair.DestinationAddress() != want {
+			t.Errorf("pair %s DestinationAddress() = %s, want %s", pair.TestID, pair.DestinationAddress(), want)
+		}
+		if !strings.HasSuffix(pair.TestID, "-v6") {
+			t.Errorf("pair %s lacks the -v6 suffix", pair.TestID)
+		}
+	}
+	if families := topo.TestFamilies(); len(families) != 8 {
+		t.Errorf("TestFamilies() has %d tests, want 8", len(families))
+	}
+}
+
+func TestGenerator_PerInterface(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+	for _, node := range nodes.GetAllNodes() {
+		n := node.ID[len("node"):]
+		node.Interfaces = []models.NodeInterface{{Name: "frontend", IP: "10.0.0." + n}}
+		if node.ID != "node3" {
+			node.Interfaces = append(node.Interfaces, models.NodeInterface{Name: "backend", IP: "192.168.0." + n})
+		}
+	}
+
+	generator := NewGenerator(nodes, profiles, profile)
+	generator.SetPerInterface(true)
+	topo, err := generator.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// Every pair runs over frontend; only node1 and node2 have backend
+	if topo.GetTestCount() != 8 {
+		t.Errorf("GetTestCount() = %d, want 8", topo.GetTestCount())
+	}
+	if topo.InterfaceSkips["backend"] != 4 || topo.InterfaceSkips["frontend"] != 0 {
+		t.Errorf("InterfaceSkips = %v, want 4 skipped over backend", topo.InterfaceSkips)
+	}
+	binds := topo.ServerBinds()
+	for _, pair := range topo.Pairs {
+		prefix := "10.0.0."
+		if pair.Interface == "backend" {
+			prefix = "192.168.0."
+		}
+		if !strings.HasSuffix(pair.TestID, "-"+pair.Interface) {
+			t.Errorf("pair %s lacks the -%s suffix", pair.TestID, pair.Interface)
+		}
+		source, dest := pair.BindAddress()
+		if want := prefix + pair.Source.ID[len("node"):]; source != want {
+			t.Errorf("pair %s binds its client to %s, want %s", pair.TestID, source, want)
+		}
+		if want := prefix + pair.Destination.ID[len("node"):]; dest != want || pair.DestinationAddress() != want {
+			t.Errorf("pair %s binds its server to %s and connects to %s, want %s", pair.TestID, dest, pair.DestinationAddress(), want)
+		}
+		if got := binds[pair.Destination.ID][pair.Ports[0]]; got != dest {
+			t.Errorf("ServerBinds() binds port %d of %s to %s, want %s", pair.Ports[0], pair.Destination.ID, got, dest)
+		}
+	}
+	if interfaces := topo.TestInterfaces(); len(interfaces) != 8 || interfaces["test-1-node1-to-node2-backend"] != "backend" {
+		t.Errorf("TestInterfaces() = %v", interfaces)
+	}
+}
+
+func TestGenerator_Repeat(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetRepeat("node1", "node2", 3)
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	if topo.GetTestCount() != 8 {
+		t.Fatalf("GetTestCount() = %d, want 8", topo.GetTestCount())
+	}
+	repeated := make([]string, 0)
+	ports := make(map[int32]bool)
+	for _, pair := range topo.Pairs {
+		if pair.RepeatOf != "" {
+			repeated = append(repeated, fmt.Sprintf("%s:%d", pair.TestID, pair.Repetition))
+			ports[pair.Ports[0]] = true
+		}
+	}
+	want := "test-1-node1-to-node2-rep1:1,test-1-node1-to-node2-rep2:2,test-1-node1-to-node2-rep3:3"
+	if got := strings.Join(repeated, ","); got != want {
+		t.Errorf("repetitions = %s, want %s", got, want)
+	}
+	// Run at once, each repetition needs its own server port
+	if len(ports) != 3 {
+		t.Errorf("repetitions use %d server ports, want 3", len(ports))
+	}
+	if repetitions := topo.TestRepetitions(); len(repetitions) != 3 ||
+		repetitions["test-1-node1-to-node2-rep2"] != "test-1-node1-to-node2" {
+		t.Errorf("TestRepetitions() = %v", repetitions)
+	}
+
+	// In rounds, the repetitions run one after the other
+	if err := topo.ApplyRounds(); err != nil {
+		t.Fatalf("ApplyRounds() error = %v", err)
+	}
+	rounds := make(map[string]bool)
+	for _, pair := range topo.Pairs {
+		if pair.RepeatOf != "" {
+			rounds[pair.Group] = true
+		}
+	}
+	if len(rounds) != 3 {
+		t.Errorf("repetitions ran in %d rounds, want 3", len(rounds))
+	}
+}
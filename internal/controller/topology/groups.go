@@ -0,0 +1,323 @@
+package topology
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// GroupOverheadSeconds is the time allowed per group for starting its
+// servers and clients and detecting completion, used when estimating how
+// long background groups must run
+const GroupOverheadSeconds = 5
+
+// Group is a named set of test pairs started together once the groups it
+// depends on allow it
+type Group struct {
+	Name  string
+	After []string // Groups that must finish (or, if background, start) first
+	// Background groups keep running while the groups depending on them
+	// execute, so dependents start as soon as a background group has started
+	Background bool
+	Pairs      []*TestPair
+}
+
+// GroupSpec describes a group to generate
+type GroupSpec struct {
+	Name       string
+	After      []string
+	Background bool
+	Pairs      []GroupPairSpec
+}
+
+// GroupPairSpec selects one pair of a group, with an optional profile and
+// timing that take precedence over the generator's overrides
+type GroupPairSpec struct {
+	SourceID string
+	DestID   string
+	Profile  string
+	Timing   PairTiming
+	Repeat   int
+}
+
+// ServerPorts returns the group's server ports by destination node
+func (g *Group) ServerPorts() map[string][]int32 {
+	ports := make(map[string][]int32)
+	for _, pair := range g.Pairs {
+		ports[pair.Destination.ID] = append(ports[pair.Destination.ID], pair.Ports...)
+	}
+	return ports
+}
+
+// ServerBinds returns the addresses the group's server ports bind to by
+// destination node, for the ports of pairs run over an interface
+func (g *Group) ServerBinds() map[string]map[int32]string {
+	return serverBinds(g.Pairs)
+}
+
+// ClientTests returns the group's test pairs by source node
+func (g *Group) ClientTests() map[string][]*TestPair {
+	tests := make(map[string][]*TestPair)
+	for _, pair := range g.Pairs {
+		tests[pair.Source.ID] = append(tests[pair.Source.ID], pair)
+	}
+	return tests
+}
+
+// Schedule returns how long after its clients start the group is expected
+// to finish
+func (g *Group) Schedule() time.Duration {
+	var schedule time.Duration
+	for _, pair := range g.Pairs {
+		if s := pair.Schedule(); s > schedule {
+			schedule = s
+		}
+	}
+	return schedule
+}
+
+// ScheduleSeconds returns Schedule rounded up to whole seconds
+func (g *Group) ScheduleSeconds() int {
+	return int(math.Ceil(g.Schedule().Seconds()))
+}
+
+// AddGroup adds a group. Once any group is added, GenerateGroups builds the
+// topology from groups instead of a full mesh.
+func (g *Generator) AddGroup(spec GroupSpec) {
+	g.groups = append(g.groups, spec)
+}
+
+// HasGroups reports whether any groups were added
+func (g *Generator) HasGroups() bool {
+	return len(g.groups) > 0
+}
+
+// GenerateGroups generates a topology containing the pairs of every group,
+// with groups ordered so that each follows the groups it depends on. A node
+// pair listed in several groups becomes a separate test in each.
+func (g *Generator) GenerateGroups() (*Topology, error) {
+	specs, err := orderGroups(g.groups)
+	if err != nil {
+		return nil, err
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	testCounter := 0
+	for _, spec := range specs {
+		group := &Group{
+			Name:       spec.Name,
+			After:      spec.After,
+			Background: spec.Background,
+		}
+
+		for _, pairSpec := range spec.Pairs {
+			source, err := g.nodes.GetNode(pairSpec.SourceID)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", spec.Name, err)
+			}
+			dest, err := g.nodes.GetNode(pairSpec.DestID)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", spec.Name, err)
+			}
+			if source.ID == dest.ID {
+				return nil, fmt.Errorf("group %s: source and destination must differ (%s)", spec.Name, source.ID)
+			}
+
+			testCounter++
+			pair := g.newPair(testCounter, source, dest)
+			if pair.Background {
+				return nil, fmt.Errorf("group %s: pair %s -> %s is marked background; use a background group instead",
+					spec.Name, source.ID, dest.ID)
+			}
+			pair.Group = spec.Name
+			if pairSpec.Profile != "" {
+				profile, err := g.profiles.GetProfile(pairSpec.Profile)
+				if err != nil {
+					return nil, fmt.Errorf("group %s: %w", spec.Name, err)
+				}
+				pair.Profile = profile
+			}
+			if pairSpec.Timing.Duration > 0 {
+				pair.Duration = pairSpec.Timing.Duration
+			}
+			if pairSpec.Timing.StartDelayMs > 0 {
+				pair.StartDelayMs = pairSpec.Timing.StartDelayMs
+			}
+			if pairSpec.Repeat > 0 {
+				pair.Repeat = pairSpec.Repeat
+			}
+
+			for _, familyPair := range g.expandPair(topology, pair) {
+				group.Pairs = append(group.Pairs, familyPair)
+				topology.Pairs = append(topology.Pairs, familyPair)
+				topology.ClientTests[familyPair.Source.ID] = append(topology.ClientTests[familyPair.Source.ID], familyPair)
+			}
+		}
+
+		topology.Groups = append(topology.Groups, group)
+	}
+
+	if err := allocateServerPorts(topology, g.nodes.GetAllNodes(), g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundGroups(topology.Groups)
+
+	return topology, nil
+}
+
+// orderGroups returns the specs sorted so that every group follows the
+// groups it depends on, rejecting duplicate names, unknown dependencies and
+// cycles. Independent groups keep their configured order.
+func orderGroups(specs []GroupSpec) ([]GroupSpec, error) {
+	byName := make(map[string]GroupSpec, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("group name cannot be empty")
+		}
+		if _, exists := byName[spec.Name]; exists {
+			return nil, fmt.Errorf("duplicate group name: %s", spec.Name)
+		}
+		byName[spec.Name] = spec
+	}
+
+	ordered := make([]GroupSpec, 0, len(specs))
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 1:
+			return fmt.Errorf("group dependency cycle: %v", append(path, name))
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range byName[name].After {
+			if _, exists := byName[dep]; !exists {
+				return fmt.Errorf("group %s: unknown dependency %q", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, byName[name])
+		return nil
+	}
+
+	for _, spec := range specs {
+		if err := visit(spec.Name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
+
+// extendBackgroundGroups lengthens the pairs of background groups so that
+// they keep running until every group depending on them, directly or
+// transitively, is expected to finish. Burst pairs follow their own schedule
+// and are not extended. Groups must be in dependency order.
+func extendBackgroundGroups(groups []*Group) {
+	byName := make(map[string]*Group, len(groups))
+	start := make(map[string]int, len(groups))
+	end := make(map[string]int, len(groups))
+
+	for _, group := range groups {
+		byName[group.Name] = group
+		for _, dep := range group.After {
+			ready := end[dep]
+			if byName[dep].Background {
+				ready = start[dep] + GroupOverheadSeconds
+			}
+			if ready > start[group.Name] {
+				start[group.Name] = ready
+			}
+		}
+		end[group.Name] = start[group.Name] + group.ScheduleSeconds() + GroupOverheadSeconds
+	}
+
+	for _, group := range groups {
+		if !group.Background {
+			continue
+		}
+
+		cover := 0
+		for _, dependent := range dependents(groups, group.Name) {
+			if end[dependent]-start[group.Name] > cover {
+				cover = end[dependent] - start[group.Name]
+			}
+		}
+
+		for _, pair := range group.Pairs {
+			if pair.Profile.Burst != nil {
+				continue
+			}
+			if coverDuration := time.Duration(cover) * time.Second; coverDuration > pair.Schedule() {
+				pair.Duration = coverDuration - time.Duration(pair.StartDelayMs)*time.Millisecond
+			}
+		}
+	}
+}
+
+// dependents returns the names of the groups that depend on name, directly
+// or transitively, in sorted order
+func dependents(groups []*Group, name string) []string {
+	found := map[string]bool{name: true}
+	for changed := true; changed; {
+		changed = false
+		for _, group := range groups {
+			if found[group.Name] {
+				continue
+			}
+			for _, dep := range group.After {
+				if found[dep] {
+					found[group.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	delete(found, name)
+
+	names := make([]string, 0, len(found))
+	for n := range found {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TestGroups maps each test ID to its group name, for tagging results
+func (t *Topology) TestGroups() map[string]string {
+	groups := make(map[string]string)
+	for _, pair := range t.Pairs {
+		if pair.Group != "" {
+			groups[pair.TestID] = pair.Group
+		}
+	}
+	return groups
+}
+
+// ExpandedTestIDs returns the IDs under which the pair's results are stored,
+// one per iperf3 process
+func (p *TestPair) ExpandedTestIDs() []string {
+	count := p.Profile.ProcessCount()
+	if count <= 1 {
+		return []string{p.TestID}
+	}
+	ids := make([]string, count)
+	for i := range ids {
+		ids[i] = models.SubTestID(p.TestID, i)
+	}
+	return ids
+}
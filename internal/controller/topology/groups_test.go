@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateGroups(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	// Listed out of order to check dependency ordering
+	gen.AddGroup(GroupSpec{
+		Name:  "victim",
+		After: []string{"load"},
+		Pairs: []GroupPairSpec{{SourceID: "node1", DestID: "node2"}},
+	})
+	gen.AddGroup(GroupSpec{
+		Name:       "load",
+		After:      []string{"baseline"},
+		Background: true,
+		Pairs:      []GroupPairSpec{{SourceID: "node3", DestID: "node2"}},
+	})
+	gen.AddGroup(GroupSpec{
+		Name:  "baseline",
+		Pairs: []GroupPairSpec{{SourceID: "node1", DestID: "node2"}},
+	})
+
+	topo, err := gen.GenerateGroups()
+	if err != nil {
+		t.Fatalf("GenerateGroups() error = %v", err)
+	}
+
+	order := make([]string, 0, len(topo.Groups))
+	for _, group := range topo.Groups {
+		order = append(order, group.Name)
+	}
+	if len(order) != 3 || order[0] != "baseline" || order[1] != "load" || order[2] != "victim" {
+		t.Errorf("group order = %v, want [baseline load victim]", order)
+	}
+
+	// The same node pair in two groups is two tests with their own ports
+	if topo.GetTestCount() != 3 || len(topo.ServerPorts["node2"]) != 3 {
+		t.Errorf("GetTestCount() = %d, node2 ports = %v; want 3 tests on 3 ports",
+			topo.GetTestCount(), topo.ServerPorts["node2"])
+	}
+	groups := topo.TestGroups()
+	if groups[topo.Groups[0].Pairs[0].TestID] != "baseline" || groups[topo.Groups[2].Pairs[0].TestID] != "victim" {
+		t.Errorf("TestGroups() = %v", groups)
+	}
+
+	// The background load must outlast the victim group it runs under
+	load, victim := topo.Groups[1].Pairs[0], topo.Groups[2].Pairs[0]
+	if want := GroupOverheadSeconds + victim.ScheduleSeconds() + GroupOverheadSeconds; load.ScheduleSeconds() < want {
+		t.Errorf("background ScheduleSeconds() = %d, want at least %d", load.ScheduleSeconds(), want)
+	}
+}
+
+func TestGenerator_GenerateGroupsErrors(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 2, profile)
+	pair := []GroupPairSpec{{SourceID: "node1", DestID: "node2"}}
+
+	tests := []struct {
+		name   string
+		groups []GroupSpec
+	}{
+		{"cycle", []GroupSpec{
+			{Name: "a", After: []string{"b"}, Pairs: pair},
+			{Name: "b", After: []string{"a"}, Pairs: pair},
+		}},
+		{"unknown dependency", []GroupSpec{{Name: "a", After: []string{"missing"}, Pairs: pair}}},
+		{"duplicate name", []GroupSpec{{Name: "a", Pairs: pair}, {Name: "a", Pairs: pair}}},
+		{"unknown node", []GroupSpec{{Name: "a", Pairs: []GroupPairSpec{{SourceID: "node1", DestID: "node9"}}}}},
+		{"unknown profile", []GroupSpec{{Name: "a", Pairs: []GroupPairSpec{{SourceID: "node1", DestID: "node2", Profile: "missing"}}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := NewGenerator(nodes, profiles, profile)
+			for _, group := range tt.groups {
+				gen.AddGroup(group)
+			}
+			if _, err := gen.GenerateGroups(); err == nil {
+				t.Error("GenerateGroups() expected error")
+			}
+		})
+	}
+}
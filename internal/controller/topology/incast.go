@@ -0,0 +1,48 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// GenerateManyToOne generates an incast topology of pairs from every other
+// node to the destination node at once. The destination gets distinct
+// server ports for every pair, so each client has its own server.
+func (g *Generator) GenerateManyToOne(destID string) (*Topology, error) {
+	dest, err := g.nodes.GetNode(destID)
+	if err != nil {
+		return nil, fmt.Errorf("many_to_one destination: %w", err)
+	}
+
+	sources := make([]*models.Node, 0, g.nodes.Count())
+	for _, node := range g.nodes.GetAllNodes() {
+		if node.ID != dest.ID {
+			sources = append(sources, node)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("many_to_one topology needs at least one node besides destination %q", destID)
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(sources)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	for i, source := range sources {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, source, dest)) {
+			topology.Pairs = append(topology.Pairs, pair)
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+		}
+	}
+
+	if err := allocateServerPorts(topology, []*models.Node{dest}, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
@@ -0,0 +1,51 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateManyToOne(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateManyToOne("node3")
+	if err != nil {
+		t.Fatalf("GenerateManyToOne() error = %v", err)
+	}
+
+	pairs := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID)
+	}
+	if got, want := strings.Join(pairs, ","), "node1>node3,node2>node3,node4>node3"; got != want {
+		t.Errorf("pairs = %s, want %s", got, want)
+	}
+
+	// Only the destination runs servers, one per client
+	if len(topo.ServerPorts) != 1 {
+		t.Errorf("ServerPorts has %d nodes, want 1", len(topo.ServerPorts))
+	}
+	ports := make(map[int32]bool)
+	for _, port := range topo.ServerPorts["node3"] {
+		ports[port] = true
+	}
+	if len(ports) != 3 {
+		t.Errorf("destination has %d distinct server ports, want 3", len(ports))
+	}
+
+	nodeTopologies, err := GenerateNodeTopologies(topo)
+	if err != nil {
+		t.Fatalf("GenerateNodeTopologies() error = %v", err)
+	}
+	if got := len(nodeTopologies["node3"].ServerAssignments); got != 3 {
+		t.Errorf("destination has %d server assignments, want 3", got)
+	}
+
+	if _, err := NewGenerator(nodes, profiles, profile).GenerateManyToOne("node9"); err == nil {
+		t.Error("GenerateManyToOne() with an unknown destination succeeded, want error")
+	}
+}
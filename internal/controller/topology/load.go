@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NodeLoad is the traffic a topology puts on a node, in iperf3 processes
+// sending and receiving. A pair's source sends and its destination
+// receives; reverse pairs send the other way, and bidirectional pairs send
+// and receive on both nodes at once.
+type NodeLoad struct {
+	Send    int
+	Receive int
+}
+
+// Slots returns the daemon process slots the load needs, which count
+// bidirectional processes twice
+func (l *NodeLoad) Slots() int {
+	return l.Send + l.Receive
+}
+
+// NodeLoads returns the load of each node the topology tests
+func (t *Topology) NodeLoads() map[string]*NodeLoad {
+	loads := make(map[string]*NodeLoad)
+	load := func(nodeID string) *NodeLoad {
+		if loads[nodeID] == nil {
+			loads[nodeID] = &NodeLoad{}
+		}
+		return loads[nodeID]
+	}
+
+	for _, pair := range t.Pairs {
+		processes := pair.Profile.ProcessCount()
+		source, destination := load(pair.Source.ID), load(pair.Destination.ID)
+		switch {
+		case pair.Profile.RunsBidir():
+			source.Send += processes
+			source.Receive += processes
+			destination.Send += processes
+			destination.Receive += processes
+		case pair.Profile.Reverse:
+			destination.Send += processes
+			source.Receive += processes
+		default:
+			source.Send += processes
+			destination.Receive += processes
+		}
+	}
+	return loads
+}
+
+// GetClientWeight returns the weight of the client processes to run, which
+// counts bidirectional processes twice
+func (t *Topology) GetClientWeight() int {
+	weight := 0
+	for _, tests := range t.ClientTests {
+		for _, pair := range tests {
+			weight += pair.Profile.Weight()
+		}
+	}
+	return weight
+}
+
+// CheckSlotCapacity verifies that every node's daemon has the process slots
+// the topology's load needs on it, given the slots available on each.
+// Nodes missing from available are not checked.
+func (t *Topology) CheckSlotCapacity(available map[string]int) error {
+	short := t.slotShortfalls(available)
+	if len(short) == 0 {
+		return nil
+	}
+	sort.Strings(short)
+
+	return fmt.Errorf("insufficient process slots (bidirectional tests count twice): %s; "+
+		"test fewer pairs per node at once with groups or a rotating topology", strings.Join(short, "; "))
+}
+
+// slotShortfalls describes each node of available with fewer process slots
+// than the topology's load on it needs
+func (t *Topology) slotShortfalls(available map[string]int) []string {
+	short := make([]string, 0)
+	for nodeID, load := range t.NodeLoads() {
+		slots, exists := available[nodeID]
+		if exists && load.Slots() > slots {
+			short = append(short, fmt.Sprintf("%s needs %d process slots, has %d available (%d short)",
+				nodeID, load.Slots(), slots, load.Slots()-slots))
+		}
+	}
+	return short
+}
@@ -0,0 +1,48 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestTopology_NodeLoads(t *testing.T) {
+	node1, node2, node3 := &models.Node{ID: "node1"}, &models.Node{ID: "node2"}, &models.Node{ID: "node3"}
+	plain := &models.TestProfile{Name: "plain", Duration: time.Second}
+	reverse := &models.TestProfile{Name: "reverse", Duration: time.Second, Reverse: true}
+	bidir := &models.TestProfile{Name: "bidir", Duration: time.Second, Bidirectional: true, ProcessesPerPair: 2}
+	topo := &Topology{Pairs: []*TestPair{
+		{TestID: "plain", Source: node1, Destination: node2, Profile: plain},
+		{TestID: "reverse", Source: node1, Destination: node3, Profile: reverse},
+		{TestID: "bidir", Source: node2, Destination: node3, Profile: bidir},
+	}}
+	topo.ClientTests = map[string][]*TestPair{"node1": topo.Pairs[:2], "node2": topo.Pairs[2:]}
+
+	loads := topo.NodeLoads()
+	want := map[string]NodeLoad{
+		"node1": {Send: 1, Receive: 1},
+		"node2": {Send: 2, Receive: 3},
+		"node3": {Send: 3, Receive: 2},
+	}
+	for nodeID, load := range want {
+		if got := loads[nodeID]; got == nil || *got != load {
+			t.Errorf("NodeLoads()[%s] = %+v, want %+v", nodeID, got, load)
+		}
+	}
+	if got := topo.GetClientWeight(); got != 6 {
+		t.Errorf("GetClientWeight() = %d, want 6 with the bidirectional processes counted twice", got)
+	}
+
+	err := topo.CheckSlotCapacity(map[string]int{"node1": 2, "node2": 4})
+	if err == nil || !strings.Contains(err.Error(), "node2 needs 5 process slots, has 4 available (1 short)") {
+		t.Errorf("CheckSlotCapacity() error = %v, want node2 short", err)
+	}
+	if strings.Contains(err.Error(), "node1") || strings.Contains(err.Error(), "node3") {
+		t.Errorf("CheckSlotCapacity() error = %v, want only node2", err)
+	}
+	if err := topo.CheckSlotCapacity(map[string]int{"node2": 5, "node3": 5}); err != nil {
+		t.Errorf("CheckSlotCapacity() with enough slots error = %v", err)
+	}
+}
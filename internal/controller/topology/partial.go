@@ -0,0 +1,145 @@
+package topology
+
+import (
+	"math"
+	"math/rand"
+)
+
+// MeshSample describes the share of the full mesh a partial mesh topology
+// tested, with the seed that reproduces its selection
+type MeshSample struct {
+	Density   float64
+	MaxTests  int // Pair budget, when set instead of Density
+	Seed      int64
+	Pairs     int // Node pairs selected
+	MeshPairs int // Node pairs of the full mesh
+	// Selected are the node pairs selected, as source and destination IDs
+	Selected [][2]string
+}
+
+// FullMesh reports whether the sample kept every mesh pair, as a budget of
+// at least the mesh size does
+func (s *MeshSample) FullMesh() bool {
+	return s.Pairs == s.MeshPairs
+}
+
+// SetPartialMesh limits full mesh generation to a random share, density,
+// of the mesh pairs, selected deterministically from seed. Every node is
+// still a source and a destination at least once.
+func (g *Generator) SetPartialMesh(density float64, seed int64) {
+	g.density = density
+	g.seed = seed
+}
+
+// SetMeshBudget limits full mesh generation to maxTests of the mesh pairs,
+// selected deterministically from seed with each node about as often a
+// source as the others. A budget of the whole mesh or more tests it all.
+func (g *Generator) SetMeshBudget(maxTests int, seed int64) {
+	g.maxTests = maxTests
+	g.seed = seed
+}
+
+// SamplePairs returns about density of the given mesh pairs, at least one
+// per node, keeping their order. A random cycle through the nodes is taken
+// first, so that every node sends and receives, and the rest is filled at
+// random; the same seed selects the same pairs.
+func SamplePairs(pairs [][2]string, density float64, seed int64) [][2]string {
+	nodes := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, pair := range pairs {
+		for _, id := range pair {
+			if !seen[id] {
+				seen[id] = true
+				nodes = append(nodes, id)
+			}
+		}
+	}
+
+	want := int(math.Ceil(density * float64(len(pairs))))
+	if want < len(nodes) {
+		want = len(nodes)
+	}
+	if want >= len(pairs) {
+		return pairs
+	}
+
+	rng := rand.New(rand.NewSource(seed)) // #nosec G404 -- Pair selection, not security sensitive
+	chosen := make(map[[2]string]bool, want)
+	rng.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+	for i, id := range nodes {
+		chosen[[2]string{id, nodes[(i+1)%len(nodes)]}] = true
+	}
+
+	rest := make([][2]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if !chosen[pair] {
+			rest = append(rest, pair)
+		}
+	}
+	rng.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	for _, pair := range rest[:want-len(chosen)] {
+		chosen[pair] = true
+	}
+
+	return keepChosen(pairs, chosen)
+}
+
+// SampleBudget returns maxTests of the given mesh pairs, keeping their
+// order, stratified by source: the sources take turns, in an order drawn
+// from seed, each choosing among its remaining destinations one that has
+// received least so far, at random between those. Each source thus sends
+// maxTests/N pairs, give or take one, unless it runs out of destinations,
+// and destinations receive nearly as evenly. The same seed selects the
+// same pairs.
+func SampleBudget(pairs [][2]string, maxTests int, seed int64) [][2]string {
+	if maxTests >= len(pairs) {
+		return pairs
+	}
+
+	rng := rand.New(rand.NewSource(seed)) // #nosec G404 -- Pair selection, not security sensitive
+	sources := make([]string, 0)
+	destinations := make(map[string][]string)
+	for _, pair := range pairs {
+		if _, seen := destinations[pair[0]]; !seen {
+			sources = append(sources, pair[0])
+		}
+		destinations[pair[0]] = append(destinations[pair[0]], pair[1])
+	}
+	rng.Shuffle(len(sources), func(i, j int) { sources[i], sources[j] = sources[j], sources[i] })
+	for _, source := range sources {
+		dests := destinations[source]
+		rng.Shuffle(len(dests), func(i, j int) { dests[i], dests[j] = dests[j], dests[i] })
+	}
+
+	chosen := make(map[[2]string]bool, maxTests)
+	received := make(map[string]int)
+	for len(chosen) < maxTests {
+		for _, source := range sources {
+			dests := destinations[source]
+			if len(dests) == 0 || len(chosen) == maxTests {
+				continue
+			}
+			best := 0
+			for i, dest := range dests {
+				if received[dest] < received[dests[best]] {
+					best = i
+				}
+			}
+			chosen[[2]string{source, dests[best]}] = true
+			received[dests[best]]++
+			destinations[source] = append(dests[:best], dests[best+1:]...)
+		}
+	}
+	return keepChosen(pairs, chosen)
+}
+
+// keepChosen returns the chosen pairs of pairs, in order
+func keepChosen(pairs [][2]string, chosen map[[2]string]bool) [][2]string {
+	selected := make([][2]string, 0, len(chosen))
+	for _, pair := range pairs {
+		if chosen[pair] {
+			selected = append(selected, pair)
+		}
+	}
+	return selected
+}
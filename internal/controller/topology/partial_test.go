@@ -0,0 +1,153 @@
+package topology
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestSamplePairs(t *testing.T) {
+	nodes, _ := newTestRegistries(t, 10, &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1})
+	mesh := MeshPairs(nodes)
+
+	tests := []struct {
+		density   float64
+		wantPairs int
+	}{
+		{0.01, 10}, // Raised to one pair per node
+		{0.25, 23},
+		{0.5, 45},
+		{1, 90},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.density), func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				sample := SamplePairs(mesh, tt.density, seed)
+				if len(sample) != tt.wantPairs {
+					t.Fatalf("seed %d: %d pairs, want %d", seed, len(sample), tt.wantPairs)
+				}
+
+				sends := make(map[string]bool)
+				receives := make(map[string]bool)
+				for _, pair := range sample {
+					sends[pair[0]] = true
+					receives[pair[1]] = true
+				}
+				if len(sends) != 10 || len(receives) != 10 {
+					t.Fatalf("seed %d: %d nodes send and %d receive, want all 10", seed, len(sends), len(receives))
+				}
+			}
+		})
+	}
+
+	// The seed alone decides the selection
+	first := fmt.Sprint(SamplePairs(mesh, 0.3, 42))
+	if again := fmt.Sprint(SamplePairs(mesh, 0.3, 42)); again != first {
+		t.Errorf("same seed selected %s, then %s", first, again)
+	}
+	if other := fmt.Sprint(SamplePairs(mesh, 0.3, 43)); other == first {
+		t.Errorf("seeds 42 and 43 selected the same pairs")
+	}
+}
+
+func TestGenerator_PartialMesh(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 6, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetPartialMesh(0.4, 7)
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.Sample == nil || len(topo.Sample.Selected) != 12 {
+		t.Fatalf("Sample = %+v, want 12 selected pairs", topo.Sample)
+	}
+	sample := *topo.Sample
+	sample.Selected = nil
+	if want := (MeshSample{Density: 0.4, Seed: 7, Pairs: 12, MeshPairs: 30}); !reflect.DeepEqual(sample, want) {
+		t.Errorf("Sample = %+v, want %+v", sample, want)
+	}
+	if len(topo.Pairs) != 12 {
+		t.Errorf("topology has %d pairs, want 12", len(topo.Pairs))
+	}
+	if _, err := GenerateNodeTopologies(topo); err != nil {
+		t.Errorf("GenerateNodeTopologies() error = %v", err)
+	}
+}
+
+func TestSampleBudget(t *testing.T) {
+	nodes, _ := newTestRegistries(t, 10, &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1})
+	mesh := MeshPairs(nodes)
+	ids := make(map[string]bool)
+	for _, pair := range mesh {
+		ids[pair[0]] = true
+	}
+
+	for _, budget := range []int{5, 25, 60} {
+		for seed := int64(1); seed <= 20; seed++ {
+			selected := SampleBudget(mesh, budget, seed)
+			if len(selected) != budget {
+				t.Fatalf("budget %d, seed %d: selected %d pairs", budget, seed, len(selected))
+			}
+			sends := make(map[string]int)
+			receives := make(map[string]int)
+			for _, pair := range selected {
+				sends[pair[0]]++
+				receives[pair[1]]++
+			}
+			// Sources send budget/N pairs, give or take one, and destinations
+			// receive within one more of that
+			low, high := budget/len(ids), (budget+len(ids)-1)/len(ids)
+			for id := range ids {
+				if sends[id] < low || sends[id] > high {
+					t.Fatalf("budget %d, seed %d: %s sends %d, want %d to %d", budget, seed, id, sends[id], low, high)
+				}
+				if receives[id] < low-1 || receives[id] > high+1 {
+					t.Fatalf("budget %d, seed %d: %s receives %d, want %d to %d", budget, seed, id, receives[id], low-1, high+1)
+				}
+			}
+		}
+	}
+
+	first := fmt.Sprint(SampleBudget(mesh, 30, 42))
+	if again := fmt.Sprint(SampleBudget(mesh, 30, 42)); again != first {
+		t.Errorf("same seed selected %s, then %s", first, again)
+	}
+	if other := fmt.Sprint(SampleBudget(mesh, 30, 43)); other == first {
+		t.Errorf("seeds 42 and 43 selected the same pairs")
+	}
+	if all := SampleBudget(mesh, 200, 1); len(all) != len(mesh) {
+		t.Errorf("budget above the mesh size selected %d pairs, want all %d", len(all), len(mesh))
+	}
+}
+
+func TestGenerator_MeshBudget(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 6, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetMeshBudget(9, 7)
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if topo.Sample == nil || topo.Sample.MaxTests != 9 || topo.Sample.Pairs != 9 || topo.Sample.FullMesh() {
+		t.Errorf("Sample = %+v, want 9 of 30 pairs", topo.Sample)
+	}
+	if len(topo.Pairs) != 9 {
+		t.Errorf("topology has %d pairs, want 9", len(topo.Pairs))
+	}
+
+	gen.SetMeshBudget(100, 7)
+	if topo, err = gen.GenerateFullMesh(); err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if !topo.Sample.FullMesh() || len(topo.Pairs) != 30 {
+		t.Errorf("budget above the mesh size generated %d pairs, want the full mesh of 30", len(topo.Pairs))
+	}
+}
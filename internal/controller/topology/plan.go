@@ -0,0 +1,166 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+)
+
+// PlannedPair is the serializable form of a test pair with its allocated
+// server ports, so that servers and clients can be started by separate
+// invocations
+type PlannedPair struct {
+	TestID          string      `json:"test_id" yaml:"test_id"`
+	Source          string      `json:"source" yaml:"source"`
+	Destination     string      `json:"destination" yaml:"destination"`
+	Ports           []int32     `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Profile         string      `json:"profile" yaml:"profile"`
+	Params          *PlanParams `json:"params,omitempty" yaml:"params,omitempty"`
+	DurationSeconds float64     `json:"duration_seconds,omitempty" yaml:"duration_seconds,omitempty"`
+	StartDelayMs    int         `json:"start_delay_ms,omitempty" yaml:"start_delay_ms,omitempty"`
+	Background      bool        `json:"background,omitempty" yaml:"background,omitempty"`
+	Family          string      `json:"family,omitempty" yaml:"family,omitempty"`
+	Interface       string      `json:"interface,omitempty" yaml:"interface,omitempty"`
+	Hop             int         `json:"hop,omitempty" yaml:"hop,omitempty"`
+	EndToEnd        bool        `json:"end_to_end,omitempty" yaml:"end_to_end,omitempty"`
+	Group           string      `json:"group,omitempty" yaml:"group,omitempty"`
+	// RepeatOf is the test ID of the repeated pair a test is run Repetition
+	// of, for results to be summarized across its repetitions
+	RepeatOf   string `json:"repeat_of,omitempty" yaml:"repeat_of,omitempty"`
+	Repetition int    `json:"repetition,omitempty" yaml:"repetition,omitempty"`
+	// Labels are free-form tags carried into the pair's results
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Plan returns the topology's pairs in serializable form
+func (t *Topology) Plan() []PlannedPair {
+	plan := make([]PlannedPair, 0, len(t.Pairs))
+	for _, pair := range t.Pairs {
+		plan = append(plan, PlannedPair{
+			TestID:          pair.TestID,
+			Source:          pair.Source.ID,
+			Destination:     pair.Destination.ID,
+			Ports:           pair.Ports,
+			Profile:         pair.Profile.Name,
+			Params:          pair.Params,
+			DurationSeconds: pair.Duration.Seconds(),
+			StartDelayMs:    pair.StartDelayMs,
+			Background:      pair.Background,
+			Family:          string(pair.Family),
+			Interface:       pair.Interface,
+			Hop:             pair.Hop,
+			EndToEnd:        pair.EndToEnd,
+			Group:           pair.Group,
+			RepeatOf:        pair.RepeatOf,
+			Repetition:      pair.Repetition,
+			Labels:          pair.Labels,
+		})
+	}
+	return plan
+}
+
+// FromPlan rebuilds a topology from planned pairs, resolving nodes and
+// profiles by name. Ports are taken as planned rather than allocated.
+func FromPlan(plan []PlannedPair, nodes *models.NodeRegistry, profiles *models.ProfileRegistry) (*Topology, error) {
+	if len(plan) == 0 {
+		return nil, fmt.Errorf("plan contains no pairs")
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(plan)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	seen := make(map[string]bool, len(plan))
+	for _, planned := range plan {
+		pair, err := resolvePlannedPair(planned, nodes, profiles, seen)
+		if err != nil {
+			return nil, err
+		}
+		if len(planned.Ports) < pair.Profile.ProcessCount() {
+			return nil, fmt.Errorf("test %s: %d ports planned but profile %s runs %d processes",
+				planned.TestID, len(planned.Ports), pair.Profile.Name, pair.Profile.ProcessCount())
+		}
+		topology.Pairs = append(topology.Pairs, pair)
+		topology.ServerPorts[pair.Destination.ID] = append(topology.ServerPorts[pair.Destination.ID], pair.Ports...)
+		topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+	}
+
+	return topology, nil
+}
+
+// resolvePlannedPair builds the test pair of a planned pair, resolving its
+// nodes and profile and applying its parameters. seen holds the test IDs
+// resolved so far, which must be unique.
+func resolvePlannedPair(planned PlannedPair, nodes *models.NodeRegistry, profiles *models.ProfileRegistry,
+	seen map[string]bool) (*TestPair, error) {
+	if planned.TestID == "" {
+		return nil, fmt.Errorf("planned pair %s -> %s has no test ID", planned.Source, planned.Destination)
+	}
+	if seen[planned.TestID] {
+		return nil, fmt.Errorf("duplicate test ID in plan: %s", planned.TestID)
+	}
+	seen[planned.TestID] = true
+
+	source, err := nodes.GetNode(planned.Source)
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", planned.TestID, err)
+	}
+	dest, err := nodes.GetNode(planned.Destination)
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", planned.TestID, err)
+	}
+	if source.ID == dest.ID {
+		return nil, fmt.Errorf("test %s: source and destination must differ (%s)", planned.TestID, source.ID)
+	}
+	profile, err := profiles.GetProfile(planned.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("test %s: %w", planned.TestID, err)
+	}
+	if planned.Params != nil {
+		if profile, err = planned.Params.apply(profile); err != nil {
+			return nil, fmt.Errorf("test %s: %w", planned.TestID, err)
+		}
+	}
+	if planned.DurationSeconds < 0 || planned.StartDelayMs < 0 {
+		return nil, fmt.Errorf("test %s: duration and start delay cannot be negative", planned.TestID)
+	}
+	var family models.AddressFamily
+	if planned.Family != "" {
+		if family, err = models.ParseAddressFamily(planned.Family); err != nil {
+			return nil, fmt.Errorf("test %s: %w", planned.TestID, err)
+		}
+		if _, ok := dest.DataAddressFor(family); !ok {
+			return nil, fmt.Errorf("test %s: %s has no %s data address", planned.TestID, dest.ID, family)
+		}
+	}
+	if planned.Interface != "" {
+		for _, node := range []*models.Node{source, dest} {
+			if _, ok := node.InterfaceAddress(planned.Interface); !ok {
+				return nil, fmt.Errorf("test %s: %s has no interface %s", planned.TestID, node.ID, planned.Interface)
+			}
+		}
+	}
+
+	return &TestPair{
+		TestID:       planned.TestID,
+		Source:       source,
+		Destination:  dest,
+		Profile:      profile,
+		Params:       planned.Params,
+		Ports:        planned.Ports,
+		Group:        planned.Group,
+		Labels:       planned.Labels,
+		Background:   planned.Background,
+		Duration:     units.Seconds(planned.DurationSeconds),
+		StartDelayMs: planned.StartDelayMs,
+		Family:       family,
+		Interface:    planned.Interface,
+		Hop:          planned.Hop,
+		EndToEnd:     planned.EndToEnd,
+		RepeatOf:     planned.RepeatOf,
+		Repetition:   planned.Repetition,
+	}, nil
+}
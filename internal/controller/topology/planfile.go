@@ -0,0 +1,349 @@
+package topology
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// PlanVersion is the version of the plan file format read and written by
+// this build
+const PlanVersion = 1
+
+// PlanFile is a complete test plan: the pairs to run, with their profiles,
+// timing and labels, and the groups they run in. Plans exported from a
+// generated topology import unchanged, and other tools can write plans to
+// have an exact set of pairs run.
+type PlanFile struct {
+	Version int            `json:"version" yaml:"version"`
+	Groups  []PlannedGroup `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Pairs   []PlannedPair  `json:"pairs" yaml:"pairs"`
+}
+
+// PlannedGroup is a group of a plan. Its pairs name it under group; they
+// start once the groups listed under after allow it.
+type PlannedGroup struct {
+	Name       string   `json:"name" yaml:"name"`
+	After      []string `json:"after,omitempty" yaml:"after,omitempty"`
+	Background bool     `json:"background,omitempty" yaml:"background,omitempty"`
+}
+
+// PlanParams are profile settings of a single planned pair, replacing those
+// of its named profile. Unset fields keep the profile's value.
+type PlanParams struct {
+	Protocol          string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Bandwidth         string `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty"`
+	WindowSize        string `json:"window_size,omitempty" yaml:"window_size,omitempty"`
+	Parallel          int    `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	CongestionControl string `json:"congestion_control,omitempty" yaml:"congestion_control,omitempty"`
+	MSS               int    `json:"mss,omitempty" yaml:"mss,omitempty"`
+	TOS               int    `json:"tos,omitempty" yaml:"tos,omitempty"`
+	Reverse           *bool  `json:"reverse,omitempty" yaml:"reverse,omitempty"`
+	Bidirectional     *bool  `json:"bidirectional,omitempty" yaml:"bidirectional,omitempty"`
+}
+
+// apply returns a copy of profile with the parameters set. The copy keeps
+// the profile's name, so that results are still reported by profile.
+func (p *PlanParams) apply(profile *models.TestProfile) (*models.TestProfile, error) {
+	derived := profile.Clone()
+	switch models.Protocol(p.Protocol) {
+	case "":
+	case models.ProtocolTCP, models.ProtocolUDP:
+		derived.Protocol = models.Protocol(p.Protocol)
+	default:
+		return nil, fmt.Errorf("params: protocol must be tcp or udp, got %q", p.Protocol)
+	}
+	if p.Bandwidth != "" {
+		derived.Bandwidth = p.Bandwidth
+	}
+	if p.WindowSize != "" {
+		derived.WindowSize = p.WindowSize
+	}
+	if p.Parallel != 0 {
+		derived.Parallel = p.Parallel
+	}
+	if p.CongestionControl != "" {
+		derived.CongestionControl = p.CongestionControl
+	}
+	if p.MSS != 0 {
+		derived.MSS = p.MSS
+	}
+	if p.TOS != 0 {
+		derived.TOS = p.TOS
+	}
+	if p.Reverse != nil {
+		derived.Reverse = *p.Reverse
+	}
+	if p.Bidirectional != nil {
+		derived.Bidirectional = *p.Bidirectional
+	}
+	if err := derived.Validate(); err != nil {
+		return nil, fmt.Errorf("params: %w", err)
+	}
+	return derived, nil
+}
+
+// Export returns the topology as a plan file
+func (t *Topology) Export() *PlanFile {
+	plan := &PlanFile{Version: PlanVersion, Pairs: t.Plan()}
+	for _, group := range t.Groups {
+		plan.Groups = append(plan.Groups, PlannedGroup{
+			Name:       group.Name,
+			After:      group.After,
+			Background: group.Background,
+		})
+	}
+	return plan
+}
+
+// ReadPlanFile reads the plan file at path, as YAML if its name ends in
+// .yaml or .yml and as JSON otherwise, and returns it with the hex SHA-256
+// of its contents. Unknown fields and versions are rejected.
+func ReadPlanFile(path string) (*PlanFile, string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- Plan file path is provided by user
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read plan file: %w", err)
+	}
+
+	plan := &PlanFile{}
+	if isYAML(path) {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(plan)
+	} else {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(plan)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse plan file %s: %w", path, err)
+	}
+	if plan.Version == 0 {
+		return nil, "", fmt.Errorf("plan file %s has no version", path)
+	}
+	if plan.Version > PlanVersion {
+		return nil, "", fmt.Errorf("plan file %s has version %d; this build reads version %d and older",
+			path, plan.Version, PlanVersion)
+	}
+
+	sum := sha256.Sum256(data)
+	return plan, hex.EncodeToString(sum[:]), nil
+}
+
+// WritePlanFile writes plan to path, as YAML if its name ends in .yaml or
+// .yml and as indented JSON otherwise
+func WritePlanFile(path string, plan *PlanFile) error {
+	var data []byte
+	var err error
+	if isYAML(path) {
+		data, err = yaml.Marshal(plan)
+	} else {
+		data, err = json.MarshalIndent(plan, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write plan file: %w", err)
+	}
+	return nil
+}
+
+// isYAML reports whether path names a YAML file
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// Nodes returns the IDs of the nodes the plan's pairs run between, sorted
+func (p *PlanFile) Nodes() []string {
+	seen := make(map[string]bool)
+	for _, pair := range p.Pairs {
+		seen[pair.Source] = true
+		seen[pair.Destination] = true
+	}
+	nodes := make([]string, 0, len(seen))
+	for nodeID := range seen {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Restrict returns the plan without the pairs of nodes missing from nodes,
+// and how many pairs were left out. Groups left without pairs are dropped,
+// and the groups that ran after them run after their dependencies instead.
+func (p *PlanFile) Restrict(nodes *models.NodeRegistry) (*PlanFile, int) {
+	restricted := &PlanFile{Version: p.Version, Pairs: make([]PlannedPair, 0, len(p.Pairs))}
+	used := make(map[string]bool)
+	for _, pair := range p.Pairs {
+		if _, err := nodes.GetNode(pair.Source); err != nil {
+			continue
+		}
+		if _, err := nodes.GetNode(pair.Destination); err != nil {
+			continue
+		}
+		restricted.Pairs = append(restricted.Pairs, pair)
+		used[pair.Group] = true
+	}
+	if len(restricted.Pairs) == len(p.Pairs) {
+		restricted.Groups = p.Groups
+		return restricted, 0
+	}
+
+	after := make(map[string][]string, len(p.Groups))
+	for _, group := range p.Groups {
+		after[group.Name] = group.After
+	}
+	// remaining returns the groups left that name stands for as a dependency
+	visiting := make(map[string]bool)
+	var remaining func(name string) []string
+	remaining = func(name string) []string {
+		if used[name] {
+			return []string{name}
+		}
+		if visiting[name] {
+			return nil // A cycle, rejected on import
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+		deps := make([]string, 0)
+		for _, dep := range after[name] {
+			deps = append(deps, remaining(dep)...)
+		}
+		return deps
+	}
+	for _, group := range p.Groups {
+		if !used[group.Name] {
+			continue
+		}
+		kept := group
+		kept.After = nil
+		seen := make(map[string]bool)
+		for _, dep := range group.After {
+			for _, name := range remaining(dep) {
+				if !seen[name] {
+					seen[name] = true
+					kept.After = append(kept.After, name)
+				}
+			}
+		}
+		restricted.Groups = append(restricted.Groups, kept)
+	}
+	return restricted, len(p.Pairs) - len(restricted.Pairs)
+}
+
+// ImportPlan builds the topology of a plan file instead of generating one.
+// Pairs run exactly as planned: overrides, address families and background
+// extension do not apply. Server ports are allocated from the port range
+// unless the plan sets them for every pair. When the plan has groups, every
+// pair must name one.
+func (g *Generator) ImportPlan(plan *PlanFile) (*Topology, error) {
+	if len(plan.Pairs) == 0 {
+		return nil, fmt.Errorf("plan contains no pairs")
+	}
+
+	specs := make([]GroupSpec, 0, len(plan.Groups))
+	for _, group := range plan.Groups {
+		specs = append(specs, GroupSpec{Name: group.Name, After: group.After, Background: group.Background})
+	}
+	specs, err := orderGroups(specs)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]*Group, len(specs))
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(plan.Pairs)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+	for _, spec := range specs {
+		group := &Group{Name: spec.Name, After: spec.After, Background: spec.Background}
+		groups[spec.Name] = group
+		topology.Groups = append(topology.Groups, group)
+	}
+
+	seen := make(map[string]bool, len(plan.Pairs))
+	planned := 0
+	for _, plannedPair := range plan.Pairs {
+		pair, err := resolvePlannedPair(plannedPair, g.nodes, g.profiles, seen)
+		if err != nil {
+			return nil, err
+		}
+		if len(pair.Ports) > 0 {
+			planned++
+			if len(pair.Ports) != pair.Profile.ProcessCount() {
+				return nil, fmt.Errorf("test %s: %d ports planned but profile %s runs %d processes",
+					pair.TestID, len(pair.Ports), pair.Profile.Name, pair.Profile.ProcessCount())
+			}
+		}
+		if len(groups) > 0 {
+			group, exists := groups[pair.Group]
+			if !exists {
+				return nil, fmt.Errorf("test %s: plan has groups but the pair names none of them (group %q)",
+					pair.TestID, pair.Group)
+			}
+			if pair.Background {
+				return nil, fmt.Errorf("test %s: grouped pairs cannot be background; use a background group instead", pair.TestID)
+			}
+			group.Pairs = append(group.Pairs, pair)
+		} else if pair.Group != "" {
+			return nil, fmt.Errorf("test %s: unknown group %q", pair.TestID, pair.Group)
+		}
+		topology.Pairs = append(topology.Pairs, pair)
+		topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+	}
+
+	for _, group := range topology.Groups {
+		if len(group.Pairs) == 0 {
+			return nil, fmt.Errorf("group %s has no pairs", group.Name)
+		}
+	}
+
+	switch planned {
+	case 0:
+		if err := allocateServerPorts(topology, g.nodes.GetAllNodes(), g.portStart, g.portEnd); err != nil {
+			return nil, err
+		}
+	case len(topology.Pairs):
+		if err := assignPlannedPorts(topology); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("plan sets ports for %d of %d pairs; set them for every pair or none", planned, len(topology.Pairs))
+	}
+
+	return topology, nil
+}
+
+// assignPlannedPorts records the planned ports of every pair as the
+// topology's server ports, rejecting ports planned twice on a node
+func assignPlannedPorts(topology *Topology) error {
+	owners := make(map[string]map[int32]string)
+	for _, pair := range topology.Pairs {
+		destID := pair.Destination.ID
+		if owners[destID] == nil {
+			owners[destID] = make(map[int32]string)
+		}
+		for _, port := range pair.Ports {
+			if owner, taken := owners[destID][port]; taken {
+				return fmt.Errorf("test %s: port %d on %s is also planned for %s", pair.TestID, port, destID, owner)
+			}
+			owners[destID][port] = pair.TestID
+		}
+		topology.ServerPorts[destID] = append(topology.ServerPorts[destID], pair.Ports...)
+	}
+	topology.PinnedPorts = true
+	return nil
+}
@@ -0,0 +1,151 @@
+package topology
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestPlanFile_RoundTrip(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.AddGroup(GroupSpec{Name: "load", Background: true, Pairs: []GroupPairSpec{{SourceID: "node3", DestID: "node2"}}})
+	gen.AddGroup(GroupSpec{Name: "victim", After: []string{"load"}, Pairs: []GroupPairSpec{{SourceID: "node1", DestID: "node2"}}})
+	topo, err := gen.GenerateGroups()
+	if err != nil {
+		t.Fatalf("GenerateGroups() error = %v", err)
+	}
+	want, _ := json.Marshal(topo.Export())
+
+	for _, name := range []string{"plan.json", "plan.yaml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := WritePlanFile(path, topo.Export()); err != nil {
+			t.Fatalf("WritePlanFile(%s) error = %v", name, err)
+		}
+		plan, sum, err := ReadPlanFile(path)
+		if err != nil {
+			t.Fatalf("ReadPlanFile(%s) error = %v", name, err)
+		}
+		if len(sum) != 64 {
+			t.Errorf("ReadPlanFile(%s) hash = %q, want a hex SHA-256", name, sum)
+		}
+
+		imported, err := NewGenerator(nodes, profiles, profile).ImportPlan(plan)
+		if err != nil {
+			t.Fatalf("ImportPlan(%s) error = %v", name, err)
+		}
+		if got, _ := json.Marshal(imported.Export()); string(got) != string(want) {
+			t.Errorf("%s: Export() after import = %s, want %s", name, got, want)
+		}
+		if len(imported.Groups) != 2 || !imported.Groups[0].Background || len(imported.Groups[1].Pairs) != 1 {
+			t.Errorf("%s: imported groups = %+v, want load then victim", name, imported.Groups)
+		}
+	}
+}
+
+func TestGenerator_ImportPlan(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+	reverse := true
+	plan := &PlanFile{
+		Version: PlanVersion,
+		Pairs: []PlannedPair{
+			{TestID: "a", Source: "node1", Destination: "node2", Profile: "default",
+				Params: &PlanParams{Parallel: 4, Reverse: &reverse}, Labels: map[string]string{"fault": "link-down"}},
+			{TestID: "b", Source: "node2", Destination: "node3", Profile: "default", StartDelayMs: 1000},
+		},
+	}
+
+	topo, err := NewGenerator(nodes, profiles, profile).ImportPlan(plan)
+	if err != nil {
+		t.Fatalf("ImportPlan() error = %v", err)
+	}
+	a := topo.Pairs[0]
+	if a.Profile.Name != "default" || a.Profile.Parallel != 4 || !a.Profile.Reverse || profile.Parallel != 1 {
+		t.Errorf("pair a profile = %+v, want default with 4 reversed streams, leaving the profile alone", a.Profile)
+	}
+	if len(a.Ports) != 1 || len(topo.ServerPorts["node3"]) != 1 {
+		t.Errorf("ports = %v, want one allocated per pair", topo.ServerPorts)
+	}
+	if labels := topo.TestLabels(); labels["a"]["fault"] != "link-down" || len(labels) != 1 {
+		t.Errorf("TestLabels() = %v, want a labelled only", labels)
+	}
+
+	tests := []struct {
+		name   string
+		change func(plan *PlanFile)
+		want   string
+	}{
+		{"unknown group", func(plan *PlanFile) { plan.Pairs[0].Group = "nope" }, "unknown group"},
+		{"ungrouped pair", func(plan *PlanFile) {
+			plan.Groups = []PlannedGroup{{Name: "g"}}
+			plan.Pairs[0].Group = "g"
+		}, "names none of them"},
+		{"some ports", func(plan *PlanFile) { plan.Pairs[0].Ports = []int32{6000} }, "1 of 2 pairs"},
+		{"port twice", func(plan *PlanFile) {
+			plan.Pairs[1].Source, plan.Pairs[1].Destination = "node3", "node2"
+			plan.Pairs[0].Ports = []int32{6000}
+			plan.Pairs[1].Ports = []int32{6000}
+		}, "also planned"},
+		{"bad params", func(plan *PlanFile) { plan.Pairs[0].Params = &PlanParams{Protocol: "sctp"} }, "protocol"},
+		{"unknown node", func(plan *PlanFile) { plan.Pairs[0].Source = "node9" }, "node9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := &PlanFile{Version: PlanVersion, Pairs: append([]PlannedPair{}, plan.Pairs...)}
+			tt.change(changed)
+			_, err := NewGenerator(nodes, profiles, profile).ImportPlan(changed)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ImportPlan() error = %v, want one mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPlanFile_Rejects(t *testing.T) {
+	tests := map[string]string{
+		"no version":     `{"pairs": []}`,
+		"newer version":  `{"version": 99, "pairs": []}`,
+		"unknown fields": `{"version": 1, "pairs": [{"test_id": "a", "sorce": "node1"}]}`,
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "plan.json")
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := ReadPlanFile(path); err == nil {
+			t.Errorf("%s: ReadPlanFile() succeeded, want error", name)
+		}
+	}
+}
+
+func TestPlanFile_Restrict(t *testing.T) {
+	nodes, _ := newTestRegistries(t, 2)
+	plan := &PlanFile{
+		Version: PlanVersion,
+		Groups:  []PlannedGroup{{Name: "first"}, {Name: "middle", After: []string{"first"}}, {Name: "last", After: []string{"middle"}}},
+		Pairs: []PlannedPair{
+			{TestID: "a", Source: "node1", Destination: "node2", Group: "first"},
+			{TestID: "b", Source: "node3", Destination: "node2", Group: "middle"},
+			{TestID: "c", Source: "node2", Destination: "node1", Group: "last"},
+		},
+	}
+
+	// node3 is not part of the run, so middle is left empty and last now
+	// follows first directly
+	restricted, dropped := plan.Restrict(nodes)
+	if dropped != 1 || len(restricted.Pairs) != 2 {
+		t.Fatalf("Restrict() dropped %d, kept %d pairs; want 1 and 2", dropped, len(restricted.Pairs))
+	}
+	if len(restricted.Groups) != 2 || restricted.Groups[1].Name != "last" || fmt.Sprint(restricted.Groups[1].After) != "[first]" {
+		t.Errorf("Restrict() groups = %+v, want first, then last after first", restricted.Groups)
+	}
+}
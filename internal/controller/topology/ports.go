@@ -0,0 +1,110 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PortCapacity describes the server ports a node's daemon provides
+type PortCapacity struct {
+	Start     int32 // Inclusive range of the daemon's server ports
+	End       int32
+	Total     int
+	Available int // Ports not allocated to other tests; Total when unknown
+}
+
+// IncomingPorts returns the number of server ports each destination node
+// needs: one per iperf3 process of every pair it receives
+func (t *Topology) IncomingPorts() map[string]int {
+	needed := make(map[string]int, len(t.ServerPorts))
+	for nodeID, ports := range t.ServerPorts {
+		if len(ports) > 0 {
+			needed[nodeID] = len(ports)
+		}
+	}
+	return needed
+}
+
+// CheckPortCapacity verifies that every node's daemon provides the server
+// ports the topology needs on it. Nodes missing from capacity are not
+// checked. The error names every short node and by how much, such as
+// "node7 needs 240 server ports, daemon range 5201-5400 provides 200 (40
+// short)".
+func (t *Topology) CheckPortCapacity(capacity map[string]PortCapacity) error {
+	short := t.portShortfalls(capacity)
+	if len(short) == 0 {
+		return nil
+	}
+	sort.Strings(short)
+
+	return fmt.Errorf("insufficient server ports: %s; increase the daemons' port_range, "+
+		"or test fewer pairs per destination at once with groups or a rotating topology",
+		strings.Join(short, "; "))
+}
+
+// portShortfalls describes each node of capacity whose daemon has fewer
+// server ports than the pairs it receives need
+func (t *Topology) portShortfalls(capacity map[string]PortCapacity) []string {
+	short := make([]string, 0)
+	for nodeID, needed := range t.IncomingPorts() {
+		ports, exists := capacity[nodeID]
+		if !exists {
+			continue
+		}
+		switch {
+		case needed > ports.Total:
+			short = append(short, fmt.Sprintf("%s needs %d server ports, daemon range %d-%d provides %d (%d short)",
+				nodeID, needed, ports.Start, ports.End, ports.Total, needed-ports.Total))
+		case needed > ports.Available:
+			short = append(short, fmt.Sprintf("%s needs %d server ports, daemon range %d-%d has %d of %d free (%d short)",
+				nodeID, needed, ports.Start, ports.End, ports.Available, ports.Total, needed-ports.Available))
+		}
+	}
+	return short
+}
+
+// AssignServerPorts replaces the server ports of each node in allocated
+// with the ports its daemon allocated, in order, updating the pairs it
+// receives to match. Nodes missing from allocated keep their ports. The
+// error names every node whose daemon allocated fewer ports than its pairs
+// need; no ports are replaced then.
+func (t *Topology) AssignServerPorts(allocated map[string][]int32) error {
+	short := make([]string, 0)
+	for nodeID, needed := range t.IncomingPorts() {
+		if ports, exists := allocated[nodeID]; exists && len(ports) < needed {
+			short = append(short, fmt.Sprintf("%s allocated %d server ports, its pairs need %d",
+				nodeID, len(ports), needed))
+		}
+	}
+	if len(short) > 0 {
+		sort.Strings(short)
+		return fmt.Errorf("daemons could not allocate enough server ports: %s", strings.Join(short, "; "))
+	}
+
+	remap := make(map[string]map[int32]int32, len(allocated))
+	for nodeID, ports := range allocated {
+		current := t.ServerPorts[nodeID]
+		if len(current) == 0 {
+			continue
+		}
+		remap[nodeID] = make(map[int32]int32, len(current))
+		for i, port := range current {
+			remap[nodeID][port] = ports[i]
+		}
+		t.ServerPorts[nodeID] = append([]int32(nil), ports[:len(current)]...)
+	}
+
+	for _, pair := range t.Pairs {
+		ports, exists := remap[pair.Destination.ID]
+		if !exists {
+			continue
+		}
+		remapped := make([]int32, len(pair.Ports))
+		for i, port := range pair.Ports {
+			remapped[i] = ports[port]
+		}
+		pair.Ports = remapped
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package topology
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestTopology_CheckPortCapacity(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1, ProcessesPerPair: 2}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	// Each node receives 3 pairs of 2 processes
+	if got := topo.IncomingPorts()["node1"]; got != 6 {
+		t.Fatalf("IncomingPorts()[node1] = %d, want 6", got)
+	}
+
+	capacity := map[string]PortCapacity{
+		"node1": {Start: 5201, End: 5206, Total: 6, Available: 6},
+		"node2": {Start: 5201, End: 5205, Total: 5, Available: 5},
+		"node3": {Start: 5201, End: 5210, Total: 10, Available: 4},
+	}
+	err = topo.CheckPortCapacity(capacity)
+	if err == nil {
+		t.Fatal("CheckPortCapacity() expected error")
+	}
+	for _, want := range []string{
+		"node2 needs 6 server ports, daemon range 5201-5205 provides 5 (1 short)",
+		"node3 needs 6 server ports, daemon range 5201-5210 has 4 of 10 free (2 short)",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("CheckPortCapacity() error = %v, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "node1") || strings.Contains(err.Error(), "node4") {
+		t.Errorf("CheckPortCapacity() error = %v, want only node2 and node3", err)
+	}
+
+	delete(capacity, "node2")
+	delete(capacity, "node3")
+	if err := topo.CheckPortCapacity(capacity); err != nil {
+		t.Errorf("CheckPortCapacity() error = %v", err)
+	}
+}
+
+func TestTopology_AssignServerPorts(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1, ProcessesPerPair: 2}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	node2Ports := append([]int32(nil), topo.ServerPorts["node2"]...)
+
+	// Each node receives 2 pairs of 2 processes
+	err = topo.AssignServerPorts(map[string][]int32{"node1": {7001, 7002, 7003}})
+	if err == nil || !strings.Contains(err.Error(), "node1 allocated 3 server ports, its pairs need 4") {
+		t.Fatalf("AssignServerPorts() error = %v, want node1 short", err)
+	}
+
+	allocated := []int32{7001, 7002, 7003, 7004}
+	if err := topo.AssignServerPorts(map[string][]int32{"node1": allocated}); err != nil {
+		t.Fatalf("AssignServerPorts() error = %v", err)
+	}
+	if !reflect.DeepEqual(topo.ServerPorts["node1"], allocated) {
+		t.Errorf("ServerPorts[node1] = %v, want %v", topo.ServerPorts["node1"], allocated)
+	}
+	if !reflect.DeepEqual(topo.ServerPorts["node2"], node2Ports) {
+		t.Errorf("ServerPorts[node2] = %v, want it unchanged at %v", topo.ServerPorts["node2"], node2Ports)
+	}
+
+	received := make([]int32, 0)
+	for _, pair := range topo.Pairs {
+		if pair.Destination.ID == "node1" {
+			received = append(received, pair.Ports...)
+		}
+	}
+	if !reflect.DeepEqual(received, allocated) {
+		t.Errorf("ports of the pairs to node1 = %v, want %v", received, allocated)
+	}
+}
@@ -0,0 +1,99 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// RingNodes returns the nodes of a ring in order: those listed in order
+// first, then the rest in node order
+func RingNodes(registry *models.NodeRegistry, order []string) ([]*models.Node, error) {
+	nodes := make([]*models.Node, 0, registry.Count())
+	listed := make(map[string]bool, len(order))
+	for _, id := range order {
+		if listed[id] {
+			return nil, fmt.Errorf("ring order lists node %q more than once", id)
+		}
+		node, err := registry.GetNode(id)
+		if err != nil {
+			return nil, fmt.Errorf("ring order: %w", err)
+		}
+		listed[id] = true
+		nodes = append(nodes, node)
+	}
+	for _, node := range registry.GetAllNodes() {
+		if !listed[node.ID] {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
+// RingPairs returns the source and destination node IDs of every pair of a
+// ring: each node sending to the next, the last wrapping around to the
+// first, and with bidirectional each next node sending back as well
+func RingPairs(nodes []*models.Node, bidirectional bool) [][2]string {
+	pairs := make([][2]string, 0, 2*len(nodes))
+	if len(nodes) < 2 {
+		return pairs
+	}
+
+	seen := make(map[[2]string]bool)
+	add := func(source, dest string) {
+		// Two nodes are each other's next node, so they would repeat
+		if pair := [2]string{source, dest}; !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	for i, node := range nodes {
+		next := nodes[(i+1)%len(nodes)]
+		add(node.ID, next.ID)
+		if bidirectional {
+			add(next.ID, node.ID)
+		}
+	}
+	return pairs
+}
+
+// GenerateRing generates a ring topology in which each node tests only its
+// neighbor, giving one test per node, or two with bidirectional. Nodes are
+// ordered as for RingNodes.
+func (g *Generator) GenerateRing(order []string, bidirectional bool) (*Topology, error) {
+	nodes, err := RingNodes(g.nodes, order)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) < 2 {
+		return nil, fmt.Errorf("at least 2 nodes required for ring topology")
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(nodes)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	byID := make(map[string]*models.Node, len(nodes))
+	for _, node := range nodes {
+		byID[node.ID] = node
+	}
+
+	for i, ids := range RingPairs(nodes, bidirectional) {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, byID[ids[0]], byID[ids[1]])) {
+			topology.Pairs = append(topology.Pairs, pair)
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+		}
+	}
+
+	// Every node receives from its previous node, and with bidirectional
+	// from its next one too
+	if err := allocateServerPorts(topology, nodes, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
@@ -0,0 +1,68 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateRing(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+
+	tests := []struct {
+		name          string
+		nodes         int
+		order         []string
+		bidirectional bool
+		wantPairs     string
+		wantPorts     int // Server ports on every node
+	}{
+		{"config order", 4, nil, false, "node1>node2,node2>node3,node3>node4,node4>node1", 1},
+		{"explicit order", 4, []string{"node3", "node1"}, false, "node3>node1,node1>node2,node2>node4,node4>node3", 1},
+		{"bidirectional", 3, nil, true, "node1>node2,node2>node1,node2>node3,node3>node2,node3>node1,node1>node3", 2},
+		{"two nodes", 2, nil, true, "node1>node2,node2>node1", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, profiles := newTestRegistries(t, tt.nodes, profile)
+			topo, err := NewGenerator(nodes, profiles, profile).GenerateRing(tt.order, tt.bidirectional)
+			if err != nil {
+				t.Fatalf("GenerateRing() error = %v", err)
+			}
+
+			pairs := make([]string, 0, len(topo.Pairs))
+			for _, pair := range topo.Pairs {
+				pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID)
+			}
+			if got := strings.Join(pairs, ","); got != tt.wantPairs {
+				t.Errorf("pairs = %s, want %s", got, tt.wantPairs)
+			}
+
+			for _, node := range nodes.GetAllNodes() {
+				if got := len(topo.ServerPorts[node.ID]); got != tt.wantPorts {
+					t.Errorf("ServerPorts[%s] has %d ports, want %d", node.ID, got, tt.wantPorts)
+				}
+				if got := len(topo.ClientTests[node.ID]); got != tt.wantPorts {
+					t.Errorf("ClientTests[%s] has %d pairs, want %d", node.ID, got, tt.wantPorts)
+				}
+			}
+			if _, err := GenerateNodeTopologies(topo); err != nil {
+				t.Errorf("GenerateNodeTopologies() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestRingNodes_InvalidOrder(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, _ := newTestRegistries(t, 3, profile)
+
+	for _, order := range [][]string{{"node1", "node1"}, {"node9"}} {
+		if _, err := RingNodes(nodes, order); err == nil {
+			t.Errorf("RingNodes(%v) succeeded, want error", order)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// SetRotation limits full mesh generation to the pairsPerRun pairs that
+// coverage records as measured longest ago, so that every pair is tested
+// within ceil(pairs/pairsPerRun) runs. Background pairs are always included
+// and do not count towards the limit.
+func (g *Generator) SetRotation(pairsPerRun int, coverage *Coverage) {
+	g.pairsPerRun = pairsPerRun
+	g.coverage = coverage
+}
+
+// MeshPairs returns the source and destination node IDs of every full mesh
+// pair of nodes, in node order
+func MeshPairs(registry *models.NodeRegistry) [][2]string {
+	nodes := registry.GetAllNodes()
+	pairs := make([][2]string, 0, len(nodes)*(len(nodes)-1))
+	for _, source := range nodes {
+		for _, dest := range nodes {
+			if source.ID != dest.ID {
+				pairs = append(pairs, [2]string{source.ID, dest.ID})
+			}
+		}
+	}
+	return pairs
+}
+
+// rotate returns the mesh pairs selected for this run, keeping mesh order
+func (g *Generator) rotate(pairs [][2]string) [][2]string {
+	if g.coverage == nil {
+		return pairs
+	}
+
+	candidates := make([][2]string, 0, len(pairs))
+	for _, pair := range pairs {
+		if !g.background[fmt.Sprintf("%s:%s", pair[0], pair[1])] {
+			candidates = append(candidates, pair)
+		}
+	}
+	g.coverage.sortStalest(candidates)
+	if len(candidates) > g.pairsPerRun {
+		candidates = candidates[:g.pairsPerRun]
+	}
+
+	chosen := make(map[[2]string]bool, len(candidates))
+	for _, pair := range candidates {
+		chosen[pair] = true
+	}
+
+	selected := make([][2]string, 0, len(candidates))
+	for _, pair := range pairs {
+		if chosen[pair] || g.background[fmt.Sprintf("%s:%s", pair[0], pair[1])] {
+			selected = append(selected, pair)
+		}
+	}
+	return selected
+}
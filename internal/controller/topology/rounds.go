@@ -0,0 +1,155 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+)
+
+// BackgroundRoundGroup is the group of the background pairs of a topology
+// run in rounds, started before the first round and kept running through
+// the last
+const BackgroundRoundGroup = "background"
+
+// Round is a set of pairs run at once, in which each node is the source or
+// destination of at most one pair
+type Round []*TestPair
+
+// RoundName returns the name of the group of the round with the given
+// index, counting from 0, such as "round-1" for the first
+func RoundName(index int) string {
+	return fmt.Sprintf("round-%d", index+1)
+}
+
+// ScheduleRounds partitions pairs into rounds. The pairs of a full mesh
+// are scheduled like a round-robin tournament: each round pairs every node
+// with another, one way, and the next round the other way, so N nodes take
+// 2(N-1) rounds, or 2N when N is odd, with one node idle each round. Any
+// other pairs are packed into the earliest round both their nodes are free
+// in, so pairs of the same nodes, such as those of another address family,
+// go to later rounds.
+func ScheduleRounds(pairs []*TestPair) []Round {
+	order := tournamentOrder(pairs)
+	sorted := make([]*TestPair, len(pairs))
+	copy(sorted, pairs)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return order[sorted[i]] < order[sorted[j]]
+	})
+
+	rounds := make([]Round, 0)
+	busy := make([]map[string]bool, 0)
+	for _, pair := range sorted {
+		index := 0
+		for index < len(rounds) && (busy[index][pair.Source.ID] || busy[index][pair.Destination.ID]) {
+			index++
+		}
+		if index == len(rounds) {
+			rounds = append(rounds, Round{})
+			busy = append(busy, make(map[string]bool))
+		}
+		rounds[index] = append(rounds[index], pair)
+		busy[index][pair.Source.ID] = true
+		busy[index][pair.Destination.ID] = true
+	}
+	return rounds
+}
+
+// tournamentOrder ranks pairs by the round of a round-robin tournament of
+// their nodes, by the circle method, that they would be played in: the
+// pairs of tournament round r rank 2r one way and 2r+1 the other
+func tournamentOrder(pairs []*TestPair) map[*TestPair]int {
+	seen := make(map[string]bool)
+	circle := make([]string, 0)
+	for _, pair := range pairs {
+		for _, id := range []string{pair.Source.ID, pair.Destination.ID} {
+			if !seen[id] {
+				seen[id] = true
+				circle = append(circle, id)
+			}
+		}
+	}
+	sort.Strings(circle)
+	if len(circle)%2 == 1 {
+		circle = append(circle, "") // The node paired with it sits out
+	}
+
+	// The tournament round of each two nodes, and which of them sends first
+	type match struct {
+		round int
+		home  string
+	}
+	matches := make(map[[2]string]match)
+	for round := 0; round < len(circle)-1; round++ {
+		for i := 0; i < len(circle)/2; i++ {
+			a, b := circle[i], circle[len(circle)-1-i]
+			// Alternate which side sends first, so no node always leads
+			home := a
+			if (round+i)%2 == 1 {
+				home = b
+			}
+			matches[[2]string{a, b}] = match{round: round, home: home}
+			matches[[2]string{b, a}] = match{round: round, home: home}
+		}
+		// Rotate every position but the first
+		last := circle[len(circle)-1]
+		copy(circle[2:], circle[1:len(circle)-1])
+		circle[1] = last
+	}
+
+	order := make(map[*TestPair]int, len(pairs))
+	for _, pair := range pairs {
+		m := matches[[2]string{pair.Source.ID, pair.Destination.ID}]
+		order[pair] = 2 * m.round
+		if pair.Source.ID != m.home {
+			order[pair]++
+		}
+	}
+	return order
+}
+
+// ApplyRounds runs the topology's pairs in rounds, one after the other, so
+// each node runs at most one test at a time. Every round becomes a group
+// named by RoundName that starts once the round before it finished, and
+// the results of its pairs record it as their group. Background pairs form
+// a background group the first round starts after, loading every round.
+func (t *Topology) ApplyRounds() error {
+	if len(t.Groups) > 0 {
+		return fmt.Errorf("pairs already run in groups cannot also be scheduled in rounds")
+	}
+
+	measured := make([]*TestPair, 0, len(t.Pairs))
+	background := &Group{Name: BackgroundRoundGroup, Background: true}
+	for _, pair := range t.Pairs {
+		if pair.Background {
+			pair.Group = background.Name
+			background.Pairs = append(background.Pairs, pair)
+		} else {
+			measured = append(measured, pair)
+		}
+	}
+
+	groups := make([]*Group, 0)
+	previous := ""
+	if len(background.Pairs) > 0 {
+		groups = append(groups, background)
+		previous = background.Name
+	}
+	for i, round := range ScheduleRounds(measured) {
+		group := &Group{Name: RoundName(i), Pairs: round}
+		if previous != "" {
+			group.After = []string{previous}
+		}
+		for _, pair := range round {
+			pair.Group = group.Name
+		}
+		groups = append(groups, group)
+		previous = group.Name
+	}
+
+	t.Groups = groups
+	t.Rounds = len(groups)
+	if len(background.Pairs) > 0 {
+		t.Rounds--
+	}
+	extendBackgroundGroups(t.Groups)
+	return nil
+}
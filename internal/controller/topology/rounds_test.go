@@ -0,0 +1,94 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestScheduleRounds_FullMesh(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+
+	for _, tt := range []struct {
+		nodes      int
+		wantRounds int
+	}{
+		{nodes: 2, wantRounds: 2},
+		{nodes: 4, wantRounds: 6},
+		{nodes: 5, wantRounds: 10},
+		{nodes: 8, wantRounds: 14},
+	} {
+		nodes, profiles := newTestRegistries(t, tt.nodes, profile)
+		topo, err := NewGenerator(nodes, profiles, profile).GenerateFullMesh()
+		if err != nil {
+			t.Fatalf("GenerateFullMesh() error = %v", err)
+		}
+
+		rounds := ScheduleRounds(topo.Pairs)
+		if len(rounds) != tt.wantRounds {
+			t.Errorf("%d nodes: %d rounds, want %d", tt.nodes, len(rounds), tt.wantRounds)
+		}
+		scheduled := make(map[*TestPair]int)
+		for i, round := range rounds {
+			busy := make(map[string]bool)
+			for _, pair := range round {
+				if busy[pair.Source.ID] || busy[pair.Destination.ID] {
+					t.Errorf("%d nodes: round %d runs %s -> %s while a node is busy",
+						tt.nodes, i, pair.Source.ID, pair.Destination.ID)
+				}
+				busy[pair.Source.ID] = true
+				busy[pair.Destination.ID] = true
+				scheduled[pair]++
+			}
+		}
+		for _, pair := range topo.Pairs {
+			if scheduled[pair] != 1 {
+				t.Errorf("%d nodes: %s scheduled %d times, want once", tt.nodes, pair.TestID, scheduled[pair])
+			}
+		}
+	}
+}
+
+func TestTopology_ApplyRounds(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 3, profile)
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetBackground("node1", "node2")
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+	if err := topo.ApplyRounds(); err != nil {
+		t.Fatalf("ApplyRounds() error = %v", err)
+	}
+
+	// 3 nodes take 6 rounds; the background pair leaves one empty
+	if topo.Rounds != 5 || len(topo.Groups) != 6 {
+		t.Fatalf("Rounds = %d with %d groups, want 5 rounds after a background group", topo.Rounds, len(topo.Groups))
+	}
+	background := topo.Groups[0]
+	if background.Name != BackgroundRoundGroup || !background.Background || len(background.Pairs) != 1 {
+		t.Errorf("first group = %+v, want the background pair", background)
+	}
+	if background.Pairs[0].Schedule() < 5*10*time.Second {
+		t.Errorf("background pair runs %s, want it to cover every round", background.Pairs[0].Schedule())
+	}
+	for i, group := range topo.Groups[1:] {
+		if group.Name != RoundName(i) || len(group.After) != 1 || group.After[0] != topo.Groups[i].Name {
+			t.Errorf("group %d = %s after %v, want %s after %s", i+1, group.Name, group.After, RoundName(i), topo.Groups[i].Name)
+		}
+		for _, pair := range group.Pairs {
+			if pair.Group != group.Name {
+				t.Errorf("%s group = %q, want %q", pair.TestID, pair.Group, group.Name)
+			}
+		}
+	}
+
+	err = topo.ApplyRounds()
+	if err == nil || !strings.Contains(err.Error(), "already run in groups") {
+		t.Errorf("ApplyRounds() on a grouped topology error = %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package topology
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// StarDirection is which way the pairs of a star topology send traffic
+type StarDirection string
+
+const (
+	// StarToHub tests every other node sending to the hub
+	StarToHub StarDirection = "to_hub"
+	// StarFromHub tests the hub sending to every other node
+	StarFromHub StarDirection = "from_hub"
+	// StarBoth tests both directions
+	StarBoth StarDirection = "both"
+)
+
+// ParseStarDirection parses a star direction, defaulting to both when empty
+func ParseStarDirection(direction string) (StarDirection, error) {
+	switch StarDirection(direction) {
+	case "", StarBoth:
+		return StarBoth, nil
+	case StarToHub, StarFromHub:
+		return StarDirection(direction), nil
+	}
+	return "", fmt.Errorf("unknown star direction %q: must be to_hub, from_hub or both", direction)
+}
+
+// HubNodes returns the node whose ID is hub or, failing that, the nodes
+// tagged hub, in node order
+func HubNodes(registry *models.NodeRegistry, hub string) []*models.Node {
+	if node, err := registry.GetNode(hub); err == nil {
+		return []*models.Node{node}
+	}
+	return registry.GetNodesByTag(hub)
+}
+
+// StarPairs returns the source and destination node IDs of every pair
+// between a hub and the nodes that are not hubs, in node order. Hubs are
+// not tested against each other.
+func StarPairs(registry *models.NodeRegistry, hubs []*models.Node, direction StarDirection) [][2]string {
+	isHub := make(map[string]bool, len(hubs))
+	for _, hub := range hubs {
+		isHub[hub.ID] = true
+	}
+
+	pairs := make([][2]string, 0)
+	for _, hub := range hubs {
+		for _, node := range registry.GetAllNodes() {
+			if isHub[node.ID] {
+				continue
+			}
+			if direction != StarFromHub {
+				pairs = append(pairs, [2]string{node.ID, hub.ID})
+			}
+			if direction != StarToHub {
+				pairs = append(pairs, [2]string{hub.ID, node.ID})
+			}
+		}
+	}
+	return pairs
+}
+
+// GenerateStar generates a hub-and-spoke topology of pairs between hub, a
+// node ID or a tag, and every other node, in the given direction. Server
+// ports are only allocated on nodes that receive connections.
+func (g *Generator) GenerateStar(hub string, direction StarDirection) (*Topology, error) {
+	hubs := HubNodes(g.nodes, hub)
+	if len(hubs) == 0 {
+		return nil, fmt.Errorf("star hub %q is neither a node ID nor a tag of a node", hub)
+	}
+	ids := StarPairs(g.nodes, hubs, direction)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("star topology needs at least one node besides hub %q", hub)
+	}
+
+	topology := &Topology{
+		Pairs:       make([]*TestPair, 0, len(ids)),
+		ServerPorts: make(map[string][]int32),
+		ClientTests: make(map[string][]*TestPair),
+	}
+
+	byID := make(map[string]*models.Node)
+	for _, node := range g.nodes.GetAllNodes() {
+		byID[node.ID] = node
+	}
+
+	receivers := make(map[string]bool)
+	for i, nodeIDs := range ids {
+		for _, pair := range g.expandPair(topology, g.newPair(i+1, byID[nodeIDs[0]], byID[nodeIDs[1]])) {
+			topology.Pairs = append(topology.Pairs, pair)
+			topology.ClientTests[pair.Source.ID] = append(topology.ClientTests[pair.Source.ID], pair)
+			receivers[pair.Destination.ID] = true
+		}
+	}
+
+	destinations := make([]*models.Node, 0, len(receivers))
+	for _, node := range g.nodes.GetAllNodes() {
+		if receivers[node.ID] {
+			destinations = append(destinations, node)
+		}
+	}
+	if err := allocateServerPorts(topology, destinations, g.portStart, g.portEnd); err != nil {
+		return nil, err
+	}
+
+	extendBackgroundPairs(topology)
+
+	return topology, nil
+}
@@ -0,0 +1,78 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_GenerateStar(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+
+	tests := []struct {
+		name      string
+		direction StarDirection
+		wantPairs string
+		wantPorts map[string]int // Server ports by node; nodes left out get none
+	}{
+		{"to hub", StarToHub, "node2>node1,node3>node1,node4>node1", map[string]int{"node1": 3}},
+		{"from hub", StarFromHub, "node1>node2,node1>node3,node1>node4",
+			map[string]int{"node2": 1, "node3": 1, "node4": 1}},
+		{"both", StarBoth, "node2>node1,node1>node2,node3>node1,node1>node3,node4>node1,node1>node4",
+			map[string]int{"node1": 3, "node2": 1, "node3": 1, "node4": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, profiles := newTestRegistries(t, 4, profile)
+			topo, err := NewGenerator(nodes, profiles, profile).GenerateStar("node1", tt.direction)
+			if err != nil {
+				t.Fatalf("GenerateStar() error = %v", err)
+			}
+
+			pairs := make([]string, 0, len(topo.Pairs))
+			for _, pair := range topo.Pairs {
+				pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID)
+			}
+			if got := strings.Join(pairs, ","); got != tt.wantPairs {
+				t.Errorf("pairs = %s, want %s", got, tt.wantPairs)
+			}
+
+			if len(topo.ServerPorts) != len(tt.wantPorts) {
+				t.Errorf("ServerPorts has %d nodes, want %d", len(topo.ServerPorts), len(tt.wantPorts))
+			}
+			for nodeID, want := range tt.wantPorts {
+				if got := len(topo.ServerPorts[nodeID]); got != want {
+					t.Errorf("ServerPorts[%s] has %d ports, want %d", nodeID, got, want)
+				}
+			}
+			if _, err := GenerateNodeTopologies(topo); err != nil {
+				t.Errorf("GenerateNodeTopologies() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestGenerator_GenerateStarByTag(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 4, profile)
+	for _, id := range []string{"node1", "node2"} {
+		node, _ := nodes.GetNode(id)
+		node.Tags = []string{"hub"}
+	}
+
+	topo, err := NewGenerator(nodes, profiles, profile).GenerateStar("hub", StarToHub)
+	if err != nil {
+		t.Fatalf("GenerateStar() error = %v", err)
+	}
+	// Hubs are not tested against each other
+	if topo.GetTestCount() != 4 {
+		t.Errorf("GetTestCount() = %d, want 4", topo.GetTestCount())
+	}
+
+	if _, err := NewGenerator(nodes, profiles, profile).GenerateStar("missing", StarBoth); err == nil {
+		t.Error("GenerateStar() with an unknown hub succeeded, want error")
+	}
+}
@@ -0,0 +1,119 @@
+package topology
+
+import (
+	"sort"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// Tag group modes, keeping the mesh pairs within or across the groups of a
+// tag key
+const (
+	GroupModeIntra = "intra_group"
+	GroupModeInter = "inter_group"
+)
+
+// TagGrouping records how a full mesh was limited to the pairs within or
+// across groups of nodes, grouped by the value of their key:value tag with
+// Key, such as a1 of rack:a1
+type TagGrouping struct {
+	Key       string
+	Mode      string
+	Groups    []string // Tag values, sorted
+	Ungrouped []string // Nodes without a tag of Key, left out of the mesh
+}
+
+// SetTagSelection limits full mesh generation to the pairs from a node with
+// any of sourceTags to a node with any of destTags
+func (g *Generator) SetTagSelection(sourceTags, destTags []string) {
+	g.sourceTags = sourceTags
+	g.destTags = destTags
+}
+
+// SetTagGroups limits full mesh generation to the pairs of nodes in the
+// same group, for GroupModeIntra, or in different groups, for
+// GroupModeInter, grouping nodes by the value of their tag with key
+func (g *Generator) SetTagGroups(key, mode string) {
+	g.groupKey = key
+	g.groupMode = mode
+}
+
+// GroupPairs returns the pairs of pairs whose nodes are in the same group,
+// for GroupModeIntra, or in different groups, for GroupModeInter, in order,
+// with the grouping of the registry's nodes by their tag with key
+func GroupPairs(registry *models.NodeRegistry, pairs [][2]string, key, mode string) ([][2]string, *TagGrouping) {
+	grouping := &TagGrouping{Key: key, Mode: mode}
+	groupOf := make(map[string]string)
+	seen := make(map[string]bool)
+	for _, node := range registry.GetAllNodes() {
+		value, ok := node.TagValue(key)
+		if !ok {
+			grouping.Ungrouped = append(grouping.Ungrouped, node.ID)
+			continue
+		}
+		groupOf[node.ID] = value
+		if !seen[value] {
+			seen[value] = true
+			grouping.Groups = append(grouping.Groups, value)
+		}
+	}
+	sort.Strings(grouping.Groups)
+
+	kept := make([][2]string, 0, len(pairs))
+	for _, pair := range pairs {
+		source, sourceGrouped := groupOf[pair[0]]
+		dest, destGrouped := groupOf[pair[1]]
+		if sourceGrouped && destGrouped && (source == dest) == (mode == GroupModeIntra) {
+			kept = append(kept, pair)
+		}
+	}
+	return kept, grouping
+}
+
+// TestGroupPairs maps each test ID of a topology limited by tag groups to
+// its group pair, such as a1->a2, for summarizing results by group pair
+func (t *Topology) TestGroupPairs() map[string]string {
+	groupPairs := make(map[string]string)
+	if t.TagGroups == nil {
+		return groupPairs
+	}
+	for _, pair := range t.Pairs {
+		source, sourceGrouped := pair.Source.TagValue(t.TagGroups.Key)
+		dest, destGrouped := pair.Destination.TagValue(t.TagGroups.Key)
+		if sourceGrouped && destGrouped {
+			groupPairs[pair.TestID] = source + "->" + dest
+		}
+	}
+	return groupPairs
+}
+
+// TaggedNodes returns the nodes with any of the tags, in node order
+func TaggedNodes(registry *models.NodeRegistry, tags []string) []*models.Node {
+	nodes := make([]*models.Node, 0)
+	for _, node := range registry.GetAllNodes() {
+		for _, tag := range tags {
+			if node.HasTag(tag) {
+				nodes = append(nodes, node)
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// TagPairs returns the source and destination node IDs of every pair from a
+// node with any of sourceTags to a node with any of destTags, in node order.
+// A node with tags of both sets is a source and a destination, but is never
+// paired with itself.
+func TagPairs(registry *models.NodeRegistry, sourceTags, destTags []string) [][2]string {
+	destinations := TaggedNodes(registry, destTags)
+	pairs := make([][2]string, 0)
+	for _, source := range TaggedNodes(registry, sourceTags) {
+		for _, dest := range destinations {
+			if source.ID != dest.ID {
+				pairs = append(pairs, [2]string{source.ID, dest.ID})
+			}
+		}
+	}
+	return pairs
+}
@@ -0,0 +1,115 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+func TestGenerator_TagSelection(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	highbw := &models.TestProfile{Name: "highbw", Duration: 30 * time.Second, Parallel: 4}
+	nodes, profiles := newTestRegistries(t, 4, profile, highbw)
+
+	// node2 is in both racks, so it sends to rack-b and receives from rack-a
+	for id, tags := range map[string][]string{
+		"node1": {"rack-a"},
+		"node2": {"rack-a", "rack-b"},
+		"node3": {"rack-b"},
+		"node4": {"spare"},
+	} {
+		node, err := nodes.GetNode(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.Tags = tags
+	}
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetTagSelection([]string{"rack-a"}, []string{"rack-b"})
+	if err := gen.AddOverride("node1", "node3", "highbw"); err != nil {
+		t.Fatal(err)
+	}
+	topo, err := gen.GenerateFullMesh()
+	if err != nil {
+		t.Fatalf("GenerateFullMesh() error = %v", err)
+	}
+
+	pairs := make([]string, 0, len(topo.Pairs))
+	for _, pair := range topo.Pairs {
+		pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID+":"+pair.Profile.Name)
+	}
+	if got, want := strings.Join(pairs, ","), "node1>node2:default,node1>node3:highbw,node2>node3:default"; got != want {
+		t.Errorf("pairs = %s, want %s", got, want)
+	}
+	if _, err := GenerateNodeTopologies(topo); err != nil {
+		t.Errorf("GenerateNodeTopologies() error = %v", err)
+	}
+
+	gen.SetTagSelection([]string{"spare"}, []string{"spare"})
+	if _, err := gen.GenerateFullMesh(); err == nil {
+		t.Error("GenerateFullMesh() with only self pairs succeeded, want error")
+	}
+}
+
+func TestGenerator_TagGroups(t *testing.T) {
+	profile := &models.TestProfile{Name: "default", Duration: 10 * time.Second, Parallel: 1}
+	nodes, profiles := newTestRegistries(t, 5, profile)
+
+	for id, tags := range map[string][]string{
+		"node1": {"rack:a1"},
+		"node2": {"rack:a1"},
+		"node3": {"rack:a2", "prod"},
+		"node4": {"rack:a2"},
+		"node5": {"spare"},
+	} {
+		node, err := nodes.GetNode(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		node.Tags = tags
+	}
+
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{GroupModeIntra, "node1>node2:a1->a1,node2>node1:a1->a1,node3>node4:a2->a2,node4>node3:a2->a2"},
+		{GroupModeInter, "node1>node3:a1->a2,node1>node4:a1->a2,node2>node3:a1->a2,node2>node4:a1->a2," +
+			"node3>node1:a2->a1,node3>node2:a2->a1,node4>node1:a2->a1,node4>node2:a2->a1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			gen := NewGenerator(nodes, profiles, profile)
+			gen.SetTagGroups("rack", tt.mode)
+			topo, err := gen.GenerateFullMesh()
+			if err != nil {
+				t.Fatalf("GenerateFullMesh() error = %v", err)
+			}
+
+			groupPairs := topo.TestGroupPairs()
+			pairs := make([]string, 0, len(topo.Pairs))
+			for _, pair := range topo.Pairs {
+				pairs = append(pairs, pair.Source.ID+">"+pair.Destination.ID+":"+groupPairs[pair.TestID])
+			}
+			if got := strings.Join(pairs, ","); got != tt.want {
+				t.Errorf("pairs = %s, want %s", got, tt.want)
+			}
+			grouping := topo.TagGroups
+			if grouping == nil || strings.Join(grouping.Groups, ",") != "a1,a2" || strings.Join(grouping.Ungrouped, ",") != "node5" {
+				t.Errorf("TagGroups = %+v, want groups a1 and a2 with node5 ungrouped", grouping)
+			}
+			if _, err := GenerateNodeTopologies(topo); err != nil {
+				t.Errorf("GenerateNodeTopologies() error = %v", err)
+			}
+		})
+	}
+
+	gen := NewGenerator(nodes, profiles, profile)
+	gen.SetTagGroups("row", GroupModeIntra)
+	if _, err := gen.GenerateFullMesh(); err == nil {
+		t.Error("GenerateFullMesh() with no grouped nodes succeeded, want error")
+	}
+}
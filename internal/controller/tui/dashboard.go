@@ -0,0 +1,374 @@
+// Package tui implements a full-screen terminal dashboard for monitoring a
+// controller run.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+)
+
+const (
+	// sparklineWidth is the number of one-second samples shown
+	sparklineWidth = 60
+
+	// healthInterval is how often node status is refreshed
+	healthInterval = 2 * time.Second
+
+	// logBufferLines is how many log lines are retained for the log pane
+	logBufferLines = 500
+)
+
+// IsTerminal reports whether stdin and stdout are both attached to a terminal
+func IsTerminal() bool {
+	return isTerminal(int(os.Stdin.Fd())) && isTerminal(int(os.Stdout.Fd()))
+}
+
+// Dashboard renders live run progress, node status and logs, and handles
+// keybindings to abort, pause and inspect nodes
+type Dashboard struct {
+	orch  *orchestrator.Orchestrator
+	pool  *client.Pool
+	abort context.CancelFunc
+	logs  *LogBuffer
+	in    *os.File
+	out   *os.File
+
+	mu         sync.Mutex
+	statuses   map[string]*pb.DaemonStatus
+	statusErrs map[string]bool
+	rates      []int
+	lastDone   int
+	selected   int
+	inspecting bool
+	confirming bool
+}
+
+// New creates a dashboard. abort is called when the user confirms aborting
+// the run.
+func New(orch *orchestrator.Orchestrator, pool *client.Pool, abort context.CancelFunc) *Dashboard {
+	return &Dashboard{
+		orch:       orch,
+		pool:       pool,
+		abort:      abort,
+		logs:       NewLogBuffer(logBufferLines),
+		in:         os.Stdin,
+		out:        os.Stdout,
+		statuses:   make(map[string]*pb.DaemonStatus),
+		statusErrs: make(map[string]bool),
+		rates:      make([]int, 0, sparklineWidth),
+	}
+}
+
+// LogWriter returns the writer that feeds the log pane
+func (d *Dashboard) LogWriter() io.Writer {
+	return d.logs
+}
+
+// Run takes over the terminal and renders until ctx is done, then restores
+// the terminal and replays the retained log lines to stderr
+func (d *Dashboard) Run(ctx context.Context) error {
+	defer func() {
+		for _, line := range d.logs.Tail(logBufferLines) {
+			fmt.Fprintln(os.Stderr, line)
+		}
+	}()
+
+	restore, err := makeRaw(int(d.in.Fd()))
+	if err != nil {
+		// Keep buffering logs so they are still replayed when the run ends
+		<-ctx.Done()
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+
+	// Alternate screen, hidden cursor
+	fmt.Fprint(d.out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(d.out, "\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	keys := make(chan []byte)
+	go d.readKeys(keys)
+
+	renderTicker := time.NewTicker(time.Second)
+	defer renderTicker.Stop()
+	healthTicker := d.pool.PollTicker(healthInterval)
+	defer healthTicker.Stop()
+
+	d.refreshHealth(ctx)
+	d.render()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case key := <-keys:
+			d.handleKey(key)
+			d.render()
+		case <-healthTicker.C:
+			d.refreshHealth(ctx)
+		case <-renderTicker.C:
+			d.sampleRate()
+			d.render()
+		}
+	}
+}
+
+// readKeys forwards raw keypresses from the terminal
+func (d *Dashboard) readKeys(keys chan<- []byte) {
+	buf := make([]byte, 16)
+	for {
+		n, err := d.in.Read(buf)
+		if err != nil {
+			return
+		}
+		keys <- append([]byte(nil), buf[:n]...)
+	}
+}
+
+// handleKey applies a keypress
+func (d *Dashboard) handleKey(key []byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.confirming {
+		d.confirming = false
+		if len(key) == 1 && (key[0] == 'y' || key[0] == 'Y') {
+			fmt.Fprintln(d.logs, "Abort confirmed from dashboard; stopping run")
+			d.abort()
+		}
+		return
+	}
+
+	nodeCount := len(d.pool.GetAllClients())
+
+	switch string(key) {
+	case "q", "\x03": // q or Ctrl-C
+		d.confirming = true
+	case "p":
+		if d.orch.Paused() {
+			d.orch.Resume()
+		} else {
+			d.orch.Pause()
+		}
+	case "j", "\x1b[B":
+		if d.selected < nodeCount-1 {
+			d.selected++
+		}
+	case "k", "\x1b[A":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "i", "\r", "\n":
+		d.inspecting = !d.inspecting
+	case "\x1b":
+		d.inspecting = false
+	}
+}
+
+// refreshHealth polls every node for its status
+func (d *Dashboard) refreshHealth(ctx context.Context) {
+	statuses, _ := d.pool.CheckHealth(ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, c := range d.pool.GetAllClients() {
+		status, ok := statuses[c.Node.ID]
+		d.statusErrs[c.Node.ID] = !ok
+		if ok {
+			d.statuses[c.Node.ID] = status
+		}
+	}
+}
+
+// sampleRate records how many tests finished in the last second
+func (d *Dashboard) sampleRate() {
+	snap := d.orch.Progress().Snapshot()
+	done := snap.CompletedTests + snap.FailedTests
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delta := done - d.lastDone
+	if delta < 0 {
+		delta = 0
+	}
+	d.lastDone = done
+
+	if len(d.rates) == sparklineWidth {
+		d.rates = d.rates[1:]
+	}
+	d.rates = append(d.rates, delta)
+}
+
+// render redraws the whole screen
+func (d *Dashboard) render() {
+	width, height := terminalSize(int(d.out.Fd()))
+	snap := d.orch.Progress().Snapshot()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	lines := make([]string, 0, height)
+
+	paused := ""
+	if d.orch.Paused() {
+		paused = "  [PAUSED]"
+	}
+	lines = append(lines,
+		fmt.Sprintf("iperf-cnc  phase: %s (%s)  elapsed: %s%s",
+			snap.CurrentPhase,
+			time.Since(snap.PhaseStart).Round(time.Second),
+			time.Since(snap.StartTime).Round(time.Second),
+			paused),
+		fmt.Sprintf("Servers %d/%d  Clients %d/%d  Tests %d/%d done, %d failed  Errors %d",
+			snap.StartedServers, snap.TotalServers,
+			snap.StartedClients, snap.TotalClients,
+			snap.CompletedTests, snap.TotalTests, snap.FailedTests,
+			snap.ErrorCount),
+		"Overall  "+progressBar(snap.CompletedTests+snap.FailedTests, snap.TotalTests, 40),
+		"Tests/s  "+Sparkline(d.rates),
+		"",
+	)
+
+	lines = append(lines, d.nodeLines(snap.Nodes)...)
+
+	if d.inspecting {
+		lines = append(lines, "")
+		lines = append(lines, d.detailLines()...)
+	}
+
+	footer := "q: abort  p: pause/resume new phases  j/k: select node  enter: inspect node"
+	if d.confirming {
+		footer = "Abort the run and stop all iperf3 processes? (y/N)"
+	}
+
+	// Fill the remaining space with the log tail
+	logRows := height - len(lines) - 3
+	if logRows > 0 {
+		lines = append(lines, strings.Repeat("─", width))
+		lines = append(lines, d.logs.Tail(logRows)...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\x1b[H\x1b[2J")
+	for _, line := range lines {
+		sb.WriteString(truncate(line, width))
+		sb.WriteString("\r\n")
+	}
+	sb.WriteString(fmt.Sprintf("\x1b[%d;1H\x1b[7m%s\x1b[0m", height, truncate(footer, width)))
+
+	fmt.Fprint(d.out, sb.String())
+}
+
+// nodeLines renders one status line per node. Once a node's clients have
+// started, DONE shows its tests of this run that finished out of those
+// expected; until then it shows the daemon's counters.
+func (d *Dashboard) nodeLines(tests map[string]orchestrator.NodeTests) []string {
+	clients := d.pool.GetAllClients()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Node.ID < clients[j].Node.ID
+	})
+
+	lines := []string{fmt.Sprintf("  %-20s %-11s %8s %8s %8s", "NODE", "STATE", "RUNNING", "DONE", "FAILED")}
+	for i, c := range clients {
+		marker := " "
+		if i == d.selected {
+			marker = ">"
+		}
+
+		state := "unknown"
+		running, done, failed := int32(0), "0", int32(0)
+		if status, ok := d.statuses[c.Node.ID]; ok && status != nil {
+			state = "healthy"
+			if !status.Healthy {
+				state = "unhealthy"
+			}
+			running, failed = status.RunningProcesses, status.FailedTests
+			done = strconv.Itoa(int(status.CompletedTests))
+		}
+		if node, ok := tests[c.Node.ID]; ok && node.Expected > 0 {
+			done = fmt.Sprintf("%d/%d", node.Done(), node.Expected)
+			failed = int32(node.Failed) // #nosec G115 -- Test count is reasonable
+		}
+		if d.statusErrs[c.Node.ID] {
+			state = "unreachable"
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %-20s %-11s %8d %8s %8d",
+			marker, c.Node.ID, state, running, done, failed))
+	}
+
+	return lines
+}
+
+// detailLines renders the inspection pane for the selected node
+func (d *Dashboard) detailLines() []string {
+	clients := d.pool.GetAllClients()
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].Node.ID < clients[j].Node.ID
+	})
+	if d.selected >= len(clients) {
+		return nil
+	}
+
+	node := clients[d.selected].Node
+	lines := []string{fmt.Sprintf("Node %s (%s:%d)", node.ID, node.IP, node.Port)}
+
+	status, ok := d.statuses[node.ID]
+	if !ok || status == nil {
+		return append(lines, "  no status available")
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("  version: %s  uptime: %s", status.Version, time.Duration(status.UptimeSeconds)*time.Second),
+		fmt.Sprintf("  running processes: %d  completed: %d  failed: %d",
+			status.RunningProcesses, status.CompletedTests, status.FailedTests),
+	)
+	if capacity := status.CurrentCapacity; capacity != nil {
+		lines = append(lines, fmt.Sprintf("  capacity: %d/%d processes available, %d cores, %d MiB free",
+			capacity.AvailableProcesses, capacity.MaxProcesses, capacity.CpuCores,
+			capacity.AvailableMemoryBytes/(1024*1024)))
+	}
+	if caps, ok := d.pool.GetCapabilities(node.ID); ok && caps.CompatibilityMode() {
+		lines = append(lines, fmt.Sprintf("  compatibility mode: %s", strings.Join(caps.Downgrades, ", ")))
+	}
+
+	return lines
+}
+
+// progressBar renders a fixed-width progress bar with a percentage
+func progressBar(done, total, width int) string {
+	percent := 0.0
+	if total > 0 {
+		percent = float64(done) / float64(total)
+	}
+	if percent > 1 {
+		percent = 1
+	}
+
+	filled := int(percent * float64(width))
+	return fmt.Sprintf("[%s%s] %5.1f%%",
+		strings.Repeat("█", filled), strings.Repeat("░", width-filled), percent*100)
+}
+
+// truncate shortens a line to the terminal width
+func truncate(line string, width int) string {
+	runes := []rune(line)
+	if len(runes) <= width {
+		return line
+	}
+	return string(runes[:width])
+}
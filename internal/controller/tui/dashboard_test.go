@@ -0,0 +1,197 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+)
+
+// statusClient answers GetStatus with status, or fails when status is nil
+type statusClient struct {
+	pb.DaemonServiceClient
+	status *pb.DaemonStatus
+}
+
+func (c *statusClient) GetStatus(context.Context, *pb.GetStatusRequest, ...grpc.CallOption) (*pb.GetStatusResponse, error) {
+	if c.status == nil {
+		return nil, errors.New("connection refused")
+	}
+	return &pb.GetStatusResponse{Status: c.status}, nil
+}
+
+// newTestDashboard returns a dashboard over nodes node1 to node3, of which
+// node3 is unreachable, counting the aborts it requests
+func newTestDashboard(t *testing.T) (*Dashboard, *int) {
+	t.Helper()
+	pool := client.NewPool(time.Second)
+	pool.AddClient(&models.Node{ID: "node1"}, &statusClient{status: &pb.DaemonStatus{
+		Healthy: true, RunningProcesses: 4, CompletedTests: 7, FailedTests: 1,
+	}})
+	pool.AddClient(&models.Node{ID: "node2"}, &statusClient{status: &pb.DaemonStatus{RunningProcesses: 2}})
+	pool.AddClient(&models.Node{ID: "node3"}, &statusClient{})
+
+	aborts := 0
+	d := New(orchestrator.NewOrchestrator(pool, false, false, ""), pool, func() { aborts++ })
+	return d, &aborts
+}
+
+func TestDashboard_NodeLines(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	d.refreshHealth(context.Background())
+	progress := d.orch.Progress()
+
+	// Before clients start the daemons' counters are shown
+	want := []string{
+		"  NODE                 STATE        RUNNING     DONE   FAILED",
+		"> node1                healthy            4        7        1",
+		"  node2                unhealthy          2        0        0",
+		"  node3                unreachable        0        0        0",
+	}
+	if got := d.nodeLines(progress.Snapshot().Nodes); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("nodeLines() before clients =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Then the tests of this run that finished out of those expected
+	progress.AddExpectedTests("node1", 4)
+	progress.SetNodeTestCounts("node1", 2, 1)
+	progress.AddExpectedTests("node3", 2)
+	progress.SetNodeTestCounts("node3", 0, 2)
+	want[1] = "> node1                healthy            4      3/4        1"
+	want[3] = "  node3                unreachable        0      2/2        2"
+	if got := d.nodeLines(progress.Snapshot().Nodes); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("nodeLines() during the run =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDashboard_SampleRate(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	progress := d.orch.Progress()
+
+	progress.IncrementCompleted(3)
+	progress.IncrementFailed(1)
+	d.sampleRate()
+	d.sampleRate()
+	progress.IncrementCompleted(2)
+	d.sampleRate()
+	// Counts corrected downwards record no negative rate
+	progress.SetTestCounts(1, 0)
+	d.sampleRate()
+
+	if got := d.rates; len(got) != 4 || got[0] != 4 || got[1] != 0 || got[2] != 2 || got[3] != 0 {
+		t.Errorf("rates = %v, want [4 0 2 0]", got)
+	}
+
+	for i := 0; i < sparklineWidth; i++ {
+		d.sampleRate()
+	}
+	if len(d.rates) != sparklineWidth {
+		t.Errorf("%d rates kept, want %d", len(d.rates), sparklineWidth)
+	}
+}
+
+func TestDashboard_HandleKey(t *testing.T) {
+	d, aborts := newTestDashboard(t)
+
+	// Abort needs confirming; anything but y cancels it
+	d.handleKey([]byte("q"))
+	if !d.confirming {
+		t.Fatal("q did not ask to confirm the abort")
+	}
+	d.handleKey([]byte("n"))
+	if d.confirming || *aborts != 0 {
+		t.Errorf("n after q: confirming %t, %d aborts; want cancelled", d.confirming, *aborts)
+	}
+	d.handleKey([]byte("\x03"))
+	d.handleKey([]byte("y"))
+	if d.confirming || *aborts != 1 {
+		t.Errorf("y after Ctrl-C: confirming %t, %d aborts; want 1 abort", d.confirming, *aborts)
+	}
+	if logs := d.logs.Tail(1); len(logs) != 1 || !strings.Contains(logs[0], "Abort confirmed") {
+		t.Errorf("logs = %v, want the abort logged", logs)
+	}
+
+	d.handleKey([]byte("p"))
+	if !d.orch.Paused() {
+		t.Error("p did not pause the run")
+	}
+	d.handleKey([]byte("p"))
+	if d.orch.Paused() {
+		t.Error("second p did not resume the run")
+	}
+
+	// Selection stays within the nodes
+	for _, key := range []string{"j", "\x1b[B", "j", "j"} {
+		d.handleKey([]byte(key))
+	}
+	if d.selected != 2 {
+		t.Errorf("selected = %d after moving past the last node, want 2", d.selected)
+	}
+	for _, key := range []string{"k", "\x1b[A", "k"} {
+		d.handleKey([]byte(key))
+	}
+	if d.selected != 0 {
+		t.Errorf("selected = %d after moving past the first node, want 0", d.selected)
+	}
+
+	d.handleKey([]byte("\r"))
+	if !d.inspecting {
+		t.Error("enter did not open the inspection pane")
+	}
+	d.handleKey([]byte("\x1b"))
+	if d.inspecting {
+		t.Error("escape did not close the inspection pane")
+	}
+}
+
+func TestDashboard_Render(t *testing.T) {
+	d, _ := newTestDashboard(t)
+	out, err := os.Create(filepath.Join(t.TempDir(), "screen"))
+	if err != nil {
+		t.Fatalf("failed to create screen file: %v", err)
+	}
+	defer func() { _ = out.Close() }()
+	d.out = out
+
+	progress := d.orch.Progress()
+	progress.SetTotals(3, 10, 3, 10)
+	progress.SetPhase("waiting")
+	progress.IncrementStartedServers(3)
+	progress.IncrementStartedClients(10)
+	progress.IncrementCompleted(4)
+	progress.IncrementFailed(1)
+	d.refreshHealth(context.Background())
+	d.orch.Pause()
+	d.handleKey([]byte("i"))
+	d.handleKey([]byte("q"))
+	d.render()
+
+	screen, err := os.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("failed to read screen: %v", err)
+	}
+	for _, want := range []string{
+		"phase: waiting",
+		"[PAUSED]",
+		"Servers 3/3  Clients 10/10  Tests 4/10 done, 1 failed",
+		"50.0%",
+		"> node1",
+		"Node node1",
+		"running processes: 4  completed: 7  failed: 1",
+		"Abort the run and stop all iperf3 processes? (y/N)",
+	} {
+		if !strings.Contains(string(screen), want) {
+			t.Errorf("screen missing %q:\n%s", want, screen)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package tui
+
+import (
+	"bytes"
+	"sync"
+)
+
+// LogBuffer is an io.Writer that keeps the most recent log lines for display
+type LogBuffer struct {
+	mu       sync.Mutex
+	lines    []string
+	partial  []byte
+	maxLines int
+}
+
+// NewLogBuffer creates a log buffer retaining up to maxLines lines
+func NewLogBuffer(maxLines int) *LogBuffer {
+	return &LogBuffer{
+		lines:    make([]string, 0, maxLines),
+		maxLines: maxLines,
+	}
+}
+
+// Write appends complete lines to the buffer; a trailing partial line is held
+// until its newline arrives
+func (b *LogBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data := append(b.partial, p...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		b.appendLine(string(data[:idx]))
+		data = data[idx+1:]
+	}
+	b.partial = append([]byte(nil), data...)
+
+	return len(p), nil
+}
+
+// appendLine adds a line, dropping the oldest when full
+func (b *LogBuffer) appendLine(line string) {
+	if len(b.lines) == b.maxLines {
+		copy(b.lines, b.lines[1:])
+		b.lines = b.lines[:len(b.lines)-1]
+	}
+	b.lines = append(b.lines, line)
+}
+
+// Tail returns up to n of the most recent lines, oldest first
+func (b *LogBuffer) Tail(n int) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if n > len(b.lines) {
+		n = len(b.lines)
+	}
+	tail := make([]string, n)
+	copy(tail, b.lines[len(b.lines)-n:])
+
+	return tail
+}
@@ -0,0 +1,27 @@
+package tui
+
+import "strings"
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a row of block characters scaled to the maximum
+func Sparkline(values []int) string {
+	maxValue := 0
+	for _, v := range values {
+		if v > maxValue {
+			maxValue = v
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		if maxValue == 0 || v <= 0 {
+			sb.WriteRune(sparkBlocks[0])
+			continue
+		}
+		idx := v * (len(sparkBlocks) - 1) / maxValue
+		sb.WriteRune(sparkBlocks[idx])
+	}
+
+	return sb.String()
+}
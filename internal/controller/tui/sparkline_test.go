@@ -0,0 +1,41 @@
+package tui
+
+import "testing"
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"all zero", []int{0, 0, 0}, "▁▁▁"},
+		{"scaled to max", []int{0, 7, 14}, "▁▄█"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sparkline(tt.values); got != tt.want {
+				t.Errorf("Sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogBuffer(t *testing.T) {
+	buf := NewLogBuffer(2)
+
+	if _, err := buf.Write([]byte("one\ntwo\nthr")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := buf.Tail(5); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("Tail = %v, want [one two]", got)
+	}
+
+	if _, err := buf.Write([]byte("ee\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := buf.Tail(5); len(got) != 2 || got[0] != "two" || got[1] != "three" {
+		t.Errorf("Tail = %v, want [two three]", got)
+	}
+}
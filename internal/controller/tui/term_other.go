@@ -0,0 +1,21 @@
+//go:build !linux
+
+package tui
+
+import "errors"
+
+// isTerminal always reports false on platforms without raw-mode support, so
+// the controller falls back to plain logging
+func isTerminal(fd int) bool {
+	return false
+}
+
+// makeRaw is not supported on this platform
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("raw terminal mode not supported on this platform")
+}
+
+// terminalSize returns a default terminal size
+func terminalSize(fd int) (int, int) {
+	return 80, 24
+}
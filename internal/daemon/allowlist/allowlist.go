@@ -0,0 +1,104 @@
+// Package allowlist restricts the daemon's gRPC service to controllers
+// connecting from trusted subnets. Connections over a Unix socket are local
+// and always allowed.
+package allowlist
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// List is a set of CIDRs control connections may come from. An empty list
+// allows every peer.
+type List struct {
+	prefixes []netip.Prefix
+}
+
+// New parses the CIDRs of a list, such as "10.0.0.0/8" or "fd00::/8"
+func New(cidrs []string) (*List, error) {
+	list := &List{prefixes: make([]netip.Prefix, 0, len(cidrs))}
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		list.prefixes = append(list.prefixes, prefix.Masked())
+	}
+	return list, nil
+}
+
+// Empty reports whether the list allows every peer
+func (l *List) Empty() bool {
+	return len(l.prefixes) == 0
+}
+
+// Allows reports whether a peer at addr may use the service. IPv4 peers of a
+// dual-stack listener, which appear as IPv4-mapped IPv6 addresses, are
+// matched against the IPv4 CIDRs.
+func (l *List) Allows(addr net.Addr) bool {
+	if l.Empty() {
+		return true
+	}
+	switch addr := addr.(type) {
+	case *net.UnixAddr:
+		return true
+	case *net.TCPAddr:
+		ip, ok := netip.AddrFromSlice(addr.IP)
+		if !ok {
+			return false
+		}
+		ip = ip.Unmap()
+		for _, prefix := range l.prefixes {
+			if prefix.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check returns a PermissionDenied error, logging it, if the peer of ctx is
+// not allowed to call method
+func (l *List) check(ctx context.Context, method string) error {
+	if l.Empty() {
+		return nil
+	}
+	p, ok := peer.FromContext(ctx)
+	if ok && l.Allows(p.Addr) {
+		return nil
+	}
+	address := "unknown address"
+	if ok && p.Addr != nil {
+		address = p.Addr.String()
+	}
+	log.Printf("Warning: rejected %s from %s: not in allowed_cidrs", method, address)
+	return status.Errorf(codes.PermissionDenied, "%s is not allowed to control this daemon", address)
+}
+
+// UnaryInterceptor rejects unary calls from peers outside the list
+func (l *List) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor rejects streaming calls from peers outside the list
+func (l *List) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,136 @@
+package allowlist
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestList_Allows(t *testing.T) {
+	list, err := New([]string{"10.1.0.0/16", "192.168.1.7/32", "fd00:1::/32"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tcp := func(ip string) net.Addr { return &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000} }
+
+	tests := []struct {
+		name string
+		addr net.Addr
+		want bool
+	}{
+		{"v4 inside", tcp("10.1.2.3"), true},
+		{"v4 outside", tcp("10.2.0.1"), false},
+		{"v4 host", tcp("192.168.1.7"), true},
+		{"v4 next to host", tcp("192.168.1.8"), false},
+		{"v4-mapped inside", tcp("::ffff:10.1.2.3"), true},
+		{"v6 inside", tcp("fd00:1::5"), true},
+		{"v6 outside", tcp("fd00:2::5"), false},
+		{"unix socket", &net.UnixAddr{Name: "/run/iperf-daemon.sock", Net: "unix"}, true},
+		{"unknown address type", &net.UDPAddr{IP: net.ParseIP("10.1.2.3")}, false},
+		{"no address", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Allows(tt.addr); got != tt.want {
+				t.Errorf("Allows(%v) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+
+	empty, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty.Allows(tcp("203.0.113.1")) {
+		t.Error("empty list rejected a peer, want every peer allowed")
+	}
+	if _, err := New([]string{"10.0.0.300/8"}); err == nil {
+		t.Error("New() with an invalid CIDR succeeded, want error")
+	}
+}
+
+// serve starts a gRPC health service behind list's interceptors on
+// listener and returns a client connected to it
+func serve(t *testing.T, list *List, listener net.Listener, target string) healthpb.HealthClient {
+	t.Helper()
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(list.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(list.StreamInterceptor()),
+	)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+// call makes a unary and a streaming call, returning their status codes
+func call(t *testing.T, client healthpb.HealthClient) (codes.Code, codes.Code) {
+	t.Helper()
+	ctx := context.Background()
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	unary := status.Code(err)
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	return unary, status.Code(err)
+}
+
+func TestList_Interceptors(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+		address string
+		cidrs   []string
+		want    codes.Code
+	}{
+		{"v4 allowed", "tcp4", "127.0.0.1:0", []string{"127.0.0.0/8"}, codes.OK},
+		{"v4 denied", "tcp4", "127.0.0.1:0", []string{"10.0.0.0/8", "::1/128"}, codes.PermissionDenied},
+		{"v6 allowed", "tcp6", "[::1]:0", []string{"::1/128"}, codes.OK},
+		{"v6 denied", "tcp6", "[::1]:0", []string{"127.0.0.0/8"}, codes.PermissionDenied},
+		{"empty list", "tcp4", "127.0.0.1:0", nil, codes.OK},
+		{"unix socket", "unix", "", []string{"10.0.0.0/8"}, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := New(tt.cidrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			address := tt.address
+			if tt.network == "unix" {
+				address = filepath.Join(t.TempDir(), "daemon.sock")
+			}
+			listener, err := net.Listen(tt.network, address)
+			if err != nil {
+				t.Skipf("cannot listen on %s %s: %v", tt.network, address, err)
+			}
+			target := "passthrough:///" + listener.Addr().String()
+			if tt.network == "unix" {
+				target = "unix://" + address
+			}
+
+			unary, streaming := call(t, serve(t, list, listener, target))
+			if unary != tt.want || streaming != tt.want {
+				t.Errorf("unary call %v, streaming call %v; want %v", unary, streaming, tt.want)
+			}
+		})
+	}
+}
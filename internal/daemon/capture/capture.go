@@ -0,0 +1,226 @@
+// Package capture records bounded packet captures of client tests with
+// tcpdump, so that chronically underperforming pairs can be looked into
+// without capturing by hand. Captures are opt-in on both the daemon and the
+// test profile, since capturing needs privileges and disk space.
+package capture
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+// readyTimeout is how long tcpdump has to open the interface before the
+// test starts without a capture
+const readyTimeout = 5 * time.Second
+
+// maxRecordBytes bounds a single captured packet, beyond any snaplen
+const maxRecordBytes = 256 << 10
+
+// Config bounds the captures of a daemon
+type Config struct {
+	Interface   string // Interface captured on; "any" captures on all
+	Snaplen     int    // Bytes kept of each packet
+	MaxBytes    int64  // Size of a capture file; packets beyond it are dropped
+	TcpdumpPath string
+}
+
+// Policy is when the capture of a test is kept; otherwise it is removed
+// once the test ends
+type Policy struct {
+	OnFailure bool
+	BelowBps  float64 // Keep when a direction's throughput is below this; 0 disables
+}
+
+// Keep reports whether a test's capture is kept under the policy, and why.
+// err is the error the test failed to run with, if any.
+func (p Policy) Keep(result *iperf.Result, err error) (bool, string) {
+	failed := err != nil || result == nil || !result.Success
+	if !failed && result.JSONOutput != "" {
+		failed = iperf.CheckOutput(result.JSONOutput) != nil
+	}
+	if failed {
+		return p.OnFailure, "test failed"
+	}
+	if p.BelowBps <= 0 {
+		return false, ""
+	}
+
+	throughputs, parseErr := iperf.ParseThroughput(result.JSONOutput)
+	if parseErr != nil {
+		return false, ""
+	}
+	directions := []iperf.Throughput{throughputs.Forward}
+	if throughputs.Reverse != nil {
+		directions = append(directions, *throughputs.Reverse)
+	}
+	for _, direction := range directions {
+		bps := direction.ReceiverBps
+		if direction.ReceiverSource == "" {
+			bps = direction.SenderBps
+		}
+		if bps < p.BelowBps {
+			return true, fmt.Sprintf("throughput %.0f bps below %.0f bps", bps, p.BelowBps)
+		}
+	}
+	return false, ""
+}
+
+// Capturer starts captures within its bounds
+type Capturer struct {
+	config Config
+}
+
+// New returns a capturer with the given bounds
+func New(config Config) *Capturer {
+	if config.Interface == "" {
+		config.Interface = "any"
+	}
+	if config.TcpdumpPath == "" {
+		config.TcpdumpPath = "tcpdump"
+	}
+	return &Capturer{config: config}
+}
+
+// Capture is a running capture of one test's traffic
+type Capture struct {
+	file   *os.File
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	done   chan struct{} // Closed once the packets are copied
+	// Set once done is closed
+	truncated bool
+	err       error
+}
+
+// Start captures the traffic to and from port on host into file until
+// Stop, Discard, or ctx is done, whichever is first. It returns once
+// tcpdump captures, so that the test's first packets are not missed.
+func (c *Capturer) Start(ctx context.Context, file *os.File, host string, port int) (*Capture, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	args := []string{
+		"-i", c.config.Interface,
+		"-s", strconv.Itoa(c.config.Snaplen),
+		"-n", "-U", "-w", "-",
+		"host", host, "and", "port", strconv.Itoa(port),
+	}
+	cmd := exec.CommandContext(ctx, c.config.TcpdumpPath, args...) // #nosec G204 -- tcpdump path is configured, host and port come from a validated target
+	cmd.Cancel = func() error { return iperf.Terminate(cmd.Process) }
+	cmd.WaitDelay = 2 * time.Second
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start tcpdump: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start tcpdump: %w", err)
+	}
+
+	capture := &Capture{file: file, cmd: cmd, cancel: cancel, done: make(chan struct{})}
+	ready := make(chan struct{})
+	go func() {
+		defer close(capture.done)
+		capture.truncated, capture.err = copyRecords(file, stdout, c.config.MaxBytes, ready)
+		// Packets beyond the limit are dropped until tcpdump is stopped
+		_, _ = io.Copy(io.Discard, stdout)
+	}()
+
+	select {
+	case <-ready:
+		return capture, nil
+	case <-capture.done:
+	case <-time.After(readyTimeout):
+	}
+	capture.stop()
+	if capture.err != nil {
+		return nil, fmt.Errorf("tcpdump did not start capturing: %w", capture.err)
+	}
+	return nil, fmt.Errorf("tcpdump did not start capturing within %v", readyTimeout)
+}
+
+// stop ends tcpdump and waits for its packets to be written. tcpdump
+// failing to capture shows as a missing pcap header, so its exit status,
+// usually that of the signal stopping it, is not checked.
+func (c *Capture) stop() {
+	c.cancel()
+	<-c.done
+	_ = c.cmd.Wait()
+}
+
+// Stop ends the capture, closing its file. truncated reports whether
+// packets were dropped for the size limit.
+func (c *Capture) Stop() (truncated bool, err error) {
+	c.stop()
+	if closeErr := c.file.Close(); closeErr != nil && c.err == nil {
+		c.err = closeErr
+	}
+	return c.truncated, c.err
+}
+
+// File returns the path of the capture's file
+func (c *Capture) File() string {
+	return c.file.Name()
+}
+
+// Discard ends the capture and removes its file
+func (c *Capture) Discard() {
+	_, _ = c.Stop()
+	_ = os.Remove(c.file.Name())
+}
+
+// copyRecords copies a pcap stream from src to dst, whole packet records
+// only, until it ends or the next record would take dst past limit bytes.
+// ready is closed once the stream's header is copied.
+func copyRecords(dst io.Writer, src io.Reader, limit int64, ready chan<- struct{}) (truncated bool, err error) {
+	header := make([]byte, 24)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return false, fmt.Errorf("no pcap header: %w", err)
+	}
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(header) {
+	case 0xa1b2c3d4, 0xa1b23c4d:
+		order = binary.LittleEndian
+	case 0xd4c3b2a1, 0x4d3cb2a1:
+		order = binary.BigEndian
+	default:
+		return false, fmt.Errorf("not a pcap stream")
+	}
+	if _, err := dst.Write(header); err != nil {
+		return false, err
+	}
+	close(ready)
+
+	written := int64(len(header))
+	record := make([]byte, 16+maxRecordBytes)
+	for {
+		if _, err := io.ReadFull(src, record[:16]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return false, nil
+			}
+			return false, err
+		}
+		length := int64(order.Uint32(record[8:12]))
+		if length > maxRecordBytes {
+			return false, fmt.Errorf("pcap record of %d bytes is too large", length)
+		}
+		if _, err := io.ReadFull(src, record[16:16+length]); err != nil {
+			return false, nil // Cut short by tcpdump stopping
+		}
+		if written+16+length > limit {
+			return true, nil
+		}
+		if _, err := dst.Write(record[:16+length]); err != nil {
+			return false, err
+		}
+		written += 16 + length
+	}
+}
@@ -0,0 +1,94 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+// pcapStream returns a little-endian pcap stream of packets of the given
+// sizes
+func pcapStream(sizes ...int) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	buf.Write(header)
+	for _, size := range sizes {
+		record := make([]byte, 16+size)
+		binary.LittleEndian.PutUint32(record[8:], uint32(size))  // #nosec G115 -- Test packet sizes are small
+		binary.LittleEndian.PutUint32(record[12:], uint32(size)) // #nosec G115 -- Test packet sizes are small
+		buf.Write(record)
+	}
+	return buf.Bytes()
+}
+
+func TestCopyRecords(t *testing.T) {
+	stream := pcapStream(100, 100, 100)
+
+	tests := []struct {
+		name          string
+		limit         int64
+		wantBytes     int
+		wantTruncated bool
+	}{
+		{"all fit", 1000, len(stream), false},
+		// The third record would pass the limit, so it is dropped whole
+		{"limited", 24 + 2*116 + 50, 24 + 2*116, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst bytes.Buffer
+			ready := make(chan struct{})
+			truncated, err := copyRecords(&dst, bytes.NewReader(stream), tt.limit, ready)
+			if err != nil {
+				t.Fatalf("copyRecords() error = %v", err)
+			}
+			if dst.Len() != tt.wantBytes || truncated != tt.wantTruncated {
+				t.Errorf("copyRecords() wrote %d bytes, truncated %v; want %d, %v",
+					dst.Len(), truncated, tt.wantBytes, tt.wantTruncated)
+			}
+			select {
+			case <-ready:
+			default:
+				t.Error("ready not closed after the header was copied")
+			}
+		})
+	}
+
+	if _, err := copyRecords(&bytes.Buffer{}, bytes.NewReader([]byte("tcpdump: permission denied")), 1000, make(chan struct{})); err == nil {
+		t.Error("copyRecords() of a non-pcap stream succeeded, want error")
+	}
+}
+
+func TestPolicy_Keep(t *testing.T) {
+	output := func(bps float64) *iperf.Result {
+		return &iperf.Result{Success: true, JSONOutput: fmt.Sprintf(
+			`{"end": {"sum_sent": {"bits_per_second": %g}, "sum_received": {"bits_per_second": %g}}}`, bps*1.01, bps)}
+	}
+
+	tests := []struct {
+		name   string
+		policy Policy
+		result *iperf.Result
+		err    error
+		want   bool
+	}{
+		{"failed run", Policy{OnFailure: true}, nil, fmt.Errorf("timed out"), true},
+		{"iperf3 error", Policy{OnFailure: true}, &iperf.Result{Success: true, JSONOutput: `{"error": "unable to connect"}`}, nil, true},
+		{"failure not asked for", Policy{BelowBps: 1e9}, nil, fmt.Errorf("timed out"), false},
+		{"below threshold", Policy{BelowBps: 1e9}, output(5e8), nil, true},
+		{"above threshold", Policy{OnFailure: true, BelowBps: 1e9}, output(2e9), nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, reason := tt.policy.Keep(tt.result, tt.err); got != tt.want {
+				t.Errorf("Keep() = %v (%s), want %v", got, reason, tt.want)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package collector
 
 import (
 	"fmt"
+	"log"
 	"sync"
 	"time"
 
@@ -19,6 +20,30 @@ type TestResult struct {
 	StartTime     time.Time
 	EndTime       time.Time
 	ExitCode      int
+
+	// Requested schedule of a client test, so timelines can be aligned
+	StartDelay time.Duration
+	Duration   time.Duration
+
+	// Data-plane addresses of a client test: the requested destination and
+	// the connection's addresses as reported by iperf3
+	DestinationAddress string
+	LocalAddress       string
+	RemoteAddress      string
+
+	// CongestionControl is the TCP algorithm requested with -C, if any
+	CongestionControl string
+
+	// ResultFile is the saved copy of IperfJSON, when results are saved
+	ResultFile string
+	// CaptureFile is the packet capture kept of a client test, if any
+	CaptureFile string
+
+	// RunID is the run the test belongs to, if the daemon was given one
+	RunID string
+
+	size int64  // Bytes counted against the limits
+	seq  uint64 // Order in which results were stored
 }
 
 // Collector collects and stores test results
@@ -28,6 +53,26 @@ type Collector struct {
 	failed    int
 	mu        sync.RWMutex
 	resultDir string
+	samples   []*ResourceSample // Resource usage since sampling was last started
+	// saveResults saves a copy of each client result under resultDir
+	saveResults bool
+
+	// Caps on the stored results, their size, and the results they dropped
+	limits     Limits
+	totalBytes int64
+	seq        uint64
+	evicted    int64
+	rejected   int64
+	dropped    map[string]string // test ID -> why its result was dropped
+	nearLimit  bool
+
+	// Tests finished per run, which stay counted after their results are
+	// cleared
+	runID    string                // Current run
+	runs     map[string]*runCounts // run ID -> tests finished in the run
+	testRuns map[string]string     // test ID -> run of a started test
+
+	captures map[string]string // test ID -> capture file kept for its next result
 }
 
 // NewCollector creates a new result collector
@@ -35,10 +80,15 @@ func NewCollector(resultDir string) *Collector {
 	return &Collector{
 		results:   make(map[string]*TestResult),
 		resultDir: resultDir,
+		dropped:   make(map[string]string),
+		runs:      make(map[string]*runCounts),
+		testRuns:  make(map[string]string),
+		captures:  make(map[string]string),
 	}
 }
 
-// StoreResult stores a test result
+// StoreResult stores a test result. It fails if the result would exceed the
+// limits and the overflow policy rejects it.
 func (c *Collector) StoreResult(result *TestResult) error {
 	if result == nil {
 		return fmt.Errorf("result cannot be nil")
@@ -51,17 +101,56 @@ func (c *Collector) StoreResult(result *TestResult) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if result.RunID == "" {
+		result.RunID = c.runOf(result.TestID)
+	}
+	// A result replacing an earlier one of the same test takes its place
+	if existing, exists := c.results[result.TestID]; exists {
+		c.countRun(existing.RunID, existing.Status, -1)
+		c.remove(result.TestID)
+	}
+	size := resultSize(result)
+	if err := c.makeRoom(result.TestID, size); err != nil {
+		// The test still finished; the controller reports it as failed
+		c.countRun(result.RunID, "failed", 1)
+		return err
+	}
+
+	c.seq++
+	result.size = size
+	result.seq = c.seq
 	c.results[result.TestID] = result
+	c.totalBytes += size
+	delete(c.dropped, result.TestID)
 
 	if result.Status == "completed" {
 		c.completed++
 	} else if result.Status == "failed" {
 		c.failed++
 	}
+	c.countRun(result.RunID, result.Status, 1)
+	c.warnNearLimits()
 
 	return nil
 }
 
+// remove deletes a stored result and updates the counts. The caller must
+// hold c.mu.
+func (c *Collector) remove(testID string) {
+	result, exists := c.results[testID]
+	if !exists {
+		return
+	}
+	delete(c.results, testID)
+	c.totalBytes -= result.size
+
+	if result.Status == "completed" {
+		c.completed--
+	} else if result.Status == "failed" {
+		c.failed--
+	}
+}
+
 // StoreIperfResult stores a result from iperf wrapper
 func (c *Collector) StoreIperfResult(testID string, result *iperf.Result) error {
 	if result == nil {
@@ -69,15 +158,22 @@ func (c *Collector) StoreIperfResult(testID string, result *iperf.Result) error
 	}
 
 	status := "completed"
+	errorMessage := result.Error
 	if !result.Success {
 		status = "failed"
+	} else if result.JSONOutput != "" {
+		// iperf3 can exit 0 after a failure, leaving only its JSON to say so
+		if err := iperf.CheckOutput(result.JSONOutput); err != nil {
+			status = "failed"
+			errorMessage = err.Error()
+		}
 	}
 
 	testResult := &TestResult{
 		TestID:       testID,
 		Status:       status,
 		IperfJSON:    result.JSONOutput,
-		ErrorMessage: result.Error,
+		ErrorMessage: errorMessage,
 		StartTime:    result.StartTime,
 		EndTime:      result.EndTime,
 		ExitCode:     result.ExitCode,
@@ -86,6 +182,56 @@ func (c *Collector) StoreIperfResult(testID string, result *iperf.Result) error
 	return c.StoreResult(testResult)
 }
 
+// StoreClientResult stores a client result along with the schedule it was
+// requested to run on
+func (c *Collector) StoreClientResult(testID string, result *iperf.Result, config *iperf.Config) error {
+	if err := c.StoreIperfResult(testID, result); err != nil {
+		c.mu.Lock()
+		delete(c.captures, testID)
+		c.mu.Unlock()
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored := c.results[testID]
+	stored.StartDelay = config.StartDelay
+	stored.Duration = config.Duration
+	stored.DestinationAddress = config.Host
+	if config.Protocol != iperf.ProtocolUDP {
+		stored.CongestionControl = config.CongestionControl
+	}
+	// Failed tests may not have connected; their addresses stay empty
+	if local, remote, err := iperf.ExtractConnection(result.JSONOutput); err == nil {
+		stored.LocalAddress = local
+		stored.RemoteAddress = remote
+	}
+	if path, exists := c.captures[testID]; exists {
+		stored.CaptureFile = path
+		delete(c.captures, testID)
+	}
+	if c.saveResults && result.JSONOutput != "" {
+		path, err := c.saveCopy(testID, result.JSONOutput)
+		if err != nil {
+			log.Printf("Warning: failed to save result of %s: %v", testID, err)
+		} else {
+			stored.ResultFile = path
+		}
+	}
+
+	return nil
+}
+
+// AttachCapture records the packet capture kept of a client test, to be
+// reported with the result stored for it next
+func (c *Collector) AttachCapture(testID, path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.captures[testID] = path
+}
+
 // GetResult retrieves a specific test result
 func (c *Collector) GetResult(testID string) (*TestResult, error) {
 	c.mu.RLock()
@@ -132,18 +278,12 @@ func (c *Collector) ClearResult(testID string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	result, exists := c.results[testID]
-	if !exists {
+	if _, exists := c.results[testID]; !exists {
 		return fmt.Errorf("result for test %s not found", testID)
 	}
 
-	delete(c.results, testID)
-
-	if result.Status == "completed" {
-		c.completed--
-	} else if result.Status == "failed" {
-		c.failed--
-	}
+	c.remove(testID)
+	c.warnNearLimits()
 
 	return nil
 }
@@ -154,26 +294,23 @@ func (c *Collector) ClearResults(testIDs []string) {
 	defer c.mu.Unlock()
 
 	for _, testID := range testIDs {
-		if result, exists := c.results[testID]; exists {
-			delete(c.results, testID)
-
-			if result.Status == "completed" {
-				c.completed--
-			} else if result.Status == "failed" {
-				c.failed--
-			}
-		}
+		c.remove(testID)
+		delete(c.dropped, testID)
 	}
+	c.warnNearLimits()
 }
 
-// ClearAll removes all stored results
+// ClearAll removes all stored results, and the reasons results were dropped
 func (c *Collector) ClearAll() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.results = make(map[string]*TestResult)
+	c.dropped = make(map[string]string)
 	c.completed = 0
 	c.failed = 0
+	c.totalBytes = 0
+	c.nearLimit = false
 }
 
 // GetCount returns the total number of stored results
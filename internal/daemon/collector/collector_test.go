@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+func TestStoreClientResult_ExitZeroFailures(t *testing.T) {
+	tests := []struct {
+		fixture    string
+		wantStatus string
+		wantErr    string
+	}{
+		{"completed.json", "completed", ""},
+		{"control_socket_closed.json", "failed", "control socket has closed unexpectedly"},
+		{"truncated.json", "failed", "no 'end' section"},
+	}
+
+	c := NewCollector("")
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join("..", "..", "common", "iperf", "testdata", tt.fixture))
+		if err != nil {
+			t.Fatalf("failed to read fixture %s: %v", tt.fixture, err)
+		}
+
+		// iperf3 exited 0, so the wrapper reports success
+		result := &iperf.Result{
+			Success:    true,
+			JSONOutput: string(data),
+			StartTime:  time.Now(),
+			EndTime:    time.Now(),
+		}
+		if err := c.StoreClientResult(tt.fixture, result, &iperf.Config{Host: "10.0.0.2"}); err != nil {
+			t.Fatalf("StoreClientResult(%s) error = %v", tt.fixture, err)
+		}
+
+		stored, err := c.GetResult(tt.fixture)
+		if err != nil {
+			t.Fatalf("GetResult(%s) error = %v", tt.fixture, err)
+		}
+		if stored.Status != tt.wantStatus {
+			t.Errorf("%s: Status = %q, want %q", tt.fixture, stored.Status, tt.wantStatus)
+		}
+		if !strings.Contains(stored.ErrorMessage, tt.wantErr) {
+			t.Errorf("%s: ErrorMessage = %q, want it to contain %q", tt.fixture, stored.ErrorMessage, tt.wantErr)
+		}
+	}
+
+	if got := c.GetCompletedCount(); got != 1 {
+		t.Errorf("GetCompletedCount() = %d, want 1", got)
+	}
+	if got := c.GetFailedCount(); got != 2 {
+		t.Errorf("GetFailedCount() = %d, want 2", got)
+	}
+}
+
+func TestSaveResults(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCollector(dir)
+	c.SetSaveResults(true)
+
+	result := &iperf.Result{Success: true, JSONOutput: `{"end": {}}`}
+	if err := c.StoreClientResult("node1/node2", result, &iperf.Config{}); err != nil {
+		t.Fatalf("StoreClientResult() error = %v", err)
+	}
+	stored, err := c.GetResult("node1/node2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(stored.ResultFile) != dir || strings.Contains(filepath.Base(stored.ResultFile), "/") {
+		t.Fatalf("ResultFile = %q, want a file in %s", stored.ResultFile, dir)
+	}
+
+	file, err := c.OpenResultFile(stored.ResultFile)
+	if err != nil {
+		t.Fatalf("OpenResultFile() error = %v", err)
+	}
+	_ = file.Close()
+
+	// Files outside the result directory are refused
+	outside := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(outside, []byte("secret"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.json")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{outside, filepath.Join(dir, "..", filepath.Base(outside)), link, dir, "../secret"} {
+		if file, err := c.OpenResultFile(path); err == nil {
+			_ = file.Close()
+			t.Errorf("OpenResultFile(%q) succeeded, want it refused", path)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	store := func(c *Collector, testID string, size int) error {
+		return c.StoreResult(&TestResult{TestID: testID, Status: "completed", IperfJSON: strings.Repeat("x", size)})
+	}
+
+	// Rejecting keeps the stored results and records why the new one is missing
+	c := NewCollector(t.TempDir())
+	c.SetLimits(Limits{MaxResults: 2, MaxBytes: 100, Overflow: OverflowReject})
+	for _, testID := range []string{"a", "b"} {
+		if err := store(c, testID, 10); err != nil {
+			t.Fatalf("StoreResult(%s) error = %v", testID, err)
+		}
+	}
+	if err := store(c, "c", 10); err == nil || !strings.Contains(err.Error(), "max_stored_results") {
+		t.Errorf("StoreResult() over max_stored_results error = %v", err)
+	}
+	if err := store(c, "huge", 101); err == nil || !strings.Contains(err.Error(), "max_total_result_bytes") {
+		t.Errorf("StoreResult() over max_total_result_bytes error = %v", err)
+	}
+	stats := c.StorageStats()
+	if stats.Results != 2 || stats.Bytes != 20 || stats.Rejected != 2 || stats.Evicted != 0 {
+		t.Errorf("StorageStats() = %+v, want 2 results, 20 bytes, 2 rejected", stats)
+	}
+	if dropped := c.DroppedResults([]string{"c"}); !strings.Contains(dropped["c"], "did not store") {
+		t.Errorf("DroppedResults() = %v, want the rejection of c", dropped)
+	}
+
+	// Evicting drops the oldest results until the new one fits
+	c = NewCollector(t.TempDir())
+	c.SetLimits(Limits{MaxResults: 10, MaxBytes: 100, Overflow: OverflowEvictOldest})
+	for _, testID := range []string{"a", "b", "c"} {
+		if err := store(c, testID, 40); err != nil {
+			t.Fatalf("StoreResult(%s) error = %v", testID, err)
+		}
+	}
+	if c.HasResult("a") || !c.HasResult("b") || !c.HasResult("c") {
+		t.Errorf("stored %v, want b and c", c.GetResultIDs())
+	}
+	if stats := c.StorageStats(); stats.Bytes != 80 || stats.Evicted != 1 {
+		t.Errorf("StorageStats() = %+v, want 80 bytes, 1 evicted", stats)
+	}
+	if dropped := c.DroppedResults(nil); !strings.Contains(dropped["a"], "evicted") {
+		t.Errorf("DroppedResults() = %v, want the eviction of a", dropped)
+	}
+
+	// Retrieving the results clears the reasons and frees the space
+	c.ClearAll()
+	if stats := c.StorageStats(); stats.Bytes != 0 || len(c.DroppedResults(nil)) != 0 {
+		t.Errorf("after ClearAll() StorageStats() = %+v, dropped = %v", stats, c.DroppedResults(nil))
+	}
+}
+
+func TestRunCounts(t *testing.T) {
+	c := NewCollector(t.TempDir())
+	c.StartRun("run1")
+	c.TagTest("slow") // Started in run1, finishes after run2 starts
+
+	if err := c.StoreResult(&TestResult{TestID: "a", Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.StoreResult(&TestResult{TestID: "b", Status: "failed"}); err != nil {
+		t.Fatal(err)
+	}
+	c.ClearAll() // Retrieved by the controller
+
+	c.StartRun("run2")
+	c.TagTest("c")
+	for _, testID := range []string{"c", "slow"} {
+		if err := c.StoreResult(&TestResult{TestID: testID, Status: "completed"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if completed, failed, ok := c.RunCounts("run1"); !ok || completed != 2 || failed != 1 {
+		t.Errorf("RunCounts(run1) = %d, %d, %v, want 2, 1, true", completed, failed, ok)
+	}
+	if completed, failed, ok := c.RunCounts("run2"); !ok || completed != 1 || failed != 0 {
+		t.Errorf("RunCounts(run2) = %d, %d, %v, want 1, 0, true", completed, failed, ok)
+	}
+	if result, _ := c.GetResult("slow"); result.RunID != "run1" {
+		t.Errorf("slow result RunID = %q, want run1", result.RunID)
+	}
+	if _, _, ok := c.RunCounts("unknown"); ok {
+		t.Errorf("RunCounts(unknown) ok = true")
+	}
+
+	// Only the most recent runs are kept
+	for i := 0; i < maxTrackedRuns; i++ {
+		c.StartRun(fmt.Sprintf("later%d", i))
+	}
+	if _, _, ok := c.RunCounts("run1"); ok {
+		t.Errorf("RunCounts(run1) ok = true after %d later runs", maxTrackedRuns)
+	}
+}
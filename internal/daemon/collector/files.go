@@ -0,0 +1,106 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SetSaveResults controls whether a copy of each client result's iperf3
+// JSON is saved under the result directory
+func (c *Collector) SetSaveResults(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.saveResults = enabled
+}
+
+// CreateFile creates a new file for a test under the result directory,
+// which DownloadFile serves, with the given extension. File names carry the
+// test ID and a nanosecond timestamp, so repeated runs of a test never
+// replace each other's files.
+func (c *Collector) CreateFile(testID, ext string) (*os.File, error) {
+	dir, err := filepath.Abs(c.resultDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create result directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%d.%s", safeFileName(testID), time.Now().UnixNano(), ext)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600) // #nosec G304 -- Name is sanitized and joined to the result directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to create result file: %w", err)
+	}
+	return file, nil
+}
+
+// saveCopy writes iperf3 JSON to a new file under the result directory and
+// returns its absolute path
+func (c *Collector) saveCopy(testID, data string) (string, error) {
+	file, err := c.CreateFile(testID, "json")
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	if _, err := file.WriteString(data); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write result file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("failed to close result file: %w", err)
+	}
+	return path, nil
+}
+
+// safeFileName replaces the characters of a test ID that are not safe in a
+// file name
+func safeFileName(testID string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, testID)
+}
+
+// OpenResultFile opens a file saved under the result directory for reading.
+// Paths outside the directory, including through symbolic links, are
+// refused, so that downloads cannot read arbitrary files.
+func (c *Collector) OpenResultFile(path string) (*os.File, error) {
+	if c.resultDir == "" || path == "" {
+		return nil, fmt.Errorf("no result file named")
+	}
+	dir, err := filepath.Abs(c.resultDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result directory: %w", err)
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return nil, fmt.Errorf("invalid result directory: %w", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("result file %s: %w", path, err)
+	}
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("%s is not under the result directory", path)
+	}
+
+	file, err := os.Open(resolved) // #nosec G304 -- Checked to be under the result directory
+	if err != nil {
+		return nil, fmt.Errorf("result file %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	return file, nil
+}
@@ -0,0 +1,174 @@
+package collector
+
+import (
+	"fmt"
+	"log"
+)
+
+// Overflow policies, applied when storing a result would exceed the limits
+const (
+	OverflowReject      = "reject"       // Refuse the new result
+	OverflowEvictOldest = "evict_oldest" // Drop the oldest stored results
+)
+
+// nearLimitRatio is the share of a limit at which a warning is logged
+const nearLimitRatio = 0.8
+
+// maxDroppedRecords bounds the reasons kept for results that were rejected or
+// evicted, so that a flood of tests can't grow them without bound either
+const maxDroppedRecords = 10000
+
+// Limits caps the results held in memory until a controller retrieves them.
+// Zero values leave the corresponding limit off.
+type Limits struct {
+	MaxResults int
+	MaxBytes   int64
+	Overflow   string // OverflowReject or OverflowEvictOldest
+}
+
+// StorageStats describes the results held in memory and those dropped
+// because of the limits
+type StorageStats struct {
+	Results  int
+	Bytes    int64
+	Evicted  int64
+	Rejected int64
+}
+
+// SetLimits sets the caps on stored results. Results already stored are kept
+// even if they exceed them.
+func (c *Collector) SetLimits(limits Limits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limits = limits
+}
+
+// StorageStats returns the size of the stored results and how many results
+// the limits dropped
+func (c *Collector) StorageStats() StorageStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return StorageStats{
+		Results:  len(c.results),
+		Bytes:    c.totalBytes,
+		Evicted:  c.evicted,
+		Rejected: c.rejected,
+	}
+}
+
+// DroppedResults returns why the results of testIDs, or of every test if
+// testIDs is empty, were rejected or evicted, by test ID
+func (c *Collector) DroppedResults(testIDs []string) map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dropped := make(map[string]string)
+	if len(testIDs) == 0 {
+		for testID, reason := range c.dropped {
+			dropped[testID] = reason
+		}
+		return dropped
+	}
+	for _, testID := range testIDs {
+		if reason, exists := c.dropped[testID]; exists {
+			dropped[testID] = reason
+		}
+	}
+	return dropped
+}
+
+// resultSize estimates the memory a result holds, which is dominated by its
+// iperf3 output
+func resultSize(result *TestResult) int64 {
+	return int64(len(result.IperfJSON) + len(result.ErrorMessage))
+}
+
+// makeRoom ensures a result of size bytes can be stored within the limits,
+// evicting the oldest results if the policy allows it. The caller must hold
+// c.mu.
+func (c *Collector) makeRoom(testID string, size int64) error {
+	if c.limits.MaxBytes > 0 && size > c.limits.MaxBytes {
+		return c.reject(testID, fmt.Sprintf("result is %d bytes, more than max_total_result_bytes (%d)", size, c.limits.MaxBytes))
+	}
+	for {
+		exceeded := c.exceededLimit(size)
+		if exceeded == "" {
+			return nil
+		}
+		if c.limits.Overflow != OverflowEvictOldest || len(c.results) == 0 {
+			return c.reject(testID, exceeded+" reached")
+		}
+
+		oldest := c.oldest()
+		c.remove(oldest)
+		c.evicted++
+		c.recordDropped(oldest, fmt.Sprintf("daemon evicted the uncollected result: %s reached", exceeded))
+		log.Printf("Warning: evicted the uncollected result of %s: %s reached", oldest, exceeded)
+	}
+}
+
+// exceededLimit names the limit storing one more result of size bytes would
+// exceed, or returns "". The caller must hold c.mu.
+func (c *Collector) exceededLimit(size int64) string {
+	if c.limits.MaxResults > 0 && len(c.results)+1 > c.limits.MaxResults {
+		return fmt.Sprintf("max_stored_results (%d)", c.limits.MaxResults)
+	}
+	if c.limits.MaxBytes > 0 && c.totalBytes+size > c.limits.MaxBytes {
+		return fmt.Sprintf("max_total_result_bytes (%d)", c.limits.MaxBytes)
+	}
+	return ""
+}
+
+// reject counts and records a result that is not stored. The caller must
+// hold c.mu.
+func (c *Collector) reject(testID, reason string) error {
+	c.rejected++
+	c.recordDropped(testID, "daemon did not store the result: "+reason)
+	log.Printf("Warning: rejected the result of %s: %s", testID, reason)
+	return fmt.Errorf("result of %s not stored: %s", testID, reason)
+}
+
+// recordDropped keeps the reason a result was dropped, so that the
+// controller can report it in place of the result. The caller must hold c.mu.
+func (c *Collector) recordDropped(testID, reason string) {
+	if len(c.dropped) >= maxDroppedRecords {
+		return
+	}
+	c.dropped[testID] = reason
+}
+
+// oldest returns the ID of the result stored first. The caller must hold
+// c.mu.
+func (c *Collector) oldest() string {
+	var oldestID string
+	var oldestSeq uint64
+	for testID, result := range c.results {
+		if oldestID == "" || result.seq < oldestSeq {
+			oldestID, oldestSeq = testID, result.seq
+		}
+	}
+	return oldestID
+}
+
+// warnNearLimits logs once when the stored results reach nearLimitRatio of a
+// limit, and again after they have dropped below it. The caller must hold
+// c.mu.
+func (c *Collector) warnNearLimits() {
+	near := ""
+	switch {
+	case c.limits.MaxResults > 0 && float64(len(c.results)) >= nearLimitRatio*float64(c.limits.MaxResults):
+		near = fmt.Sprintf("%d of max_stored_results (%d)", len(c.results), c.limits.MaxResults)
+	case c.limits.MaxBytes > 0 && float64(c.totalBytes) >= nearLimitRatio*float64(c.limits.MaxBytes):
+		near = fmt.Sprintf("%d bytes of max_total_result_bytes (%d)", c.totalBytes, c.limits.MaxBytes)
+	}
+	if near == "" {
+		c.nearLimit = false
+		return
+	}
+	if !c.nearLimit {
+		c.nearLimit = true
+		log.Printf("Warning: stored results are at %s; results are dropped once it is reached", near)
+	}
+}
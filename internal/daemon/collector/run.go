@@ -0,0 +1,105 @@
+package collector
+
+// maxTrackedRuns bounds the runs whose test counts are kept, so that a
+// daemon serving many runs doesn't grow them without bound
+const maxTrackedRuns = 32
+
+// runCounts counts the tests of a run that finished, whether or not their
+// results are still stored
+type runCounts struct {
+	completed int
+	failed    int
+	seq       uint64 // Order in which runs started
+}
+
+// StartRun makes runID the current run. Tests started from now on, and
+// results stored without a run, belong to it.
+func (c *Collector) StartRun(runID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.runID = runID
+	if _, exists := c.runs[runID]; exists {
+		return // Initialize was retried for the same run
+	}
+	c.seq++
+	c.runs[runID] = &runCounts{seq: c.seq}
+	for len(c.runs) > maxTrackedRuns {
+		c.forgetOldestRun()
+	}
+}
+
+// RunID returns the current run, or "" if no run has started
+func (c *Collector) RunID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.runID
+}
+
+// TagTest records that testID belongs to the current run, so that its result
+// is counted for that run even if another run starts before it finishes
+func (c *Collector) TagTest(testID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.runID != "" {
+		c.testRuns[testID] = c.runID
+	}
+}
+
+// RunCounts returns the completed and failed tests of runID. ok is false if
+// the run is unknown, having never started or been forgotten.
+func (c *Collector) RunCounts(runID string) (completed, failed int, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	counts, exists := c.runs[runID]
+	if !exists {
+		return 0, 0, false
+	}
+	return counts.completed, counts.failed, true
+}
+
+// runOf returns the run testID belongs to, and stops tracking it. The caller
+// must hold c.mu.
+func (c *Collector) runOf(testID string) string {
+	runID, tagged := c.testRuns[testID]
+	if !tagged {
+		return c.runID
+	}
+	delete(c.testRuns, testID)
+	return runID
+}
+
+// countRun adds a finished test of status to the counts of runID. The caller
+// must hold c.mu.
+func (c *Collector) countRun(runID, status string, delta int) {
+	counts, exists := c.runs[runID]
+	if !exists {
+		return
+	}
+	if status == "completed" {
+		counts.completed += delta
+	} else if status == "failed" {
+		counts.failed += delta
+	}
+}
+
+// forgetOldestRun drops the counts of the run that started first, and the
+// tags of its unfinished tests. The caller must hold c.mu.
+func (c *Collector) forgetOldestRun() {
+	var oldestID string
+	var oldestSeq uint64
+	for runID, counts := range c.runs {
+		if oldestID == "" || counts.seq < oldestSeq {
+			oldestID, oldestSeq = runID, counts.seq
+		}
+	}
+	delete(c.runs, oldestID)
+	for testID, runID := range c.testRuns {
+		if runID == oldestID {
+			delete(c.testRuns, testID)
+		}
+	}
+}
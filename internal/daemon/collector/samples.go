@@ -0,0 +1,50 @@
+package collector
+
+import "time"
+
+// MaxSamples bounds the stored resource samples; the oldest are dropped
+// first, so a long run keeps its most recent samples
+const MaxSamples = 10000
+
+// ResourceSample is the host's resource usage at one point in time
+type ResourceSample struct {
+	Time            time.Time
+	CPUPercent      float64 // Busy share of all cores since the previous sample
+	MemoryUsedBytes uint64
+	MemoryPercent   float64
+	NICs            []NICCounters
+}
+
+// NICCounters are an interface's cumulative byte counters
+type NICCounters struct {
+	Name      string
+	BytesSent uint64
+	BytesRecv uint64
+}
+
+// AddSample stores a resource sample
+func (c *Collector) AddSample(sample *ResourceSample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.samples) >= MaxSamples {
+		c.samples = c.samples[1:]
+	}
+	c.samples = append(c.samples, sample)
+}
+
+// GetSamples returns the stored resource samples in time order
+func (c *Collector) GetSamples() []*ResourceSample {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return append([]*ResourceSample(nil), c.samples...)
+}
+
+// ClearSamples drops the stored resource samples
+func (c *Collector) ClearSamples() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.samples = nil
+}
@@ -0,0 +1,194 @@
+// Package facts gathers read-only facts about the daemon's host environment.
+package facts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// Timeout bounds the total time spent gathering facts
+const Timeout = time.Second
+
+// Host locates the sources facts are read from
+type Host struct {
+	ProcSys     string // Root of the sysctl tree, /proc/sys
+	SysClassNet string // Root of the network interface tree, /sys/class/net
+	// Interfaces lists the host's network interfaces
+	Interfaces func() ([]net.Interface, error)
+}
+
+// LocalHost reads the facts of the host the daemon runs on
+var LocalHost = Host{
+	ProcSys:     "/proc/sys",
+	SysClassNet: "/sys/class/net",
+	Interfaces:  net.Interfaces,
+}
+
+// Gather collects the facts of the local host
+func Gather(ctx context.Context, iperfPath string) *models.NodeFacts {
+	return LocalHost.Gather(ctx, iperfPath)
+}
+
+// Gather collects host facts. It only reads files and runs iperf3 --version,
+// and leaves fields empty when a source is missing (e.g. on non-Linux hosts).
+// It returns within Timeout, or ctx's deadline if earlier, with the facts
+// gathered by then; a read that hangs leaves its fields and the later ones
+// empty.
+func (h Host) Gather(ctx context.Context, iperfPath string) *models.NodeFacts {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	// Each step reads a source and returns how to record it
+	steps := []func() func(*models.NodeFacts){
+		func() func(*models.NodeFacts) {
+			info, err := host.InfoWithContext(ctx)
+			return func(f *models.NodeFacts) {
+				if err == nil {
+					f.Platform = strings.TrimSpace(info.Platform + " " + info.PlatformVersion)
+					f.KernelVersion = info.KernelVersion
+				}
+			}
+		},
+		func() func(*models.NodeFacts) {
+			version := iperfVersion(ctx, iperfPath)
+			return func(f *models.NodeFacts) { f.IperfVersion = version }
+		},
+		func() func(*models.NodeFacts) {
+			qdisc := h.readSysctl("net/core/default_qdisc")
+			congestion := h.readSysctl("net/ipv4/tcp_congestion_control")
+			rmem, wmem := h.readSysctlInt("net/core/rmem_max"), h.readSysctlInt("net/core/wmem_max")
+			return func(f *models.NodeFacts) {
+				f.DefaultQdisc, f.TCPCongestionControl = qdisc, congestion
+				f.RmemMax, f.WmemMax = rmem, wmem
+			}
+		},
+		func() func(*models.NodeFacts) {
+			interfaces := h.interfaceFacts()
+			return func(f *models.NodeFacts) { f.Interfaces = interfaces }
+		},
+	}
+
+	var mu sync.Mutex
+	facts := models.NodeFacts{
+		OS:   runtime.GOOS,
+		Arch: runtime.GOARCH,
+	}
+	timedOut := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for _, step := range steps {
+			record := step()
+			mu.Lock()
+			if timedOut {
+				mu.Unlock()
+				return
+			}
+			record(&facts)
+			mu.Unlock()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	timedOut = true
+	gathered := facts
+	return &gathered
+}
+
+// iperfVersion returns the first line of iperf3 --version output
+func iperfVersion(ctx context.Context, iperfPath string) string {
+	if iperfPath == "" {
+		iperfPath = "iperf3"
+	}
+
+	output, err := exec.CommandContext(ctx, iperfPath, "--version").Output() // #nosec G204 -- iperf3 path is controlled
+	if err != nil && len(output) == 0 {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	if scanner.Scan() {
+		return strings.TrimSpace(scanner.Text())
+	}
+	return ""
+}
+
+// AvailableCongestionControl returns the TCP congestion control algorithms
+// the kernel has loaded, or nil where that is not reported
+func AvailableCongestionControl() []string {
+	return strings.Fields(LocalHost.readSysctl("net/ipv4/tcp_available_congestion_control"))
+}
+
+// readSysctl reads a sysctl value, returning "" if unavailable
+func (h Host) readSysctl(name string) string {
+	return readTrimmed(filepath.Join(h.ProcSys, name))
+}
+
+// readSysctlInt reads an integer sysctl value, returning 0 if unavailable
+func (h Host) readSysctlInt(name string) int64 {
+	value, err := strconv.ParseInt(h.readSysctl(name), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// readTrimmed reads a small file and trims surrounding whitespace
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path) // #nosec G304 -- Paths are under the configured sysfs/procfs roots
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// interfaceFacts describes the non-loopback interfaces that are up
+func (h Host) interfaceFacts() []models.InterfaceFacts {
+	interfaces, err := h.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	result := make([]models.InterfaceFacts, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+
+		facts := models.InterfaceFacts{
+			Name: iface.Name,
+			MTU:  iface.MTU,
+		}
+
+		// Speed is -1 or unreadable for virtual and down links
+		if speed, err := strconv.ParseInt(readTrimmed(filepath.Join(h.SysClassNet, iface.Name, "speed")), 10, 64); err == nil && speed > 0 {
+			facts.SpeedMbps = speed
+		}
+		if driver, err := os.Readlink(filepath.Join(h.SysClassNet, iface.Name, "device", "driver")); err == nil {
+			facts.Driver = filepath.Base(driver)
+		}
+
+		result = append(result, facts)
+	}
+
+	return result
+}
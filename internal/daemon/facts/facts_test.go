@@ -0,0 +1,109 @@
+package facts
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/models"
+)
+
+// newTestHost returns a host whose procfs and sysfs are under a temporary
+// directory holding files, with the given interfaces
+func newTestHost(t *testing.T, files map[string]string, interfaces []net.Interface) Host {
+	t.Helper()
+	root := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Host{
+		ProcSys:     filepath.Join(root, "proc/sys"),
+		SysClassNet: filepath.Join(root, "sys/class/net"),
+		Interfaces:  func() ([]net.Interface, error) { return interfaces, nil },
+	}
+}
+
+func TestHost_Gather(t *testing.T) {
+	host := newTestHost(t, map[string]string{
+		"proc/sys/net/core/default_qdisc":          "fq\n",
+		"proc/sys/net/ipv4/tcp_congestion_control": "bbr\n",
+		"proc/sys/net/core/rmem_max":               "67108864\n",
+		"proc/sys/net/core/wmem_max":               "unlimited\n", // Not a number: left out
+		"sys/class/net/eth0/speed":                 "25000\n",
+		"sys/class/net/eth1/speed":                 "-1\n", // Virtual link: left out
+	}, []net.Interface{
+		{Name: "lo", MTU: 65536, Flags: net.FlagUp | net.FlagLoopback},
+		{Name: "eth0", MTU: 9000, Flags: net.FlagUp},
+		{Name: "eth1", MTU: 1500, Flags: net.FlagUp},
+		{Name: "eth2", MTU: 1500}, // Down
+	})
+	if err := os.MkdirAll(filepath.Join(host.SysClassNet, "eth0/device"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	// Like sysfs, link to the driver relative to the device
+	if err := os.Symlink("../../../bus/pci/drivers/mlx5_core", filepath.Join(host.SysClassNet, "eth0/device/driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	facts := host.Gather(context.Background(), filepath.Join(t.TempDir(), "no-iperf3"))
+
+	if facts.DefaultQdisc != "fq" || facts.TCPCongestionControl != "bbr" {
+		t.Errorf("qdisc, congestion control = %q, %q; want fq, bbr", facts.DefaultQdisc, facts.TCPCongestionControl)
+	}
+	if facts.RmemMax != 67108864 || facts.WmemMax != 0 {
+		t.Errorf("rmem_max, wmem_max = %d, %d; want 67108864, 0", facts.RmemMax, facts.WmemMax)
+	}
+	if facts.IperfVersion != "" {
+		t.Errorf("IperfVersion = %q without iperf3, want empty", facts.IperfVersion)
+	}
+	want := []models.InterfaceFacts{
+		{Name: "eth0", MTU: 9000, SpeedMbps: 25000, Driver: "mlx5_core"},
+		{Name: "eth1", MTU: 1500},
+	}
+	if !reflect.DeepEqual(facts.Interfaces, want) {
+		t.Errorf("Interfaces = %+v, want %+v", facts.Interfaces, want)
+	}
+}
+
+func TestHost_GatherMissingSources(t *testing.T) {
+	host := newTestHost(t, nil, nil)
+	facts := host.Gather(context.Background(), "")
+
+	if facts.OS == "" || facts.Arch == "" {
+		t.Errorf("OS, Arch = %q, %q; want the runtime's", facts.OS, facts.Arch)
+	}
+	if facts.DefaultQdisc != "" || facts.TCPCongestionControl != "" || facts.RmemMax != 0 || facts.WmemMax != 0 || len(facts.Interfaces) != 0 {
+		t.Errorf("facts = %+v, want sysctls and interfaces empty", facts)
+	}
+}
+
+func TestHost_GatherTimeout(t *testing.T) {
+	host := newTestHost(t, map[string]string{"proc/sys/net/core/default_qdisc": "fq"}, nil)
+	release := make(chan struct{})
+	defer close(release)
+	host.Interfaces = func() ([]net.Interface, error) {
+		<-release // A hanging read
+		return []net.Interface{{Name: "eth0", Flags: net.FlagUp}}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	facts := host.Gather(ctx, "")
+	if elapsed := time.Since(start); elapsed > Timeout {
+		t.Errorf("Gather() took %s, want it bounded by the deadline", elapsed)
+	}
+	if facts.DefaultQdisc != "fq" || facts.Interfaces != nil {
+		t.Errorf("facts = %+v, want those read before the hanging read", facts)
+	}
+}
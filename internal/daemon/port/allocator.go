@@ -2,7 +2,9 @@ package port
 
 import (
 	"fmt"
+	"math/rand"
 	"sync"
+	"time"
 )
 
 // Allocator manages port allocation for iperf3 servers
@@ -12,7 +14,19 @@ type Allocator struct {
 	allocatedPorts map[int]bool
 	portToTestID   map[int]string
 	testIDToPort   map[string]int
-	mu             sync.RWMutex
+	reservations   map[string]*Reservation // owner -> reserved sub-range
+	claims         map[string][]int        // owner -> ports allocated to its run
+	// excluded ports are never allocated, such as the daemon's own gRPC port
+	excluded map[int]bool
+
+	strategy Strategy
+	cooldown time.Duration
+	released map[int]time.Time // When ports were last released, for the cool-down
+	next     int               // Where round-robin allocation continues
+	rng      *rand.Rand        // Set for the random strategy
+	now      func() time.Time
+
+	mu sync.RWMutex
 }
 
 // NewAllocator creates a new port allocator
@@ -33,9 +47,49 @@ func NewAllocator(startPort, endPort int) (*Allocator, error) {
 		allocatedPorts: make(map[int]bool),
 		portToTestID:   make(map[int]string),
 		testIDToPort:   make(map[string]int),
+		reservations:   make(map[string]*Reservation),
+		claims:         make(map[string][]int),
+		excluded:       make(map[int]bool),
+		strategy:       StrategySequential,
+		released:       make(map[int]time.Time),
+		now:            time.Now,
 	}, nil
 }
 
+// Exclude keeps ports from ever being allocated, so that ports the daemon
+// listens on itself are not handed to iperf3 servers even when they fall
+// inside the range
+func (a *Allocator) Exclude(ports ...int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, port := range ports {
+		if port > 0 {
+			a.excluded[port] = true
+		}
+	}
+}
+
+// IsExcluded reports whether a port is excluded from allocation
+func (a *Allocator) IsExcluded(port int) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.excluded[port]
+}
+
+// excludedIn returns the number of excluded ports within start-end; the
+// caller must hold a.mu
+func (a *Allocator) excludedIn(start, end int) int {
+	count := 0
+	for port := range a.excluded {
+		if port >= start && port <= end {
+			count++
+		}
+	}
+	return count
+}
+
 // AllocatePort allocates a port for a test
 func (a *Allocator) AllocatePort(testID string) (int, error) {
 	a.mu.Lock()
@@ -46,14 +100,12 @@ func (a *Allocator) AllocatePort(testID string) (int, error) {
 		return port, nil
 	}
 
-	// Find first available port
-	for port := a.startPort; port <= a.endPort; port++ {
-		if !a.allocatedPorts[port] {
-			a.allocatedPorts[port] = true
-			a.portToTestID[port] = testID
-			a.testIDToPort[testID] = port
-			return port, nil
-		}
+	if ports := a.pickPorts(1); len(ports) == 1 {
+		port := ports[0]
+		a.allocatedPorts[port] = true
+		a.portToTestID[port] = testID
+		a.testIDToPort[testID] = port
+		return port, nil
 	}
 
 	return 0, fmt.Errorf("no available ports in range %d-%d", a.startPort, a.endPort)
@@ -64,14 +116,7 @@ func (a *Allocator) AllocatePorts(count int) ([]int, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	ports := make([]int, 0, count)
-
-	for port := a.startPort; port <= a.endPort && len(ports) < count; port++ {
-		if !a.allocatedPorts[port] {
-			ports = append(ports, port)
-		}
-	}
-
+	ports := a.pickPorts(count)
 	if len(ports) < count {
 		return nil, fmt.Errorf("insufficient ports: need %d, found %d", count, len(ports))
 	}
@@ -97,6 +142,7 @@ func (a *Allocator) ReleasePort(testID string) error {
 	delete(a.allocatedPorts, port)
 	delete(a.portToTestID, port)
 	delete(a.testIDToPort, testID)
+	a.markReleased(port)
 
 	return nil
 }
@@ -114,6 +160,7 @@ func (a *Allocator) ReleasePortByNumber(port int) error {
 	delete(a.allocatedPorts, port)
 	delete(a.portToTestID, port)
 	delete(a.testIDToPort, testID)
+	a.markReleased(port)
 
 	return nil
 }
@@ -129,6 +176,7 @@ func (a *Allocator) ReleasePorts(ports []int) {
 			delete(a.allocatedPorts, port)
 			delete(a.portToTestID, port)
 			delete(a.testIDToPort, testID)
+			a.markReleased(port)
 		}
 	}
 }
@@ -172,13 +220,16 @@ func (a *Allocator) GetAvailableCount() int {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	totalPorts := a.endPort - a.startPort + 1
+	totalPorts := a.endPort - a.startPort + 1 - a.excludedIn(a.startPort, a.endPort)
 	return totalPorts - len(a.allocatedPorts)
 }
 
-// GetCapacity returns the total port capacity
+// GetCapacity returns the total port capacity, less the excluded ports
 func (a *Allocator) GetCapacity() int {
-	return a.endPort - a.startPort + 1
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.endPort - a.startPort + 1 - a.excludedIn(a.startPort, a.endPort)
 }
 
 // ReleaseAll releases all allocated ports
@@ -186,9 +237,13 @@ func (a *Allocator) ReleaseAll() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	for port := range a.allocatedPorts {
+		a.markReleased(port)
+	}
 	a.allocatedPorts = make(map[int]bool)
 	a.portToTestID = make(map[int]string)
 	a.testIDToPort = make(map[string]int)
+	a.claims = make(map[string][]int)
 }
 
 // GetAllocatedPorts returns a list of all allocated ports
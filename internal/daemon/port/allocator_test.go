@@ -2,6 +2,7 @@ package port
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -253,3 +254,140 @@ func TestAllocator_ConcurrentAllocation(t *testing.T) {
 		t.Errorf("GetAllocatedCount() = %d, want %d", allocator.GetAllocatedCount(), numGoroutines)
 	}
 }
+
+func TestAllocator_Reserve(t *testing.T) {
+	allocator, err := NewAllocator(5201, 5300)
+	if err != nil {
+		t.Fatalf("Failed to create allocator: %v", err)
+	}
+
+	if err := allocator.Reserve("team-a", 5201, 5250, 40); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		owner     string
+		start     int
+		end       int
+		requested int
+		wantErr   bool
+	}{
+		{"disjoint range", "team-b", 5251, 5300, 10, false},
+		{"overlapping another owner", "team-c", 5240, 5260, 5, true},
+		{"same owner replaces", "team-a", 5201, 5230, 20, false},
+		{"outside daemon range", "team-d", 5100, 5210, 5, true},
+		{"range exhausted", "team-e", 5231, 5240, 20, true},
+		{"missing owner", "", 5231, 5240, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := allocator.Reserve(tt.owner, tt.start, tt.end, tt.requested)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Reserve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	reservations := allocator.GetReservations()
+	if len(reservations) != 2 {
+		t.Fatalf("Expected 2 reservations, got %d", len(reservations))
+	}
+	if reservations[0].Owner != "team-a" || reservations[0].End != 5230 {
+		t.Errorf("Expected team-a to hold 5201-5230, got %+v", reservations[0])
+	}
+
+	allocator.ReleaseReservation("team-a")
+	if err := allocator.Reserve("team-c", 5201, 5250, 5); err != nil {
+		t.Errorf("Reserve after release failed: %v", err)
+	}
+}
+
+func TestAllocator_Exclude(t *testing.T) {
+	allocator, err := NewAllocator(5201, 5203)
+	if err != nil {
+		t.Fatalf("NewAllocator() error = %v", err)
+	}
+	// Both ends of the range and a port outside it
+	allocator.Exclude(5201, 5203, 50051)
+
+	if got := allocator.GetCapacity(); got != 1 {
+		t.Errorf("GetCapacity() = %d, want 1", got)
+	}
+	if got := allocator.GetAvailableCount(); got != 1 {
+		t.Errorf("GetAvailableCount() = %d, want 1", got)
+	}
+	port, err := allocator.AllocatePort("test-1")
+	if err != nil || port != 5202 {
+		t.Errorf("AllocatePort() = %d, %v, want 5202", port, err)
+	}
+	if _, err := allocator.AllocatePort("test-2"); err == nil {
+		t.Errorf("AllocatePort() allocated an excluded port")
+	}
+	allocator.ReleaseAll()
+	if _, err := allocator.AllocatePorts(2); err == nil {
+		t.Errorf("AllocatePorts(2) allocated an excluded port")
+	}
+	if !allocator.IsExcluded(5203) || allocator.IsExcluded(5202) {
+		t.Errorf("IsExcluded() does not match the excluded ports")
+	}
+
+	// Reservations must fit their requested ports besides excluded ones
+	if err := allocator.Reserve("team-a", 5201, 5203, 2); err == nil {
+		t.Errorf("Reserve() counted excluded ports towards the request")
+	}
+	if err := allocator.Reserve("team-a", 5201, 5203, 1); err != nil {
+		t.Errorf("Reserve() error = %v", err)
+	}
+}
+
+func TestAllocator_Claim(t *testing.T) {
+	allocator, err := NewAllocator(5201, 5210)
+	if err != nil {
+		t.Fatalf("NewAllocator() error = %v", err)
+	}
+	allocator.Exclude(5202)
+	if err := allocator.Reserve("team-b", 5206, 5210, 2); err != nil {
+		t.Fatalf("Reserve() error = %v", err)
+	}
+
+	// Ports reserved by another run and excluded ones are skipped
+	ports, err := allocator.Claim("team-a", 5201, 5210, 3)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if !reflect.DeepEqual(ports, []int{5201, 5203, 5204}) {
+		t.Errorf("Claim() = %v, want [5201 5203 5204]", ports)
+	}
+	if !allocator.IsClaimed(5203) || allocator.GetAllocatedCount() != 3 {
+		t.Errorf("claimed ports not allocated: count %d", allocator.GetAllocatedCount())
+	}
+
+	if _, err := allocator.Claim("team-a", 5201, 5210, 5); err == nil {
+		t.Error("Claim() beyond the unreserved ports expected error")
+	}
+
+	// Claiming again replaces the owner's ports
+	if _, err := allocator.Claim("team-b", 5206, 5210, 2); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if _, err := allocator.Claim("team-a", 5201, 5205, 4); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if allocator.GetAllocatedCount() != 6 {
+		t.Errorf("GetAllocatedCount() = %d, want 6", allocator.GetAllocatedCount())
+	}
+
+	allocator.ReleaseClaim("team-a")
+	if allocator.IsClaimed(5201) || allocator.GetAllocatedCount() != 2 {
+		t.Errorf("ReleaseClaim() left %d ports allocated, want team-b's 2", allocator.GetAllocatedCount())
+	}
+
+	if _, err := allocator.Claim("team-a", 5100, 5205, 1); err == nil {
+		t.Error("Claim() outside the daemon range expected error")
+	}
+	if _, err := allocator.Claim("", 5201, 5205, 1); err == nil {
+		t.Error("Claim() without an owner expected error")
+	}
+}
@@ -0,0 +1,81 @@
+package port
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Claim allocates count server ports to owner's run, replacing any it
+// claimed before. The ports come from start-end, which must lie within the
+// allocator's range, and never from a range another owner reserved. They
+// stay allocated until released with ReleaseClaim or ReleaseAll, and are
+// returned in ascending order.
+func (a *Allocator) Claim(owner string, start, end, count int) ([]int, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("port claim requires an owner")
+	}
+	if start < a.startPort || end > a.endPort || start > end {
+		return nil, fmt.Errorf("port range %d-%d is outside the daemon range %d-%d",
+			start, end, a.startPort, a.endPort)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.releaseClaim(owner)
+
+	ports := a.pickPortsIn(start, end, count, func(port int) bool {
+		for _, reservation := range a.reservations {
+			if reservation.Owner != owner && reservation.overlaps(port, port) {
+				return false
+			}
+		}
+		return true
+	})
+	if len(ports) < count {
+		return nil, fmt.Errorf("insufficient ports: need %d server ports, only %d free in range %d-%d",
+			count, len(ports), start, end)
+	}
+
+	sort.Ints(ports)
+	for _, port := range ports {
+		a.allocatedPorts[port] = true
+	}
+	a.claims[owner] = ports
+
+	return ports, nil
+}
+
+// ReleaseClaim releases the ports claimed by owner, if any
+func (a *Allocator) ReleaseClaim(owner string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.releaseClaim(owner)
+}
+
+// releaseClaim releases the ports claimed by owner; the caller must hold
+// a.mu
+func (a *Allocator) releaseClaim(owner string) {
+	for _, port := range a.claims[owner] {
+		delete(a.allocatedPorts, port)
+		a.markReleased(port)
+	}
+	delete(a.claims, owner)
+}
+
+// IsClaimed reports whether a port is claimed by a run, so it is allocated
+// while nothing may run on it yet
+func (a *Allocator) IsClaimed(port int) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, ports := range a.claims {
+		for _, claimed := range ports {
+			if claimed == port {
+				return true
+			}
+		}
+	}
+	return false
+}
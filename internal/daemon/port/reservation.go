@@ -0,0 +1,99 @@
+package port
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Reservation is a sub-range of the daemon's port range claimed by one
+// controller run, so concurrent runs sharing a daemon do not collide
+type Reservation struct {
+	Owner     string
+	Start     int
+	End       int
+	Requested int // Server ports the run's topology needs
+}
+
+// Size returns the number of ports in the reservation
+func (r *Reservation) Size() int {
+	return r.End - r.Start + 1
+}
+
+// overlaps reports whether the reservation shares any port with start-end
+func (r *Reservation) overlaps(start, end int) bool {
+	return start <= r.End && end >= r.Start
+}
+
+// Reserve claims start-end for owner. The range must lie within the
+// allocator's range, hold at least requested ports besides the excluded
+// ones, and not overlap a range held by another owner. Reserving again with
+// the same owner replaces its previous reservation.
+func (a *Allocator) Reserve(owner string, start, end, requested int) error {
+	if owner == "" {
+		return fmt.Errorf("port range reservation requires an owner")
+	}
+	if start > end {
+		return fmt.Errorf("invalid port range %d-%d: start must not exceed end", start, end)
+	}
+	if start < a.startPort || end > a.endPort {
+		return fmt.Errorf("requested port range %d-%d is outside the daemon range %d-%d",
+			start, end, a.startPort, a.endPort)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	size := end - start + 1 - a.excludedIn(start, end)
+	if requested > size {
+		return fmt.Errorf("requested port range %d-%d exhausted: need %d server ports, range has %d",
+			start, end, requested, size)
+	}
+
+	for _, other := range a.reservations {
+		if other.Owner != owner && other.overlaps(start, end) {
+			return fmt.Errorf("requested port range %d-%d overlaps range %d-%d reserved by %s",
+				start, end, other.Start, other.End, other.Owner)
+		}
+	}
+
+	a.reservations[owner] = &Reservation{
+		Owner:     owner,
+		Start:     start,
+		End:       end,
+		Requested: requested,
+	}
+
+	return nil
+}
+
+// ReleaseReservation releases the range held by owner, if any
+func (a *Allocator) ReleaseReservation(owner string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.reservations, owner)
+}
+
+// ReleaseAllReservations releases every reserved range
+func (a *Allocator) ReleaseAllReservations() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.reservations = make(map[string]*Reservation)
+}
+
+// GetReservations returns the current reservations ordered by start port
+func (a *Allocator) GetReservations() []Reservation {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	reservations := make([]Reservation, 0, len(a.reservations))
+	for _, r := range a.reservations {
+		reservations = append(reservations, *r)
+	}
+	sort.Slice(reservations, func(i, j int) bool {
+		return reservations[i].Start < reservations[j].Start
+	})
+
+	return reservations
+}
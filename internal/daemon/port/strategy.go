@@ -0,0 +1,134 @@
+package port
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Strategy is how an Allocator chooses among free ports
+type Strategy string
+
+const (
+	// StrategySequential allocates the lowest free ports
+	StrategySequential Strategy = "sequential"
+	// StrategyRoundRobin continues from the port after the last allocated
+	// one, wrapping around the range
+	StrategyRoundRobin Strategy = "round_robin"
+	// StrategyRandom picks uniformly among the free ports
+	StrategyRandom Strategy = "random"
+)
+
+// ParseStrategy parses an allocation strategy, defaulting to sequential
+// when empty
+func ParseStrategy(strategy string) (Strategy, error) {
+	switch Strategy(strategy) {
+	case "", StrategySequential:
+		return StrategySequential, nil
+	case StrategyRoundRobin, StrategyRandom:
+		return Strategy(strategy), nil
+	}
+	return "", fmt.Errorf("unknown port allocation strategy %q: must be sequential, round_robin or random", strategy)
+}
+
+// SetStrategy sets how ports are chosen. The round_robin and random
+// strategies avoid ports released less than cooldown ago, whose sockets may
+// still be in TIME_WAIT, unless no other port is free.
+func (a *Allocator) SetStrategy(strategy Strategy, cooldown time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.strategy = strategy
+	a.cooldown = cooldown
+	if strategy == StrategyRandom && a.rng == nil {
+		a.rng = rand.New(rand.NewSource(time.Now().UnixNano())) // #nosec G404 -- Port choice needs no cryptographic randomness
+	}
+}
+
+// Strategy returns how ports are chosen and the cool-down of released ports
+func (a *Allocator) Strategy() (Strategy, time.Duration) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.strategy, a.cooldown
+}
+
+// pickPorts chooses count free ports by the allocation strategy, returning
+// fewer when fewer are free; the caller must hold a.mu
+func (a *Allocator) pickPorts(count int) []int {
+	return a.pickPortsIn(a.startPort, a.endPort, count, nil)
+}
+
+// pickPortsIn chooses count free ports within start-end by the allocation
+// strategy, skipping those usable rejects if it is set; the caller must
+// hold a.mu
+func (a *Allocator) pickPortsIn(start, end, count int, usable func(port int) bool) []int {
+	candidates := make([]int, 0, end-start+1)
+	first := start
+	if a.strategy == StrategyRoundRobin && a.next > start && a.next <= end {
+		first = a.next
+	}
+	for i := 0; i <= end-start; i++ {
+		port := first + i
+		if port > end {
+			port -= end - start + 1
+		}
+		if !a.allocatedPorts[port] && !a.excluded[port] && (usable == nil || usable(port)) {
+			candidates = append(candidates, port)
+		}
+	}
+	if a.strategy == StrategyRandom {
+		a.rng.Shuffle(len(candidates), func(i, j int) {
+			candidates[i], candidates[j] = candidates[j], candidates[i]
+		})
+	}
+
+	// Ports still cooling down go last
+	if a.strategy != StrategySequential && a.cooldown > 0 {
+		cutoff := a.now().Add(-a.cooldown)
+		ready := make([]int, 0, len(candidates))
+		cooling := make([]int, 0)
+		for _, port := range candidates {
+			if released, ok := a.released[port]; ok && released.After(cutoff) {
+				cooling = append(cooling, port)
+			} else {
+				delete(a.released, port)
+				ready = append(ready, port)
+			}
+		}
+		candidates = append(ready, cooling...)
+	}
+
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+	if a.strategy == StrategyRoundRobin && len(candidates) > 0 {
+		a.next = nextAfter(candidates, first, start, end)
+	}
+	return candidates
+}
+
+// nextAfter returns where round-robin allocation continues after picked, the
+// port following the one furthest from first in scan order, wrapped within
+// start-end; cooling ports picked last may come before the others
+func nextAfter(picked []int, first, start, end int) int {
+	span := end - start + 1
+	last := picked[0]
+	for _, port := range picked[1:] {
+		if (port-first+span)%span > (last-first+span)%span {
+			last = port
+		}
+	}
+	if last == end {
+		return start
+	}
+	return last + 1
+}
+
+// markReleased records when a port was released, for the cool-down; the
+// caller must hold a.mu
+func (a *Allocator) markReleased(port int) {
+	if a.strategy != StrategySequential && a.cooldown > 0 {
+		a.released[port] = a.now()
+	}
+}
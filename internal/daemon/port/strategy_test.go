@@ -0,0 +1,139 @@
+package port
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAllocator_RoundRobin(t *testing.T) {
+	allocator, err := NewAllocator(5201, 5204)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocator.SetStrategy(StrategyRoundRobin, 0)
+
+	// Released ports are not reused until the range wraps around
+	for i, want := range []int{5201, 5202, 5203, 5204, 5201} {
+		testID := fmt.Sprintf("test-%d", i)
+		port, err := allocator.AllocatePort(testID)
+		if err != nil {
+			t.Fatalf("AllocatePort(%s) error = %v", testID, err)
+		}
+		if port != want {
+			t.Errorf("AllocatePort(%s) = %d, want %d", testID, port, want)
+		}
+		if err := allocator.ReleasePort(testID); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAllocator_Cooldown(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	for _, strategy := range []Strategy{StrategyRoundRobin, StrategyRandom} {
+		t.Run(string(strategy), func(t *testing.T) {
+			allocator, err := NewAllocator(5201, 5203)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allocator.now = func() time.Time { return now }
+			allocator.SetStrategy(strategy, time.Minute)
+
+			// Release two ports and keep one: only cooling ports are free
+			ports, err := allocator.AllocatePorts(3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allocator.ReleasePorts(ports[:2])
+			cooling := map[int]bool{ports[0]: true, ports[1]: true}
+
+			// With nothing else free, a cooling port is used
+			port, err := allocator.AllocatePort("test-a")
+			if err != nil {
+				t.Fatalf("AllocatePort() error = %v", err)
+			}
+			if !cooling[port] {
+				t.Errorf("AllocatePort() = %d, want one of the released ports %v", port, ports[:2])
+			}
+
+			// Once the other released port has cooled down, it is preferred
+			// to the one just released
+			now = now.Add(2 * time.Minute)
+			allocator.ReleasePorts([]int{ports[2]})
+			port, err = allocator.AllocatePort("test-b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port == ports[2] || !cooling[port] {
+				t.Errorf("AllocatePort() = %d, want the other port released before the cool-down", port)
+			}
+		})
+	}
+}
+
+func TestAllocator_RoundRobinCooldown(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	allocator, err := NewAllocator(5201, 5205)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocator.now = func() time.Time { return now }
+	allocator.SetStrategy(StrategyRoundRobin, time.Minute)
+
+	// 5202 and 5203 have cooled down when 5201 is released
+	if _, err := allocator.AllocatePorts(5); err != nil {
+		t.Fatal(err)
+	}
+	allocator.ReleasePorts([]int{5202, 5203})
+	now = now.Add(2 * time.Minute)
+	allocator.ReleasePorts([]int{5201})
+
+	// The cooling 5201 is picked last, after the ports that follow it
+	ports, err := allocator.AllocatePorts(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ports) != "[5202 5203 5201]" {
+		t.Errorf("AllocatePorts(3) = %v, want [5202 5203 5201]", ports)
+	}
+
+	// Allocation continues after 5203, the last of them in the range
+	allocator.ReleasePorts(append(ports, 5204, 5205))
+	now = now.Add(2 * time.Minute)
+	port, err := allocator.AllocatePort("test-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 5204 {
+		t.Errorf("AllocatePort() = %d, want 5204", port)
+	}
+
+	// And wraps around after the end of the range
+	port, err = allocator.AllocatePort("test-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if port != 5205 {
+		t.Fatalf("AllocatePort() = %d, want 5205", port)
+	}
+	if allocator.next != 5201 {
+		t.Errorf("next = %d after the end of the range, want 5201", allocator.next)
+	}
+}
+
+func TestParseStrategy(t *testing.T) {
+	for input, want := range map[string]Strategy{
+		"":            StrategySequential,
+		"sequential":  StrategySequential,
+		"round_robin": StrategyRoundRobin,
+		"random":      StrategyRandom,
+	} {
+		if got, err := ParseStrategy(input); err != nil || got != want {
+			t.Errorf("ParseStrategy(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	if _, err := ParseStrategy("lowest"); err == nil {
+		t.Error("ParseStrategy(lowest) succeeded, want error")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"runtime"
+	"sync"
 
 	"github.com/shirou/gopsutil/v3/cpu"
 	"github.com/shirou/gopsutil/v3/mem"
@@ -22,6 +23,7 @@ type Capacity struct {
 type CapacityCalculator struct {
 	maxProcesses int
 	usedSlots    int
+	mu           sync.Mutex
 }
 
 // NewCapacityCalculator creates a new capacity calculator
@@ -59,12 +61,15 @@ func (c *CapacityCalculator) DetectCapacity() (*Capacity, error) {
 		AvailableMemory:    vmStat.Available,
 		NetworkInterfaces:  interfaces,
 		MaxProcesses:       maxProcs,
-		AvailableProcesses: maxProcs - c.usedSlots,
+		AvailableProcesses: maxProcs - c.GetUsedSlots(),
 	}, nil
 }
 
 // ReserveSlots reserves process slots
 func (c *CapacityCalculator) ReserveSlots(count int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.usedSlots+count > c.maxProcesses {
 		return fmt.Errorf("insufficient capacity: need %d slots, have %d available",
 			count, c.maxProcesses-c.usedSlots)
@@ -75,6 +80,9 @@ func (c *CapacityCalculator) ReserveSlots(count int) error {
 
 // ReleaseSlots releases process slots
 func (c *CapacityCalculator) ReleaseSlots(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.usedSlots -= count
 	if c.usedSlots < 0 {
 		c.usedSlots = 0
@@ -83,14 +91,25 @@ func (c *CapacityCalculator) ReleaseSlots(count int) {
 
 // GetAvailableSlots returns the number of available process slots
 func (c *CapacityCalculator) GetAvailableSlots() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.maxProcesses - c.usedSlots
 }
 
 // GetUsedSlots returns the number of used process slots
 func (c *CapacityCalculator) GetUsedSlots() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	return c.usedSlots
 }
 
+// GetMaxSlots returns the number of process slots
+func (c *CapacityCalculator) GetMaxSlots() int {
+	return c.maxProcesses
+}
+
 // getNetworkInterfaces returns a list of active network interface names
 func getNetworkInterfaces() ([]string, error) {
 	interfaces, err := net.Interfaces()
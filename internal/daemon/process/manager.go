@@ -2,12 +2,18 @@ package process
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bensons/iperf-cnc/internal/common/iperf"
+	"github.com/bensons/iperf-cnc/internal/daemon/capture"
 	"github.com/bensons/iperf-cnc/internal/daemon/collector"
 	"github.com/bensons/iperf-cnc/internal/daemon/port"
 )
@@ -15,24 +21,39 @@ import (
 // ProcessInfo contains information about a running process
 type ProcessInfo struct {
 	TestID    string
+	Owner     string // Controller run that started it; "" if none was given
 	PID       int
 	Port      int
 	Mode      iperf.Mode
 	StartTime time.Time
 	Cmd       *exec.Cmd
 	Cancel    context.CancelFunc
+	// Process is the iperf3 process, once it runs; nil for simulated tests
+	Process *os.Process
+	// Stopping is set once the process was stopped, until it exits
+	Stopping bool
+}
+
+// Runner runs iperf3 clients and servers. The iperf.Wrapper runs the iperf3
+// binary; a simulated runner may return a nil command for servers, which
+// then have no process and run until stopped.
+type Runner interface {
+	Run(ctx context.Context, config *iperf.Config) (*iperf.Result, error)
+	RunServer(ctx context.Context, port int, bindIP, logFile string) (*exec.Cmd, error)
 }
 
 // Manager manages iperf3 processes
 type Manager struct {
 	portAllocator *port.Allocator
 	capacity      *CapacityCalculator
-	iperf         *iperf.Wrapper
+	iperf         Runner
 	collector     *collector.Collector
 	processes     map[string]*ProcessInfo // testID -> ProcessInfo
 	servers       map[int]*ProcessInfo    // port -> ProcessInfo for servers
+	stopping      map[*ProcessInfo]bool   // Stopped processes that have not exited yet
 	mu            sync.RWMutex
 	iperfPath     string
+	capturer      *capture.Capturer // Set when packet captures are enabled
 }
 
 // NewManager creates a new process manager
@@ -44,12 +65,31 @@ func NewManager(portAllocator *port.Allocator, capacity *CapacityCalculator, res
 		collector:     resultCollector,
 		processes:     make(map[string]*ProcessInfo),
 		servers:       make(map[int]*ProcessInfo),
+		stopping:      make(map[*ProcessInfo]bool),
 		iperfPath:     iperfPath,
 	}
 }
 
-// StartServer starts an iperf3 server on the specified port
-func (m *Manager) StartServer(port int) error {
+// SetRunner replaces the iperf3 binary with another runner
+func (m *Manager) SetRunner(runner Runner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.iperf = runner
+}
+
+// SetCapturer enables packet captures of the client tests that ask for one
+func (m *Manager) SetCapturer(capturer *capture.Capturer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.capturer = capturer
+}
+
+// StartServer starts an iperf3 server on the specified port for owner's
+// run. A non-empty bindIP limits it to that local address, for tests of one
+// interface.
+func (m *Manager) StartServer(port int, bindIP, owner string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -73,7 +113,7 @@ func (m *Manager) StartServer(port int) error {
 	// If server-side logging is needed, use iperf3's syslog or other mechanisms
 
 	// Start the server (without logfile)
-	cmd, err := m.iperf.RunServer(ctx, port, "")
+	cmd, err := m.iperf.RunServer(ctx, port, bindIP, "")
 	if err != nil {
 		m.capacity.ReleaseSlots(1)
 		cancel()
@@ -83,7 +123,7 @@ func (m *Manager) StartServer(port int) error {
 	// Create process info
 	processInfo := &ProcessInfo{
 		TestID:    fmt.Sprintf("server-%d", port),
-		PID:       cmd.Process.Pid,
+		Owner:     owner,
 		Port:      port,
 		Mode:      iperf.ModeServer,
 		StartTime: time.Now(),
@@ -94,14 +134,21 @@ func (m *Manager) StartServer(port int) error {
 	m.servers[port] = processInfo
 	m.processes[processInfo.TestID] = processInfo
 
-	// Monitor server in background
-	go m.monitorProcess(processInfo)
+	// Monitor server in background; a server without a process runs until
+	// it is stopped
+	if cmd != nil {
+		processInfo.PID = cmd.Process.Pid
+		processInfo.Process = cmd.Process
+		go m.monitorProcess(processInfo)
+	}
 
 	return nil
 }
 
-// StartClient starts an iperf3 client test
-func (m *Manager) StartClient(testID, host string, port int, config *iperf.Config) error {
+// StartClient starts an iperf3 client test for owner's run. With a capture
+// policy and captures enabled, the test's packets are captured and the
+// capture kept if the policy says so.
+func (m *Manager) StartClient(testID, owner, host string, port int, config *iperf.Config, policy *capture.Policy) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -127,12 +174,18 @@ func (m *Manager) StartClient(testID, host string, port int, config *iperf.Confi
 	// Server tests can use --logfile since they don't need to return JSON results
 
 	// Create context with timeout
-	timeout := time.Duration(config.Duration+30) * time.Second // Add buffer
+	timeout := config.Duration + 30*time.Second // Add buffer
+	if config.Burst != nil {
+		// Each burst is a separate iperf3 run with its own setup time
+		timeout = config.Burst.Length() + time.Duration(config.Burst.Repeat+30)*time.Second
+	}
+	timeout += config.StartDelay
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 
 	// Create process info
 	processInfo := &ProcessInfo{
 		TestID:    testID,
+		Owner:     owner,
 		Port:      port,
 		Mode:      iperf.ModeClient,
 		StartTime: time.Now(),
@@ -140,9 +193,20 @@ func (m *Manager) StartClient(testID, host string, port int, config *iperf.Confi
 	}
 
 	m.processes[testID] = processInfo
+	config.Started = func(process *os.Process) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		processInfo.Process = process
+		processInfo.PID = process.Pid
+	}
+
+	if policy != nil && m.capturer == nil {
+		log.Printf("Warning: test %s asks for a packet capture, but captures are not enabled", testID)
+		policy = nil
+	}
 
 	// Run client in background
-	go m.runClient(ctx, processInfo, config)
+	go m.runClient(ctx, processInfo, config, policy)
 
 	return nil
 }
@@ -157,10 +221,7 @@ func (m *Manager) StopProcess(testID string) error {
 		return fmt.Errorf("process %s not found", testID)
 	}
 
-	// Cancel context to stop process
-	if processInfo.Cancel != nil {
-		processInfo.Cancel()
-	}
+	m.stop(processInfo, false)
 
 	// If it's a server, also remove from servers map
 	if processInfo.Mode == iperf.ModeServer {
@@ -180,9 +241,7 @@ func (m *Manager) StopAllServers() int {
 
 	count := 0
 	for port, processInfo := range m.servers {
-		if processInfo.Cancel != nil {
-			processInfo.Cancel()
-		}
+		m.stop(processInfo, false)
 		delete(m.servers, port)
 		delete(m.processes, processInfo.TestID)
 		m.capacity.ReleaseSlots(1)
@@ -200,9 +259,7 @@ func (m *Manager) StopAllClients() int {
 	count := 0
 	for testID, processInfo := range m.processes {
 		if processInfo.Mode == iperf.ModeClient {
-			if processInfo.Cancel != nil {
-				processInfo.Cancel()
-			}
+			m.stop(processInfo, false)
 			delete(m.processes, testID)
 			m.capacity.ReleaseSlots(1)
 			count++
@@ -212,26 +269,74 @@ func (m *Manager) StopAllClients() int {
 	return count
 }
 
-// StopAll stops all running processes
-func (m *Manager) StopAll() int {
+// StopAll stops the running processes of owner's run, or every process
+// when owner is "", returning how many it stopped. They are sent SIGTERM and
+// are killed if they don't exit within the stop timeout; with force they,
+// and those of the run stopped earlier that have not exited, are killed at
+// once.
+func (m *Manager) StopAll(owner string, force bool) int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	count := len(m.processes)
-
-	for _, processInfo := range m.processes {
-		if processInfo.Cancel != nil {
-			processInfo.Cancel()
+	count := 0
+	for testID, processInfo := range m.processes {
+		if owner != "" && processInfo.Owner != owner {
+			continue
+		}
+		m.stop(processInfo, force)
+		if processInfo.Mode == iperf.ModeServer {
+			delete(m.servers, processInfo.Port)
+		}
+		delete(m.processes, testID)
+		count++
+	}
+	if force {
+		for processInfo := range m.stopping {
+			if owner == "" || processInfo.Owner == owner {
+				m.stop(processInfo, true)
+			}
 		}
 	}
-
-	m.processes = make(map[string]*ProcessInfo)
-	m.servers = make(map[int]*ProcessInfo)
 	m.capacity.ReleaseSlots(count)
 
 	return count
 }
 
+// stop cancels a process and tracks it until it exits; with force its
+// iperf3 process is killed rather than asked to exit. The caller must hold
+// m.mu.
+func (m *Manager) stop(processInfo *ProcessInfo, force bool) {
+	if processInfo.Cancel != nil {
+		processInfo.Cancel()
+	}
+	if force && processInfo.Process != nil {
+		_ = processInfo.Process.Kill()
+	}
+	// A server without a process has nothing to wait for
+	if processInfo.Mode == iperf.ModeServer && processInfo.Cmd == nil {
+		return
+	}
+	processInfo.Stopping = true
+	m.stopping[processInfo] = true
+}
+
+// exited forgets a process that has exited. A process that was stopped was
+// already removed and its slot released.
+func (m *Manager) exited(processInfo *ProcessInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.stopping, processInfo)
+	if m.processes[processInfo.TestID] != processInfo {
+		return
+	}
+	if processInfo.Mode == iperf.ModeServer {
+		delete(m.servers, processInfo.Port)
+	}
+	delete(m.processes, processInfo.TestID)
+	m.capacity.ReleaseSlots(1)
+}
+
 // GetProcessInfo returns information about a process
 func (m *Manager) GetProcessInfo(testID string) (*ProcessInfo, error) {
 	m.mu.RLock()
@@ -245,12 +350,55 @@ func (m *Manager) GetProcessInfo(testID string) (*ProcessInfo, error) {
 	return processInfo, nil
 }
 
-// GetRunningCount returns the number of running processes
+// Processes returns a snapshot of the running processes, and of those
+// stopped that have not exited yet, sorted by test ID
+func (m *Manager) Processes() []ProcessInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	processes := make([]ProcessInfo, 0, len(m.processes)+len(m.stopping))
+	for _, processInfo := range m.processes {
+		processes = append(processes, *processInfo)
+	}
+	for processInfo := range m.stopping {
+		processes = append(processes, *processInfo)
+	}
+	sort.Slice(processes, func(i, j int) bool { return processes[i].TestID < processes[j].TestID })
+	return processes
+}
+
+// StopClient stops a running client test. Its result is stored as failed
+// once the client exits.
+func (m *Manager) StopClient(testID string) error {
+	m.mu.RLock()
+	processInfo, exists := m.processes[testID]
+	m.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("test %s is not running", testID)
+	}
+	if processInfo.Mode != iperf.ModeClient {
+		return fmt.Errorf("%s is not a client test", testID)
+	}
+	return m.StopProcess(testID)
+}
+
+// GetRunningCount returns the number of running processes, counting those
+// stopped that have not exited yet
 func (m *Manager) GetRunningCount() int {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return len(m.processes)
+	return len(m.processes) + len(m.stopping)
+}
+
+// GetStoppingCount returns the number of processes stopped that have not
+// exited yet
+func (m *Manager) GetStoppingCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.stopping)
 }
 
 // GetServerCount returns the number of running servers
@@ -270,49 +418,192 @@ func (m *Manager) IsServerRunning(port int) bool {
 	return exists
 }
 
+// ServerPorts returns the ports with a running server, in ascending order
+func (m *Manager) ServerPorts() []int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	ports := make([]int, 0, len(m.servers))
+	for port := range m.servers {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
 // monitorProcess monitors a process and cleans up when it exits
 func (m *Manager) monitorProcess(processInfo *ProcessInfo) {
 	if processInfo.Cmd != nil {
 		_ = processInfo.Cmd.Wait()
 	}
 
-	// Clean up
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if processInfo.Mode == iperf.ModeServer {
-		delete(m.servers, processInfo.Port)
-	}
-	delete(m.processes, processInfo.TestID)
-	m.capacity.ReleaseSlots(1)
+	m.exited(processInfo)
 }
 
 // runClient runs an iperf3 client test
-func (m *Manager) runClient(ctx context.Context, processInfo *ProcessInfo, config *iperf.Config) {
-	result, err := m.iperf.Run(ctx, config)
+func (m *Manager) runClient(ctx context.Context, processInfo *ProcessInfo, config *iperf.Config, policy *capture.Policy) {
+	var result *iperf.Result
+	var err error
+	if err = waitStartDelay(ctx, config.StartDelay); err == nil {
+		var testCapture *capture.Capture
+		if policy != nil {
+			testCapture = m.startCapture(ctx, processInfo.TestID, config)
+		}
+		if config.Burst != nil {
+			result, err = m.runBursts(ctx, config)
+		} else {
+			result, err = m.iperf.Run(ctx, config)
+		}
+		if testCapture != nil {
+			m.finishCapture(processInfo.TestID, testCapture, *policy, result, err)
+		}
+	}
 
 	// Store result in collector
 	if m.collector != nil {
 		if err != nil {
 			// Store error result
-			_ = m.collector.StoreIperfResult(processInfo.TestID, &iperf.Result{
+			_ = m.collector.StoreClientResult(processInfo.TestID, &iperf.Result{
 				Success:    false,
 				Error:      err.Error(),
 				StartTime:  processInfo.StartTime,
 				EndTime:    time.Now(),
 				ExitCode:   -1,
 				JSONOutput: "",
-			})
+			}, config)
 		} else if result != nil {
 			// Store successful result
-			_ = m.collector.StoreIperfResult(processInfo.TestID, result)
+			_ = m.collector.StoreClientResult(processInfo.TestID, result, config)
 		}
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	m.exited(processInfo)
+}
 
-	// Clean up
-	delete(m.processes, processInfo.TestID)
-	m.capacity.ReleaseSlots(1)
+// startCapture starts capturing the packets of a client test into a file
+// under the result directory, returning nil if the capture cannot start
+func (m *Manager) startCapture(ctx context.Context, testID string, config *iperf.Config) *capture.Capture {
+	file, err := m.collector.CreateFile(testID, "pcap")
+	if err != nil {
+		log.Printf("Warning: test %s runs without a packet capture: %v", testID, err)
+		return nil
+	}
+	testCapture, err := m.capturer.Start(ctx, file, config.Host, config.Port)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		log.Printf("Warning: test %s runs without a packet capture: %v", testID, err)
+		return nil
+	}
+	return testCapture
+}
+
+// finishCapture stops the capture of a finished client test, keeping it
+// for the test's result if the policy says so and removing it otherwise
+func (m *Manager) finishCapture(testID string, testCapture *capture.Capture, policy capture.Policy, result *iperf.Result, runErr error) {
+	keep, reason := policy.Keep(result, runErr)
+	if !keep {
+		testCapture.Discard()
+		return
+	}
+
+	path := testCapture.File()
+	truncated, err := testCapture.Stop()
+	if err != nil {
+		log.Printf("Warning: packet capture of test %s failed: %v", testID, err)
+		_ = os.Remove(path)
+		return
+	}
+	if truncated {
+		log.Printf("Packet capture of test %s reached its size limit; later packets were dropped", testID)
+	}
+	log.Printf("Kept packet capture of test %s (%s): %s", testID, reason, path)
+	m.collector.AttachCapture(testID, path)
+}
+
+// waitStartDelay waits out a client's requested start delay
+func waitStartDelay(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cancelled during start delay: %w", ctx.Err())
+	}
+}
+
+// runBursts runs a UDP microburst test as a series of short iperf3 runs.
+// Each burst sends the number of bytes that lasts on_ms at the configured
+// bandwidth; the off_ms gap is measured from the end of the previous run.
+// The combined result holds every burst's JSON output in an iperf.BurstOutput.
+func (m *Manager) runBursts(ctx context.Context, config *iperf.Config) (*iperf.Result, error) {
+	schedule := *config.Burst
+
+	bytes, err := iperf.BurstBytes(config.Bandwidth, schedule.OnMs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid burst configuration: %w", err)
+	}
+
+	burstConfig := *config
+	burstConfig.Burst = nil
+	burstConfig.Duration = 0
+	burstConfig.Bytes = bytes
+
+	output := iperf.BurstOutput{
+		Burst:  schedule,
+		Bursts: make([]json.RawMessage, 0, schedule.Repeat),
+	}
+	result := &iperf.Result{
+		Success:   true,
+		StartTime: time.Now(),
+	}
+	errors := make([]string, 0)
+
+	for i := 0; i < schedule.Repeat; i++ {
+		if i > 0 && schedule.OffMs > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(schedule.OffMs) * time.Millisecond):
+			}
+		}
+		if ctx.Err() != nil {
+			errors = append(errors, fmt.Sprintf("burst %d: %v", i, ctx.Err()))
+			break
+		}
+
+		burstResult, runErr := m.iperf.Run(ctx, &burstConfig)
+		if runErr != nil {
+			errors = append(errors, fmt.Sprintf("burst %d: %v", i, runErr))
+			continue
+		}
+		if !burstResult.Success {
+			result.ExitCode = burstResult.ExitCode
+			errors = append(errors, fmt.Sprintf("burst %d: %s", i, burstResult.Error))
+		}
+		if burstResult.JSONOutput != "" && json.Valid([]byte(burstResult.JSONOutput)) {
+			output.Bursts = append(output.Bursts, json.RawMessage(burstResult.JSONOutput))
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+
+	if len(errors) > 0 {
+		result.Success = false
+		result.Error = strings.Join(errors, "; ")
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode burst results: %w", err)
+	}
+	result.JSONOutput = string(data)
+
+	return result, nil
 }
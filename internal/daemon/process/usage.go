@@ -0,0 +1,79 @@
+package process
+
+import (
+	"fmt"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+// Usage accounts for the process slots and server ports in use
+type Usage struct {
+	MaxSlots  int
+	UsedSlots int // As counted by the capacity calculator
+	Servers   int
+	Clients   int
+	Stopping  int // Stopped processes that have not exited; they hold no slot
+	// UnaccountedSlots are used slots held by no running process, or if
+	// negative, processes running without a slot
+	UnaccountedSlots int
+	ServerPorts      []int // Ports running servers listen on
+	// Divergences are the ways the manager's, capacity calculator's and
+	// port allocator's counts disagree
+	Divergences []string
+}
+
+// Usage returns what the process slots and server ports are used by, and
+// checks that the process table, the capacity calculator and the port
+// allocator agree. Their counts are updated separately, so an error path
+// that misses one of them shows up here.
+func (m *Manager) Usage() Usage {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	usage := Usage{
+		MaxSlots:  m.capacity.GetMaxSlots(),
+		UsedSlots: m.capacity.GetUsedSlots(),
+		Stopping:  len(m.stopping),
+	}
+	for _, processInfo := range m.processes {
+		if processInfo.Mode == iperf.ModeServer {
+			usage.Servers++
+		} else {
+			usage.Clients++
+		}
+	}
+	for port := range m.servers {
+		usage.ServerPorts = append(usage.ServerPorts, port)
+	}
+	usage.UnaccountedSlots = usage.UsedSlots - len(m.processes)
+
+	if usage.UnaccountedSlots > 0 {
+		usage.Divergences = append(usage.Divergences, fmt.Sprintf(
+			"%d slots are used but only %d processes run", usage.UsedSlots, len(m.processes)))
+	} else if usage.UnaccountedSlots < 0 {
+		usage.Divergences = append(usage.Divergences, fmt.Sprintf(
+			"%d processes run but only %d slots are used", len(m.processes), usage.UsedSlots))
+	}
+	if usage.Servers != len(m.servers) {
+		usage.Divergences = append(usage.Divergences, fmt.Sprintf(
+			"%d server processes run but %d server ports are tracked", usage.Servers, len(m.servers)))
+	}
+	for port := range m.servers {
+		if m.portAllocator.IsExcluded(port) {
+			usage.Divergences = append(usage.Divergences, fmt.Sprintf(
+				"a server runs on port %d, which is excluded from allocation", port))
+		}
+	}
+	for _, port := range m.portAllocator.GetAllocatedPorts() {
+		// Claimed ports are held for a run's servers before they start
+		if m.servers[port] != nil || m.portAllocator.IsClaimed(port) {
+			continue
+		}
+		if testID, ok := m.portAllocator.GetTestForPort(port); !ok || m.processes[testID] == nil {
+			usage.Divergences = append(usage.Divergences, fmt.Sprintf(
+				"port %d is allocated but nothing runs on it", port))
+		}
+	}
+
+	return usage
+}
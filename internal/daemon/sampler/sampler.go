@@ -0,0 +1,146 @@
+// Package sampler records the host's CPU, memory and per-NIC byte counters
+// at a fixed interval while tests run, so that host-side bottlenecks can be
+// told apart from network ones. Each sample is a few bulk reads of /proc.
+package sampler
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+	psnet "github.com/shirou/gopsutil/v3/net"
+
+	"github.com/bensons/iperf-cnc/internal/daemon/collector"
+)
+
+// MinInterval is the shortest sampling interval accepted
+const MinInterval = 100 * time.Millisecond
+
+// Sampler samples resource usage into a collector
+type Sampler struct {
+	collector *collector.Collector
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a sampler storing samples in c
+func New(c *collector.Collector) *Sampler {
+	return &Sampler{collector: c}
+}
+
+// Start samples every interval, at least MinInterval, until Stop. Sampling
+// already running is stopped and the samples it stored are dropped.
+func (s *Sampler) Start(interval time.Duration) {
+	s.Stop()
+	s.collector.ClearSamples()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.done = done
+	s.mu.Unlock()
+
+	go s.run(ctx, max(interval, MinInterval), done)
+}
+
+// Stop ends sampling, keeping the samples stored so far
+func (s *Sampler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// Running reports whether sampling is in progress
+func (s *Sampler) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cancel != nil
+}
+
+// run takes a sample every interval until ctx is cancelled
+func (s *Sampler) run(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// CPU usage is the change in CPU times since the previous sample
+	prev, err := cpuTimes(ctx)
+	if err != nil {
+		log.Printf("Warning: resource sampling disabled: %v", err)
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := cpuTimes(ctx)
+		if err != nil {
+			continue
+		}
+		sample := &collector.ResourceSample{
+			Time:       time.Now(),
+			CPUPercent: busyPercent(prev, cur),
+		}
+		prev = cur
+
+		if vm, err := mem.VirtualMemoryWithContext(ctx); err == nil {
+			sample.MemoryUsedBytes = vm.Used
+			sample.MemoryPercent = vm.UsedPercent
+		}
+		if counters, err := psnet.IOCountersWithContext(ctx, true); err == nil {
+			for _, c := range counters {
+				if c.Name == "lo" {
+					continue
+				}
+				sample.NICs = append(sample.NICs, collector.NICCounters{
+					Name:      c.Name,
+					BytesSent: c.BytesSent,
+					BytesRecv: c.BytesRecv,
+				})
+			}
+		}
+		s.collector.AddSample(sample)
+	}
+}
+
+// cpuTimes returns the CPU times summed over all cores
+func cpuTimes(ctx context.Context) (cpu.TimesStat, error) {
+	times, err := cpu.TimesWithContext(ctx, false)
+	if err != nil {
+		return cpu.TimesStat{}, err
+	}
+	if len(times) == 0 {
+		return cpu.TimesStat{}, nil
+	}
+	return times[0], nil
+}
+
+// busyPercent returns the share of CPU time between two readings that was
+// not idle, 0-100
+func busyPercent(prev, cur cpu.TimesStat) float64 {
+	total := cur.Total() - prev.Total()
+	if total <= 0 {
+		return 0
+	}
+	idle := (cur.Idle + cur.Iowait) - (prev.Idle + prev.Iowait)
+	return math.Max(0, math.Min(100, (total-idle)/total*100))
+}
@@ -0,0 +1,61 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+
+	"github.com/bensons/iperf-cnc/internal/daemon/collector"
+)
+
+func TestBusyPercent(t *testing.T) {
+	prev := cpu.TimesStat{User: 10, System: 5, Idle: 80, Iowait: 5}
+	cur := cpu.TimesStat{User: 40, System: 15, Idle: 140, Iowait: 5}
+
+	// 100s elapsed, 60 of them idle
+	if got := busyPercent(prev, cur); got != 40 {
+		t.Errorf("busyPercent() = %v, want 40", got)
+	}
+	if got := busyPercent(cur, cur); got != 0 {
+		t.Errorf("busyPercent() with no elapsed time = %v, want 0", got)
+	}
+}
+
+func TestSampler_StartStop(t *testing.T) {
+	c := collector.NewCollector("")
+	s := New(c)
+
+	s.Start(MinInterval)
+	if !s.Running() {
+		t.Fatal("Running() = false after Start")
+	}
+	time.Sleep(3*MinInterval + MinInterval/2)
+	s.Stop()
+	if s.Running() {
+		t.Error("Running() = true after Stop")
+	}
+
+	samples := c.GetSamples()
+	if len(samples) == 0 {
+		t.Fatal("no samples stored")
+	}
+	for _, sample := range samples {
+		if sample.CPUPercent < 0 || sample.CPUPercent > 100 {
+			t.Errorf("CPUPercent = %v, want 0-100", sample.CPUPercent)
+		}
+	}
+
+	// Stopped sampling stores nothing more
+	time.Sleep(2 * MinInterval)
+	if got := len(c.GetSamples()); got != len(samples) {
+		t.Errorf("%d samples after Stop, want %d", got, len(samples))
+	}
+
+	// Restarting drops the previous samples
+	s.Start(time.Hour)
+	defer s.Stop()
+	if got := len(c.GetSamples()); got != 0 {
+		t.Errorf("%d samples after restart, want 0", got)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"log"
+	"strings"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/daemon/process"
+)
+
+// checkUsage returns what the process slots and server ports are used by,
+// logging any disagreement between the components counting them
+func (s *DaemonServer) checkUsage() process.Usage {
+	usage := s.processManager.Usage()
+	if len(usage.Divergences) > 0 {
+		log.Printf("Warning: capacity accounting diverged: %s", strings.Join(usage.Divergences, "; "))
+	}
+	return usage
+}
+
+// portRange returns the daemon's configured server port range
+func (s *DaemonServer) portRange() *pb.PortRange {
+	return &pb.PortRange{
+		Start: int32(s.config.PortRangeStart), // #nosec G115 -- Ports are validated to be in valid range
+		End:   int32(s.config.PortRangeEnd),   // #nosec G115 -- Ports are validated to be in valid range
+	}
+}
+
+// capacityBreakdown accounts for the daemon's process slots and server ports
+func (s *DaemonServer) capacityBreakdown() *pb.CapacityBreakdown {
+	usage := s.checkUsage()
+
+	inUse := make(map[int]bool)
+	serverPorts := 0
+	for _, port := range usage.ServerPorts {
+		if port >= s.config.PortRangeStart && port <= s.config.PortRangeEnd && !s.portAllocator.IsExcluded(port) {
+			inUse[port] = true
+			serverPorts++
+		}
+	}
+	for _, port := range s.portAllocator.GetAllocatedPorts() {
+		inUse[port] = true
+	}
+	reservedPorts := 0
+	for _, reservation := range s.portAllocator.GetReservations() {
+		reservedPorts += reservation.Size()
+	}
+	totalPorts := s.portAllocator.GetCapacity()
+
+	return &pb.CapacityBreakdown{
+		MaxSlots:          int32(usage.MaxSlots),                      // #nosec G115 -- Process count is reasonable
+		UsedSlots:         int32(usage.UsedSlots),                     // #nosec G115 -- Process count is reasonable
+		RunningServers:    int32(usage.Servers),                       // #nosec G115 -- Process count is reasonable
+		RunningClients:    int32(usage.Clients),                       // #nosec G115 -- Process count is reasonable
+		UnaccountedSlots:  int32(usage.UnaccountedSlots),              // #nosec G115 -- Process count is reasonable
+		StoppingProcesses: int32(usage.Stopping),                      // #nosec G115 -- Process count is reasonable
+		TotalPorts:        int32(totalPorts),                          // #nosec G115 -- Port count is at most 65535
+		ServerPorts:       int32(serverPorts),                         // #nosec G115 -- Port count is at most 65535
+		AllocatedPorts:    int32(s.portAllocator.GetAllocatedCount()), // #nosec G115 -- Port count is at most 65535
+		FreePorts:         int32(totalPorts - len(inUse)),             // #nosec G115 -- Port count is at most 65535
+		ReservedPorts:     int32(reservedPorts),                       // #nosec G115 -- Port count is at most 65535
+		Divergences:       usage.Divergences,
+	}
+}
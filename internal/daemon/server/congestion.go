@@ -0,0 +1,79 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/daemon/facts"
+)
+
+// congestionControlWarn is the PrepareTestRequest policy that reports
+// unavailable congestion control algorithms instead of refusing the topology
+const congestionControlWarn = "warn"
+
+// checkCongestionControl returns the congestion control algorithms the
+// topology requests that the host does not have, and the ones it has. iperf3
+// falls back to the default algorithm without failing, so such tests would
+// silently measure something else. Clients need the algorithm to send, and
+// servers too for reverse and bidirectional tests. Nothing is checked when
+// the host does not report its algorithms or the daemon is simulated.
+func (s *DaemonServer) checkCongestionControl(topology *pb.TestTopology) (unavailable, available []string) {
+	if s.config.Simulate != nil {
+		return nil, nil
+	}
+	available = facts.AvailableCongestionControl()
+	if len(available) == 0 {
+		return nil, nil
+	}
+
+	loaded := make(map[string]bool, len(available))
+	for _, algorithm := range available {
+		loaded[algorithm] = true
+	}
+	missing := make(map[string]bool)
+	check := func(profile *pb.TestProfile, sends bool) {
+		if profile == nil || !sends || profile.Protocol == pb.Protocol_PROTOCOL_UDP {
+			return
+		}
+		if algorithm := profile.CongestionControl; algorithm != "" && !loaded[algorithm] {
+			missing[algorithm] = true
+		}
+	}
+	for _, assignment := range topology.ClientAssignments {
+		check(assignment.Profile, true)
+	}
+	for _, assignment := range topology.ServerAssignments {
+		profile := assignment.Profile
+		check(profile, profile != nil && (profile.Reverse || profile.Bidirectional))
+	}
+
+	for algorithm := range missing {
+		unavailable = append(unavailable, algorithm)
+	}
+	sort.Strings(unavailable)
+	return unavailable, available
+}
+
+// congestionControlMessage describes unavailable algorithms
+func congestionControlMessage(unavailable, available []string) string {
+	return fmt.Sprintf("congestion control %s not available (available: %s)",
+		strings.Join(unavailable, ", "), strings.Join(available, " "))
+}
+
+// applyCongestionControlPolicy refuses the topology, or only logs, when it
+// requests unavailable algorithms. It returns the message to report, or ""
+// when the topology may run.
+func applyCongestionControlPolicy(policy string, unavailable, available []string) string {
+	if len(unavailable) == 0 {
+		return ""
+	}
+	message := congestionControlMessage(unavailable, available)
+	if policy == congestionControlWarn {
+		log.Printf("Warning: %s; tests will use the default algorithm", message)
+		return ""
+	}
+	return message
+}
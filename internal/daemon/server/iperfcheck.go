@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+const (
+	// iperfCheckInterval is how long GetStatus reuses an iperf3 check, as
+	// controllers poll status several times a second during a run
+	iperfCheckInterval = 10 * time.Second
+	// iperfCheckTimeout bounds iperf3 --version
+	iperfCheckTimeout = 5 * time.Second
+)
+
+// iperfCheckState caches whether iperf3 can be run
+type iperfCheckState struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+// iperfError returns why iperf3 cannot be run, or "" if it can. A cached
+// result is reused unless fresh is set. Simulated daemons need no iperf3.
+func (s *DaemonServer) iperfError(ctx context.Context, fresh bool) string {
+	if s.config.Simulate != nil {
+		return ""
+	}
+
+	c := &s.iperfCheck
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fresh || time.Since(c.checkedAt) >= iperfCheckInterval {
+		ctx, cancel := context.WithTimeout(ctx, iperfCheckTimeout)
+		defer cancel()
+		err := iperf.Check(ctx, s.config.IperfPath)
+		if err != nil && (c.err == nil || c.checkedAt.IsZero()) {
+			log.Printf("Warning: iperf3 cannot be run: %v", err)
+		}
+		c.err = err
+		c.checkedAt = time.Now()
+	}
+	if c.err != nil {
+		return c.err.Error()
+	}
+	return ""
+}
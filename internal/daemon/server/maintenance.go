@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// maintenanceState records whether the daemon refuses new tests
+type maintenanceState struct {
+	mu      sync.RWMutex
+	enabled bool
+	since   time.Time
+	reason  string
+}
+
+// SetMaintenance enables or disables maintenance mode. While enabled,
+// PrepareTest, StartServers and StartClients refuse new work; running tests
+// finish and results, status and StopAll keep working.
+func (s *DaemonServer) SetMaintenance(ctx context.Context, req *pb.SetMaintenanceRequest) (*pb.SetMaintenanceResponse, error) {
+	m := &s.maintenance
+	m.mu.Lock()
+	if req.Enabled {
+		if !m.enabled {
+			m.since = time.Now()
+		}
+		m.enabled = true
+		m.reason = req.Reason
+		log.Printf("Maintenance mode enabled: %s", m.describeLocked())
+	} else {
+		if m.enabled {
+			log.Printf("Maintenance mode disabled")
+		}
+		m.enabled = false
+		m.since = time.Time{}
+		m.reason = ""
+	}
+	since := m.since
+	m.mu.Unlock()
+
+	resp := &pb.SetMaintenanceResponse{
+		Enabled:          req.Enabled,
+		RunningProcesses: int32(s.processManager.GetRunningCount()), // #nosec G115 -- Process count is reasonable
+	}
+	if !since.IsZero() {
+		resp.SinceUnix = since.Unix()
+	}
+	return resp, nil
+}
+
+// maintenanceMessage returns why new work is refused, or "" when the daemon
+// is not in maintenance mode
+func (s *DaemonServer) maintenanceMessage() string {
+	m := &s.maintenance
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.enabled {
+		return ""
+	}
+	return "maintenance: " + m.describeLocked()
+}
+
+// describeLocked describes the maintenance state; the caller holds m.mu
+func (m *maintenanceState) describeLocked() string {
+	description := fmt.Sprintf("daemon is in maintenance mode since %s", m.since.UTC().Format(time.RFC3339))
+	if m.reason != "" {
+		description += " (" + m.reason + ")"
+	}
+	return description
+}
+
+// setMaintenanceStatus copies the maintenance state into a status report
+func (s *DaemonServer) setMaintenanceStatus(status *pb.DaemonStatus) {
+	m := &s.maintenance
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status.Maintenance = m.enabled
+	status.MaintenanceReason = m.reason
+	if m.enabled {
+		status.MaintenanceSinceUnix = m.since.Unix()
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/daemon/simulate"
+)
+
+// newTestServer returns a daemon server running simulated iperf3 processes
+func newTestServer(t *testing.T) *DaemonServer {
+	t.Helper()
+
+	s, err := NewDaemonServer(&Config{
+		PortRangeStart: 5201,
+		PortRangeEnd:   5300,
+		MaxProcesses:   10,
+		ResultDir:      t.TempDir(),
+		Version:        "test",
+		Simulate:       &simulate.Config{ThroughputMeanBps: 1e9, Seed: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewDaemonServer() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = s.StopAll(context.Background(), &pb.StopAllRequest{Force: true})
+	})
+	return s
+}
+
+func TestDaemonServer_MaintenanceRefusesNewWork(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	if _, err := s.SetMaintenance(ctx, &pb.SetMaintenanceRequest{Enabled: true, Reason: "kernel upgrade"}); err != nil {
+		t.Fatalf("SetMaintenance() error = %v", err)
+	}
+
+	prepare, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{Topology: &pb.TestTopology{}})
+	if err != nil {
+		t.Fatalf("PrepareTest() error = %v", err)
+	}
+	if prepare.CanHandle || !strings.Contains(prepare.Message, "kernel upgrade") {
+		t.Errorf("PrepareTest() = %t %q, want refused for maintenance", prepare.CanHandle, prepare.Message)
+	}
+
+	servers, err := s.StartServers(ctx, &pb.StartServersRequest{Ports: []int32{5201}})
+	if err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if servers.Success || len(servers.StartedPorts) != 0 || !strings.HasPrefix(servers.Message, "maintenance:") {
+		t.Errorf("StartServers() = %+v, want refused for maintenance", servers)
+	}
+
+	clients, err := s.StartClients(ctx, &pb.StartClientsRequest{
+		Targets: []*pb.ClientTarget{{TestId: "test-1", DestinationIp: "127.0.0.1", DestinationPort: 5201}},
+	})
+	if err != nil {
+		t.Fatalf("StartClients() error = %v", err)
+	}
+	if clients.Success || !strings.HasPrefix(clients.Message, "maintenance:") {
+		t.Errorf("StartClients() = %+v, want refused for maintenance", clients)
+	}
+	if count := s.processManager.GetRunningCount(); count != 0 {
+		t.Errorf("%d processes running, want none started in maintenance mode", count)
+	}
+
+	// Disabling maintenance mode accepts work again
+	if _, err := s.SetMaintenance(ctx, &pb.SetMaintenanceRequest{}); err != nil {
+		t.Fatalf("SetMaintenance() error = %v", err)
+	}
+	servers, err = s.StartServers(ctx, &pb.StartServersRequest{Ports: []int32{5201}})
+	if err != nil {
+		t.Fatalf("StartServers() error = %v", err)
+	}
+	if !servers.Success {
+		t.Errorf("StartServers() after maintenance = %+v, want success", servers)
+	}
+}
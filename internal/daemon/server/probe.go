@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// Bounds of ProbeConnectivity requests, which keep a probe from tying up
+// the daemon however large the mesh
+const (
+	defaultProbeTimeout    = 2 * time.Second
+	maxProbeTimeout        = 10 * time.Second
+	defaultProbeConcurrent = 16
+	maxProbeConcurrent     = 64
+	maxProbeBudget         = 60 * time.Second
+)
+
+// ProbeConnectivity attempts a TCP connection to each target, at most
+// max_concurrent at once, and closes it without sending data. A refused
+// connection still shows the node is reachable. Targets not attempted
+// within the budget are reported as not probed.
+func (s *DaemonServer) ProbeConnectivity(ctx context.Context, req *pb.ProbeConnectivityRequest) (*pb.ProbeConnectivityResponse, error) {
+	timeout := probeLimit(req.TimeoutMs, defaultProbeTimeout, maxProbeTimeout)
+	budget := probeLimit(req.BudgetMs, maxProbeBudget, maxProbeBudget)
+	concurrent := int(req.MaxConcurrent)
+	if concurrent <= 0 {
+		concurrent = defaultProbeConcurrent
+	}
+	concurrent = min(concurrent, maxProbeConcurrent)
+
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	results := make([]*pb.ProbeResult, len(req.Targets))
+	slots := make(chan struct{}, concurrent)
+	var wg sync.WaitGroup
+	for i, target := range req.Targets {
+		results[i] = &pb.ProbeResult{Target: target}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		wg.Add(1)
+		go func(result *pb.ProbeResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			probeTarget(ctx, result, timeout)
+		}(results[i])
+	}
+	wg.Wait()
+
+	return &pb.ProbeConnectivityResponse{Results: results}, nil
+}
+
+// probeTarget attempts a TCP connection to the result's target, recording
+// whether and how quickly the node answered
+func probeTarget(ctx context.Context, result *pb.ProbeResult, timeout time.Duration) {
+	if ctx.Err() != nil {
+		return
+	}
+	result.Probed = true
+
+	address := net.JoinHostPort(result.Target.Address, strconv.Itoa(int(result.Target.Port)))
+	dialer := net.Dialer{Timeout: timeout}
+	start := time.Now()
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	elapsed := time.Since(start)
+	switch {
+	case err == nil:
+		_ = conn.Close()
+		result.Reachable = true
+	case errors.Is(err, syscall.ECONNREFUSED):
+		result.Reachable = true
+		result.Refused = true
+	case ctx.Err() != nil:
+		result.Probed = false // The budget ran out, which says nothing of the target
+		return
+	default:
+		result.Error = err.Error()
+		return
+	}
+	result.LatencyUs = elapsed.Microseconds()
+}
+
+// probeLimit returns ms as a duration, or def if it is not positive, capped
+// at limit
+func probeLimit(ms int32, def, limit time.Duration) time.Duration {
+	d := time.Duration(ms) * time.Millisecond
+	if d <= 0 {
+		d = def
+	}
+	return min(d, limit)
+}
@@ -2,15 +2,25 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"math"
 	"os"
+	"sort"
 	"time"
 
 	pb "github.com/bensons/iperf-cnc/api/proto"
 	"github.com/bensons/iperf-cnc/internal/common/iperf"
+	"github.com/bensons/iperf-cnc/internal/common/models"
+	"github.com/bensons/iperf-cnc/internal/daemon/capture"
 	"github.com/bensons/iperf-cnc/internal/daemon/collector"
+	"github.com/bensons/iperf-cnc/internal/daemon/facts"
 	"github.com/bensons/iperf-cnc/internal/daemon/port"
 	"github.com/bensons/iperf-cnc/internal/daemon/process"
+	"github.com/bensons/iperf-cnc/internal/daemon/sampler"
+	"github.com/bensons/iperf-cnc/internal/daemon/simulate"
 )
 
 // DaemonServer implements the DaemonService gRPC service
@@ -21,6 +31,7 @@ type DaemonServer struct {
 	processManager *process.Manager
 	capacity       *process.CapacityCalculator
 	collector      *collector.Collector
+	sampler        *sampler.Sampler
 
 	// Daemon metadata
 	hostname  string
@@ -28,8 +39,10 @@ type DaemonServer struct {
 	startTime time.Time
 
 	// Configuration
-	config      *Config
-	saveResults bool // Whether to save results to timestamped files
+	config *Config
+
+	maintenance maintenanceState
+	iperfCheck  iperfCheckState
 }
 
 // Config contains daemon server configuration
@@ -41,7 +54,25 @@ type Config struct {
 	CPUAffinity    bool
 	LogLevel       string
 	ResultDir      string
-	IperfPath      string
+	// ExcludedPorts are other ports the daemon listens on, such as the debug
+	// listener's; like ListenPort they are never given to iperf3 servers
+	ExcludedPorts []int
+	IperfPath     string
+	Version       string
+	// PortStrategy is how free ports are chosen, avoiding those released
+	// less than PortCooldown ago unless it is sequential
+	PortStrategy port.Strategy
+	PortCooldown time.Duration
+	// StopTimeout is how long a stopped iperf3 process has to exit after
+	// SIGTERM before it is killed; 0 waits for it indefinitely
+	StopTimeout time.Duration
+	// ResultLimits caps the results held until a controller retrieves them
+	ResultLimits collector.Limits
+	// Simulate, when set, replaces iperf3 with synthetic results
+	Simulate *simulate.Config
+	// Capture, when set, enables packet captures of the client tests whose
+	// profile asks for one
+	Capture *capture.Config
 }
 
 // NewDaemonServer creates a new daemon gRPC server
@@ -55,12 +86,18 @@ func NewDaemonServer(config *Config) (*DaemonServer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create port allocator: %w", err)
 	}
+	portAllocator.Exclude(config.ListenPort)
+	portAllocator.Exclude(config.ExcludedPorts...)
+	if config.PortStrategy != "" {
+		portAllocator.SetStrategy(config.PortStrategy, config.PortCooldown)
+	}
 
 	// Create capacity calculator
 	capacityCalc := process.NewCapacityCalculator(config.MaxProcesses)
 
 	// Create result collector
 	resultCollector := collector.NewCollector(config.ResultDir)
+	resultCollector.SetLimits(config.ResultLimits)
 
 	// Create process manager
 	iperfPath := config.IperfPath
@@ -68,6 +105,19 @@ func NewDaemonServer(config *Config) (*DaemonServer, error) {
 		iperfPath = "iperf3"
 	}
 	processManager := process.NewManager(portAllocator, capacityCalc, resultCollector, iperfPath)
+	if config.Simulate != nil {
+		processManager.SetRunner(simulate.New(*config.Simulate))
+		if config.Capture != nil {
+			log.Printf("Warning: packet captures are disabled in simulate mode, which sends no traffic")
+		}
+	} else {
+		wrapper := iperf.NewWrapper(iperfPath)
+		wrapper.SetStopTimeout(config.StopTimeout)
+		processManager.SetRunner(wrapper)
+		if config.Capture != nil {
+			processManager.SetCapturer(capture.New(*config.Capture))
+		}
+	}
 
 	// Get hostname
 	hostname, err := os.Hostname()
@@ -75,13 +125,19 @@ func NewDaemonServer(config *Config) (*DaemonServer, error) {
 		hostname = "unknown"
 	}
 
+	version := config.Version
+	if version == "" {
+		version = "dev"
+	}
+
 	return &DaemonServer{
 		portAllocator:  portAllocator,
 		processManager: processManager,
 		capacity:       capacityCalc,
 		collector:      resultCollector,
+		sampler:        sampler.New(resultCollector),
 		hostname:       hostname,
-		version:        "dev",
+		version:        version,
 		startTime:      time.Now(),
 		config:         config,
 	}, nil
@@ -94,13 +150,17 @@ func (s *DaemonServer) Initialize(ctx context.Context, req *pb.InitializeRequest
 		s.config.MaxProcesses = int(req.MaxProcesses)
 	}
 
-	// Update save results flag
-	s.saveResults = req.SaveResults
+	// Results are still read from iperf3's stdout; iperf3 --logfile made
+	// tests fail, so copies are written from the collected output instead
+	s.collector.SetSaveResults(req.SaveResults)
 
-	// Note: save_daemon_results flag is no longer used for iperf3 --logfile
-	// because it caused test failures. The daemon always collects results
-	// from iperf3 stdout and returns them to the controller.
-	// If you need to save results, use save_raw_results on the controller side.
+	// Tests started from now on are counted for this run, apart from the
+	// results of earlier runs still held
+	runID := req.RunId
+	if runID == "" {
+		runID = models.NewRunID()
+	}
+	s.collector.StartRun(runID)
 
 	// Detect capacity
 	capacity, err := s.capacity.DetectCapacity()
@@ -110,15 +170,27 @@ func (s *DaemonServer) Initialize(ctx context.Context, req *pb.InitializeRequest
 			Message: fmt.Sprintf("failed to detect capacity: %v", err),
 		}, nil
 	}
+	iperfErr := s.iperfError(ctx, true)
+
+	// Sampling covers the run this Initialize starts and ends with StopAll
+	if req.SampleIntervalMs > 0 {
+		s.sampler.Start(time.Duration(req.SampleIntervalMs) * time.Millisecond)
+	}
 
 	return &pb.InitializeResponse{
 		Success: true,
 		Message: "daemon initialized successfully",
+		RunId:   runID,
 		NodeInfo: &pb.NodeInfo{
-			Id:       s.hostname,
-			Hostname: s.hostname,
-			Ip:       "",                         // Will be filled by controller
-			Port:     int32(s.config.ListenPort), // #nosec G115 -- Port is validated to be in valid range
+			Id:        s.hostname,
+			Hostname:  s.hostname,
+			Ip:        "",                         // Will be filled by controller
+			Port:      int32(s.config.ListenPort), // #nosec G115 -- Port is validated to be in valid range
+			Version:   s.version,
+			Simulated: s.config.Simulate != nil,
+			// Reported rather than failed, so the controller can name the node
+			IperfAvailable: iperfErr == "",
+			IperfError:     iperfErr,
 			Capacity: &pb.ProcessCapacity{
 				MaxProcesses:         int32(capacity.MaxProcesses),       // #nosec G115 -- Process count is reasonable
 				AvailableProcesses:   int32(capacity.AvailableProcesses), // #nosec G115 -- Process count is reasonable
@@ -126,32 +198,97 @@ func (s *DaemonServer) Initialize(ctx context.Context, req *pb.InitializeRequest
 				AvailableMemoryBytes: int64(capacity.AvailableMemory),    // #nosec G115 -- Safe conversion to int64
 				NetworkInterfaces:    capacity.NetworkInterfaces,
 			},
+			PortRange:      s.portRange(),
+			TotalPorts:     int32(s.portAllocator.GetCapacity()),       // #nosec G115 -- Port count is at most 65535
+			AvailablePorts: int32(s.portAllocator.GetAvailableCount()), // #nosec G115 -- Port count is at most 65535
 		},
 	}, nil
 }
 
-// PrepareTest validates if the daemon can handle the test topology
+// PrepareTest validates if the daemon can handle the test topology. A run
+// that is refused gives up the port range it reserved and the ports it
+// claimed, so it does not block runs of other owners.
 func (s *DaemonServer) PrepareTest(ctx context.Context, req *pb.PrepareTestRequest) (*pb.PrepareTestResponse, error) {
+	resp, err := s.prepareTest(req)
+	if err == nil && !resp.CanHandle && req.Owner != "" {
+		s.portAllocator.ReleaseClaim(req.Owner)
+		s.portAllocator.ReleaseReservation(req.Owner)
+	}
+	return resp, err
+}
+
+// prepareTest checks the topology against the daemon's capacity and
+// reserves and claims the run's ports
+func (s *DaemonServer) prepareTest(req *pb.PrepareTestRequest) (*pb.PrepareTestResponse, error) {
+	if message := s.maintenanceMessage(); message != "" {
+		return &pb.PrepareTestResponse{
+			CanHandle: false,
+			Message:   message,
+		}, nil
+	}
 	if req.Topology == nil {
 		return &pb.PrepareTestResponse{
 			CanHandle: false,
 			Message:   "topology is required",
 		}, nil
 	}
+	// Ports claimed without an owner could be released by any other run
+	if req.AllocatePorts && req.Owner == "" {
+		return &pb.PrepareTestResponse{
+			CanHandle: false,
+			Message:   "allocating ports requires an owner",
+		}, nil
+	}
+	// A repeated request of the run replaces the ports it was given
+	if req.AllocatePorts {
+		s.portAllocator.ReleaseClaim(req.Owner)
+	}
 
-	// Calculate required capacity
-	serverCount := len(req.Topology.ServerAssignments)
-	clientCount := len(req.Topology.ClientAssignments)
-	totalRequired := serverCount + clientCount
+	// Calculate required capacity; multi-process pairs need one slot (and,
+	// on the server side, one port) per iperf3 instance, and bidirectional
+	// ones two slots, as they send and receive at once
+	serverCount := 0
+	totalRequired := 0
+	for _, assignment := range req.Topology.ServerAssignments {
+		serverCount += processesPerPair(assignment.Profile)
+		totalRequired += slotsPerPair(assignment.Profile)
+	}
+	for _, assignment := range req.Topology.ClientAssignments {
+		totalRequired += slotsPerPair(assignment.Profile)
+	}
 
-	// Check if we have enough slots
+	// Check if we have enough slots and server ports
 	availableSlots := s.capacity.GetAvailableSlots()
-	canHandle := availableSlots >= totalRequired
+	availablePorts := s.portAllocator.GetAvailableCount()
+	canHandle := availableSlots >= totalRequired && availablePorts >= serverCount
 
 	message := "sufficient capacity available"
-	if !canHandle {
-		message = fmt.Sprintf("insufficient capacity: need %d slots, have %d available",
+	if availableSlots < totalRequired {
+		message = fmt.Sprintf("insufficient capacity: need %d slots (bidirectional tests count twice), have %d available",
 			totalRequired, availableSlots)
+	} else if availablePorts < serverCount {
+		message = fmt.Sprintf("insufficient ports: need %d server ports, have %d available in range %d-%d",
+			serverCount, availablePorts, s.config.PortRangeStart, s.config.PortRangeEnd)
+	}
+
+	unavailableCC, availableCC := s.checkCongestionControl(req.Topology)
+	if canHandle {
+		if ccMessage := applyCongestionControlPolicy(req.CongestionControlPolicy, unavailableCC, availableCC); ccMessage != "" {
+			canHandle = false
+			message = ccMessage
+		}
+	}
+
+	// Claim the run's port sub-range so concurrent runs fail fast here
+	// rather than with bind errors when servers start
+	if canHandle && req.PortRange != nil {
+		if err := s.portAllocator.Reserve(req.Owner, int(req.PortRange.Start), int(req.PortRange.End), serverCount); err != nil {
+			canHandle = false
+			message = err.Error()
+		} else {
+			message = fmt.Sprintf("sufficient capacity available; ports %d-%d reserved for %s",
+				req.PortRange.Start, req.PortRange.End, req.Owner)
+		}
 	}
 
 	// Detect current capacity
@@ -163,6 +300,24 @@ func (s *DaemonServer) PrepareTest(ctx context.Context, req *pb.PrepareTestReque
 		}, nil
 	}
 
+	// Hand out the servers' ports, so the controller connects clients to
+	// ports this daemon allocated rather than ones it assumed are free
+	var allocatedPorts []int32
+	if canHandle && req.AllocatePorts {
+		start, end := s.config.PortRangeStart, s.config.PortRangeEnd
+		if req.PortRange != nil {
+			start, end = int(req.PortRange.Start), int(req.PortRange.End)
+		}
+		ports, err := s.portAllocator.Claim(req.Owner, start, end, serverCount)
+		if err != nil {
+			canHandle = false
+			message = err.Error()
+		}
+		for _, port := range ports {
+			allocatedPorts = append(allocatedPorts, int32(port)) // #nosec G115 -- Ports are validated to be in valid range
+		}
+	}
+
 	return &pb.PrepareTestResponse{
 		CanHandle: canHandle,
 		Message:   message,
@@ -177,11 +332,25 @@ func (s *DaemonServer) PrepareTest(ctx context.Context, req *pb.PrepareTestReque
 			AvailableMemoryBytes: int64(capacity.AvailableMemory),    // #nosec G115 -- Safe conversion to int64
 			NetworkInterfaces:    capacity.NetworkInterfaces,
 		},
+		PortRange:      s.portRange(),
+		TotalPorts:     int32(s.portAllocator.GetCapacity()), // #nosec G115 -- Port count is at most 65535
+		AvailablePorts: int32(availablePorts),                // #nosec G115 -- Port count is at most 65535
+
+		UnavailableCongestionControl: unavailableCC,
+		AvailableCongestionControl:   availableCC,
+		Breakdown:                    s.capacityBreakdown(),
+		AllocatedPorts:               allocatedPorts,
 	}, nil
 }
 
 // StartServers starts iperf3 servers on allocated ports
 func (s *DaemonServer) StartServers(ctx context.Context, req *pb.StartServersRequest) (*pb.StartServersResponse, error) {
+	if message := s.maintenanceMessage(); message != "" {
+		return &pb.StartServersResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
 	if len(req.Ports) == 0 {
 		return &pb.StartServersResponse{
 			Success: false,
@@ -193,7 +362,11 @@ func (s *DaemonServer) StartServers(ctx context.Context, req *pb.StartServersReq
 	errors := make([]string, 0)
 
 	for _, port := range req.Ports {
-		if err := s.processManager.StartServer(int(port)); err != nil {
+		if s.portAllocator.IsExcluded(int(port)) {
+			errors = append(errors, fmt.Sprintf("port %d: reserved for the daemon's own listeners", port))
+			continue
+		}
+		if err := s.processManager.StartServer(int(port), req.BindIps[port], req.Owner); err != nil {
 			errors = append(errors, fmt.Sprintf("port %d: %v", port, err))
 		} else {
 			startedPorts = append(startedPorts, port)
@@ -213,6 +386,12 @@ func (s *DaemonServer) StartServers(ctx context.Context, req *pb.StartServersReq
 
 // StartClients starts iperf3 clients to connect to targets
 func (s *DaemonServer) StartClients(ctx context.Context, req *pb.StartClientsRequest) (*pb.StartClientsResponse, error) {
+	if message := s.maintenanceMessage(); message != "" {
+		return &pb.StartClientsResponse{
+			Success: false,
+			Message: message,
+		}, nil
+	}
 	if len(req.Targets) == 0 {
 		return &pb.StartClientsResponse{
 			Success: false,
@@ -223,25 +402,40 @@ func (s *DaemonServer) StartClients(ctx context.Context, req *pb.StartClientsReq
 	startedTestIDs := make([]string, 0)
 	errors := make([]string, 0)
 
+	requested := 0
 	for _, target := range req.Targets {
-		config := convertProfileToIperfConfig(target.Profile)
+		processes := processesPerPair(target.Profile)
+		requested += processes
+
+		// Single-process tests keep their test ID; multi-process tests run
+		// one client per destination port with sub-test IDs (testid#0..n)
+		if processes == 1 {
+			if err := s.startClient(target.TestId, req.Owner, target, int(target.DestinationPort)); err != nil {
+				errors = append(errors, err.Error())
+			} else {
+				startedTestIDs = append(startedTestIDs, target.TestId)
+			}
+			continue
+		}
 
-		err := s.processManager.StartClient(
-			target.TestId,
-			target.DestinationIp,
-			int(target.DestinationPort),
-			config,
-		)
+		if len(target.DestinationPorts) < processes {
+			errors = append(errors, fmt.Sprintf("test %s: %d processes requested but only %d destination ports given",
+				target.TestId, processes, len(target.DestinationPorts)))
+			continue
+		}
 
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("test %s: %v", target.TestId, err))
-		} else {
-			startedTestIDs = append(startedTestIDs, target.TestId)
+		for i := 0; i < processes; i++ {
+			subTestID := models.SubTestID(target.TestId, i)
+			if err := s.startClient(subTestID, req.Owner, target, int(target.DestinationPorts[i])); err != nil {
+				errors = append(errors, err.Error())
+			} else {
+				startedTestIDs = append(startedTestIDs, subTestID)
+			}
 		}
 	}
 
 	success := len(startedTestIDs) > 0
-	message := fmt.Sprintf("started %d/%d clients", len(startedTestIDs), len(req.Targets))
+	message := fmt.Sprintf("started %d/%d clients", len(startedTestIDs), requested)
 
 	return &pb.StartClientsResponse{
 		Success:        success,
@@ -251,9 +445,52 @@ func (s *DaemonServer) StartClients(ctx context.Context, req *pb.StartClientsReq
 	}, nil
 }
 
-// StopAll stops all running iperf3 processes
+// startClient starts a single iperf3 client process for a target of owner's
+// run
+func (s *DaemonServer) startClient(testID, owner string, target *pb.ClientTarget, port int) error {
+	config := convertProfileToIperfConfig(target.Profile)
+
+	// Per-target timing takes precedence over the profile
+	if duration := protoDuration(target.DurationSeconds, target.DurationMs); duration > 0 {
+		config.Duration = duration
+	}
+	if target.StartDelayMs > 0 {
+		config.StartDelay = time.Duration(target.StartDelayMs) * time.Millisecond
+	}
+	config.IPVersion = int(target.IpVersion)
+	config.BindIP = target.BindIp
+
+	var policy *capture.Policy
+	if c := target.GetProfile().GetCapture(); c != nil && (c.OnFailure || c.BelowBps > 0) {
+		policy = &capture.Policy{OnFailure: c.OnFailure, BelowBps: c.BelowBps}
+	}
+
+	s.collector.TagTest(testID)
+	if err := s.processManager.StartClient(testID, owner, target.DestinationIp, port, config, policy); err != nil {
+		return fmt.Errorf("test %s: %w", testID, err)
+	}
+
+	return nil
+}
+
+// StopAll stops the requesting run's iperf3 processes and releases its
+// ports, leaving other runs sharing the daemon running. A request without an
+// owner stops every process and releases every port.
 func (s *DaemonServer) StopAll(ctx context.Context, req *pb.StopAllRequest) (*pb.StopAllResponse, error) {
-	stoppedCount := s.processManager.StopAll()
+	stoppedCount := s.processManager.StopAll(req.Owner, req.Force)
+	if req.Owner == "" {
+		s.portAllocator.ReleaseAllReservations()
+		s.portAllocator.ReleaseAll()
+	} else {
+		s.portAllocator.ReleaseReservation(req.Owner)
+		s.portAllocator.ReleaseClaim(req.Owner)
+	}
+	// Other runs may still be sampled
+	if req.Owner == "" || s.processManager.GetRunningCount() == 0 {
+		s.sampler.Stop()
+	}
+	// Leftover slots and unclaimed ports are leaks
+	s.checkUsage()
 
 	return &pb.StopAllResponse{
 		Success:          true,
@@ -262,6 +499,84 @@ func (s *DaemonServer) StopAll(ctx context.Context, req *pb.StopAllRequest) (*pb
 	}, nil
 }
 
+// StopTests stops the requested client tests, leaving servers and other
+// clients running
+func (s *DaemonServer) StopTests(ctx context.Context, req *pb.StopTestsRequest) (*pb.StopTestsResponse, error) {
+	resp := &pb.StopTestsResponse{}
+	for _, testID := range req.TestIds {
+		if err := s.processManager.StopClient(testID); err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+			continue
+		}
+		resp.StoppedTestIds = append(resp.StoppedTestIds, testID)
+	}
+	return resp, nil
+}
+
+// GetSamples returns the resource usage samples of the latest sampling
+func (s *DaemonServer) GetSamples(ctx context.Context, req *pb.GetSamplesRequest) (*pb.GetSamplesResponse, error) {
+	samples := s.collector.GetSamples()
+	resp := &pb.GetSamplesResponse{Samples: make([]*pb.ResourceSample, 0, len(samples))}
+	for _, sample := range samples {
+		pbSample := &pb.ResourceSample{
+			TimeUnixMs:      sample.Time.UnixMilli(),
+			CpuPercent:      sample.CPUPercent,
+			MemoryUsedBytes: sample.MemoryUsedBytes,
+			MemoryPercent:   sample.MemoryPercent,
+		}
+		for _, nic := range sample.NICs {
+			pbSample.Nics = append(pbSample.Nics, &pb.NICCounters{
+				Name:      nic.Name,
+				BytesSent: nic.BytesSent,
+				BytesRecv: nic.BytesRecv,
+			})
+		}
+		resp.Samples = append(resp.Samples, pbSample)
+	}
+	return resp, nil
+}
+
+// resultHost returns hostname for results with a saved copy or a packet
+// capture, or ""
+func resultHost(result *collector.TestResult, hostname string) string {
+	if result.ResultFile == "" && result.CaptureFile == "" {
+		return ""
+	}
+	return hostname
+}
+
+// fileChunkSize is the size of the chunks DownloadFile streams
+const fileChunkSize = 64 * 1024
+
+// DownloadFile streams a result file saved under the result directory
+func (s *DaemonServer) DownloadFile(req *pb.DownloadFileRequest, stream pb.DaemonService_DownloadFileServer) error {
+	file, err := s.collector.OpenResultFile(req.Path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close %s: %v", req.Path, closeErr)
+		}
+	}()
+
+	buf := make([]byte, fileChunkSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.FileChunk{Data: buf[:n]}); sendErr != nil {
+				return sendErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", req.Path, err)
+		}
+	}
+}
+
 // GetResults retrieves test results from completed runs
 func (s *DaemonServer) GetResults(ctx context.Context, req *pb.GetResultsRequest) (*pb.GetResultsResponse, error) {
 	var results []*collector.TestResult
@@ -283,20 +598,33 @@ func (s *DaemonServer) GetResults(ctx context.Context, req *pb.GetResultsRequest
 		}
 
 		pbResults = append(pbResults, &pb.TestResult{
-			TestId:        result.TestID,
-			SourceId:      result.SourceID,
-			DestinationId: result.DestinationID,
-			Status:        status,
-			IperfJson:     result.IperfJSON,
-			ErrorMessage:  result.ErrorMessage,
-			StartTimeUnix: result.StartTime.Unix(),
-			EndTimeUnix:   result.EndTime.Unix(),
-			ExitCode:      int32(result.ExitCode), // #nosec G115 -- Exit code is in valid range
+			TestId:          result.TestID,
+			SourceId:        result.SourceID,
+			DestinationId:   result.DestinationID,
+			Status:          status,
+			IperfJson:       result.IperfJSON,
+			ErrorMessage:    result.ErrorMessage,
+			StartTimeUnix:   result.StartTime.Unix(),
+			EndTimeUnix:     result.EndTime.Unix(),
+			StartTimeUnixMs: result.StartTime.UnixMilli(),
+			EndTimeUnixMs:   result.EndTime.UnixMilli(),
+			ExitCode:        int32(result.ExitCode),                      // #nosec G115 -- Exit code is in valid range
+			StartDelayMs:    int32(result.StartDelay.Milliseconds()),     // #nosec G115 -- Delay is set from an int32
+			DurationSeconds: int32(math.Ceil(result.Duration.Seconds())), // #nosec G115 -- Duration is set from an int32
+			DurationMs:      int32(result.Duration.Milliseconds()),       // #nosec G115 -- Duration is set from an int32
+
+			DestinationAddress: result.DestinationAddress,
+			LocalAddress:       result.LocalAddress,
+			RemoteAddress:      result.RemoteAddress,
+			CongestionControl:  result.CongestionControl,
+
+			ResultFile:  result.ResultFile,
+			CaptureFile: result.CaptureFile,
+			ResultHost:  resultHost(result, s.hostname),
+			RunId:       result.RunID,
 		})
 	}
-
-	// Note: When save_daemon_results is enabled, iperf3 saves results directly
-	// to files using --logfile option. No need to save here.
+	pbResults = append(pbResults, droppedResults(s.collector.DroppedResults(req.TestIds))...)
 
 	// Clear results if requested
 	if req.ClearAfterRetrieval {
@@ -309,6 +637,26 @@ func (s *DaemonServer) GetResults(ctx context.Context, req *pb.GetResultsRequest
 	}, nil
 }
 
+// droppedResults reports the results the collector's limits dropped as
+// failed tests, so that the controller shows why they are missing
+func droppedResults(dropped map[string]string) []*pb.TestResult {
+	testIDs := make([]string, 0, len(dropped))
+	for testID := range dropped {
+		testIDs = append(testIDs, testID)
+	}
+	sort.Strings(testIDs)
+
+	results := make([]*pb.TestResult, 0, len(testIDs))
+	for _, testID := range testIDs {
+		results = append(results, &pb.TestResult{
+			TestId:       testID,
+			Status:       pb.TestStatus_TEST_STATUS_FAILED,
+			ErrorMessage: dropped[testID],
+		})
+	}
+	return results
+}
+
 // GetStatus returns current daemon health and resource usage
 func (s *DaemonServer) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.GetStatusResponse, error) {
 	capacity, err := s.capacity.DetectCapacity()
@@ -317,13 +665,15 @@ func (s *DaemonServer) GetStatus(ctx context.Context, req *pb.GetStatusRequest)
 	}
 
 	uptime := time.Since(s.startTime).Seconds()
+	storage := s.collector.StorageStats()
 
-	return &pb.GetStatusResponse{
+	status := &pb.GetStatusResponse{
 		Status: &pb.DaemonStatus{
-			Healthy:          true,
-			RunningProcesses: int32(s.processManager.GetRunningCount()), // #nosec G115 -- Process count is reasonable
-			CompletedTests:   int32(s.collector.GetCompletedCount()),    // #nosec G115 -- Test count is reasonable
-			FailedTests:      int32(s.collector.GetFailedCount()),       // #nosec G115 -- Test count is reasonable
+			Healthy:           true,
+			RunningProcesses:  int32(s.processManager.GetRunningCount()),  // #nosec G115 -- Process count is reasonable
+			CompletedTests:    int32(s.collector.GetCompletedCount()),     // #nosec G115 -- Test count is reasonable
+			StoppingProcesses: int32(s.processManager.GetStoppingCount()), // #nosec G115 -- Process count is reasonable
+			FailedTests:       int32(s.collector.GetFailedCount()),        // #nosec G115 -- Test count is reasonable
 			CurrentCapacity: &pb.ProcessCapacity{
 				MaxProcesses:         int32(capacity.MaxProcesses),       // #nosec G115 -- Process count is reasonable
 				AvailableProcesses:   int32(capacity.AvailableProcesses), // #nosec G115 -- Process count is reasonable
@@ -331,12 +681,170 @@ func (s *DaemonServer) GetStatus(ctx context.Context, req *pb.GetStatusRequest)
 				AvailableMemoryBytes: int64(capacity.AvailableMemory),    // #nosec G115 -- Safe conversion to int64
 				NetworkInterfaces:    capacity.NetworkInterfaces,
 			},
-			UptimeSeconds: int64(uptime),
-			Version:       s.version,
+			UptimeSeconds:    int64(uptime),
+			Version:          s.version,
+			PortReservations: s.portReservations(),
+			ServerPorts:      s.serverPorts(),
+			StoredResults:    int32(storage.Results), // #nosec G115 -- Result count is capped
+			Simulated:        s.config.Simulate != nil,
+
+			StoredResultBytes: storage.Bytes,
+			ResultsEvicted:    storage.Evicted,
+			ResultsRejected:   storage.Rejected,
+
+			PortRange:      s.portRange(),
+			TotalPorts:     int32(s.portAllocator.GetCapacity()),       // #nosec G115 -- Port count is at most 65535
+			AvailablePorts: int32(s.portAllocator.GetAvailableCount()), // #nosec G115 -- Port count is at most 65535
 		},
+	}
+	if completed, failed, ok := s.collector.RunCounts(req.RunId); ok && req.RunId != "" {
+		status.Status.RunId = req.RunId
+		status.Status.RunCompletedTests = int32(completed) // #nosec G115 -- Test count is reasonable
+		status.Status.RunFailedTests = int32(failed)       // #nosec G115 -- Test count is reasonable
+	}
+	s.setMaintenanceStatus(status.Status)
+	status.Status.IperfError = s.iperfError(ctx, false)
+	status.Status.IperfAvailable = status.Status.IperfError == ""
+	if req.IncludeProcesses {
+		status.Status.Processes = s.processStatuses()
+	}
+	return status, nil
+}
+
+// processStatuses describes the running iperf3 processes
+func (s *DaemonServer) processStatuses() []*pb.ProcessStatus {
+	processes := s.processManager.Processes()
+	statuses := make([]*pb.ProcessStatus, 0, len(processes))
+	for _, processInfo := range processes {
+		statuses = append(statuses, &pb.ProcessStatus{
+			TestId:          processInfo.TestID,
+			Server:          processInfo.Mode == iperf.ModeServer,
+			Port:            int32(processInfo.Port), // #nosec G115 -- Port is validated to be in valid range
+			StartTimeUnixMs: processInfo.StartTime.UnixMilli(),
+			Pid:             int32(processInfo.PID), // #nosec G115 -- PIDs fit in 32 bits
+			Stopping:        processInfo.Stopping,
+			Owner:           processInfo.Owner,
+		})
+	}
+	return statuses
+}
+
+// serverPorts returns the ports with a running iperf3 server
+func (s *DaemonServer) serverPorts() []int32 {
+	running := s.processManager.ServerPorts()
+	ports := make([]int32, len(running))
+	for i, port := range running {
+		ports[i] = int32(port) // #nosec G115 -- Port is validated to be in valid range
+	}
+	return ports
+}
+
+// DebugStats returns counters of the daemon's internals for diagnostics
+func (s *DaemonServer) DebugStats() map[string]interface{} {
+	storage := s.collector.StorageStats()
+	return map[string]interface{}{
+		"running_processes": s.processManager.GetRunningCount(),
+		"running_servers":   s.processManager.GetServerCount(),
+		"stored_results":    s.collector.GetCount(),
+		"stored_bytes":      storage.Bytes,
+		"results_evicted":   storage.Evicted,
+		"results_rejected":  storage.Rejected,
+		"completed_tests":   s.collector.GetCompletedCount(),
+		"failed_tests":      s.collector.GetFailedCount(),
+		"ports_allocated":   s.portAllocator.GetAllocatedCount(),
+		"ports_capacity":    s.portAllocator.GetCapacity(),
+		"port_reservations": len(s.portAllocator.GetReservations()),
+		"uptime_seconds":    int64(time.Since(s.startTime).Seconds()),
+	}
+}
+
+// portReservations converts the allocator's reservations to protobuf
+func (s *DaemonServer) portReservations() []*pb.PortReservation {
+	reservations := s.portAllocator.GetReservations()
+	result := make([]*pb.PortReservation, 0, len(reservations))
+	for _, r := range reservations {
+		result = append(result, &pb.PortReservation{
+			Owner: r.Owner,
+			Range: &pb.PortRange{
+				Start: int32(r.Start), // #nosec G115 -- Port is validated to be in valid range
+				End:   int32(r.End),   // #nosec G115 -- Port is validated to be in valid range
+			},
+			RequestedPorts: int32(r.Requested), // #nosec G115 -- Port count is reasonable
+		})
+	}
+	return result
+}
+
+// GetCapabilities returns the daemon version and supported features
+func (s *DaemonServer) GetCapabilities(ctx context.Context, req *pb.GetCapabilitiesRequest) (*pb.GetCapabilitiesResponse, error) {
+	features := make([]string, 0)
+	for _, feature := range models.SupportedFeatures() {
+		features = append(features, string(feature))
+	}
+
+	return &pb.GetCapabilitiesResponse{
+		Version:  s.version,
+		Features: features,
 	}, nil
 }
 
+// GatherFacts returns read-only facts about the host environment
+func (s *DaemonServer) GatherFacts(ctx context.Context, req *pb.GatherFactsRequest) (*pb.GatherFactsResponse, error) {
+	nodeFacts := facts.Gather(ctx, s.config.IperfPath)
+
+	interfaces := make([]*pb.InterfaceFacts, 0, len(nodeFacts.Interfaces))
+	for _, iface := range nodeFacts.Interfaces {
+		interfaces = append(interfaces, &pb.InterfaceFacts{
+			Name:      iface.Name,
+			Mtu:       int32(iface.MTU), // #nosec G115 -- MTU is small
+			SpeedMbps: iface.SpeedMbps,
+			Driver:    iface.Driver,
+		})
+	}
+
+	return &pb.GatherFactsResponse{
+		Facts: &pb.NodeFacts{
+			Os:                   nodeFacts.OS,
+			Platform:             nodeFacts.Platform,
+			KernelVersion:        nodeFacts.KernelVersion,
+			Arch:                 nodeFacts.Arch,
+			IperfVersion:         nodeFacts.IperfVersion,
+			DefaultQdisc:         nodeFacts.DefaultQdisc,
+			TcpCongestionControl: nodeFacts.TCPCongestionControl,
+			RmemMax:              nodeFacts.RmemMax,
+			WmemMax:              nodeFacts.WmemMax,
+			Interfaces:           interfaces,
+		},
+	}, nil
+}
+
+// processesPerPair returns the number of iperf3 processes a profile requires
+func processesPerPair(profile *pb.TestProfile) int {
+	if profile == nil || profile.ProcessesPerPair < 1 {
+		return 1
+	}
+	return int(profile.ProcessesPerPair)
+}
+
+// slotsPerPair returns the process slots a profile's pair needs on each of
+// its nodes: one per iperf3 process, two for bidirectional ones
+func slotsPerPair(profile *pb.TestProfile) int {
+	if profile.GetBidirectional() {
+		return 2 * processesPerPair(profile)
+	}
+	return processesPerPair(profile)
+}
+
+// protoDuration returns a duration sent as both whole seconds and
+// milliseconds, preferring milliseconds when set. Older controllers only send
+// seconds.
+func protoDuration(seconds, ms int32) time.Duration {
+	if ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 // convertProfileToIperfConfig converts protobuf TestProfile to iperf.Config
 func convertProfileToIperfConfig(profile *pb.TestProfile) *iperf.Config {
 	if profile == nil {
@@ -349,9 +857,18 @@ func convertProfileToIperfConfig(profile *pb.TestProfile) *iperf.Config {
 		protocol = iperf.ProtocolUDP
 	}
 
+	var burst *iperf.Burst
+	if profile.Burst != nil && profile.Burst.Repeat > 0 {
+		burst = &iperf.Burst{
+			OnMs:   int(profile.Burst.OnMs),
+			OffMs:  int(profile.Burst.OffMs),
+			Repeat: int(profile.Burst.Repeat),
+		}
+	}
+
 	return &iperf.Config{
 		Protocol:          protocol,
-		Duration:          int(profile.DurationSeconds),
+		Duration:          protoDuration(profile.DurationSeconds, profile.DurationMs),
 		Bandwidth:         profile.Bandwidth,
 		WindowSize:        profile.WindowSize,
 		Parallel:          int(profile.ParallelStreams),
@@ -364,5 +881,6 @@ func convertProfileToIperfConfig(profile *pb.TestProfile) *iperf.Config {
 		TOS:               int(profile.Tos),
 		ZeroCopy:          profile.Zerocopy,
 		OmitSeconds:       int(profile.OmitSeconds),
+		Burst:             burst,
 	}
 }
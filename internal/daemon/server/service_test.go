@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+)
+
+// prepareRun prepares a run of owner with two servers in start-end,
+// returning the ports allocated to it
+func prepareRun(t *testing.T, s *DaemonServer, owner string, start, end int32) []int32 {
+	t.Helper()
+	pair := &pb.TestPair{SourceId: "node2", DestinationId: "node1"}
+	resp, err := s.PrepareTest(context.Background(), &pb.PrepareTestRequest{
+		Topology:      &pb.TestTopology{ServerAssignments: []*pb.TestPair{pair, pair}},
+		PortRange:     &pb.PortRange{Start: start, End: end},
+		Owner:         owner,
+		AllocatePorts: true,
+	})
+	if err != nil {
+		t.Fatalf("PrepareTest(%s) error = %v", owner, err)
+	}
+	if !resp.CanHandle || len(resp.AllocatedPorts) != 2 {
+		t.Fatalf("PrepareTest(%s) = %t %q with ports %v, want 2 ports allocated",
+			owner, resp.CanHandle, resp.Message, resp.AllocatedPorts)
+	}
+	return resp.AllocatedPorts
+}
+
+func TestDaemonServer_StopAllKeepsOtherOwners(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	// Two controllers share the daemon with their own sub-ranges, servers
+	// and clients
+	portsA := prepareRun(t, s, "team-a", 5201, 5210)
+	portsB := prepareRun(t, s, "team-b", 5211, 5220)
+	startRun(t, s, "team-a", portsA)
+	startRun(t, s, "team-b", portsB)
+
+	resp, err := s.StopAll(ctx, &pb.StopAllRequest{Owner: "team-a"})
+	if err != nil {
+		t.Fatalf("StopAll() error = %v", err)
+	}
+	if resp.StoppedProcesses != 3 {
+		t.Errorf("StopAll(team-a) stopped %d processes, want team-a's 3", resp.StoppedProcesses)
+	}
+	for _, process := range s.processManager.Processes() {
+		if process.Owner != "team-b" && !process.Stopping {
+			t.Errorf("%s of %q still running after team-a's StopAll", process.TestID, process.Owner)
+		}
+	}
+	for _, port := range portsB {
+		if !s.processManager.IsServerRunning(int(port)) {
+			t.Errorf("team-b's server on port %d stopped by team-a's StopAll", port)
+		}
+	}
+	if _, err := s.processManager.GetProcessInfo("team-b-test"); err != nil {
+		t.Errorf("team-b's client stopped by team-a's StopAll: %v", err)
+	}
+
+	reservations := s.portAllocator.GetReservations()
+	if len(reservations) != 1 || reservations[0].Owner != "team-b" {
+		t.Errorf("reservations after team-a stopped = %+v, want only team-b's", reservations)
+	}
+	for _, port := range portsA {
+		if s.portAllocator.IsPortAllocated(int(port)) {
+			t.Errorf("team-a's port %d still allocated after its StopAll", port)
+		}
+	}
+	for _, port := range portsB {
+		if !s.portAllocator.IsClaimed(int(port)) {
+			t.Errorf("team-b's port %d released by team-a's StopAll", port)
+		}
+	}
+
+	// team-a's range is free for another run, team-b's is not
+	prepareRun(t, s, "team-c", 5201, 5210)
+	prepare, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{
+		Topology:  &pb.TestTopology{ServerAssignments: []*pb.TestPair{{}}},
+		PortRange: &pb.PortRange{Start: 5215, End: 5230},
+		Owner:     "team-d",
+	})
+	if err != nil {
+		t.Fatalf("PrepareTest(team-d) error = %v", err)
+	}
+	if prepare.CanHandle {
+		t.Errorf("PrepareTest() overlapping team-b's range = %q, want refused", prepare.Message)
+	}
+
+	// A StopAll without an owner stops every run and releases every port
+	resp, err = s.StopAll(ctx, &pb.StopAllRequest{Force: true})
+	if err != nil {
+		t.Fatalf("StopAll() error = %v", err)
+	}
+	if resp.StoppedProcesses != 3 || s.processManager.GetServerCount() != 0 {
+		t.Errorf("unowned StopAll stopped %d processes, %d servers left; want team-b's 3 stopped",
+			resp.StoppedProcesses, s.processManager.GetServerCount())
+	}
+	if reservations := s.portAllocator.GetReservations(); len(reservations) != 0 {
+		t.Errorf("reservations after an unowned StopAll = %+v, want none", reservations)
+	}
+	if got := s.portAllocator.GetAllocatedCount(); got != 0 {
+		t.Errorf("%d ports allocated after an unowned StopAll, want none", got)
+	}
+}
+
+// startRun starts owner's servers on ports and a client of its own
+func startRun(t *testing.T, s *DaemonServer, owner string, ports []int32) {
+	t.Helper()
+	ctx := context.Background()
+	servers, err := s.StartServers(ctx, &pb.StartServersRequest{Ports: ports, Owner: owner})
+	if err != nil || !servers.Success {
+		t.Fatalf("StartServers(%s) = %+v, %v", owner, servers, err)
+	}
+	clients, err := s.StartClients(ctx, &pb.StartClientsRequest{
+		Owner: owner,
+		Targets: []*pb.ClientTarget{{
+			TestId: owner + "-test", DestinationIp: "127.0.0.1", DestinationPort: ports[0],
+			Profile: &pb.TestProfile{DurationSeconds: 60},
+		}},
+	})
+	if err != nil || !clients.Success {
+		t.Fatalf("StartClients(%s) = %+v, %v", owner, clients, err)
+	}
+}
+
+func TestDaemonServer_PrepareTestRefusedReleasesPorts(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	// A run without a sub-range claims the first ports of the daemon range
+	pair := &pb.TestPair{SourceId: "node2", DestinationId: "node1"}
+	topology := &pb.TestTopology{ServerAssignments: []*pb.TestPair{pair, pair}}
+	if resp, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{Topology: topology, Owner: "team-x", AllocatePorts: true}); err != nil || !resp.CanHandle {
+		t.Fatalf("PrepareTest(team-x) = %+v, %v", resp, err)
+	}
+
+	// team-a reserves those ports but cannot claim them
+	resp, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{
+		Topology:      topology,
+		PortRange:     &pb.PortRange{Start: 5201, End: 5202},
+		Owner:         "team-a",
+		AllocatePorts: true,
+	})
+	if err != nil {
+		t.Fatalf("PrepareTest(team-a) error = %v", err)
+	}
+	if resp.CanHandle || len(resp.AllocatedPorts) != 0 {
+		t.Fatalf("PrepareTest(team-a) = %t %q with ports %v, want refused", resp.CanHandle, resp.Message, resp.AllocatedPorts)
+	}
+	if reservations := s.portAllocator.GetReservations(); len(reservations) != 0 {
+		t.Errorf("reservations after team-a was refused = %+v, want none", reservations)
+	}
+
+	// The refused run does not block another owner's range
+	prepareRun(t, s, "team-b", 5201, 5210)
+}
+
+func TestDaemonServer_PrepareTestClaimsPerOwner(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	pair := &pb.TestPair{SourceId: "node2", DestinationId: "node1"}
+	topology := &pb.TestTopology{ServerAssignments: []*pb.TestPair{pair, pair}}
+
+	// Claiming ports needs an owner to release them under
+	resp, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{Topology: topology, AllocatePorts: true})
+	if err != nil {
+		t.Fatalf("PrepareTest() error = %v", err)
+	}
+	if resp.CanHandle || s.portAllocator.GetAllocatedCount() != 0 {
+		t.Errorf("PrepareTest() without an owner = %t %q, want refused with no ports claimed", resp.CanHandle, resp.Message)
+	}
+
+	// Runs without a port range claim disjoint ports, and preparing one
+	// again keeps the other's
+	claimed := make(map[string][]int32)
+	for _, owner := range []string{"run-a", "run-b", "run-a"} {
+		resp, err := s.PrepareTest(ctx, &pb.PrepareTestRequest{Topology: topology, Owner: owner, AllocatePorts: true})
+		if err != nil || !resp.CanHandle {
+			t.Fatalf("PrepareTest(%s) = %+v, %v", owner, resp, err)
+		}
+		claimed[owner] = resp.AllocatedPorts
+	}
+	for _, a := range claimed["run-a"] {
+		for _, b := range claimed["run-b"] {
+			if a == b {
+				t.Errorf("port %d claimed by both run-a and run-b", a)
+			}
+		}
+	}
+	for _, port := range claimed["run-b"] {
+		if !s.portAllocator.IsClaimed(int(port)) {
+			t.Errorf("run-b's port %d released by run-a's PrepareTest", port)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+// Package simulate fakes iperf3 for the daemon's --simulate mode. Servers are
+// not started and clients produce synthetic iperf3 JSON after the test's
+// duration, so that the controller can be exercised without iperf3 or a
+// network to measure.
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+// Version is reported by simulated results in place of the iperf3 version
+const Version = "iperf 3 (simulated)"
+
+// defaultMSS is the TCP maximum segment size reported by simulated tests
+const defaultMSS = 1448
+
+// udpPacketBytes is the datagram size simulated UDP tests count packets in
+const udpPacketBytes = 1448
+
+// Config describes the distribution of simulated results
+type Config struct {
+	ThroughputMeanBps   float64       // Mean throughput of a test
+	ThroughputStddevBps float64       // Standard deviation of a test's throughput
+	FailureRate         float64       // Share of tests that fail, 0-1
+	RTT                 time.Duration // Mean round-trip time of TCP streams
+	StartLatency        time.Duration // Delay before a test starts sending
+	Seed                int64         // Random seed; 0 seeds from the clock
+}
+
+// Simulator runs fake iperf3 clients and servers
+type Simulator struct {
+	config Config
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+// New creates a simulator producing results distributed as in config
+func New(config Config) *Simulator {
+	seed := config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Simulator{
+		config: config,
+		rng:    rand.New(rand.NewSource(seed)), // #nosec G404 -- Simulated results need no secure randomness
+	}
+}
+
+// RunServer pretends to start an iperf3 server. There is no process, so the
+// returned command is nil.
+func (s *Simulator) RunServer(ctx context.Context, port int, bindIP, logFile string) (*exec.Cmd, error) {
+	return nil, nil
+}
+
+// Run waits for the length of the test and returns a synthetic result, or a
+// failed result for the configured share of tests. Tests limited to a number
+// of bytes, such as microbursts, last as long as sending them takes.
+func (s *Simulator) Run(ctx context.Context, config *iperf.Config) (*iperf.Result, error) {
+	bps := s.throughput(config)
+	duration := config.Duration
+	if config.Bytes > 0 && bps > 0 {
+		duration = time.Duration(float64(config.Bytes*8) / bps * float64(time.Second))
+	}
+	fail, failAfter := s.failure(duration)
+
+	result := &iperf.Result{StartTime: time.Now()}
+	wait := s.config.StartLatency + duration
+	if fail {
+		wait = s.config.StartLatency + failAfter
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		result.EndTime = time.Now()
+		result.Duration = result.EndTime.Sub(result.StartTime)
+		result.ExitCode = -1
+		result.Error = fmt.Sprintf("iperf3 failed: %v, stderr: simulated test interrupted", ctx.Err())
+		return result, nil
+	}
+
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	if fail {
+		result.ExitCode = 1
+		result.Error = "iperf3 failed: exit status 1, stderr: iperf3: error - control socket has closed unexpectedly (simulated failure)"
+		return result, nil
+	}
+
+	started := result.StartTime.Add(s.config.StartLatency)
+	output, err := s.output(config, started, duration, bps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode simulated result: %w", err)
+	}
+	result.Success = true
+	result.JSONOutput = output
+	return result, nil
+}
+
+// throughput draws the throughput of one test, capped at its bandwidth limit
+func (s *Simulator) throughput(config *iperf.Config) float64 {
+	s.mu.Lock()
+	bps := s.config.ThroughputMeanBps + s.rng.NormFloat64()*s.config.ThroughputStddevBps
+	s.mu.Unlock()
+
+	// iperf3 applies the bandwidth limit to each stream
+	if limit, err := iperf.ParseBandwidth(config.Bandwidth); err == nil && limit > 0 {
+		bps = math.Min(bps, limit*float64(max(config.Parallel, 1)))
+	}
+	return math.Max(bps, 1)
+}
+
+// failure decides whether a test fails and, if so, after how long
+func (s *Simulator) failure(duration time.Duration) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.rng.Float64() >= s.config.FailureRate {
+		return false, 0
+	}
+	return true, time.Duration(s.rng.Float64() * float64(duration))
+}
+
+// jitter returns a random factor around 1 for the variation between intervals
+func (s *Simulator) jitter() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return math.Max(1+s.rng.NormFloat64()*0.02, 0)
+}
+
+// output builds the iperf3 JSON of a test that sent at bps for duration,
+// with one-second intervals and the sections the controller reads
+func (s *Simulator) output(config *iperf.Config, started time.Time, duration time.Duration, bps float64) (string, error) {
+	udp := config.Protocol == iperf.ProtocolUDP
+	streams := max(config.Parallel, 1)
+	seconds := duration.Seconds()
+
+	intervals := make([]map[string]interface{}, 0, int(math.Ceil(seconds)))
+	for start := 0.0; start < seconds; start++ {
+		end := math.Min(start+1, seconds)
+		intervalBps := bps * s.jitter()
+		sum := map[string]interface{}{
+			"start":           start,
+			"end":             end,
+			"seconds":         end - start,
+			"bytes":           int64(intervalBps * (end - start) / 8),
+			"bits_per_second": intervalBps,
+			"omitted":         int(start) < config.OmitSeconds,
+			"sender":          true,
+		}
+		if !udp {
+			sum["retransmits"] = 0
+		}
+		intervals = append(intervals, map[string]interface{}{"sum": sum})
+	}
+
+	bytes := int64(bps * seconds / 8)
+	sumSent := map[string]interface{}{
+		"start":           0,
+		"end":             seconds,
+		"seconds":         seconds,
+		"bytes":           bytes,
+		"bits_per_second": bps,
+		"sender":          true,
+	}
+	sumReceived := map[string]interface{}{
+		"start":           0,
+		"end":             seconds,
+		"seconds":         seconds,
+		"bytes":           bytes,
+		"bits_per_second": bps,
+		"sender":          false,
+	}
+
+	protocol := "TCP"
+	end := map[string]interface{}{
+		"sum_sent":     sumSent,
+		"sum_received": sumReceived,
+	}
+	serverEnd := map[string]interface{}{"sum_received": sumReceived}
+	if udp {
+		protocol = "UDP"
+		packets := bytes / udpPacketBytes
+		end["sum"] = map[string]interface{}{
+			"start":           0,
+			"end":             seconds,
+			"seconds":         seconds,
+			"bytes":           bytes,
+			"bits_per_second": bps,
+			"jitter_ms":       0.01,
+			"lost_packets":    0,
+			"packets":         packets,
+			"lost_percent":    0,
+			"sender":          true,
+		}
+	} else {
+		sumSent["retransmits"] = 0
+		end["streams"] = s.tcpStreams(streams, bps, seconds)
+		algorithm := config.CongestionControl
+		if algorithm == "" {
+			algorithm = "cubic"
+		}
+		end["sender_tcp_congestion"] = algorithm
+		end["receiver_tcp_congestion"] = algorithm
+	}
+
+	data := map[string]interface{}{
+		"start": map[string]interface{}{
+			"connected": []map[string]interface{}{{
+				"socket":      5,
+				"local_host":  "127.0.0.1",
+				"local_port":  0,
+				"remote_host": config.Host,
+				"remote_port": config.Port,
+			}},
+			"version": Version,
+			"timestamp": map[string]interface{}{
+				"time":     started.UTC().Format(time.RFC1123),
+				"timesecs": started.Unix(),
+			},
+			"connecting_to": map[string]interface{}{
+				"host": config.Host,
+				"port": config.Port,
+			},
+			"tcp_mss_default": defaultMSS,
+			"test_start": map[string]interface{}{
+				"protocol":    protocol,
+				"num_streams": streams,
+				"omit":        config.OmitSeconds,
+				"duration":    int(math.Round(seconds)),
+				"reverse":     boolInt(config.Reverse),
+				"bidir":       boolInt(config.Bidirectional),
+			},
+		},
+		"intervals":          intervals,
+		"end":                end,
+		"server_output_json": map[string]interface{}{"end": serverEnd},
+		"simulated":          true,
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// tcpStreams returns the per-stream end statistics of a TCP test, with the
+// throughput split evenly and a congestion window that covers the RTT
+func (s *Simulator) tcpStreams(streams int, bps, seconds float64) []map[string]interface{} {
+	rtt := s.config.RTT
+	if rtt <= 0 {
+		rtt = 100 * time.Microsecond
+	}
+	rttUs := rtt.Microseconds()
+	perStream := bps / float64(streams)
+	cwnd := int64(perStream / 8 * rtt.Seconds() * 1.5)
+
+	result := make([]map[string]interface{}, 0, streams)
+	for i := 0; i < streams; i++ {
+		result = append(result, map[string]interface{}{
+			"sender": map[string]interface{}{
+				"socket":          5 + i,
+				"start":           0,
+				"end":             seconds,
+				"seconds":         seconds,
+				"bytes":           int64(perStream * seconds / 8),
+				"bits_per_second": perStream,
+				"retransmits":     0,
+				"max_snd_cwnd":    max(cwnd, defaultMSS),
+				"max_rtt":         rttUs * 2,
+				"min_rtt":         max(rttUs/2, 1),
+				"mean_rtt":        max(rttUs, 1),
+				"sender":          true,
+			},
+		})
+	}
+	return result
+}
+
+// boolInt returns b as iperf3's 0/1 flags
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,95 @@
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/iperf"
+)
+
+func TestSimulator_Run(t *testing.T) {
+	sim := New(Config{ThroughputMeanBps: 10e9, RTT: time.Millisecond, Seed: 1})
+
+	result, err := sim.Run(context.Background(), &iperf.Config{
+		Host:      "10.0.0.2",
+		Port:      5201,
+		Duration:  25 * time.Millisecond,
+		Parallel:  2,
+		Bandwidth: "1G",
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !result.Success {
+		t.Fatalf("Run() failed: %s", result.Error)
+	}
+
+	var output struct {
+		Start struct {
+			Version   string `json:"version"`
+			Timestamp struct {
+				Timesecs int64 `json:"timesecs"`
+			} `json:"timestamp"`
+		} `json:"start"`
+		Intervals []json.RawMessage `json:"intervals"`
+		End       struct {
+			SumSent struct {
+				BitsPerSecond float64 `json:"bits_per_second"`
+			} `json:"sum_sent"`
+			Streams []struct {
+				Sender struct {
+					MeanRTT int64 `json:"mean_rtt"`
+				} `json:"sender"`
+			} `json:"streams"`
+		} `json:"end"`
+		Simulated bool `json:"simulated"`
+	}
+	if err := json.Unmarshal([]byte(result.JSONOutput), &output); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+
+	if !output.Simulated || output.Start.Version != Version {
+		t.Error("output is not labeled as simulated")
+	}
+	if output.Start.Timestamp.Timesecs == 0 {
+		t.Error("missing start timestamp")
+	}
+	// Two streams limited to 1G each
+	if output.End.SumSent.BitsPerSecond != 2e9 {
+		t.Errorf("throughput = %v, want 2e9", output.End.SumSent.BitsPerSecond)
+	}
+	if len(output.Intervals) != 1 {
+		t.Errorf("intervals = %d, want 1", len(output.Intervals))
+	}
+	if len(output.End.Streams) != 2 || output.End.Streams[0].Sender.MeanRTT != 1000 {
+		t.Errorf("streams = %+v, want 2 with a 1000us RTT", output.End.Streams)
+	}
+}
+
+func TestSimulator_Failure(t *testing.T) {
+	sim := New(Config{ThroughputMeanBps: 1e9, FailureRate: 1, Seed: 1})
+
+	result, err := sim.Run(context.Background(), &iperf.Config{Duration: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Success || result.Error == "" || result.JSONOutput != "" {
+		t.Errorf("Run() = %+v, want a failed result", result)
+	}
+}
+
+func TestSimulator_Cancelled(t *testing.T) {
+	sim := New(Config{ThroughputMeanBps: 1e9, Seed: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err := sim.Run(ctx, &iperf.Config{Duration: time.Hour})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Success || result.ExitCode != -1 {
+		t.Errorf("Run() = %+v, want an interrupted result", result)
+	}
+}
@@ -0,0 +1,7 @@
+// Package integration tests the controller and daemons end to end. Its
+// tests start daemons in process on loopback and run the real orchestrator
+// against them with iperf3, so they need the integration build tag and
+// iperf3 on the PATH:
+//
+//	go test -tags integration ./internal/integration/
+package integration
@@ -0,0 +1,271 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+	"github.com/bensons/iperf-cnc/internal/controller/topology"
+	"github.com/bensons/iperf-cnc/internal/daemon/server"
+)
+
+// Each daemon gets its own block of portsPerDaemon server ports from
+// portBase, away from iperf3's default port
+const (
+	portBase       = 27100
+	portsPerDaemon = 100
+)
+
+// testDaemon is a daemon serving on loopback in this process
+type testDaemon struct {
+	id         string
+	listenPort int
+	server     *server.DaemonServer
+}
+
+// requireIperf skips the test unless iperf3 can be run
+func requireIperf(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("iperf3"); err != nil {
+		t.Skip("iperf3 is not installed")
+	}
+}
+
+// startDaemons starts count daemons on loopback ports chosen by the kernel,
+// each with its own server port range, stopping them when the test ends
+func startDaemons(t *testing.T, count int) []*testDaemon {
+	t.Helper()
+
+	daemons := make([]*testDaemon, 0, count)
+	for i := 0; i < count; i++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		listenPort := listener.Addr().(*net.TCPAddr).Port
+
+		start := portBase + i*portsPerDaemon
+		daemonServer, err := server.NewDaemonServer(&server.Config{
+			ListenPort:     listenPort,
+			PortRangeStart: start,
+			PortRangeEnd:   start + portsPerDaemon - 1,
+			MaxProcesses:   50,
+			ResultDir:      t.TempDir(),
+			StopTimeout:    5 * time.Second,
+			Version:        "integration",
+		})
+		if err != nil {
+			t.Fatalf("NewDaemonServer() error = %v", err)
+		}
+
+		grpcServer := grpc.NewServer()
+		pb.RegisterDaemonServiceServer(grpcServer, daemonServer)
+		go func() {
+			_ = grpcServer.Serve(listener)
+		}()
+		t.Cleanup(func() {
+			_, _ = daemonServer.StopAll(context.Background(), &pb.StopAllRequest{})
+			grpcServer.Stop()
+		})
+
+		daemons = append(daemons, &testDaemon{
+			id:         fmt.Sprintf("node%d", i+1),
+			listenPort: listenPort,
+			server:     daemonServer,
+		})
+	}
+	return daemons
+}
+
+// newConfig builds a full mesh controller configuration of the daemons
+// testing with a TCP profile of the given duration. The daemons share
+// loopback, so server ports come from one range spanning all of theirs.
+func newConfig(t *testing.T, daemons []*testDaemon, seconds float64) *config.ControllerConfig {
+	t.Helper()
+
+	nodes := make([]config.NodeConfig, 0, len(daemons))
+	for _, daemon := range daemons {
+		nodes = append(nodes, config.NodeConfig{
+			ID:       daemon.id,
+			Hostname: daemon.id,
+			IP:       "127.0.0.1",
+			Port:     daemon.listenPort,
+		})
+	}
+
+	cfg := &config.ControllerConfig{Controller: config.ControllerSettings{
+		Nodes: nodes,
+		TestProfiles: map[string]config.TestProfile{
+			"default": {Duration: seconds, Protocol: "tcp", Parallel: 1},
+		},
+		Topology: config.TopologyConfig{Type: "full_mesh", DefaultProfile: "default"},
+		PortRange: &config.PortRangeConfig{
+			Start: portBase,
+			End:   portBase + len(daemons)*portsPerDaemon - 1,
+		},
+		Output: config.OutputConfig{JSONFile: filepath.Join(t.TempDir(), "results.json")},
+	}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	cfg.SetDefaults()
+	return cfg
+}
+
+// runResult is what a run left behind
+type runResult struct {
+	topology *topology.Topology
+	results  []*aggregator.TestResult
+	pool     *client.Pool
+}
+
+// runTopology runs the configured topology as the run command does, and
+// collects its results
+func runTopology(t *testing.T, cfg *config.ControllerConfig) *runResult {
+	t.Helper()
+
+	nodeRegistry, err := setup.BuildNodeRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	topo, err := setup.GenerateTopology(cfg, nodeRegistry, profileRegistry, defaultProfile, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	pool := client.NewPool(5 * time.Second)
+	if err := pool.ConnectAll(ctx, nodeRegistry.GetAllNodes()); err != nil {
+		t.Fatalf("ConnectAll() error = %v", err)
+	}
+	t.Cleanup(func() {
+		_ = pool.Close()
+	})
+
+	// The port range is not reserved on the daemons, as it lies outside
+	// each one's own range
+	orch := orchestrator.NewOrchestrator(pool, false, false, "")
+	if err := orch.ExecuteTest(ctx, topo); err != nil {
+		t.Fatalf("ExecuteTest() error = %v", err)
+	}
+
+	agg := aggregator.NewAggregator()
+	agg.SetExpectedTests(topo.TestIDs())
+	if err := agg.CollectResults(ctx, pool); err != nil {
+		t.Fatalf("CollectResults() error = %v", err)
+	}
+
+	return &runResult{topology: topo, results: agg.GetResults(), pool: pool}
+}
+
+// checkIdle fails the test unless every daemon has released its ports and
+// process slots and no iperf3 process of this test is left. Stopped
+// processes are given a few seconds to exit.
+func checkIdle(t *testing.T, daemons []*testDaemon) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		problems := idleProblems(daemons)
+		if len(problems) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("daemons are not idle after the run:\n  %s", strings.Join(problems, "\n  "))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// idleProblems returns what is still held after a run
+func idleProblems(daemons []*testDaemon) []string {
+	problems := make([]string, 0)
+	for _, daemon := range daemons {
+		// Preparing an empty topology reports the daemon's capacity
+		// breakdown without claiming anything
+		resp, err := daemon.server.PrepareTest(context.Background(), &pb.PrepareTestRequest{Topology: &pb.TestTopology{}})
+		if err != nil || resp.Breakdown == nil {
+			problems = append(problems, fmt.Sprintf("%s: no capacity breakdown: %v", daemon.id, err))
+			continue
+		}
+		b := resp.Breakdown
+		if b.UsedSlots > 0 || b.RunningServers > 0 || b.RunningClients > 0 || b.StoppingProcesses > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d slots used by %d servers and %d clients, %d processes stopping",
+				daemon.id, b.UsedSlots, b.RunningServers, b.RunningClients, b.StoppingProcesses))
+		}
+		if b.ServerPorts > 0 || b.AllocatedPorts > 0 || b.ReservedPorts > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %d ports with servers, %d allocated, %d reserved",
+				daemon.id, b.ServerPorts, b.AllocatedPorts, b.ReservedPorts))
+		}
+		for _, divergence := range b.Divergences {
+			problems = append(problems, fmt.Sprintf("%s: %s", daemon.id, divergence))
+		}
+	}
+	if pids := iperfChildren(); len(pids) > 0 {
+		problems = append(problems, fmt.Sprintf("iperf3 processes %v remain", pids))
+	}
+	return problems
+}
+
+// iperfChildren returns the IDs of the iperf3 processes this process
+// started that have not been reaped. It returns none where /proc is missing.
+func iperfChildren() []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	parent := os.Getpid()
+	pids := make([]int, 0)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat")) // #nosec G304 -- Path is built from a process ID
+		if err != nil {
+			continue
+		}
+		// The command name is parenthesized and may contain spaces, so the
+		// fields after it are split from the last parenthesis
+		stat := string(data)
+		open, closing := strings.IndexByte(stat, '('), strings.LastIndexByte(stat, ')')
+		if open < 0 || closing < open {
+			continue
+		}
+		fields := strings.Fields(stat[closing+1:])
+		if len(fields) < 2 || stat[open+1:closing] != "iperf3" {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil && ppid == parent {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
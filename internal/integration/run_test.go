@@ -0,0 +1,29 @@
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+)
+
+func TestFullMeshOverLoopback(t *testing.T) {
+	requireIperf(t)
+
+	daemons := startDaemons(t, 3)
+	run := runTopology(t, newConfig(t, daemons, 2))
+
+	if got, want := len(run.results), run.topology.GetTestCount(); got != want {
+		t.Fatalf("collected %d results, want %d", got, want)
+	}
+	for _, result := range run.results {
+		if result.Status != "TEST_STATUS_COMPLETED" {
+			t.Errorf("%s: status %s: %s", result.TestID, result.Status, result.ErrorMessage)
+			continue
+		}
+		if result.ThroughputBps <= 0 {
+			t.Errorf("%s: throughput %v, want > 0", result.TestID, result.ThroughputBps)
+		}
+	}
+
+	checkIdle(t, daemons)
+}
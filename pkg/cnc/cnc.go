@@ -0,0 +1,220 @@
+// Package cnc runs iperf-cnc tests from Go code. A Cluster describes the
+// daemons and test profiles, loaded from a controller configuration file or
+// built from Go values, and Run drives a full mesh run over them, returning
+// typed results in place of the files the iperf-controller command writes.
+//
+// This package is the stable API; the internal packages behind it may
+// change between releases. Progress is logged with the standard log package.
+package cnc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/controller/client"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+)
+
+// DefaultDaemonPort is the port daemons listen on unless configured otherwise
+const DefaultDaemonPort = 50051
+
+// Node is a host running iperf-daemon
+type Node struct {
+	ID       string   // Unique name of the node (default: Hostname)
+	Hostname string   // Expected hostname of the daemon
+	IP       string   // Address the daemon is reached at
+	DataIP   string   // Address for iperf3 traffic, if not IP
+	Port     int      // Daemon port (default: DefaultDaemonPort)
+	Tags     []string // Labels that RunOptions.Tags select nodes by
+}
+
+// Profile is a set of iperf3 test parameters
+type Profile struct {
+	Name              string
+	Duration          time.Duration
+	Protocol          string // "tcp" (default) or "udp"
+	Bandwidth         string // Target bandwidth per stream, e.g. "1G"; required for UDP
+	Parallel          int    // Parallel streams (default: 1)
+	Reverse           bool
+	Bidirectional     bool
+	WindowSize        string // Socket buffer size, e.g. "4M"
+	CongestionControl string // TCP congestion control algorithm, e.g. "bbr"
+	OmitSeconds       int    // Seconds excluded from the results at the start of a test
+}
+
+// Cluster is a set of daemons and the profiles tests run with
+type Cluster struct {
+	cfg *config.ControllerConfig
+}
+
+// LoadCluster loads a cluster from an iperf-controller configuration file.
+// Its topology, port range and failure settings apply to Run; output
+// settings are ignored.
+func LoadCluster(path string) (*Cluster, error) {
+	cfg, err := config.LoadControllerConfig(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return newCluster(cfg)
+}
+
+// NewCluster builds a cluster testing every pair of nodes in both directions
+// with the first profile
+func NewCluster(nodes []Node, profiles []Profile) (*Cluster, error) {
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("at least one test profile is required")
+	}
+
+	cfg := &config.ControllerConfig{}
+	for _, node := range nodes {
+		port := node.Port
+		if port == 0 {
+			port = DefaultDaemonPort
+		}
+		cfg.Controller.Nodes = append(cfg.Controller.Nodes, config.NodeConfig{
+			ID:       node.ID,
+			Hostname: node.Hostname,
+			IP:       node.IP,
+			DataIP:   node.DataIP,
+			Port:     port,
+			Tags:     node.Tags,
+		})
+	}
+
+	cfg.Controller.TestProfiles = make(map[string]config.TestProfile, len(profiles))
+	for _, profile := range profiles {
+		if _, exists := cfg.Controller.TestProfiles[profile.Name]; exists {
+			return nil, fmt.Errorf("duplicate test profile: %s", profile.Name)
+		}
+		cfg.Controller.TestProfiles[profile.Name] = config.TestProfile{
+			Duration:          profile.Duration.Seconds(),
+			Protocol:          profile.Protocol,
+			Bandwidth:         profile.Bandwidth,
+			WindowSize:        profile.WindowSize,
+			Parallel:          max(profile.Parallel, 1),
+			Bidirectional:     profile.Bidirectional,
+			Reverse:           profile.Reverse,
+			CongestionControl: profile.CongestionControl,
+			OmitSeconds:       profile.OmitSeconds,
+		}
+	}
+	cfg.Controller.Topology = config.TopologyConfig{
+		Type:           "full_mesh",
+		DefaultProfile: profiles[0].Name,
+	}
+	// Required by validation; Run returns results instead of writing files
+	cfg.Controller.Output.JSONFile = "results.json"
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cluster: %w", err)
+	}
+	return newCluster(cfg)
+}
+
+// newCluster applies defaults to a validated configuration
+func newCluster(cfg *config.ControllerConfig) (*Cluster, error) {
+	if cfg.Controller.Topology.Type == "rotating" {
+		return nil, fmt.Errorf("rotating topologies are not supported; use the iperf-controller command")
+	}
+	cfg.SetDefaults()
+	return &Cluster{cfg: cfg}, nil
+}
+
+// Nodes returns the IDs of the cluster's nodes
+func (c *Cluster) Nodes() []string {
+	ids := make([]string, 0, len(c.cfg.Controller.Nodes))
+	for _, node := range c.cfg.Controller.Nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+// NodeStatus is the state a daemon reports
+type NodeStatus struct {
+	Healthy           bool
+	Version           string
+	RunningProcesses  int
+	CompletedTests    int
+	FailedTests       int
+	StoredResults     int // Results not yet retrieved by a run
+	StoredResultBytes int64
+	Maintenance       bool
+	MaintenanceReason string
+	Simulated         bool // The daemon returns synthetic results
+	// Results the daemon dropped, by eviction or rejection, to stay within
+	// its caps on stored results
+	ResultsEvicted  int64
+	ResultsRejected int64
+}
+
+// Status returns the status of each node's daemon by node ID. Nodes that
+// cannot be reached are left out and reported in the error.
+func (c *Cluster) Status(ctx context.Context) (map[string]*NodeStatus, error) {
+	pool, err := c.connect(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer closePool(pool)
+
+	statuses, healthErr := pool.CheckHealth(ctx)
+	result := make(map[string]*NodeStatus, len(statuses))
+	for nodeID, status := range statuses {
+		result[nodeID] = &NodeStatus{
+			Healthy:           status.Healthy,
+			Version:           status.Version,
+			RunningProcesses:  int(status.RunningProcesses),
+			CompletedTests:    int(status.CompletedTests),
+			FailedTests:       int(status.FailedTests),
+			StoredResults:     int(status.StoredResults),
+			StoredResultBytes: status.StoredResultBytes,
+			ResultsEvicted:    status.ResultsEvicted,
+			ResultsRejected:   status.ResultsRejected,
+			Maintenance:       status.Maintenance,
+			MaintenanceReason: status.MaintenanceReason,
+			Simulated:         status.Simulated,
+		}
+	}
+	return result, healthErr
+}
+
+// Stop stops every iperf3 process on the cluster's daemons, such as those
+// left by a run that was interrupted
+func (c *Cluster) Stop(ctx context.Context) error {
+	pool, err := c.connect(ctx, false)
+	if err != nil {
+		return err
+	}
+	defer closePool(pool)
+
+	return pool.StopAll(ctx, "")
+}
+
+// connect connects to the cluster's daemons. With partial set, nodes that
+// cannot be reached are skipped as long as one can.
+func (c *Cluster) connect(ctx context.Context, partial bool) (*client.Pool, error) {
+	nodeRegistry, err := setup.BuildNodeRegistry(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := client.NewPool(time.Duration(c.cfg.Controller.Concurrency.ConnectionTimeout) * time.Second)
+	pool.SetPollPolicy(c.cfg.Controller.Concurrency.PollPolicy())
+	if connErr := pool.ConnectAll(ctx, nodeRegistry.GetAllNodes()); connErr != nil {
+		if !partial || pool.Count() == 0 {
+			closePool(pool)
+			return nil, fmt.Errorf("failed to connect to daemons: %w", connErr)
+		}
+		log.Printf("Warning: %v", connErr)
+	}
+	return pool, nil
+}
+
+// closePool closes a pool, logging failures
+func closePool(pool *client.Pool) {
+	if err := pool.Close(); err != nil {
+		log.Printf("Warning: failed to close connection pool: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package cnc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/bensons/iperf-cnc/api/proto"
+	"github.com/bensons/iperf-cnc/internal/daemon/server"
+	"github.com/bensons/iperf-cnc/internal/daemon/simulate"
+)
+
+// startDaemon serves a simulated daemon on a local port and returns the port
+func startDaemon(t *testing.T) int {
+	t.Helper()
+
+	daemon, err := server.NewDaemonServer(&server.Config{
+		PortRangeStart: 5201,
+		PortRangeEnd:   5300,
+		MaxProcesses:   10,
+		LogLevel:       "error",
+		ResultDir:      t.TempDir(),
+		Version:        "test",
+		Simulate:       &simulate.Config{ThroughputMeanBps: 1e9, Seed: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewDaemonServer() error = %v", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterDaemonServiceServer(grpcServer, daemon)
+	go func() { _ = grpcServer.Serve(listener) }()
+	t.Cleanup(grpcServer.Stop)
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestCluster_Run(t *testing.T) {
+	nodes := make([]Node, 0, 2)
+	for i := 1; i <= 2; i++ {
+		name := fmt.Sprintf("node%d", i)
+		nodes = append(nodes, Node{Hostname: name, IP: "127.0.0.1", Port: startDaemon(t)})
+	}
+	cluster, err := NewCluster(nodes, []Profile{{Name: "quick", Duration: 200 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("NewCluster() error = %v", err)
+	}
+
+	ctx := context.Background()
+	report, err := cluster.Run(ctx, RunOptions{})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if report.Summary.TotalTests != 2 || report.Summary.CompletedTests != 2 {
+		t.Errorf("Summary = %+v, want 2 completed tests", report.Summary)
+	}
+	for _, result := range report.Results {
+		if result.Status != StatusCompleted || result.ThroughputBps <= 0 || result.Profile != "quick" {
+			t.Errorf("result %s: status %s, throughput %v, profile %q; want completed with throughput",
+				result.TestID, result.Status, result.ThroughputBps, result.Profile)
+		}
+	}
+
+	statuses, err := cluster.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, id := range cluster.Nodes() {
+		if status := statuses[id]; status == nil || !status.Healthy || !status.Simulated {
+			t.Errorf("Status()[%s] = %+v, want healthy simulated daemon", id, status)
+		}
+	}
+	if err := cluster.Stop(ctx); err != nil {
+		t.Errorf("Stop() error = %v", err)
+	}
+}
+
+func TestNewCluster_Invalid(t *testing.T) {
+	node := Node{Hostname: "node1", IP: "127.0.0.1"}
+	profile := Profile{Name: "quick", Duration: time.Second}
+
+	if _, err := NewCluster([]Node{node}, []Profile{profile}); err == nil {
+		t.Error("NewCluster() with one node succeeded, want error")
+	}
+	other := Node{Hostname: "node2", IP: "127.0.0.2"}
+	if _, err := NewCluster([]Node{node, other}, nil); err == nil {
+		t.Error("NewCluster() without profiles succeeded, want error")
+	}
+	if _, err := NewCluster([]Node{node, other}, []Profile{profile, profile}); err == nil {
+		t.Error("NewCluster() with duplicate profiles succeeded, want error")
+	}
+}
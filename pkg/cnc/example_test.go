@@ -0,0 +1,46 @@
+package cnc_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bensons/iperf-cnc/pkg/cnc"
+)
+
+func Example() {
+	cluster, err := cnc.NewCluster(
+		[]cnc.Node{
+			{Hostname: "node1", IP: "10.0.0.1"},
+			{Hostname: "node2", IP: "10.0.0.2"},
+		},
+		[]cnc.Profile{{Name: "tcp-10s", Duration: 10 * time.Second, Parallel: 4}},
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := cluster.Run(context.Background(), cnc.RunOptions{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, result := range report.Results {
+		fmt.Printf("%s -> %s: %s, %.0f bps\n", result.Source, result.Destination, result.Status, result.ThroughputBps)
+	}
+}
+
+func ExampleLoadCluster() {
+	cluster, err := cnc.LoadCluster("controller.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Test only the nodes tagged rack-a
+	report, err := cluster.Run(context.Background(), cnc.RunOptions{Tags: []string{"rack-a"}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d of %d tests completed, avg %.0f bps\n",
+		report.Summary.CompletedTests, report.Summary.TotalTests, report.Summary.AvgThroughputBps)
+}
@@ -0,0 +1,207 @@
+package cnc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bensons/iperf-cnc/internal/common/config"
+	"github.com/bensons/iperf-cnc/internal/common/units"
+	"github.com/bensons/iperf-cnc/internal/controller/aggregator"
+	"github.com/bensons/iperf-cnc/internal/controller/orchestrator"
+	"github.com/bensons/iperf-cnc/internal/controller/setup"
+)
+
+// Test statuses
+const (
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// RunOptions select the nodes of a run. A node takes part if it is listed in
+// Nodes or its tags satisfy any of the tag expressions in Tags, such as
+// "zone:us-east and not rack:a", or if both are empty, and is not in Exclude.
+type RunOptions struct {
+	Nodes   []string
+	Tags    []string
+	Exclude []string
+	// Force stops the processes and clears the results of daemons busy with
+	// another run instead of refusing to start
+	Force bool
+}
+
+// Report is the outcome of a run
+type Report struct {
+	Results []*Result
+	Summary Summary
+	// Stalled lists tests still running when the run stopped waiting for them
+	Stalled []string
+}
+
+// Result is the outcome of one test between a pair of nodes
+type Result struct {
+	TestID      string
+	Source      string // Node running the iperf3 client
+	Destination string // Node running the iperf3 server
+	Profile     string
+	Group       string
+	Background  bool   // Unmeasured load, not counted in the summary
+	Status      string // StatusCompleted or StatusFailed
+	Error       string
+	Start, End  time.Time
+
+	ThroughputBps         float64 // Receiver throughput when reported, else sender
+	SenderThroughputBps   float64
+	ReceiverThroughputBps float64
+	Retransmits           int64
+	MeanRTT               time.Duration // Mean RTT across TCP streams
+
+	// IperfData is the decoded iperf3 JSON output. Its layout is iperf3's
+	// and is not covered by this package's compatibility promise.
+	IperfData map[string]interface{}
+}
+
+// Summary aggregates the measured tests of a run
+type Summary struct {
+	TotalTests       int
+	CompletedTests   int
+	FailedTests      int
+	BackgroundTests  int
+	AvgThroughputBps float64
+	MinThroughputBps float64
+	MaxThroughputBps float64
+	TotalRetransmits int64
+}
+
+// Run tests the cluster and returns the results. If the run fails part-way,
+// the error is returned together with a report of whatever results could be
+// collected, or a nil report if there were none.
+func (c *Cluster) Run(ctx context.Context, opts RunOptions) (*Report, error) {
+	cfg := *c.cfg
+	subset := config.NodeSubset{Nodes: opts.Nodes, Tags: opts.Tags, Exclude: opts.Exclude}
+	if _, err := cfg.SelectNodes(subset); err != nil {
+		return nil, fmt.Errorf("invalid node selection: %w", err)
+	}
+
+	nodeRegistry, err := setup.BuildNodeRegistry(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	profileRegistry, err := setup.BuildProfileRegistry(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	defaultProfile, err := profileRegistry.GetProfile(cfg.Controller.Topology.DefaultProfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default profile: %w", err)
+	}
+	topo, err := setup.GenerateTopology(&cfg, nodeRegistry, profileRegistry, defaultProfile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := &Cluster{cfg: &cfg}
+	pool, err := selected.connect(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	defer closePool(pool)
+
+	orch := orchestrator.NewOrchestrator(pool, false, false, "")
+	orch.SetFailurePolicy(orchestrator.FailurePolicy{
+		StopAll:        cfg.Controller.OnFailure.StopAllEnabled(),
+		ClearResults:   cfg.Controller.OnFailure.ClearResultsEnabled(),
+		CollectPartial: cfg.Controller.OnFailure.CollectPartialEnabled(),
+	})
+	if pr := cfg.Controller.PortRange; pr != nil {
+		orch.SetPortRange(pr.Owner, int32(pr.Start), int32(pr.End)) // #nosec G115 -- Ports are validated to be in valid range
+	}
+	orch.SetForce(opts.Force)
+	orch.SetCongestionControlPolicy(cfg.Controller.CongestionControlPolicy)
+	orch.SetMaxConcurrentTests(cfg.Controller.Concurrency.MaxConcurrentTests)
+	orch.SetProcessStopTimeout(time.Duration(cfg.Controller.Concurrency.ProcessStopSeconds) * time.Second)
+	orch.SetStallPolicy(time.Duration(max(cfg.Controller.Concurrency.StallWindowSeconds, 0))*time.Second,
+		cfg.Controller.Concurrency.StopStalledTests)
+	orch.SetVerbose(cfg.Controller.Logging.Verbose)
+
+	agg := aggregator.NewAggregator()
+	agg.SetDiscrepancyThreshold(cfg.Controller.Analysis.DiscrepancyThreshold())
+	agg.SetRetransmitThreshold(cfg.Controller.Analysis.RetransmitThresholdPerMB)
+	agg.SetV6LagThreshold(cfg.Controller.Analysis.V6LagThresholdPercent)
+	agg.SetTimelineBucket(units.Seconds(cfg.Controller.Analysis.TimelineBucketSeconds))
+	agg.SetTestGroups(topo.TestGroups())
+	agg.SetTestProfiles(topo.TestProfiles())
+	agg.SetTestFamilies(topo.TestFamilies())
+	agg.SetTestInterfaces(topo.TestInterfaces())
+	agg.SetTestHops(topo.TestHops())
+	agg.SetBackgroundTests(topo.BackgroundTests())
+	agg.SetTestSources(topo.TestSources())
+	agg.SetTestDestinations(topo.TestDestinations())
+	agg.SetExpectedTests(topo.TestIDs())
+
+	if runErr := orch.ExecuteTest(ctx, topo); runErr != nil {
+		runErr = fmt.Errorf("test execution failed: %w", runErr)
+		partial := orch.PartialResults()
+		if len(partial) == 0 || agg.AddResults(partial) != nil {
+			return nil, runErr
+		}
+		return newReport(agg, orch), runErr
+	}
+
+	if err := agg.CollectResults(ctx, pool); err != nil {
+		return newReport(agg, orch), fmt.Errorf("failed to collect results: %w", err)
+	}
+	return newReport(agg, orch), nil
+}
+
+// newReport converts the aggregated results of a run
+func newReport(agg *aggregator.Aggregator, orch *orchestrator.Orchestrator) *Report {
+	summary := agg.GetSummary()
+	report := &Report{
+		Summary: Summary{
+			TotalTests:       summary.TotalTests,
+			CompletedTests:   summary.CompletedTests,
+			FailedTests:      summary.FailedTests,
+			BackgroundTests:  summary.BackgroundTests,
+			AvgThroughputBps: summary.AvgThroughput,
+			MinThroughputBps: summary.MinThroughput,
+			MaxThroughputBps: summary.MaxThroughput,
+			TotalRetransmits: summary.TotalRetransmits,
+		},
+	}
+	for _, result := range agg.GetResults() {
+		report.Results = append(report.Results, newResult(result))
+	}
+	for _, stalled := range orch.StalledTests() {
+		report.Stalled = append(report.Stalled, stalled.TestID)
+	}
+	return report
+}
+
+// newResult converts an aggregated result
+func newResult(result *aggregator.TestResult) *Result {
+	converted := &Result{
+		TestID:                result.TestID,
+		Source:                result.SourceNode,
+		Destination:           result.DestNode,
+		Profile:               result.Profile,
+		Group:                 result.Group,
+		Background:            result.Background,
+		Status:                strings.ToLower(strings.TrimPrefix(result.Status, "TEST_STATUS_")),
+		Error:                 result.ErrorMessage,
+		ThroughputBps:         result.ThroughputBps,
+		SenderThroughputBps:   result.SenderThroughputBps,
+		ReceiverThroughputBps: result.ReceiverThroughputBps,
+		Retransmits:           result.Retransmits,
+		MeanRTT:               time.Duration(result.MeanRTTMs * float64(time.Millisecond)),
+		IperfData:             result.IperfData,
+	}
+	if result.StartTime > 0 {
+		converted.Start = time.Unix(result.StartTime, 0)
+	}
+	if result.EndTime > 0 {
+		converted.End = time.Unix(result.EndTime, 0)
+	}
+	return converted
+}